@@ -18,6 +18,10 @@ package linux
 const (
 	CSIGNAL = 0xff
 
+	// CLONE_NEWTIME overlaps with CSIGNAL, and can thus only be passed to
+	// clone3(2), unshare(2) and setns(2).
+	CLONE_NEWTIME = 0x80
+
 	CLONE_VM             = 0x100
 	CLONE_FS             = 0x200
 	CLONE_FILES          = 0x400
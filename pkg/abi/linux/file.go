@@ -42,6 +42,28 @@ const (
 	O_TMPFILE  = 020000000 // __O_TMPFILE in Linux
 )
 
+// OpenHow is struct open_how, from uapi/linux/openat2.h.
+//
+// +marshal
+type OpenHow struct {
+	Flags   uint64
+	Mode    uint64
+	Resolve uint64
+}
+
+// OPEN_HOW_SIZE_VER0 is the size of the first published struct open_how.
+const OPEN_HOW_SIZE_VER0 = 24
+
+// Constants for open_how.resolve.
+const (
+	RESOLVE_NO_XDEV       = 0x01
+	RESOLVE_NO_MAGICLINKS = 0x02
+	RESOLVE_NO_SYMLINKS   = 0x04
+	RESOLVE_BENEATH       = 0x08
+	RESOLVE_IN_ROOT       = 0x10
+	RESOLVE_CACHED        = 0x20
+)
+
 // Constants for fstatat(2).
 const (
 	AT_SYMLINK_NOFOLLOW = 0x100
@@ -96,6 +118,11 @@ const (
 	AT_EMPTY_PATH     = 0x1000
 )
 
+// Constants for open_tree(2) and mount_setattr(2).
+const (
+	AT_RECURSIVE = 0x8000
+)
+
 // Constants for faccessat2(2).
 const (
 	AT_EACCESS = 0x200
@@ -30,6 +30,7 @@ const (
 	FUTEX_WAKE_BITSET     = 10
 	FUTEX_WAIT_REQUEUE_PI = 11
 	FUTEX_CMP_REQUEUE_PI  = 12
+	FUTEX_LOCK_PI2        = 13
 
 	FUTEX_PRIVATE_FLAG   = 128
 	FUTEX_CLOCK_REALTIME = 256
@@ -64,6 +65,36 @@ const (
 // FUTEX_BITSET_MATCH_ANY has all bits set.
 const FUTEX_BITSET_MATCH_ANY = 0xffffffff
 
+// Flags used by the futex2 syscalls: futex_waitv(2), futex_wake(2),
+// futex_wait(2) and futex_requeue(2).
+const (
+	FUTEX2_SIZE_U8   = 0x00
+	FUTEX2_SIZE_U16  = 0x01
+	FUTEX2_SIZE_U32  = 0x02
+	FUTEX2_SIZE_U64  = 0x03
+	FUTEX2_NUMA      = 0x04
+	FUTEX2_MPOL      = 0x08
+	FUTEX2_PRIVATE   = FUTEX_PRIVATE_FLAG
+	FUTEX2_SIZE_MASK = 0x03
+
+	// FUTEX_32 is the legacy name of FUTEX2_SIZE_U32.
+	FUTEX_32 = FUTEX2_SIZE_U32
+)
+
+// FUTEX_WAITV_MAX is the maximum number of futexes that can be waited on by
+// futex_waitv(2).
+const FUTEX_WAITV_MAX = 128
+
+// FutexWaitv corresponds to Linux's struct futex_waitv.
+//
+// +marshal slice:FutexWaitvSlice
+type FutexWaitv struct {
+	Val      uint64
+	Uaddr    uint64
+	Flags    uint32
+	Reserved uint32
+}
+
 // ROBUST_LIST_LIMIT protects against a deliberately circular list.
 const ROBUST_LIST_LIMIT = 2048
 
@@ -29,11 +29,57 @@ const (
 // Constants for io_uring_enter(2). See include/uapi/linux/io_uring.h.
 const (
 	IORING_ENTER_GETEVENTS = (1 << 0)
+	IORING_ENTER_SQ_WAKEUP = (1 << 1)
+	IORING_ENTER_SQ_WAIT   = (1 << 2)
 )
 
 // Constants for IoUringParams.Features. See include/uapi/linux/io_uring.h.
 const (
-	IORING_FEAT_SINGLE_MMAP = (1 << 0)
+	IORING_FEAT_SINGLE_MMAP     = (1 << 0)
+	IORING_FEAT_NODROP          = (1 << 1)
+	IORING_FEAT_SUBMIT_STABLE   = (1 << 2)
+	IORING_FEAT_RW_CUR_POS      = (1 << 3)
+	IORING_FEAT_CUR_PERSONALITY = (1 << 4)
+	IORING_FEAT_FAST_POLL       = (1 << 5)
+)
+
+// Constants for IORings.sqFlags. See include/uapi/linux/io_uring.h.
+const (
+	IORING_SQ_NEED_WAKEUP = (1 << 0)
+	IORING_SQ_CQ_OVERFLOW = (1 << 1)
+	IORING_SQ_TASKRUN     = (1 << 2)
+)
+
+// Constants for IOUringSqe.Flags. See include/uapi/linux/io_uring.h.
+const (
+	IOSQE_FIXED_FILE       = (1 << 0)
+	IOSQE_IO_DRAIN         = (1 << 1)
+	IOSQE_IO_LINK          = (1 << 2)
+	IOSQE_IO_HARDLINK      = (1 << 3)
+	IOSQE_ASYNC            = (1 << 4)
+	IOSQE_BUFFER_SELECT    = (1 << 5)
+	IOSQE_CQE_SKIP_SUCCESS = (1 << 6)
+)
+
+// Constants for IOUringSqe.OpFlags of IORING_OP_FSYNC. See
+// include/uapi/linux/io_uring.h.
+const (
+	IORING_FSYNC_DATASYNC = (1 << 0)
+)
+
+// Constants for IOUringSqe.OpFlags of IORING_OP_TIMEOUT and
+// IORING_OP_LINK_TIMEOUT. See include/uapi/linux/io_uring.h.
+const (
+	IORING_TIMEOUT_ABS      = (1 << 0)
+	IORING_TIMEOUT_UPDATE   = (1 << 1)
+	IORING_TIMEOUT_BOOTTIME = (1 << 2)
+	IORING_TIMEOUT_REALTIME = (1 << 3)
+)
+
+// Constants for IOUringSqe.Len of IORING_OP_POLL_ADD. See
+// include/uapi/linux/io_uring.h.
+const (
+	IORING_POLL_ADD_MULTI = (1 << 0)
 )
 
 // Constants for IO_URING. See include/uapi/linux/io_uring.h.
@@ -60,8 +106,63 @@ const (
 
 // Constants for the IO_URING opcodes. See include/uapi/linux/io_uring.h.
 const (
-	IORING_OP_NOP   = 0
-	IORING_OP_READV = 1
+	IORING_OP_NOP             = 0
+	IORING_OP_READV           = 1
+	IORING_OP_WRITEV          = 2
+	IORING_OP_FSYNC           = 3
+	IORING_OP_READ_FIXED      = 4
+	IORING_OP_WRITE_FIXED     = 5
+	IORING_OP_POLL_ADD        = 6
+	IORING_OP_POLL_REMOVE     = 7
+	IORING_OP_SYNC_FILE_RANGE = 8
+	IORING_OP_SENDMSG         = 9
+	IORING_OP_RECVMSG         = 10
+	IORING_OP_TIMEOUT         = 11
+	IORING_OP_TIMEOUT_REMOVE  = 12
+	IORING_OP_ACCEPT          = 13
+	IORING_OP_ASYNC_CANCEL    = 14
+	IORING_OP_LINK_TIMEOUT    = 15
+	IORING_OP_CONNECT         = 16
+	IORING_OP_FALLOCATE       = 17
+	IORING_OP_OPENAT          = 18
+	IORING_OP_CLOSE           = 19
+	IORING_OP_FILES_UPDATE    = 20
+	IORING_OP_STATX           = 21
+	IORING_OP_READ            = 22
+	IORING_OP_WRITE           = 23
+	IORING_OP_FADVISE         = 24
+	IORING_OP_MADVISE         = 25
+	IORING_OP_SEND            = 26
+	IORING_OP_RECV            = 27
+	IORING_OP_LAST            = 28
+)
+
+// Constants for io_uring_register(2) opcodes. See
+// include/uapi/linux/io_uring.h.
+const (
+	IORING_REGISTER_BUFFERS       = 0
+	IORING_UNREGISTER_BUFFERS     = 1
+	IORING_REGISTER_FILES         = 2
+	IORING_UNREGISTER_FILES       = 3
+	IORING_REGISTER_EVENTFD       = 4
+	IORING_UNREGISTER_EVENTFD     = 5
+	IORING_REGISTER_FILES_UPDATE  = 6
+	IORING_REGISTER_EVENTFD_ASYNC = 7
+	IORING_REGISTER_PROBE         = 8
+)
+
+// IORING_REGISTER_FILES_SKIP is the fd value that leaves a registered file
+// unchanged in IORING_REGISTER_FILES_UPDATE. See include/uapi/linux/io_uring.h.
+const IORING_REGISTER_FILES_SKIP = -2
+
+// IO_URING_OP_SUPPORTED is set in IOUringProbeOp.Flags for supported opcodes.
+// See include/uapi/linux/io_uring.h.
+const IO_URING_OP_SUPPORTED = (1 << 0)
+
+// Limits for registered resources. See io_uring/rsrc.h.
+const (
+	IORING_MAX_REG_BUFFERS = (1 << 14)
+	IORING_MAX_FIXED_FILES = (1 << 20)
 )
 
 // IORingIndex represents SQE array indexes.
@@ -169,6 +270,38 @@ type IORings struct {
 	// a dynamic array. We don't include it here in order to enable marshalling.
 }
 
+// IOUringProbeOp implements io_uring_probe_op struct.
+// See include/uapi/linux/io_uring.h.
+//
+// +marshal slice:IOUringProbeOpSlice
+type IOUringProbeOp struct {
+	Op    uint8
+	_     uint8
+	Flags uint16
+	_     uint32
+}
+
+// IOUringProbe implements io_uring_probe struct, without the trailing ops
+// array. See include/uapi/linux/io_uring.h.
+//
+// +marshal
+type IOUringProbe struct {
+	LastOp uint8
+	OpsLen uint8
+	_      uint16
+	_      [3]uint32
+}
+
+// IOUringFilesUpdate implements io_uring_files_update struct.
+// See include/uapi/linux/io_uring.h.
+//
+// +marshal
+type IOUringFilesUpdate struct {
+	Offset uint32
+	_      uint32
+	Fds    uint64
+}
+
 // IOUringSqe implements io_uring_sqe struct.
 // This struct represents IO submission data structure (Submission Queue Entry). As we don't yet
 // support IORING_SETUP_SQE128 flag, its size is 64 bytes with no extra padding at the end.
@@ -184,7 +317,7 @@ type IOUringSqe struct {
 	OffOrAddrOrCmdOp    uint64
 	AddrOrSpliceOff     uint64
 	Len                 uint32
-	specialFlags        uint32
+	OpFlags             uint32 // rw_flags, poll32_events, msg_flags, etc.
 	UserData            uint64
 	BufIndexOrGroup     uint16
 	personality         uint16
@@ -0,0 +1,79 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linux
+
+// Flags for landlock_create_ruleset(2), from include/uapi/linux/landlock.h.
+const (
+	LANDLOCK_CREATE_RULESET_VERSION = 1 << 0
+)
+
+// Rule types for landlock_add_rule(2).
+const (
+	LANDLOCK_RULE_PATH_BENEATH = 1
+	LANDLOCK_RULE_NET_PORT     = 2
+)
+
+// Filesystem access rights.
+const (
+	LANDLOCK_ACCESS_FS_EXECUTE     = 1 << 0
+	LANDLOCK_ACCESS_FS_WRITE_FILE  = 1 << 1
+	LANDLOCK_ACCESS_FS_READ_FILE   = 1 << 2
+	LANDLOCK_ACCESS_FS_READ_DIR    = 1 << 3
+	LANDLOCK_ACCESS_FS_REMOVE_DIR  = 1 << 4
+	LANDLOCK_ACCESS_FS_REMOVE_FILE = 1 << 5
+	LANDLOCK_ACCESS_FS_MAKE_CHAR   = 1 << 6
+	LANDLOCK_ACCESS_FS_MAKE_DIR    = 1 << 7
+	LANDLOCK_ACCESS_FS_MAKE_REG    = 1 << 8
+	LANDLOCK_ACCESS_FS_MAKE_SOCK   = 1 << 9
+	LANDLOCK_ACCESS_FS_MAKE_FIFO   = 1 << 10
+	LANDLOCK_ACCESS_FS_MAKE_BLOCK  = 1 << 11
+	LANDLOCK_ACCESS_FS_MAKE_SYM    = 1 << 12
+	LANDLOCK_ACCESS_FS_REFER       = 1 << 13
+	LANDLOCK_ACCESS_FS_TRUNCATE    = 1 << 14
+	LANDLOCK_ACCESS_FS_IOCTL_DEV   = 1 << 15
+)
+
+// Network access rights.
+const (
+	LANDLOCK_ACCESS_NET_BIND_TCP    = 1 << 0
+	LANDLOCK_ACCESS_NET_CONNECT_TCP = 1 << 1
+)
+
+// LandlockRulesetAttr is struct landlock_ruleset_attr, from
+// include/uapi/linux/landlock.h.
+//
+// +marshal
+type LandlockRulesetAttr struct {
+	HandledAccessFS  uint64
+	HandledAccessNet uint64
+}
+
+// SizeOfLandlockPathBeneathAttr is the size of struct
+// landlock_path_beneath_attr, which is packed:
+//
+//	struct landlock_path_beneath_attr {
+//		__u64 allowed_access;
+//		__s32 parent_fd;
+//	} __attribute__((packed));
+const SizeOfLandlockPathBeneathAttr = 12
+
+// LandlockNetPortAttr is struct landlock_net_port_attr, from
+// include/uapi/linux/landlock.h.
+//
+// +marshal
+type LandlockNetPortAttr struct {
+	AllowedAccess uint64
+	Port          uint64
+}
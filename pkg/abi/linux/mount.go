@@ -0,0 +1,94 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linux
+
+// Flags for open_tree(2), from include/uapi/linux/mount.h.
+const (
+	OPEN_TREE_CLONE   = 1
+	OPEN_TREE_CLOEXEC = O_CLOEXEC
+)
+
+// Flags for move_mount(2), from include/uapi/linux/mount.h.
+const (
+	MOVE_MOUNT_F_SYMLINKS   = 0x00000001
+	MOVE_MOUNT_F_AUTOMOUNTS = 0x00000002
+	MOVE_MOUNT_F_EMPTY_PATH = 0x00000004
+	MOVE_MOUNT_T_SYMLINKS   = 0x00000010
+	MOVE_MOUNT_T_AUTOMOUNTS = 0x00000020
+	MOVE_MOUNT_T_EMPTY_PATH = 0x00000040
+	MOVE_MOUNT_SET_GROUP    = 0x00000100
+	MOVE_MOUNT_BENEATH      = 0x00000200
+)
+
+// Flags for fsopen(2), from include/uapi/linux/mount.h.
+const (
+	FSOPEN_CLOEXEC = 0x00000001
+)
+
+// Flags for fspick(2), from include/uapi/linux/mount.h.
+const (
+	FSPICK_CLOEXEC          = 0x00000001
+	FSPICK_SYMLINK_NOFOLLOW = 0x00000002
+	FSPICK_NO_AUTOMOUNT     = 0x00000004
+	FSPICK_EMPTY_PATH       = 0x00000008
+)
+
+// Commands for fsconfig(2), from include/uapi/linux/mount.h.
+const (
+	FSCONFIG_SET_FLAG        = 0
+	FSCONFIG_SET_STRING      = 1
+	FSCONFIG_SET_BINARY      = 2
+	FSCONFIG_SET_PATH        = 3
+	FSCONFIG_SET_PATH_EMPTY  = 4
+	FSCONFIG_SET_FD          = 5
+	FSCONFIG_CMD_CREATE      = 6
+	FSCONFIG_CMD_RECONFIGURE = 7
+	FSCONFIG_CMD_CREATE_EXCL = 8
+)
+
+// Flags for fsmount(2), from include/uapi/linux/mount.h.
+const (
+	FSMOUNT_CLOEXEC = 0x00000001
+)
+
+// Mount attributes for fsmount(2) and mount_setattr(2), from
+// include/uapi/linux/mount.h.
+const (
+	MOUNT_ATTR_RDONLY      = 0x00000001
+	MOUNT_ATTR_NOSUID      = 0x00000002
+	MOUNT_ATTR_NODEV       = 0x00000004
+	MOUNT_ATTR_NOEXEC      = 0x00000008
+	MOUNT_ATTR__ATIME      = 0x00000070
+	MOUNT_ATTR_RELATIME    = 0x00000000
+	MOUNT_ATTR_NOATIME     = 0x00000010
+	MOUNT_ATTR_STRICTATIME = 0x00000020
+	MOUNT_ATTR_NODIRATIME  = 0x00000080
+	MOUNT_ATTR_IDMAP       = 0x00100000
+	MOUNT_ATTR_NOSYMFOLLOW = 0x00200000
+)
+
+// MountAttr is equivalent to struct mount_attr, the argument to
+// mount_setattr(2).
+//
+// +marshal
+type MountAttr struct {
+	AttrSet     uint64
+	AttrClr     uint64
+	Propagation uint64
+	UsernsFD    uint64
+}
+
+// MOUNT_ATTR_SIZE_VER0 is the size of the first published struct mount_attr.
+const MOUNT_ATTR_SIZE_VER0 = 32
@@ -0,0 +1,163 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linux
+
+// NETLINK_SOCK_DIAG message types, from uapi/linux/sock_diag.h.
+const (
+	SOCK_DIAG_BY_FAMILY = 20
+	SOCK_DESTROY        = 21
+)
+
+// Legacy NETLINK_INET_DIAG message types, from uapi/linux/inet_diag.h.
+const (
+	TCPDIAG_GETSOCK  = 18
+	DCCPDIAG_GETSOCK = 19
+)
+
+// INET_DIAG_NOCOOKIE is the cookie value matching any socket, from
+// uapi/linux/inet_diag.h.
+const INET_DIAG_NOCOOKIE = ^uint32(0)
+
+// InetDiagSockID is struct inet_diag_sockid, from uapi/linux/inet_diag.h.
+//
+// Ports are in network byte order.
+//
+// +marshal
+type InetDiagSockID struct {
+	Sport  uint16
+	Dport  uint16
+	Src    [16]byte
+	Dst    [16]byte
+	If     uint32
+	Cookie [2]uint32
+}
+
+// InetDiagReqV2 is struct inet_diag_req_v2, from uapi/linux/inet_diag.h.
+//
+// +marshal
+type InetDiagReqV2 struct {
+	Family   uint8
+	Protocol uint8
+	Ext      uint8
+	Pad      uint8
+	States   uint32
+	ID       InetDiagSockID
+}
+
+// InetDiagMsg is struct inet_diag_msg, from uapi/linux/inet_diag.h.
+//
+// +marshal
+type InetDiagMsg struct {
+	Family  uint8
+	State   uint8
+	Timer   uint8
+	Retrans uint8
+	ID      InetDiagSockID
+	Expires uint32
+	RQueue  uint32
+	WQueue  uint32
+	UID     uint32
+	Inode   uint32
+}
+
+// INET_DIAG attributes, from uapi/linux/inet_diag.h.
+//
+// The extensions requested in InetDiagReqV2.Ext are selected by the bit
+// 1 << (attribute - 1).
+const (
+	INET_DIAG_NONE      = 0
+	INET_DIAG_MEMINFO   = 1
+	INET_DIAG_INFO      = 2
+	INET_DIAG_VEGASINFO = 3
+	INET_DIAG_CONG      = 4
+	INET_DIAG_TOS       = 5
+	INET_DIAG_TCLASS    = 6
+	INET_DIAG_SKMEMINFO = 7
+	INET_DIAG_SHUTDOWN  = 8
+	INET_DIAG_DCTCPINFO = 9
+	INET_DIAG_PROTOCOL  = 10
+	INET_DIAG_SKV6ONLY  = 11
+	INET_DIAG_LOCALS    = 12
+	INET_DIAG_PEERS     = 13
+	INET_DIAG_PAD       = 14
+	INET_DIAG_MARK      = 15
+	INET_DIAG_BBRINFO   = 16
+)
+
+// InetDiagMeminfo is struct inet_diag_meminfo, from uapi/linux/inet_diag.h.
+//
+// +marshal
+type InetDiagMeminfo struct {
+	RMem uint32
+	WMem uint32
+	FMem uint32
+	TMem uint32
+}
+
+// UnixDiagReq is struct unix_diag_req, from uapi/linux/unix_diag.h.
+//
+// +marshal
+type UnixDiagReq struct {
+	Family   uint8
+	Protocol uint8
+	Pad      uint16
+	States   uint32
+	Ino      uint32
+	Show     uint32
+	Cookie   [2]uint32
+}
+
+// UnixDiagMsg is struct unix_diag_msg, from uapi/linux/unix_diag.h.
+//
+// +marshal
+type UnixDiagMsg struct {
+	Family uint8
+	Type   uint8
+	State  uint8
+	Pad    uint8
+	Ino    uint32
+	Cookie [2]uint32
+}
+
+// Flags for UnixDiagReq.Show, from uapi/linux/unix_diag.h.
+const (
+	UDIAG_SHOW_NAME    = 0x00000001
+	UDIAG_SHOW_VFS     = 0x00000002
+	UDIAG_SHOW_PEER    = 0x00000004
+	UDIAG_SHOW_ICONS   = 0x00000008
+	UDIAG_SHOW_RQLEN   = 0x00000010
+	UDIAG_SHOW_MEMINFO = 0x00000020
+	UDIAG_SHOW_UID     = 0x00000040
+)
+
+// UNIX_DIAG attributes, from uapi/linux/unix_diag.h.
+const (
+	UNIX_DIAG_NAME     = 0
+	UNIX_DIAG_VFS      = 1
+	UNIX_DIAG_PEER     = 2
+	UNIX_DIAG_ICONS    = 3
+	UNIX_DIAG_RQLEN    = 4
+	UNIX_DIAG_MEMINFO  = 5
+	UNIX_DIAG_SHUTDOWN = 6
+	UNIX_DIAG_UID      = 7
+)
+
+// UnixDiagRQlen is struct unix_diag_rqlen, from uapi/linux/unix_diag.h.
+//
+// +marshal
+type UnixDiagRQlen struct {
+	RQueue uint32
+	WQueue uint32
+}
@@ -273,3 +273,303 @@ const (
 	NFT_META_SDIFNAME             // Slave device interface name
 	NFT_META_BRI_BROUTE           // Packet br_netfilter_broute bit
 )
+
+// Name and data length limits.
+// These correspond to values in include/uapi/linux/netfilter/nf_tables.h.
+const (
+	NFT_NAME_MAXLEN       = 256
+	NFT_TABLE_MAXNAMELEN  = NFT_NAME_MAXLEN
+	NFT_CHAIN_MAXNAMELEN  = NFT_NAME_MAXLEN
+	NFT_SET_MAXNAMELEN    = NFT_NAME_MAXLEN
+	NFT_USERDATA_MAXLEN   = 256
+	NFT_DATA_VALUE_MAXLEN = 64
+)
+
+// Nf table data types, used to describe the data of set elements.
+// These correspond to values in include/uapi/linux/netfilter/nf_tables.h.
+const (
+	NFT_DATA_VALUE   = 0
+	NFT_DATA_VERDICT = 0xffffff00
+)
+
+// Nf table chain flags.
+// These correspond to values in include/uapi/linux/netfilter/nf_tables.h.
+const (
+	NFT_CHAIN_BASE       = 1 << 0
+	NFT_CHAIN_HW_OFFLOAD = 1 << 1
+	NFT_CHAIN_BINDING    = 1 << 2
+)
+
+// Nf table chain attributes.
+// These correspond to values in include/uapi/linux/netfilter/nf_tables.h.
+const (
+	NFTA_CHAIN_UNSPEC uint16 = iota
+	NFTA_CHAIN_TABLE
+	NFTA_CHAIN_HANDLE
+	NFTA_CHAIN_NAME
+	NFTA_CHAIN_HOOK
+	NFTA_CHAIN_POLICY
+	NFTA_CHAIN_USE
+	NFTA_CHAIN_TYPE
+	NFTA_CHAIN_COUNTERS
+	NFTA_CHAIN_PAD
+	NFTA_CHAIN_FLAGS
+	NFTA_CHAIN_ID
+	NFTA_CHAIN_USERDATA
+)
+
+// Nf table hook attributes, nested in NFTA_CHAIN_HOOK.
+// These correspond to values in include/uapi/linux/netfilter/nf_tables.h.
+const (
+	NFTA_HOOK_UNSPEC uint16 = iota
+	NFTA_HOOK_HOOKNUM
+	NFTA_HOOK_PRIORITY
+	NFTA_HOOK_DEV
+	NFTA_HOOK_DEVS
+)
+
+// Nf table rule attributes.
+// These correspond to values in include/uapi/linux/netfilter/nf_tables.h.
+const (
+	NFTA_RULE_UNSPEC uint16 = iota
+	NFTA_RULE_TABLE
+	NFTA_RULE_CHAIN
+	NFTA_RULE_HANDLE
+	NFTA_RULE_EXPRESSIONS
+	NFTA_RULE_COMPAT
+	NFTA_RULE_POSITION
+	NFTA_RULE_USERDATA
+	NFTA_RULE_PAD
+	NFTA_RULE_ID
+	NFTA_RULE_POSITION_ID
+	NFTA_RULE_CHAIN_ID
+)
+
+// Nf table list attributes, used for lists of expressions and set elements.
+// These correspond to values in include/uapi/linux/netfilter/nf_tables.h.
+const (
+	NFTA_LIST_UNSPEC uint16 = iota
+	NFTA_LIST_ELEM
+)
+
+// Nf table expression attributes.
+// These correspond to values in include/uapi/linux/netfilter/nf_tables.h.
+const (
+	NFTA_EXPR_UNSPEC uint16 = iota
+	NFTA_EXPR_NAME
+	NFTA_EXPR_DATA
+)
+
+// Nf table data attributes.
+// These correspond to values in include/uapi/linux/netfilter/nf_tables.h.
+const (
+	NFTA_DATA_UNSPEC uint16 = iota
+	NFTA_DATA_VALUE
+	NFTA_DATA_VERDICT
+)
+
+// Nf table verdict attributes, nested in NFTA_DATA_VERDICT.
+// These correspond to values in include/uapi/linux/netfilter/nf_tables.h.
+const (
+	NFTA_VERDICT_UNSPEC uint16 = iota
+	NFTA_VERDICT_CODE
+	NFTA_VERDICT_CHAIN
+	NFTA_VERDICT_CHAIN_ID
+)
+
+// Nf table immediate expression attributes.
+// These correspond to values in include/uapi/linux/netfilter/nf_tables.h.
+const (
+	NFTA_IMMEDIATE_UNSPEC uint16 = iota
+	NFTA_IMMEDIATE_DREG
+	NFTA_IMMEDIATE_DATA
+)
+
+// Nf table bitwise expression attributes.
+// These correspond to values in include/uapi/linux/netfilter/nf_tables.h.
+const (
+	NFTA_BITWISE_UNSPEC uint16 = iota
+	NFTA_BITWISE_SREG
+	NFTA_BITWISE_DREG
+	NFTA_BITWISE_LEN
+	NFTA_BITWISE_MASK
+	NFTA_BITWISE_XOR
+	NFTA_BITWISE_OP
+	NFTA_BITWISE_DATA
+)
+
+// Nf table byteorder expression attributes.
+// These correspond to values in include/uapi/linux/netfilter/nf_tables.h.
+const (
+	NFTA_BYTEORDER_UNSPEC uint16 = iota
+	NFTA_BYTEORDER_SREG
+	NFTA_BYTEORDER_DREG
+	NFTA_BYTEORDER_OP
+	NFTA_BYTEORDER_LEN
+	NFTA_BYTEORDER_SIZE
+)
+
+// Nf table comparison expression attributes.
+// These correspond to values in include/uapi/linux/netfilter/nf_tables.h.
+const (
+	NFTA_CMP_UNSPEC uint16 = iota
+	NFTA_CMP_SREG
+	NFTA_CMP_OP
+	NFTA_CMP_DATA
+)
+
+// Nf table range expression attributes.
+// These correspond to values in include/uapi/linux/netfilter/nf_tables.h.
+const (
+	NFTA_RANGE_UNSPEC uint16 = iota
+	NFTA_RANGE_SREG
+	NFTA_RANGE_OP
+	NFTA_RANGE_FROM_DATA
+	NFTA_RANGE_TO_DATA
+)
+
+// Nf table lookup expression flags.
+// These correspond to values in include/uapi/linux/netfilter/nf_tables.h.
+const (
+	NFT_LOOKUP_F_INV = 1 << 0
+)
+
+// Nf table lookup expression attributes.
+// These correspond to values in include/uapi/linux/netfilter/nf_tables.h.
+const (
+	NFTA_LOOKUP_UNSPEC uint16 = iota
+	NFTA_LOOKUP_SET
+	NFTA_LOOKUP_SREG
+	NFTA_LOOKUP_DREG
+	NFTA_LOOKUP_SET_ID
+	NFTA_LOOKUP_FLAGS
+)
+
+// Nf table payload expression attributes.
+// These correspond to values in include/uapi/linux/netfilter/nf_tables.h.
+const (
+	NFTA_PAYLOAD_UNSPEC uint16 = iota
+	NFTA_PAYLOAD_DREG
+	NFTA_PAYLOAD_BASE
+	NFTA_PAYLOAD_OFFSET
+	NFTA_PAYLOAD_LEN
+	NFTA_PAYLOAD_SREG
+	NFTA_PAYLOAD_CSUM_TYPE
+	NFTA_PAYLOAD_CSUM_OFFSET
+	NFTA_PAYLOAD_CSUM_FLAGS
+)
+
+// Nf table meta expression attributes.
+// These correspond to values in include/uapi/linux/netfilter/nf_tables.h.
+const (
+	NFTA_META_UNSPEC uint16 = iota
+	NFTA_META_DREG
+	NFTA_META_KEY
+	NFTA_META_SREG
+)
+
+// Nf table route expression attributes.
+// These correspond to values in include/uapi/linux/netfilter/nf_tables.h.
+const (
+	NFTA_RT_UNSPEC uint16 = iota
+	NFTA_RT_DREG
+	NFTA_RT_KEY
+)
+
+// Nf table counter expression attributes.
+// These correspond to values in include/uapi/linux/netfilter/nf_tables.h.
+const (
+	NFTA_COUNTER_UNSPEC uint16 = iota
+	NFTA_COUNTER_BYTES
+	NFTA_COUNTER_PACKETS
+	NFTA_COUNTER_PAD
+)
+
+// Nf table last expression attributes.
+// These correspond to values in include/uapi/linux/netfilter/nf_tables.h.
+const (
+	NFTA_LAST_UNSPEC uint16 = iota
+	NFTA_LAST_SET
+	NFTA_LAST_MSECS
+	NFTA_LAST_PAD
+)
+
+// Nf table set flags.
+// These correspond to values in include/uapi/linux/netfilter/nf_tables.h.
+const (
+	NFT_SET_ANONYMOUS = 0x1
+	NFT_SET_CONSTANT  = 0x2
+	NFT_SET_INTERVAL  = 0x4
+	NFT_SET_MAP       = 0x8
+	NFT_SET_TIMEOUT   = 0x10
+	NFT_SET_EVAL      = 0x20
+	NFT_SET_OBJECT    = 0x40
+	NFT_SET_CONCAT    = 0x80
+	NFT_SET_EXPR      = 0x100
+)
+
+// Nf table set attributes.
+// These correspond to values in include/uapi/linux/netfilter/nf_tables.h.
+const (
+	NFTA_SET_UNSPEC uint16 = iota
+	NFTA_SET_TABLE
+	NFTA_SET_NAME
+	NFTA_SET_FLAGS
+	NFTA_SET_KEY_TYPE
+	NFTA_SET_KEY_LEN
+	NFTA_SET_DATA_TYPE
+	NFTA_SET_DATA_LEN
+	NFTA_SET_POLICY
+	NFTA_SET_DESC
+	NFTA_SET_ID
+	NFTA_SET_TIMEOUT
+	NFTA_SET_GC_INTERVAL
+	NFTA_SET_USERDATA
+	NFTA_SET_PAD
+	NFTA_SET_OBJ_TYPE
+	NFTA_SET_HANDLE
+	NFTA_SET_EXPR
+	NFTA_SET_EXPRESSIONS
+)
+
+// Nf table set element flags.
+// These correspond to values in include/uapi/linux/netfilter/nf_tables.h.
+const (
+	NFT_SET_ELEM_INTERVAL_END = 0x1
+	NFT_SET_ELEM_CATCHALL     = 0x2
+)
+
+// Nf table set element attributes.
+// These correspond to values in include/uapi/linux/netfilter/nf_tables.h.
+const (
+	NFTA_SET_ELEM_UNSPEC uint16 = iota
+	NFTA_SET_ELEM_KEY
+	NFTA_SET_ELEM_DATA
+	NFTA_SET_ELEM_FLAGS
+	NFTA_SET_ELEM_TIMEOUT
+	NFTA_SET_ELEM_EXPIRATION
+	NFTA_SET_ELEM_USERDATA
+	NFTA_SET_ELEM_EXPR
+	NFTA_SET_ELEM_OBJREF
+	NFTA_SET_ELEM_KEY_END
+	NFTA_SET_ELEM_EXPRESSIONS
+)
+
+// Nf table set element list attributes.
+// These correspond to values in include/uapi/linux/netfilter/nf_tables.h.
+const (
+	NFTA_SET_ELEM_LIST_UNSPEC uint16 = iota
+	NFTA_SET_ELEM_LIST_TABLE
+	NFTA_SET_ELEM_LIST_SET
+	NFTA_SET_ELEM_LIST_ELEMENTS
+	NFTA_SET_ELEM_LIST_SET_ID
+)
+
+// Nf table ruleset generation attributes.
+// These correspond to values in include/uapi/linux/netfilter/nf_tables.h.
+const (
+	NFTA_GEN_UNSPEC uint16 = iota
+	NFTA_GEN_ID
+	NFTA_GEN_PROC_PID
+	NFTA_GEN_PROC_NAME
+)
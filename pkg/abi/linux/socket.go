@@ -504,8 +504,8 @@ type TCPInfo struct {
 	// rcv_wscale (second 4 bits)
 	WindowScale uint8
 
-	// DeliveryRateAppLimited is a boolean and only the first bit is
-	// meaningful.
+	// DeliveryRateAppLimited is a boolean held in the first bit. The next
+	// two bits hold the TCP Fast Open client failure reason (TFO_*).
 	DeliveryRateAppLimited uint8
 
 	// RTO is the retransmission timeout.
@@ -628,12 +628,121 @@ type TCPInfo struct {
 // SizeOfTCPInfo is the binary size of a TCPInfo struct.
 var SizeOfTCPInfo = (*TCPInfo)(nil).SizeBytes()
 
+// TCPBBRInfo is the information reported by the BBR congestion control
+// algorithm with TCP_CC_INFO.
+//
+// From uapi/linux/inet_diag.h.
+//
+// +marshal
+type TCPBBRInfo struct {
+	// BwLo is the lower 32 bits of the estimated bandwidth, in bytes per
+	// second.
+	BwLo uint32
+
+	// BwHi is the upper 32 bits of the estimated bandwidth.
+	BwHi uint32
+
+	// MinRTT is the minimum RTT, in microseconds.
+	MinRTT uint32
+
+	// PacingGain is the pacing gain, scaled by 256.
+	PacingGain uint32
+
+	// CwndGain is the congestion window gain, scaled by 256.
+	CwndGain uint32
+}
+
 // Control message types, from linux/socket.h.
 const (
 	SCM_CREDENTIALS = 0x2
 	SCM_RIGHTS      = 0x1
 )
 
+// Control message types of SOL_SOCKET which are socket options too, from
+// include/uapi/asm-generic/socket.h.
+const (
+	SCM_TIMESTAMPING     = SO_TIMESTAMPING
+	SCM_TXTIME           = SO_TXTIME
+	SCM_TIMESTAMPING_NEW = SO_TIMESTAMPING_NEW
+)
+
+// Flags of SO_TIMESTAMPING, from include/uapi/linux/net_tstamp.h.
+const (
+	SOF_TIMESTAMPING_TX_HARDWARE  = 1 << 0
+	SOF_TIMESTAMPING_TX_SOFTWARE  = 1 << 1
+	SOF_TIMESTAMPING_RX_HARDWARE  = 1 << 2
+	SOF_TIMESTAMPING_RX_SOFTWARE  = 1 << 3
+	SOF_TIMESTAMPING_SOFTWARE     = 1 << 4
+	SOF_TIMESTAMPING_SYS_HARDWARE = 1 << 5
+	SOF_TIMESTAMPING_RAW_HARDWARE = 1 << 6
+	SOF_TIMESTAMPING_OPT_ID       = 1 << 7
+	SOF_TIMESTAMPING_TX_SCHED     = 1 << 8
+	SOF_TIMESTAMPING_TX_ACK       = 1 << 9
+	SOF_TIMESTAMPING_OPT_CMSG     = 1 << 10
+	SOF_TIMESTAMPING_OPT_TSONLY   = 1 << 11
+	SOF_TIMESTAMPING_OPT_STATS    = 1 << 12
+	SOF_TIMESTAMPING_OPT_PKTINFO  = 1 << 13
+	SOF_TIMESTAMPING_OPT_TX_SWHW  = 1 << 14
+	SOF_TIMESTAMPING_BIND_PHC     = 1 << 15
+	SOF_TIMESTAMPING_OPT_ID_TCP   = 1 << 16
+
+	SOF_TIMESTAMPING_LAST = SOF_TIMESTAMPING_OPT_ID_TCP
+	SOF_TIMESTAMPING_MASK = SOF_TIMESTAMPING_LAST<<1 - 1
+)
+
+// Kinds of TX timestamps, reported in the ee_info field of the
+// sock_extended_err of SO_EE_ORIGIN_TIMESTAMPING, from
+// include/uapi/linux/errqueue.h.
+const (
+	SCM_TSTAMP_SND   = 0
+	SCM_TSTAMP_SCHED = 1
+	SCM_TSTAMP_ACK   = 2
+)
+
+// Flags of SO_TXTIME, from include/uapi/linux/net_tstamp.h.
+const (
+	SOF_TXTIME_DEADLINE_MODE = 1 << 0
+	SOF_TXTIME_REPORT_ERRORS = 1 << 1
+
+	SOF_TXTIME_FLAGS_LAST = SOF_TXTIME_REPORT_ERRORS
+	SOF_TXTIME_FLAGS_MASK = SOF_TXTIME_FLAGS_LAST<<1 - 1
+)
+
+// SockTimestamping is struct so_timestamping, from
+// include/uapi/linux/net_tstamp.h.
+//
+// +marshal
+type SockTimestamping struct {
+	Flags   int32
+	BindPHC int32
+}
+
+// SizeOfSockTimestamping is the size of SockTimestamping.
+const SizeOfSockTimestamping = 8
+
+// SockTxtime is struct sock_txtime, from include/uapi/linux/net_tstamp.h.
+//
+// +marshal
+type SockTxtime struct {
+	ClockID int32
+	Flags   uint32
+}
+
+// SizeOfSockTxtime is the size of SockTxtime.
+const SizeOfSockTxtime = 8
+
+// ScmTimestamping is struct scm_timestamping, from
+// include/uapi/linux/errqueue.h. TS[0] holds the software timestamp, and the
+// others hardware timestamps.
+//
+// +marshal
+type ScmTimestamping struct {
+	TS [3]Timespec
+}
+
+// SizeOfScmTimestamping is the size of ScmTimestamping.
+const SizeOfScmTimestamping = 48
+
 // A ControlMessageHeader is the header for a socket control message.
 //
 // ControlMessageHeader represents struct cmsghdr from linux/socket.h.
@@ -704,6 +813,9 @@ const SizeOfControlMessageTClass = 4
 // SizeOfControlMessageHopLimit is the size of an IPV6_HOPLIMIT control message.
 const SizeOfControlMessageHopLimit = 4
 
+// SizeOfControlMessageTXTime is the size of an SCM_TXTIME control message.
+const SizeOfControlMessageTXTime = 8
+
 // SizeOfControlMessageIPPacketInfo is the size of an IP_PKTINFO control
 // message.
 const SizeOfControlMessageIPPacketInfo = 12
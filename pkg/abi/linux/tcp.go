@@ -52,6 +52,11 @@ const (
 	TCP_ZEROCOPY_RECEIVE     = 35
 	TCP_INQ                  = 36
 	TCP_TX_DELAY             = 37
+	TCP_AO_ADD_KEY           = 38
+	TCP_AO_DEL_KEY           = 39
+	TCP_AO_INFO              = 40
+	TCP_AO_GET_KEYS          = 41
+	TCP_AO_REPAIR            = 42
 )
 
 // Socket constants from include/net/tcp.h.
@@ -69,3 +74,52 @@ const (
 	TCP_CA_Recovery = 3
 	TCP_CA_Loss     = 4
 )
+
+// Values for TCPInfo.Options from include/uapi/linux/tcp.h.
+const (
+	TCPI_OPT_TIMESTAMPS = 1
+	TCPI_OPT_SACK       = 2
+	TCPI_OPT_WSCALE     = 4
+	TCPI_OPT_ECN        = 8
+	TCPI_OPT_ECN_SEEN   = 16
+	TCPI_OPT_SYN_DATA   = 32
+)
+
+// TCP Fast Open client failure reasons reported in the tcpi_fastopen_client_fail
+// field of struct tcp_info, from include/uapi/linux/tcp.h.
+const (
+	TFO_STATUS_UNSPEC      = 0
+	TFO_COOKIE_UNAVAILABLE = 1
+	TFO_DATA_NOT_ACKED     = 2
+	TFO_SYN_RETRANSMITTED  = 3
+)
+
+// TCPFastOpenKeyLength is the length of a TCP Fast Open key set with
+// TCP_FASTOPEN_KEY, from include/net/tcp.h:TCP_FASTOPEN_KEY_LENGTH.
+const TCPFastOpenKeyLength = 16
+
+// TCP_MD5SIG_MAXKEYLEN is the maximum length of a TCP MD5 signature key, from
+// include/uapi/linux/tcp.h.
+const TCP_MD5SIG_MAXKEYLEN = 80
+
+// Flags for TCPMD5Sig.Flags, from include/uapi/linux/tcp.h.
+const (
+	TCP_MD5SIG_FLAG_PREFIX  = 1
+	TCP_MD5SIG_FLAG_IFINDEX = 2
+)
+
+// TCPMD5Sig is struct tcp_md5sig, from include/uapi/linux/tcp.h. It is used
+// with the TCP_MD5SIG and TCP_MD5SIG_EXT socket options.
+//
+// +marshal
+type TCPMD5Sig struct {
+	// Addr is the address of the peer, as a struct sockaddr_storage.
+	Addr [SockAddrMax]byte
+
+	// Flags and PrefixLen and IfIndex are only used with TCP_MD5SIG_EXT.
+	Flags     uint8
+	PrefixLen uint8
+	KeyLen    uint16
+	IfIndex   int32
+	Key       [TCP_MD5SIG_MAXKEYLEN]byte
+}
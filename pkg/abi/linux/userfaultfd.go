@@ -0,0 +1,185 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linux
+
+// Flags for userfaultfd(2), from include/uapi/linux/userfaultfd.h.
+const (
+	UFFD_USER_MODE_ONLY = 1
+)
+
+// UFFD_API is the userfaultfd API version, passed in UFFDIOAPI.API.
+const UFFD_API = 0xAA
+
+// Userfaultfd ioctl command numbers, from include/uapi/linux/userfaultfd.h.
+const (
+	UFFDIO = 0xAA
+
+	UFFDIO_REGISTER_NR     = 0x00
+	UFFDIO_UNREGISTER_NR   = 0x01
+	UFFDIO_WAKE_NR         = 0x02
+	UFFDIO_COPY_NR         = 0x03
+	UFFDIO_ZEROPAGE_NR     = 0x04
+	UFFDIO_WRITEPROTECT_NR = 0x06
+	UFFDIO_API_NR          = 0x3F
+)
+
+// Userfaultfd ioctl(2) request numbers, from include/uapi/linux/userfaultfd.h.
+var (
+	UFFDIO_API          = IOWR(UFFDIO, UFFDIO_API_NR, SizeOfUFFDIOAPI)
+	UFFDIO_REGISTER     = IOWR(UFFDIO, UFFDIO_REGISTER_NR, SizeOfUFFDIORegister)
+	UFFDIO_UNREGISTER   = IOR(UFFDIO, UFFDIO_UNREGISTER_NR, SizeOfUFFDIORange)
+	UFFDIO_WAKE         = IOR(UFFDIO, UFFDIO_WAKE_NR, SizeOfUFFDIORange)
+	UFFDIO_COPY         = IOWR(UFFDIO, UFFDIO_COPY_NR, SizeOfUFFDIOCopy)
+	UFFDIO_ZEROPAGE     = IOWR(UFFDIO, UFFDIO_ZEROPAGE_NR, SizeOfUFFDIOZeropage)
+	UFFDIO_WRITEPROTECT = IOWR(UFFDIO, UFFDIO_WRITEPROTECT_NR, SizeOfUFFDIOWriteProtect)
+)
+
+// Bitmasks of ioctls reported in UFFDIOAPI.Ioctls and UFFDIORegister.Ioctls.
+const (
+	UFFD_API_IOCTLS       = 1<<UFFDIO_REGISTER_NR | 1<<UFFDIO_UNREGISTER_NR | 1<<UFFDIO_API_NR
+	UFFD_API_RANGE_IOCTLS = 1<<UFFDIO_WAKE_NR | 1<<UFFDIO_COPY_NR | 1<<UFFDIO_ZEROPAGE_NR | 1<<UFFDIO_WRITEPROTECT_NR
+)
+
+// Userfaultfd features, negotiated by UFFDIO_API.
+const (
+	UFFD_FEATURE_PAGEFAULT_FLAG_WP  = 1 << 0
+	UFFD_FEATURE_EVENT_FORK         = 1 << 1
+	UFFD_FEATURE_EVENT_REMAP        = 1 << 2
+	UFFD_FEATURE_EVENT_REMOVE       = 1 << 3
+	UFFD_FEATURE_MISSING_HUGETLBFS  = 1 << 4
+	UFFD_FEATURE_MISSING_SHMEM      = 1 << 5
+	UFFD_FEATURE_EVENT_UNMAP        = 1 << 6
+	UFFD_FEATURE_SIGBUS             = 1 << 7
+	UFFD_FEATURE_THREAD_ID          = 1 << 8
+	UFFD_FEATURE_MINOR_HUGETLBFS    = 1 << 9
+	UFFD_FEATURE_MINOR_SHMEM        = 1 << 10
+	UFFD_FEATURE_EXACT_ADDRESS      = 1 << 11
+	UFFD_FEATURE_WP_HUGETLBFS_SHMEM = 1 << 12
+	UFFD_FEATURE_WP_UNPOPULATED     = 1 << 13
+	UFFD_FEATURE_POISON             = 1 << 14
+	UFFD_FEATURE_WP_ASYNC           = 1 << 15
+	UFFD_FEATURE_MOVE               = 1 << 16
+)
+
+// Userfaultfd events, reported in UFFDMsg.Event.
+const (
+	UFFD_EVENT_PAGEFAULT = 0x12
+	UFFD_EVENT_FORK      = 0x13
+	UFFD_EVENT_REMAP     = 0x14
+	UFFD_EVENT_REMOVE    = 0x15
+	UFFD_EVENT_UNMAP     = 0x16
+)
+
+// Flags for UFFD_EVENT_PAGEFAULT.
+const (
+	UFFD_PAGEFAULT_FLAG_WRITE = 1 << 0
+	UFFD_PAGEFAULT_FLAG_WP    = 1 << 1
+	UFFD_PAGEFAULT_FLAG_MINOR = 1 << 2
+)
+
+// Modes for UFFDIO_REGISTER.
+const (
+	UFFDIO_REGISTER_MODE_MISSING = 1 << 0
+	UFFDIO_REGISTER_MODE_WP      = 1 << 1
+	UFFDIO_REGISTER_MODE_MINOR   = 1 << 2
+)
+
+// Modes for UFFDIO_COPY, UFFDIO_ZEROPAGE and UFFDIO_WRITEPROTECT.
+const (
+	UFFDIO_COPY_MODE_DONTWAKE         = 1 << 0
+	UFFDIO_COPY_MODE_WP               = 1 << 1
+	UFFDIO_ZEROPAGE_MODE_DONTWAKE     = 1 << 0
+	UFFDIO_WRITEPROTECT_MODE_WP       = 1 << 0
+	UFFDIO_WRITEPROTECT_MODE_DONTWAKE = 1 << 1
+)
+
+// Sizes of userfaultfd structs.
+const (
+	SizeOfUFFDIOAPI          = 24
+	SizeOfUFFDIORange        = 16
+	SizeOfUFFDIORegister     = 32
+	SizeOfUFFDIOCopy         = 40
+	SizeOfUFFDIOZeropage     = 32
+	SizeOfUFFDIOWriteProtect = 24
+	SizeOfUFFDMsg            = 32
+)
+
+// UFFDIOAPI is equivalent to struct uffdio_api.
+//
+// +marshal
+type UFFDIOAPI struct {
+	API      uint64
+	Features uint64
+	Ioctls   uint64
+}
+
+// UFFDIORange is equivalent to struct uffdio_range.
+//
+// +marshal
+type UFFDIORange struct {
+	Start uint64
+	Len   uint64
+}
+
+// UFFDIORegister is equivalent to struct uffdio_register.
+//
+// +marshal
+type UFFDIORegister struct {
+	Range  UFFDIORange
+	Mode   uint64
+	Ioctls uint64
+}
+
+// UFFDIOCopy is equivalent to struct uffdio_copy.
+//
+// +marshal
+type UFFDIOCopy struct {
+	Dst  uint64
+	Src  uint64
+	Len  uint64
+	Mode uint64
+	Copy int64
+}
+
+// UFFDIOZeropage is equivalent to struct uffdio_zeropage.
+//
+// +marshal
+type UFFDIOZeropage struct {
+	Range    UFFDIORange
+	Mode     uint64
+	Zeropage int64
+}
+
+// UFFDIOWriteProtect is equivalent to struct uffdio_writeprotect.
+//
+// +marshal
+type UFFDIOWriteProtect struct {
+	Range UFFDIORange
+	Mode  uint64
+}
+
+// UFFDMsg is equivalent to struct uffd_msg, for UFFD_EVENT_PAGEFAULT events.
+//
+// +marshal
+type UFFDMsg struct {
+	Event   uint8
+	_       uint8
+	_       uint16
+	_       uint32
+	Flags   uint64
+	Address uint64
+	PTID    uint32
+	_       uint32
+}
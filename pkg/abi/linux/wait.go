@@ -34,9 +34,10 @@ const (
 
 // ID types for waitid(2), from include/uapi/linux/wait.h.
 const (
-	P_ALL  = 0x0
-	P_PID  = 0x1
-	P_PGID = 0x2
+	P_ALL   = 0x0
+	P_PID   = 0x1
+	P_PGID  = 0x2
+	P_PIDFD = 0x3
 )
 
 // WaitStatus represents a thread status, as returned by the wait* family of
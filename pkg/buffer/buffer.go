@@ -503,6 +503,11 @@ func (b *Buffer) WriteFromReader(r io.Reader, count int64) (int64, error) {
 //
 // This function clobbers the values of lr.
 func (b *Buffer) WriteFromReaderAndLimitedReader(r io.Reader, count int64, lr *io.LimitedReader) (int64, error) {
+	// Move views out of a BufferReader rather than copying them.
+	if br, ok := r.(*BufferReader); ok {
+		return br.b.moveFront(b, count), nil
+	}
+
 	if lr == nil {
 		lr = &io.LimitedReader{}
 	}
@@ -529,6 +534,28 @@ func (b *Buffer) WriteFromReaderAndLimitedReader(r io.Reader, count int64, lr *i
 	return done, nil
 }
 
+// moveFront moves up to count bytes from the front of b to the end of dst,
+// without copying them, and returns the number of bytes moved.
+func (b *Buffer) moveFront(dst *Buffer, count int64) int64 {
+	var done int64
+	for v := b.data.Front(); v != nil && done < count; v = b.data.Front() {
+		sz := int64(v.Size())
+		if done+sz > count {
+			// Share the view's chunk rather than splitting it.
+			clone := v.Clone()
+			clone.CapLength(int(count - done))
+			dst.appendOwned(clone)
+			b.advanceRead(count - done)
+			return count
+		}
+		b.data.Remove(v)
+		b.size -= sz
+		dst.appendOwned(v)
+		done += sz
+	}
+	return done
+}
+
 // ReadToWriter reads from the buffer into an io.Writer.
 //
 // N.B. This does not consume the bytes read. TrimFront should
@@ -623,6 +650,17 @@ func (br *BufferReader) Len() int {
 	return int(br.b.Size())
 }
 
+// ViewWriter is an io.Writer that can also take ownership of Views, allowing
+// their contents to be written without copying.
+type ViewWriter interface {
+	io.Writer
+
+	// WriteView writes the contents of v and takes ownership of v. As with
+	// io.Writer.Write, WriteView must return a non-nil error if it writes
+	// fewer than v.Size() bytes.
+	WriteView(v *View) (int, error)
+}
+
 // Range specifies a range of buffer.
 type Range struct {
 	begin int
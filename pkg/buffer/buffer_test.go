@@ -650,6 +650,45 @@ func TestReadFromLargeWriter(t *testing.T) {
 	}
 }
 
+func TestWriteFromBufferReader(t *testing.T) {
+	data := []byte("0123456789abcd")
+	for count := 0; count <= len(data)+1; count++ {
+		t.Run(fmt.Sprintf("count=%d", count), func(t *testing.T) {
+			var src Buffer
+			src.appendOwned(NewViewWithData(data[:4]))
+			src.appendOwned(NewViewWithData(data[4:9]))
+			src.appendOwned(NewViewWithData(data[9:]))
+			first := src.data.Front().chunk
+			r := src.AsBufferReader()
+			defer r.Close()
+
+			var dst Buffer
+			defer dst.Release()
+			n, err := dst.WriteFromReader(&r, int64(count))
+			if err != nil {
+				t.Fatalf("dst.WriteFromReader() failed: %v", err)
+			}
+			want := count
+			if want > len(data) {
+				want = len(data)
+			}
+			if int(n) != want {
+				t.Errorf("got dst.WriteFromReader()=%d, want %d", n, want)
+			}
+			if got := dst.Flatten(); !bytes.Equal(got, data[:want]) {
+				t.Errorf("got dst=%q, want %q", got, data[:want])
+			}
+			if got := src.Flatten(); !bytes.Equal(got, data[want:]) {
+				t.Errorf("got src=%q, want %q", got, data[want:])
+			}
+			// The data is moved rather than copied.
+			if want > 0 && dst.data.Front().chunk != first {
+				t.Errorf("dst does not reuse the first chunk of src")
+			}
+		})
+	}
+}
+
 func TestRead(t *testing.T) {
 	readStrings := []string{"abcdef", "123456", "ghijkl"}
 	totalSize := len(readStrings) * len(readStrings[0])
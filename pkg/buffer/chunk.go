@@ -76,6 +76,13 @@ func getChunkPool(size int) *sync.Pool {
 type chunk struct {
 	chunkRefs
 	data []byte
+
+	// external is true if data isn't pooled but borrowed, and must not be
+	// modified.
+	external bool
+
+	// release is called once an external chunk is destroyed.
+	release func() `state:"nosave"`
 }
 
 func newChunk(size int) *chunk {
@@ -93,7 +100,28 @@ func newChunk(size int) *chunk {
 	return c
 }
 
+// newExternalChunk returns a chunk borrowing data, which is released with
+// release once the chunk is destroyed.
+func newExternalChunk(data []byte, release func()) *chunk {
+	c := &chunk{
+		data:     data,
+		external: true,
+		release:  release,
+	}
+	c.InitRefs()
+	return c
+}
+
 func (c *chunk) destroy() {
+	if c.external {
+		// release may be nil after restore.
+		if c.release != nil {
+			c.release()
+		}
+		c.data = nil
+		c.release = nil
+		return
+	}
 	if len(c.data) > MaxChunkSize {
 		c.data = nil
 		return
@@ -83,6 +83,19 @@ func NewViewWithData(data []byte) *View {
 	return v
 }
 
+// NewViewWithExternalData creates a new view of data, which is borrowed rather
+// than copied. data must not be modified until release is called, once the
+// view and all of its clones are released. Writes to the view, or to its
+// clones, copy data first.
+func NewViewWithExternalData(data []byte, release func()) *View {
+	v := viewPool.Get().(*View)
+	*v = View{
+		write: len(data),
+		chunk: newExternalChunk(data, release),
+	}
+	return v
+}
+
 // Clone creates a shallow clone of v where the underlying chunk is shared.
 //
 // The caller must own the View to call Clone. It is not safe to call Clone
@@ -118,15 +131,17 @@ func (v *View) Reset() {
 	v.write = 0
 }
 
+// sharesChunk returns true if v's chunk can't be modified in place, as it is
+// shared with other views or external.
 func (v *View) sharesChunk() bool {
-	return v.chunk.refCount.Load() > 1
+	return v.chunk.refCount.Load() > 1 || v.chunk.external
 }
 
 // Full indicates the chunk is full.
 //
 // This indicates there is no capacity left to write.
 func (v *View) Full() bool {
-	return v == nil || v.write == len(v.chunk.data)
+	return v == nil || v.AvailableSize() == 0
 }
 
 // Capacity returns the total size of this view's chunk.
@@ -173,12 +188,22 @@ func (v *View) ToSlice() []byte {
 
 // AvailableSize returns the number of bytes available for writing.
 func (v *View) AvailableSize() int {
-	if v == nil {
+	if v == nil || v.chunk.external {
 		return 0
 	}
 	return len(v.chunk.data) - v.write
 }
 
+// WritableSlice returns the unused capacity of v's chunk, which the caller
+// may fill and then add to v with Grow. It returns nil if v's chunk is shared
+// or external, since it then can't be written in place.
+func (v *View) WritableSlice() []byte {
+	if v == nil || v.sharesChunk() {
+		return nil
+	}
+	return v.chunk.data[v.write:]
+}
+
 // Read reads v's data into p.
 //
 // Implements the io.Reader interface.
@@ -322,7 +347,7 @@ func (v *View) Grow(n int) {
 	if v == nil {
 		panic("cannot grow a nil view")
 	}
-	if v.write+n > v.Capacity() {
+	if n > v.AvailableSize() {
 		v.growCap(n)
 	}
 	v.write += n
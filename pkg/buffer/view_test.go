@@ -151,6 +151,65 @@ func TestWriteToCloned(t *testing.T) {
 	}
 }
 
+func TestExternalData(t *testing.T) {
+	data := []byte("external")
+	released := false
+	v := NewViewWithExternalData(data, func() { released = true })
+	clone := v.Clone()
+
+	// Writes must not modify the external data.
+	v.Write([]byte("!"))
+	if got, want := string(data), "external"; got != want {
+		t.Errorf("got data = %q, want %q", got, want)
+	}
+	if got, want := string(v.AsSlice()), "external!"; got != want {
+		t.Errorf("got v.AsSlice() = %q, want %q", got, want)
+	}
+	v.Release()
+
+	if released {
+		t.Errorf("external data released with a clone still held")
+	}
+	if got, want := string(clone.AsSlice()), "external"; got != want {
+		t.Errorf("got clone.AsSlice() = %q, want %q", got, want)
+	}
+	clone.Release()
+	if !released {
+		t.Errorf("external data not released")
+	}
+}
+
+func TestWritableSlice(t *testing.T) {
+	v := NewView(100)
+	defer v.Release()
+	v.Write([]byte("abc"))
+
+	s := v.WritableSlice()
+	if got, want := len(s), v.AvailableSize(); got != want {
+		t.Fatalf("got len(v.WritableSlice()) = %d, want %d", got, want)
+	}
+	copy(s, "def")
+	v.Grow(3)
+	if got, want := string(v.AsSlice()), "abcdef"; got != want {
+		t.Errorf("got v.AsSlice() = %q, want %q", got, want)
+	}
+
+	// Shared and external chunks can't be written in place.
+	clone := v.Clone()
+	if s := v.WritableSlice(); s != nil {
+		t.Errorf("got v.WritableSlice() = %v with a clone held, want nil", s)
+	}
+	clone.Release()
+	if s := v.WritableSlice(); s == nil {
+		t.Errorf("got v.WritableSlice() = nil after releasing the clone, want non-nil")
+	}
+	ext := NewViewWithExternalData([]byte("external"), func() {})
+	defer ext.Release()
+	if s := ext.WritableSlice(); s != nil {
+		t.Errorf("got ext.WritableSlice() = %v, want nil", s)
+	}
+}
+
 func TestWriteAt(t *testing.T) {
 	size := 10
 	off := 5
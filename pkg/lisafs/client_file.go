@@ -402,6 +402,27 @@ func (f *ClientFD) Allocate(ctx context.Context, mode, offset, length uint64) er
 	return err
 }
 
+// CopyFileRange makes the CopyFileRange RPC, copying up to length bytes from
+// src at srcOff to f at dstOff. src and f must be Open FDs on the same
+// connection.
+func (f *ClientFD) CopyFileRange(ctx context.Context, src *ClientFD, srcOff, dstOff, length uint64) (uint64, error) {
+	if src.client != f.client {
+		return 0, unix.EXDEV
+	}
+	req := CopyFileRangeReq{
+		SrcFD:     src.fd,
+		DstFD:     f.fd,
+		SrcOffset: srcOff,
+		DstOffset: dstOff,
+		Length:    length,
+	}
+	var resp CopyFileRangeResp
+	ctx.UninterruptibleSleepStart(false)
+	err := f.client.SndRcvMessage(CopyFileRange, uint32(req.SizeBytes()), req.MarshalUnsafe, resp.CheckedUnmarshal, nil, req.String, resp.String)
+	ctx.UninterruptibleSleepFinish(false)
+	return resp.Count, err
+}
+
 // ReadLinkAt makes the ReadLinkAt RPC.
 func (f *ClientFD) ReadLinkAt(ctx context.Context) (string, error) {
 	req := ReadLinkAtReq{FD: f.fd}
@@ -606,6 +606,14 @@ type OpenFDImpl interface {
 	// On the server, Allocate has a write concurrency guarantee.
 	Allocate(mode, off, length uint64) error
 
+	// CopyFileRange copies up to length bytes from src at offset srcOff to
+	// offset dstOff in the backing file of this open FD. It returns the number
+	// of bytes copied. See copy_file_range(2) for more details.
+	//
+	// On the server, CopyFileRange has a write concurrency guarantee on the
+	// destination node.
+	CopyFileRange(src OpenFDImpl, srcOff, dstOff, length uint64) (uint64, error)
+
 	// Flush can be used to clean up the file state. Behavior is
 	// implementation-specific.
 	//
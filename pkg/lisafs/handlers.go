@@ -81,6 +81,7 @@ var handlers = [...]RPCHandler{
 	Listen:           ListenHandler,
 	Accept:           AcceptHandler,
 	ConnectWithCreds: ConnectWithCredsHandler,
+	CopyFileRange:    CopyFileRangeHandler,
 }
 
 // ErrorHandler handles Error message.
@@ -984,6 +985,49 @@ func FAllocateHandler(c *Connection, comm Communicator, payloadLen uint32) (uint
 	})
 }
 
+// CopyFileRangeHandler handles the CopyFileRange RPC.
+func CopyFileRangeHandler(c *Connection, comm Communicator, payloadLen uint32) (uint32, error) {
+	if c.readonly {
+		return 0, unix.EROFS
+	}
+	var req CopyFileRangeReq
+	if _, ok := req.CheckedUnmarshal(comm.PayloadBuf(payloadLen)); !ok {
+		return 0, unix.EIO
+	}
+
+	srcFD, err := c.lookupOpenFD(req.SrcFD)
+	if err != nil {
+		return 0, err
+	}
+	defer srcFD.DecRef(nil)
+	if !srcFD.readable {
+		return 0, unix.EBADF
+	}
+	dstFD, err := c.lookupOpenFD(req.DstFD)
+	if err != nil {
+		return 0, err
+	}
+	defer dstFD.DecRef(nil)
+	if !dstFD.writable {
+		return 0, unix.EBADF
+	}
+
+	// Only the destination node is locked. Reading from srcFD does not
+	// depend on its path, and locking both nodes could deadlock with a
+	// concurrent copy in the opposite direction.
+	var count uint64
+	if err := dstFD.controlFD.safelyWrite(func() error {
+		count, err = dstFD.impl.CopyFileRange(srcFD.impl, req.SrcOffset, req.DstOffset, req.Length)
+		return err
+	}); err != nil {
+		return 0, err
+	}
+	resp := CopyFileRangeResp{Count: count}
+	respLen := uint32(resp.SizeBytes())
+	resp.MarshalUnsafe(comm.PayloadBuf(respLen))
+	return respLen, nil
+}
+
 // ReadLinkAtHandler handles the ReadLinkAt RPC.
 func ReadLinkAtHandler(c *Connection, comm Communicator, payloadLen uint32) (uint32, error) {
 	var req ReadLinkAtReq
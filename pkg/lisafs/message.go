@@ -176,6 +176,9 @@ const (
 	// ConnectWithCreds is analogous to connect(2) but it asks the server
 	// to connect with the provided effective uid/gid.
 	ConnectWithCreds MID = 32
+
+	// CopyFileRange is analogous to copy_file_range(2).
+	CopyFileRange MID = 33
 )
 
 const (
@@ -1337,6 +1340,35 @@ func (c *ConnectWithCredsReq) String() string {
 	return fmt.Sprintf("ConnectWithCredsReq{FD: %d, SockType: %d, UID: %d, GID: %d}", c.FD, c.SockType, c.UID, c.GID)
 }
 
+// CopyFileRangeReq is used to request to copy_file_range(2) from one FD to
+// another.
+//
+// +marshal boundCheck
+type CopyFileRangeReq struct {
+	SrcFD     FDID
+	DstFD     FDID
+	SrcOffset uint64
+	DstOffset uint64
+	Length    uint64
+}
+
+// String implements fmt.Stringer.String.
+func (c *CopyFileRangeReq) String() string {
+	return fmt.Sprintf("CopyFileRangeReq{SrcFD: %d, DstFD: %d, SrcOffset: %d, DstOffset: %d, Length: %d}", c.SrcFD, c.DstFD, c.SrcOffset, c.DstOffset, c.Length)
+}
+
+// CopyFileRangeResp is used to return the result of copy_file_range(2).
+//
+// +marshal boundCheck
+type CopyFileRangeResp struct {
+	Count uint64
+}
+
+// String implements fmt.Stringer.String.
+func (c *CopyFileRangeResp) String() string {
+	return fmt.Sprintf("CopyFileRangeResp{Count: %d}", c.Count)
+}
+
 // BindAtReq is used to make BindAt requests.
 type BindAtReq struct {
 	createCommon
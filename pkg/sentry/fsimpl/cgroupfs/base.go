@@ -155,6 +155,10 @@ type cgroupInode struct {
 	// id is the id of this cgroup.
 	id uint32
 
+	// parent is the parent cgroup, or nil for the root cgroup. Immutable since
+	// cgroupfs doesn't allow cross directory renames.
+	parent *cgroupInode
+
 	// controllers is the set of controllers for this cgroup. This is used to
 	// store controller-specific state per cgroup. The set of controllers should
 	// match the controllers for this hierarchy as tracked by the filesystem
@@ -167,15 +171,28 @@ type cgroupInode struct {
 	//
 	// ts, and cgroup membership in general is protected by fs.tasksMu.
 	ts map[*kernel.Task]struct{}
+
+	// subtreeControl is the set of controllers enabled for the children of
+	// this cgroup, as configured through cgroup.subtree_control. Only used on
+	// the unified hierarchy. Protected by fs.tasksMu.
+	subtreeControl map[kernel.CgroupControllerType]struct{}
+
+	// threaded is whether this cgroup is part of a threaded subtree, as
+	// configured through cgroup.type. Threads of a process may be spread
+	// across the threaded cgroups of a subtree. Only used on the unified
+	// hierarchy. Protected by fs.tasksMu.
+	threaded bool
 }
 
 var _ kernel.CgroupImpl = (*cgroupInode)(nil)
 
 func (fs *filesystem) newCgroupInode(ctx context.Context, creds *auth.Credentials, parent *cgroupInode, mode linux.FileMode) kernfs.Inode {
 	c := &cgroupInode{
-		dir:         dir{fs: fs},
-		ts:          make(map[*kernel.Task]struct{}),
-		controllers: make(map[kernel.CgroupControllerType]controller),
+		dir:            dir{fs: fs},
+		parent:         parent,
+		ts:             make(map[*kernel.Task]struct{}),
+		controllers:    make(map[kernel.CgroupControllerType]controller),
+		subtreeControl: make(map[kernel.CgroupControllerType]struct{}),
 	}
 	c.dir.cgi = c
 
@@ -191,7 +208,17 @@ func (fs *filesystem) newCgroupInode(ctx context.Context, creds *auth.Credential
 
 	contents := make(map[string]kernfs.Inode)
 	contents["cgroup.procs"] = fs.newControllerWritableFile(ctx, creds, &cgroupProcsData{c}, false)
-	contents["tasks"] = fs.newControllerWritableFile(ctx, creds, &tasksData{c}, false)
+	if fs.v2 {
+		contents["cgroup.threads"] = fs.newControllerWritableFile(ctx, creds, &cgroupThreadsData{c}, false)
+		contents["cgroup.controllers"] = fs.newControllerFile(ctx, creds, &cgroupControllersData{c}, true)
+		contents["cgroup.subtree_control"] = fs.newControllerWritableFile(ctx, creds, &cgroupSubtreeControlData{c}, true)
+		contents["cgroup.events"] = fs.newControllerFile(ctx, creds, &cgroupEventsData{c}, true)
+		if parent != nil {
+			contents["cgroup.type"] = fs.newControllerWritableFile(ctx, creds, &cgroupTypeData{c}, true)
+		}
+	} else {
+		contents["tasks"] = fs.newControllerWritableFile(ctx, creds, &tasksData{c}, false)
+	}
 
 	if parent != nil {
 		for ty, ctl := range parent.controllers {
@@ -233,6 +260,11 @@ func (c *cgroupInode) Controllers() []kernel.CgroupController {
 	return c.fs.kcontrollers
 }
 
+// Unified implements kernel.CgroupImpl.Unified.
+func (c *cgroupInode) Unified() bool {
+	return c.fs.v2
+}
+
 // tasks returns a snapshot of the tasks inside the cgroup.
 func (c *cgroupInode) tasks() []*kernel.Task {
 	c.fs.tasksMu.RLock()
@@ -409,9 +441,24 @@ func (d *cgroupProcsData) Generate(ctx context.Context, buf *bytes.Buffer) error
 	t := kernel.TaskFromContext(ctx)
 	currPidns := t.ThreadGroup().PIDNamespace()
 
+	var tasks []*kernel.Task
+	if d.fs.v2 {
+		// Processes belong to the domain of a threaded subtree, which lists
+		// the processes of all threads in the subtree.
+		d.fs.tasksMu.RLock()
+		threaded := d.threaded
+		tasks = d.threadedTasksLocked()
+		d.fs.tasksMu.RUnlock()
+		if threaded {
+			return linuxerr.EOPNOTSUPP
+		}
+	} else {
+		tasks = d.tasks()
+	}
+
 	pgids := make(map[kernel.ThreadID]struct{})
 
-	for _, task := range d.tasks() {
+	for _, task := range tasks {
 		// Map dedups pgid, since iterating over all tasks produces multiple
 		// entries for the group leaders.
 		if pgid := currPidns.IDOfThreadGroup(task.ThreadGroup()); pgid != 0 {
@@ -451,6 +498,11 @@ func (d *cgroupProcsData) Write(ctx context.Context, fd *vfs.FileDescription, sr
 	if targetTG == nil {
 		return 0, linuxerr.EINVAL
 	}
+	if d.fs.v2 {
+		if err := d.vetMigrateDst(); err != nil {
+			return 0, err
+		}
+	}
 	return n, targetTG.MigrateCgroup(d.CgroupFromControlFileFD(fd))
 }
 
@@ -524,6 +576,27 @@ func parseInt64FromString(ctx context.Context, src usermem.IOSequence) (val, len
 	return val, int64(n), nil
 }
 
+// parseInt64OrMaxFromString is like parseInt64FromString, but also accepts the
+// string "max", for which it returns maxVal.
+func parseInt64OrMaxFromString(ctx context.Context, src usermem.IOSequence, maxVal int64) (val, len int64, err error) {
+	buf := copyScratchBufferFromContext(ctx, hostarch.PageSize)
+	n, err := src.CopyIn(ctx, buf)
+	if err != nil {
+		return 0, int64(n), err
+	}
+	str := strings.TrimSpace(string(buf[:n]))
+	if str == "max" {
+		return maxVal, int64(n), nil
+	}
+
+	val, err = strconv.ParseInt(str, 10, 64)
+	if err != nil {
+		ctx.Debugf("cgroupfs.parseInt64OrMaxFromString: failed to parse %q: %v", str, err)
+		return 0, int64(n), linuxerr.EINVAL
+	}
+	return val, int64(n), nil
+}
+
 // copyScratchBufferFromContext returns a scratch buffer of the given size. It
 // tries to use the task's copy scratch buffer if we're on a task context,
 // otherwise it allocates a new buffer.
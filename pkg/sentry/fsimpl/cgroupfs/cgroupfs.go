@@ -31,6 +31,29 @@
 // system-wide state related to cgroups such as active hierarchies and the
 // controllers associated with them.
 //
+// # Unified hierarchy
+//
+// Cgroupfs can also be mounted as "cgroup2", which exposes the cgroup v2
+// unified hierarchy. There is at most one unified hierarchy on the system, and
+// it uses the same controllers as the v1 hierarchies, presenting the v2
+// interface files instead of the v1 ones. Controllers already attached to a v1
+// hierarchy are unavailable on the unified hierarchy.
+//
+// On the unified hierarchy, the control files for all available controllers
+// are present in every cgroup. The cgroup.subtree_control file tracks which
+// controllers are enabled for the children of a cgroup, and determines the
+// contents of the children's cgroup.controllers, but doesn't add or remove
+// control files.
+//
+// # Resource limits
+//
+// The memory and cpu controllers enforce their limits. Memory allocated from
+// the application memory file is charged to the allocating task's memory
+// cgroup, and allocations that would exceed memory.limit_in_bytes (memory.max)
+// fail, invoking the cgroup's OOM killer. CPU time accounted by the kernel's
+// CPU clock ticker is charged to the task's cpu cgroup, and tasks are throttled
+// for the rest of the period once cpu.cfs_quota_us (cpu.max) is exhausted.
+//
 // Since cgroupfs doesn't allow hardlinks, there is a unique mapping between
 // cgroupfs dentries and inodes. Thus, cgroupfs inodes don't need to be ref
 // counted and exist until they're unlinked once or the FS is destroyed.
@@ -46,6 +69,9 @@
 // cgroupfs.filesystem.tasksMu. Tasks also maintain a set of all cgroups they're
 // in, and this list is protected by Task.mu.
 //
+// Memory charges are made with mm.MemoryManager.activeMu held, so tasksMu must
+// not be held while locking a MemoryManager.
+//
 // Lock order:
 //
 //	kernel.CgroupRegistry.mu
@@ -77,7 +103,9 @@ import (
 
 const (
 	// Name is the default filesystem name.
-	Name             = "cgroup"
+	Name = "cgroup"
+	// V2Name is the filesystem name for the cgroup v2 unified hierarchy.
+	V2Name           = "cgroup2"
 	readonlyFileMode = linux.FileMode(0444)
 	writableFileMode = linux.FileMode(0644)
 	defaultDirMode   = linux.FileMode(0555) | linux.ModeDirectory
@@ -95,14 +123,34 @@ var allControllers = []kernel.CgroupControllerType{
 	kernel.CgroupControllerPIDs,
 }
 
+// v2Controllers are the controllers that may be attached to the unified
+// hierarchy. Cpuacct has no v2 interface of its own, and only backs the usage
+// statistics in cpu.stat.
+var v2Controllers = []kernel.CgroupControllerType{
+	kernel.CgroupControllerCPU,
+	kernel.CgroupControllerCPUAcct,
+	kernel.CgroupControllerMemory,
+	kernel.CgroupControllerPIDs,
+}
+
 // SupportedMountOptions is the set of supported mount options for cgroupfs.
 var SupportedMountOptions = []string{"all", "cpu", "cpuacct", "cpuset", "devices", "job", "memory", "pids"}
 
+// V2SupportedMountOptions is the set of supported mount options for the
+// unified hierarchy. These are accepted, but have no effect.
+var V2SupportedMountOptions = []string{"nsdelegate", "memory_recursiveprot"}
+
 // FilesystemType implements vfs.FilesystemType.
 //
 // +stateify savable
 type FilesystemType struct{}
 
+// V2FilesystemType implements vfs.FilesystemType for the cgroup v2 unified
+// hierarchy.
+//
+// +stateify savable
+type V2FilesystemType struct{}
+
 // InitialCgroup specifies properties of the cgroup for the init task.
 //
 // +stateify savable
@@ -155,6 +203,9 @@ type filesystem struct {
 	// Immutable after initialization.
 	hierarchyName string
 
+	// v2 indicates this filesystem is the unified hierarchy. Immutable.
+	v2 bool
+
 	// controllers and kcontrollers are both the list of controllers attached to
 	// this cgroupfs. Both lists are the same set of controllers, but typecast
 	// to different interfaces for convenience. Both must stay in sync, and are
@@ -189,6 +240,11 @@ func (fs *filesystem) RootCgroup() kernel.Cgroup {
 	}
 }
 
+// Unified implements kernel.cgroupFS.Unified.
+func (fs *filesystem) Unified() bool {
+	return fs.v2
+}
+
 // Name implements vfs.FilesystemType.Name.
 func (FilesystemType) Name() string {
 	return Name
@@ -197,6 +253,21 @@ func (FilesystemType) Name() string {
 // Release implements vfs.FilesystemType.Release.
 func (FilesystemType) Release(ctx context.Context) {}
 
+// parseDentryCacheLimit consumes the "dentry_cache_limit" option from mopts.
+func parseDentryCacheLimit(ctx context.Context, mopts map[string]string) (uint64, error) {
+	str, ok := mopts["dentry_cache_limit"]
+	if !ok {
+		return defaultMaxCachedDentries, nil
+	}
+	delete(mopts, "dentry_cache_limit")
+	maxCachedDentries, err := strconv.ParseUint(str, 10, 64)
+	if err != nil {
+		ctx.Warningf("sys.FilesystemType.GetFilesystem: invalid dentry cache limit: dentry_cache_limit=%s", str)
+		return 0, linuxerr.EINVAL
+	}
+	return maxCachedDentries, nil
+}
+
 // GetFilesystem implements vfs.FilesystemType.GetFilesystem.
 func (fsType FilesystemType) GetFilesystem(ctx context.Context, vfsObj *vfs.VirtualFilesystem, creds *auth.Credentials, source string, opts vfs.GetFilesystemOptions) (*vfs.Filesystem, *vfs.Dentry, error) {
 	devMinor, err := vfsObj.GetAnonBlockDevMinor()
@@ -205,14 +276,9 @@ func (fsType FilesystemType) GetFilesystem(ctx context.Context, vfsObj *vfs.Virt
 	}
 
 	mopts := vfs.GenericParseMountOptions(opts.Data)
-	maxCachedDentries := defaultMaxCachedDentries
-	if str, ok := mopts["dentry_cache_limit"]; ok {
-		delete(mopts, "dentry_cache_limit")
-		maxCachedDentries, err = strconv.ParseUint(str, 10, 64)
-		if err != nil {
-			ctx.Warningf("sys.FilesystemType.GetFilesystem: invalid dentry cache limit: dentry_cache_limit=%s", str)
-			return nil, nil, linuxerr.EINVAL
-		}
+	maxCachedDentries, err := parseDentryCacheLimit(ctx, mopts)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	var wantControllers []kernel.CgroupControllerType
@@ -312,11 +378,7 @@ func (fsType FilesystemType) GetFilesystem(ctx context.Context, vfsObj *vfs.Virt
 	if vfsfs != nil {
 		fs := vfsfs.Impl().(*filesystem)
 		ctx.Debugf("cgroupfs.FilesystemType.GetFilesystem: mounting new view to hierarchy %v", fs.hierarchyID)
-		fs.root.IncRef()
-		if fs.effectiveRoot != fs.root {
-			fs.effectiveRoot.IncRef()
-		}
-		return vfsfs, fs.root.VFSDentry(), nil
+		return fs.newView()
 	}
 
 	// No existing hierarchy with the exactly controllers found. Make a new
@@ -330,6 +392,80 @@ func (fsType FilesystemType) GetFilesystem(ctx context.Context, vfsObj *vfs.Virt
 	}
 	fs.MaxCachedDentries = maxCachedDentries
 	fs.VFSFilesystem().Init(vfsObj, &fsType, fs)
+	return fs.initHierarchy(ctx, vfsObj, creds, opts, wantControllers)
+}
+
+// Name implements vfs.FilesystemType.Name.
+func (V2FilesystemType) Name() string {
+	return V2Name
+}
+
+// Release implements vfs.FilesystemType.Release.
+func (V2FilesystemType) Release(ctx context.Context) {}
+
+// GetFilesystem implements vfs.FilesystemType.GetFilesystem.
+func (fsType V2FilesystemType) GetFilesystem(ctx context.Context, vfsObj *vfs.VirtualFilesystem, creds *auth.Credentials, source string, opts vfs.GetFilesystemOptions) (*vfs.Filesystem, *vfs.Dentry, error) {
+	devMinor, err := vfsObj.GetAnonBlockDevMinor()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mopts := vfs.GenericParseMountOptions(opts.Data)
+	maxCachedDentries, err := parseDentryCacheLimit(ctx, mopts)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, o := range V2SupportedMountOptions {
+		delete(mopts, o)
+	}
+	if len(mopts) != 0 {
+		ctx.Debugf("cgroupfs.V2FilesystemType.GetFilesystem: unknown options: %v", mopts)
+		return nil, nil, linuxerr.EINVAL
+	}
+
+	k := kernel.KernelFromContext(ctx)
+	r := k.CgroupRegistry()
+
+	// There is only a single unified hierarchy, all cgroup2 mounts are views
+	// into it.
+	if vfsfs := r.FindUnifiedHierarchy(); vfsfs != nil {
+		fs := vfsfs.Impl().(*filesystem)
+		ctx.Debugf("cgroupfs.V2FilesystemType.GetFilesystem: mounting new view to hierarchy %v", fs.hierarchyID)
+		return fs.newView()
+	}
+
+	fs := &filesystem{
+		devMinor: devMinor,
+		v2:       true,
+	}
+	fs.MaxCachedDentries = maxCachedDentries
+	fs.VFSFilesystem().Init(vfsObj, &fsType, fs)
+
+	// "A controller can be moved across hierarchies only after the controller
+	// is no longer referenced in its current hierarchy." - Linux,
+	// Documentation/admin-guide/cgroup-v2.rst. We don't move controllers
+	// between hierarchies, so the unified hierarchy gets whichever controllers
+	// aren't attached to a v1 hierarchy at creation time.
+	return fs.initHierarchy(ctx, vfsObj, creds, opts, r.UnboundControllers(v2Controllers))
+}
+
+// newView returns a new reference to the root of an existing hierarchy, for a
+// new mount of fs.
+func (fs *filesystem) newView() (*vfs.Filesystem, *vfs.Dentry, error) {
+	fs.root.IncRef()
+	if fs.effectiveRoot != fs.root {
+		fs.effectiveRoot.IncRef()
+	}
+	return fs.VFSFilesystem(), fs.root.VFSDentry(), nil
+}
+
+// initHierarchy creates the controllers and root cgroup for a new hierarchy
+// and registers it with the cgroup registry.
+//
+// Precondition: fs.VFSFilesystem() must be initialized.
+func (fs *filesystem) initHierarchy(ctx context.Context, vfsObj *vfs.VirtualFilesystem, creds *auth.Credentials, opts vfs.GetFilesystemOptions, wantControllers []kernel.CgroupControllerType) (*vfs.Filesystem, *vfs.Dentry, error) {
+	k := kernel.KernelFromContext(ctx)
+	r := k.CgroupRegistry()
 
 	var defaults map[string]int64
 	if opts.InternalData != nil {
@@ -341,7 +477,7 @@ func (fsType FilesystemType) GetFilesystem(ctx context.Context, vfsObj *vfs.Virt
 		var c controller
 		switch ty {
 		case kernel.CgroupControllerCPU:
-			c = newCPUController(fs, defaults)
+			c = newCPUController(k, fs, defaults)
 		case kernel.CgroupControllerCPUAcct:
 			c = newCPUAcctController(fs)
 		case kernel.CgroupControllerCPUSet:
@@ -390,7 +526,7 @@ func (fsType FilesystemType) GetFilesystem(ctx context.Context, vfsObj *vfs.Virt
 	// Register controllers. The registry may be modified concurrently, so if we
 	// get an error, we raced with someone else who registered the same
 	// controllers first.
-	if err := r.Register(name, fs.kcontrollers, fs); err != nil {
+	if err := r.Register(fs.hierarchyName, fs.kcontrollers, fs); err != nil {
 		ctx.Infof("cgroupfs.FilesystemType.GetFilesystem: failed to register new hierarchy with controllers %v: %v", wantControllers, err)
 		rootD.DecRef(ctx)
 		fs.VFSFilesystem().DecRef(ctx)
@@ -483,6 +619,10 @@ func (fs *filesystem) Release(ctx context.Context) {
 
 // MountOptions implements vfs.FilesystemImpl.MountOptions.
 func (fs *filesystem) MountOptions() string {
+	if fs.v2 {
+		// The unified hierarchy doesn't list its controllers.
+		return ""
+	}
 	var cnames []string
 	for _, c := range fs.controllers {
 		cnames = append(cnames, string(c.Type()))
@@ -640,6 +780,10 @@ func (d *dir) RmDir(ctx context.Context, name string, child kernfs.Inode) error
 	err := d.OrderedChildren.RmDir(ctx, name, child)
 	if err == nil {
 		d.InodeAttrs.DecLinks()
+		// The removed cgroup's bandwidth quota no longer limits anything.
+		if ctl, ok := cgi.controllers[kernel.CgroupControllerCPU]; ok {
+			ctl.(*cpuController).setQuota(kernel.KernelFromContext(ctx).CgroupRegistry(), -1)
+		}
 	}
 	return err
 }
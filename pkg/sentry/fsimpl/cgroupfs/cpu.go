@@ -15,30 +15,91 @@
 package cgroupfs
 
 import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
 	"gvisor.dev/gvisor/pkg/atomicbitops"
 	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/hostarch"
 	"gvisor.dev/gvisor/pkg/sentry/fsimpl/kernfs"
 	"gvisor.dev/gvisor/pkg/sentry/kernel"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/usage"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/usermem"
+)
+
+// Limits for the CFS bandwidth period, in microseconds. See Linux,
+// kernel/sched/core.c.
+const (
+	minCFSPeriod = 1000    // 1ms
+	maxCFSPeriod = 1000000 // 1s
+)
+
+// Limits for cpu.weight. See Linux, include/linux/cgroup.h.
+const (
+	cgroupWeightMin = 1
+	cgroupWeightDfl = 100
+	cgroupWeightMax = 10000
 )
 
+// cpuController enforces CFS bandwidth control: tasks in a cgroup may consume
+// at most cfsQuota of CPU time in each cfsPeriod, after which they are
+// throttled until the period ends. CPU time is charged by the kernel's CPU
+// clock ticker through ChargeCPUTime.
+//
 // +stateify savable
 type cpuController struct {
 	controllerCommon
-	controllerStateless
 	controllerNoResource
 
-	// CFS bandwidth control parameters, values in microseconds.
+	// cgID is the ID of the cgroup that this controller belongs to.
+	cgID uint32
+
+	// CFS bandwidth control parameters, values in microseconds. cfsQuota is
+	// -1 if the bandwidth is unlimited, and must be changed with setQuota.
 	cfsPeriod atomicbitops.Int64
 	cfsQuota  atomicbitops.Int64
 
-	// CPU shares, values should be (num core * 1024).
+	// CPU shares, values should be (num core * 1024). Shares are only
+	// reported, not enforced: the sentry doesn't schedule tasks itself, but
+	// leaves that to the Go runtime and the host, neither of which can
+	// weight tasks relative to each other.
 	shares atomicbitops.Int64
+
+	// mu protects the bandwidth accounting state below. Times are in
+	// nanoseconds on the kernel's monotonic clock.
+	mu cpuControllerMutex `state:"nosave"`
+
+	// periodStart is the start of the current bandwidth period.
+	periodStart int64
+
+	// runtime is the CPU time consumed in the current period.
+	runtime int64
+
+	// throttledAt is the time at which the quota was exhausted in the current
+	// period, or 0 if it wasn't.
+	throttledAt int64
+
+	// nrPeriods is the number of elapsed periods in which bandwidth control
+	// was enabled.
+	nrPeriods int64
+
+	// nrThrottled is the number of periods in which the cgroup was throttled.
+	nrThrottled int64
+
+	// throttledTime is the total time for which the cgroup was throttled,
+	// excluding the current period.
+	throttledTime int64
 }
 
 var _ controller = (*cpuController)(nil)
 
-func newCPUController(fs *filesystem, defaults map[string]int64) *cpuController {
+func newCPUController(k *kernel.Kernel, fs *filesystem, defaults map[string]int64) *cpuController {
 	// Default values for controller parameters from Linux.
 	c := &cpuController{
 		cfsPeriod: atomicbitops.FromInt64(100000),
@@ -51,7 +112,7 @@ func newCPUController(fs *filesystem, defaults map[string]int64) *cpuController
 		delete(defaults, "cpu.cfs_period_us")
 	}
 	if val, ok := defaults["cpu.cfs_quota_us"]; ok {
-		c.cfsQuota = atomicbitops.FromInt64(val)
+		c.setQuota(k.CgroupRegistry(), val)
 		delete(defaults, "cpu.cfs_quota_us")
 	}
 	if val, ok := defaults["cpu.shares"]; ok {
@@ -65,9 +126,10 @@ func newCPUController(fs *filesystem, defaults map[string]int64) *cpuController
 
 // Clone implements controller.Clone.
 func (c *cpuController) Clone() controller {
+	// As in Linux, new cgroups start without a bandwidth quota.
 	new := &cpuController{
 		cfsPeriod: atomicbitops.FromInt64(c.cfsPeriod.Load()),
-		cfsQuota:  atomicbitops.FromInt64(c.cfsQuota.Load()),
+		cfsQuota:  atomicbitops.FromInt64(-1),
 		shares:    atomicbitops.FromInt64(c.shares.Load()),
 	}
 	new.controllerCommon.cloneFromParent(c)
@@ -75,8 +137,344 @@ func (c *cpuController) Clone() controller {
 }
 
 // AddControlFiles implements controller.AddControlFiles.
-func (c *cpuController) AddControlFiles(ctx context.Context, creds *auth.Credentials, _ *cgroupInode, contents map[string]kernfs.Inode) {
+func (c *cpuController) AddControlFiles(ctx context.Context, creds *auth.Credentials, cg *cgroupInode, contents map[string]kernfs.Inode) {
+	c.cgID = cg.ID()
+	if c.fs.v2 {
+		contents["cpu.max"] = c.fs.newControllerWritableFile(ctx, creds, &cpuMaxData{c: c}, true)
+		contents["cpu.weight"] = c.fs.newControllerWritableFile(ctx, creds, &cpuWeightData{c: c}, true)
+		contents["cpu.stat"] = c.fs.newControllerFile(ctx, creds, &cpuStatData{c: c, cg: cg}, true)
+		return
+	}
 	contents["cpu.cfs_period_us"] = c.fs.newStubControllerFile(ctx, creds, &c.cfsPeriod, true)
-	contents["cpu.cfs_quota_us"] = c.fs.newStubControllerFile(ctx, creds, &c.cfsQuota, true)
+	contents["cpu.cfs_quota_us"] = c.fs.newControllerWritableFile(ctx, creds, &cpuQuotaData{c: c}, true)
 	contents["cpu.shares"] = c.fs.newStubControllerFile(ctx, creds, &c.shares, true)
+	contents["cpu.stat"] = c.fs.newControllerFile(ctx, creds, &cpuV1StatData{c: c}, true)
+}
+
+// Enter implements controller.Enter.
+func (c *cpuController) Enter(t *kernel.Task) {
+	t.SetCPUCgID(c.cgID)
+}
+
+// Leave implements controller.Leave.
+func (c *cpuController) Leave(t *kernel.Task) {
+	t.SetCPUCgID(0)
+}
+
+// PrepareMigrate implements controller.PrepareMigrate.
+func (c *cpuController) PrepareMigrate(t *kernel.Task, src controller) error {
+	return nil
+}
+
+// CommitMigrate implements controller.CommitMigrate.
+func (c *cpuController) CommitMigrate(t *kernel.Task, src controller) {
+	t.SetCPUCgID(c.cgID)
+}
+
+// AbortMigrate implements controller.AbortMigrate.
+func (c *cpuController) AbortMigrate(t *kernel.Task, src controller) {}
+
+// setQuota sets c's CFS bandwidth quota in microseconds, where a negative
+// quota is unlimited.
+func (c *cpuController) setQuota(r *kernel.CgroupRegistry, quota int64) {
+	if quota < 0 {
+		quota = -1
+	}
+	old := c.cfsQuota.Swap(quota)
+	r.CPUQuotaChanged(old, quota)
+}
+
+// parentCPU returns the cpu controller of the parent cgroup, or nil if this is
+// the root cgroup.
+func (c *cpuController) parentCPU() *cpuController {
+	if c.parent == nil {
+		return nil
+	}
+	return c.parent.(*cpuController)
+}
+
+// advancePeriodLocked starts a new bandwidth period if the current one has
+// ended by now.
+//
+// Preconditions: c.mu must be locked.
+func (c *cpuController) advancePeriodLocked(now int64) {
+	period := c.cfsPeriod.Load() * int64(time.Microsecond)
+	if now < c.periodStart+period {
+		return
+	}
+	if c.throttledAt != 0 {
+		c.throttledTime += c.periodStart + period - c.throttledAt
+		c.throttledAt = 0
+	}
+	if c.periodStart != 0 && c.cfsQuota.Load() >= 0 {
+		c.nrPeriods++
+	}
+	c.periodStart = now
+	c.runtime = 0
+}
+
+// throttledUntilLocked returns the end of the current period if c is
+// throttled, and 0 otherwise.
+//
+// Preconditions: c.mu must be locked.
+func (c *cpuController) throttledUntilLocked() int64 {
+	if c.throttledAt == 0 {
+		return 0
+	}
+	return c.periodStart + c.cfsPeriod.Load()*int64(time.Microsecond)
+}
+
+// chargeCPUTime charges d of CPU time, consumed at time now, to c alone. It
+// returns the time until which c is throttled, or 0 if it isn't.
+func (c *cpuController) chargeCPUTime(now int64, d time.Duration) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.advancePeriodLocked(now)
+	quota := c.cfsQuota.Load()
+	if quota < 0 {
+		return 0
+	}
+	c.runtime += d.Nanoseconds()
+	if c.throttledAt == 0 && c.runtime >= quota*int64(time.Microsecond) {
+		c.throttledAt = now
+		c.nrThrottled++
+	}
+	return c.throttledUntilLocked()
+}
+
+// throttledUntil returns the time until which c is throttled, or 0 if it
+// isn't.
+func (c *cpuController) throttledUntil(now int64) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.advancePeriodLocked(now)
+	return c.throttledUntilLocked()
+}
+
+// cpuBandwidthStats are the bandwidth control statistics reported by cpu.stat.
+type cpuBandwidthStats struct {
+	nrPeriods     int64
+	nrThrottled   int64
+	throttledTime time.Duration
+}
+
+// bandwidthStats returns c's bandwidth control statistics as of now.
+func (c *cpuController) bandwidthStats(now int64) cpuBandwidthStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.advancePeriodLocked(now)
+	throttledTime := c.throttledTime
+	if c.throttledAt != 0 {
+		throttledTime += now - c.throttledAt
+	}
+	return cpuBandwidthStats{
+		nrPeriods:     c.nrPeriods,
+		nrThrottled:   c.nrThrottled,
+		throttledTime: time.Duration(throttledTime),
+	}
+}
+
+// ChargeCPUTime implements kernel.CgroupImpl.ChargeCPUTime.
+//
+// See Linux, kernel/sched/fair.c:account_cfs_rq_runtime().
+func (c *cgroupInode) ChargeCPUTime(now int64, d time.Duration) int64 {
+	c.fs.tasksMu.RLock()
+	defer c.fs.tasksMu.RUnlock()
+	ctl, ok := c.controllers[kernel.CgroupControllerCPU]
+	if !ok {
+		return 0
+	}
+	var until int64
+	for cc := ctl.(*cpuController); cc != nil; cc = cc.parentCPU() {
+		until = max(until, cc.chargeCPUTime(now, d))
+	}
+	return until
+}
+
+// CPUThrottledUntil implements kernel.CgroupImpl.CPUThrottledUntil.
+func (c *cgroupInode) CPUThrottledUntil(now int64) int64 {
+	c.fs.tasksMu.RLock()
+	defer c.fs.tasksMu.RUnlock()
+	ctl, ok := c.controllers[kernel.CgroupControllerCPU]
+	if !ok {
+		return 0
+	}
+	var until int64
+	for cc := ctl.(*cpuController); cc != nil; cc = cc.parentCPU() {
+		until = max(until, cc.throttledUntil(now))
+	}
+	return until
+}
+
+// cpuV1StatData implements cpu.stat on cgroup v1 hierarchies.
+//
+// +stateify savable
+type cpuV1StatData struct {
+	c *cpuController
+}
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *cpuV1StatData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	bs := d.c.bandwidthStats(kernel.KernelFromContext(ctx).MonotonicClock().Now().Nanoseconds())
+	fmt.Fprintf(buf, "nr_periods %d\n", bs.nrPeriods)
+	fmt.Fprintf(buf, "nr_throttled %d\n", bs.nrThrottled)
+	fmt.Fprintf(buf, "throttled_time %d\n", bs.throttledTime.Nanoseconds())
+	return nil
+}
+
+// cpuMaxData implements cpu.max, the unified hierarchy's combination of
+// cpu.cfs_quota_us and cpu.cfs_period_us.
+//
+// +stateify savable
+type cpuMaxData struct {
+	c *cpuController
+}
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *cpuMaxData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	period := d.c.cfsPeriod.Load()
+	if quota := d.c.cfsQuota.Load(); quota < 0 {
+		fmt.Fprintf(buf, "max %d\n", period)
+	} else {
+		fmt.Fprintf(buf, "%d %d\n", quota, period)
+	}
+	return nil
+}
+
+// Write implements vfs.WritableDynamicBytesSource.Write.
+func (d *cpuMaxData) Write(ctx context.Context, _ *vfs.FileDescription, src usermem.IOSequence, offset int64) (int64, error) {
+	return d.WriteBackground(ctx, src)
+}
+
+// WriteBackground implements writableControllerFileImpl.WriteBackground.
+//
+// The input is "$MAX [$PERIOD]", where $MAX is either a quota in microseconds
+// or "max". See Linux, kernel/sched/core.c:cpu_max_write().
+func (d *cpuMaxData) WriteBackground(ctx context.Context, src usermem.IOSequence) (int64, error) {
+	buf := copyScratchBufferFromContext(ctx, hostarch.PageSize)
+	n, err := src.CopyIn(ctx, buf)
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(string(buf[:n]))
+	if len(fields) < 1 || len(fields) > 2 {
+		return 0, linuxerr.EINVAL
+	}
+
+	period := d.c.cfsPeriod.Load()
+	if len(fields) == 2 {
+		period, err = strconv.ParseInt(fields[1], 10, 64)
+		if err != nil || period < minCFSPeriod || period > maxCFSPeriod {
+			return 0, linuxerr.EINVAL
+		}
+	}
+
+	quota := int64(-1)
+	if fields[0] != "max" {
+		quota, err = strconv.ParseInt(fields[0], 10, 64)
+		if err != nil || quota < minCFSPeriod {
+			return 0, linuxerr.EINVAL
+		}
+	}
+
+	d.c.cfsPeriod.Store(period)
+	d.c.setQuota(kernel.KernelFromContext(ctx).CgroupRegistry(), quota)
+	return int64(n), nil
+}
+
+// cpuQuotaData implements cpu.cfs_quota_us.
+//
+// +stateify savable
+type cpuQuotaData struct {
+	c *cpuController
+}
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *cpuQuotaData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	fmt.Fprintf(buf, "%d\n", d.c.cfsQuota.Load())
+	return nil
+}
+
+// Write implements vfs.WritableDynamicBytesSource.Write.
+func (d *cpuQuotaData) Write(ctx context.Context, _ *vfs.FileDescription, src usermem.IOSequence, offset int64) (int64, error) {
+	return d.WriteBackground(ctx, src)
+}
+
+// WriteBackground implements writableControllerFileImpl.WriteBackground.
+//
+// Any negative quota is unlimited. See Linux, kernel/sched/core.c:tg_set_cfs_quota().
+func (d *cpuQuotaData) WriteBackground(ctx context.Context, src usermem.IOSequence) (int64, error) {
+	quota, n, err := parseInt64FromString(ctx, src)
+	if err != nil {
+		return 0, err
+	}
+	if quota >= 0 && quota < minCFSPeriod {
+		return 0, linuxerr.EINVAL
+	}
+	d.c.setQuota(kernel.KernelFromContext(ctx).CgroupRegistry(), quota)
+	return n, nil
+}
+
+// cpuWeightData implements cpu.weight, the unified hierarchy's equivalent of
+// cpu.shares.
+//
+// +stateify savable
+type cpuWeightData struct {
+	c *cpuController
+}
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+//
+// See Linux, kernel/sched/core.c:cpu_weight_read_u64().
+func (d *cpuWeightData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	shares := d.c.shares.Load()
+	fmt.Fprintf(buf, "%d\n", (shares*cgroupWeightDfl+512)/1024)
+	return nil
+}
+
+// Write implements vfs.WritableDynamicBytesSource.Write.
+func (d *cpuWeightData) Write(ctx context.Context, _ *vfs.FileDescription, src usermem.IOSequence, offset int64) (int64, error) {
+	return d.WriteBackground(ctx, src)
+}
+
+// WriteBackground implements writableControllerFileImpl.WriteBackground.
+//
+// See Linux, kernel/sched/core.c:cpu_weight_write_u64().
+func (d *cpuWeightData) WriteBackground(ctx context.Context, src usermem.IOSequence) (int64, error) {
+	weight, n, err := parseInt64FromString(ctx, src)
+	if err != nil {
+		return 0, err
+	}
+	if weight < cgroupWeightMin || weight > cgroupWeightMax {
+		return 0, linuxerr.ERANGE
+	}
+	d.c.shares.Store((weight*1024 + cgroupWeightDfl/2) / cgroupWeightDfl)
+	return n, nil
+}
+
+// cpuStatData implements cpu.stat on the unified hierarchy. The usage
+// statistics come from the cpuacct controller, if it's attached to the same
+// hierarchy.
+//
+// +stateify savable
+type cpuStatData struct {
+	c  *cpuController
+	cg *cgroupInode
+}
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *cpuStatData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	var cs usage.CPUStats
+	if _, ok := d.cg.controllers[kernel.CgroupControllerCPUAcct]; ok {
+		cg := cpuacctCgroup{d.cg}
+		cs = cg.collectCPUStats()
+	}
+	fmt.Fprintf(buf, "usage_usec %d\n", (cs.UserTime + cs.SysTime).Microseconds())
+	fmt.Fprintf(buf, "user_usec %d\n", cs.UserTime.Microseconds())
+	fmt.Fprintf(buf, "system_usec %d\n", cs.SysTime.Microseconds())
+	bs := d.c.bandwidthStats(kernel.KernelFromContext(ctx).MonotonicClock().Now().Nanoseconds())
+	fmt.Fprintf(buf, "nr_periods %d\n", bs.nrPeriods)
+	fmt.Fprintf(buf, "nr_throttled %d\n", bs.nrThrottled)
+	fmt.Fprintf(buf, "throttled_usec %d\n", bs.throttledTime.Microseconds())
+	return nil
 }
@@ -80,6 +80,11 @@ func (c *cpuacctController) Clone() controller {
 
 // AddControlFiles implements controller.AddControlFiles.
 func (c *cpuacctController) AddControlFiles(ctx context.Context, creds *auth.Credentials, cg *cgroupInode, contents map[string]kernfs.Inode) {
+	if c.fs.v2 {
+		// Cpuacct has no interface files on the unified hierarchy, its usage
+		// statistics are reported through cpu.stat.
+		return
+	}
 	cpuacctCG := &cpuacctCgroup{cg}
 	contents["cpuacct.stat"] = c.fs.newControllerFile(ctx, creds, &cpuacctStatData{cpuacctCG}, true)
 	contents["cpuacct.usage"] = c.fs.newControllerFile(ctx, creds, &cpuacctUsageData{cpuacctCG}, true)
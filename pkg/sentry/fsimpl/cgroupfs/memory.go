@@ -18,26 +18,58 @@ import (
 	"bytes"
 	"fmt"
 	"math"
+	"sync/atomic"
 
 	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/atomicbitops"
 	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/log"
 	"gvisor.dev/gvisor/pkg/sentry/fsimpl/kernfs"
 	"gvisor.dev/gvisor/pkg/sentry/kernel"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/mm"
 	"gvisor.dev/gvisor/pkg/sentry/usage"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/usermem"
 )
 
+// memoryController enforces memory limits on a cgroup. Allocations from the
+// application memory file are charged to the memory cgroup of the allocating
+// task through Charge. An allocation fails if it would push the charged amount
+// of this cgroup or any of its ancestors over memory.limit_in_bytes (or
+// memory.max), in which case the faulting task invokes the OOM killer through
+// OOMKill.
+//
 // +stateify savable
 type memoryController struct {
 	controllerCommon
-	controllerNoResource
 
 	limitBytes            atomicbitops.Int64
 	softLimitBytes        atomicbitops.Int64
 	moveChargeAtImmigrate atomicbitops.Int64
 	pressureLevel         int64
 
+	// highBytes is the throttling threshold from memory.high. Only exposed on
+	// the unified hierarchy.
+	highBytes atomicbitops.Int64
+
+	// events counts the memory events reported through memory.events.
+	events memoryEvents
+
+	// charged is the number of bytes currently charged to this cgroup and its
+	// descendants.
+	charged atomicbitops.Int64
+
+	// failedCharge is the size of the last charge to this cgroup that was
+	// rejected because it exceeded the limit of this cgroup or an ancestor.
+	failedCharge atomicbitops.Int64
+
+	// oomVictim is the last task killed by the OOM killer on behalf of this
+	// cgroup, if any.
+	oomVictim atomic.Pointer[kernel.Task] `state:"nosave"`
+
 	// memCg is the memory cgroup for this controller.
 	memCg *memoryCgroup
 }
@@ -52,6 +84,7 @@ func newMemoryController(fs *filesystem, defaults map[string]int64) *memoryContr
 
 		limitBytes:     atomicbitops.FromInt64(math.MaxInt64),
 		softLimitBytes: atomicbitops.FromInt64(math.MaxInt64),
+		highBytes:      atomicbitops.FromInt64(math.MaxInt64),
 	}
 
 	consumeDefault := func(name string, valPtr *atomicbitops.Int64) {
@@ -75,6 +108,7 @@ func (c *memoryController) Clone() controller {
 		limitBytes:            atomicbitops.FromInt64(c.limitBytes.Load()),
 		softLimitBytes:        atomicbitops.FromInt64(c.softLimitBytes.Load()),
 		moveChargeAtImmigrate: atomicbitops.FromInt64(c.moveChargeAtImmigrate.Load()),
+		highBytes:             atomicbitops.FromInt64(c.highBytes.Load()),
 	}
 	new.controllerCommon.cloneFromParent(c)
 	return new
@@ -83,6 +117,13 @@ func (c *memoryController) Clone() controller {
 // AddControlFiles implements controller.AddControlFiles.
 func (c *memoryController) AddControlFiles(ctx context.Context, creds *auth.Credentials, cg *cgroupInode, contents map[string]kernfs.Inode) {
 	c.memCg = &memoryCgroup{cg}
+	if c.fs.v2 {
+		contents["memory.current"] = c.fs.newControllerFile(ctx, creds, &memoryUsageInBytesData{memCg: &memoryCgroup{cg}}, true)
+		contents["memory.max"] = c.fs.newControllerWritableFile(ctx, creds, &memoryLimitData{limit: &c.limitBytes}, true)
+		contents["memory.high"] = c.fs.newControllerWritableFile(ctx, creds, &memoryLimitData{limit: &c.highBytes}, true)
+		contents["memory.events"] = c.fs.newControllerFile(ctx, creds, &memoryEventsData{events: &c.events}, true)
+		return
+	}
 	contents["memory.usage_in_bytes"] = c.fs.newControllerFile(ctx, creds, &memoryUsageInBytesData{memCg: &memoryCgroup{cg}}, true)
 	contents["memory.limit_in_bytes"] = c.fs.newStubControllerFile(ctx, creds, &c.limitBytes, true)
 	contents["memory.soft_limit_in_bytes"] = c.fs.newStubControllerFile(ctx, creds, &c.softLimitBytes, true)
@@ -116,6 +157,133 @@ func (c *memoryController) CommitMigrate(t *kernel.Task, src controller) {
 // AbortMigrate implements controller.AbortMigrate.
 func (c *memoryController) AbortMigrate(t *kernel.Task, src controller) {}
 
+// parentMemory returns the memory controller of the parent cgroup, or nil if
+// this is the root cgroup.
+func (c *memoryController) parentMemory() *memoryController {
+	if c.parent == nil {
+		return nil
+	}
+	return c.parent.(*memoryController)
+}
+
+// Charge implements controller.Charge.
+//
+// Charges are hierarchical: a positive charge succeeds only if it fits within
+// the limits of this cgroup and all of its ancestors. See Linux,
+// mm/page_counter.c:page_counter_try_charge().
+func (c *memoryController) Charge(t *kernel.Task, d *kernfs.Dentry, res kernel.CgroupResourceType, value int64) error {
+	if res != kernel.CgroupResourceMemory {
+		panic(fmt.Sprintf("cgroupfs: memory controller invalid resource type %v", res))
+	}
+
+	if value < 0 {
+		for mc := c; mc != nil; mc = mc.parentMemory() {
+			mc.charged.Add(value)
+		}
+		return nil
+	}
+
+	for mc := c; mc != nil; mc = mc.parentMemory() {
+		charged := mc.charged.Add(value)
+		if charged > mc.limitBytes.Load() {
+			mc.events.max.Add(1)
+			mc.events.oom.Add(1)
+			// Undo the charges made so far, including mc's.
+			for r := c; r != mc; r = r.parentMemory() {
+				r.charged.Add(-value)
+			}
+			mc.charged.Add(-value)
+			c.failedCharge.Store(value)
+			return linuxerr.ENOMEM
+		}
+		if charged > mc.highBytes.Load() {
+			mc.events.high.Add(1)
+		}
+	}
+	return nil
+}
+
+// oomDomain returns the memory controller, among c and its ancestors, whose
+// limit caused the last failed charge to c, or nil if that charge would now
+// fit. This is the nearest controller that still can't fit the charge.
+func (c *memoryController) oomDomain() *memoryController {
+	pending := c.failedCharge.Load()
+	if pending == 0 {
+		return nil
+	}
+	for mc := c; mc != nil; mc = mc.parentMemory() {
+		if mc.limitBytes.Load()-mc.charged.Load() < pending {
+			return mc
+		}
+	}
+	return nil
+}
+
+// OOMKill implements kernel.CgroupImpl.OOMKill.
+//
+// The victim is the process with the largest resident set in the subtree of
+// the cgroup whose limit was reached. See Linux,
+// mm/memcontrol.c:mem_cgroup_out_of_memory().
+func (c *cgroupInode) OOMKill(t *kernel.Task) bool {
+	c.fs.tasksMu.RLock()
+	ctl, ok := c.controllers[kernel.CgroupControllerMemory]
+	if !ok {
+		c.fs.tasksMu.RUnlock()
+		return false
+	}
+	mc := ctl.(*memoryController).oomDomain()
+	if mc == nil {
+		c.fs.tasksMu.RUnlock()
+		return false
+	}
+	var ts []*kernel.Task
+	mc.memCg.collectTasksLocked(&ts)
+	c.fs.tasksMu.RUnlock()
+
+	// Don't kill another task while the previous victim is still releasing
+	// its memory.
+	if v := mc.oomVictim.Load(); v != nil && v.ExitState() < kernel.TaskExitZombie {
+		return true
+	}
+
+	// fs.tasksMu must not be held while reading resident set sizes, since
+	// allocations are charged with MemoryManager locks held.
+	var (
+		victim    *kernel.Task
+		victimRSS uint64
+	)
+	for _, vt := range ts {
+		if vt.ExitState() != kernel.TaskExitNone {
+			continue
+		}
+		var m *mm.MemoryManager
+		vt.WithMuLocked(func(vt *kernel.Task) {
+			m = vt.MemoryManager()
+		})
+		if m == nil || !m.IncUsers() {
+			continue
+		}
+		rss := m.ResidentSetSize()
+		m.DecUsers(t)
+		if victim == nil || rss > victimRSS {
+			victim = vt
+			victimRSS = rss
+		}
+	}
+	if victim == nil {
+		return false
+	}
+
+	mc.oomVictim.Store(victim)
+	mc.events.oomKill.Add(1)
+	tg := victim.ThreadGroup()
+	log.Infof("Memory cgroup out of memory: killing thread group %d with %d bytes resident", t.Kernel().TaskSet().Root.IDOfThreadGroup(tg), victimRSS)
+	if err := tg.SendSignal(kernel.SignalInfoPriv(linux.SIGKILL)); err != nil {
+		log.Warningf("Failed to kill OOM victim: %v", err)
+	}
+	return true
+}
+
 // +stateify savable
 type memoryCgroup struct {
 	*cgroupInode
@@ -132,6 +300,19 @@ func (memCg *memoryCgroup) collectMemCgIDs(memCgIDs map[uint32]struct{}) {
 	})
 }
 
+// collectTasksLocked appends the tasks in memCg and its descendants to ts.
+//
+// +checklocksread:memCg.fs.tasksMu
+func (memCg *memoryCgroup) collectTasksLocked(ts *[]*kernel.Task) {
+	for t := range memCg.ts {
+		*ts = append(*ts, t)
+	}
+	memCg.forEachChildDir(func(d *dir) {
+		cg := memoryCgroup{d.cgi}
+		cg.collectTasksLocked(ts)
+	})
+}
+
 // Returns the memory usage for all cgroup ids in memCgIDs.
 func getUsage(k *kernel.Kernel, memCgIDs map[uint32]struct{}) uint64 {
 	k.MemoryFile().UpdateUsage(memCgIDs)
@@ -158,3 +339,76 @@ func (d *memoryUsageInBytesData) Generate(ctx context.Context, buf *bytes.Buffer
 	fmt.Fprintf(buf, "%d\n", totalBytes)
 	return nil
 }
+
+// memoryEvents are the counters reported by memory.events.
+//
+// +stateify savable
+type memoryEvents struct {
+	// low is the number of times the cgroup was reclaimed despite being under
+	// its low boundary.
+	low atomicbitops.Uint64
+	// high is the number of times the cgroup exceeded memory.high.
+	high atomicbitops.Uint64
+	// max is the number of times the cgroup was about to exceed memory.max.
+	max atomicbitops.Uint64
+	// oom is the number of times the cgroup hit memory.max and allocation
+	// failed.
+	oom atomicbitops.Uint64
+	// oomKill is the number of tasks in the cgroup killed by the OOM killer.
+	oomKill atomicbitops.Uint64
+}
+
+// +stateify savable
+type memoryEventsData struct {
+	events *memoryEvents
+}
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *memoryEventsData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	fmt.Fprintf(buf, "low %d\n", d.events.low.Load())
+	fmt.Fprintf(buf, "high %d\n", d.events.high.Load())
+	fmt.Fprintf(buf, "max %d\n", d.events.max.Load())
+	fmt.Fprintf(buf, "oom %d\n", d.events.oom.Load())
+	fmt.Fprintf(buf, "oom_kill %d\n", d.events.oomKill.Load())
+	return nil
+}
+
+// memoryLimitData implements the unified hierarchy's memory limit files, which
+// accept either a byte count or "max".
+//
+// +stateify savable
+type memoryLimitData struct {
+	limit *atomicbitops.Int64
+}
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *memoryLimitData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	if val := d.limit.Load(); val == math.MaxInt64 {
+		fmt.Fprintf(buf, "max\n")
+	} else {
+		fmt.Fprintf(buf, "%d\n", val)
+	}
+	return nil
+}
+
+// Write implements vfs.WritableDynamicBytesSource.Write.
+func (d *memoryLimitData) Write(ctx context.Context, _ *vfs.FileDescription, src usermem.IOSequence, offset int64) (int64, error) {
+	return d.WriteBackground(ctx, src)
+}
+
+// WriteBackground implements writableControllerFileImpl.WriteBackground.
+func (d *memoryLimitData) WriteBackground(ctx context.Context, src usermem.IOSequence) (int64, error) {
+	val, n, err := parseInt64OrMaxFromString(ctx, src, math.MaxInt64)
+	if err != nil {
+		return 0, err
+	}
+	if val < 0 {
+		return 0, linuxerr.EINVAL
+	}
+	if val != math.MaxInt64 {
+		// Limits are tracked in pages. See Linux, mm/page_counter.c:page_counter_memparse().
+		val = int64(hostarch.PageRoundDown(uint64(val)))
+	}
+	d.limit.Store(val)
+	return n, nil
+}
@@ -0,0 +1,100 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cgroupfs
+
+import (
+	"math"
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/atomicbitops"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+)
+
+func newTestMemoryController(parent *memoryController, limit int64) *memoryController {
+	c := &memoryController{
+		limitBytes: atomicbitops.FromInt64(limit),
+		highBytes:  atomicbitops.FromInt64(math.MaxInt64),
+	}
+	if parent != nil {
+		c.parent = parent
+	}
+	return c
+}
+
+func charge(t *testing.T, c *memoryController, pages int64) error {
+	t.Helper()
+	return c.Charge(nil, nil, kernel.CgroupResourceMemory, pages*hostarch.PageSize)
+}
+
+func TestOOMDomainNoFailure(t *testing.T) {
+	root := newTestMemoryController(nil, 4*hostarch.PageSize)
+	child := newTestMemoryController(root, 4*hostarch.PageSize)
+
+	// child is at its limit, but no charge has failed.
+	if err := charge(t, child, 4); err != nil {
+		t.Fatalf("Charge failed: %v", err)
+	}
+	if mc := child.oomDomain(); mc != nil {
+		t.Errorf("oomDomain() = %p, want nil", mc)
+	}
+}
+
+func TestOOMDomainOwnLimit(t *testing.T) {
+	root := newTestMemoryController(nil, math.MaxInt64)
+	child := newTestMemoryController(root, 4*hostarch.PageSize)
+
+	if err := charge(t, child, 3); err != nil {
+		t.Fatalf("Charge failed: %v", err)
+	}
+	if err := charge(t, child, 2); !linuxerr.Equals(linuxerr.ENOMEM, err) {
+		t.Fatalf("Charge got err %v, want ENOMEM", err)
+	}
+	if mc := child.oomDomain(); mc != child {
+		t.Errorf("oomDomain() = %p, want child %p", mc, child)
+	}
+
+	// Once the failed charge fits again, there is nothing to kill.
+	if err := charge(t, child, -1); err != nil {
+		t.Fatalf("Uncharge failed: %v", err)
+	}
+	if mc := child.oomDomain(); mc != nil {
+		t.Errorf("oomDomain() after uncharge = %p, want nil", mc)
+	}
+}
+
+func TestOOMDomainAncestorLimit(t *testing.T) {
+	root := newTestMemoryController(nil, 4*hostarch.PageSize)
+	child := newTestMemoryController(root, 4*hostarch.PageSize)
+	sibling := newTestMemoryController(root, math.MaxInt64)
+
+	if err := charge(t, sibling, 2); err != nil {
+		t.Fatalf("Charge failed: %v", err)
+	}
+	if err := charge(t, child, 2); err != nil {
+		t.Fatalf("Charge failed: %v", err)
+	}
+	// The charge fits within child's limit, but not within root's.
+	if err := charge(t, child, 1); !linuxerr.Equals(linuxerr.ENOMEM, err) {
+		t.Fatalf("Charge got err %v, want ENOMEM", err)
+	}
+	if got := child.charged.Load(); got != 2*hostarch.PageSize {
+		t.Errorf("child charged = %d, want %d", got, 2*hostarch.PageSize)
+	}
+	if mc := child.oomDomain(); mc != root {
+		t.Errorf("oomDomain() = %p, want root %p", mc, root)
+	}
+}
@@ -0,0 +1,513 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cgroupfs
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/usermem"
+)
+
+// This file implements the core interface files of the cgroup v2 unified
+// hierarchy. See Linux, Documentation/admin-guide/cgroup-v2.rst.
+
+// v2Visible returns whether ty is listed in cgroup.controllers and may be
+// enabled through cgroup.subtree_control.
+func v2Visible(ty kernel.CgroupControllerType) bool {
+	return ty != kernel.CgroupControllerCPUAcct
+}
+
+// threadedController returns whether ty may be enabled in threaded subtrees.
+// Other controllers are domain controllers, which only apply to whole
+// processes. See Linux, kernel/cgroup/cgroup.c:cgrp_dfl_threaded_ss_mask.
+func threadedController(ty kernel.CgroupControllerType) bool {
+	switch ty {
+	case kernel.CgroupControllerCPU, kernel.CgroupControllerCPUSet, kernel.CgroupControllerPIDs:
+		return true
+	default:
+		return false
+	}
+}
+
+// availableControllersLocked returns the sorted list of controllers available
+// to c. For the root cgroup, these are all the controllers attached to the
+// hierarchy. Otherwise, these are the controllers enabled in the parent's
+// cgroup.subtree_control.
+//
+// +checklocksread:c.fs.tasksMu
+func (c *cgroupInode) availableControllersLocked() []kernel.CgroupControllerType {
+	var ctypes []kernel.CgroupControllerType
+	if c.parent == nil {
+		for _, ctl := range c.fs.controllers {
+			if v2Visible(ctl.Type()) {
+				ctypes = append(ctypes, ctl.Type())
+			}
+		}
+		// fs.controllers is already sorted.
+		return ctypes
+	}
+	for ty := range c.parent.subtreeControl {
+		ctypes = append(ctypes, ty)
+	}
+	sortControllerTypes(ctypes)
+	return ctypes
+}
+
+func sortControllerTypes(ctypes []kernel.CgroupControllerType) {
+	sort.Slice(ctypes, func(i, j int) bool { return ctypes[i] < ctypes[j] })
+}
+
+// domainLocked returns the threaded domain of c: c itself if c isn't
+// threaded, or the nearest ancestor of c that isn't threaded otherwise.
+//
+// +checklocksread:c.fs.tasksMu
+func (c *cgroupInode) domainLocked() *cgroupInode {
+	for c.threaded {
+		c = c.parent
+	}
+	return c
+}
+
+// invalidLocked returns whether c is an invalid domain, i.e. a domain cgroup
+// created under a threaded cgroup. Invalid domains can't contain tasks until
+// they are made threaded.
+//
+// +checklocksread:c.fs.tasksMu
+func (c *cgroupInode) invalidLocked() bool {
+	return !c.threaded && c.parent != nil && c.parent.threaded
+}
+
+// hasThreadedChildrenLocked returns whether c is the root of a threaded
+// subtree.
+//
+// +checklocksread:c.fs.tasksMu
+func (c *cgroupInode) hasThreadedChildrenLocked() bool {
+	threaded := false
+	c.forEachChildDir(func(d *dir) {
+		if d.cgi.threaded {
+			threaded = true
+		}
+	})
+	return threaded
+}
+
+// hasDomainControllersLocked returns whether c distributes any domain
+// controller to its children.
+//
+// +checklocksread:c.fs.tasksMu
+func (c *cgroupInode) hasDomainControllersLocked() bool {
+	for ty := range c.subtreeControl {
+		if !threadedController(ty) {
+			return true
+		}
+	}
+	return false
+}
+
+// canBeThreadRootLocked returns whether c is, or may become, the root of a
+// threaded subtree. See Linux,
+// kernel/cgroup/cgroup.c:cgroup_can_be_thread_root().
+//
+// +checklocksread:c.fs.tasksMu
+func (c *cgroupInode) canBeThreadRootLocked() bool {
+	if c.parent == nil {
+		return true
+	}
+	if c.threaded || c.hasDomainControllersLocked() {
+		return false
+	}
+	// A thread root can only have either domain or threaded children.
+	populatedDomain := false
+	c.forEachChildDir(func(d *dir) {
+		if !d.cgi.threaded && d.cgi.populatedLocked() {
+			populatedDomain = true
+		}
+	})
+	return !populatedDomain
+}
+
+// vetMigrateDstLocked checks whether tasks may be moved into c. In particular,
+// a non-root domain cgroup with controllers enabled for its children can't
+// contain processes itself ("no internal process" constraint). See Linux,
+// kernel/cgroup/cgroup.c:cgroup_migrate_vet_dst().
+//
+// +checklocksread:c.fs.tasksMu
+func (c *cgroupInode) vetMigrateDstLocked() error {
+	if c.invalidLocked() {
+		return linuxerr.EOPNOTSUPP
+	}
+	if c.parent == nil || c.threaded || c.canBeThreadRootLocked() {
+		return nil
+	}
+	if len(c.subtreeControl) > 0 {
+		return linuxerr.EBUSY
+	}
+	return nil
+}
+
+// vetMigrateDst is like vetMigrateDstLocked, but acquires fs.tasksMu.
+func (c *cgroupInode) vetMigrateDst() error {
+	c.fs.tasksMu.RLock()
+	defer c.fs.tasksMu.RUnlock()
+	return c.vetMigrateDstLocked()
+}
+
+// cgroupOfLocked returns the cgroup in c's subtree that contains t, or nil if
+// there is none.
+//
+// +checklocksread:c.fs.tasksMu
+func (c *cgroupInode) cgroupOfLocked(t *kernel.Task) *cgroupInode {
+	if _, ok := c.ts[t]; ok {
+		return c
+	}
+	var found *cgroupInode
+	c.forEachChildDir(func(d *dir) {
+		if found == nil {
+			found = d.cgi.cgroupOfLocked(t)
+		}
+	})
+	return found
+}
+
+// threadedTasksLocked returns the tasks in c and in the threaded subtree
+// rooted at c.
+//
+// +checklocksread:c.fs.tasksMu
+func (c *cgroupInode) threadedTasksLocked() []*kernel.Task {
+	ts := make([]*kernel.Task, 0, len(c.ts))
+	for t := range c.ts {
+		ts = append(ts, t)
+	}
+	c.forEachChildDir(func(d *dir) {
+		if d.cgi.threaded {
+			ts = append(ts, d.cgi.threadedTasksLocked()...)
+		}
+	})
+	return ts
+}
+
+// populatedLocked returns whether c or any of its descendants contain tasks.
+//
+// +checklocksread:c.fs.tasksMu
+func (c *cgroupInode) populatedLocked() bool {
+	if len(c.ts) > 0 {
+		return true
+	}
+	populated := false
+	c.forEachChildDir(func(d *dir) {
+		if !populated && d.cgi.populatedLocked() {
+			populated = true
+		}
+	})
+	return populated
+}
+
+// +stateify savable
+type cgroupControllersData struct {
+	*cgroupInode
+}
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *cgroupControllersData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	d.fs.tasksMu.RLock()
+	ctypes := d.availableControllersLocked()
+	d.fs.tasksMu.RUnlock()
+	writeControllerTypes(buf, ctypes)
+	return nil
+}
+
+func writeControllerTypes(buf *bytes.Buffer, ctypes []kernel.CgroupControllerType) {
+	names := make([]string, 0, len(ctypes))
+	for _, ty := range ctypes {
+		names = append(names, string(ty))
+	}
+	fmt.Fprintf(buf, "%s\n", strings.Join(names, " "))
+}
+
+// +stateify savable
+type cgroupSubtreeControlData struct {
+	*cgroupInode
+}
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *cgroupSubtreeControlData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	d.fs.tasksMu.RLock()
+	ctypes := make([]kernel.CgroupControllerType, 0, len(d.subtreeControl))
+	for ty := range d.subtreeControl {
+		ctypes = append(ctypes, ty)
+	}
+	d.fs.tasksMu.RUnlock()
+	sortControllerTypes(ctypes)
+	writeControllerTypes(buf, ctypes)
+	return nil
+}
+
+// Write implements vfs.WritableDynamicBytesSource.Write.
+func (d *cgroupSubtreeControlData) Write(ctx context.Context, _ *vfs.FileDescription, src usermem.IOSequence, offset int64) (int64, error) {
+	return d.WriteBackground(ctx, src)
+}
+
+// WriteBackground implements writableControllerFileImpl.WriteBackground.
+//
+// The input is a space separated list of controller names prefixed with '+'
+// or '-' to enable or disable the controller. See Linux,
+// kernel/cgroup/cgroup.c:cgroup_subtree_control_write().
+func (d *cgroupSubtreeControlData) WriteBackground(ctx context.Context, src usermem.IOSequence) (int64, error) {
+	buf := copyScratchBufferFromContext(ctx, hostarch.PageSize)
+	n, err := src.CopyIn(ctx, buf)
+	if err != nil {
+		return 0, err
+	}
+
+	enable := make(map[kernel.CgroupControllerType]struct{})
+	disable := make(map[kernel.CgroupControllerType]struct{})
+	for _, tok := range strings.Fields(string(buf[:n])) {
+		ty, err := kernel.ParseCgroupController(tok[1:])
+		if err != nil || !v2Visible(ty) {
+			return 0, linuxerr.EINVAL
+		}
+		switch tok[0] {
+		case '+':
+			enable[ty] = struct{}{}
+			delete(disable, ty)
+		case '-':
+			disable[ty] = struct{}{}
+			delete(enable, ty)
+		default:
+			return 0, linuxerr.EINVAL
+		}
+	}
+
+	d.fs.tasksMu.Lock()
+	defer d.fs.tasksMu.Unlock()
+
+	if len(enable) > 0 {
+		available := make(map[kernel.CgroupControllerType]struct{})
+		for _, ty := range d.availableControllersLocked() {
+			available[ty] = struct{}{}
+		}
+		for ty := range enable {
+			if _, ok := available[ty]; !ok {
+				return 0, linuxerr.ENOENT
+			}
+		}
+		if err := d.vetSubtreeControlEnableLocked(enable); err != nil {
+			return 0, err
+		}
+	}
+
+	// A controller can't be disabled while a child still distributes it
+	// further down the hierarchy.
+	busy := false
+	d.forEachChildDir(func(child *dir) {
+		for ty := range disable {
+			if _, ok := child.cgi.subtreeControl[ty]; ok {
+				busy = true
+			}
+		}
+	})
+	if busy {
+		return 0, linuxerr.EBUSY
+	}
+
+	for ty := range enable {
+		d.subtreeControl[ty] = struct{}{}
+	}
+	for ty := range disable {
+		delete(d.subtreeControl, ty)
+	}
+	return int64(n), nil
+}
+
+// vetSubtreeControlEnableLocked checks whether the controllers in enable may
+// be enabled for the children of c. See Linux,
+// kernel/cgroup/cgroup.c:cgroup_vet_subtree_control_enable().
+//
+// +checklocksread:c.fs.tasksMu
+func (c *cgroupInode) vetSubtreeControlEnableLocked(enable map[kernel.CgroupControllerType]struct{}) error {
+	if c.invalidLocked() {
+		return linuxerr.EOPNOTSUPP
+	}
+	if c.parent == nil {
+		return nil
+	}
+	domain := false
+	for ty := range enable {
+		if !threadedController(ty) {
+			domain = true
+		}
+	}
+	if !domain {
+		// Threaded controllers handle competition between a cgroup's own
+		// threads and its children.
+		return nil
+	}
+	// Domain controllers can't be enabled inside a threaded subtree.
+	if c.threaded || c.hasThreadedChildrenLocked() {
+		return linuxerr.EOPNOTSUPP
+	}
+	// Non-root cgroups can only distribute resources to their children when
+	// they don't have any processes of their own.
+	if len(c.ts) > 0 {
+		return linuxerr.EBUSY
+	}
+	return nil
+}
+
+// cgroupTypeData implements cgroup.type, which reports and changes whether a
+// cgroup is a domain or threaded cgroup.
+//
+// +stateify savable
+type cgroupTypeData struct {
+	*cgroupInode
+}
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *cgroupTypeData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	d.fs.tasksMu.RLock()
+	defer d.fs.tasksMu.RUnlock()
+	switch {
+	case d.threaded:
+		buf.WriteString("threaded\n")
+	case d.invalidLocked():
+		buf.WriteString("domain invalid\n")
+	case d.hasThreadedChildrenLocked():
+		buf.WriteString("domain threaded\n")
+	default:
+		buf.WriteString("domain\n")
+	}
+	return nil
+}
+
+// Write implements vfs.WritableDynamicBytesSource.Write.
+func (d *cgroupTypeData) Write(ctx context.Context, _ *vfs.FileDescription, src usermem.IOSequence, offset int64) (int64, error) {
+	return d.WriteBackground(ctx, src)
+}
+
+// WriteBackground implements writableControllerFileImpl.WriteBackground.
+//
+// The only accepted value is "threaded", since a threaded cgroup can't be
+// turned back into a domain. See Linux,
+// kernel/cgroup/cgroup.c:cgroup_enable_threaded().
+func (d *cgroupTypeData) WriteBackground(ctx context.Context, src usermem.IOSequence) (int64, error) {
+	buf := copyScratchBufferFromContext(ctx, hostarch.PageSize)
+	n, err := src.CopyIn(ctx, buf)
+	if err != nil {
+		return 0, err
+	}
+	if strings.TrimSpace(string(buf[:n])) != "threaded" {
+		return 0, linuxerr.EINVAL
+	}
+
+	d.fs.tasksMu.Lock()
+	defer d.fs.tasksMu.Unlock()
+
+	if d.threaded {
+		return int64(n), nil
+	}
+	// A populated cgroup, or one that distributes domain controllers, can't
+	// be switched.
+	if d.populatedLocked() || d.hasDomainControllersLocked() {
+		return 0, linuxerr.EOPNOTSUPP
+	}
+	// d joins the threaded subtree of its parent's domain, which must be
+	// able to host it.
+	dom := d.parent.domainLocked()
+	if dom.invalidLocked() || !dom.canBeThreadRootLocked() {
+		return 0, linuxerr.EOPNOTSUPP
+	}
+	d.threaded = true
+	return int64(n), nil
+}
+
+// +stateify savable
+type cgroupEventsData struct {
+	*cgroupInode
+}
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *cgroupEventsData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	d.fs.tasksMu.RLock()
+	populated := d.populatedLocked()
+	d.fs.tasksMu.RUnlock()
+
+	p := 0
+	if populated {
+		p = 1
+	}
+	fmt.Fprintf(buf, "populated %d\n", p)
+	// Freezing isn't supported.
+	fmt.Fprintf(buf, "frozen 0\n")
+	return nil
+}
+
+// cgroupThreadsData implements cgroup.threads, which lists the threads in a
+// cgroup and moves individual threads between the cgroups of a threaded
+// subtree.
+//
+// +stateify savable
+type cgroupThreadsData struct {
+	*cgroupInode
+}
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *cgroupThreadsData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	t := &tasksData{d.cgroupInode}
+	return t.Generate(ctx, buf)
+}
+
+// Write implements vfs.WritableDynamicBytesSource.Write.
+func (d *cgroupThreadsData) Write(ctx context.Context, fd *vfs.FileDescription, src usermem.IOSequence, offset int64) (int64, error) {
+	tid, n, err := parseInt64FromString(ctx, src)
+	if err != nil {
+		return n, err
+	}
+
+	t := kernel.TaskFromContext(ctx)
+	currPidns := t.ThreadGroup().PIDNamespace()
+	var targetTask *kernel.Task
+	if tid != 0 {
+		targetTask = currPidns.TaskWithID(kernel.ThreadID(tid))
+	} else {
+		targetTask = t
+	}
+	if targetTask == nil {
+		return 0, linuxerr.EINVAL
+	}
+
+	// Threads may only move between cgroups of the same threaded domain. See
+	// Linux, kernel/cgroup/cgroup.c:cgroup_attach_permissions().
+	d.fs.tasksMu.RLock()
+	err = d.vetMigrateDstLocked()
+	if err == nil {
+		src := d.fs.root.Inode().(*cgroupInode).cgroupOfLocked(targetTask)
+		if src == nil || src.domainLocked() != d.domainLocked() {
+			err = linuxerr.EOPNOTSUPP
+		}
+	}
+	d.fs.tasksMu.RUnlock()
+	if err != nil {
+		return 0, err
+	}
+	return n, targetTask.MigrateCgroup(d.CgroupFromControlFileFD(fd))
+}
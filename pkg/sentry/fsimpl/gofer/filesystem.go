@@ -299,6 +299,9 @@ func (fs *filesystem) getChildAndWalkPathLocked(ctx context.Context, parent *den
 	if child, err := parent.getCachedChildLocked(rp.Component()); child != nil || err != nil {
 		return child, err
 	}
+	if rp.Cached() {
+		return nil, linuxerr.EAGAIN
+	}
 	// dentry.getRemoteChildAndWalkPathLocked already handles dentry caching.
 	return parent.getRemoteChildAndWalkPathLocked(ctx, rp, ds)
 }
@@ -1766,6 +1769,13 @@ func (fs *filesystem) IsDescendant(vfsroot, vd vfs.VirtualDentry) bool {
 	return genericIsDescendant(fs, vfsroot.Dentry(), vd.Dentry().Impl().(*dentry))
 }
 
+// SupportsCachedResolution implements
+// vfs.FilesystemImplCachedResolutionExtension.SupportsCachedResolution. Lookups
+// that miss the dentry cache or need revalidation fail with EAGAIN.
+func (fs *filesystem) SupportsCachedResolution() bool {
+	return true
+}
+
 type mopt struct {
 	key   string
 	value any
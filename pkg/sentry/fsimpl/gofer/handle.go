@@ -87,6 +87,27 @@ func (h *handle) allocate(ctx context.Context, mode, offset, length uint64) erro
 	return nil
 }
 
+// copyFileRange copies up to length bytes from src at srcOff to h at dstOff,
+// using the host's copy_file_range(2) either directly or via the gofer. It
+// returns EXDEV if neither is possible.
+func (h *handle) copyFileRange(ctx context.Context, src *handle, srcOff, dstOff, length uint64) (uint64, error) {
+	if h.fd >= 0 && src.fd >= 0 {
+		rOff := int64(srcOff)
+		wOff := int64(dstOff)
+		ctx.UninterruptibleSleepStart(false)
+		n, err := unix.CopyFileRange(int(src.fd), &rOff, int(h.fd), &wOff, int(length), 0)
+		ctx.UninterruptibleSleepFinish(false)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(n), nil
+	}
+	if h.fdLisa.Ok() && src.fdLisa.Ok() {
+		return h.fdLisa.CopyFileRange(ctx, &src.fdLisa, srcOff, dstOff, length)
+	}
+	return 0, unix.EXDEV
+}
+
 func (h *handle) sync(ctx context.Context) error {
 	// If we have a host FD, fsyncing it is likely to be faster than an fsync
 	// RPC.
@@ -232,7 +232,7 @@ func (fd *regularFileFD) pwrite(ctx context.Context, src usermem.IOSequence, off
 	defer putDentryReadWriter(rw)
 
 	if fd.vfsfd.StatusFlags()&linux.O_DIRECT != 0 {
-		if err := fd.writeCache(ctx, d, offset, src); err != nil {
+		if err := fd.writeCache(ctx, d, offset, src.NumBytes()); err != nil {
 			return 0, offset, err
 		}
 
@@ -278,16 +278,16 @@ func (fd *regularFileFD) pwrite(ctx context.Context, src usermem.IOSequence, off
 	return n, offset + n, nil
 }
 
-func (fd *regularFileFD) writeCache(ctx context.Context, d *dentry, offset int64, src usermem.IOSequence) error {
+func (fd *regularFileFD) writeCache(ctx context.Context, d *dentry, offset, size int64) error {
 	// Write dirty cached pages that will be touched by the write back to
 	// the remote file.
-	if err := d.writeback(ctx, offset, src.NumBytes()); err != nil {
+	if err := d.writeback(ctx, offset, size); err != nil {
 		return err
 	}
 
 	// Remove touched pages from the cache.
 	pgstart := hostarch.PageRoundDown(uint64(offset))
-	pgend, ok := hostarch.PageRoundUp(uint64(offset + src.NumBytes()))
+	pgend, ok := hostarch.PageRoundUp(uint64(offset + size))
 	if !ok {
 		return linuxerr.EINVAL
 	}
@@ -313,6 +313,76 @@ func (fd *regularFileFD) writeCache(ctx context.Context, d *dentry, offset int64
 	return nil
 }
 
+// CopyFileRange implements vfs.FileDescriptionImpl.CopyFileRange.
+func (fd *regularFileFD) CopyFileRange(ctx context.Context, src *vfs.FileDescription, srcOff, dstOff, length int64) (int64, error) {
+	srcFD, ok := src.Impl().(*regularFileFD)
+	if !ok {
+		return 0, linuxerr.EXDEV
+	}
+	if srcOff < 0 || dstOff < 0 || length < 0 {
+		return 0, linuxerr.EINVAL
+	}
+	if length == 0 {
+		return 0, nil
+	}
+	srcD := srcFD.dentry()
+	d := fd.dentry()
+
+	// The copy is done by the host, so the remote source file must be up to
+	// date.
+	if err := srcD.writeback(ctx, srcOff, length); err != nil {
+		return 0, err
+	}
+
+	d.metadataMu.Lock()
+	defer d.metadataMu.Unlock()
+	length, err := vfs.CheckLimit(ctx, dstOff, length)
+	if err != nil {
+		return 0, err
+	}
+	// As for O_DIRECT writes, cached pages in the destination range would be
+	// stale after the copy.
+	if err := fd.writeCache(ctx, d, dstOff, length); err != nil {
+		return 0, err
+	}
+
+	// Both handles must stay open until the copy is complete, so hold
+	// handleMu of both dentries to prevent them from being replaced by
+	// ensureSharedHandle.
+	rlockTwoHandles(d, srcD)
+	srcH := srcD.readHandle()
+	h := d.writeHandle()
+	n, err := h.copyFileRange(ctx, &srcH, uint64(srcOff), uint64(dstOff), uint64(length))
+	runlockTwoHandles(d, srcD)
+	if err != nil {
+		return 0, err
+	}
+	if n == 0 {
+		return 0, nil
+	}
+
+	d.dataMu.Lock()
+	if end := uint64(dstOff) + n; end > d.size.Load() {
+		d.size.Store(end)
+	}
+	d.dataMu.Unlock()
+	if d.fs.opts.interop != InteropModeShared {
+		d.touchCMtimeLocked()
+	}
+	if srcD.fs.opts.interop != InteropModeShared {
+		srcD.touchAtime(srcFD.vfsfd.Mount())
+	}
+	// As with Linux, writing clears the setuid and setgid bits.
+	oldMode := d.mode.Load()
+	if newMode := vfs.ClearSUIDAndSGID(oldMode); newMode != oldMode {
+		if err := d.chmod(ctx, uint16(newMode)); err != nil {
+			return 0, err
+		}
+		d.mode.Store(newMode)
+	}
+	return int64(n), nil
+}
+
 // Write implements vfs.FileDescriptionImpl.Write.
 func (fd *regularFileFD) Write(ctx context.Context, src usermem.IOSequence, opts vfs.WriteOptions) (int64, error) {
 	fd.mu.Lock()
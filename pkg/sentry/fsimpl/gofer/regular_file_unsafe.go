@@ -0,0 +1,45 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gofer
+
+import (
+	"unsafe"
+)
+
+// rlockTwoHandles locks both x.handleMu and y.handleMu for reading, in an
+// order that is consistent for both rlockTwoHandles(x, y) and
+// rlockTwoHandles(y, x), such that concurrent calls cannot deadlock with
+// writers waiting for either lock. If x == y, its handleMu is only locked
+// once. The caller must unlock them with runlockTwoHandles(x, y).
+func rlockTwoHandles(x, y *dentry) {
+	if x == y {
+		x.handleMu.RLock()
+		return
+	}
+	// Lock the two dentries in order of increasing address.
+	if uintptr(unsafe.Pointer(x)) > uintptr(unsafe.Pointer(y)) {
+		x, y = y, x
+	}
+	x.handleMu.RLock()
+	y.handleMu.RLock()
+}
+
+// runlockTwoHandles unlocks the locks taken by rlockTwoHandles(x, y).
+func runlockTwoHandles(x, y *dentry) {
+	x.handleMu.RUnlock()
+	if x != y {
+		y.handleMu.RUnlock()
+	}
+}
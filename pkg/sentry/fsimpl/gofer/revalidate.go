@@ -16,6 +16,7 @@ package gofer
 
 import (
 	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
 	"gvisor.dev/gvisor/pkg/sync"
 )
@@ -50,6 +51,10 @@ func (fs *filesystem) revalidatePath(ctx context.Context, rpOrig resolvingPath,
 	if fs.opts.interop != InteropModeShared {
 		return nil
 	}
+	if rpOrig.Cached() {
+		// Revalidation requires RPCs.
+		return linuxerr.EAGAIN
+	}
 
 	// Copy resolving path to walk the path for revalidation.
 	rp := rpOrig.copy()
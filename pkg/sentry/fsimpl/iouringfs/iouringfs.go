@@ -13,9 +13,15 @@
 // limitations under the License.
 
 // Package iouringfs provides a filesystem implementation for IO_URING basing
-// it on anonfs. Currently, we don't support neither IOPOLL nor SQPOLL modes.
-// Thus, user needs to set up IO_URING first with io_uring_setup(2) syscall and
-// then issue submission request using io_uring_enter(2).
+// it on anonfs. User needs to set up IO_URING first with io_uring_setup(2)
+// syscall and then issue submission request using io_uring_enter(2), or let
+// the SQPOLL goroutine pick them up. IOPOLL mode isn't supported.
+//
+// Requests are issued by the submitter without blocking. A request that can't
+// make progress waits for its file to become ready, and is then retried from a
+// worker goroutine. This is similar to the poll-based retry ("fast poll") in
+// Linux, except that there is no io-wq fallback: operations that don't
+// support waiting for readiness are always completed inline.
 //
 // Another important note, as of now, we don't support deferred CQE. In other
 // words, the size of the backlogged set of CQE is zero. Whenever, completion
@@ -24,20 +30,21 @@ package iouringfs
 
 import (
 	"fmt"
-	"io"
 
 	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/atomicbitops"
 	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/log"
 	"gvisor.dev/gvisor/pkg/safemem"
+	"gvisor.dev/gvisor/pkg/sentry/fsimpl/eventfd"
 	"gvisor.dev/gvisor/pkg/sentry/kernel"
 	"gvisor.dev/gvisor/pkg/sentry/memmap"
 	"gvisor.dev/gvisor/pkg/sentry/pgalloc"
 	"gvisor.dev/gvisor/pkg/sentry/usage"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
-	"gvisor.dev/gvisor/pkg/usermem"
+	"gvisor.dev/gvisor/pkg/waiter"
 )
 
 // FileDescription implements vfs.FileDescriptionImpl for file-based IO_URING.
@@ -69,8 +76,64 @@ type FileDescription struct {
 	cqesBuf    sharedBuffer `state:"nosave"`
 
 	// remap indicates whether the shared buffers need to be remapped
-	// due to a S/R. Protected by ProcessSubmissions critical section.
+	// due to a S/R. Protected by mu.
 	remap bool
+
+	// flags are the IORING_SETUP_* flags the ring was set up with. flags is
+	// immutable.
+	flags uint32
+
+	// queue is notified with ReadableEvents when CQEs are posted, and with
+	// WritableEvents when SQEs are consumed.
+	queue waiter.Queue
+
+	// mu serializes accesses to the shared buffers, since completions may be
+	// posted by goroutines other than the one processing the submission
+	// queue. It also protects the fields below.
+	//
+	// mu must not be held while calling into files, timers or waiter queues.
+	mu ringMutex `state:"nosave"`
+
+	// closed is set once the ring has been released. Requests that complete
+	// after that point don't post CQEs.
+	closed bool
+
+	// cqPosted is the number of completions posted to the CQ ring, including
+	// the ones that overflowed.
+	cqPosted uint32
+
+	// inflight contains all requests that have been submitted but haven't
+	// completed yet.
+	inflight map[*request]struct{}
+
+	// ready contains requests that are waiting to be run by the worker
+	// goroutine. workerRunning indicates whether the worker goroutine is
+	// running.
+	ready         []*request
+	workerRunning bool
+
+	// deferred contains the heads of request chains held back due to
+	// IOSQE_IO_DRAIN, in submission order. deferredCount is the total number
+	// of requests in these chains. draining is the IOSQE_IO_DRAIN request that
+	// is currently in flight, if any; later requests are deferred until it
+	// completes.
+	deferred      []*request
+	deferredCount int
+	draining      *request
+
+	// countTimeouts contains IORING_OP_TIMEOUT requests that also complete
+	// after a number of completions have been posted.
+	countTimeouts []*request
+
+	// Resources registered with io_uring_register(2). See register.go.
+	fixedBufs    []hostarch.AddrRange
+	fixedFiles   []*vfs.FileDescription
+	eventfd      *vfs.FileDescription
+	eventfdAsync bool
+
+	// sqPoll is the state of the SQPOLL goroutine, if the ring was set up
+	// with IORING_SETUP_SQPOLL.
+	sqPoll *sqPollState
 }
 
 var _ vfs.FileDescriptionImpl = (*FileDescription)(nil)
@@ -106,6 +169,9 @@ func New(ctx context.Context, vfsObj *vfs.VirtualFilesystem, entries uint32, par
 	}
 	var numCqEntries uint32
 	if params.Flags&linux.IORING_SETUP_CQSIZE != 0 {
+		if params.CqEntries == 0 {
+			return nil, linuxerr.EINVAL
+		}
 		var ok bool
 		numCqEntries, ok = roundUpPowerOfTwo(params.CqEntries)
 		if !ok || numCqEntries < numSqEntries || numCqEntries > linux.IORING_MAX_CQ_ENTRIES {
@@ -145,8 +211,10 @@ func New(ctx context.Context, vfsObj *vfs.VirtualFilesystem, entries uint32, par
 		sqemf: sqEntriesFile{
 			fr: sqefr,
 		},
-		// See ProcessSubmissions for why the capacity is 1.
-		runC: make(chan struct{}, 1),
+		// See submitFromTask for why the capacity is 1.
+		runC:     make(chan struct{}, 1),
+		flags:    params.Flags,
+		inflight: make(map[*request]struct{}),
 	}
 
 	// iouringfd is always set up with read/write mode.
@@ -182,7 +250,8 @@ func New(ctx context.Context, vfsObj *vfs.VirtualFilesystem, entries uint32, par
 	params.CqOff.Cqes = uint32(cqesOffset)
 
 	// Set features supported by the current IO_URING implementation.
-	params.Features = linux.IORING_FEAT_SINGLE_MMAP
+	params.Features = linux.IORING_FEAT_SINGLE_MMAP | linux.IORING_FEAT_SUBMIT_STABLE |
+		linux.IORING_FEAT_RW_CUR_POS | linux.IORING_FEAT_FAST_POLL
 
 	// Map all shared buffers.
 	if err := iouringfd.mapSharedBuffers(); err != nil {
@@ -209,13 +278,70 @@ func New(ctx context.Context, vfsObj *vfs.VirtualFilesystem, entries uint32, par
 		return nil, err
 	}
 
+	if params.Flags&linux.IORING_SETUP_SQPOLL != 0 {
+		if err := iouringfd.startSQPoll(ctx, params); err != nil {
+			iouringfd.vfsfd.DecRef(ctx)
+			return nil, err
+		}
+	}
+
 	return &iouringfd.vfsfd, nil
 }
 
 // Release implements vfs.FileDescriptionImpl.Release.
 func (fd *FileDescription) Release(ctx context.Context) {
+	fd.mu.Lock()
+	fd.closed = true
+	inflight := fd.inflight
+	fd.inflight = nil
+	fd.ready = nil
+	fd.deferred = nil
+	fd.countTimeouts = nil
+	fd.mu.Unlock()
+
+	// Cancel all outstanding requests. Requests that are being run
+	// concurrently notice that the ring is closed when they complete.
+	for r := range inflight {
+		r.abandon(ctx)
+	}
+	fd.unregisterAll(ctx)
+
+	// Synchronize with goroutines that may still be accessing the shared
+	// buffers, since they check fd.closed with fd.mu locked.
+	fd.mu.Lock()
 	fd.mf.DecRef(fd.rbmf.fr)
 	fd.mf.DecRef(fd.sqemf.fr)
+	fd.mu.Unlock()
+}
+
+// Readiness implements waiter.Waitable.Readiness.
+func (fd *FileDescription) Readiness(mask waiter.EventMask) waiter.EventMask {
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+	var ready waiter.EventMask
+	if n, err := fd.cqReadyLocked(); err == nil && n > 0 {
+		ready |= waiter.ReadableEvents
+	}
+	if n, err := fd.sqPendingLocked(); err == nil && n < fd.ioRings.SqRingEntries {
+		ready |= waiter.WritableEvents
+	}
+	return ready & mask
+}
+
+// EventRegister implements waiter.Waitable.EventRegister.
+func (fd *FileDescription) EventRegister(e *waiter.Entry) error {
+	fd.queue.EventRegister(e)
+	return nil
+}
+
+// EventUnregister implements waiter.Waitable.EventUnregister.
+func (fd *FileDescription) EventUnregister(e *waiter.Entry) {
+	fd.queue.EventUnregister(e)
+}
+
+// Epollable implements FileDescriptionImpl.Epollable.
+func (fd *FileDescription) Epollable() bool {
+	return true
 }
 
 // mapSharedBuffers caches internal mappings for the ring's shared memory
@@ -265,17 +391,50 @@ func (fd *FileDescription) ConfigureMMap(ctx context.Context, opts *memmap.MMapO
 	return vfs.GenericConfigureMMap(&fd.vfsfd, mf, opts)
 }
 
-// ProcessSubmissions processes the submission queue. Concurrent calls to
-// ProcessSubmissions serialize, yielding task goroutines with Task.Block since
-// processing can take a long time.
+// ProcessSubmissions processes the submission queue and waits for minComplete
+// completions if IORING_ENTER_GETEVENTS is set in flags. If the ring was set up
+// with IORING_SETUP_SQPOLL, the submission queue is consumed by the SQPOLL
+// goroutine instead, and ProcessSubmissions only wakes it up.
 func (fd *FileDescription) ProcessSubmissions(t *kernel.Task, toSubmit uint32, minComplete uint32, flags uint32) (int, error) {
+	submitted := 0
+	if fd.sqPoll != nil {
+		if flags&linux.IORING_ENTER_SQ_WAKEUP != 0 {
+			fd.wakeSQPoll()
+		}
+		fd.kickSQPoll()
+		if flags&linux.IORING_ENTER_SQ_WAIT != 0 {
+			if err := fd.waitSQSpace(t); err != nil {
+				return -1, err
+			}
+		}
+		submitted = int(toSubmit)
+	} else if toSubmit > 0 {
+		n, err := fd.submitFromTask(t, toSubmit)
+		if err != nil && n == 0 {
+			return -1, err
+		}
+		submitted = n
+	}
+
+	if flags&linux.IORING_ENTER_GETEVENTS != 0 && minComplete > 0 {
+		if err := fd.waitCompletions(t, minComplete); err != nil && submitted == 0 {
+			return -1, err
+		}
+	}
+	return submitted, nil
+}
+
+// submitFromTask consumes up to toSubmit SQEs on the task goroutine. Concurrent
+// calls to submitFromTask serialize, yielding task goroutines with Task.Block
+// since processing can take a long time.
+func (fd *FileDescription) submitFromTask(t *kernel.Task, toSubmit uint32) (int, error) {
 	// We use a combination of fd.running and fd.runC to serialize concurrent
-	// callers to ProcessSubmissions. runC has a capacity of 1. The protocol
+	// callers to submitFromTask. runC has a capacity of 1. The protocol
 	// works as follows:
 	//
 	// * Becoming the active task
 	//
-	// On entry to ProcessSubmissions, we try to transition running from 0 to
+	// On entry to submitFromTask, we try to transition running from 0 to
 	// 1. If there is already an active task, this will fail and we'll go to
 	// sleep with Task.Block(). If we succeed, we're the active task.
 	//
@@ -285,7 +444,7 @@ func (fd *FileDescription) ProcessSubmissions(t *kernel.Task, toSubmit uint32, m
 	// we could still be racing with other tasks. Note that if multiple tasks
 	// are sleeping, only one will wake up since only one will successfully
 	// receive from runC. However we could still race with a new caller of
-	// ProcessSubmissions that hasn't gone to sleep yet. Only one waiting task
+	// submitFromTask that hasn't gone to sleep yet. Only one waiting task
 	// will succeed and become the active task, the rest will go to sleep.
 	//
 	// runC needs to be buffered to avoid a race between checking running and
@@ -319,203 +478,322 @@ func (fd *FileDescription) ProcessSubmissions(t *kernel.Task, toSubmit uint32, m
 		t.Block(fd.runC)
 	}
 	// We successfully set fd.running, so we're the active task now.
-	defer func() {
-		// Unblock any potentially waiting tasks.
-		if !fd.running.CompareAndSwap(1, 0) {
-			panic(fmt.Sprintf("iouringfs.FileDescription.ProcessSubmissions: active task encountered invalid fd.running state %v", fd.running.Load()))
-		}
-		select {
-		case fd.runC <- struct{}{}:
-		default:
-		}
-	}()
+	defer fd.releaseRunning()
 
 	// The rest of this function is a critical section with respect to
 	// concurrent callers.
+	s := submitter{
+		t:               t,
+		ctx:             t,
+		onTaskGoroutine: true,
+		mm:              t.MemoryManager(),
+		fdTable:         t.FDTable(),
+	}
+	return fd.submit(&s, toSubmit)
+}
 
-	if fd.remap {
-		fd.mapSharedBuffers()
-		fd.remap = false
+// releaseRunning releases the critical section acquired by setting
+// fd.running, and unblocks any potentially waiting tasks.
+func (fd *FileDescription) releaseRunning() {
+	if !fd.running.CompareAndSwap(1, 0) {
+		panic(fmt.Sprintf("iouringfs.FileDescription.releaseRunning: active submitter encountered invalid fd.running state %v", fd.running.Load()))
 	}
+	select {
+	case fd.runC <- struct{}{}:
+	default:
+	}
+}
 
-	var err error
-	var sqe linux.IOUringSqe
-
-	sqOff := linux.PreComputedIOSqRingOffsets()
-	cqOff := linux.PreComputedIOCqRingOffsets()
-	sqArraySize := sqe.SizeBytes() * int(fd.ioRings.SqRingEntries)
-	cqArraySize := (*linux.IOUringCqe)(nil).SizeBytes() * int(fd.ioRings.CqRingEntries)
-
-	// Fetch all buffers initially.
-	fetchRB := true
-	fetchSQA := true
-	fetchCQA := true
-
-	var view, sqaView, cqaView []byte
-	submitted := uint32(0)
-
-	for toSubmit > submitted {
+// submit consumes up to toSubmit SQEs and issues the corresponding requests.
+// It returns the number of SQEs consumed.
+//
+// Preconditions: The caller must have set fd.running.
+func (fd *FileDescription) submit(s *submitter, toSubmit uint32) (int, error) {
+	var (
+		err        error
+		submitted  int
+		head, tail *request
+	)
+	for uint32(submitted) < toSubmit {
 		// This loop can take a long time to process, so periodically check for
 		// interrupts. This also pets the watchdog.
-		if t.Interrupted() {
-			return -1, linuxerr.EINTR
+		if s.interrupted() {
+			err = linuxerr.EINTR
+			break
 		}
 
-		if fetchRB {
-			view, err = fd.ioRingsBuf.view(fd.ioRings.SizeBytes())
-			if err != nil {
-				return -1, err
-			}
+		var sqe linux.IOUringSqe
+		fd.mu.Lock()
+		ok, sqErr := fd.nextSQELocked(&sqe)
+		fd.mu.Unlock()
+		if sqErr != nil {
+			err = sqErr
+			break
 		}
-
-		// Note: The kernel uses sqHead as a cursor and writes cqTail. Userspace
-		// uses cqHead as a cursor and writes sqTail.
-
-		sqHeadPtr := atomicUint32AtOffset(view, int(sqOff.Head))
-		sqTailPtr := atomicUint32AtOffset(view, int(sqOff.Tail))
-		cqHeadPtr := atomicUint32AtOffset(view, int(cqOff.Head))
-		cqTailPtr := atomicUint32AtOffset(view, int(cqOff.Tail))
-		overflowPtr := atomicUint32AtOffset(view, int(cqOff.Overflow))
-
-		// Load the pointers once, so we work with a stable value. Particularly,
-		// userspace can update the SQ tail at any time.
-		sqHead := sqHeadPtr.Load()
-		sqTail := sqTailPtr.Load()
-
-		// Is the submission queue is empty?
-		if sqHead == sqTail {
-			return int(submitted), nil
+		if !ok {
+			// The submission queue is empty.
+			break
 		}
+		submitted++
 
-		// We have at least one pending sqe, unmarshal the first from the
-		// submission queue.
-		if fetchSQA {
-			sqaView, err = fd.sqesBuf.view(sqArraySize)
-			if err != nil {
-				return -1, err
-			}
-		}
-		sqaOff := int(sqHead&fd.ioRings.SqRingMask) * sqe.SizeBytes()
-		sqe.UnmarshalUnsafe(sqaView[sqaOff : sqaOff+sqe.SizeBytes()])
-		fetchSQA = fd.sqesBuf.drop()
-
-		// Dispatch request from unmarshalled entry.
-		cqe := fd.ProcessSubmission(t, &sqe, flags)
-
-		// Advance sq head.
-		sqHeadPtr.Add(1)
-
-		// Load once so we have stable values. Particularly, userspace can
-		// update the CQ head at any time.
-		cqHead := cqHeadPtr.Load()
-		cqTail := cqTailPtr.Load()
-
-		// Marshal response to completion queue.
-		if (cqTail - cqHead) >= fd.ioRings.CqRingEntries {
-			// CQ ring full.
-			fd.ioRings.CqOverflow++
-			overflowPtr.Store(fd.ioRings.CqOverflow)
-		} else {
-			// Have room in CQ, marshal CQE.
-			if fetchCQA {
-				cqaView, err = fd.cqesBuf.view(cqArraySize)
-				if err != nil {
-					return -1, err
+		r := fd.newRequest(s, &sqe)
+		if r.opcode == linux.IORING_OP_LINK_TIMEOUT && r.op != nil {
+			if tail == nil || tail.linkTimeout != nil {
+				// A link timeout must directly follow the request it guards.
+				r.op.release(s.ctx)
+				r.op = nil
+				r.errno = int32(linuxerr.EINVAL.Errno())
+			} else {
+				tail.linkTimeout = r
+				r.target = tail
+				if !r.linked() {
+					fd.startChain(s.ctx, head)
+					head, tail = nil, nil
 				}
+				continue
 			}
-			cqaOff := int(cqTail&fd.ioRings.CqRingMask) * cqe.SizeBytes()
-			cqe.MarshalUnsafe(cqaView[cqaOff : cqaOff+cqe.SizeBytes()])
-			fetchCQA, err = fd.cqesBuf.writebackWindow(cqaOff, cqe.SizeBytes())
-			if err != nil {
-				return -1, err
-			}
-
-			// Advance cq tail.
-			cqTailPtr.Add(1)
 		}
 
-		fetchRB, err = fd.ioRingsBuf.writeback(fd.ioRings.SizeBytes())
-		if err != nil {
-			return -1, err
+		if tail != nil {
+			tail.link = r
+		} else {
+			head = r
 		}
+		tail = r
+		if !r.linked() {
+			fd.startChain(s.ctx, head)
+			head, tail = nil, nil
+		}
+	}
+	if head != nil {
+		// The last SQE had IOSQE_IO_LINK set, but there are no more SQEs.
+		fd.startChain(s.ctx, head)
+	}
 
-		submitted++
+	if submitted > 0 {
+		fd.queue.Notify(waiter.WritableEvents)
 	}
+	return submitted, err
+}
 
-	return int(submitted), nil
+// checkRingsLocked checks that the shared buffers may be accessed, remapping
+// them after a restore if needed.
+//
+// Preconditions: fd.mu must be locked.
+func (fd *FileDescription) checkRingsLocked() error {
+	if fd.closed {
+		return linuxerr.EBADF
+	}
+	if fd.remap {
+		if err := fd.mapSharedBuffers(); err != nil {
+			return err
+		}
+		fd.remap = false
+	}
+	return nil
 }
 
-// ProcessSubmission processes a single submission request.
-func (fd *FileDescription) ProcessSubmission(t *kernel.Task, sqe *linux.IOUringSqe, flags uint32) *linux.IOUringCqe {
-	var (
-		cqeErr   error
-		cqeFlags uint32
-		retValue int32
-	)
+// nextSQELocked copies the SQE at the head of the submission queue to sqe, and
+// consumes it. It returns false if the submission queue is empty.
+//
+// Preconditions: fd.mu must be locked.
+func (fd *FileDescription) nextSQELocked(sqe *linux.IOUringSqe) (bool, error) {
+	if err := fd.checkRingsLocked(); err != nil {
+		return false, err
+	}
+	view, err := fd.ioRingsBuf.view(fd.ioRings.SizeBytes())
+	if err != nil {
+		return false, err
+	}
 
-	switch op := sqe.Opcode; op {
-	case linux.IORING_OP_NOP:
-		// For the NOP operation, we don't do anything special.
-	case linux.IORING_OP_READV:
-		retValue, cqeErr = fd.handleReadv(t, sqe, flags)
-		if cqeErr == io.EOF {
-			// Don't raise EOF as errno, error translation will fail. Short
-			// reads aren't failures.
-			cqeErr = nil
-		}
-	default: // Unsupported operation
-		retValue = -int32(linuxerr.EINVAL.Errno())
+	// Note: The kernel uses sqHead as a cursor and writes cqTail. Userspace
+	// uses cqHead as a cursor and writes sqTail.
+	sqOff := linux.PreComputedIOSqRingOffsets()
+	sqHeadPtr := atomicUint32AtOffset(view, int(sqOff.Head))
+	sqTailPtr := atomicUint32AtOffset(view, int(sqOff.Tail))
+
+	// Load the pointers once, so we work with a stable value. Particularly,
+	// userspace can update the SQ tail at any time.
+	sqHead := sqHeadPtr.Load()
+	sqTail := sqTailPtr.Load()
+	if sqHead == sqTail {
+		fd.ioRingsBuf.drop()
+		return false, nil
 	}
 
-	if cqeErr != nil {
-		retValue = -int32(kernel.ExtractErrno(cqeErr, -1))
+	sqaView, err := fd.sqesBuf.view(sqe.SizeBytes() * int(fd.ioRings.SqRingEntries))
+	if err != nil {
+		fd.ioRingsBuf.drop()
+		return false, err
 	}
+	sqaOff := int(sqHead&fd.ioRings.SqRingMask) * sqe.SizeBytes()
+	sqe.UnmarshalUnsafe(sqaView[sqaOff : sqaOff+sqe.SizeBytes()])
+	fd.sqesBuf.drop()
 
-	return &linux.IOUringCqe{
-		UserData: sqe.UserData,
-		Res:      retValue,
-		Flags:    cqeFlags,
+	// Advance sq head.
+	sqHeadPtr.Store(sqHead + 1)
+	if _, err := fd.ioRingsBuf.writeback(fd.ioRings.SizeBytes()); err != nil {
+		return false, err
 	}
+	return true, nil
 }
 
-// handleReadv handles IORING_OP_READV.
-func (fd *FileDescription) handleReadv(t *kernel.Task, sqe *linux.IOUringSqe, flags uint32) (int32, error) {
-	// Check that a file descriptor is valid.
-	if sqe.Fd < 0 {
-		return 0, linuxerr.EBADF
+// sqPendingLocked returns the number of SQEs that haven't been consumed yet.
+//
+// Preconditions: fd.mu must be locked.
+func (fd *FileDescription) sqPendingLocked() (uint32, error) {
+	if err := fd.checkRingsLocked(); err != nil {
+		return 0, err
 	}
-	// Currently we don't support any flags for the SQEs.
-	if sqe.Flags != 0 {
-		return 0, linuxerr.EINVAL
+	view, err := fd.ioRingsBuf.view(fd.ioRings.SizeBytes())
+	if err != nil {
+		return 0, err
 	}
-	// If the file is not seekable then offset must be zero. And currently, we don't support them.
-	if sqe.OffOrAddrOrCmdOp != 0 {
-		return 0, linuxerr.EINVAL
+	sqOff := linux.PreComputedIOSqRingOffsets()
+	n := atomicUint32AtOffset(view, int(sqOff.Tail)).Load() - atomicUint32AtOffset(view, int(sqOff.Head)).Load()
+	fd.ioRingsBuf.drop()
+	return n, nil
+}
+
+// cqReadyLocked returns the number of CQEs that haven't been consumed by
+// userspace yet.
+//
+// Preconditions: fd.mu must be locked.
+func (fd *FileDescription) cqReadyLocked() (uint32, error) {
+	if err := fd.checkRingsLocked(); err != nil {
+		return 0, err
 	}
-	// ioprio should not be set for the READV operation.
-	if sqe.IoPrio != 0 {
-		return 0, linuxerr.EINVAL
+	view, err := fd.ioRingsBuf.view(fd.ioRings.SizeBytes())
+	if err != nil {
+		return 0, err
 	}
+	cqOff := linux.PreComputedIOCqRingOffsets()
+	n := atomicUint32AtOffset(view, int(cqOff.Tail)).Load() - atomicUint32AtOffset(view, int(cqOff.Head)).Load()
+	fd.ioRingsBuf.drop()
+	return n, nil
+}
 
-	// AddressSpaceActive is set to true as we are doing this from the task goroutine.And this is a
-	// case as we currently don't support neither IOPOLL nor SQPOLL modes.
-	dst, err := t.IovecsIOSequence(hostarch.Addr(sqe.AddrOrSpliceOff), int(sqe.Len), usermem.IOOpts{
-		AddressSpaceActive: true,
-	})
+// postCQELocked marshals cqe to the completion queue.
+//
+// Preconditions: fd.mu must be locked.
+func (fd *FileDescription) postCQELocked(cqe *linux.IOUringCqe) error {
+	if err := fd.checkRingsLocked(); err != nil {
+		return err
+	}
+	view, err := fd.ioRingsBuf.view(fd.ioRings.SizeBytes())
 	if err != nil {
-		return 0, err
+		return err
+	}
+
+	cqOff := linux.PreComputedIOCqRingOffsets()
+	cqHeadPtr := atomicUint32AtOffset(view, int(cqOff.Head))
+	cqTailPtr := atomicUint32AtOffset(view, int(cqOff.Tail))
+	overflowPtr := atomicUint32AtOffset(view, int(cqOff.Overflow))
+
+	// Load once so we have stable values. Particularly, userspace can update
+	// the CQ head at any time.
+	cqHead := cqHeadPtr.Load()
+	cqTail := cqTailPtr.Load()
+
+	if (cqTail - cqHead) >= fd.ioRings.CqRingEntries {
+		// CQ ring full.
+		fd.ioRings.CqOverflow++
+		overflowPtr.Store(fd.ioRings.CqOverflow)
+	} else {
+		// Have room in CQ, marshal CQE.
+		cqArraySize := cqe.SizeBytes() * int(fd.ioRings.CqRingEntries)
+		cqaView, err := fd.cqesBuf.view(cqArraySize)
+		if err != nil {
+			fd.ioRingsBuf.drop()
+			return err
+		}
+		cqaOff := int(cqTail&fd.ioRings.CqRingMask) * cqe.SizeBytes()
+		cqe.MarshalUnsafe(cqaView[cqaOff : cqaOff+cqe.SizeBytes()])
+		if _, err := fd.cqesBuf.writebackWindow(cqaOff, cqe.SizeBytes()); err != nil {
+			fd.ioRingsBuf.drop()
+			return err
+		}
+
+		// Advance cq tail.
+		cqTailPtr.Store(cqTail + 1)
 	}
-	file := t.GetFile(sqe.Fd)
-	if file == nil {
-		return 0, linuxerr.EBADF
+	if _, err := fd.ioRingsBuf.writeback(fd.ioRings.SizeBytes()); err != nil {
+		return err
 	}
-	defer file.DecRef(t)
-	n, err := file.PRead(t, dst, 0, vfs.ReadOptions{})
+
+	fd.cqPosted++
+	fd.flushCountTimeoutsLocked()
+	return nil
+}
+
+// postCompletion posts a CQE for a completed request, and notifies waiters.
+// async indicates whether the request completed outside of the submitter's
+// context, which matters for IORING_REGISTER_EVENTFD_ASYNC.
+func (fd *FileDescription) postCompletion(ctx context.Context, userData uint64, res int32, async bool) {
+	fd.mu.Lock()
+	if fd.closed {
+		fd.mu.Unlock()
+		return
+	}
+	err := fd.postCQELocked(&linux.IOUringCqe{
+		UserData: userData,
+		Res:      res,
+	})
+	efd := fd.eventfd
+	if efd != nil && (async || !fd.eventfdAsync) {
+		efd.IncRef()
+	} else {
+		efd = nil
+	}
+	fd.mu.Unlock()
+
 	if err != nil {
-		return 0, err
+		log.Warningf("iouringfs: failed to post CQE: %v", err)
+	} else {
+		fd.queue.Notify(waiter.ReadableEvents)
 	}
+	if efd != nil {
+		efd.Impl().(*eventfd.EventFileDescription).Signal(1)
+		efd.DecRef(ctx)
+	}
+}
 
-	return int32(n), nil
+// waitCompletions blocks until at least minComplete CQEs are available.
+func (fd *FileDescription) waitCompletions(t *kernel.Task, minComplete uint32) error {
+	if minComplete > fd.ioRings.CqRingEntries {
+		minComplete = fd.ioRings.CqRingEntries
+	}
+	return fd.waitRing(t, waiter.ReadableEvents, func() (bool, error) {
+		n, err := fd.cqReadyLocked()
+		return n >= minComplete, err
+	})
+}
+
+// waitSQSpace blocks until the submission queue isn't full.
+func (fd *FileDescription) waitSQSpace(t *kernel.Task) error {
+	return fd.waitRing(t, waiter.WritableEvents, func() (bool, error) {
+		n, err := fd.sqPendingLocked()
+		return n < fd.ioRings.SqRingEntries, err
+	})
+}
+
+// waitRing blocks until cond, which is called with fd.mu locked, returns true.
+// fd.queue must be notified with mask whenever cond may become true.
+func (fd *FileDescription) waitRing(t *kernel.Task, mask waiter.EventMask, cond func() (bool, error)) error {
+	e, ch := waiter.NewChannelEntry(mask)
+	fd.queue.EventRegister(&e)
+	defer fd.queue.EventUnregister(&e)
+	for {
+		fd.mu.Lock()
+		ok, err := cond()
+		fd.mu.Unlock()
+		if err != nil || ok {
+			return err
+		}
+		if err := t.Block(ch); err != nil {
+			return linuxerr.EINTR
+		}
+	}
 }
 
 // updateCq updates a completion queue by adding a given completion queue entry.
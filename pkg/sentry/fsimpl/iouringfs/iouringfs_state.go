@@ -25,6 +25,14 @@ func (fd *FileDescription) beforeSave() {
 	if fd.running.Load() != 0 {
 		panic("Task goroutine in fd.ProcessSubmissions during Save! This shouldn't be possible due to Kernel.Pause")
 	}
+	// The worker and SQPOLL goroutines are asynchronous I/O goroutines, which
+	// Kernel.Pause waits for.
+	if fd.workerRunning {
+		panic("Worker goroutine running during Save! This shouldn't be possible due to Kernel.Pause")
+	}
+	if fd.sqPoll != nil && fd.sqPoll.running {
+		panic("SQPOLL goroutine running during Save! This shouldn't be possible due to Kernel.Pause")
+	}
 }
 
 // afterLoad is invoked by stateify.
@@ -33,4 +41,7 @@ func (fd *FileDescription) afterLoad(ctx context.Context) {
 	// Remap shared buffers.
 	fd.remap = true
 	fd.runC = make(chan struct{}, 1)
+	if fd.sqPoll != nil {
+		fd.sqPoll.kickC = make(chan struct{}, 1)
+	}
 }
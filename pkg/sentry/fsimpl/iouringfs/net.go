@@ -0,0 +1,487 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package iouringfs
+
+import (
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/marshal/primitive"
+	"gvisor.dev/gvisor/pkg/sentry/ktime"
+	"gvisor.dev/gvisor/pkg/sentry/socket"
+	"gvisor.dev/gvisor/pkg/sentry/socket/control"
+	"gvisor.dev/gvisor/pkg/sentry/socket/unix/transport"
+	"gvisor.dev/gvisor/pkg/usermem"
+	"gvisor.dev/gvisor/pkg/waiter"
+)
+
+// The following limits and offsets mirror the ones used by the socket
+// syscalls, see syscalls/linux/sys_socket.go.
+const (
+	// maxAddrLen is the maximum socket address length we're willing to
+	// accept.
+	maxAddrLen = 200
+
+	// maxControlLen is the maximum length of the msghdr.msg_control buffer
+	// we're willing to accept.
+	maxControlLen = 10 * 1024 * 1024
+
+	// The size of, and offsets of fields in, struct msghdr on 64-bit
+	// architectures.
+	msghdrSize             = 56
+	msghdrNameOffset       = 0
+	msghdrNameLenOffset    = 8
+	msghdrIovOffset        = 16
+	msghdrIovLenOffset     = 24
+	msghdrControlOffset    = 32
+	msghdrControlLenOffset = 40
+	msghdrFlagsOffset      = 48
+
+	// baseRecvFlags are the flags that are accepted by both IORING_OP_RECV
+	// and IORING_OP_RECVMSG.
+	baseRecvFlags = linux.MSG_OOB | linux.MSG_DONTROUTE | linux.MSG_DONTWAIT | linux.MSG_NOSIGNAL | linux.MSG_WAITALL | linux.MSG_TRUNC | linux.MSG_CTRUNC | linux.MSG_PEEK
+
+	// sendFlags are the flags accepted by IORING_OP_SEND and
+	// IORING_OP_SENDMSG.
+	sendFlags = linux.MSG_DONTWAIT | linux.MSG_EOR | linux.MSG_MORE | linux.MSG_NOSIGNAL
+)
+
+// msghdr is the part of struct msghdr used by IORING_OP_SENDMSG and
+// IORING_OP_RECVMSG.
+type msghdr struct {
+	name       hostarch.Addr
+	nameLen    uint32
+	iov        hostarch.Addr
+	iovLen     uint64
+	control    hostarch.Addr
+	controlLen uint64
+}
+
+func copyInMsghdr(s *submitter, addr hostarch.Addr) (msghdr, error) {
+	var buf [msghdrSize]byte
+	if _, err := s.mm.CopyIn(s.ctx, addr, buf[:], usermem.IOOpts{}); err != nil {
+		return msghdr{}, err
+	}
+	msg := msghdr{
+		name:       hostarch.Addr(hostarch.ByteOrder.Uint64(buf[msghdrNameOffset:])),
+		nameLen:    hostarch.ByteOrder.Uint32(buf[msghdrNameLenOffset:]),
+		iov:        hostarch.Addr(hostarch.ByteOrder.Uint64(buf[msghdrIovOffset:])),
+		iovLen:     hostarch.ByteOrder.Uint64(buf[msghdrIovLenOffset:]),
+		control:    hostarch.Addr(hostarch.ByteOrder.Uint64(buf[msghdrControlOffset:])),
+		controlLen: hostarch.ByteOrder.Uint64(buf[msghdrControlLenOffset:]),
+	}
+	if msg.iovLen > linux.UIO_MAXIOV {
+		return msghdr{}, linuxerr.EMSGSIZE
+	}
+	if msg.controlLen > maxControlLen {
+		return msghdr{}, linuxerr.ENOBUFS
+	}
+	return msg, nil
+}
+
+// captureAddress copies in a socket address of addrlen bytes at addr.
+func captureAddress(s *submitter, addr hostarch.Addr, addrlen uint32) ([]byte, error) {
+	if addrlen > maxAddrLen {
+		return nil, linuxerr.EINVAL
+	}
+	buf := make([]byte, addrlen)
+	if _, err := s.mm.CopyIn(s.ctx, addr, buf, usermem.IOOpts{}); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// writeAddress is equivalent to syscalls/linux.writeAddress, but writes to the
+// request's address space.
+func writeAddress(cc *memoryCopyContext, addr linux.SockAddr, addrLen uint32, addrPtr hostarch.Addr, addrLenPtr hostarch.Addr) error {
+	// Get the buffer length.
+	var bufLen uint32
+	if _, err := primitive.CopyUint32In(cc, addrLenPtr, &bufLen); err != nil {
+		return err
+	}
+	if int32(bufLen) < 0 {
+		return linuxerr.EINVAL
+	}
+
+	// Write the length unconditionally.
+	if _, err := primitive.CopyUint32Out(cc, addrLenPtr, addrLen); err != nil {
+		return err
+	}
+	if addr == nil {
+		return nil
+	}
+	if bufLen > addrLen {
+		bufLen = addrLen
+	}
+
+	// Copy as much of the address as will fit in the buffer.
+	encodedAddr := cc.CopyScratchBuffer(addr.SizeBytes())
+	addr.MarshalUnsafe(encodedAddr)
+	if bufLen > uint32(len(encodedAddr)) {
+		bufLen = uint32(len(encodedAddr))
+	}
+	_, err := cc.CopyOutBytes(addrPtr, encodedAddr[:int(bufLen)])
+	return err
+}
+
+// getSocket sets r.file to the socket that sqe refers to, and returns it.
+func getSocket(s *submitter, r *request, sqe *linux.IOUringSqe) (socket.Socket, error) {
+	if sqe.IoPrio != 0 {
+		return nil, linuxerr.EINVAL
+	}
+	if err := r.fd.getFile(s, r, sqe); err != nil {
+		return nil, err
+	}
+	sock, ok := r.file.Impl().(socket.Socket)
+	if !ok {
+		return nil, linuxerr.ENOTSOCK
+	}
+	return sock, nil
+}
+
+// acceptOp implements IORING_OP_ACCEPT.
+//
+// +stateify savable
+type acceptOp struct {
+	noopRelease
+
+	sock       socket.Socket
+	flags      int
+	addr       hostarch.Addr
+	addrLenPtr hostarch.Addr
+}
+
+func prepAccept(s *submitter, r *request, sqe *linux.IOUringSqe) (operation, error) {
+	flags := int(sqe.OpFlags)
+	// Check that no unsupported flags are passed in.
+	if flags&^(linux.SOCK_NONBLOCK|linux.SOCK_CLOEXEC) != 0 {
+		return nil, linuxerr.EINVAL
+	}
+	sock, err := getSocket(s, r, sqe)
+	if err != nil {
+		return nil, err
+	}
+	r.events = waiter.ReadableEvents
+	return &acceptOp{
+		sock:       sock,
+		flags:      flags,
+		addr:       hostarch.Addr(sqe.AddrOrSpliceOff),
+		addrLenPtr: hostarch.Addr(sqe.OffOrAddrOrCmdOp),
+	}, nil
+}
+
+// issue implements operation.issue.
+func (op *acceptOp) issue(ctx context.Context, r *request) (int32, error) {
+	peerRequested := op.addrLenPtr != 0
+	nfd, peer, peerLen, e := op.sock.Accept(r.t, peerRequested, op.flags, false /* blocking */)
+	if e != nil {
+		return 0, e.ToError()
+	}
+	if peerRequested {
+		// Linux does not give you an error if it can't write the data back
+		// out, see syscalls/linux.accept.
+		cc := &memoryCopyContext{ctx: ctx, mm: r.mm}
+		if err := writeAddress(cc, peer, peerLen, op.addr, op.addrLenPtr); linuxerr.Equals(linuxerr.EINVAL, err) {
+			return 0, err
+		}
+	}
+	return nfd, nil
+}
+
+// connectOp implements IORING_OP_CONNECT.
+//
+// +stateify savable
+type connectOp struct {
+	noopRelease
+
+	sock socket.Socket
+	addr []byte
+
+	// inProgress indicates that a previous attempt started connecting.
+	inProgress bool
+}
+
+func prepConnect(s *submitter, r *request, sqe *linux.IOUringSqe) (operation, error) {
+	if sqe.OpFlags != 0 || sqe.Len != 0 {
+		return nil, linuxerr.EINVAL
+	}
+	sock, err := getSocket(s, r, sqe)
+	if err != nil {
+		return nil, err
+	}
+	// For IORING_OP_CONNECT, the address length is passed in the offset
+	// field.
+	addr, err := captureAddress(s, hostarch.Addr(sqe.AddrOrSpliceOff), uint32(sqe.OffOrAddrOrCmdOp))
+	if err != nil {
+		return nil, err
+	}
+	r.events = waiter.WritableEvents
+	return &connectOp{
+		sock: sock,
+		addr: addr,
+	}, nil
+}
+
+// issue implements operation.issue.
+func (op *connectOp) issue(ctx context.Context, r *request) (int32, error) {
+	e := op.sock.Connect(r.t, op.addr, false /* blocking */)
+	if e == nil {
+		return 0, nil
+	}
+	err := e.ToError()
+	switch {
+	case op.inProgress && linuxerr.Equals(linuxerr.EISCONN, err):
+		// The connection started by a previous attempt succeeded.
+		return 0, nil
+	case linuxerr.Equals(linuxerr.EINPROGRESS, err), op.inProgress && linuxerr.Equals(linuxerr.EALREADY, err):
+		op.inProgress = true
+		if r.canWait() {
+			return 0, linuxerr.ErrWouldBlock
+		}
+	}
+	return 0, err
+}
+
+// sendOp implements IORING_OP_SEND and IORING_OP_SENDMSG.
+//
+// +stateify savable
+type sendOp struct {
+	noopRelease
+
+	sock  socket.Socket
+	ars   []hostarch.AddrRange
+	flags int32
+
+	// to is the destination address, and control contains the unparsed
+	// control messages. Control messages are parsed each time the operation
+	// is issued, since they may hold references on files.
+	to      []byte
+	control []byte
+}
+
+func prepSend(s *submitter, r *request, sqe *linux.IOUringSqe) (operation, error) {
+	op, err := newSendOp(s, r, sqe)
+	if err != nil {
+		return nil, err
+	}
+	op.ars, err = s.singleRange(hostarch.Addr(sqe.AddrOrSpliceOff), sqe.Len)
+	if err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+func prepSendMsg(s *submitter, r *request, sqe *linux.IOUringSqe) (operation, error) {
+	if sqe.Len != 1 {
+		return nil, linuxerr.EINVAL
+	}
+	op, err := newSendOp(s, r, sqe)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := copyInMsghdr(s, hostarch.Addr(sqe.AddrOrSpliceOff))
+	if err != nil {
+		return nil, err
+	}
+	if msg.controlLen > 0 {
+		op.control = make([]byte, msg.controlLen)
+		if _, err := s.mm.CopyIn(s.ctx, msg.control, op.control, usermem.IOOpts{}); err != nil {
+			return nil, err
+		}
+	}
+	if msg.nameLen != 0 {
+		if op.to, err = captureAddress(s, msg.name, msg.nameLen); err != nil {
+			return nil, err
+		}
+	}
+	if op.ars, err = s.copyInIovecs(msg.iov, int(msg.iovLen)); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+func newSendOp(s *submitter, r *request, sqe *linux.IOUringSqe) (*sendOp, error) {
+	flags := int32(sqe.OpFlags)
+	if flags&^sendFlags != 0 {
+		return nil, linuxerr.EINVAL
+	}
+	sock, err := getSocket(s, r, sqe)
+	if err != nil {
+		return nil, err
+	}
+	r.events = waiter.WritableEvents
+	r.nowait = flags&linux.MSG_DONTWAIT != 0
+	// Like Linux, never raise SIGPIPE.
+	return &sendOp{
+		sock:  sock,
+		flags: flags | linux.MSG_NOSIGNAL | linux.MSG_DONTWAIT,
+	}, nil
+}
+
+// issue implements operation.issue.
+func (op *sendOp) issue(ctx context.Context, r *request) (int32, error) {
+	src := usermem.IOSequence{
+		IO:    r.mm,
+		Addrs: hostarch.AddrRangeSeqFromSlice(op.ars),
+	}
+	var cms socket.ControlMessages
+	if len(op.control) > 0 {
+		var err error
+		if cms, err = control.Parse(r.t, op.sock, op.control, r.t.Arch().Width()); err != nil {
+			return 0, err
+		}
+	} else {
+		cms = socket.ControlMessages{Unix: control.New(r.t, op.sock)}
+	}
+	n, e := op.sock.SendMsg(r.t, src, op.to, int(op.flags), false, ktime.Time{}, cms)
+	// Control messages should be released on error as well as for zero-length
+	// messages, which are discarded by the receiver.
+	if n == 0 || e != nil {
+		cms.Release(ctx)
+	}
+	if n > 0 {
+		return int32(n), nil
+	}
+	return 0, e.ToError()
+}
+
+// recvOp implements IORING_OP_RECV and IORING_OP_RECVMSG.
+//
+// +stateify savable
+type recvOp struct {
+	noopRelease
+
+	sock  socket.Socket
+	ars   []hostarch.AddrRange
+	flags int32
+
+	// The following fields are only used by IORING_OP_RECVMSG. msgPtr is the
+	// address of the struct msghdr, which is updated on completion.
+	msgPtr     hostarch.Addr
+	name       hostarch.Addr
+	nameLen    uint32
+	control    hostarch.Addr
+	controlLen uint64
+}
+
+func prepRecv(s *submitter, r *request, sqe *linux.IOUringSqe) (operation, error) {
+	op, err := newRecvOp(s, r, sqe, baseRecvFlags|linux.MSG_CONFIRM)
+	if err != nil {
+		return nil, err
+	}
+	op.ars, err = s.singleRange(hostarch.Addr(sqe.AddrOrSpliceOff), sqe.Len)
+	if err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+func prepRecvMsg(s *submitter, r *request, sqe *linux.IOUringSqe) (operation, error) {
+	if sqe.Len != 1 {
+		return nil, linuxerr.EINVAL
+	}
+	op, err := newRecvOp(s, r, sqe, baseRecvFlags|linux.MSG_CMSG_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+	op.msgPtr = hostarch.Addr(sqe.AddrOrSpliceOff)
+	msg, err := copyInMsghdr(s, op.msgPtr)
+	if err != nil {
+		return nil, err
+	}
+	op.name = msg.name
+	op.nameLen = msg.nameLen
+	op.control = msg.control
+	op.controlLen = msg.controlLen
+	if op.ars, err = s.copyInIovecs(msg.iov, int(msg.iovLen)); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+func newRecvOp(s *submitter, r *request, sqe *linux.IOUringSqe, allowed int32) (*recvOp, error) {
+	flags := int32(sqe.OpFlags)
+	if flags&^allowed != 0 {
+		return nil, linuxerr.EINVAL
+	}
+	sock, err := getSocket(s, r, sqe)
+	if err != nil {
+		return nil, err
+	}
+	r.events = waiter.ReadableEvents
+	r.nowait = flags&linux.MSG_DONTWAIT != 0
+	return &recvOp{
+		sock:  sock,
+		flags: flags | linux.MSG_DONTWAIT,
+	}, nil
+}
+
+// issue implements operation.issue.
+func (op *recvOp) issue(ctx context.Context, r *request) (int32, error) {
+	dst := usermem.IOSequence{
+		IO:    r.mm,
+		Addrs: hostarch.AddrRangeSeqFromSlice(op.ars),
+	}
+	n, mflags, sender, senderLen, cms, e := op.sock.RecvMsg(r.t, dst, int(op.flags), false, ktime.Time{}, op.nameLen != 0, op.controlLen)
+	if e != nil {
+		return 0, e.ToError()
+	}
+	defer cms.Release(ctx)
+	if op.msgPtr == 0 {
+		return int32(n), nil
+	}
+
+	cc := &memoryCopyContext{ctx: ctx, mm: r.mm}
+	controlData := make([]byte, 0, op.controlLen)
+	if op.controlLen > 0 {
+		controlData = control.PackControlMessages(r.t, cms, controlData)
+		if cr, ok := op.sock.(transport.Credentialer); ok && cr.Passcred() {
+			creds, _ := cms.Unix.Credentials.(control.SCMCredentials)
+			controlData, mflags = control.PackCredentials(r.t, creds, controlData, mflags)
+		}
+		if rights, ok := cms.Unix.Rights.(control.SCMRights); ok {
+			controlData, mflags = control.PackRights(r.t, rights, op.flags&linux.MSG_CMSG_CLOEXEC != 0, controlData, mflags)
+		} else if cms.Unix.Rights != nil {
+			// Rights received from host sockets aren't supported.
+			mflags |= linux.MSG_CTRUNC
+		}
+	} else if !cms.Unix.Empty() {
+		mflags |= linux.MSG_CTRUNC
+	}
+
+	// Copy the address to the caller.
+	if op.nameLen != 0 {
+		if err := writeAddress(cc, sender, senderLen, op.name, op.msgPtr+msghdrNameLenOffset); err != nil {
+			return 0, err
+		}
+	}
+
+	// Copy the control data to the caller.
+	if _, err := primitive.CopyUint64Out(cc, op.msgPtr+msghdrControlLenOffset, uint64(len(controlData))); err != nil {
+		return 0, err
+	}
+	if len(controlData) > 0 {
+		if _, err := cc.CopyOutBytes(op.control, controlData); err != nil {
+			return 0, err
+		}
+	}
+
+	// Copy out the flags to the caller.
+	if _, err := primitive.CopyInt32Out(cc, op.msgPtr+msghdrFlagsOffset, int32(mflags)); err != nil {
+		return 0, err
+	}
+	return int32(n), nil
+}
@@ -0,0 +1,302 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package iouringfs
+
+import (
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/bits"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/fspath"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/usermem"
+)
+
+// copyInPath copies in the pathname at addr. Pathnames are parsed when the
+// request is issued, since fspath.Path isn't savable.
+func copyInPath(s *submitter, addr hostarch.Addr) (string, error) {
+	return usermem.CopyStringIn(s.ctx, s.mm, addr, linux.PATH_MAX, usermem.IOOpts{})
+}
+
+// requestPathOperation is equivalent to syscalls/linux.taskPathOperation, but
+// resolves dirfd in the request's file table.
+type requestPathOperation struct {
+	pop          vfs.PathOperation
+	haveStartRef bool
+}
+
+// getPathOperation returns the vfs.PathOperation for path relative to dirfd.
+// It also returns the umask of r's task.
+func (r *request) getPathOperation(dirfd int32, path fspath.Path, allowEmptyPath, followFinalSymlink bool) (requestPathOperation, uint, error) {
+	var (
+		root, cwd vfs.VirtualDentry
+		umask     uint
+	)
+	// The task's FSContext can only be accessed with its mutex locked, since
+	// requests may not be issued from the task goroutine.
+	r.t.WithMuLocked(func(t *kernel.Task) {
+		fsc := t.FSContext()
+		if fsc == nil {
+			return
+		}
+		root = fsc.RootDirectory()
+		umask = fsc.Umask()
+		if !path.Absolute && dirfd == linux.AT_FDCWD {
+			cwd = fsc.WorkingDirectory()
+		}
+	})
+	if !root.Ok() {
+		// The task has exited.
+		return requestPathOperation{}, 0, linuxerr.EBADF
+	}
+
+	start := root
+	haveStartRef := false
+	if !path.Absolute {
+		if !path.HasComponents() && !allowEmptyPath {
+			root.DecRef(r.t)
+			if cwd.Ok() {
+				cwd.DecRef(r.t)
+			}
+			return requestPathOperation{}, 0, linuxerr.ENOENT
+		}
+		if dirfd == linux.AT_FDCWD {
+			start = cwd
+			haveStartRef = true
+		} else {
+			dirfile, _ := r.fdTable.Get(dirfd)
+			if dirfile == nil {
+				root.DecRef(r.t)
+				return requestPathOperation{}, 0, linuxerr.EBADF
+			}
+			start = dirfile.VirtualDentry()
+			start.IncRef()
+			haveStartRef = true
+			dirfile.DecRef(r.t)
+		}
+	}
+	return requestPathOperation{
+		pop: vfs.PathOperation{
+			Root:               root,
+			Start:              start,
+			Path:               path,
+			FollowFinalSymlink: followFinalSymlink,
+		},
+		haveStartRef: haveStartRef,
+	}, umask, nil
+}
+
+// Release releases the references held by rpop.
+func (rpop *requestPathOperation) Release(ctx context.Context) {
+	rpop.pop.Root.DecRef(ctx)
+	if rpop.haveStartRef {
+		rpop.pop.Start.DecRef(ctx)
+		rpop.haveStartRef = false
+	}
+}
+
+// openatOp implements IORING_OP_OPENAT.
+//
+// +stateify savable
+type openatOp struct {
+	noopRelease
+
+	dirfd    int32
+	pathname string
+	flags    uint32
+	mode     uint32
+}
+
+func prepOpenat(s *submitter, r *request, sqe *linux.IOUringSqe) (operation, error) {
+	if sqe.IoPrio != 0 || sqe.BufIndexOrGroup != 0 {
+		return nil, linuxerr.EINVAL
+	}
+	if sqe.Flags&linux.IOSQE_FIXED_FILE != 0 {
+		// Installing opened files directly in the fixed file table isn't
+		// supported.
+		return nil, linuxerr.EINVAL
+	}
+	pathname, err := copyInPath(s, hostarch.Addr(sqe.AddrOrSpliceOff))
+	if err != nil {
+		return nil, err
+	}
+	return &openatOp{
+		dirfd:    sqe.Fd,
+		pathname: pathname,
+		flags:    sqe.OpFlags,
+		mode:     sqe.Len,
+	}, nil
+}
+
+// issue implements operation.issue.
+func (op *openatOp) issue(ctx context.Context, r *request) (int32, error) {
+	rpop, umask, err := r.getPathOperation(op.dirfd, fspath.Parse(op.pathname), false /* allowEmptyPath */, op.flags&linux.O_NOFOLLOW == 0)
+	if err != nil {
+		return 0, err
+	}
+	defer rpop.Release(ctx)
+
+	file, err := r.t.Kernel().VFS().OpenAt(ctx, r.t.Credentials(), &rpop.pop, &vfs.OpenOptions{
+		Flags: op.flags | linux.O_LARGEFILE,
+		Mode:  linux.FileMode(uint(op.mode) & (0777 | linux.S_ISUID | linux.S_ISGID | linux.S_ISVTX) &^ umask),
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer file.DecRef(ctx)
+
+	fd, err := r.fdTable.NewFD(ctx, 0, file, kernel.FDFlags{
+		CloseOnExec: op.flags&linux.O_CLOEXEC != 0,
+	})
+	return fd, err
+}
+
+// statxOp implements IORING_OP_STATX.
+//
+// +stateify savable
+type statxOp struct {
+	noopRelease
+
+	dirfd     int32
+	pathname  string
+	flags     uint32
+	mask      uint32
+	statxAddr hostarch.Addr
+}
+
+func prepStatx(s *submitter, r *request, sqe *linux.IOUringSqe) (operation, error) {
+	if sqe.IoPrio != 0 || sqe.BufIndexOrGroup != 0 {
+		return nil, linuxerr.EINVAL
+	}
+	if sqe.Flags&linux.IOSQE_FIXED_FILE != 0 {
+		return nil, linuxerr.EBADF
+	}
+
+	// gVisor does not yet support automount, so AT_NO_AUTOMOUNT flag is a
+	// no-op. See syscalls/linux.Statx.
+	flags := sqe.OpFlags &^ linux.AT_NO_AUTOMOUNT
+	if flags&^(linux.AT_EMPTY_PATH|linux.AT_SYMLINK_NOFOLLOW|linux.AT_STATX_SYNC_TYPE) != 0 {
+		return nil, linuxerr.EINVAL
+	}
+	// Make sure that only one sync type option is set.
+	syncType := flags & linux.AT_STATX_SYNC_TYPE
+	if syncType != 0 && !bits.IsPowerOfTwo32(syncType) {
+		return nil, linuxerr.EINVAL
+	}
+	mask := sqe.Len
+	if mask&linux.STATX__RESERVED != 0 {
+		return nil, linuxerr.EINVAL
+	}
+
+	pathname, err := copyInPath(s, hostarch.Addr(sqe.AddrOrSpliceOff))
+	if err != nil {
+		return nil, err
+	}
+	return &statxOp{
+		dirfd:     sqe.Fd,
+		pathname:  pathname,
+		flags:     flags,
+		mask:      mask,
+		statxAddr: hostarch.Addr(sqe.OffOrAddrOrCmdOp),
+	}, nil
+}
+
+// issue implements operation.issue.
+func (op *statxOp) issue(ctx context.Context, r *request) (int32, error) {
+	opts := vfs.StatOptions{
+		Mask: op.mask,
+		Sync: op.flags & linux.AT_STATX_SYNC_TYPE,
+	}
+
+	var (
+		statx linux.Statx
+		err   error
+	)
+	path := fspath.Parse(op.pathname)
+	if !path.Absolute && !path.HasComponents() && op.flags&linux.AT_EMPTY_PATH != 0 && op.dirfd != linux.AT_FDCWD {
+		// Use FileDescription.Stat() for statx(fd, ""), since it may be able
+		// to use opened file state to expedite the Stat.
+		dirfile, _ := r.fdTable.Get(op.dirfd)
+		if dirfile == nil {
+			return 0, linuxerr.EBADF
+		}
+		statx, err = dirfile.Stat(ctx, opts)
+		dirfile.DecRef(ctx)
+	} else {
+		var rpop requestPathOperation
+		rpop, _, err = r.getPathOperation(op.dirfd, path, op.flags&linux.AT_EMPTY_PATH != 0, op.flags&linux.AT_SYMLINK_NOFOLLOW == 0)
+		if err != nil {
+			return 0, err
+		}
+		statx, err = r.t.Kernel().VFS().StatAt(ctx, r.t.Credentials(), &rpop.pop, &opts)
+		rpop.Release(ctx)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	userns := r.t.UserNamespace()
+	statx.UID = uint32(auth.KUID(statx.UID).In(userns).OrOverflow())
+	statx.GID = uint32(auth.KGID(statx.GID).In(userns).OrOverflow())
+	if _, err := statx.CopyOut(&memoryCopyContext{ctx: ctx, mm: r.mm}, op.statxAddr); err != nil {
+		return 0, err
+	}
+	return 0, nil
+}
+
+// closeOp implements IORING_OP_CLOSE.
+//
+// +stateify savable
+type closeOp struct {
+	noopRelease
+
+	fd int32
+}
+
+func prepClose(s *submitter, r *request, sqe *linux.IOUringSqe) (operation, error) {
+	if sqe.IoPrio != 0 || sqe.OffOrAddrOrCmdOp != 0 || sqe.AddrOrSpliceOff != 0 || sqe.Len != 0 || sqe.OpFlags != 0 || sqe.BufIndexOrGroup != 0 {
+		return nil, linuxerr.EINVAL
+	}
+	if sqe.Flags&linux.IOSQE_FIXED_FILE != 0 {
+		return nil, linuxerr.EINVAL
+	}
+	return &closeOp{fd: sqe.Fd}, nil
+}
+
+// issue implements operation.issue.
+func (op *closeOp) issue(ctx context.Context, r *request) (int32, error) {
+	// Like Linux, don't allow closing io_uring files through io_uring.
+	file, _ := r.fdTable.Get(op.fd)
+	if file == nil {
+		return 0, linuxerr.EBADF
+	}
+	_, isRing := file.Impl().(*FileDescription)
+	file.DecRef(ctx)
+	if isRing {
+		return 0, linuxerr.EBADF
+	}
+
+	// Note that Remove provides a reference on the file that we may use to
+	// flush.
+	file = r.fdTable.Remove(ctx, op.fd)
+	if file == nil {
+		return 0, linuxerr.EBADF
+	}
+	defer file.DecRef(ctx)
+	return 0, file.OnClose(ctx)
+}
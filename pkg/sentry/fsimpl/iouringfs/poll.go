@@ -0,0 +1,84 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package iouringfs
+
+import (
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/waiter"
+)
+
+// pollAddOp implements IORING_OP_POLL_ADD. Multishot polls aren't supported.
+//
+// +stateify savable
+type pollAddOp struct {
+	noopRelease
+}
+
+func prepPollAdd(s *submitter, r *request, sqe *linux.IOUringSqe) (operation, error) {
+	if sqe.IoPrio != 0 || sqe.OffOrAddrOrCmdOp != 0 || sqe.AddrOrSpliceOff != 0 || sqe.BufIndexOrGroup != 0 || sqe.Len != 0 {
+		return nil, linuxerr.EINVAL
+	}
+	if err := r.fd.getFile(s, r, sqe); err != nil {
+		return nil, err
+	}
+	// Like poll(2), errors and hangups are always reported.
+	r.events = waiter.EventMaskFromLinux(sqe.OpFlags&0xffff) | waiter.EventErr | waiter.EventHUp
+	return &pollAddOp{}, nil
+}
+
+// issue implements operation.issue.
+func (*pollAddOp) issue(ctx context.Context, r *request) (int32, error) {
+	if ready := r.file.Readiness(r.events) & r.events; ready != 0 {
+		return int32(ready.ToLinux()), nil
+	}
+	return 0, linuxerr.ErrWouldBlock
+}
+
+// pollRemoveOp implements IORING_OP_POLL_REMOVE.
+//
+// +stateify savable
+type pollRemoveOp struct {
+	noopRelease
+
+	// userData identifies the IORING_OP_POLL_ADD request to remove.
+	userData uint64
+}
+
+func prepPollRemove(s *submitter, r *request, sqe *linux.IOUringSqe) (operation, error) {
+	if sqe.IoPrio != 0 || sqe.OffOrAddrOrCmdOp != 0 || sqe.BufIndexOrGroup != 0 || sqe.Len != 0 || sqe.OpFlags != 0 {
+		return nil, linuxerr.EINVAL
+	}
+	return &pollRemoveOp{userData: sqe.AddrOrSpliceOff}, nil
+}
+
+// issue implements operation.issue.
+func (op *pollRemoveOp) issue(ctx context.Context, r *request) (int32, error) {
+	fd := r.fd
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+	for other := range fd.inflight {
+		if other.opcode != linux.IORING_OP_POLL_ADD || other.userData != op.userData {
+			continue
+		}
+		if other.done || other.finishing {
+			return 0, linuxerr.EALREADY
+		}
+		fd.cancelLocked(other, -int32(linuxerr.ECANCELED.Errno()))
+		return 0, nil
+	}
+	return 0, linuxerr.ENOENT
+}
@@ -0,0 +1,372 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package iouringfs
+
+import (
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/sentry/fsimpl/eventfd"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+)
+
+// maxFixedBufferSize is the maximum size of a registered buffer. See Linux,
+// io_uring/rsrc.c:io_buffer_validate().
+const maxFixedBufferSize = 1 << 30
+
+// Register implements io_uring_register(2) for the given opcode. See Linux,
+// io_uring/register.c:__io_uring_register().
+func (fd *FileDescription) Register(t *kernel.Task, opcode uint32, arg hostarch.Addr, nrArgs uint32) (int, error) {
+	switch opcode {
+	case linux.IORING_REGISTER_BUFFERS:
+		return 0, fd.registerBuffers(t, arg, nrArgs)
+	case linux.IORING_UNREGISTER_BUFFERS:
+		if arg != 0 || nrArgs != 0 {
+			return 0, linuxerr.EINVAL
+		}
+		return 0, fd.unregisterBuffers()
+	case linux.IORING_REGISTER_FILES:
+		return 0, fd.registerFiles(t, arg, nrArgs)
+	case linux.IORING_UNREGISTER_FILES:
+		if arg != 0 || nrArgs != 0 {
+			return 0, linuxerr.EINVAL
+		}
+		return 0, fd.unregisterFiles(t)
+	case linux.IORING_REGISTER_FILES_UPDATE:
+		return fd.updateFiles(t, arg, nrArgs)
+	case linux.IORING_REGISTER_EVENTFD, linux.IORING_REGISTER_EVENTFD_ASYNC:
+		if nrArgs != 1 {
+			return 0, linuxerr.EINVAL
+		}
+		return 0, fd.registerEventfd(t, arg, opcode == linux.IORING_REGISTER_EVENTFD_ASYNC)
+	case linux.IORING_UNREGISTER_EVENTFD:
+		if arg != 0 || nrArgs != 0 {
+			return 0, linuxerr.EINVAL
+		}
+		return 0, fd.unregisterEventfd(t)
+	case linux.IORING_REGISTER_PROBE:
+		return 0, fd.probe(t, arg, nrArgs)
+	default:
+		return 0, linuxerr.EINVAL
+	}
+}
+
+// registerBuffers implements IORING_REGISTER_BUFFERS.
+//
+// Unlike Linux, registered buffers aren't pinned: they are only checked to be
+// valid address ranges, and are accessed through the address space of the
+// task that submits IORING_OP_READ_FIXED and IORING_OP_WRITE_FIXED requests.
+func (fd *FileDescription) registerBuffers(t *kernel.Task, arg hostarch.Addr, nrArgs uint32) error {
+	const iovecSize = 16
+	if nrArgs == 0 || nrArgs > linux.IORING_MAX_REG_BUFFERS {
+		return linuxerr.EINVAL
+	}
+	buf := make([]byte, nrArgs*iovecSize)
+	if _, err := t.CopyInBytes(arg, buf); err != nil {
+		return err
+	}
+	bufs := make([]hostarch.AddrRange, nrArgs)
+	for i := range bufs {
+		b := buf[i*iovecSize:]
+		base := hostarch.Addr(hostarch.ByteOrder.Uint64(b[0:8]))
+		length := hostarch.ByteOrder.Uint64(b[8:16])
+		if length == 0 || length > maxFixedBufferSize {
+			return linuxerr.EFAULT
+		}
+		ar, ok := t.MemoryManager().CheckIORange(base, int64(length))
+		if !ok {
+			return linuxerr.EFAULT
+		}
+		bufs[i] = ar
+	}
+
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+	if fd.fixedBufs != nil {
+		return linuxerr.EBUSY
+	}
+	fd.fixedBufs = bufs
+	return nil
+}
+
+// unregisterBuffers implements IORING_UNREGISTER_BUFFERS.
+func (fd *FileDescription) unregisterBuffers() error {
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+	if fd.fixedBufs == nil {
+		return linuxerr.ENXIO
+	}
+	fd.fixedBufs = nil
+	return nil
+}
+
+// fixedBufferRange returns the address range [addr, addr+length), after
+// checking that it is contained in the registered buffer at index idx.
+func (fd *FileDescription) fixedBufferRange(idx uint16, addr hostarch.Addr, length uint32) (hostarch.AddrRange, error) {
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+	if int(idx) >= len(fd.fixedBufs) {
+		return hostarch.AddrRange{}, linuxerr.EFAULT
+	}
+	end, ok := addr.AddLength(uint64(length))
+	if !ok {
+		return hostarch.AddrRange{}, linuxerr.EFAULT
+	}
+	ar := hostarch.AddrRange{Start: addr, End: end}
+	if !fd.fixedBufs[idx].IsSupersetOf(ar) {
+		return hostarch.AddrRange{}, linuxerr.EFAULT
+	}
+	return ar, nil
+}
+
+// copyInFDs copies in an array of n file descriptors at addr.
+func copyInFDs(t *kernel.Task, addr hostarch.Addr, n uint32) ([]int32, error) {
+	const fdSize = 4
+	buf := make([]byte, n*fdSize)
+	if _, err := t.CopyInBytes(addr, buf); err != nil {
+		return nil, err
+	}
+	fds := make([]int32, n)
+	for i := range fds {
+		fds[i] = int32(hostarch.ByteOrder.Uint32(buf[i*fdSize:]))
+	}
+	return fds, nil
+}
+
+// getFixedFile returns a reference on the file to register for fd.
+func getFixedFile(t *kernel.Task, fd int32) (*vfs.FileDescription, error) {
+	file := t.GetFile(fd)
+	if file == nil {
+		return nil, linuxerr.EBADF
+	}
+	if _, ok := file.Impl().(*FileDescription); ok {
+		// Registering io_uring files could create reference cycles.
+		file.DecRef(t)
+		return nil, linuxerr.EBADF
+	}
+	return file, nil
+}
+
+// registerFiles implements IORING_REGISTER_FILES. A file descriptor of -1
+// leaves an empty slot, which may be filled by IORING_REGISTER_FILES_UPDATE.
+func (fd *FileDescription) registerFiles(t *kernel.Task, arg hostarch.Addr, nrArgs uint32) error {
+	if nrArgs == 0 || nrArgs > linux.IORING_MAX_FIXED_FILES {
+		return linuxerr.EINVAL
+	}
+	fds, err := copyInFDs(t, arg, nrArgs)
+	if err != nil {
+		return err
+	}
+	files := make([]*vfs.FileDescription, nrArgs)
+	for i, fdNum := range fds {
+		if fdNum == -1 {
+			continue
+		}
+		if files[i], err = getFixedFile(t, fdNum); err != nil {
+			decRefAll(t, files)
+			return err
+		}
+	}
+
+	fd.mu.Lock()
+	if fd.fixedFiles != nil {
+		fd.mu.Unlock()
+		decRefAll(t, files)
+		return linuxerr.EBUSY
+	}
+	fd.fixedFiles = files
+	fd.mu.Unlock()
+	return nil
+}
+
+// unregisterFiles implements IORING_UNREGISTER_FILES.
+func (fd *FileDescription) unregisterFiles(ctx context.Context) error {
+	fd.mu.Lock()
+	files := fd.fixedFiles
+	fd.fixedFiles = nil
+	fd.mu.Unlock()
+	if files == nil {
+		return linuxerr.ENXIO
+	}
+	decRefAll(ctx, files)
+	return nil
+}
+
+// updateFiles implements IORING_REGISTER_FILES_UPDATE. It returns the number of
+// updated slots.
+func (fd *FileDescription) updateFiles(t *kernel.Task, arg hostarch.Addr, nrArgs uint32) (int, error) {
+	var up linux.IOUringFilesUpdate
+	if _, err := up.CopyIn(t, arg); err != nil {
+		return 0, err
+	}
+	if nrArgs == 0 {
+		return 0, linuxerr.EINVAL
+	}
+	fds, err := copyInFDs(t, hostarch.Addr(up.Fds), nrArgs)
+	if err != nil {
+		return 0, err
+	}
+
+	fd.mu.Lock()
+	if fd.fixedFiles == nil {
+		fd.mu.Unlock()
+		return 0, linuxerr.ENXIO
+	}
+	if uint64(up.Offset)+uint64(nrArgs) > uint64(len(fd.fixedFiles)) {
+		fd.mu.Unlock()
+		return 0, linuxerr.EINVAL
+	}
+	fd.mu.Unlock()
+
+	var (
+		done int
+		old  []*vfs.FileDescription
+	)
+	for i, fdNum := range fds {
+		if fdNum == linux.IORING_REGISTER_FILES_SKIP {
+			done++
+			continue
+		}
+		var file *vfs.FileDescription
+		if fdNum != -1 {
+			if file, err = getFixedFile(t, fdNum); err != nil {
+				break
+			}
+		}
+		fd.mu.Lock()
+		if fd.fixedFiles == nil {
+			// Raced with IORING_UNREGISTER_FILES.
+			fd.mu.Unlock()
+			if file != nil {
+				file.DecRef(t)
+			}
+			err = linuxerr.ENXIO
+			break
+		}
+		slot := &fd.fixedFiles[up.Offset+uint32(i)]
+		if *slot != nil {
+			old = append(old, *slot)
+		}
+		*slot = file
+		fd.mu.Unlock()
+		done++
+	}
+	decRefAll(t, old)
+	if done == 0 && err != nil {
+		return 0, err
+	}
+	return done, nil
+}
+
+// registerEventfd implements IORING_REGISTER_EVENTFD and
+// IORING_REGISTER_EVENTFD_ASYNC.
+func (fd *FileDescription) registerEventfd(t *kernel.Task, arg hostarch.Addr, async bool) error {
+	fds, err := copyInFDs(t, arg, 1)
+	if err != nil {
+		return err
+	}
+	file := t.GetFile(fds[0])
+	if file == nil {
+		return linuxerr.EBADF
+	}
+	if _, ok := file.Impl().(*eventfd.EventFileDescription); !ok {
+		file.DecRef(t)
+		return linuxerr.EINVAL
+	}
+
+	fd.mu.Lock()
+	if fd.eventfd != nil {
+		fd.mu.Unlock()
+		file.DecRef(t)
+		return linuxerr.EBUSY
+	}
+	fd.eventfd = file
+	fd.eventfdAsync = async
+	fd.mu.Unlock()
+	return nil
+}
+
+// unregisterEventfd implements IORING_UNREGISTER_EVENTFD.
+func (fd *FileDescription) unregisterEventfd(ctx context.Context) error {
+	fd.mu.Lock()
+	file := fd.eventfd
+	fd.eventfd = nil
+	fd.mu.Unlock()
+	if file == nil {
+		return linuxerr.ENXIO
+	}
+	file.DecRef(ctx)
+	return nil
+}
+
+// probe implements IORING_REGISTER_PROBE.
+func (fd *FileDescription) probe(t *kernel.Task, arg hostarch.Addr, nrArgs uint32) error {
+	if nrArgs > linux.IORING_OP_LAST {
+		nrArgs = linux.IORING_OP_LAST
+	}
+	var p linux.IOUringProbe
+	ops := make([]linux.IOUringProbeOp, nrArgs)
+	size := p.SizeBytes() + len(ops)*(*linux.IOUringProbeOp)(nil).SizeBytes()
+	buf := make([]byte, size)
+	if _, err := t.CopyInBytes(arg, buf); err != nil {
+		return err
+	}
+	// The probe structure must be zeroed by userspace.
+	for _, b := range buf {
+		if b != 0 {
+			return linuxerr.EINVAL
+		}
+	}
+
+	p.LastOp = linux.IORING_OP_LAST - 1
+	p.OpsLen = uint8(nrArgs)
+	for i := range ops {
+		ops[i].Op = uint8(i)
+		if opSupported(uint8(i)) {
+			ops[i].Flags = linux.IO_URING_OP_SUPPORTED
+		}
+	}
+	if _, err := p.CopyOut(t, arg); err != nil {
+		return err
+	}
+	_, err := linux.CopyIOUringProbeOpSliceOut(t, arg+hostarch.Addr(p.SizeBytes()), ops)
+	return err
+}
+
+// unregisterAll releases all resources registered with io_uring_register(2).
+func (fd *FileDescription) unregisterAll(ctx context.Context) {
+	fd.mu.Lock()
+	files := fd.fixedFiles
+	efd := fd.eventfd
+	fd.fixedBufs = nil
+	fd.fixedFiles = nil
+	fd.eventfd = nil
+	fd.mu.Unlock()
+	decRefAll(ctx, files)
+	if efd != nil {
+		efd.DecRef(ctx)
+	}
+}
+
+// decRefAll drops a reference on all non-nil files.
+func decRefAll(ctx context.Context, files []*vfs.FileDescription) {
+	for _, file := range files {
+		if file != nil {
+			file.DecRef(ctx)
+		}
+	}
+}
@@ -0,0 +1,705 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package iouringfs
+
+import (
+	"errors"
+	"io"
+	"math"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/mm"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/usermem"
+	"gvisor.dev/gvisor/pkg/waiter"
+)
+
+// supportedSQEFlags are the IOSQE_* flags accepted in IOUringSqe.Flags.
+// IOSQE_ASYNC is accepted as a hint, but requests are always issued inline
+// first.
+const supportedSQEFlags = linux.IOSQE_FIXED_FILE | linux.IOSQE_IO_DRAIN | linux.IOSQE_IO_LINK |
+	linux.IOSQE_IO_HARDLINK | linux.IOSQE_ASYNC | linux.IOSQE_CQE_SKIP_SUCCESS
+
+// errInProgress is returned by operation.issue for operations that complete
+// asynchronously, without waiting for file readiness.
+var errInProgress = errors.New("io_uring operation in progress")
+
+// operation is the opcode-specific part of a request.
+type operation interface {
+	// issue performs the operation without blocking, and returns the result
+	// to post in the request's CQE.
+	//
+	// If the operation can't make progress, issue returns
+	// linuxerr.ErrWouldBlock and the request is retried once r.file is ready
+	// for r.events. If the operation completes asynchronously, issue returns
+	// errInProgress and the request is later completed through
+	// FileDescription.cancelLocked.
+	issue(ctx context.Context, r *request) (int32, error)
+
+	// release releases resources held by the operation.
+	release(ctx context.Context)
+}
+
+// noopRelease implements operation.release for operations that don't hold
+// resources.
+type noopRelease struct{}
+
+func (noopRelease) release(context.Context) {}
+
+// prepFunc validates sqe and returns the operation of r. Arguments in user
+// memory are copied in by prepFunc, such that userspace may reuse the SQE and
+// the structures it points to once the SQE has been consumed.
+type prepFunc func(s *submitter, r *request, sqe *linux.IOUringSqe) (operation, error)
+
+// opTable maps opcodes to prepFuncs. Opcodes without an entry are not
+// supported and complete with EINVAL.
+var opTable = [linux.IORING_OP_LAST]prepFunc{
+	linux.IORING_OP_NOP:          prepNop,
+	linux.IORING_OP_READV:        prepRW,
+	linux.IORING_OP_WRITEV:       prepRW,
+	linux.IORING_OP_FSYNC:        prepFsync,
+	linux.IORING_OP_READ_FIXED:   prepRW,
+	linux.IORING_OP_WRITE_FIXED:  prepRW,
+	linux.IORING_OP_POLL_ADD:     prepPollAdd,
+	linux.IORING_OP_POLL_REMOVE:  prepPollRemove,
+	linux.IORING_OP_SENDMSG:      prepSendMsg,
+	linux.IORING_OP_RECVMSG:      prepRecvMsg,
+	linux.IORING_OP_TIMEOUT:      prepTimeout,
+	linux.IORING_OP_ACCEPT:       prepAccept,
+	linux.IORING_OP_LINK_TIMEOUT: prepTimeout,
+	linux.IORING_OP_CONNECT:      prepConnect,
+	linux.IORING_OP_OPENAT:       prepOpenat,
+	linux.IORING_OP_CLOSE:        prepClose,
+	linux.IORING_OP_STATX:        prepStatx,
+	linux.IORING_OP_READ:         prepRW,
+	linux.IORING_OP_WRITE:        prepRW,
+	linux.IORING_OP_SEND:         prepSend,
+	linux.IORING_OP_RECV:         prepRecv,
+}
+
+// opSupported returns whether opcode is supported.
+func opSupported(opcode uint8) bool {
+	return int(opcode) < len(opTable) && opTable[opcode] != nil
+}
+
+// submitter describes the context that SQEs are consumed in.
+type submitter struct {
+	// t is the task on whose behalf requests are issued.
+	t *kernel.Task
+
+	// ctx is the context to use for copying in request arguments. It is t if
+	// onTaskGoroutine is true, and t.AsyncContext() otherwise.
+	ctx             context.Context
+	onTaskGoroutine bool
+
+	// mm and fdTable are the address space and file table that requests
+	// refer to.
+	mm      *mm.MemoryManager
+	fdTable *kernel.FDTable
+}
+
+// interrupted returns whether the submitter should stop consuming SQEs
+// because of a pending signal.
+func (s *submitter) interrupted() bool {
+	return s.onTaskGoroutine && s.t.Interrupted()
+}
+
+// copyContext returns a marshal.CopyContext for s's address space.
+func (s *submitter) copyContext() *memoryCopyContext {
+	return &memoryCopyContext{ctx: s.ctx, mm: s.mm}
+}
+
+// copyInIovecs copies in the array of iovcnt struct iovecs at addr. Unlike
+// Task.CopyInIovecs, it may be called from any goroutine. Like Linux's
+// lib/iov_iter.c:import_iovec(), the combined length is truncated to
+// MAX_RW_COUNT.
+func (s *submitter) copyInIovecs(addr hostarch.Addr, iovcnt int) ([]hostarch.AddrRange, error) {
+	const iovecSize = 16
+	if iovcnt < 0 || iovcnt > linux.UIO_MAXIOV {
+		return nil, linuxerr.EINVAL
+	}
+	if _, ok := addr.AddLength(uint64(iovcnt) * iovecSize); !ok {
+		return nil, linuxerr.EFAULT
+	}
+	buf := make([]byte, iovcnt*iovecSize)
+	if _, err := s.mm.CopyIn(s.ctx, addr, buf, usermem.IOOpts{}); err != nil {
+		return nil, err
+	}
+	ars := make([]hostarch.AddrRange, 0, iovcnt)
+	var total uint64
+	for i := 0; i < iovcnt; i++ {
+		b := buf[i*iovecSize:]
+		base := hostarch.Addr(hostarch.ByteOrder.Uint64(b[0:8]))
+		length := hostarch.ByteOrder.Uint64(b[8:16])
+		if length > math.MaxInt64 {
+			return nil, linuxerr.EINVAL
+		}
+		ar, ok := s.mm.CheckIORange(base, int64(length))
+		if !ok {
+			return nil, linuxerr.EFAULT
+		}
+		if rem := uint64(kernel.MAX_RW_COUNT) - total; rem < length {
+			ar.End -= hostarch.Addr(length - rem)
+			length = rem
+		}
+		total += length
+		ars = append(ars, ar)
+	}
+	return ars, nil
+}
+
+// singleRange returns the address range [addr, addr+length), truncated to
+// MAX_RW_COUNT.
+func (s *submitter) singleRange(addr hostarch.Addr, length uint32) ([]hostarch.AddrRange, error) {
+	n := int64(length)
+	if n > int64(kernel.MAX_RW_COUNT) {
+		n = int64(kernel.MAX_RW_COUNT)
+	}
+	ar, ok := s.mm.CheckIORange(addr, n)
+	if !ok {
+		return nil, linuxerr.EFAULT
+	}
+	return []hostarch.AddrRange{ar}, nil
+}
+
+// memoryCopyContext implements marshal.CopyContext for an address space that
+// isn't necessarily active, from any goroutine.
+type memoryCopyContext struct {
+	ctx context.Context
+	mm  *mm.MemoryManager
+}
+
+// CopyScratchBuffer implements marshal.CopyContext.CopyScratchBuffer.
+func (cc *memoryCopyContext) CopyScratchBuffer(size int) []byte {
+	return make([]byte, size)
+}
+
+// CopyInBytes implements marshal.CopyContext.CopyInBytes.
+func (cc *memoryCopyContext) CopyInBytes(addr hostarch.Addr, dst []byte) (int, error) {
+	return cc.mm.CopyIn(cc.ctx, addr, dst, usermem.IOOpts{})
+}
+
+// CopyOutBytes implements marshal.CopyContext.CopyOutBytes.
+func (cc *memoryCopyContext) CopyOutBytes(addr hostarch.Addr, src []byte) (int, error) {
+	return cc.mm.CopyOut(cc.ctx, addr, src, usermem.IOOpts{})
+}
+
+// request is a submitted SQE that hasn't completed yet.
+//
+// +stateify savable
+type request struct {
+	fd *FileDescription
+
+	// t is the task on whose behalf the request is issued.
+	t *kernel.Task
+
+	// mm is the address space that the request's buffers refer to, and
+	// fdTable is the file table used by operations that look up or install
+	// file descriptors. The request holds a user of mm and a reference on
+	// fdTable until it completes.
+	mm      *mm.MemoryManager
+	fdTable *kernel.FDTable
+
+	// userData, opcode and flags are copied from the SQE.
+	userData uint64
+	opcode   uint8
+	flags    uint8
+
+	// op is the opcode-specific state of the request. If the request couldn't
+	// be prepared, op is nil and the request completes with -errno.
+	op    operation
+	errno int32
+
+	// file is the file the request operates on, if any. The request holds a
+	// reference on file.
+	file *vfs.FileDescription
+
+	// events are the events that the request waits for on file when the
+	// operation can't make progress. If nowait is true, the request completes
+	// with EAGAIN instead of waiting.
+	events waiter.EventMask
+	nowait bool
+
+	// link is the next request in the link chain. linkTimeout is the
+	// IORING_OP_LINK_TIMEOUT request that guards this request, and target is
+	// the request guarded by this IORING_OP_LINK_TIMEOUT request.
+	link        *request
+	linkTimeout *request
+	target      *request
+
+	// waiter is registered with file while the request waits for readiness.
+	// registered is only accessed by the goroutine running the request.
+	waiter     waiter.Entry
+	registered bool
+
+	// The following fields are protected by fd.mu.
+
+	// started indicates that the request has been run.
+	started bool
+
+	// pending indicates that the request is waiting for an event, and isn't
+	// being run by any goroutine.
+	pending bool
+
+	// queued indicates that the request is in fd.ready.
+	queued bool
+
+	// async indicates that the request has been run by the worker goroutine.
+	async bool
+
+	// finishing indicates that the request must complete with finishRes the
+	// next time it's run, rather than being issued again.
+	finishing bool
+	finishRes int32
+
+	// done indicates that the request has completed.
+	done bool
+}
+
+// linked returns whether the next SQE is linked to r.
+func (r *request) linked() bool {
+	return r.flags&(linux.IOSQE_IO_LINK|linux.IOSQE_IO_HARDLINK) != 0
+}
+
+// chainLen returns the number of requests in the link chain headed by r,
+// including link timeouts.
+func chainLen(r *request) int {
+	n := 0
+	for ; r != nil; r = r.link {
+		n++
+		if r.linkTimeout != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// newRequest prepares a request from sqe on behalf of s. Errors are reported
+// through the request's CQE.
+func (fd *FileDescription) newRequest(s *submitter, sqe *linux.IOUringSqe) *request {
+	r := &request{
+		fd:       fd,
+		t:        s.t,
+		userData: sqe.UserData,
+		opcode:   sqe.Opcode,
+		flags:    sqe.Flags,
+	}
+	fd.mu.Lock()
+	fd.inflight[r] = struct{}{}
+	fd.mu.Unlock()
+
+	op, err := fd.prepare(s, r, sqe)
+	if err != nil {
+		r.errno = int32(kernel.ExtractErrno(err, -1))
+		return r
+	}
+	r.op = op
+	return r
+}
+
+func (fd *FileDescription) prepare(s *submitter, r *request, sqe *linux.IOUringSqe) (operation, error) {
+	if sqe.Flags&^supportedSQEFlags != 0 || !opSupported(sqe.Opcode) {
+		return nil, linuxerr.EINVAL
+	}
+	if !s.mm.IncUsers() {
+		return nil, linuxerr.EFAULT
+	}
+	r.mm = s.mm
+	s.fdTable.IncRef()
+	r.fdTable = s.fdTable
+	return opTable[sqe.Opcode](s, r, sqe)
+}
+
+// getFile looks up the file that sqe refers to and stores it in r.file.
+func (fd *FileDescription) getFile(s *submitter, r *request, sqe *linux.IOUringSqe) error {
+	// Check that a file descriptor is valid.
+	if sqe.Fd < 0 {
+		return linuxerr.EBADF
+	}
+	if sqe.Flags&linux.IOSQE_FIXED_FILE != 0 {
+		fd.mu.Lock()
+		defer fd.mu.Unlock()
+		if int(sqe.Fd) >= len(fd.fixedFiles) || fd.fixedFiles[sqe.Fd] == nil {
+			return linuxerr.EBADF
+		}
+		r.file = fd.fixedFiles[sqe.Fd]
+		r.file.IncRef()
+		return nil
+	}
+	file, _ := s.fdTable.Get(sqe.Fd)
+	if file == nil {
+		return linuxerr.EBADF
+	}
+	r.file = file
+	return nil
+}
+
+// startChain starts the link chain headed by r, unless it must be deferred
+// due to IOSQE_IO_DRAIN.
+func (fd *FileDescription) startChain(ctx context.Context, r *request) {
+	fd.mu.Lock()
+	n := chainLen(r)
+	drain := r.flags&linux.IOSQE_IO_DRAIN != 0
+	if fd.draining != nil || len(fd.deferred) > 0 || (drain && len(fd.inflight) > n) {
+		fd.deferred = append(fd.deferred, r)
+		fd.deferredCount += n
+		fd.mu.Unlock()
+		return
+	}
+	if drain {
+		fd.draining = r
+	}
+	fd.mu.Unlock()
+	fd.run(ctx, r)
+}
+
+// releaseDeferredLocked queues the deferred request chains that may be started
+// now.
+//
+// Preconditions: fd.mu must be locked.
+func (fd *FileDescription) releaseDeferredLocked() {
+	for len(fd.deferred) > 0 && fd.draining == nil && !fd.closed {
+		r := fd.deferred[0]
+		drain := r.flags&linux.IOSQE_IO_DRAIN != 0
+		if drain && len(fd.inflight) > fd.deferredCount {
+			// Requests submitted before r are still in flight.
+			return
+		}
+		fd.deferred = fd.deferred[1:]
+		fd.deferredCount -= chainLen(r)
+		if drain {
+			fd.draining = r
+		}
+		fd.queueLocked(r)
+	}
+}
+
+// queueLocked queues r to be run by the worker goroutine.
+//
+// Preconditions: fd.mu must be locked.
+func (fd *FileDescription) queueLocked(r *request) {
+	if r.queued {
+		return
+	}
+	r.queued = true
+	fd.ready = append(fd.ready, r)
+	if !fd.workerRunning {
+		fd.workerRunning = true
+		r.t.QueueAIO(func(context.Context) { fd.runWorker() })
+	}
+}
+
+// runWorker runs the requests in fd.ready until there are none left.
+func (fd *FileDescription) runWorker() {
+	for {
+		fd.mu.Lock()
+		if len(fd.ready) == 0 || fd.closed {
+			fd.workerRunning = false
+			fd.mu.Unlock()
+			return
+		}
+		r := fd.ready[0]
+		fd.ready[0] = nil
+		fd.ready = fd.ready[1:]
+		r.queued = false
+		r.async = true
+		fd.mu.Unlock()
+
+		fd.run(r.t.AsyncContext(), r)
+	}
+}
+
+// run runs r, followed by the requests linked to it, until a request has to
+// wait.
+func (fd *FileDescription) run(ctx context.Context, r *request) {
+	for r != nil {
+		r = fd.runOne(ctx, r)
+	}
+}
+
+// runOne runs r. If r completes, runOne returns the next request of its link
+// chain that should be run.
+func (fd *FileDescription) runOne(ctx context.Context, r *request) *request {
+	for {
+		if r.registered {
+			r.file.EventUnregister(&r.waiter)
+			r.registered = false
+		}
+
+		fd.mu.Lock()
+		if r.done {
+			fd.mu.Unlock()
+			return nil
+		}
+		r.started = true
+		finishing, res := r.finishing, r.finishRes
+		fd.mu.Unlock()
+		if finishing {
+			return r.complete(ctx, res)
+		}
+		if r.op == nil {
+			return r.complete(ctx, -r.errno)
+		}
+
+		n, err := r.op.issue(ctx, r)
+		switch {
+		case err == errInProgress:
+			if r.park(ctx) {
+				return nil
+			}
+			continue
+		case linuxerr.Equals(linuxerr.ErrWouldBlock, err) && r.canWait():
+			parked, err := r.wait(ctx)
+			if err != nil {
+				return r.complete(ctx, -int32(kernel.ExtractErrno(err, -1)))
+			}
+			if parked {
+				return nil
+			}
+			continue
+		case err == io.EOF:
+			// Don't raise EOF as errno, error translation will fail. Short
+			// reads aren't failures.
+			return r.complete(ctx, n)
+		case err != nil:
+			return r.complete(ctx, -int32(kernel.ExtractErrno(err, -1)))
+		default:
+			return r.complete(ctx, n)
+		}
+	}
+}
+
+// canWait returns whether r may wait for its file to become ready, instead of
+// failing with EAGAIN. Like Linux, operations on files with O_NONBLOCK set
+// don't wait, except for IORING_OP_POLL_ADD.
+func (r *request) canWait() bool {
+	if r.file == nil || r.nowait {
+		return false
+	}
+	return r.opcode == linux.IORING_OP_POLL_ADD || r.file.StatusFlags()&linux.O_NONBLOCK == 0
+}
+
+// park marks r as pending after its operation returned errInProgress. It
+// returns false if r has been cancelled in the meantime and should be run
+// again to complete it.
+func (r *request) park(ctx context.Context) bool {
+	fd := r.fd
+	fd.mu.Lock()
+	if r.finishing || fd.closed {
+		fd.cancelLocked(r, -int32(linuxerr.ECANCELED.Errno()))
+		fd.mu.Unlock()
+		return false
+	}
+	r.pending = true
+	fd.mu.Unlock()
+	r.startLinkTimeout(ctx)
+	return true
+}
+
+// wait registers r to be retried once r.file is ready. It returns false if r
+// should be run again immediately instead.
+func (r *request) wait(ctx context.Context) (bool, error) {
+	fd := r.fd
+	r.waiter.Init(r, r.events)
+	if err := r.file.EventRegister(&r.waiter); err != nil {
+		return false, err
+	}
+	r.registered = true
+	if !r.park(ctx) {
+		return false, nil
+	}
+	if r.file.Readiness(r.events)&r.events != 0 {
+		// The file became ready before the waiter was registered. Retry now,
+		// unless r has already been queued.
+		fd.mu.Lock()
+		pending := r.pending
+		r.pending = false
+		fd.mu.Unlock()
+		if pending {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// NotifyEvent implements waiter.EventListener.NotifyEvent.
+func (r *request) NotifyEvent(waiter.EventMask) {
+	fd := r.fd
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+	if r.pending && !fd.closed {
+		r.pending = false
+		fd.queueLocked(r)
+	}
+}
+
+// startLinkTimeout arms the link timeout guarding r, if any. Link timeouts are
+// only armed once the guarded request has to wait.
+func (r *request) startLinkTimeout(ctx context.Context) {
+	lt := r.linkTimeout
+	if lt == nil {
+		return
+	}
+	fd := r.fd
+	fd.mu.Lock()
+	started := lt.started
+	fd.mu.Unlock()
+	if !started {
+		fd.run(ctx, lt)
+	}
+}
+
+// cancelLocked arranges for r to complete with res, unless it completes
+// first.
+//
+// Preconditions: fd.mu must be locked.
+func (fd *FileDescription) cancelLocked(r *request, res int32) {
+	if r.done || r.finishing {
+		return
+	}
+	r.finishing = true
+	r.finishRes = res
+	if r.pending {
+		r.pending = false
+		fd.queueLocked(r)
+	}
+	// Otherwise, r is either queued, being run, or hasn't been started yet.
+	// In all cases, r.finishing will be noticed when r is run.
+}
+
+// complete posts the CQE of r and releases its resources. It returns the next
+// request of r's link chain that should be run.
+func (r *request) complete(ctx context.Context, res int32) *request {
+	fd := r.fd
+	canceled := -int32(linuxerr.ECANCELED.Errno())
+
+	fd.mu.Lock()
+	if r.done {
+		fd.mu.Unlock()
+		return nil
+	}
+	r.done = true
+	lt := r.linkTimeout
+	completeLT := false
+	if lt != nil {
+		if lt.started {
+			fd.cancelLocked(lt, canceled)
+		} else {
+			lt.started = true
+			completeLT = true
+		}
+	}
+	fd.mu.Unlock()
+
+	if completeLT {
+		lt.complete(ctx, canceled)
+	}
+	r.release(ctx)
+	if res < 0 || r.flags&linux.IOSQE_CQE_SKIP_SUCCESS == 0 {
+		fd.postCompletion(ctx, r.userData, res, r.async)
+	}
+
+	fd.mu.Lock()
+	delete(fd.inflight, r)
+	if fd.draining == r {
+		fd.draining = nil
+	}
+	fd.removeCountTimeoutLocked(r)
+	fd.releaseDeferredLocked()
+	fd.mu.Unlock()
+
+	next := r.link
+	r.link = nil
+	if next == nil || res >= 0 || r.flags&linux.IOSQE_IO_HARDLINK != 0 {
+		return next
+	}
+	// A failed request breaks the link chain, cancelling all remaining
+	// requests.
+	for next != nil {
+		following := next.link
+		next.link = nil
+		fd.mu.Lock()
+		next.started = true
+		fd.mu.Unlock()
+		next.complete(ctx, canceled)
+		next = following
+	}
+	return nil
+}
+
+// abandon releases r after the ring has been released, without posting a
+// CQE. Requests that are being run are cancelled instead, and complete once
+// their runner notices.
+func (r *request) abandon(ctx context.Context) {
+	fd := r.fd
+	fd.mu.Lock()
+	if r.done {
+		fd.mu.Unlock()
+		return
+	}
+	if r.started && !r.pending && !r.queued {
+		fd.cancelLocked(r, -int32(linuxerr.ECANCELED.Errno()))
+		fd.mu.Unlock()
+		return
+	}
+	r.done = true
+	r.pending = false
+	r.queued = false
+	fd.mu.Unlock()
+	r.release(ctx)
+}
+
+// release releases the resources held by r.
+func (r *request) release(ctx context.Context) {
+	if r.registered {
+		r.file.EventUnregister(&r.waiter)
+		r.registered = false
+	}
+	if r.op != nil {
+		r.op.release(ctx)
+	}
+	if r.file != nil {
+		r.file.DecRef(ctx)
+		r.file = nil
+	}
+	if r.fdTable != nil {
+		r.fdTable.DecRef(ctx)
+		r.fdTable = nil
+	}
+	if r.mm != nil {
+		r.mm.DecUsers(ctx)
+		r.mm = nil
+	}
+}
+
+// nopOp implements IORING_OP_NOP.
+//
+// +stateify savable
+type nopOp struct {
+	noopRelease
+}
+
+func prepNop(s *submitter, r *request, sqe *linux.IOUringSqe) (operation, error) {
+	return &nopOp{}, nil
+}
+
+// issue implements operation.issue.
+func (*nopOp) issue(context.Context, *request) (int32, error) {
+	return 0, nil
+}
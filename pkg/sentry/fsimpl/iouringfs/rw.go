@@ -0,0 +1,175 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package iouringfs
+
+import (
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/usermem"
+	"gvisor.dev/gvisor/pkg/waiter"
+)
+
+// rwOp implements IORING_OP_READV, IORING_OP_WRITEV, IORING_OP_READ_FIXED,
+// IORING_OP_WRITE_FIXED, IORING_OP_READ and IORING_OP_WRITE.
+//
+// +stateify savable
+type rwOp struct {
+	noopRelease
+
+	write bool
+
+	// ars are the buffers to transfer data from or to, in the request's
+	// address space. The IOSequence is rebuilt each time the operation is
+	// issued.
+	ars []hostarch.AddrRange
+
+	// offset is the file offset to transfer data at, or -1 to use and update
+	// the file position.
+	offset int64
+
+	// rwFlags are the RWF_* flags of the operation.
+	rwFlags uint32
+
+	// done is the number of bytes written by previous attempts of a write that
+	// had to wait for the file to become writable.
+	done int64
+}
+
+func prepRW(s *submitter, r *request, sqe *linux.IOUringSqe) (operation, error) {
+	// ioprio should not be set for read and write operations.
+	if sqe.IoPrio != 0 {
+		return nil, linuxerr.EINVAL
+	}
+	if sqe.OpFlags&^linux.RWF_VALID != 0 {
+		return nil, linuxerr.EOPNOTSUPP
+	}
+	if err := r.fd.getFile(s, r, sqe); err != nil {
+		return nil, err
+	}
+
+	op := &rwOp{
+		offset:  int64(sqe.OffOrAddrOrCmdOp),
+		rwFlags: sqe.OpFlags,
+	}
+	addr := hostarch.Addr(sqe.AddrOrSpliceOff)
+	var err error
+	switch sqe.Opcode {
+	case linux.IORING_OP_READV, linux.IORING_OP_WRITEV:
+		op.ars, err = s.copyInIovecs(addr, int(sqe.Len))
+	case linux.IORING_OP_READ, linux.IORING_OP_WRITE:
+		op.ars, err = s.singleRange(addr, sqe.Len)
+	case linux.IORING_OP_READ_FIXED, linux.IORING_OP_WRITE_FIXED:
+		var ar hostarch.AddrRange
+		ar, err = r.fd.fixedBufferRange(sqe.BufIndexOrGroup, addr, sqe.Len)
+		op.ars = []hostarch.AddrRange{ar}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch sqe.Opcode {
+	case linux.IORING_OP_WRITEV, linux.IORING_OP_WRITE, linux.IORING_OP_WRITE_FIXED:
+		op.write = true
+		r.events = waiter.WritableEvents
+	default:
+		r.events = waiter.ReadableEvents
+	}
+	return op, nil
+}
+
+// issue implements operation.issue.
+func (op *rwOp) issue(ctx context.Context, r *request) (int32, error) {
+	seq := usermem.IOSequence{
+		IO:    r.mm,
+		Addrs: hostarch.AddrRangeSeqFromSlice(op.ars),
+	}
+	if op.write {
+		return op.issueWrite(ctx, r, seq.DropFirst64(op.done))
+	}
+
+	var (
+		n   int64
+		err error
+	)
+	opts := vfs.ReadOptions{Flags: op.rwFlags}
+	if op.offset == -1 {
+		n, err = r.file.Read(ctx, seq, opts)
+	} else {
+		n, err = r.file.PRead(ctx, seq, op.offset, opts)
+		if linuxerr.Equals(linuxerr.ESPIPE, err) {
+			// The offset is ignored for non-seekable files.
+			n, err = r.file.Read(ctx, seq, opts)
+		}
+	}
+	if n > 0 {
+		return int32(n), nil
+	}
+	return 0, err
+}
+
+func (op *rwOp) issueWrite(ctx context.Context, r *request, src usermem.IOSequence) (int32, error) {
+	var (
+		n   int64
+		err error
+	)
+	opts := vfs.WriteOptions{Flags: op.rwFlags}
+	if op.offset == -1 {
+		n, err = r.file.Write(ctx, src, opts)
+	} else {
+		n, err = r.file.PWrite(ctx, src, op.offset+op.done, opts)
+		if linuxerr.Equals(linuxerr.ESPIPE, err) {
+			n, err = r.file.Write(ctx, src, opts)
+		}
+	}
+	op.done += n
+	if linuxerr.Equals(linuxerr.ErrWouldBlock, err) && r.canWait() {
+		// Like a blocking write(2), wait until the whole buffer has been
+		// written.
+		return 0, err
+	}
+	if op.done > 0 {
+		return int32(op.done), nil
+	}
+	return 0, err
+}
+
+// fsyncOp implements IORING_OP_FSYNC.
+//
+// +stateify savable
+type fsyncOp struct {
+	noopRelease
+}
+
+func prepFsync(s *submitter, r *request, sqe *linux.IOUringSqe) (operation, error) {
+	if sqe.IoPrio != 0 || sqe.AddrOrSpliceOff != 0 || sqe.BufIndexOrGroup != 0 {
+		return nil, linuxerr.EINVAL
+	}
+	if sqe.OpFlags&^linux.IORING_FSYNC_DATASYNC != 0 {
+		return nil, linuxerr.EINVAL
+	}
+	if err := r.fd.getFile(s, r, sqe); err != nil {
+		return nil, err
+	}
+	return &fsyncOp{}, nil
+}
+
+// issue implements operation.issue.
+func (*fsyncOp) issue(ctx context.Context, r *request) (int32, error) {
+	// Like fdatasync(2), IORING_FSYNC_DATASYNC also syncs metadata.
+	return 0, r.file.Sync(ctx)
+}
@@ -0,0 +1,267 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package iouringfs
+
+import (
+	"math"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/ktime"
+	"gvisor.dev/gvisor/pkg/sentry/mm"
+)
+
+const (
+	// sqPollDefaultIdle is the time after which the SQPOLL goroutine goes to
+	// sleep when params.sq_thread_idle is 0. Linux uses one second.
+	sqPollDefaultIdle = time.Second
+
+	// sqPollMinInterval and sqPollMaxInterval bound the time the SQPOLL
+	// goroutine waits between two polls of an empty submission queue. The
+	// interval doubles on each empty poll, and is reset once SQEs are found.
+	// sqPollMaxInterval also bounds how long Kernel.Pause waits for the
+	// goroutine to notice that the kernel is paused.
+	sqPollMinInterval = 50 * time.Microsecond
+	sqPollMaxInterval = 10 * time.Millisecond
+)
+
+// sqPollState emulates the SQPOLL kernel thread of Linux with a goroutine,
+// which consumes the submission queue until it has been empty for idle. The
+// goroutine then sets IORING_SQ_NEED_WAKEUP in the SQ ring flags and exits,
+// until userspace wakes it up with IORING_ENTER_SQ_WAKEUP.
+//
+// Userspace doesn't notify the goroutine of new SQEs while it is running, so
+// the goroutine polls the submission queue with an exponential backoff. Calls
+// to io_uring_enter(2) cut the current wait short through kickC.
+//
+// The goroutine also exits when the kernel is paused, since the kernel waits
+// for asynchronous I/O goroutines to complete before saving.
+//
+// +stateify savable
+type sqPollState struct {
+	// t is the task that set up the ring. Requests are issued on its behalf,
+	// using its current address space and file table.
+	t *kernel.Task
+
+	// idle is the time the submission queue must stay empty before the
+	// goroutine exits, from params.sq_thread_idle.
+	idle time.Duration
+
+	// kickC is used to wake up the goroutine while it waits between two
+	// polls. kickC has a capacity of 1.
+	kickC chan struct{} `state:"nosave"`
+
+	// running indicates whether the SQPOLL goroutine is running. running is
+	// protected by FileDescription.mu.
+	running bool
+}
+
+// startSQPoll starts the SQPOLL goroutine for a ring set up with
+// IORING_SETUP_SQPOLL.
+func (fd *FileDescription) startSQPoll(ctx context.Context, params *linux.IOUringParams) error {
+	t := kernel.TaskFromContext(ctx)
+	if t == nil {
+		return linuxerr.EINVAL
+	}
+	idle := time.Duration(params.SqThreadIdle) * time.Millisecond
+	if idle == 0 {
+		idle = sqPollDefaultIdle
+	}
+	fd.sqPoll = &sqPollState{
+		t:     t,
+		idle:  idle,
+		kickC: make(chan struct{}, 1),
+	}
+	fd.wakeSQPoll()
+	return nil
+}
+
+// wakeSQPoll starts the SQPOLL goroutine if it isn't running.
+func (fd *FileDescription) wakeSQPoll() {
+	sp := fd.sqPoll
+	fd.mu.Lock()
+	if sp.running || fd.closed {
+		fd.mu.Unlock()
+		return
+	}
+	sp.running = true
+	fd.setSQFlagLocked(linux.IORING_SQ_NEED_WAKEUP, false)
+	fd.mu.Unlock()
+
+	// The SQPOLL goroutine holds a reference on the ring while it runs.
+	if !fd.vfsfd.TryIncRef() {
+		fd.mu.Lock()
+		sp.running = false
+		fd.mu.Unlock()
+		return
+	}
+	sp.t.QueueAIO(fd.sqPollLoop)
+}
+
+// kickSQPoll cuts short the current wait of the SQPOLL goroutine, if any.
+func (fd *FileDescription) kickSQPoll() {
+	select {
+	case fd.sqPoll.kickC <- struct{}{}:
+	default:
+	}
+}
+
+// sqPollLoop is the body of the SQPOLL goroutine.
+func (fd *FileDescription) sqPollLoop(ctx context.Context) {
+	defer fd.vfsfd.DecRef(ctx)
+	sp := fd.sqPoll
+	k := sp.t.Kernel()
+	clock := k.MonotonicClock()
+	listener, timerC := ktime.NewChannelNotifier()
+	timer := clock.NewTimer(listener)
+	defer timer.Destroy()
+	lastWork := clock.Now()
+	interval := sqPollMinInterval
+	for {
+		if !k.IsPaused() {
+			if n := fd.sqPollOnce(ctx); n > 0 {
+				lastWork = clock.Now()
+				interval = sqPollMinInterval
+				continue
+			}
+			if remaining := sp.idle - clock.Now().Sub(lastWork); remaining > 0 {
+				if sp.wait(timer, timerC, min(interval, remaining)) {
+					interval = sqPollMinInterval
+				} else {
+					interval = min(2*interval, sqPollMaxInterval)
+				}
+				continue
+			}
+		}
+		if fd.sqPollSleep(k) {
+			return
+		}
+		lastWork = clock.Now()
+		interval = sqPollMinInterval
+	}
+}
+
+// wait waits for d using timer, whose expirations are sent to timerC, or
+// until the SQPOLL goroutine is kicked. It returns true if the goroutine was
+// kicked.
+func (sp *sqPollState) wait(timer ktime.Timer, timerC <-chan struct{}, d time.Duration) bool {
+	timer.Set(ktime.Setting{
+		Enabled: true,
+		Next:    timer.Clock().Now().Add(d),
+	}, nil)
+	select {
+	case <-sp.kickC:
+		// Stop the timer and drain timerC. If s.Enabled is true, the timer
+		// didn't fire yet, so timerC must be empty.
+		if _, s := timer.Set(ktime.Setting{}, nil); !s.Enabled {
+			select {
+			case <-timerC:
+			default:
+			}
+		}
+		return true
+	case <-timerC:
+		return false
+	}
+}
+
+// sqPollOnce consumes all pending SQEs, and returns the number of consumed
+// SQEs.
+func (fd *FileDescription) sqPollOnce(ctx context.Context) int {
+	if !fd.running.CompareAndSwap(0, 1) {
+		return 0
+	}
+	defer fd.releaseRunning()
+
+	// The address space and file table are looked up on each pass rather than
+	// held by the ring, as they may hold references on the ring themselves.
+	var (
+		m       *mm.MemoryManager
+		fdTable *kernel.FDTable
+	)
+	sp := fd.sqPoll
+	sp.t.WithMuLocked(func(t *kernel.Task) {
+		if tm := t.MemoryManager(); tm != nil && tm.IncUsers() {
+			m = tm
+		}
+		if fdTable = t.FDTable(); fdTable != nil {
+			fdTable.IncRef()
+		}
+	})
+	if fdTable != nil {
+		defer fdTable.DecRef(ctx)
+	}
+	if m == nil {
+		return 0
+	}
+	defer m.DecUsers(ctx)
+	if fdTable == nil {
+		return 0
+	}
+
+	s := submitter{
+		t:       sp.t,
+		ctx:     ctx,
+		mm:      m,
+		fdTable: fdTable,
+	}
+	n, err := fd.submit(&s, math.MaxUint32)
+	if err != nil {
+		ctx.Debugf("iouringfs: SQPOLL submission failed: %v", err)
+	}
+	return n
+}
+
+// sqPollSleep prepares the SQPOLL goroutine to exit by setting
+// IORING_SQ_NEED_WAKEUP. It returns false if new SQEs have been submitted in
+// the meantime, in which case the goroutine should keep running.
+func (fd *FileDescription) sqPollSleep(k *kernel.Kernel) bool {
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+	fd.setSQFlagLocked(linux.IORING_SQ_NEED_WAKEUP, true)
+	if !k.IsPaused() && !fd.closed {
+		// Userspace may have submitted new SQEs before observing
+		// IORING_SQ_NEED_WAKEUP.
+		if n, err := fd.sqPendingLocked(); err == nil && n > 0 {
+			fd.setSQFlagLocked(linux.IORING_SQ_NEED_WAKEUP, false)
+			return false
+		}
+	}
+	fd.sqPoll.running = false
+	return true
+}
+
+// setSQFlagLocked sets or clears flag in the SQ ring flags.
+//
+// Preconditions: fd.mu must be locked.
+func (fd *FileDescription) setSQFlagLocked(flag uint32, set bool) {
+	if err := fd.checkRingsLocked(); err != nil {
+		return
+	}
+	view, err := fd.ioRingsBuf.view(fd.ioRings.SizeBytes())
+	if err != nil {
+		return
+	}
+	flagsPtr := atomicUint32AtOffset(view, int(linux.PreComputedIOSqRingOffsets().Flags))
+	if set {
+		flagsPtr.Store(flagsPtr.Load() | flag)
+	} else {
+		flagsPtr.Store(flagsPtr.Load() &^ flag)
+	}
+	fd.ioRingsBuf.writeback(fd.ioRings.SizeBytes())
+}
@@ -0,0 +1,212 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package iouringfs
+
+import (
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/ktime"
+)
+
+// timeoutOp implements IORING_OP_TIMEOUT and IORING_OP_LINK_TIMEOUT.
+//
+// An IORING_OP_TIMEOUT request completes with ETIME when its timer expires,
+// or with 0 once count other completions have been posted. An
+// IORING_OP_LINK_TIMEOUT request cancels the request it guards when its timer
+// expires. In both cases, the timer is only created when the request is
+// issued.
+//
+// +stateify savable
+type timeoutOp struct {
+	r *request
+
+	clock ktime.Clock
+	ts    linux.Timespec
+	abs   bool
+
+	// count is the number of completions that complete the timeout, or 0 if
+	// only the timer completes it. target is the value of fd.cqPosted at
+	// which the timeout completes. target is protected by fd.mu.
+	count  uint32
+	target uint32
+
+	// timer is protected by fd.mu.
+	timer ktime.Timer
+}
+
+var _ ktime.Listener = (*timeoutOp)(nil)
+
+func prepTimeout(s *submitter, r *request, sqe *linux.IOUringSqe) (operation, error) {
+	if sqe.IoPrio != 0 || sqe.BufIndexOrGroup != 0 || sqe.Len != 1 {
+		return nil, linuxerr.EINVAL
+	}
+	flags := sqe.OpFlags
+	if flags&^(linux.IORING_TIMEOUT_ABS|linux.IORING_TIMEOUT_BOOTTIME|linux.IORING_TIMEOUT_REALTIME) != 0 {
+		return nil, linuxerr.EINVAL
+	}
+	op := &timeoutOp{
+		r:   r,
+		abs: flags&linux.IORING_TIMEOUT_ABS != 0,
+	}
+	k := s.t.Kernel()
+	switch flags & (linux.IORING_TIMEOUT_BOOTTIME | linux.IORING_TIMEOUT_REALTIME) {
+	case 0, linux.IORING_TIMEOUT_BOOTTIME:
+		// CLOCK_BOOTTIME is internally mapped to CLOCK_MONOTONIC, see
+		// syscalls/linux/sys_time.go.
+		op.clock = k.MonotonicClock()
+	case linux.IORING_TIMEOUT_REALTIME:
+		op.clock = k.RealtimeClock()
+	default:
+		return nil, linuxerr.EINVAL
+	}
+
+	if sqe.Opcode == linux.IORING_OP_LINK_TIMEOUT {
+		if sqe.OffOrAddrOrCmdOp != 0 {
+			return nil, linuxerr.EINVAL
+		}
+	} else {
+		op.count = uint32(sqe.OffOrAddrOrCmdOp)
+	}
+
+	if _, err := op.ts.CopyIn(s.copyContext(), hostarch.Addr(sqe.AddrOrSpliceOff)); err != nil {
+		return nil, err
+	}
+	if !op.ts.Valid() {
+		return nil, linuxerr.EINVAL
+	}
+	return op, nil
+}
+
+// issue implements operation.issue.
+func (op *timeoutOp) issue(ctx context.Context, r *request) (int32, error) {
+	var (
+		setting ktime.Setting
+		err     error
+	)
+	if op.abs {
+		setting, err = ktime.SettingFromAbsSpec(ktime.FromTimespec(op.ts), 0)
+	} else {
+		setting, err = ktime.SettingFromSpec(op.ts.ToDuration(), 0, op.clock)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if !setting.Enabled {
+		// A zero timeout expires immediately.
+		op.expire()
+		return 0, errInProgress
+	}
+
+	timer := op.clock.NewTimer(op)
+	fd := r.fd
+	fd.mu.Lock()
+	op.timer = timer
+	if op.count != 0 {
+		op.target = fd.cqPosted + op.count
+		fd.countTimeouts = append(fd.countTimeouts, r)
+	}
+	fd.mu.Unlock()
+	timer.Set(setting, nil)
+	return 0, errInProgress
+}
+
+// release implements operation.release.
+func (op *timeoutOp) release(context.Context) {
+	fd := op.r.fd
+	fd.mu.Lock()
+	timer := op.timer
+	op.timer = nil
+	fd.mu.Unlock()
+	if timer != nil {
+		timer.Destroy()
+	}
+}
+
+// NotifyTimer implements ktime.Listener.NotifyTimer.
+func (op *timeoutOp) NotifyTimer(uint64) {
+	op.expire()
+}
+
+func (op *timeoutOp) expire() {
+	fd := op.r.fd
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+	fd.cancelLocked(op.r, -int32(linuxerr.ETIME.Errno()))
+	if target := op.r.target; target != nil {
+		fd.cancelLocked(target, -int32(linuxerr.ECANCELED.Errno()))
+	}
+}
+
+// flushCountTimeoutsLocked completes the IORING_OP_TIMEOUT requests whose
+// completion count has been reached.
+//
+// Preconditions: fd.mu must be locked.
+func (fd *FileDescription) flushCountTimeoutsLocked() {
+	for i := 0; i < len(fd.countTimeouts); {
+		r := fd.countTimeouts[i]
+		if op := r.op.(*timeoutOp); int32(fd.cqPosted-op.target) >= 0 {
+			fd.cancelLocked(r, 0)
+			fd.countTimeouts = append(fd.countTimeouts[:i], fd.countTimeouts[i+1:]...)
+			continue
+		}
+		i++
+	}
+}
+
+// removeCountTimeoutLocked removes r from fd.countTimeouts, if present.
+//
+// Preconditions: fd.mu must be locked.
+func (fd *FileDescription) removeCountTimeoutLocked(r *request) {
+	for i, other := range fd.countTimeouts {
+		if other == r {
+			fd.countTimeouts = append(fd.countTimeouts[:i], fd.countTimeouts[i+1:]...)
+			return
+		}
+	}
+}
+
+// timers returns the timers of in-flight timeout requests.
+func (fd *FileDescription) timers() []ktime.Timer {
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+	var timers []ktime.Timer
+	for r := range fd.inflight {
+		if op, ok := r.op.(*timeoutOp); ok && op.timer != nil {
+			timers = append(timers, op.timer)
+		}
+	}
+	return timers
+}
+
+var _ kernel.TimerFile = (*FileDescription)(nil)
+
+// PauseTimer implements kernel.TimerFile.PauseTimer.
+func (fd *FileDescription) PauseTimer() {
+	// Timer methods can't be called with fd.mu locked, since NotifyTimer
+	// locks fd.mu.
+	for _, timer := range fd.timers() {
+		timer.Pause()
+	}
+}
+
+// ResumeTimer implements kernel.TimerFile.ResumeTimer.
+func (fd *FileDescription) ResumeTimer() {
+	for _, timer := range fd.timers() {
+		timer.Resume()
+	}
+}
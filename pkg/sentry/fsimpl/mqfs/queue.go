@@ -53,6 +53,32 @@ func (q *queueInode) Keep() bool {
 	return true
 }
 
+// Open implements kernfs.Inode.Open. Similar to Linux, a queue opened through
+// a mount of the filesystem can be used with the mq_* syscalls.
+func (q *queueInode) Open(ctx context.Context, rp *vfs.ResolvingPath, d *kernfs.Dentry, opts vfs.OpenOptions) (*vfs.FileDescription, error) {
+	var access mq.AccessType
+	switch opts.Flags & linux.O_ACCMODE {
+	case linux.O_RDONLY:
+		access = mq.ReadOnly
+	case linux.O_WRONLY:
+		access = mq.WriteOnly
+	case linux.O_RDWR:
+		access = mq.ReadWrite
+	default:
+		return nil, linuxerr.EINVAL
+	}
+	view, err := mq.NewView(q.queue, access, opts.Flags&linux.O_NONBLOCK == 0)
+	if err != nil {
+		return nil, err
+	}
+
+	fd := &queueFD{queue: view}
+	if err := fd.Init(rp.Mount(), d, q.queue, q.Locks(), opts.Flags); err != nil {
+		return nil, err
+	}
+	return &fd.vfsfd, nil
+}
+
 // queueFD implements vfs.FileDescriptionImpl for FD backed by a POSIX message
 // queue. It's mostly similar to DynamicBytesFD, but implements more operations.
 //
@@ -69,6 +95,16 @@ type queueFD struct {
 	queue mq.View
 }
 
+// ViewFromFD returns the message queue view backing fd, or false if fd is not a
+// message queue file description.
+func ViewFromFD(fd *vfs.FileDescription) (mq.View, bool) {
+	qfd, ok := fd.Impl().(*queueFD)
+	if !ok {
+		return nil, false
+	}
+	return qfd.queue, true
+}
+
 // Init initializes a queueFD. Mostly copied from DynamicBytesFD.Init, but uses
 // the queueFD as FileDescriptionImpl.
 func (fd *queueFD) Init(m *vfs.Mount, d *kernfs.Dentry, data vfs.DynamicBytesSource, locks *vfs.FileLocks, flags uint32) error {
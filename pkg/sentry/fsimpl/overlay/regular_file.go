@@ -317,6 +317,30 @@ func (fd *regularFileFD) Write(ctx context.Context, src usermem.IOSequence, opts
 	return fd.updateSetUserGroupIDs(ctx, wrappedFD, n)
 }
 
+// CopyFileRange implements vfs.FileDescriptionImpl.CopyFileRange.
+func (fd *regularFileFD) CopyFileRange(ctx context.Context, src *vfs.FileDescription, srcOff, dstOff, length int64) (int64, error) {
+	// Unwrap src if it is also an overlay file, so that the copy may be done
+	// between the underlying files.
+	if srcFD, ok := src.Impl().(*regularFileFD); ok {
+		wrappedSrc, err := srcFD.getCurrentFD(ctx)
+		if err != nil {
+			return 0, err
+		}
+		defer wrappedSrc.DecRef(ctx)
+		src = wrappedSrc
+	}
+	wrappedFD, err := fd.getCurrentFD(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer wrappedFD.DecRef(ctx)
+	n, err := wrappedFD.CopyFileRange(ctx, src, srcOff, dstOff, length)
+	if err != nil {
+		return n, err
+	}
+	return fd.updateSetUserGroupIDs(ctx, wrappedFD, n)
+}
+
 func (fd *regularFileFD) updateSetUserGroupIDs(ctx context.Context, wrappedFD *vfs.FileDescription, written int64) (int64, error) {
 	// Writing can clear the setuid and/or setgid bits. We only have to
 	// check this if something was written and one of those bits was set.
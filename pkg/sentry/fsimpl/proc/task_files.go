@@ -409,6 +409,94 @@ func (d *idMapData) Write(ctx context.Context, _ *vfs.FileDescription, src userm
 	return int64(srclen), nil
 }
 
+// maxTimensOffsets is the maximum number of offsets that can be written to
+// /proc/[pid]/timens_offsets at once, one per clock.
+const maxTimensOffsets = 2
+
+// timensOffsetsData implements vfs.WritableDynamicBytesSource for
+// /proc/[pid]/timens_offsets, which contains the clock offsets of the time
+// namespace that children of the task are created in.
+//
+// +stateify savable
+type timensOffsetsData struct {
+	kernfs.DynamicBytesFile
+
+	task *kernel.Task
+}
+
+var _ dynamicInode = (*timensOffsetsData)(nil)
+var _ vfs.WritableDynamicBytesSource = (*timensOffsetsData)(nil)
+
+// Generate implements vfs.WritableDynamicBytesSource.Generate.
+func (d *timensOffsetsData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	timens := d.task.GetTimeNamespaceForChildren()
+	if timens == nil {
+		return linuxerr.ESRCH
+	}
+	defer timens.DecRef(ctx)
+	monotonic, boottime := timens.Offsets()
+	for _, o := range []struct {
+		name   string
+		offset linux.Timespec
+	}{
+		{"monotonic", linux.NsecToTimespec(monotonic.Nanoseconds())},
+		{"boottime", linux.NsecToTimespec(boottime.Nanoseconds())},
+	} {
+		fmt.Fprintf(buf, "%-10s %10d %9d\n", o.name, o.offset.Sec, o.offset.Nsec)
+	}
+	return nil
+}
+
+// Write implements vfs.WritableDynamicBytesSource.Write.
+func (d *timensOffsetsData) Write(ctx context.Context, _ *vfs.FileDescription, src usermem.IOSequence, offset int64) (int64, error) {
+	// Like Linux, only allow writes of less than a page at the beginning of
+	// the file.
+	srclen := src.NumBytes()
+	if srclen >= hostarch.PageSize || offset != 0 {
+		return 0, linuxerr.EINVAL
+	}
+	b := make([]byte, srclen)
+	if _, err := src.CopyIn(ctx, b); err != nil {
+		return 0, err
+	}
+
+	// Each line has the form "<clock> <offset-secs> <offset-nanosecs>", where
+	// clock is either the name or the ID of the clock. Like Linux, stop after
+	// maxTimensOffsets lines, and only report the consumed bytes as written.
+	var offsets []kernel.TimeOffset
+	rest := string(b)
+	for rest != "" && len(offsets) < maxTimensOffsets {
+		line, next, _ := strings.Cut(rest, "\n")
+		rest = next
+		var (
+			clock string
+			o     kernel.TimeOffset
+		)
+		if _, err := fmt.Sscan(line, &clock, &o.Offset.Sec, &o.Offset.Nsec); err != nil {
+			return 0, linuxerr.EINVAL
+		}
+		switch clock {
+		case "monotonic", strconv.Itoa(linux.CLOCK_MONOTONIC):
+			o.ClockID = linux.CLOCK_MONOTONIC
+		case "boottime", strconv.Itoa(linux.CLOCK_BOOTTIME):
+			o.ClockID = linux.CLOCK_BOOTTIME
+		default:
+			return 0, linuxerr.EINVAL
+		}
+		offsets = append(offsets, o)
+	}
+
+	timens := d.task.GetTimeNamespaceForChildren()
+	if timens == nil {
+		return 0, linuxerr.ESRCH
+	}
+	defer timens.DecRef(ctx)
+	if err := timens.SetOffsets(ctx, auth.CredentialsFromContext(ctx), offsets); err != nil {
+		return 0, err
+	}
+	return srclen - int64(len(rest)), nil
+}
+
 var _ kernfs.Inode = (*memInode)(nil)
 
 // memInode implements kernfs.Inode for /proc/[pid]/mem.
@@ -1277,6 +1365,10 @@ type namespaceSymlink struct {
 
 	task   *kernel.Task
 	nsType int
+
+	// forChildren is true if the symlink refers to the namespace that
+	// children of task are created in, as for /proc/[pid]/ns/*_for_children.
+	forChildren bool
 }
 
 func (fs *filesystem) newNamespaceSymlink(ctx context.Context, task *kernel.Task, ino uint64, nsType int) kernfs.Inode {
@@ -1289,6 +1381,16 @@ func (fs *filesystem) newNamespaceSymlink(ctx context.Context, task *kernel.Task
 	return taskInode
 }
 
+func (fs *filesystem) newChildNamespaceSymlink(ctx context.Context, task *kernel.Task, ino uint64, nsType int) kernfs.Inode {
+	inode := &namespaceSymlink{task: task, nsType: nsType, forChildren: true}
+
+	// Note: credentials are overridden by taskOwnedInode.
+	inode.Init(ctx, task.Credentials(), linux.UNNAMED_MAJOR, fs.devMinor, ino, "")
+
+	taskInode := &taskOwnedInode{Inode: inode, owner: task}
+	return taskInode
+}
+
 func (fs *filesystem) newFakeNamespaceSymlink(ctx context.Context, task *kernel.Task, ino uint64, ns string) kernfs.Inode {
 	// Namespace symlinks should contain the namespace name and the inode number
 	// for the namespace instance, so for example user:[123456]. We currently fake
@@ -1333,6 +1435,17 @@ func (s *namespaceSymlink) getInode(t *kernel.Task) *nsfs.Inode {
 			return pidns.GetInode()
 		}
 		return nil
+	case linux.CLONE_NEWTIME:
+		var timens *kernel.TimeNamespace
+		if s.forChildren {
+			timens = t.GetTimeNamespaceForChildren()
+		} else {
+			timens = t.GetTimeNamespace()
+		}
+		if timens != nil {
+			return timens.GetInode()
+		}
+		return nil
 	default:
 		panic("unknown namespace")
 	}
@@ -325,9 +325,16 @@ var _ dynamicInode = (*uptimeData)(nil)
 func (*uptimeData) Generate(ctx context.Context, buf *bytes.Buffer) error {
 	k := kernel.KernelFromContext(ctx)
 	now := ktime.NowFromContext(ctx)
+	uptime := now.Sub(k.Timekeeper().BootTime())
+	if t := kernel.TaskFromContext(ctx); t != nil {
+		// Like Linux, apply the CLOCK_BOOTTIME offset of the reader's time
+		// namespace.
+		_, boottime := t.TimeNamespace().Offsets()
+		uptime += boottime
+	}
 
 	// Pretend that we've spent zero time sleeping (second number).
-	fmt.Fprintf(buf, "%.2f 0.00\n", now.Sub(k.Timekeeper().BootTime()).Seconds())
+	fmt.Fprintf(buf, "%.2f 0.00\n", uptime.Seconds())
 	return nil
 }
 
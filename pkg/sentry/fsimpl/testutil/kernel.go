@@ -157,6 +157,10 @@ func CreateTask(ctx context.Context, name string, tc *kernel.ThreadGroup, mntns
 		UserCounters:     k.GetUserCounters(creds.RealKUID),
 	}
 	config.NetworkNamespace.IncRef()
+	config.TimeNamespace = k.RootTimeNamespace()
+	config.TimeNamespace.IncRef()
+	config.TimeNamespaceForChildren = k.RootTimeNamespace()
+	config.TimeNamespaceForChildren.IncRef()
 	t, err := k.TaskSet().NewTask(ctx, config)
 	if err != nil {
 		config.ThreadGroup.Release(ctx)
@@ -967,6 +967,13 @@ func (fs *filesystem) IsDescendant(vfsroot, vd vfs.VirtualDentry) bool {
 	return genericIsDescendant(fs, vfsroot.Dentry(), vd.Dentry().Impl().(*dentry))
 }
 
+// SupportsCachedResolution implements
+// vfs.FilesystemImplCachedResolutionExtension.SupportsCachedResolution. All
+// dentries are always cached.
+func (fs *filesystem) SupportsCachedResolution() bool {
+	return true
+}
+
 // MountOptions implements vfs.FilesystemImpl.MountOptions.
 func (fs *filesystem) MountOptions() string {
 	return fs.mopts
@@ -593,6 +593,72 @@ func (fd *regularFileFD) Write(ctx context.Context, src usermem.IOSequence, opts
 	return n, err
 }
 
+// CopyFileRange implements vfs.FileDescriptionImpl.CopyFileRange.
+func (fd *regularFileFD) CopyFileRange(ctx context.Context, src *vfs.FileDescription, srcOff, dstOff, length int64) (int64, error) {
+	srcFD, ok := src.Impl().(*regularFileFD)
+	if !ok {
+		return 0, linuxerr.EXDEV
+	}
+	if srcOff < 0 || dstOff < 0 || length < 0 {
+		return 0, linuxerr.EINVAL
+	}
+	if length == 0 {
+		return 0, nil
+	}
+	srcFile := srcFD.inode().impl.(*regularFile)
+	f := fd.inode().impl.(*regularFile)
+
+	// Take references on the pages backing the source range, so that they can
+	// be copied from without holding srcFile.dataMu. (srcFile may be f, whose
+	// dataMu is locked for writing by regularFileReadWriter.WriteFromBlocks.)
+	spans := srcFile.pinSpans(uint64(srcOff), uint64(length))
+	defer srcFile.unpinSpans(spans)
+	srcFD.inode().touchAtime(srcFD.vfsfd.Mount())
+	if len(spans) == 0 {
+		return 0, nil
+	}
+
+	f.inode.mu.Lock()
+	defer f.inode.mu.Unlock()
+	limit, err := vfs.CheckLimit(ctx, dstOff, int64(spans[len(spans)-1].mr.End)-srcOff)
+	if err != nil {
+		return 0, err
+	}
+	rw := getRegularFileReadWriter(f, dstOff, pgalloc.MemoryCgroupIDFromContext(ctx))
+	defer putRegularFileReadWriter(rw)
+	var done int64
+	for _, span := range spans {
+		if done >= limit {
+			break
+		}
+		if rem := uint64(limit - done); span.mr.Length() > rem {
+			span.mr.End = span.mr.Start + rem
+			if span.fr.Length() != 0 {
+				span.fr.End = span.fr.Start + rem
+			}
+		}
+		n, err := rw.writeFromSpan(srcFile.inode.fs.mf, span)
+		done += int64(n)
+		if err != nil || n != span.mr.Length() {
+			if done == 0 {
+				return 0, err
+			}
+			break
+		}
+	}
+	if done > 0 {
+		f.inode.touchCMtimeLocked()
+		for {
+			old := f.inode.mode.Load()
+			new := vfs.ClearSUIDAndSGID(old)
+			if swapped := f.inode.mode.CompareAndSwap(old, new); swapped {
+				break
+			}
+		}
+	}
+	return done, nil
+}
+
 // Seek implements vfs.FileDescriptionImpl.Seek.
 func (fd *regularFileFD) Seek(ctx context.Context, offset int64, whence int32) (int64, error) {
 	fd.offMu.Lock()
@@ -667,6 +733,122 @@ func putRegularFileReadWriter(rw *regularFileReadWriter) {
 	regularFileReadWriterPool.Put(rw)
 }
 
+// regularFileSpan is a range of a regularFile's data, returned by
+// regularFile.pinSpans.
+type regularFileSpan struct {
+	// mr is the range of the file represented by the span.
+	mr memmap.MappableRange
+
+	// fr is the range of the file's MemoryFile backing mr, or an empty range
+	// if mr is a hole.
+	fr memmap.FileRange
+
+	// pinned is the page-aligned range of the MemoryFile on which pinSpans
+	// took a reference, and which contains fr.
+	pinned memmap.FileRange
+}
+
+// pinSpans returns spans representing the range [off, off+length) of rf,
+// truncated to the file's size. It takes a reference on the pages backing the
+// returned spans, which must be released by calling rf.unpinSpans.
+func (rf *regularFile) pinSpans(off, length uint64) []regularFileSpan {
+	rf.dataMu.RLock()
+	defer rf.dataMu.RUnlock()
+	size := rf.size.RacyLoad()
+	if off >= size {
+		return nil
+	}
+	end := size
+	if rend := off + length; rend > off && rend < end {
+		end = rend
+	}
+	mr := memmap.MappableRange{off, end}
+	pgMR := memmap.MappableRange{hostarch.PageRoundDown(off), offsetPageEnd(int64(end))}
+
+	var spans []regularFileSpan
+	seg, gap := rf.data.Find(off)
+	for seg.Ok() || gap.Ok() {
+		if seg.Ok() {
+			if seg.Start() >= end {
+				break
+			}
+			span := regularFileSpan{
+				mr:     seg.Range().Intersect(mr),
+				pinned: seg.FileRangeOf(seg.Range().Intersect(pgMR)),
+			}
+			span.fr = seg.FileRangeOf(span.mr)
+			// memCgID can be 0 because IncRef doesn't allocate.
+			rf.inode.fs.mf.IncRef(span.pinned, 0)
+			spans = append(spans, span)
+			seg, gap = seg.NextNonEmpty()
+		} else {
+			if gap.Start() >= end {
+				break
+			}
+			spans = append(spans, regularFileSpan{mr: gap.Range().Intersect(mr)})
+			seg, gap = gap.NextSegment(), fsutil.FileRangeGapIterator{}
+		}
+	}
+	return spans
+}
+
+// unpinSpans releases the references taken by rf.pinSpans.
+func (rf *regularFile) unpinSpans(spans []regularFileSpan) {
+	for _, span := range spans {
+		if span.pinned.Length() != 0 {
+			rf.inode.fs.mf.DecRef(span.pinned)
+		}
+	}
+}
+
+// writeFromSpan writes the data represented by span, whose pages are in mf,
+// to rw.
+//
+// Preconditions: rw.file.inode.mu must be held.
+func (rw *regularFileReadWriter) writeFromSpan(mf *pgalloc.MemoryFile, span regularFileSpan) (uint64, error) {
+	if span.fr.Length() == 0 {
+		// Tmpfs holes are zero-filled.
+		return rw.writeZeros(span.mr.Length())
+	}
+	ims, err := mf.MapInternal(span.fr, hostarch.Read)
+	if err != nil {
+		return 0, err
+	}
+	return rw.WriteFromBlocks(ims)
+}
+
+// zeroPage is a page of zeroes, used as the source of writes that fill
+// regular files with zeroes.
+var zeroPage [hostarch.PageSize]byte
+
+// writeZeros writes length zero bytes to rw.
+//
+// Preconditions: rw.file.inode.mu must be held.
+func (rw *regularFileReadWriter) writeZeros(length uint64) (uint64, error) {
+	const maxPages = 64
+	var blocks [maxPages]safemem.Block
+	var done uint64
+	for done < length {
+		var srcs []safemem.Block
+		for rem := length - done; rem > 0 && len(srcs) < maxPages; {
+			n := uint64(hostarch.PageSize)
+			if rem < n {
+				n = rem
+			}
+			blocks[len(srcs)] = safemem.BlockFromSafeSlice(zeroPage[:n])
+			srcs = blocks[:len(srcs)+1]
+			rem -= n
+		}
+		seq := safemem.BlockSeqFromSlice(srcs)
+		n, err := rw.WriteFromBlocks(seq)
+		done += n
+		if err != nil || n != seq.NumBytes() {
+			return done, err
+		}
+	}
+	return done, nil
+}
+
 // ReadToBlocks implements safemem.Reader.ReadToBlocks.
 func (rw *regularFileReadWriter) ReadToBlocks(dsts safemem.BlockSeq) (uint64, error) {
 	rw.file.dataMu.RLock()
@@ -0,0 +1,541 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package userfaultfd implements userfaultfd(2) file descriptions.
+package userfaultfd
+
+import (
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/sentry/arch"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/memmap"
+	"gvisor.dev/gvisor/pkg/sentry/mm"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/sync"
+	"gvisor.dev/gvisor/pkg/usermem"
+	"gvisor.dev/gvisor/pkg/waiter"
+)
+
+// supportedFeatures is the set of features that may be requested by
+// UFFDIO_API.
+const supportedFeatures = linux.UFFD_FEATURE_PAGEFAULT_FLAG_WP | linux.UFFD_FEATURE_SIGBUS | linux.UFFD_FEATURE_THREAD_ID
+
+// maxCopyBytes is the maximum number of bytes copied from the caller's memory
+// at a time by UFFDIO_COPY.
+const maxCopyBytes = 64 * hostarch.PageSize
+
+// UserfaultFileDescription implements vfs.FileDescriptionImpl for
+// userfaultfds. It also implements mm.UserfaultHandler for the address ranges
+// registered with it.
+//
+// +stateify savable
+type UserfaultFileDescription struct {
+	vfsfd vfs.FileDescription
+	vfs.FileDescriptionDefaultImpl
+	vfs.DentryMetadataFileDescriptionImpl
+	vfs.NoLockFD
+
+	// mm is the MemoryManager of the task that created the userfaultfd, whose
+	// address ranges may be registered with it. The userfaultfd does not hold
+	// a user reference on mm; operations that require mm's address space to
+	// exist must take one with mm.IncUsers. mm is immutable.
+	mm *mm.MemoryManager
+
+	// userModeOnly is true if UFFD_USER_MODE_ONLY was passed to
+	// userfaultfd(2), in which case faults that occur while the sentry is
+	// accessing application memory are not handled. userModeOnly is
+	// immutable.
+	userModeOnly bool
+
+	// queue is notified when faults become pending.
+	queue waiter.Queue
+
+	// mu protects the fields below.
+	mu sync.Mutex `state:"nosave"`
+
+	// ready is true once the UFFDIO_API handshake is complete.
+	ready bool
+
+	// features is the set of features enabled by UFFDIO_API.
+	features uint64
+
+	// faults contains the unresolved faults reported to the userfaultfd, in
+	// the order in which they occurred.
+	faults []*fault
+
+	// released is true once the userfaultfd has been released.
+	released bool
+}
+
+// fault is a fault waiting to be resolved by a userfaultfd.
+//
+// +stateify savable
+type fault struct {
+	addr  hostarch.Addr
+	flags uint64
+	ptid  uint32
+
+	// read is true if the fault has been read from the userfaultfd.
+	read bool
+
+	// done is closed when the fault is resolved.
+	done chan struct{} `state:"nosave"`
+}
+
+var _ vfs.FileDescriptionImpl = (*UserfaultFileDescription)(nil)
+var _ mm.UserfaultHandler = (*UserfaultFileDescription)(nil)
+
+// New creates a new userfaultfd that handles faults in m.
+func New(ctx context.Context, vfsObj *vfs.VirtualFilesystem, m *mm.MemoryManager, flags uint32, userModeOnly bool) (*vfs.FileDescription, error) {
+	vd := vfsObj.NewAnonVirtualDentry("[userfaultfd]")
+	defer vd.DecRef(ctx)
+	fd := &UserfaultFileDescription{
+		mm:           m,
+		userModeOnly: userModeOnly,
+	}
+	if err := fd.vfsfd.Init(fd, flags, vd.Mount(), vd.Dentry(), &vfs.FileDescriptionOptions{
+		UseDentryMetadata: true,
+		DenyPRead:         true,
+		DenyPWrite:        true,
+		DenySpliceIn:      true,
+	}); err != nil {
+		return nil, err
+	}
+	return &fd.vfsfd, nil
+}
+
+// Release implements vfs.FileDescriptionImpl.Release.
+func (fd *UserfaultFileDescription) Release(ctx context.Context) {
+	if fd.mm.IncUsers() {
+		fd.mm.ReleaseUserfaultfd(fd)
+		fd.mm.DecUsers(ctx)
+	}
+
+	// Wake all faulting tasks; since their ranges are no longer registered,
+	// their faults will be handled normally when retried.
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+	fd.released = true
+	for _, f := range fd.faults {
+		close(f.done)
+	}
+	fd.faults = nil
+}
+
+// Read implements vfs.FileDescriptionImpl.Read.
+func (fd *UserfaultFileDescription) Read(ctx context.Context, dst usermem.IOSequence, _ vfs.ReadOptions) (int64, error) {
+	if dst.NumBytes() < linux.SizeOfUFFDMsg {
+		return 0, linuxerr.EINVAL
+	}
+
+	fd.mu.Lock()
+	if !fd.ready {
+		fd.mu.Unlock()
+		return 0, linuxerr.EINVAL
+	}
+	var msgs []linux.UFFDMsg
+	for _, f := range fd.faults {
+		if int64(len(msgs)+1)*linux.SizeOfUFFDMsg > dst.NumBytes() {
+			break
+		}
+		if f.read {
+			continue
+		}
+		// Like Linux, the fault stays pending until it is woken, but is
+		// only reported once.
+		f.read = true
+		msgs = append(msgs, linux.UFFDMsg{
+			Event:   linux.UFFD_EVENT_PAGEFAULT,
+			Flags:   f.flags,
+			Address: uint64(f.addr),
+			PTID:    f.ptid,
+		})
+	}
+	fd.mu.Unlock()
+
+	if len(msgs) == 0 {
+		return 0, linuxerr.ErrWouldBlock
+	}
+	buf := make([]byte, len(msgs)*linux.SizeOfUFFDMsg)
+	for i := range msgs {
+		msgs[i].MarshalBytes(buf[i*linux.SizeOfUFFDMsg:])
+	}
+	n, err := dst.CopyOut(ctx, buf)
+	return int64(n), err
+}
+
+// Readiness implements waiter.Waitable.Readiness.
+func (fd *UserfaultFileDescription) Readiness(mask waiter.EventMask) waiter.EventMask {
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+	var ready waiter.EventMask
+	for _, f := range fd.faults {
+		if !f.read {
+			ready |= waiter.ReadableEvents
+			break
+		}
+	}
+	return mask & ready
+}
+
+// EventRegister implements waiter.Waitable.EventRegister.
+func (fd *UserfaultFileDescription) EventRegister(e *waiter.Entry) error {
+	fd.queue.EventRegister(e)
+	return nil
+}
+
+// EventUnregister implements waiter.Waitable.EventUnregister.
+func (fd *UserfaultFileDescription) EventUnregister(e *waiter.Entry) {
+	fd.queue.EventUnregister(e)
+}
+
+// Epollable implements FileDescriptionImpl.Epollable.
+func (fd *UserfaultFileDescription) Epollable() bool {
+	return true
+}
+
+// Ioctl implements vfs.FileDescriptionImpl.Ioctl.
+func (fd *UserfaultFileDescription) Ioctl(ctx context.Context, uio usermem.IO, sysno uintptr, args arch.SyscallArguments) (uintptr, error) {
+	t := kernel.TaskFromContext(ctx)
+	if t == nil {
+		return 0, linuxerr.ENOTTY
+	}
+	addr := args[2].Pointer()
+
+	cmd := args[1].Uint()
+	if cmd == linux.UFFDIO_API {
+		return 0, fd.api(t, addr)
+	}
+	fd.mu.Lock()
+	ready := fd.ready
+	fd.mu.Unlock()
+	if !ready {
+		return 0, linuxerr.EINVAL
+	}
+
+	switch cmd {
+	case linux.UFFDIO_REGISTER:
+		return 0, fd.register(t, addr)
+	case linux.UFFDIO_UNREGISTER:
+		return 0, fd.unregister(t, addr)
+	case linux.UFFDIO_WAKE:
+		var r linux.UFFDIORange
+		if _, err := r.CopyIn(t, addr); err != nil {
+			return 0, err
+		}
+		ar, err := fd.checkRange(r)
+		if err != nil {
+			return 0, err
+		}
+		fd.wake(ar)
+		return 0, nil
+	case linux.UFFDIO_COPY:
+		return 0, fd.copy(t, uio, addr)
+	case linux.UFFDIO_ZEROPAGE:
+		return 0, fd.zeropage(t, addr)
+	case linux.UFFDIO_WRITEPROTECT:
+		return 0, fd.writeProtect(t, addr)
+	default:
+		return 0, linuxerr.ENOTTY
+	}
+}
+
+// api handles UFFDIO_API.
+func (fd *UserfaultFileDescription) api(t *kernel.Task, addr hostarch.Addr) error {
+	var api linux.UFFDIOAPI
+	if _, err := api.CopyIn(t, addr); err != nil {
+		return err
+	}
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+	if fd.ready || api.API != linux.UFFD_API || api.Features&^supportedFeatures != 0 {
+		// Like Linux, report that no features or ioctls are available.
+		api.Features = 0
+		api.Ioctls = 0
+		api.CopyOut(t, addr)
+		return linuxerr.EINVAL
+	}
+	requested := api.Features
+	api.Features = supportedFeatures
+	api.Ioctls = linux.UFFD_API_IOCTLS
+	if _, err := api.CopyOut(t, addr); err != nil {
+		return err
+	}
+	fd.ready = true
+	// Features are enabled if they were requested, except for
+	// UFFD_FEATURE_PAGEFAULT_FLAG_WP, which is always enabled.
+	fd.features = requested | linux.UFFD_FEATURE_PAGEFAULT_FLAG_WP
+	return nil
+}
+
+// register handles UFFDIO_REGISTER.
+func (fd *UserfaultFileDescription) register(t *kernel.Task, addr hostarch.Addr) error {
+	var reg linux.UFFDIORegister
+	if _, err := reg.CopyIn(t, addr); err != nil {
+		return err
+	}
+	if reg.Mode == 0 || reg.Mode&^(linux.UFFDIO_REGISTER_MODE_MISSING|linux.UFFDIO_REGISTER_MODE_WP) != 0 {
+		return linuxerr.EINVAL
+	}
+	ar, err := fd.checkRange(reg.Range)
+	if err != nil {
+		return err
+	}
+	if !fd.mm.IncUsers() {
+		return linuxerr.ESRCH
+	}
+	err = fd.mm.RegisterUserfaultfd(ar, fd, reg.Mode)
+	fd.mm.DecUsers(t)
+	if err != nil {
+		return err
+	}
+	reg.Ioctls = linux.UFFD_API_RANGE_IOCTLS
+	if reg.Mode&linux.UFFDIO_REGISTER_MODE_WP == 0 {
+		reg.Ioctls &^= 1 << linux.UFFDIO_WRITEPROTECT_NR
+	}
+	_, err = reg.CopyOut(t, addr)
+	return err
+}
+
+// unregister handles UFFDIO_UNREGISTER.
+func (fd *UserfaultFileDescription) unregister(t *kernel.Task, addr hostarch.Addr) error {
+	var r linux.UFFDIORange
+	if _, err := r.CopyIn(t, addr); err != nil {
+		return err
+	}
+	ar, err := fd.checkRange(r)
+	if err != nil {
+		return err
+	}
+	if !fd.mm.IncUsers() {
+		return linuxerr.ESRCH
+	}
+	err = fd.mm.UnregisterUserfaultfd(ar, fd)
+	fd.mm.DecUsers(t)
+	if err != nil {
+		return err
+	}
+	// Faults in the range will no longer be reported, so wake tasks that are
+	// still waiting for them.
+	fd.wake(ar)
+	return nil
+}
+
+// copy handles UFFDIO_COPY.
+func (fd *UserfaultFileDescription) copy(t *kernel.Task, uio usermem.IO, addr hostarch.Addr) error {
+	var c linux.UFFDIOCopy
+	if _, err := c.CopyIn(t, addr); err != nil {
+		return err
+	}
+	if c.Mode&^(linux.UFFDIO_COPY_MODE_DONTWAKE|linux.UFFDIO_COPY_MODE_WP) != 0 {
+		return linuxerr.EINVAL
+	}
+	ar, err := fd.checkRange(linux.UFFDIORange{Start: c.Dst, Len: c.Len})
+	if err != nil {
+		return err
+	}
+	src := hostarch.Addr(c.Src)
+	if _, ok := src.AddLength(c.Len); !ok {
+		return linuxerr.EINVAL
+	}
+	if !fd.mm.IncUsers() {
+		return linuxerr.ESRCH
+	}
+	defer fd.mm.DecUsers(t)
+
+	// Copy from the caller's memory without holding locks on fd.mm, which
+	// may be the caller's MemoryManager.
+	var done uint64
+	buf := make([]byte, min(c.Len, maxCopyBytes))
+	for done < c.Len {
+		n := min(c.Len-done, maxCopyBytes)
+		if _, err = uio.CopyIn(t, src+hostarch.Addr(done), buf[:n], usermem.IOOpts{}); err != nil {
+			break
+		}
+		dstAR := hostarch.AddrRange{ar.Start + hostarch.Addr(done), ar.Start + hostarch.Addr(done+n)}
+		var filled uint64
+		filled, err = fd.mm.FillUserfaultfd(t, fd, dstAR, buf[:n], c.Mode&linux.UFFDIO_COPY_MODE_WP != 0)
+		done += filled
+		if err != nil {
+			break
+		}
+	}
+	c.Copy = fillResult(done, err)
+	if _, err := c.CopyOut(t, addr); err != nil {
+		return err
+	}
+	return fd.fillDone(ar, done, err, c.Mode&linux.UFFDIO_COPY_MODE_DONTWAKE != 0)
+}
+
+// zeropage handles UFFDIO_ZEROPAGE.
+func (fd *UserfaultFileDescription) zeropage(t *kernel.Task, addr hostarch.Addr) error {
+	var z linux.UFFDIOZeropage
+	if _, err := z.CopyIn(t, addr); err != nil {
+		return err
+	}
+	if z.Mode&^linux.UFFDIO_ZEROPAGE_MODE_DONTWAKE != 0 {
+		return linuxerr.EINVAL
+	}
+	ar, err := fd.checkRange(z.Range)
+	if err != nil {
+		return err
+	}
+	if !fd.mm.IncUsers() {
+		return linuxerr.ESRCH
+	}
+	defer fd.mm.DecUsers(t)
+	done, err := fd.mm.FillUserfaultfd(t, fd, ar, nil /* src */, false /* wp */)
+	z.Zeropage = fillResult(done, err)
+	if _, err := z.CopyOut(t, addr); err != nil {
+		return err
+	}
+	return fd.fillDone(ar, done, err, z.Mode&linux.UFFDIO_ZEROPAGE_MODE_DONTWAKE != 0)
+}
+
+// fillResult returns the result reported by UFFDIO_COPY or UFFDIO_ZEROPAGE
+// after done bytes were filled, stopping with error err. Like Linux, this is
+// the number of bytes filled, or a negative errno if none were.
+func fillResult(done uint64, err error) int64 {
+	if done == 0 && err != nil {
+		return -int64(kernel.ExtractErrno(err, -1))
+	}
+	return int64(done)
+}
+
+// fillDone completes UFFDIO_COPY or UFFDIO_ZEROPAGE after done bytes of ar
+// were filled, stopping with error err. Unless dontWake is true, it wakes
+// tasks waiting for faults in the filled range.
+func (fd *UserfaultFileDescription) fillDone(ar hostarch.AddrRange, done uint64, err error, dontWake bool) error {
+	if done == 0 {
+		return err
+	}
+	if !dontWake {
+		fd.wake(hostarch.AddrRange{ar.Start, ar.Start + hostarch.Addr(done)})
+	}
+	if done != uint64(ar.Length()) {
+		// Like Linux, partial success is reported with EAGAIN.
+		return linuxerr.EAGAIN
+	}
+	return nil
+}
+
+// writeProtect handles UFFDIO_WRITEPROTECT.
+func (fd *UserfaultFileDescription) writeProtect(t *kernel.Task, addr hostarch.Addr) error {
+	var w linux.UFFDIOWriteProtect
+	if _, err := w.CopyIn(t, addr); err != nil {
+		return err
+	}
+	if w.Mode&^(linux.UFFDIO_WRITEPROTECT_MODE_WP|linux.UFFDIO_WRITEPROTECT_MODE_DONTWAKE) != 0 {
+		return linuxerr.EINVAL
+	}
+	wp := w.Mode&linux.UFFDIO_WRITEPROTECT_MODE_WP != 0
+	dontWake := w.Mode&linux.UFFDIO_WRITEPROTECT_MODE_DONTWAKE != 0
+	if wp && dontWake {
+		return linuxerr.EINVAL
+	}
+	ar, err := fd.checkRange(w.Range)
+	if err != nil {
+		return err
+	}
+	if !fd.mm.IncUsers() {
+		return linuxerr.ESRCH
+	}
+	err = fd.mm.WriteProtectUserfaultfd(fd, ar, wp)
+	fd.mm.DecUsers(t)
+	if err != nil {
+		return err
+	}
+	if !wp && !dontWake {
+		fd.wake(ar)
+	}
+	return nil
+}
+
+// checkRange returns the address range represented by r, after checking that
+// it is valid in fd.mm.
+func (fd *UserfaultFileDescription) checkRange(r linux.UFFDIORange) (hostarch.AddrRange, error) {
+	start := hostarch.Addr(r.Start)
+	if r.Len == 0 || int64(r.Len) < 0 || !start.IsPageAligned() || !hostarch.Addr(r.Len).IsPageAligned() {
+		return hostarch.AddrRange{}, linuxerr.EINVAL
+	}
+	ar, ok := fd.mm.CheckIORange(start, int64(r.Len))
+	if !ok {
+		return hostarch.AddrRange{}, linuxerr.EINVAL
+	}
+	return ar, nil
+}
+
+// wake resolves pending faults in ar.
+func (fd *UserfaultFileDescription) wake(ar hostarch.AddrRange) {
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+	faults := fd.faults[:0]
+	for _, f := range fd.faults {
+		if ar.Contains(f.addr) {
+			close(f.done)
+		} else {
+			faults = append(faults, f)
+		}
+	}
+	for i := len(faults); i < len(fd.faults); i++ {
+		fd.faults[i] = nil
+	}
+	fd.faults = faults
+}
+
+// HandleUserfault implements mm.UserfaultHandler.HandleUserfault.
+func (fd *UserfaultFileDescription) HandleUserfault(ctx context.Context, addr hostarch.Addr, flags uint64, kernelFault bool) error {
+	fd.mu.Lock()
+	if fd.released {
+		// The range is about to be unregistered; retry the fault.
+		fd.mu.Unlock()
+		return nil
+	}
+	if !fd.ready || fd.features&linux.UFFD_FEATURE_SIGBUS != 0 || (kernelFault && fd.userModeOnly) {
+		fd.mu.Unlock()
+		return &memmap.BusError{linuxerr.EFAULT}
+	}
+	f := &fault{
+		addr:  addr,
+		flags: flags,
+		done:  make(chan struct{}),
+	}
+	if fd.features&linux.UFFD_FEATURE_PAGEFAULT_FLAG_WP == 0 {
+		f.flags &^= linux.UFFD_PAGEFAULT_FLAG_WP
+	}
+	if t := kernel.TaskFromContext(ctx); t != nil && fd.features&linux.UFFD_FEATURE_THREAD_ID != 0 {
+		f.ptid = uint32(t.ThreadID())
+	}
+	fd.faults = append(fd.faults, f)
+	fd.mu.Unlock()
+	fd.queue.Notify(waiter.ReadableEvents)
+
+	if err := ctx.Block(f.done); err != nil {
+		// Stop waiting for the fault. If it is retried, it will be reported
+		// again.
+		fd.mu.Lock()
+		for i, pf := range fd.faults {
+			if pf == f {
+				fd.faults = append(fd.faults[:i], fd.faults[i+1:]...)
+				break
+			}
+		}
+		fd.mu.Unlock()
+		return err
+	}
+	return nil
+}
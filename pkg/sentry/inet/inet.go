@@ -79,6 +79,31 @@ type Stack interface {
 	// SetTCPRecovery attempts to change TCP loss detection algorithm.
 	SetTCPRecovery(recovery TCPLossRecovery) error
 
+	// TCPFastOpen returns the TCP Fast Open mode, a bitmask of the
+	// tcp_fastopen sysctl flags.
+	TCPFastOpen() (int32, error)
+
+	// SetTCPFastOpen attempts to change the TCP Fast Open mode.
+	SetTCPFastOpen(mode int32) error
+
+	// TCPCongestionControl returns the default TCP congestion control
+	// algorithm.
+	TCPCongestionControl() (string, error)
+
+	// SetTCPCongestionControl attempts to change the default TCP
+	// congestion control algorithm.
+	SetTCPCongestionControl(name string) error
+
+	// TCPAvailableCongestionControl returns the space separated list of
+	// available TCP congestion control algorithms.
+	TCPAvailableCongestionControl() (string, error)
+
+	// TCPECN returns the TCP ECN mode, as in the tcp_ecn sysctl.
+	TCPECN() (int32, error)
+
+	// SetTCPECN attempts to change the TCP ECN mode.
+	SetTCPECN(mode int32) error
+
 	// Statistics reports stack statistics.
 	Statistics(stat any, arg string) error
 
@@ -38,6 +38,9 @@ type TestStack struct {
 	TCPSendBufSize    TCPBufferSize
 	TCPSACKFlag       bool
 	Recovery          TCPLossRecovery
+	FastOpen          int32
+	CongestionControl string
+	ECN               int32
 	IPForwarding      bool
 }
 
@@ -149,6 +152,44 @@ func (s *TestStack) SetTCPRecovery(recovery TCPLossRecovery) error {
 	return nil
 }
 
+// TCPFastOpen implements Stack.
+func (s *TestStack) TCPFastOpen() (int32, error) {
+	return s.FastOpen, nil
+}
+
+// SetTCPFastOpen implements Stack.
+func (s *TestStack) SetTCPFastOpen(mode int32) error {
+	s.FastOpen = mode
+	return nil
+}
+
+// TCPCongestionControl implements Stack.
+func (s *TestStack) TCPCongestionControl() (string, error) {
+	return s.CongestionControl, nil
+}
+
+// SetTCPCongestionControl implements Stack.
+func (s *TestStack) SetTCPCongestionControl(name string) error {
+	s.CongestionControl = name
+	return nil
+}
+
+// TCPAvailableCongestionControl implements Stack.
+func (s *TestStack) TCPAvailableCongestionControl() (string, error) {
+	return s.CongestionControl, nil
+}
+
+// TCPECN implements Stack.
+func (s *TestStack) TCPECN() (int32, error) {
+	return s.ECN, nil
+}
+
+// SetTCPECN implements Stack.
+func (s *TestStack) SetTCPECN(mode int32) error {
+	s.ECN = mode
+	return nil
+}
+
 // Statistics implements Stack.
 func (s *TestStack) Statistics(stat any, arg string) error {
 	return nil
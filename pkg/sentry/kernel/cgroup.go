@@ -18,6 +18,8 @@ import (
 	"bytes"
 	"fmt"
 	"sort"
+	"sync/atomic"
+	"time"
 
 	"gvisor.dev/gvisor/pkg/atomicbitops"
 	"gvisor.dev/gvisor/pkg/context"
@@ -81,6 +83,9 @@ type CgroupResourceType int
 const (
 	// CgroupResourcePID represents a charge for pids.current.
 	CgroupResourcePID CgroupResourceType = iota
+
+	// CgroupResourceMemory represents a charge for memory, in bytes.
+	CgroupResourceMemory
 )
 
 // CgroupController is the common interface to cgroup controllers available to
@@ -224,6 +229,29 @@ type CgroupImpl interface {
 
 	// ID returns the id of this cgroup.
 	ID() uint32
+
+	// Unified returns whether this cgroup is part of the cgroup v2 unified
+	// hierarchy.
+	Unified() bool
+
+	// OOMKill is called after t failed to allocate memory charged to this
+	// cgroup. If the allocation failed because this cgroup or one of its
+	// ancestors reached its memory limit, OOMKill kills a task in the
+	// offending cgroup's subtree to free memory (unless a previous victim is
+	// still exiting) and returns true. Otherwise, it returns false.
+	OOMKill(t *Task) bool
+
+	// ChargeCPUTime charges d of CPU time, consumed at time now, to the cpu
+	// controller of this cgroup and its ancestors. If doing so exhausts the
+	// CPU bandwidth quota of any of them, ChargeCPUTime returns the time at
+	// which tasks in this cgroup may run again; otherwise it returns 0. Times
+	// are in nanoseconds on the kernel's monotonic clock.
+	ChargeCPUTime(now int64, d time.Duration) int64
+
+	// CPUThrottledUntil returns the time at which tasks in this cgroup may run
+	// again if the cgroup is throttled due to its CPU bandwidth quota, and 0
+	// otherwise. Times are in nanoseconds on the kernel's monotonic clock.
+	CPUThrottledUntil(now int64) int64
 }
 
 // hierarchy represents a cgroupfs filesystem instance, with a unique set of
@@ -234,6 +262,9 @@ type CgroupImpl interface {
 type hierarchy struct {
 	id   uint32
 	name string
+	// unified indicates this is the cgroup v2 unified hierarchy. There is at
+	// most one unified hierarchy on the system.
+	unified bool
 	// These are a subset of the controllers in CgroupRegistry.controllers,
 	// grouped here by hierarchy for convenient lookup.
 	controllers map[CgroupControllerType]CgroupController
@@ -269,6 +300,10 @@ type cgroupFS interface {
 	// RootCgroup returns the root cgroup of this instance. This returns the
 	// actual root, and ignores any overrides setting an effective root.
 	RootCgroup() Cgroup
+
+	// Unified returns whether this filesystem is the cgroup v2 unified
+	// hierarchy.
+	Unified() bool
 }
 
 // CgroupRegistry tracks the active set of cgroup controllers on the system.
@@ -310,6 +345,17 @@ type CgroupRegistry struct {
 	//
 	// +checklocks:mu
 	cgroups map[uint32]CgroupImpl
+
+	// cgroupsSnapshot is a copy of cgroups that is replaced, rather than
+	// mutated, whenever a cgroup is added. It allows cgroups to be looked up
+	// without locking mu on hot paths, such as memory charging on every
+	// page allocation. cgroupsSnapshot is nil if it hasn't been created
+	// since the registry was restored.
+	cgroupsSnapshot atomic.Pointer[map[uint32]CgroupImpl] `state:"nosave"`
+
+	// cpuQuotas is the number of cpu controllers with a CFS bandwidth quota.
+	// CPU time is only charged to cgroups while it is non-zero.
+	cpuQuotas atomicbitops.Int64
 }
 
 func newCgroupRegistry() *CgroupRegistry {
@@ -340,7 +386,7 @@ func (r *CgroupRegistry) FindHierarchy(name string, ctypes []CgroupControllerTyp
 	// If we have a hierarchy name, lookup by name.
 	if name != "" {
 		h, ok := r.hierarchiesByName[name]
-		if !ok {
+		if !ok || h.unified {
 			// Name not found.
 			return nil, nil
 		}
@@ -361,6 +407,11 @@ func (r *CgroupRegistry) FindHierarchy(name string, ctypes []CgroupControllerTyp
 	}
 
 	for _, h := range r.hierarchies {
+		if h.unified {
+			// The unified hierarchy is only reachable through a cgroup2 mount,
+			// see FindUnifiedHierarchy.
+			continue
+		}
 		if h.match(ctypes) {
 			if !h.fs.TryIncRef() {
 				// Racing with filesystem destruction, namely h.fs.Release.
@@ -387,6 +438,54 @@ func (r *CgroupRegistry) FindHierarchy(name string, ctypes []CgroupControllerTyp
 	return nil, nil
 }
 
+// FindUnifiedHierarchy returns the cgroup v2 filesystem, if one exists. If no
+// such FS is found, FindUnifiedHierarchy returns nil. FindUnifiedHierarchy
+// takes a reference on the returned FS, which is transferred to the caller.
+func (r *CgroupRegistry) FindUnifiedHierarchy() *vfs.Filesystem {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.findUnifiedHierarchyLocked("")
+}
+
+// findUnifiedHierarchyLocked returns the unified hierarchy with a reference
+// held on it. If ctype isn't empty, the hierarchy must also have ctype
+// attached.
+//
+// +checklocks:r.mu
+func (r *CgroupRegistry) findUnifiedHierarchyLocked(ctype CgroupControllerType) *vfs.Filesystem {
+	for _, h := range r.hierarchies {
+		if !h.unified {
+			continue
+		}
+		if _, ok := h.controllers[ctype]; ctype != "" && !ok {
+			return nil
+		}
+		if !h.fs.TryIncRef() {
+			// Racing with filesystem destruction, see FindHierarchy.
+			r.unregisterLocked(h.id)
+			return nil
+		}
+		return h.fs
+	}
+	return nil
+}
+
+// UnboundControllers returns the subset of ctypes that isn't currently
+// attached to any hierarchy. The result is a snapshot, callers must still be
+// prepared for Register to fail.
+func (r *CgroupRegistry) UnboundControllers(ctypes []CgroupControllerType) []CgroupControllerType {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var unbound []CgroupControllerType
+	for _, ty := range ctypes {
+		if _, ok := r.controllers[ty]; !ok {
+			unbound = append(unbound, ty)
+		}
+	}
+	return unbound
+}
+
 // FindCgroup locates a cgroup with the given parameters.
 //
 // A cgroup is considered a match even if it contains other controllers on the
@@ -401,6 +500,12 @@ func (r *CgroupRegistry) FindCgroup(ctx context.Context, ctype CgroupControllerT
 	if err != nil {
 		return Cgroup{}, err
 	}
+	if vfsfs == nil {
+		// All controllers on the unified hierarchy share a single tree.
+		r.mu.Lock()
+		vfsfs = r.findUnifiedHierarchyLocked(ctype)
+		r.mu.Unlock()
+	}
 	if vfsfs == nil {
 		return Cgroup{}, fmt.Errorf("controller not active")
 	}
@@ -424,7 +529,8 @@ func (r *CgroupRegistry) Register(name string, cs []CgroupController, fs cgroupF
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if name == "" && len(cs) == 0 {
+	unified := fs.Unified()
+	if name == "" && len(cs) == 0 && !unified {
 		return fmt.Errorf("can't register hierarchy with both no controllers and no name")
 	}
 
@@ -438,6 +544,14 @@ func (r *CgroupRegistry) Register(name string, cs []CgroupController, fs cgroupF
 		return fmt.Errorf("hierarchy named %q already exists", name)
 	}
 
+	if unified {
+		for _, h := range r.hierarchies {
+			if h.unified {
+				return fmt.Errorf("unified hierarchy already exists")
+			}
+		}
+	}
+
 	hid, err := r.nextHierarchyID()
 	if err != nil {
 		return err
@@ -450,6 +564,7 @@ func (r *CgroupRegistry) Register(name string, cs []CgroupController, fs cgroupF
 	h := hierarchy{
 		id:          hid,
 		name:        name,
+		unified:     unified,
 		controllers: make(map[CgroupControllerType]CgroupController),
 		fs:          fs.VFSFilesystem(),
 	}
@@ -496,9 +611,11 @@ func (r *CgroupRegistry) computeInitialGroups(inherit map[Cgroup]struct{}) map[C
 	// Remember controllers from the inherited cgroups set...
 	for cg := range inherit {
 		cg.IncRef() // Ref transferred to caller.
+		// Hierarchies without controllers, such as named hierarchies or an
+		// empty unified hierarchy, are inherited as well.
+		cgset[cg] = struct{}{}
 		for _, ctl := range cg.Controllers() {
 			ctlSet[ctl.Type()] = ctl
-			cgset[cg] = struct{}{}
 		}
 	}
 
@@ -515,6 +632,25 @@ func (r *CgroupRegistry) computeInitialGroups(inherit map[Cgroup]struct{}) map[C
 			cgset[cg] = struct{}{}
 		}
 	}
+
+	// An empty unified hierarchy has no controllers to find its root through.
+	for _, h := range r.hierarchies {
+		if !h.unified || len(h.controllers) != 0 {
+			continue
+		}
+		found := false
+		for cg := range cgset {
+			if cg.HierarchyID() == h.id {
+				found = true
+				break
+			}
+		}
+		if !found {
+			cg := h.fs.Impl().(cgroupFS).RootCgroup()
+			cg.IncRef() // Ref transferred to caller.
+			cgset[cg] = struct{}{}
+		}
+	}
 	return cgset
 }
 
@@ -550,9 +686,32 @@ func (r *CgroupRegistry) NextCgroupID() (uint32, error) {
 func (r *CgroupRegistry) AddCgroup(cg CgroupImpl) {
 	r.mu.Lock()
 	r.cgroups[cg.ID()] = cg
+	r.updateCgroupsSnapshotLocked()
 	r.mu.Unlock()
 }
 
+// +checklocks:r.mu
+func (r *CgroupRegistry) updateCgroupsSnapshotLocked() {
+	cgroups := make(map[uint32]CgroupImpl, len(r.cgroups))
+	for cid, cg := range r.cgroups {
+		cgroups[cid] = cg
+	}
+	r.cgroupsSnapshot.Store(&cgroups)
+}
+
+// getCgroupFast is equivalent to GetCgroup, but usually doesn't lock r.mu.
+func (r *CgroupRegistry) getCgroupFast(cid uint32) (CgroupImpl, bool) {
+	cgroups := r.cgroupsSnapshot.Load()
+	if cgroups == nil {
+		r.mu.Lock()
+		r.updateCgroupsSnapshotLocked()
+		cgroups = r.cgroupsSnapshot.Load()
+		r.mu.Unlock()
+	}
+	cg, ok := (*cgroups)[cid]
+	return cg, ok
+}
+
 // GetCgroup returns the cgroup associated with the cgroup ID.
 func (r *CgroupRegistry) GetCgroup(cid uint32) (CgroupImpl, error) {
 	r.mu.Lock()
@@ -563,3 +722,55 @@ func (r *CgroupRegistry) GetCgroup(cid uint32) (CgroupImpl, error) {
 	}
 	return cg, nil
 }
+
+// CPUQuotaChanged must be called by cpu controllers when their CFS bandwidth
+// quota in microseconds changes from old to new, where a negative quota is
+// unlimited.
+func (r *CgroupRegistry) CPUQuotaChanged(old, new int64) {
+	switch {
+	case old < 0 && new >= 0:
+		r.cpuQuotas.Add(1)
+	case old >= 0 && new < 0:
+		r.cpuQuotas.Add(-1)
+	}
+}
+
+// hasCPUQuotas returns true if any cpu controller has a CFS bandwidth quota.
+func (r *CgroupRegistry) hasCPUQuotas() bool {
+	return r.cpuQuotas.Load() > 0
+}
+
+// memoryCgroupCharger implements pgalloc.MemoryCgroupCharger by charging the
+// memory controllers of k's cgroups.
+type memoryCgroupCharger struct {
+	k *Kernel
+}
+
+// TryCharge implements pgalloc.MemoryCgroupCharger.TryCharge.
+func (c memoryCgroupCharger) TryCharge(memCgID uint32, length uint64) error {
+	r := c.k.cgroupRegistry
+	if r == nil {
+		return nil
+	}
+	cg, ok := r.getCgroupFast(memCgID)
+	if !ok {
+		// Without a cgroup, there is no limit to enforce.
+		return nil
+	}
+	return cg.Charge(nil, nil, CgroupControllerMemory, CgroupResourceMemory, int64(length))
+}
+
+// Uncharge implements pgalloc.MemoryCgroupCharger.Uncharge.
+func (c memoryCgroupCharger) Uncharge(memCgID uint32, length uint64) {
+	r := c.k.cgroupRegistry
+	if r == nil {
+		return
+	}
+	cg, ok := r.getCgroupFast(memCgID)
+	if !ok {
+		return
+	}
+	if err := cg.Charge(nil, nil, CgroupControllerMemory, CgroupResourceMemory, -int64(length)); err != nil {
+		log.Warningf("Failed to uncharge %d bytes from memory cgroup %d: %v", length, memCgID, err)
+	}
+}
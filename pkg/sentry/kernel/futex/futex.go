@@ -126,6 +126,43 @@ func check(t Target, addr hostarch.Addr, val uint32) error {
 	return nil
 }
 
+// checkWord performs a basic equality check on the given futex word.
+func checkWord(t Target, f *Word, val uint64) error {
+	var cur uint64
+	switch f.Size {
+	case 4:
+		v, err := t.LoadUint32(f.Addr)
+		if err != nil {
+			return err
+		}
+		cur = uint64(v)
+	case 8:
+		// Both supported architectures are little-endian.
+		lo, err := t.LoadUint32(f.Addr)
+		if err != nil {
+			return err
+		}
+		hi, err := t.LoadUint32(f.Addr + 4)
+		if err != nil {
+			return err
+		}
+		cur = uint64(hi)<<32 | uint64(lo)
+	default:
+		// Load the aligned 32-bit word containing the futex word, which can't
+		// cross a page boundary.
+		aligned := f.Addr &^ 3
+		v, err := t.LoadUint32(aligned)
+		if err != nil {
+			return err
+		}
+		cur = uint64(v>>(8*(f.Addr-aligned))) & (1<<(8*f.Size) - 1)
+	}
+	if cur != val {
+		return linuxerr.EAGAIN
+	}
+	return nil
+}
+
 // atomicOp performs a complex operation on the given address.
 func atomicOp(t Target, addr hostarch.Addr, opIn uint32) (bool, error) {
 	opType := (opIn >> 28) & 0xf
@@ -232,6 +269,17 @@ type Waiter struct {
 
 	// tid is the thread ID for the waiter in case this is a PI mutex.
 	tid uint32
+
+	// requeuePI is true if the waiter was enqueued by WaitRequeuePIPrepare, in
+	// which case requeuePIKey is the key of the PI futex it may be requeued
+	// to. requeuePI and requeuePIKey are immutable while the waiter is
+	// enqueued.
+	requeuePI    bool
+	requeuePIKey Key
+
+	// requeuedPI is true if the waiter was requeued to requeuePIKey by
+	// CmpRequeuePI, after which it is woken when it acquires the PI futex.
+	requeuedPI bool
 }
 
 // NewWaiter returns a new unqueued Waiter.
@@ -241,6 +289,17 @@ func NewWaiter() *Waiter {
 	}
 }
 
+// NewWaiters returns n new unqueued Waiters that share the same C, for use
+// with WaitvPrepare.
+func NewWaiters(n int) []*Waiter {
+	c := make(chan struct{}, 1)
+	ws := make([]*Waiter, n)
+	for i := range ws {
+		ws[i] = &Waiter{C: c}
+	}
+	return ws
+}
+
 // woken returns true if w has been woken since the last call to WaitPrepare.
 func (w *Waiter) woken() bool {
 	return len(w.C) != 0
@@ -279,9 +338,13 @@ func (b *bucket) wakeLocked(key *Key, bitmask uint32, n int) int {
 }
 
 func (b *bucket) wakeWaiterLocked(w *Waiter) {
-	// Remove from the bucket and wake the waiter.
+	// Remove from the bucket and wake the waiter. w.C may be shared with other
+	// Waiters (see NewWaiters), so it may already be full.
 	b.waiters.Remove(w)
-	w.C <- struct{}{}
+	select {
+	case w.C <- struct{}{}:
+	default:
+	}
 
 	// NOTE: The above channel write establishes a write barrier according
 	// to the memory model, so nothing may be ordered around it. Since
@@ -328,10 +391,15 @@ const (
 
 // getKey returns a Key representing address addr in c.
 func getKey(t Target, addr hostarch.Addr, private bool) (Key, error) {
+	return getKeySized(t, addr, 4, private)
+}
+
+// getKeySized returns a Key representing a futex word of the given size at
+// address addr in c.
+func getKeySized(t Target, addr hostarch.Addr, size uint, private bool) (Key, error) {
 	addr = hostarch.UntaggedUserAddr(addr)
-	// Ensure the address is aligned.
-	// It must be a DWORD boundary.
-	if addr&0x3 != 0 {
+	// Ensure the address is naturally aligned.
+	if uint64(addr)&uint64(size-1) != 0 {
 		return Key{}, linuxerr.EINVAL
 	}
 	if private {
@@ -340,6 +408,23 @@ func getKey(t Target, addr hostarch.Addr, private bool) (Key, error) {
 	return t.GetSharedKey(addr)
 }
 
+// Word identifies a futex word, as used by the futex2 syscalls which support
+// futex words of sizes other than 32 bits.
+type Word struct {
+	// Addr is the address of the futex word.
+	Addr hostarch.Addr
+
+	// Size is the size of the futex word in bytes: 1, 2, 4 or 8.
+	Size uint
+
+	// Private is true if the futex word is private to the address space.
+	Private bool
+}
+
+func (f *Word) key(t Target) (Key, error) {
+	return getKeySized(t, f.Addr, f.Size, f.Private)
+}
+
 // bucketIndexForAddr returns the index into Manager.buckets for addr.
 func bucketIndexForAddr(addr hostarch.Addr) uintptr {
 	//	- The bottom 2 bits of addr must be 0, per getKey.
@@ -521,6 +606,31 @@ func (m *Manager) Requeue(t Target, addr, naddr hostarch.Addr, private bool, nwa
 	return m.doRequeue(t, addr, naddr, private, false, 0, nwake, nreq)
 }
 
+// RequeueWord atomically checks that the futex word from contains val (via the
+// Target), wakes up to nwake waiters on from and then requeues up to nreq
+// waiters on to.
+func (m *Manager) RequeueWord(t Target, from, to Word, val uint64, nwake int, nreq int) (int, error) {
+	k1, err := from.key(t)
+	if err != nil {
+		return 0, err
+	}
+	defer k1.release(t)
+	k2, err := to.key(t)
+	if err != nil {
+		return 0, err
+	}
+	defer k2.release(t)
+
+	b1, b2, lockedFirst, lockedSecond := m.lockBuckets(&k1, &k2)
+	defer m.unlockBuckets(lockedFirst, lockedSecond)
+
+	if err := checkWord(t, &from, val); err != nil {
+		return 0, err
+	}
+	done := b1.wakeLocked(&k1, ^uint32(0), nwake)
+	return done + b1.requeueLocked(t, b2, &k1, &k2, nreq), nil
+}
+
 // RequeueCmp atomically checks that the addr contains val (via the Target),
 // wakes up to nwake waiters on addr and then unconditionally requeues nreq
 // waiters on naddr.
@@ -602,10 +712,106 @@ func (m *Manager) WaitPrepare(w *Waiter, t Target, addr hostarch.Addr, private b
 	return nil
 }
 
+// WakeWord wakes up to n waiters matching the bitmask on the given futex word.
+// The number of waiters woken is returned.
+func (m *Manager) WakeWord(t Target, f Word, bitmask uint32, n int) (int, error) {
+	k, err := f.key(t)
+	if err != nil {
+		return 0, err
+	}
+	defer k.release(t)
+
+	b := m.lockBucket(&k)
+	defer b.mu.Unlock()
+	return b.wakeLocked(&k, bitmask, n), nil
+}
+
+// WaitPrepareWord is like WaitPrepare, but waits on a futex word of any size.
+func (m *Manager) WaitPrepareWord(w *Waiter, t Target, f Word, val uint64, bitmask uint32) error {
+	select {
+	case <-w.C:
+	default:
+	}
+	return m.enqueueWord(w, t, &f, val, bitmask)
+}
+
+// enqueueWord atomically checks that f contains val, then enqueues w to be
+// woken by a send to w.C.
+func (m *Manager) enqueueWord(w *Waiter, t Target, f *Word, val uint64, bitmask uint32) error {
+	k, err := f.key(t)
+	if err != nil {
+		return err
+	}
+	// Ownership of k is transferred to w below.
+	w.key = k
+	w.bitmask = bitmask
+
+	b := m.lockBucket(&k)
+	if err := checkWord(t, f, val); err != nil {
+		b.mu.Unlock()
+		w.key.release(t)
+		return err
+	}
+	b.waiters.PushBack(w)
+	w.bucket.Store(b)
+	b.mu.Unlock()
+	return nil
+}
+
+// WaitvPrepare atomically checks that each futex word in fs contains the
+// corresponding value in vals, and enqueues ws[i] to be woken on fs[i] by a
+// send to the C shared by ws (see NewWaiters).
+//
+// If WaitvPrepare returns (-1, nil), the Waiters must be subsequently removed
+// by calling WaitvComplete, whether or not a wakeup is received. Otherwise,
+// no Waiters are enqueued; if a Waiter was woken before WaitvPrepare failed,
+// WaitvPrepare returns its index and a nil error.
+//
+// Preconditions: len(ws) == len(fs) == len(vals) > 0.
+func (m *Manager) WaitvPrepare(ws []*Waiter, t Target, fs []Word, vals []uint64) (int, error) {
+	select {
+	case <-ws[0].C:
+	default:
+	}
+	for i := range fs {
+		if err := m.enqueueWord(ws[i], t, &fs[i], vals[i], linux.FUTEX_BITSET_MATCH_ANY); err != nil {
+			// Like Linux, report a wakeup that raced with the failure rather
+			// than losing it.
+			if woken := m.WaitvComplete(ws[:i], t); woken >= 0 {
+				return woken, nil
+			}
+			return -1, err
+		}
+	}
+	return -1, nil
+}
+
+// WaitvComplete must be called when Waiters previously added by WaitvPrepare
+// are no longer eligible to be woken. It returns the index of the last Waiter
+// in ws that was woken, or -1 if none were.
+func (m *Manager) WaitvComplete(ws []*Waiter, t Target) int {
+	woken := -1
+	for i, w := range ws {
+		if !m.dequeue(w) {
+			woken = i
+		}
+		w.key.release(t)
+	}
+	return woken
+}
+
 // WaitComplete must be called when a Waiter previously added by WaitPrepare is
 // no longer eligible to be woken.
 func (m *Manager) WaitComplete(w *Waiter, t Target) {
-	// Remove w from the bucket it's in.
+	m.dequeue(w)
+
+	// Release references held by the waiter.
+	w.key.release(t)
+}
+
+// dequeue removes w from the bucket it's in, if any. It returns true if w was
+// still enqueued, i.e. it wasn't woken.
+func (m *Manager) dequeue(w *Waiter) bool {
 	for {
 		b := w.bucket.Load()
 
@@ -613,7 +819,7 @@ func (m *Manager) WaitComplete(w *Waiter, t Target) {
 		// racy because the waiter can't be concurrently re-queued in another
 		// bucket.
 		if b == nil {
-			break
+			return false
 		}
 
 		// Take the bucket lock. Note that without holding the bucket lock, the
@@ -632,11 +838,8 @@ func (m *Manager) WaitComplete(w *Waiter, t Target) {
 		b.waiters.Remove(w)
 		w.bucket.Store(nil)
 		b.mu.Unlock()
-		break
+		return true
 	}
-
-	// Release references held by the waiter.
-	w.key.release(t)
 }
 
 // LockPI attempts to lock the futex following the Priority-inheritance futex
@@ -644,9 +847,8 @@ func (m *Manager) WaitComplete(w *Waiter, t Target) {
 // calling task is set to 'addr' to indicate the futex is owned. It returns true
 // if the futex was successfully acquired.
 //
-// FUTEX_OWNER_DIED is only set by the Linux when robust lists are in use (see
-// exit_robust_list()). Given we don't support robust lists, although handled
-// below, it's never set.
+// FUTEX_OWNER_DIED is set when the previous owner died while holding the futex
+// on its robust list (see HandOffPI), and is preserved on acquisition.
 func (m *Manager) LockPI(w *Waiter, t Target, addr hostarch.Addr, tid uint32, private, try bool) (bool, error) {
 	k, err := getKey(t, addr, private)
 	if err != nil {
@@ -692,9 +894,12 @@ func (m *Manager) lockPILocked(w *Waiter, t Target, addr hostarch.Addr, tid uint
 		if (cur & linux.FUTEX_TID_MASK) == 0 {
 			// No owner and no waiters, try to acquire the futex.
 
-			// Set TID and preserve owner died status.
+			// Set TID and preserve owner died status. Also preserve the waiters
+			// bit, which may be set if the previous owner died with waiters
+			// that haven't been handed the futex yet, so that the next unlock
+			// goes through the kernel.
 			val := tid
-			val |= cur & linux.FUTEX_OWNER_DIED
+			val |= cur & (linux.FUTEX_OWNER_DIED | linux.FUTEX_WAITERS)
 			prev, err := t.CompareAndSwapUint32(addr, cur, val)
 			if err != nil {
 				return false, err
@@ -764,20 +969,8 @@ func (m *Manager) unlockPILocked(t Target, addr hostarch.Addr, tid uint32, b *bu
 		return linuxerr.EPERM
 	}
 
-	var next *Waiter  // Who's the next owner?
-	var next2 *Waiter // Who's the one after that?
-	for w := b.waiters.Front(); w != nil; w = w.Next() {
-		if !w.key.matches(key) {
-			continue
-		}
-
-		if next == nil {
-			next = w
-		} else {
-			next2 = w
-			break
-		}
-	}
+	// Who's the next owner, and the one after that?
+	next, next2 := b.nextPIWaitersLocked(key)
 
 	if next == nil {
 		// It's safe to set 0 because there are no waiters, no new owner, and the
@@ -812,3 +1005,212 @@ func (m *Manager) unlockPILocked(t Target, addr hostarch.Addr, tid uint32, b *bu
 	b.wakeWaiterLocked(next)
 	return nil
 }
+
+// HandOffPI hands the PI futex at addr, whose owner died and which therefore
+// contains no TID, over to the next waiter, if any. FUTEX_OWNER_DIED is set in
+// the futex for the new owner to observe. It returns true if a waiter was
+// woken.
+func (m *Manager) HandOffPI(t Target, addr hostarch.Addr, private bool) (bool, error) {
+	k, err := getKey(t, addr, private)
+	if err != nil {
+		return false, err
+	}
+	defer k.release(t)
+	b := m.lockBucket(&k)
+	defer b.mu.Unlock()
+
+	for {
+		cur, err := t.LoadUint32(addr)
+		if err != nil {
+			return false, err
+		}
+		if cur&linux.FUTEX_TID_MASK != 0 {
+			// Someone else acquired the futex in the meantime.
+			return false, nil
+		}
+
+		next, next2 := b.nextPIWaitersLocked(&k)
+		if next == nil {
+			return false, nil
+		}
+		val := next.tid | linux.FUTEX_OWNER_DIED
+		if next2 != nil {
+			val |= linux.FUTEX_WAITERS
+		}
+		prev, err := t.CompareAndSwapUint32(addr, cur, val)
+		if err != nil {
+			return false, err
+		}
+		if prev != cur {
+			// CAS failed, retry...
+			continue
+		}
+		b.wakeWaiterLocked(next)
+		return true, nil
+	}
+}
+
+// nextPIWaitersLocked returns the next two waiters for the PI futex identified
+// by key, in FIFO order.
+//
+// Preconditions: b is locked.
+func (b *bucket) nextPIWaitersLocked(key *Key) (next, next2 *Waiter) {
+	for w := b.waiters.Front(); w != nil; w = w.Next() {
+		if !w.key.matches(key) {
+			continue
+		}
+		if next == nil {
+			next = w
+		} else {
+			return next, w
+		}
+	}
+	return next, nil
+}
+
+// WaitRequeuePIPrepare is like WaitPrepare, but additionally specifies that
+// the waiter, whose thread ID is tid, may be requeued by CmpRequeuePI to the
+// PI futex at addr2. The Waiter must be removed by calling
+// WaitRequeuePIComplete.
+func (m *Manager) WaitRequeuePIPrepare(w *Waiter, t Target, addr, addr2 hostarch.Addr, private bool, val uint32, tid uint32) error {
+	k2, err := getKey(t, addr2, private)
+	if err != nil {
+		return err
+	}
+	k, err := getKey(t, addr, private)
+	if err != nil {
+		k2.release(t)
+		return err
+	}
+	defer k.release(t)
+	if k.matches(&k2) {
+		k2.release(t)
+		return linuxerr.EINVAL
+	}
+
+	// Ownership of k2 is transferred to w.
+	w.tid = tid
+	w.requeuePI = true
+	w.requeuePIKey = k2
+	w.requeuedPI = false
+	if err := m.WaitPrepare(w, t, addr, private, val, linux.FUTEX_BITSET_MATCH_ANY); err != nil {
+		w.requeuePI = false
+		w.requeuePIKey.release(t)
+		return err
+	}
+	return nil
+}
+
+// WaitRequeuePIComplete must be called when a Waiter previously added by
+// WaitRequeuePIPrepare is no longer eligible to be woken. It returns whether
+// the waiter was requeued to the PI futex and whether it acquired it.
+func (m *Manager) WaitRequeuePIComplete(w *Waiter, t Target) (requeued, locked bool) {
+	woken := !m.dequeue(w)
+	requeued = w.requeuedPI
+	w.key.release(t)
+	w.requeuePI = false
+	w.requeuePIKey.release(t)
+	// A requeued waiter is only woken by being handed the PI futex.
+	return requeued, woken && requeued
+}
+
+// CmpRequeuePI atomically checks that addr contains val (via the Target),
+// tries to acquire the PI futex at naddr on behalf of the first waiter on
+// addr, waking it if successful, and then requeues up to nreq remaining
+// waiters to wait on naddr as PI waiters. All waiters on addr must have been
+// enqueued by WaitRequeuePIPrepare with naddr. It returns the number of
+// waiters woken or requeued.
+func (m *Manager) CmpRequeuePI(t Target, addr, naddr hostarch.Addr, private bool, val uint32, nreq int) (int, error) {
+	k1, err := getKey(t, addr, private)
+	if err != nil {
+		return 0, err
+	}
+	defer k1.release(t)
+	k2, err := getKey(t, naddr, private)
+	if err != nil {
+		return 0, err
+	}
+	defer k2.release(t)
+	if k1.matches(&k2) {
+		return 0, linuxerr.EINVAL
+	}
+
+	b1, b2, lockedFirst, lockedSecond := m.lockBuckets(&k1, &k2)
+	defer m.unlockBuckets(lockedFirst, lockedSecond)
+
+	if err := check(t, addr, val); err != nil {
+		return 0, err
+	}
+
+	// Validate the waiters before changing anything.
+	var waiters []*Waiter
+	for w := b1.waiters.Front(); w != nil && len(waiters) <= nreq; w = w.Next() {
+		if !w.key.matches(&k1) {
+			continue
+		}
+		if !w.requeuePI || !w.requeuePIKey.matches(&k2) {
+			return 0, linuxerr.EINVAL
+		}
+		waiters = append(waiters, w)
+	}
+	if len(waiters) == 0 {
+		return 0, nil
+	}
+
+	// Try to acquire the PI futex for the first waiter, like
+	// futex_proxy_trylock_atomic() in Linux.
+	done := 0
+	top := waiters[0]
+	locked, err := m.lockPILocked(top, t, naddr, top.tid, b2, true /* try */)
+	if err != nil {
+		return 0, err
+	}
+	if locked {
+		top.requeuePILocked(t, &k2)
+		b1.wakeWaiterLocked(top)
+		waiters = waiters[1:]
+		done++
+	}
+	if len(waiters) > nreq {
+		waiters = waiters[:nreq]
+	}
+	if len(waiters) == 0 {
+		return done, nil
+	}
+
+	// The remaining waiters block on the PI futex, so make sure its owner
+	// unlocks it through the kernel.
+	for {
+		cur, err := t.LoadUint32(naddr)
+		if err != nil {
+			return done, err
+		}
+		if cur&linux.FUTEX_WAITERS != 0 {
+			break
+		}
+		prev, err := t.CompareAndSwapUint32(naddr, cur, cur|linux.FUTEX_WAITERS)
+		if err != nil {
+			return done, err
+		}
+		if prev == cur {
+			break
+		}
+	}
+	for _, w := range waiters {
+		b1.waiters.Remove(w)
+		w.requeuePILocked(t, &k2)
+		b2.waiters.PushBack(w)
+		w.bucket.Store(b2)
+		done++
+	}
+	return done, nil
+}
+
+// requeuePILocked switches w to wait on the PI futex identified by key.
+//
+// Preconditions: The bucket containing w is locked.
+func (w *Waiter) requeuePILocked(t Target, key *Key) {
+	w.key.release(t)
+	w.key = key.clone()
+	w.requeuedPI = true
+}
@@ -20,6 +20,7 @@ import (
 	"testing"
 	"unsafe"
 
+	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/atomicbitops"
 	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
@@ -462,6 +463,141 @@ const (
 // futex manager in order to implement the sync.Locker interface.
 // Beyond being used as a Locker, this is a simple mechanism for
 // changing the underlying values for simpler tests.
+func TestWaitvWake(t *testing.T) {
+	for _, private := range []bool{false, true} {
+		t.Run(futexKind(private), func(t *testing.T) {
+			m := NewManager()
+			d := newTestData(16)
+			d.data[1] = 0x12
+			d.data[8] = 0x34
+
+			words := []Word{
+				{Addr: 1, Size: 1, Private: private},
+				{Addr: 2, Size: 2, Private: private},
+				{Addr: 8, Size: 8, Private: private},
+			}
+			ws := NewWaiters(len(words))
+			if woken, err := m.WaitvPrepare(ws, d, words, []uint64{0x12, 0, 0x34}); err != nil || woken != -1 {
+				t.Fatalf("WaitvPrepare: got (%d, %v), wanted (-1, nil)", woken, err)
+			}
+
+			// Wake the second futex word.
+			if n, err := m.WakeWord(d, words[1], ^uint32(0), 1); err != nil || n != 1 {
+				t.Errorf("WakeWord: got (%d, %v), wanted (1, nil)", n, err)
+			}
+			if !ws[0].woken() {
+				t.Error("waiters not woken")
+			}
+			if woken := m.WaitvComplete(ws, d); woken != 1 {
+				t.Errorf("WaitvComplete: got %d, wanted 1", woken)
+			}
+		})
+	}
+}
+
+func TestWaitvMismatch(t *testing.T) {
+	for _, private := range []bool{false, true} {
+		t.Run(futexKind(private), func(t *testing.T) {
+			m := NewManager()
+			d := newTestData(2 * sizeofInt32)
+
+			words := []Word{
+				{Addr: 0, Size: 4, Private: private},
+				{Addr: sizeofInt32, Size: 4, Private: private},
+			}
+			ws := NewWaiters(len(words))
+			if _, err := m.WaitvPrepare(ws, d, words, []uint64{0, 1}); err != linuxerr.EAGAIN {
+				t.Fatalf("WaitvPrepare: got %v, wanted EAGAIN", err)
+			}
+
+			// No waiters should remain queued.
+			if n, err := m.WakeWord(d, words[0], ^uint32(0), 1); err != nil || n != 0 {
+				t.Errorf("WakeWord: got (%d, %v), wanted (0, nil)", n, err)
+			}
+		})
+	}
+}
+
+func TestCmpRequeuePI(t *testing.T) {
+	for _, private := range []bool{false, true} {
+		t.Run(futexKind(private), func(t *testing.T) {
+			m := NewManager()
+			d := newTestData(2 * sizeofInt32)
+
+			// Wait on the first futex to be requeued to the second.
+			var ws []*Waiter
+			for tid := uint32(1); tid <= 2; tid++ {
+				w := NewWaiter()
+				if err := m.WaitRequeuePIPrepare(w, d, 0, sizeofInt32, private, 0, tid); err != nil {
+					t.Fatalf("WaitRequeuePIPrepare failed: %v", err)
+				}
+				ws = append(ws, w)
+			}
+
+			// The first waiter acquires the unowned PI futex, the second is
+			// requeued.
+			if n, err := m.CmpRequeuePI(d, 0, sizeofInt32, private, 0, 1); err != nil || n != 2 {
+				t.Fatalf("CmpRequeuePI: got (%d, %v), wanted (2, nil)", n, err)
+			}
+			if !ws[0].woken() || ws[1].woken() {
+				t.Fatalf("got woken (%t, %t), wanted (true, false)", ws[0].woken(), ws[1].woken())
+			}
+			if got, want := mustLoad(t, d, sizeofInt32), uint32(1|linux.FUTEX_WAITERS); got != want {
+				t.Errorf("PI futex: got %#x, wanted %#x", got, want)
+			}
+
+			// Unlocking the PI futex hands it to the requeued waiter.
+			if err := m.UnlockPI(d, sizeofInt32, 1, private); err != nil {
+				t.Fatalf("UnlockPI failed: %v", err)
+			}
+			if !ws[1].woken() {
+				t.Error("requeued waiter not woken")
+			}
+			for i, w := range ws {
+				if requeued, locked := m.WaitRequeuePIComplete(w, d); !requeued || !locked {
+					t.Errorf("WaitRequeuePIComplete(%d): got (%t, %t), wanted (true, true)", i, requeued, locked)
+				}
+			}
+		})
+	}
+}
+
+func TestHandOffPI(t *testing.T) {
+	for _, private := range []bool{false, true} {
+		t.Run(futexKind(private), func(t *testing.T) {
+			m := NewManager()
+			d := newTestData(sizeofInt32)
+			d.SwapUint32(0, 1)
+
+			w := NewWaiter()
+			if locked, err := m.LockPI(w, d, 0, 2, private, false); err != nil || locked {
+				t.Fatalf("LockPI: got (%t, %v), wanted (false, nil)", locked, err)
+			}
+			defer m.WaitComplete(w, d)
+
+			// Simulate the death of the owner.
+			d.SwapUint32(0, linux.FUTEX_WAITERS|linux.FUTEX_OWNER_DIED)
+			if woken, err := m.HandOffPI(d, 0, private); err != nil || !woken {
+				t.Fatalf("HandOffPI: got (%t, %v), wanted (true, nil)", woken, err)
+			}
+			if !w.woken() {
+				t.Error("waiter not woken")
+			}
+			if got, want := mustLoad(t, d, 0), uint32(2|linux.FUTEX_OWNER_DIED); got != want {
+				t.Errorf("PI futex: got %#x, wanted %#x", got, want)
+			}
+		})
+	}
+}
+
+func mustLoad(t *testing.T, d testData, addr hostarch.Addr) uint32 {
+	v, err := d.LoadUint32(addr)
+	if err != nil {
+		t.Fatalf("LoadUint32 failed: %v", err)
+	}
+	return v
+}
+
 type testMutex struct {
 	a hostarch.Addr
 	d testData
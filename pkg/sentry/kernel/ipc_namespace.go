@@ -153,3 +153,26 @@ func (t *Task) GetIPCNamespace() *IPCNamespace {
 	}
 	return t.ipcns
 }
+
+// SemUndoList returns the list of System V semaphore adjustments of t,
+// creating it if t doesn't have one.
+//
+// Preconditions: The caller must be running on the task goroutine.
+func (t *Task) SemUndoList() *semaphore.UndoList {
+	if t.semUndoList == nil {
+		t.semUndoList = semaphore.NewUndoList()
+	}
+	return t.semUndoList
+}
+
+// releaseSemUndoList drops t's reference on its list of System V semaphore
+// adjustments, which are applied if no other task shares the list.
+//
+// Preconditions: The caller must be running on the task goroutine.
+func (t *Task) releaseSemUndoList() {
+	if t.semUndoList == nil {
+		return
+	}
+	t.semUndoList.DecRef(int32(t.k.tasks.Root.IDOfThreadGroup(t.tg)))
+	t.semUndoList = nil
+}
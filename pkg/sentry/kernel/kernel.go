@@ -57,7 +57,6 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/fsimpl/nsfs"
 	"gvisor.dev/gvisor/pkg/sentry/fsimpl/pipefs"
 	"gvisor.dev/gvisor/pkg/sentry/fsimpl/sockfs"
-	"gvisor.dev/gvisor/pkg/sentry/fsimpl/timerfd"
 	"gvisor.dev/gvisor/pkg/sentry/fsimpl/tmpfs"
 	"gvisor.dev/gvisor/pkg/sentry/hostcpu"
 	"gvisor.dev/gvisor/pkg/sentry/inet"
@@ -184,6 +183,9 @@ type Kernel struct {
 	rootUTSNamespace     *UTSNamespace
 	rootIPCNamespace     *IPCNamespace
 
+	// rootTimeNamespace is the root time namespace, whose offsets are zero.
+	rootTimeNamespace *TimeNamespace
+
 	// futexes is the "root" futex.Manager, from which all others are forked.
 	// This is necessary to ensure that shared futexes are coherent across all
 	// tasks, including those created by CreateProcess.
@@ -500,6 +502,7 @@ func (k *Kernel) Init(args InitKernelArgs) error {
 	k.extraAuxv = args.ExtraAuxv
 	k.vdso = args.Vdso
 	k.vdsoParams = args.VdsoParams
+	k.rootTimeNamespace = NewRootTimeNamespace(k, args.RootUserNamespace)
 	k.futexes = futex.NewManager()
 	k.netlinkPorts = port.New()
 	k.ptraceExceptions = make(map[*Task]*Task)
@@ -539,6 +542,7 @@ func (k *Kernel) Init(args InitKernelArgs) error {
 	k.rootNetworkNamespace.SetInode(nsfs.NewInode(ctx, k.nsfsMount, k.rootNetworkNamespace))
 	k.rootIPCNamespace.SetInode(nsfs.NewInode(ctx, k.nsfsMount, k.rootIPCNamespace))
 	k.rootUTSNamespace.SetInode(nsfs.NewInode(ctx, k.nsfsMount, k.rootUTSNamespace))
+	k.rootTimeNamespace.SetInode(nsfs.NewInode(ctx, k.nsfsMount, k.rootTimeNamespace))
 
 	args.RootPIDNamespace.InitInode(ctx, k)
 
@@ -1141,6 +1145,11 @@ func (k *Kernel) CreateProcess(args CreateProcessArgs) (*ThreadGroup, ThreadID,
 	}
 	config.UTSNamespace.IncRef()
 	config.IPCNamespace.IncRef()
+	// Processes created by CreateProcess start in the root time namespace.
+	config.TimeNamespace = k.rootTimeNamespace
+	config.TimeNamespace.IncRef()
+	config.TimeNamespaceForChildren = k.rootTimeNamespace
+	config.TimeNamespaceForChildren.IncRef()
 	config.NetworkNamespace.IncRef()
 	t, err := k.tasks.NewTask(ctx, config)
 	if err != nil {
@@ -1208,6 +1217,16 @@ func (k *Kernel) Start() error {
 	return nil
 }
 
+// TimerFile is implemented by file descriptions that own Timers, such as
+// timerfds, whose Timers must be paused along with the rest of the Kernel.
+type TimerFile interface {
+	// PauseTimer pauses the file's Timers.
+	PauseTimer()
+
+	// ResumeTimer resumes the file's Timers.
+	ResumeTimer()
+}
+
 // pauseTimeLocked pauses all Timers and Timekeeper updates.
 //
 // Preconditions:
@@ -1243,8 +1262,8 @@ func (k *Kernel) pauseTimeLocked(ctx context.Context) {
 		// but ktime.Timer.Pause is idempotent so this is harmless.
 		if t.fdTable != nil {
 			t.fdTable.ForEach(ctx, func(_ int32, fd *vfs.FileDescription, _ FDFlags) bool {
-				if tfd, ok := fd.Impl().(*timerfd.TimerFileDescription); ok {
-					tfd.PauseTimer()
+				if tf, ok := fd.Impl().(TimerFile); ok {
+					tf.PauseTimer()
 				}
 				return true
 			})
@@ -1274,8 +1293,8 @@ func (k *Kernel) resumeTimeLocked(ctx context.Context) {
 		}
 		if t.fdTable != nil {
 			t.fdTable.ForEach(ctx, func(_ int32, fd *vfs.FileDescription, _ FDFlags) bool {
-				if tfd, ok := fd.Impl().(*timerfd.TimerFileDescription); ok {
-					tfd.ResumeTimer()
+				if tf, ok := fd.Impl().(TimerFile); ok {
+					tf.ResumeTimer()
 				}
 				return true
 			})
@@ -1518,6 +1537,11 @@ func (k *Kernel) RootUTSNamespace() *UTSNamespace {
 	return k.rootUTSNamespace
 }
 
+// RootTimeNamespace returns the root TimeNamespace.
+func (k *Kernel) RootTimeNamespace() *TimeNamespace {
+	return k.rootTimeNamespace
+}
+
 // RootIPCNamespace takes a reference and returns the root IPCNamespace.
 func (k *Kernel) RootIPCNamespace() *IPCNamespace {
 	return k.rootIPCNamespace
@@ -1639,6 +1663,7 @@ func (k *Kernel) SetSaveError(err error) {
 // LoadFrom.
 func (k *Kernel) SetMemoryFile(mf *pgalloc.MemoryFile) {
 	k.mf = mf
+	mf.SetMemoryCgroupCharger(memoryCgroupCharger{k})
 }
 
 // MemoryFile returns the MemoryFile that provides application memory.
@@ -1911,6 +1936,7 @@ func (k *Kernel) Release() {
 	k.RootNetworkNamespace().DecRef(ctx)
 	k.rootIPCNamespace.DecRef(ctx)
 	k.rootUTSNamespace.DecRef(ctx)
+	k.rootTimeNamespace.DecRef(ctx)
 	k.cleaupDevGofers()
 	k.mf.Destroy()
 	k.RootPIDNamespace().DecRef(ctx)
@@ -147,7 +147,7 @@ func (r *Registry) FindOrCreate(ctx context.Context, opts OpenOpts, mode linux.F
 
 	// Construct status flags.
 	var flags uint32
-	if opts.Block {
+	if !opts.Block {
 		flags = linux.O_NONBLOCK
 	}
 	switch opts.Access {
@@ -271,13 +271,20 @@ type Queue struct {
 	// queue is the queue of waiters.
 	queue waiter.Queue
 
-	// messages is a list of messages currently in the queue.
+	// messages is a list of messages currently in the queue. Messages are
+	// kept in decreasing order of priority, and in FIFO order among messages
+	// of equal priority.
 	messages msgList
 
 	// subscriber represents a task registered to receive async notification
 	// from this queue.
 	subscriber *Subscriber
 
+	// blockedReceivers is the number of tasks blocked in Receive waiting for
+	// a message to arrive. Notifications are only delivered if no task is
+	// waiting to receive.
+	blockedReceivers int
+
 	// messageCount is the number of messages currently in the queue.
 	messageCount int64
 
@@ -296,14 +303,28 @@ type Queue struct {
 // descriptions, but not inodes, because we use inodes to retrieve the actual
 // queue, and only FDs are responsible for providing user functionality.
 type View interface {
-	// TODO: Add Send and Receive when mq_timedsend(2) and mq_timedreceive(2)
-	// are implemented.
+	// Send adds a message to the queue, blocking if the queue is full and
+	// block is true. See mq_timedsend(2).
+	Send(ctx context.Context, msg *Message, b Blocker, block bool) error
+
+	// Receive removes the oldest message of the highest priority from the
+	// queue and returns it, blocking if the queue is empty and block is true.
+	// bufSize is the size of the buffer the message will be copied into. See
+	// mq_timedreceive(2).
+	Receive(ctx context.Context, b Blocker, bufSize uint64, block bool) (*Message, error)
+
+	// Subscribe registers the calling process to be notified when a message
+	// arrives on the empty queue. See mq_notify(2).
+	Subscribe(ctx context.Context, method, signo int32, notifier Notifier) error
 
 	// Flush checks if the calling process has attached a notification request
 	// to this queue, if yes, then the request is removed, and another process
 	// can attach a request.
 	Flush(ctx context.Context)
 
+	// Attr returns the attributes of the queue. See mq_getsetattr(2).
+	Attr() linux.MqAttr
+
 	waiter.Waitable
 }
 
@@ -316,7 +337,17 @@ type ReaderWriter struct {
 	block bool
 }
 
-// Reader provides a send-only view into a queue.
+// Send implements View.Send.
+func (rw ReaderWriter) Send(ctx context.Context, msg *Message, b Blocker, block bool) error {
+	return rw.Queue.send(ctx, msg, b, block)
+}
+
+// Receive implements View.Receive.
+func (rw ReaderWriter) Receive(ctx context.Context, b Blocker, bufSize uint64, block bool) (*Message, error) {
+	return rw.Queue.receive(ctx, b, bufSize, block)
+}
+
+// Reader provides a receive-only view into a queue.
 //
 // +stateify savable
 type Reader struct {
@@ -325,7 +356,18 @@ type Reader struct {
 	block bool
 }
 
-// Writer provides a receive-only view into a queue.
+// Send implements View.Send.
+func (Reader) Send(context.Context, *Message, Blocker, bool) error {
+	// "mqdes is not a valid message queue descriptor open for writing."
+	return linuxerr.EBADF
+}
+
+// Receive implements View.Receive.
+func (r Reader) Receive(ctx context.Context, b Blocker, bufSize uint64, block bool) (*Message, error) {
+	return r.Queue.receive(ctx, b, bufSize, block)
+}
+
+// Writer provides a send-only view into a queue.
 //
 // +stateify savable
 type Writer struct {
@@ -334,6 +376,17 @@ type Writer struct {
 	block bool
 }
 
+// Send implements View.Send.
+func (w Writer) Send(ctx context.Context, msg *Message, b Blocker, block bool) error {
+	return w.Queue.send(ctx, msg, b, block)
+}
+
+// Receive implements View.Receive.
+func (Writer) Receive(context.Context, Blocker, uint64, bool) (*Message, error) {
+	// "mqdes is not a valid message queue descriptor open for reading."
+	return nil, linuxerr.EBADF
+}
+
 // NewView creates a new view into a queue and returns it.
 func NewView(q *Queue, access AccessType, block bool) (View, error) {
 	switch access {
@@ -367,14 +420,44 @@ type Message struct {
 	Priority uint32
 }
 
+// Blocker is used for blocking Queue.Send and Queue.Receive calls that serves
+// as an abstracted version of kernel.Task. kernel.Task is not directly used to
+// prevent circular dependencies. Implementations are responsible for
+// enforcing the timeout passed to mq_timedsend(2) and mq_timedreceive(2).
+type Blocker interface {
+	Block(C <-chan struct{}) error
+}
+
+// Notifier delivers an asynchronous notification requested via mq_notify(2).
+// It is implemented outside this package, as delivering signals and netlink
+// messages requires access to kernel objects that would otherwise cause
+// circular dependencies.
+type Notifier interface {
+	// Notify delivers the notification. ctx is the context of the task that
+	// sent the message triggering the notification.
+	Notify(ctx context.Context)
+
+	// Remove is called when the registration is removed without the
+	// notification being delivered.
+	Remove(ctx context.Context)
+}
+
 // Subscriber represents a task registered for async notification from a Queue.
 //
 // +stateify savable
 type Subscriber struct {
-	// TODO: Add fields when mq_notify(2) is implemented.
-
 	// pid is the PID of the registered task.
 	pid int32
+
+	// method is the notification method, one of SIGEV_NONE, SIGEV_SIGNAL and
+	// SIGEV_THREAD.
+	method int32
+
+	// signo is the signal number sent for SIGEV_SIGNAL notifications.
+	signo int32
+
+	// notifier delivers the notification. It is nil for SIGEV_NONE.
+	notifier Notifier
 }
 
 // Generate implements vfs.DynamicBytesSource.Generate. Queue is used as a
@@ -385,12 +468,15 @@ func (q *Queue) Generate(ctx context.Context, buf *bytes.Buffer) error {
 
 	var (
 		pid       int32
-		method    int
-		sigNumber int
+		method    int32
+		sigNumber int32
 	)
 	if q.subscriber != nil {
 		pid = q.subscriber.pid
-		// TODO: add method and sigNumber when mq_notify(2) is implemented.
+		method = q.subscriber.method
+		if method == linux.SIGEV_SIGNAL {
+			sigNumber = q.subscriber.signo
+		}
 	}
 
 	buf.WriteString(
@@ -400,16 +486,197 @@ func (q *Queue) Generate(ctx context.Context, buf *bytes.Buffer) error {
 	return nil
 }
 
-// Flush implements View.Flush.
-func (q *Queue) Flush(ctx context.Context) {
+// send adds msg to the queue. If the queue is full and block is true, send
+// blocks using b until space is available.
+func (q *Queue) send(ctx context.Context, msg *Message, b Blocker, block bool) error {
+	// "msg_len was greater than the mq_msgsize attribute of the message
+	//  queue."
+	if msg.Size > q.maxMessageSize {
+		return linuxerr.EMSGSIZE
+	}
+
+	// Fast path: first attempt a non-blocking push.
+	if err := q.push(ctx, msg); err != linuxerr.EWOULDBLOCK {
+		return err
+	}
+
+	if !block {
+		return linuxerr.EAGAIN
+	}
+
+	// Slow path: at this point, the queue was found to be full, and we were
+	// asked to block.
+	e, ch := waiter.NewChannelEntry(waiter.WritableEvents)
+	q.EventRegister(&e)
+	defer q.EventUnregister(&e)
+
+	// Note: we need to check again before blocking the first time since space
+	// may have become available.
+	for {
+		if err := q.push(ctx, msg); err != linuxerr.EWOULDBLOCK {
+			return err
+		}
+		if err := b.Block(ch); err != nil {
+			return err
+		}
+	}
+}
+
+// push inserts msg into the queue according to its priority and notifies
+// waiting receivers. It returns EWOULDBLOCK if the queue is full.
+func (q *Queue) push(ctx context.Context, msg *Message) error {
+	q.mu.Lock()
+	if q.messageCount >= q.maxMessageCount {
+		q.mu.Unlock()
+		return linuxerr.EWOULDBLOCK
+	}
+
+	pos := q.messages.Back()
+	for pos != nil && pos.Priority < msg.Priority {
+		pos = pos.Prev()
+	}
+	if pos == nil {
+		q.messages.PushFront(msg)
+	} else {
+		q.messages.InsertAfter(pos, msg)
+	}
+	q.messageCount++
+	q.byteCount += msg.Size
+
+	// Similar to ipc/mqueue.c::__do_notify, notify the subscriber only if the
+	// queue was previously empty and no task is waiting to receive the
+	// message. The registration is removed once the notification is sent.
+	var sub *Subscriber
+	if q.messageCount == 1 && q.blockedReceivers == 0 && q.subscriber != nil {
+		sub = q.subscriber
+		q.subscriber = nil
+	}
+	q.mu.Unlock()
+
+	q.queue.Notify(waiter.ReadableEvents)
+	if sub != nil && sub.notifier != nil {
+		sub.notifier.Notify(ctx)
+	}
+	return nil
+}
+
+// receive removes the first message from the queue and returns it. If the
+// queue is empty and block is true, receive blocks using b until a message is
+// available.
+func (q *Queue) receive(ctx context.Context, b Blocker, bufSize uint64, block bool) (*Message, error) {
+	// "msg_len was less than the mq_msgsize attribute of the message queue."
+	if bufSize < q.maxMessageSize {
+		return nil, linuxerr.EMSGSIZE
+	}
+
+	// Fast path: first attempt a non-blocking pop.
+	if msg, err := q.pop(); err != linuxerr.EWOULDBLOCK {
+		return msg, err
+	}
+
+	if !block {
+		return nil, linuxerr.EAGAIN
+	}
+
+	// Slow path: at this point, the queue was found to be empty, and we were
+	// asked to block.
+	e, ch := waiter.NewChannelEntry(waiter.ReadableEvents)
+	q.EventRegister(&e)
+	defer q.EventUnregister(&e)
+
+	q.mu.Lock()
+	q.blockedReceivers++
+	q.mu.Unlock()
+	defer func() {
+		q.mu.Lock()
+		q.blockedReceivers--
+		q.mu.Unlock()
+	}()
+
+	// Note: we need to check again before blocking the first time since a
+	// message may have become available.
+	for {
+		if msg, err := q.pop(); err != linuxerr.EWOULDBLOCK {
+			return msg, err
+		}
+		if err := b.Block(ch); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// pop removes the first message from the queue and notifies waiting senders.
+// It returns EWOULDBLOCK if the queue is empty.
+func (q *Queue) pop() (*Message, error) {
+	q.mu.Lock()
+	msg := q.messages.Front()
+	if msg == nil {
+		q.mu.Unlock()
+		return nil, linuxerr.EWOULDBLOCK
+	}
+	q.messages.Remove(msg)
+	q.messageCount--
+	q.byteCount -= msg.Size
+	q.mu.Unlock()
+
+	q.queue.Notify(waiter.WritableEvents)
+	return msg, nil
+}
+
+// Subscribe implements View.Subscribe.
+func (q *Queue) Subscribe(ctx context.Context, method, signo int32, notifier Notifier) error {
+	pid, ok := auth.ThreadGroupIDFromContext(ctx)
+	if !ok {
+		return linuxerr.EINVAL
+	}
+
 	q.mu.Lock()
 	defer q.mu.Unlock()
 
+	// "Another process has already registered to receive notification for
+	//  this message queue."
+	if q.subscriber != nil {
+		return linuxerr.EBUSY
+	}
+	q.subscriber = &Subscriber{
+		pid:      pid,
+		method:   method,
+		signo:    signo,
+		notifier: notifier,
+	}
+	return nil
+}
+
+// Flush implements View.Flush.
+func (q *Queue) Flush(ctx context.Context) {
 	pid, ok := auth.ThreadGroupIDFromContext(ctx)
-	if ok {
-		if q.subscriber != nil && pid == q.subscriber.pid {
-			q.subscriber = nil
-		}
+	if !ok {
+		return
+	}
+
+	q.mu.Lock()
+	sub := q.subscriber
+	if sub == nil || sub.pid != pid {
+		q.mu.Unlock()
+		return
+	}
+	q.subscriber = nil
+	q.mu.Unlock()
+
+	if sub.notifier != nil {
+		sub.notifier.Remove(ctx)
+	}
+}
+
+// Attr implements View.Attr. The returned MqFlags is always zero, as
+// O_NONBLOCK is a property of the file description rather than the queue.
+func (q *Queue) Attr() linux.MqAttr {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return linux.MqAttr{
+		MqMaxmsg:  q.maxMessageCount,
+		MqMsgsize: int64(q.maxMessageSize),
+		MqCurmsgs: q.messageCount,
 	}
 }
 
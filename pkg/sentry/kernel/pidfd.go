@@ -0,0 +1,106 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kernel
+
+import (
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/waiter"
+)
+
+// PIDFD implements vfs.FileDescriptionImpl for process file descriptors, as
+// returned by pidfd_open(2) and clone(CLONE_PIDFD). It refers to a thread
+// group, rather than to a thread ID that may be reused.
+//
+// +stateify savable
+type PIDFD struct {
+	vfsfd vfs.FileDescription
+	vfs.FileDescriptionDefaultImpl
+	vfs.DentryMetadataFileDescriptionImpl
+	vfs.NoLockFD
+
+	// tg is the thread group referred to by the pidfd. tg is immutable.
+	tg *ThreadGroup
+}
+
+var _ vfs.FileDescriptionImpl = (*PIDFD)(nil)
+
+// NewPIDFD returns a new pidfd referring to tg. flags may contain
+// linux.O_NONBLOCK.
+func (k *Kernel) NewPIDFD(ctx context.Context, tg *ThreadGroup, flags uint32) (*vfs.FileDescription, error) {
+	vd := k.VFS().NewAnonVirtualDentry("[pidfd]")
+	defer vd.DecRef(ctx)
+	fd := &PIDFD{tg: tg}
+	if err := fd.vfsfd.Init(fd, linux.O_RDWR|flags, vd.Mount(), vd.Dentry(), &vfs.FileDescriptionOptions{
+		UseDentryMetadata: true,
+		DenyPRead:         true,
+		DenyPWrite:        true,
+		DenySpliceIn:      true,
+	}); err != nil {
+		return nil, err
+	}
+	return &fd.vfsfd, nil
+}
+
+// ThreadGroup returns the thread group referred to by fd.
+func (fd *PIDFD) ThreadGroup() *ThreadGroup {
+	return fd.tg
+}
+
+// Release implements vfs.FileDescriptionImpl.Release.
+func (fd *PIDFD) Release(context.Context) {}
+
+// Readiness implements waiter.Waitable.Readiness.
+//
+// Like Linux, a pidfd is readable once all tasks in its thread group have
+// exited, and reports a hang up once the thread group has been reaped.
+func (fd *PIDFD) Readiness(mask waiter.EventMask) waiter.EventMask {
+	ts := fd.tg.TaskSet()
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	var ready waiter.EventMask
+	if fd.tg.exitedLocked() {
+		ready |= waiter.ReadableEvents
+	}
+	if fd.tg.tasksCount == 0 {
+		ready |= waiter.EventHUp
+	}
+	return mask & ready
+}
+
+// EventRegister implements waiter.Waitable.EventRegister.
+func (fd *PIDFD) EventRegister(e *waiter.Entry) error {
+	fd.tg.exitQueue.EventRegister(e)
+	return nil
+}
+
+// EventUnregister implements waiter.Waitable.EventUnregister.
+func (fd *PIDFD) EventUnregister(e *waiter.Entry) {
+	fd.tg.exitQueue.EventUnregister(e)
+}
+
+// Epollable implements FileDescriptionImpl.Epollable.
+func (fd *PIDFD) Epollable() bool {
+	return true
+}
+
+// exitedLocked returns true if all tasks in tg have exited. This is
+// equivalent to Linux's thread_group_exited().
+//
+// Preconditions: The TaskSet mutex must be locked.
+func (tg *ThreadGroup) exitedLocked() bool {
+	return tg.leader != nil && tg.liveTasks == 0
+}
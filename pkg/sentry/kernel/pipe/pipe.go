@@ -21,6 +21,7 @@ import (
 
 	"golang.org/x/sys/unix"
 	"gvisor.dev/gvisor/pkg/atomicbitops"
+	"gvisor.dev/gvisor/pkg/buffer"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/hostarch"
 	"gvisor.dev/gvisor/pkg/safemem"
@@ -44,6 +45,11 @@ const (
 	// guarantee atomic reads or writes atomically.
 	// It corresponds to limits.h:PIPE_BUF.
 	atomicIOBytes = 4096
+
+	// pipeChunkSize is the size of the chunks that data copied into the pipe
+	// is stored in, and the amount of memory that each buffer slot of the
+	// pipe accounts for, like a page in a Linux pipe_buffer.
+	pipeChunkSize = hostarch.PageSize
 )
 
 // waitReaders is a wrapper around Pipe.
@@ -131,21 +137,37 @@ type Pipe struct {
 	// mu protects all pipe internal state below.
 	mu pipeMutex `state:"nosave"`
 
-	// buf holds the pipe's data. buf is a circular buffer; the first valid
-	// byte in buf is at offset off, and the pipe contains size valid bytes.
-	// bufBlocks contains two identical safemem.Blocks representing buf; this
-	// avoids needing to heap-allocate a new safemem.Block slice when buf is
-	// resized. bufBlockSeq is a safemem.BlockSeq representing bufBlocks.
+	// buf holds the pipe's data. buf consists of reference-counted chunks,
+	// which may be shared with other pipes (after tee(2)) or with netstack
+	// (after splice(2) to or from a socket).
+	//
+	// buf is protected by mu.
+	buf buffer.Buffer
+
+	// views is the number of views in buf, and charged is the total
+	// capacity of their chunks. Like Linux's pipe buffer slots, each view
+	// in buf holds its whole chunk in memory regardless of how few bytes it
+	// contains, so views is limited to max / pipeChunkSize and charged is
+	// limited to max (see freeLocked).
+	//
+	// These fields are protected by mu, and are recomputed from buf by
+	// afterLoad.
+	views   int64 `state:"nosave"`
+	charged int64 `state:"nosave"`
+
+	// readBlocks and writeBlocks are scratch space for the safemem.Blocks
+	// passed to the callbacks of peekLocked and writeLocked respectively,
+	// and writeViews is scratch space for the buffer.Views backing
+	// writeBlocks. They avoid heap-allocating a new slice for each read or
+	// write.
 	//
 	// These fields are protected by mu.
-	buf         []byte
-	bufBlocks   [2]safemem.Block `state:"nosave"`
-	bufBlockSeq safemem.BlockSeq `state:"nosave"`
-	off         int64
-	size        int64
-
-	// max is the maximum size of the pipe in bytes. When this max has been
-	// reached, writers will get EWOULDBLOCK.
+	readBlocks  []safemem.Block `state:"nosave"`
+	writeBlocks []safemem.Block `state:"nosave"`
+	writeViews  []*buffer.View  `state:"nosave"`
+
+	// max is the maximum size of the pipe in bytes. When the pipe's chunks
+	// take up this much memory, writers will get EWOULDBLOCK.
 	//
 	// This is protected by mu.
 	max int64
@@ -191,15 +213,74 @@ func initPipe(pipe *Pipe, isNamed bool, sizeBytes int64) {
 // Preconditions:
 //   - p.mu must be locked.
 //   - This pipe must have readers.
-//   - off <= p.size.
+//   - off <= p.buf.Size().
 func (p *Pipe) peekLocked(off, count int64, f func(safemem.BlockSeq) (uint64, error)) (int64, error) {
+	count, err := p.readableLocked(off, count)
+	if count == 0 {
+		return 0, err
+	}
+
+	// Prepare the view of the data to be read.
+	blocks := p.readBlocks[:0]
+	views := p.buf.AsViewList()
+	for v := views.Front(); v != nil && count > 0; v = v.Next() {
+		bs := v.AsSlice()
+		if off >= int64(len(bs)) {
+			off -= int64(len(bs))
+			continue
+		}
+		bs = bs[off:]
+		off = 0
+		if int64(len(bs)) > count {
+			bs = bs[:count]
+		}
+		blocks = append(blocks, safemem.BlockFromSafeSlice(bs))
+		count -= int64(len(bs))
+	}
+
+	// Perform the read.
+	done, err := f(safemem.BlockSeqFromSlice(blocks))
+	clear(blocks)
+	p.readBlocks = blocks[:0]
+	return int64(done), err
+}
+
+// peekBufferLocked returns a buffer.Buffer that shares the first count bytes
+// in the pipe. If fewer than count bytes are available, the returned
+// buffer.Buffer will be less than count bytes in length.
+//
+// Like peekLocked, peekBufferLocked does not mutate the pipe.
+//
+// Preconditions:
+//   - p.mu must be locked.
+//   - This pipe must have readers.
+func (p *Pipe) peekBufferLocked(count int64) (buffer.Buffer, error) {
+	var b buffer.Buffer
+	count, err := p.readableLocked(0, count)
+	if count == 0 {
+		return b, err
+	}
+	p.buf.SubApply(0, int(count), func(v *buffer.View) {
+		b.Append(v.Clone())
+	})
+	return b, nil
+}
+
+// readableLocked returns the number of bytes, up to count, that can be read
+// from the pipe starting at offset off. If no bytes can be read, it also
+// returns the reason why.
+//
+// Preconditions:
+//   - p.mu must be locked.
+//   - off <= p.buf.Size().
+func (p *Pipe) readableLocked(off, count int64) (int64, error) {
 	// Don't block for a zero-length read even if the pipe is empty.
 	if count == 0 {
 		return 0, nil
 	}
 
 	// Limit the amount of data read to the amount of data in the pipe.
-	if rem := p.size - off; count > rem {
+	if rem := p.buf.Size() - off; count > rem {
 		if rem == 0 {
 			if !p.HasWriters() {
 				return 0, io.EOF
@@ -208,17 +289,7 @@ func (p *Pipe) peekLocked(off, count int64, f func(safemem.BlockSeq) (uint64, er
 		}
 		count = rem
 	}
-
-	// Prepare the view of the data to be read.
-	pipeOff := p.off + off
-	if max := int64(len(p.buf)); pipeOff >= max {
-		pipeOff -= max
-	}
-	bs := p.bufBlockSeq.DropFirst64(uint64(pipeOff)).TakeFirst64(uint64(count))
-
-	// Perform the read.
-	done, err := f(bs)
-	return int64(done), err
+	return count, nil
 }
 
 // consumeLocked consumes the first n bytes in the pipe, such that they will no
@@ -228,11 +299,67 @@ func (p *Pipe) peekLocked(off, count int64, f func(safemem.BlockSeq) (uint64, er
 //   - p.mu must be locked.
 //   - The pipe must contain at least n bytes.
 func (p *Pipe) consumeLocked(n int64) {
-	p.off += n
-	if max := int64(len(p.buf)); p.off >= max {
-		p.off -= max
+	views := p.buf.AsViewList()
+	for v, rem := views.Front(), n; v != nil && rem > 0 && int64(v.Size()) <= rem; v = v.Next() {
+		// TrimFront will remove v from buf.
+		rem -= int64(v.Size())
+		p.uncharge(v)
+	}
+	p.buf.TrimFront(n)
+}
+
+// appendLocked appends v to the pipe, taking ownership of it.
+//
+// Preconditions: p.mu must be locked.
+func (p *Pipe) appendLocked(v *buffer.View) {
+	if v.Size() == 0 {
+		v.Release()
+		return
 	}
-	p.size -= n
+	p.charge(v)
+	// Don't use p.buf.Append, which may copy v into the last view in buf
+	// instead of adding it.
+	b := buffer.MakeWithView(v)
+	p.buf.Merge(&b)
+}
+
+// charge accounts for v being added to buf.
+//
+// Preconditions: p.mu must be locked.
+func (p *Pipe) charge(v *buffer.View) {
+	p.views++
+	p.charged += int64(v.Capacity())
+}
+
+// uncharge accounts for v being removed from buf.
+//
+// Preconditions: p.mu must be locked.
+func (p *Pipe) uncharge(v *buffer.View) {
+	p.views--
+	p.charged -= int64(v.Capacity())
+}
+
+// maxViews returns the maximum number of views in a pipe of the given size.
+func maxViews(size int64) int64 {
+	return size / pipeChunkSize
+}
+
+// freeLocked returns the number of bytes that can be copied into the pipe:
+// the unused space at the end of its last view, plus as many new
+// pipeChunkSize chunks as fit in its limits.
+//
+// Preconditions: p.mu must be locked.
+func (p *Pipe) freeLocked() int64 {
+	views := p.buf.AsViewList()
+	free := int64(len(views.Back().WritableSlice()))
+	chunks := (p.max - p.charged) / pipeChunkSize
+	if slots := maxViews(p.max) - p.views; chunks > slots {
+		chunks = slots
+	}
+	if chunks > 0 {
+		free += chunks * pipeChunkSize
+	}
+	return free
 }
 
 // writeLocked passes a safemem.BlockSeq representing the first count bytes of
@@ -250,74 +377,194 @@ func (p *Pipe) consumeLocked(n int64) {
 // Preconditions:
 //   - p.mu must be locked.
 func (p *Pipe) writeLocked(count int64, f func(safemem.BlockSeq) (uint64, error)) (int64, error) {
+	count, short, err := p.writableLocked(count)
+	if err != nil {
+		return 0, err
+	}
+
+	done, err := p.fillLocked(count, f)
+	if done < count || err != nil {
+		return done, err
+	}
+
+	// If we shortened the write, adjust the returned error appropriately.
+	if short {
+		return done, linuxerr.ErrWouldBlock
+	}
+
+	return done, nil
+}
+
+// fillLocked passes a safemem.BlockSeq representing the first count bytes of
+// unused space in the pipe to f, and adds the number of bytes that f returns
+// to the pipe. The space is taken from the end of the pipe's last view if
+// possible, so that small writes are packed together, and then from new
+// pipeChunkSize chunks.
+//
+// Preconditions:
+//   - p.mu must be locked.
+//   - count <= p.freeLocked().
+func (p *Pipe) fillLocked(count int64, f func(safemem.BlockSeq) (uint64, error)) (int64, error) {
+	blocks := p.writeBlocks[:0]
+	bufViews := p.buf.AsViewList()
+	tail := bufViews.Back().WritableSlice()
+	if int64(len(tail)) > count {
+		tail = tail[:count]
+	}
+	if len(tail) > 0 {
+		blocks = append(blocks, safemem.BlockFromSafeSlice(tail))
+	}
+	views := p.writeViews[:0]
+	for rem := count - int64(len(tail)); rem > 0; {
+		v := buffer.NewView(pipeChunkSize)
+		bs := v.WritableSlice()
+		if int64(len(bs)) > rem {
+			bs = bs[:rem]
+		}
+		views = append(views, v)
+		blocks = append(blocks, safemem.BlockFromSafeSlice(bs))
+		rem -= int64(len(bs))
+	}
+
+	// Perform the write.
+	doneU64, err := f(safemem.BlockSeqFromSlice(blocks))
+	done := int64(doneU64)
+
+	// Keep only the part of the space that was written.
+	rem := done
+	if n := min(rem, int64(len(tail))); n > 0 {
+		p.buf.GrowTo(p.buf.Size()+n, false /* zero */)
+		rem -= n
+	}
+	for i, v := range views {
+		n := min(rem, int64(v.AvailableSize()))
+		v.Grow(int(n))
+		p.appendLocked(v)
+		rem -= n
+		views[i] = nil
+	}
+	clear(blocks)
+	p.writeViews = views[:0]
+	p.writeBlocks = blocks[:0]
+	return done, err
+}
+
+// writeViewsLocked passes a pipeViewWriter that appends up to count bytes to
+// the pipe to f, and returns the number of bytes appended. As with
+// writeLocked, if the pipe cannot accommodate a write of any number of bytes
+// up to count, writeViewsLocked returns ErrWouldBlock without calling f, and
+// callers are responsible for calling p.queue.Notify(waiter.ReadableEvents)
+// with p.mu unlocked.
+//
+// Preconditions:
+//   - p.mu must be locked.
+func (p *Pipe) writeViewsLocked(count int64, f func(w *pipeViewWriter) error) (int64, error) {
+	count, short, err := p.writableLocked(count)
+	if err != nil {
+		return 0, err
+	}
+
+	w := pipeViewWriter{p: p, limit: count}
+	err = f(&w)
+	if w.done < count || err != nil {
+		return w.done, err
+	}
+	if short {
+		return w.done, linuxerr.ErrWouldBlock
+	}
+	return w.done, nil
+}
+
+// pipeViewWriter is a buffer.ViewWriter that appends up to limit bytes to a
+// pipe. Views that are mostly full are appended by reference, while the
+// contents of other views are copied, so that small payloads in large
+// chunks, such as those of received packets, don't hold the chunks in
+// memory.
+//
+// Preconditions: p.mu must be locked while the pipeViewWriter is used.
+type pipeViewWriter struct {
+	p     *Pipe
+	limit int64
+	done  int64
+}
+
+// Write implements io.Writer.Write.
+func (w *pipeViewWriter) Write(src []byte) (int, error) {
+	var n int64
+	if count := min(int64(len(src)), w.limit-w.done, w.p.freeLocked()); count > 0 {
+		n, _ = w.p.fillLocked(count, func(dsts safemem.BlockSeq) (uint64, error) {
+			return safemem.CopySeq(dsts, safemem.BlockSeqOf(safemem.BlockFromSafeSlice(src[:count])))
+		})
+		w.done += n
+	}
+	if int(n) < len(src) {
+		return int(n), io.ErrShortWrite
+	}
+	return int(n), nil
+}
+
+// WriteView implements buffer.ViewWriter.WriteView.
+func (w *pipeViewWriter) WriteView(v *buffer.View) (int, error) {
+	size := v.Size()
+	if rem := w.limit - w.done; int64(size) > rem {
+		v.CapLength(int(rem))
+	}
+	p := w.p
+	if 2*v.Size() < v.Capacity() || p.views >= maxViews(p.max) || p.charged+int64(v.Capacity()) > p.max {
+		n, _ := w.Write(v.AsSlice())
+		v.Release()
+		if n < size {
+			return n, io.ErrShortWrite
+		}
+		return n, nil
+	}
+	n := v.Size()
+	p.appendLocked(v)
+	w.done += int64(n)
+	if n < size {
+		return n, io.ErrShortWrite
+	}
+	return n, nil
+}
+
+// writableLocked returns the number of bytes, up to count, that can be
+// written to the pipe, and whether that is less than count. If no bytes can
+// be written, it returns an error explaining why.
+//
+// Preconditions:
+//   - p.mu must be locked.
+func (p *Pipe) writableLocked(count int64) (int64, bool, error) {
 	// Can't write to a pipe with no readers.
 	if !p.HasReaders() {
-		return 0, unix.EPIPE
+		return 0, false, unix.EPIPE
 	}
 
-	avail := p.max - p.size
+	avail := p.freeLocked()
 	if avail == 0 {
-		return 0, linuxerr.ErrWouldBlock
+		return 0, false, linuxerr.ErrWouldBlock
 	}
-	short := false
 	if count > avail {
 		// POSIX requires that a write smaller than atomicIOBytes
 		// (PIPE_BUF) be atomic, but requires no atomicity for writes
 		// larger than this.
 		if count <= atomicIOBytes {
-			return 0, linuxerr.ErrWouldBlock
+			return 0, false, linuxerr.ErrWouldBlock
 		}
-		count = avail
-		short = true
-	}
-
-	// Ensure that the buffer is big enough.
-	if newLen, oldCap := p.size+count, int64(len(p.buf)); newLen > oldCap {
-		// Allocate a new buffer.
-		newCap := oldCap * 2
-		if oldCap == 0 {
-			newCap = 8 // arbitrary; sending individual integers across pipes is relatively common
-		}
-		for newLen > newCap {
-			newCap *= 2
-		}
-		if newCap > p.max {
-			newCap = p.max
-		}
-		newBuf := make([]byte, newCap)
-		// Copy the old buffer's contents to the beginning of the new one.
-		safemem.CopySeq(
-			safemem.BlockSeqOf(safemem.BlockFromSafeSlice(newBuf)),
-			p.bufBlockSeq.DropFirst64(uint64(p.off)).TakeFirst64(uint64(p.size)))
-		// Switch to the new buffer.
-		p.buf = newBuf
-		p.bufBlocks[0] = safemem.BlockFromSafeSlice(newBuf)
-		p.bufBlocks[1] = p.bufBlocks[0]
-		p.bufBlockSeq = safemem.BlockSeqFromSlice(p.bufBlocks[:])
-		p.off = 0
-	}
-
-	// Prepare the view of the space to be written.
-	woff := p.off + p.size
-	if woff >= int64(len(p.buf)) {
-		woff -= int64(len(p.buf))
-	}
-	bs := p.bufBlockSeq.DropFirst64(uint64(woff)).TakeFirst64(uint64(count))
-
-	// Perform the write.
-	doneU64, err := f(bs)
-	done := int64(doneU64)
-	p.size += done
-	if done < count || err != nil {
-		return done, err
+		return avail, true, nil
 	}
+	return count, false, nil
+}
 
-	// If we shortened the write, adjust the returned error appropriately.
-	if short {
-		return done, linuxerr.ErrWouldBlock
+// releaseLocked releases the pipe's data once the pipe has neither readers
+// nor writers, as the data can no longer be read.
+//
+// Preconditions: p.mu must be locked.
+func (p *Pipe) releaseLocked() {
+	if !p.HasReaders() && !p.HasWriters() {
+		p.buf.Release()
+		p.views = 0
+		p.charged = 0
 	}
-
-	return done, nil
 }
 
 // rOpen signals a new reader of the pipe.
@@ -370,7 +617,7 @@ func (p *Pipe) HasWriters() bool {
 // Precondition: mu must be held.
 func (p *Pipe) rReadinessLocked() waiter.EventMask {
 	ready := waiter.EventMask(0)
-	if p.HasReaders() && p.size != 0 {
+	if p.HasReaders() && p.buf.Size() != 0 {
 		ready |= waiter.ReadableEvents
 	}
 	if !p.HasWriters() && p.hadWriter {
@@ -396,7 +643,10 @@ func (p *Pipe) rReadiness() waiter.EventMask {
 // Precondition: mu must be held.
 func (p *Pipe) wReadinessLocked() waiter.EventMask {
 	ready := waiter.EventMask(0)
-	if p.HasWriters() && p.size < p.max {
+	// Like Linux, only report the pipe as writable if a write of up to
+	// atomicIOBytes would succeed, which Linux ensures by requiring a free
+	// buffer slot.
+	if p.HasWriters() && p.freeLocked() >= atomicIOBytes {
 		ready |= waiter.WritableEvents
 	}
 	if !p.HasReaders() {
@@ -440,7 +690,7 @@ func (p *Pipe) queued() int64 {
 }
 
 func (p *Pipe) queuedLocked() int64 {
-	return p.size
+	return p.buf.Size()
 }
 
 // SetFifoSize implements fs.FifoSizer.SetFifoSize.
@@ -456,7 +706,7 @@ func (p *Pipe) SetFifoSize(size int64) (int64, error) {
 	}
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	if size < p.size {
+	if size < p.buf.Size() || p.views > maxViews(size) {
 		return 0, linuxerr.EBUSY
 	}
 	p.max = size
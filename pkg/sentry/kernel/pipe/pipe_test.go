@@ -18,6 +18,7 @@ import (
 	"bytes"
 	"testing"
 
+	"gvisor.dev/gvisor/pkg/buffer"
 	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/sentry/contexttest"
@@ -147,3 +148,99 @@ func TestPipeWriteUntilEnd(t *testing.T) {
 		}
 	})
 }
+
+func TestPipeTeeAndSplice(t *testing.T) {
+	runTest(t, 65536, func(ctx context.Context, r1, w1 *vfs.FileDescription) {
+		runTest(t, 65536, func(_ context.Context, r2, w2 *vfs.FileDescription) {
+			msg := []byte("here's some bytes")
+			wantN := int64(len(msg))
+			if n, err := w1.Write(ctx, usermem.BytesIOSequence(msg), vfs.WriteOptions{}); n != wantN || err != nil {
+				t.Fatalf("Writev: got (%d, %v), wanted (%d, nil)", n, err, wantN)
+			}
+			src := r1.Impl().(*VFSPipeFD)
+			dst := w2.Impl().(*VFSPipeFD)
+
+			// Tee leaves the data in the source pipe.
+			if n, err := Tee(ctx, dst, src, wantN); n != wantN || err != nil {
+				t.Fatalf("Tee: got (%d, %v), wanted (%d, nil)", n, err, wantN)
+			}
+			buf := make([]byte, len(msg))
+			if n, err := r2.Read(ctx, usermem.BytesIOSequence(buf), vfs.ReadOptions{}); n != wantN || err != nil || !bytes.Equal(buf, msg) {
+				t.Fatalf("Readv after Tee: got (%d, %v) %q, wanted (%d, nil) %q", n, err, buf, wantN, msg)
+			}
+
+			// Splice moves it.
+			if n, err := Splice(ctx, dst, src, wantN); n != wantN || err != nil {
+				t.Fatalf("Splice: got (%d, %v), wanted (%d, nil)", n, err, wantN)
+			}
+			if n, err := r1.Read(ctx, usermem.BytesIOSequence(buf), vfs.ReadOptions{}); n != 0 || err != linuxerr.ErrWouldBlock {
+				t.Fatalf("Readv after Splice: got (%d, %v), wanted (0, %v)", n, err, linuxerr.ErrWouldBlock)
+			}
+			clear(buf)
+			if n, err := r2.Read(ctx, usermem.BytesIOSequence(buf), vfs.ReadOptions{}); n != wantN || err != nil || !bytes.Equal(buf, msg) {
+				t.Fatalf("Readv after Splice: got (%d, %v) %q, wanted (%d, nil) %q", n, err, buf, wantN, msg)
+			}
+		})
+	})
+}
+
+func TestPipeSmallWritesArePacked(t *testing.T) {
+	const capacity = 2 * pipeChunkSize
+	runTest(t, capacity, func(ctx context.Context, r, w *vfs.FileDescription) {
+		p := w.Impl().(*VFSPipeFD).pipe
+		total := int64(0)
+		for {
+			n, err := w.Write(ctx, usermem.BytesIOSequence([]byte{'a'}), vfs.WriteOptions{})
+			total += n
+			if err == linuxerr.ErrWouldBlock {
+				break
+			}
+			if err != nil {
+				t.Fatalf("Writev: got (%d, %v)", n, err)
+			}
+		}
+		if total != capacity {
+			t.Errorf("got %d bytes written before the pipe was full, want %d", total, capacity)
+		}
+		if p.views != capacity/pipeChunkSize || p.charged != capacity {
+			t.Errorf("got %d views charging %d bytes, want %d views charging %d bytes", p.views, p.charged, capacity/pipeChunkSize, capacity)
+		}
+
+		// Reading frees the chunks.
+		buf := make([]byte, capacity)
+		if n, err := r.Read(ctx, usermem.BytesIOSequence(buf), vfs.ReadOptions{}); n != capacity || err != nil {
+			t.Fatalf("Readv: got (%d, %v), wanted (%d, nil)", n, err, capacity)
+		}
+		if p.views != 0 || p.charged != 0 {
+			t.Errorf("got %d views charging %d bytes after reading everything, want none", p.views, p.charged)
+		}
+	})
+}
+
+func TestPipeSparseViewsAreCopied(t *testing.T) {
+	const capacity = 2 * pipeChunkSize
+	runTest(t, capacity, func(ctx context.Context, r, w *vfs.FileDescription) {
+		p := w.Impl().(*VFSPipeFD).pipe
+		p.mu.Lock()
+		n, err := p.writeViewsLocked(capacity, func(w *pipeViewWriter) error {
+			// Views holding a few bytes of a large chunk, like received
+			// packets.
+			for i := 0; i < capacity; i++ {
+				v := buffer.NewView(buffer.MaxChunkSize)
+				v.Write([]byte{'a'})
+				if _, err := w.WriteView(v); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		views, charged := p.views, p.charged
+		p.mu.Unlock()
+		if n != capacity || err != nil {
+			t.Fatalf("writeViewsLocked: got (%d, %v), wanted (%d, nil)", n, err, capacity)
+		}
+		if views != capacity/pipeChunkSize || charged != capacity {
+			t.Errorf("got %d views charging %d bytes, want %d views charging %d bytes", views, charged, capacity/pipeChunkSize, capacity)
+		}
+	})
+}
@@ -36,6 +36,9 @@ import (
 func (p *Pipe) Release(context.Context) {
 	p.rClose()
 	p.wClose()
+	p.mu.Lock()
+	p.releaseLocked()
+	p.mu.Unlock()
 
 	// Wake up readers and writers.
 	p.queue.Notify(waiter.ReadableEvents | waiter.WritableEvents)
@@ -1,4 +1,4 @@
-// Copyright 2020 The gVisor Authors.
+// Copyright 2025 The gVisor Authors.
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -16,13 +16,12 @@ package pipe
 
 import (
 	"context"
-
-	"gvisor.dev/gvisor/pkg/safemem"
 )
 
 // afterLoad is called by stateify.
 func (p *Pipe) afterLoad(context.Context) {
-	p.bufBlocks[0] = safemem.BlockFromSafeSlice(p.buf)
-	p.bufBlocks[1] = p.bufBlocks[0]
-	p.bufBlockSeq = safemem.BlockSeqFromSlice(p.bufBlocks[:])
+	views := p.buf.AsViewList()
+	for v := views.Front(); v != nil; v = v.Next() {
+		p.charge(v)
+	}
 }
@@ -16,6 +16,7 @@ package pipe
 
 import (
 	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/buffer"
 	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/hostarch"
@@ -189,6 +190,9 @@ func (fd *VFSPipeFD) Release(context.Context) {
 	if event == 0 {
 		panic("invalid pipe flags: must be readable, writable, or both")
 	}
+	fd.pipe.mu.Lock()
+	fd.pipe.releaseLocked()
+	fd.pipe.mu.Unlock()
 
 	fd.pipe.queue.Notify(event)
 }
@@ -259,28 +263,51 @@ func (fd *VFSPipeFD) SetPipeSize(size int64) (int64, error) {
 	return fd.pipe.SetFifoSize(size)
 }
 
+// ViewReader is implemented by vfs.FileDescriptionImpls that can move data
+// into a pipe by reference rather than by copying, such as TCP sockets.
+type ViewReader interface {
+	// ReadToViewWriter reads from the file into w without blocking. It
+	// returns EOPNOTSUPP if the file can't be read this way.
+	ReadToViewWriter(ctx context.Context, w buffer.ViewWriter) (int64, error)
+}
+
+// BufferWriter is implemented by vfs.FileDescriptionImpls that can take data
+// from a pipe by reference rather than by copying, such as TCP sockets.
+type BufferWriter interface {
+	// WriteFromBuffer writes a prefix of buf to the file without blocking,
+	// removes the written bytes from buf, and returns their number.
+	WriteFromBuffer(ctx context.Context, buf *buffer.Buffer) (int64, error)
+}
+
 // SpliceToNonPipe performs a splice operation from fd to a non-pipe file.
 func (fd *VFSPipeFD) SpliceToNonPipe(ctx context.Context, out *vfs.FileDescription, off, count int64) (int64, error) {
 	fd.pipe.mu.Lock()
 
 	// Cap the sequence at number of bytes actually available.
-	if count > fd.pipe.size {
-		count = fd.pipe.size
-	}
-	src := usermem.IOSequence{
-		IO:    fd,
-		Addrs: hostarch.AddrRangeSeqOf(hostarch.AddrRange{0, hostarch.Addr(count)}),
+	if size := fd.pipe.buf.Size(); count > size {
+		count = size
 	}
 
 	var (
 		n   int64
 		err error
 	)
-	fd.lastAddr = 0
-	if off == -1 {
-		n, err = out.Write(ctx, src, vfs.WriteOptions{})
+	if w, ok := out.Impl().(BufferWriter); ok && off == -1 && count > 0 {
+		// Pass references on the pipe's data to out.
+		buf, _ := fd.pipe.peekBufferLocked(count)
+		n, err = w.WriteFromBuffer(ctx, &buf)
+		buf.Release()
 	} else {
-		n, err = out.PWrite(ctx, src, off, vfs.WriteOptions{})
+		src := usermem.IOSequence{
+			IO:    fd,
+			Addrs: hostarch.AddrRangeSeqOf(hostarch.AddrRange{0, hostarch.Addr(count)}),
+		}
+		fd.lastAddr = 0
+		if off == -1 {
+			n, err = out.Write(ctx, src, vfs.WriteOptions{})
+		} else {
+			n, err = out.PWrite(ctx, src, off, vfs.WriteOptions{})
+		}
 	}
 	// Implementations of out.[P]Write() that ignore written data (e.g.
 	// /dev/null) may skip calling src.CopyIn[To](), so:
@@ -289,7 +316,7 @@ func (fd *VFSPipeFD) SpliceToNonPipe(ctx context.Context, out *vfs.FileDescripti
 	//
 	// - We must check if Pipe.peekLocked() would have returned ErrWouldBlock.
 	fd.pipe.consumeLocked(n)
-	if n == 0 && err == nil && fd.pipe.size == 0 && fd.pipe.HasWriters() {
+	if n == 0 && err == nil && fd.pipe.buf.Size() == 0 && fd.pipe.HasWriters() {
 		err = linuxerr.ErrWouldBlock
 	}
 
@@ -313,11 +340,22 @@ func (fd *VFSPipeFD) SpliceFromNonPipe(ctx context.Context, in *vfs.FileDescript
 		err error
 	)
 	fd.pipe.mu.Lock()
-	fd.lastAddr = 0
-	if off == -1 {
-		n, err = in.Read(ctx, dst, vfs.ReadOptions{})
-	} else {
-		n, err = in.PRead(ctx, dst, off, vfs.ReadOptions{})
+	viaViews := false
+	if r, ok := in.Impl().(ViewReader); ok && off == -1 {
+		// Take references on in's data where that doesn't waste memory.
+		n, err = fd.pipe.writeViewsLocked(count, func(w *pipeViewWriter) error {
+			_, err := r.ReadToViewWriter(ctx, w)
+			return err
+		})
+		viaViews = n != 0 || !linuxerr.Equals(linuxerr.EOPNOTSUPP, err)
+	}
+	if !viaViews {
+		fd.lastAddr = 0
+		if off == -1 {
+			n, err = in.Read(ctx, dst, vfs.ReadOptions{})
+		} else {
+			n, err = in.PRead(ctx, dst, off, vfs.ReadOptions{})
+		}
 	}
 	fd.pipe.mu.Unlock()
 
@@ -466,15 +504,24 @@ func spliceOrTee(ctx context.Context, dst, src *VFSPipeFD, count int64, removeFr
 		return 0, linuxerr.EINVAL
 	}
 
+	// The data is shared between the two pipes rather than copied, except
+	// where pipeViewWriter packs small views.
 	firstLocked, secondLocked := lockTwoPipes(dst.pipe, src.pipe)
-	n, err := dst.pipe.writeLocked(count, func(dsts safemem.BlockSeq) (uint64, error) {
-		n, err := src.pipe.peekLocked(0, int64(dsts.NumBytes()), func(srcs safemem.BlockSeq) (uint64, error) {
-			return safemem.CopySeq(dsts, srcs)
+	n, err := dst.pipe.writeViewsLocked(count, func(w *pipeViewWriter) error {
+		count, err := src.pipe.readableLocked(0, w.limit)
+		if count == 0 {
+			return err
+		}
+		var werr error
+		src.pipe.buf.SubApply(0, int(count), func(v *buffer.View) {
+			if werr == nil {
+				_, werr = w.WriteView(v.Clone())
+			}
 		})
-		if n > 0 && removeFromSrc {
-			src.pipe.consumeLocked(n)
+		if removeFromSrc {
+			src.pipe.consumeLocked(w.done)
 		}
-		return uint64(n), err
+		return nil
 	})
 	secondLocked.mu.NestedUnlock(pipeLockPipe)
 	firstLocked.mu.Unlock()
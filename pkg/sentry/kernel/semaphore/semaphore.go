@@ -79,6 +79,9 @@ type Set struct {
 	// dead is set to true when the set is removed and can't be reached anymore.
 	// All waiters must wake up and fail when set is dead.
 	dead bool
+
+	// undos holds the undo of each UndoList with adjustments to the set.
+	undos map[*undo]struct{}
 }
 
 // sem represents a single semaphore from a set.
@@ -403,7 +406,7 @@ func (s *Set) SetVal(ctx context.Context, num int32, val int16, creds *auth.Cred
 		return linuxerr.ERANGE
 	}
 
-	// TODO(gvisor.dev/issue/137): Clear undo entries in all processes.
+	s.clearUndosLocked(num)
 	sem.value = val
 	sem.pid = pid
 	s.changeTime = ktime.NowFromContext(ctx)
@@ -434,10 +437,9 @@ func (s *Set) SetValAll(ctx context.Context, vals []uint16, creds *auth.Credenti
 		return linuxerr.EACCES
 	}
 
+	s.clearUndosLocked(-1)
 	for i, val := range vals {
 		sem := &s.sems[i]
-
-		// TODO(gvisor.dev/issue/137): Clear undo entries in all processes.
 		sem.value = int16(val)
 		sem.pid = pid
 		sem.wakeWaiters()
@@ -535,10 +537,12 @@ func (s *Set) CountNegativeWaiters(num int32, creds *auth.Credentials) (uint16,
 
 // ExecuteOps attempts to execute a list of operations to the set. It only
 // succeeds when all operations can be applied. No changes are made if it fails.
+// The adjustments of operations performed with SEM_UNDO are recorded in
+// undoList, which must not be nil if any operation has SEM_UNDO.
 //
 // On failure, it may return an error (retries are hopeless) or it may return
 // a channel that can be waited on before attempting again.
-func (s *Set) ExecuteOps(ctx context.Context, ops []linux.Sembuf, creds *auth.Credentials, pid int32) (chan struct{}, int32, error) {
+func (s *Set) ExecuteOps(ctx context.Context, ops []linux.Sembuf, creds *auth.Credentials, pid int32, undoList *UndoList) (chan struct{}, int32, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -566,20 +570,32 @@ func (s *Set) ExecuteOps(ctx context.Context, ops []linux.Sembuf, creds *auth.Cr
 		return nil, 0, linuxerr.EACCES
 	}
 
-	ch, num, err := s.executeOps(ctx, ops, pid)
+	ch, num, err := s.executeOps(ctx, ops, pid, undoList)
 	if err != nil {
 		return nil, 0, err
 	}
 	return ch, num, nil
 }
 
-func (s *Set) executeOps(ctx context.Context, ops []linux.Sembuf, pid int32) (chan struct{}, int32, error) {
+func (s *Set) executeOps(ctx context.Context, ops []linux.Sembuf, pid int32, undoList *UndoList) (chan struct{}, int32, error) {
 	// Changes to semaphores go to this slice temporarily until they all succeed.
 	tmpVals := make([]int16, len(s.sems))
 	for i := range s.sems {
 		tmpVals[i] = s.sems[i].value
 	}
 
+	// Likewise for changes to the adjustments of SEM_UNDO operations.
+	var tmpAdj []int16
+	for _, op := range ops {
+		if op.SemFlg&linux.SEM_UNDO != 0 {
+			tmpAdj = make([]int16, len(s.sems))
+			if u := undoList.find(s); u != nil {
+				copy(tmpAdj, u.adj)
+			}
+			break
+		}
+	}
+
 	for _, op := range ops {
 		sem := &s.sems[op.SemNum]
 		if op.SemOp == 0 {
@@ -617,12 +633,23 @@ func (s *Set) executeOps(ctx context.Context, ops []linux.Sembuf, pid int32) (ch
 				}
 			}
 
+			if op.SemFlg&linux.SEM_UNDO != 0 {
+				// The adjustment must also be a valid semaphore value, see
+				// Linux's ipc/sem.c:perform_atomic_semop().
+				adj := int32(tmpAdj[op.SemNum]) - int32(op.SemOp)
+				if adj < -linux.SEMAEM-1 || adj > linux.SEMAEM {
+					return nil, 0, linuxerr.ERANGE
+				}
+				tmpAdj[op.SemNum] = int16(adj)
+			}
 			tmpVals[op.SemNum] += op.SemOp
 		}
 	}
 
 	// All operations succeeded, apply them.
-	// TODO(gvisor.dev/issue/137): handle undo operations.
+	if tmpAdj != nil {
+		copy(undoList.findOrCreate(s).adj, tmpAdj)
+	}
 	for i, v := range tmpVals {
 		s.sems[i].value = v
 		s.sems[i].wakeWaiters()
@@ -655,6 +682,7 @@ func (s *Set) Destroy() {
 	// Notify all waiters. They will fail on the next attempt to execute
 	// operations and return error.
 	s.dead = true
+	s.removeUndosLocked()
 	for _, s := range s.sems {
 		for w := s.waiters.Front(); w != nil; w = w.Next() {
 			w.ch <- struct{}{}
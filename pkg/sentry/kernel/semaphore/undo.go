@@ -0,0 +1,170 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package semaphore
+
+import (
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+// UndoList holds the semaphore adjustments recorded by operations performed
+// with SEM_UNDO, which are applied when the last reference on the UndoList is
+// dropped. An UndoList is shared by tasks created with CLONE_SYSVSEM.
+//
+// Lock order: Set.mu -> UndoList.mu.
+//
+// +stateify savable
+type UndoList struct {
+	undoListRefs
+
+	// mu protects undos.
+	mu sync.Mutex `state:"nosave"`
+
+	// undos maps each set with adjustments in this list to its undo.
+	undos map[*Set]*undo
+}
+
+// undo holds the adjustments of a single UndoList to the semaphores of a
+// single Set.
+//
+// +stateify savable
+type undo struct {
+	// list is the UndoList that contains this undo. Immutable.
+	list *UndoList
+
+	// set is the Set the adjustments apply to. Immutable.
+	set *Set
+
+	// adj holds the adjustment ("semadj") of each semaphore in set. adj is
+	// protected by set.mu.
+	adj []int16
+}
+
+// NewUndoList returns a new empty UndoList with a single reference.
+func NewUndoList() *UndoList {
+	l := &UndoList{
+		undos: make(map[*Set]*undo),
+	}
+	l.InitRefs()
+	return l
+}
+
+// DecRef drops a reference on l. If this is the last reference, the recorded
+// adjustments are applied to the semaphores, whose PID is set to pid.
+func (l *UndoList) DecRef(pid int32) {
+	l.undoListRefs.DecRef(func() {
+		l.mu.Lock()
+		undos := l.undos
+		l.undos = nil
+		l.mu.Unlock()
+		for _, u := range undos {
+			u.set.applyUndo(u, pid)
+		}
+	})
+}
+
+// find returns the undo of l for set, or nil if there is none.
+//
+// Preconditions: set.mu must be locked.
+func (l *UndoList) find(set *Set) *undo {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.undos[set]
+}
+
+// findOrCreate returns the undo of l for set, creating it if necessary.
+//
+// Preconditions: set.mu must be locked.
+func (l *UndoList) findOrCreate(set *Set) *undo {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if u, ok := l.undos[set]; ok {
+		return u
+	}
+	u := &undo{
+		list: l,
+		set:  set,
+		adj:  make([]int16, set.Size()),
+	}
+	l.undos[set] = u
+	if set.undos == nil {
+		set.undos = make(map[*undo]struct{})
+	}
+	set.undos[u] = struct{}{}
+	return u
+}
+
+// remove removes the undo of l for set.
+//
+// Preconditions: set.mu must be locked.
+func (l *UndoList) remove(set *Set) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.undos, set)
+}
+
+// applyUndo applies the adjustments of u to s, which is removed from s.
+func (s *Set) applyUndo(u *undo, pid int32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// u is no longer in s.undos if the set was removed.
+	if _, ok := s.undos[u]; !ok {
+		return
+	}
+	delete(s.undos, u)
+
+	for i, adj := range u.adj {
+		if adj == 0 {
+			continue
+		}
+		// Like Linux, clamp the result to the valid range of semaphore values.
+		// See ipc/sem.c:exit_sem().
+		val := int32(s.sems[i].value) + int32(adj)
+		if val < 0 {
+			val = 0
+		}
+		if val > valueMax {
+			val = valueMax
+		}
+		s.sems[i].value = int16(val)
+		s.sems[i].pid = pid
+		s.sems[i].wakeWaiters()
+	}
+}
+
+// clearUndosLocked resets the adjustments of all processes for semaphore num,
+// or for all semaphores if num is negative.
+//
+// Preconditions: s.mu must be locked.
+func (s *Set) clearUndosLocked(num int32) {
+	for u := range s.undos {
+		if num < 0 {
+			clear(u.adj)
+		} else {
+			u.adj[num] = 0
+		}
+	}
+}
+
+// removeUndosLocked removes s from all the UndoLists that hold adjustments to
+// it.
+//
+// Preconditions: s.mu must be locked.
+func (s *Set) removeUndosLocked() {
+	for u := range s.undos {
+		u.list.remove(s)
+	}
+	s.undos = nil
+}
@@ -28,6 +28,7 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/futex"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/sched"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/semaphore"
 	"gvisor.dev/gvisor/pkg/sentry/ktime"
 	"gvisor.dev/gvisor/pkg/sentry/platform"
 	"gvisor.dev/gvisor/pkg/sentry/usage"
@@ -465,11 +466,31 @@ type Task struct {
 	// utsns is protected by mu. utsns is owned by the task goroutine.
 	utsns *UTSNamespace
 
+	// timens is the task's time namespace.
+	//
+	// timens is protected by mu. timens is owned by the task goroutine.
+	timens *TimeNamespace
+
+	// timensForChildren is the time namespace that children of the task are
+	// created in. It differs from timens after unshare(CLONE_NEWTIME).
+	//
+	// timensForChildren is protected by mu. timensForChildren is owned by
+	// the task goroutine.
+	timensForChildren *TimeNamespace
+
 	// ipcns is the task's IPC namespace.
 	//
 	// ipcns is protected by mu. ipcns is owned by the task goroutine.
 	ipcns *IPCNamespace
 
+	// semUndoList holds the adjustments of System V semaphore operations
+	// performed with SEM_UNDO. It is nil until the task first needs it, and
+	// is shared with tasks created with CLONE_SYSVSEM. If semUndoList is not
+	// nil, a reference is held on it.
+	//
+	// semUndoList is owned by the task goroutine.
+	semUndoList *semaphore.UndoList
+
 	// mountNamespace is the task's mount namespace.
 	//
 	// It is protected by mu. It is owned by the task goroutine.
@@ -487,6 +508,13 @@ type Task struct {
 	// seccomp is owned by the task goroutine.
 	seccomp atomic.Pointer[taskSeccomp] `state:".(*taskSeccomp)"`
 
+	// landlockDomain is the Landlock domain enforced on the task. A nil
+	// landlockDomain restricts nothing. If landlockDomain is not nil, a
+	// reference is held on it.
+	//
+	// landlockDomain is protected by mu. It is owned by the task goroutine.
+	landlockDomain *vfs.LandlockDomain
+
 	// If cleartid is non-zero, treat it as a pointer to a ThreadID in the
 	// task's virtual address space; when the task exits, set the pointed-to
 	// ThreadID to 0, and wake any futex waiters.
@@ -619,6 +647,15 @@ type Task struct {
 	// memCgID is the memory cgroup id.
 	memCgID atomicbitops.Uint32
 
+	// cpuCgID is the id of the cgroup whose cpu controller t is charged to,
+	// or 0 if t isn't in a cgroup with a cpu controller.
+	cpuCgID atomicbitops.Uint32
+
+	// cpuThrottled is set by the CPU clock ticker when one of t's cpu cgroups
+	// exhausts its CPU bandwidth quota. It is cleared by the task goroutine
+	// once the throttling period has elapsed.
+	cpuThrottled atomicbitops.Bool
+
 	// userCounters is a pointer to a set of user counters.
 	//
 	// The userCounters pointer is exclusive to the task goroutine, but the
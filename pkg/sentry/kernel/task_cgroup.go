@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/log"
@@ -70,6 +71,11 @@ func (t *Task) SetMemCgIDFromCgroup(cg Cgroup) {
 	}
 }
 
+// SetCPUCgID sets the id of the cgroup whose cpu controller t is charged to.
+func (t *Task) SetCPUCgID(cpuCgID uint32) {
+	t.cpuCgID.Store(cpuCgID)
+}
+
 // ResetMemCgIDFromCgroup sets the memory cgroup id to zero, if the task has
 // a memory cgroup.
 func (t *Task) ResetMemCgIDFromCgroup(cg Cgroup) {
@@ -230,6 +236,16 @@ func (t *Task) GetCgroupEntries() []TaskCgroupEntry {
 
 	cgEntries := make([]TaskCgroupEntry, 0, len(t.cgroups))
 	for c := range t.cgroups {
+		if c.Unified() {
+			// The unified hierarchy is always displayed as "0::$PATH". See
+			// Linux, kernel/cgroup/cgroup.c:proc_cgroup_show().
+			cgEntries = append(cgEntries, TaskCgroupEntry{
+				HierarchyID: 0,
+				Path:        c.Path(),
+			})
+			continue
+		}
+
 		ctls := c.Controllers()
 		ctlNames := make([]string, 0, len(ctls))
 
@@ -287,3 +303,85 @@ func (t *Task) ChargeFor(other *Task, ctl CgroupControllerType, res CgroupResour
 	defer t.mu.Unlock()
 	return t.chargeLocked(other, ctl, res, value)
 }
+
+// oomRetryDelay is how long a task waits after invoking its memory cgroup's OOM
+// killer before retrying the allocation that failed.
+const oomRetryDelay = 10 * time.Millisecond
+
+// memoryCgroupOOM is called after t failed to allocate memory. If the failure
+// was due to the limit of t's memory cgroup, memoryCgroupOOM invokes the
+// cgroup's OOM killer, waits briefly for the victim to release its memory, and
+// returns true to indicate that the allocation should be retried.
+//
+// Preconditions: The caller must be running on the task goroutine.
+func (t *Task) memoryCgroupOOM() bool {
+	id := t.memCgID.Load()
+	if id == 0 {
+		return false
+	}
+	cg, err := t.k.cgroupRegistry.GetCgroup(id)
+	if err != nil {
+		return false
+	}
+	if !cg.OOMKill(t) {
+		return false
+	}
+	// If t itself was chosen as the victim, this is interrupted by SIGKILL.
+	t.BlockWithTimeout(nil, true, oomRetryDelay)
+	return true
+}
+
+// cpuCgroup returns the cgroup whose cpu controller t is charged to, or nil if
+// there is none.
+func (t *Task) cpuCgroup() CgroupImpl {
+	id := t.cpuCgID.Load()
+	if id == 0 {
+		return nil
+	}
+	cg, ok := t.k.cgroupRegistry.getCgroupFast(id)
+	if !ok {
+		return nil
+	}
+	return cg
+}
+
+// chargeCPUTime charges d of CPU time, consumed at time now, to t's cpu
+// cgroup. If it or one of its ancestors exhausts its CPU bandwidth quota, t is
+// interrupted so that it is throttled before returning to user space.
+//
+// chargeCPUTime is called by the CPU clock ticker, so it doesn't lock t.mu.
+func (t *Task) chargeCPUTime(now int64, d time.Duration) {
+	cg := t.cpuCgroup()
+	if cg == nil || cg.ChargeCPUTime(now, d) == 0 {
+		return
+	}
+	if !t.cpuThrottled.Swap(true) {
+		t.interrupt()
+	}
+}
+
+// doCPUThrottle blocks t until none of its cpu cgroups is throttled. It
+// returns false if t was interrupted while waiting.
+//
+// Preconditions: The caller must be running on the task goroutine.
+func (t *Task) doCPUThrottle() bool {
+	if !t.cpuThrottled.Load() {
+		return true
+	}
+	clock := t.k.MonotonicClock()
+	for {
+		now := clock.Now().Nanoseconds()
+		var until int64
+		if cg := t.cpuCgroup(); cg != nil {
+			until = cg.CPUThrottledUntil(now)
+		}
+		if until == 0 {
+			break
+		}
+		if _, err := t.BlockWithTimeout(nil, true, time.Duration(until-now)); err == linuxerr.ErrInterrupted {
+			return false
+		}
+	}
+	t.cpuThrottled.Store(false)
+	return true
+}
@@ -20,6 +20,7 @@ import (
 	"gvisor.dev/gvisor/pkg/cleanup"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/marshal/primitive"
 	"gvisor.dev/gvisor/pkg/sentry/fsimpl/kernfs"
 	"gvisor.dev/gvisor/pkg/sentry/fsimpl/nsfs"
 	"gvisor.dev/gvisor/pkg/sentry/inet"
@@ -37,7 +38,8 @@ const SupportedCloneFlags = linux.CLONE_VM | linux.CLONE_FS | linux.CLONE_FILES
 	linux.CLONE_CHILD_CLEARTID | linux.CLONE_CHILD_SETTID | linux.CLONE_PARENT |
 	linux.CLONE_PARENT_SETTID | linux.CLONE_SETTLS | linux.CLONE_NEWUSER | linux.CLONE_NEWUTS |
 	linux.CLONE_NEWIPC | linux.CLONE_NEWNET | linux.CLONE_PTRACE | linux.CLONE_UNTRACED |
-	linux.CLONE_IO | linux.CLONE_VFORK | linux.CLONE_DETACHED | linux.CLONE_NEWNS
+	linux.CLONE_IO | linux.CLONE_VFORK | linux.CLONE_DETACHED | linux.CLONE_NEWNS | linux.CLONE_PIDFD |
+	linux.CLONE_NEWTIME
 
 // Clone implements the clone(2) syscall and returns the thread ID of the new
 // task in t's PID namespace. Clone may return both a non-zero thread ID and a
@@ -58,6 +60,17 @@ func (t *Task) Clone(args *linux.CloneArgs) (ThreadID, *SyscallControl, error) {
 	if args.SetTID != 0 {
 		return 0, nil, linuxerr.ENOTSUP
 	}
+	// pidfds refer to thread groups, and are incompatible with the legacy
+	// CLONE_DETACHED flag. For clone(2), the pidfd is returned through the
+	// parent_tid argument.
+	if args.Flags&linux.CLONE_PIDFD != 0 {
+		if args.Flags&(linux.CLONE_THREAD|linux.CLONE_DETACHED) != 0 {
+			return 0, nil, linuxerr.EINVAL
+		}
+		if args.Flags&linux.CLONE_PARENT_SETTID != 0 && args.Pidfd == args.ParentTID {
+			return 0, nil, linuxerr.EINVAL
+		}
+	}
 	// In order for the behavior of thread-group-directed signals to be sane,
 	// all tasks in a thread group must share signal handlers.
 	if args.Flags&(linux.CLONE_THREAD|linux.CLONE_SIGHAND) == linux.CLONE_THREAD {
@@ -115,9 +128,15 @@ func (t *Task) Clone(args *linux.CloneArgs) (ThreadID, *SyscallControl, error) {
 			return 0, nil, err
 		}
 	}
-	if args.Flags&(linux.CLONE_NEWPID|linux.CLONE_NEWNET|linux.CLONE_NEWUTS|linux.CLONE_NEWIPC) != 0 && !creds.HasCapabilityIn(linux.CAP_SYS_ADMIN, userns) {
+	if args.Flags&(linux.CLONE_NEWPID|linux.CLONE_NEWNET|linux.CLONE_NEWUTS|linux.CLONE_NEWIPC|linux.CLONE_NEWTIME) != 0 && !creds.HasCapabilityIn(linux.CAP_SYS_ADMIN, userns) {
 		return 0, nil, linuxerr.EPERM
 	}
+	// "CLONE_NEWIPC must detach from the undolist: after switching to a new
+	// ipc namespace, the semaphore arrays from the old namespace are
+	// unreachable." - kernel/nsproxy.c:copy_namespaces()
+	if args.Flags&(linux.CLONE_NEWIPC|linux.CLONE_SYSVSEM) == linux.CLONE_NEWIPC|linux.CLONE_SYSVSEM {
+		return 0, nil, linuxerr.EINVAL
+	}
 
 	cu := cleanup.Make(func() {})
 	defer cu.Clean()
@@ -135,6 +154,33 @@ func (t *Task) Clone(args *linux.CloneArgs) (ThreadID, *SyscallControl, error) {
 		utsns.DecRef(t)
 	})
 
+	timensForChildren := t.timensForChildren
+	if args.Flags&linux.CLONE_NEWTIME != 0 {
+		var err error
+		timensForChildren, err = timensForChildren.Clone(t.k, userns)
+		if err != nil {
+			return 0, nil, err
+		}
+		timensForChildren.SetInode(nsfs.NewInode(t, t.k.nsfsMount, timensForChildren))
+	} else {
+		timensForChildren.IncRef()
+	}
+	cu.Add(func() {
+		timensForChildren.DecRef(t)
+	})
+
+	// Tasks that share an address space must share a time namespace, since
+	// they share a VDSO parameter page. Otherwise, the child enters the time
+	// namespace for children, as after unshare(CLONE_NEWTIME).
+	timens := t.timens
+	if args.Flags&linux.CLONE_VM == 0 {
+		timens = timensForChildren
+	}
+	timens.IncRef()
+	cu.Add(func() {
+		timens.DecRef(t)
+	})
+
 	ipcns := t.ipcns
 	if args.Flags&linux.CLONE_NEWIPC != 0 {
 		ipcns = NewIPCNamespace(userns)
@@ -173,6 +219,13 @@ func (t *Task) Clone(args *linux.CloneArgs) (ThreadID, *SyscallControl, error) {
 	cu.Add(func() {
 		image.release(t)
 	})
+	if timens != t.timens {
+		// The child's copy of the address space must map the VDSO parameter
+		// page of its time namespace.
+		if oldPage, newPage := t.timens.vdsoParamPage(t.k), timens.enter(t.k); oldPage != newPage {
+			image.MemoryManager.ReplaceSpecialMappable(t, oldPage, newPage)
+		}
+	}
 
 	if args.Flags&linux.CLONE_NEWUSER != 0 {
 		// If the task is in a new user namespace, it cannot share keys.
@@ -268,6 +321,8 @@ func (t *Task) Clone(args *linux.CloneArgs) (ThreadID, *SyscallControl, error) {
 		SessionKeyring:   sessionKeyring,
 		Origin:           t.Origin,
 	}
+	cfg.TimeNamespace = timens
+	cfg.TimeNamespaceForChildren = timensForChildren
 	if args.Flags&linux.CLONE_THREAD == 0 {
 		cfg.Parent = t
 	} else {
@@ -316,11 +371,15 @@ func (t *Task) Clone(args *linux.CloneArgs) (ThreadID, *SyscallControl, error) {
 			// for group signal delivery, had children reparented to it, etc.
 			// Thus we can't just drop it on the floor. Instead, instruct the
 			// task goroutine to exit immediately, as quietly as possible.
-			nt.exitTracerNotified = true
-			nt.exitTracerAcked = true
-			nt.exitParentNotified = true
-			nt.exitParentAcked = true
-			nt.runState = (*runExitMain)(nil)
+			nt.abandonClone()
+			return 0, nil, err
+		}
+	}
+
+	if args.Flags&linux.CLONE_PIDFD != 0 {
+		if err := t.installClonePIDFD(nt.tg, hostarch.Addr(args.Pidfd)); err != nil {
+			// As above, nt can't be dropped on the floor.
+			nt.abandonClone()
 			return 0, nil, err
 		}
 	}
@@ -335,6 +394,22 @@ func (t *Task) Clone(args *linux.CloneArgs) (ThreadID, *SyscallControl, error) {
 	} else {
 		nt.seccomp.Store(nil)
 	}
+	// "If CLONE_SYSVSEM is set, then the child and the calling process share a
+	// single list of System V semaphore adjustment (semadj) values. ... If
+	// this flag is not set, then the child has a separate semadj list that is
+	// initially empty." - clone(2)
+	if args.Flags&linux.CLONE_SYSVSEM != 0 {
+		l := t.SemUndoList()
+		l.IncRef()
+		nt.semUndoList = l
+	}
+	// Landlock domains are inherited by children.
+	if d := t.landlockDomain; d != nil {
+		d.IncRef()
+		nt.mu.Lock()
+		nt.landlockDomain = d
+		nt.mu.Unlock()
+	}
 	if args.Flags&linux.CLONE_VFORK != 0 {
 		nt.vforkParent.Store(t)
 	}
@@ -394,6 +469,42 @@ func getCloneSeccheckInfo(t, nt *Task, flags uint64) (seccheck.FieldSet, *pb.Clo
 	return fields, info
 }
 
+// abandonClone instructs the task goroutine of t, a task created by Task.Clone
+// that can't be returned to the caller, to exit immediately, as quietly as
+// possible.
+//
+// Preconditions: t must not have been started.
+func (t *Task) abandonClone() {
+	t.exitTracerNotified = true
+	t.exitTracerAcked = true
+	t.exitParentNotified = true
+	t.exitParentAcked = true
+	t.runState = (*runExitMain)(nil)
+}
+
+// installClonePIDFD installs a pidfd referring to tg in t's file table, and
+// copies its file descriptor number out to addr.
+func (t *Task) installClonePIDFD(tg *ThreadGroup, addr hostarch.Addr) error {
+	file, err := t.k.NewPIDFD(t, tg, 0)
+	if err != nil {
+		return err
+	}
+	defer file.DecRef(t)
+	// "CLONE_PIDFD ... The close-on-exec flag is set on this new file
+	// descriptor." - clone(2)
+	fd, err := t.NewFDFrom(0, file, FDFlags{CloseOnExec: true})
+	if err != nil {
+		return err
+	}
+	if _, err := primitive.CopyInt32Out(t, addr, fd); err != nil {
+		if file := t.fdTable.Remove(t, fd); file != nil {
+			file.DecRef(t)
+		}
+		return err
+	}
+	return nil
+}
+
 // maybeBeginVforkStop checks if a previously-started vfork child is still
 // running and has not yet released its MM, such that its parent t should enter
 // a vforkStop.
@@ -504,6 +615,9 @@ func (t *Task) Setns(fd *vfs.FileDescription, flags int32) error {
 		t.ipcns = ns
 		t.mu.Unlock()
 		oldNS.DecRef(t)
+		// Like Linux, detach from (and apply) the semaphore adjustments of
+		// the old namespace.
+		t.releaseSemUndoList()
 		return nil
 	case *vfs.MountNamespace:
 		if flags != 0 && flags != linux.CLONE_NEWNS {
@@ -551,6 +665,35 @@ func (t *Task) Setns(fd *vfs.FileDescription, flags int32) error {
 		t.mu.Unlock()
 		oldNS.DecRef(t)
 		return nil
+	case *TimeNamespace:
+		if flags != 0 && flags != linux.CLONE_NEWTIME {
+			return linuxerr.EINVAL
+		}
+		// The task's address space, and thus its VDSO parameter page, must
+		// not be shared with other threads.
+		t.tg.signalHandlers.mu.Lock()
+		tasksCount := t.tg.tasksCount
+		t.tg.signalHandlers.mu.Unlock()
+		if tasksCount != 1 {
+			return linuxerr.EUSERS
+		}
+		if !t.HasCapabilityIn(linux.CAP_SYS_ADMIN, ns.UserNamespace()) ||
+			!t.Credentials().HasCapability(linux.CAP_SYS_ADMIN) {
+			return linuxerr.EPERM
+		}
+		if oldPage, newPage := t.timens.vdsoParamPage(t.k), ns.enter(t.k); oldPage != newPage {
+			t.MemoryManager().ReplaceSpecialMappable(t, oldPage, newPage)
+		}
+		oldNS, oldNSForChildren := t.timens, t.timensForChildren
+		ns.IncRef()
+		ns.IncRef()
+		t.mu.Lock()
+		t.timens = ns
+		t.timensForChildren = ns
+		t.mu.Unlock()
+		oldNS.DecRef(t)
+		oldNSForChildren.DecRef(t)
+		return nil
 	case *PIDNamespace:
 		if flags != 0 && flags != linux.CLONE_NEWPID {
 			return linuxerr.EINVAL
@@ -679,6 +822,23 @@ func (t *Task) Unshare(flags int32) error {
 		t.utsns.SetInode(nsfs.NewInode(t, t.k.nsfsMount, t.utsns))
 		cu.Add(func() { oldUTSNS.DecRef(t) })
 	}
+	if flags&linux.CLONE_NEWTIME != 0 {
+		if !haveCapSysAdmin {
+			return linuxerr.EPERM
+		}
+		// "Unshare the time namespace, so that the calling process has a new
+		// time namespace for its children which is not shared with any
+		// previously existing process. The calling process is not moved into
+		// the new namespace." - unshare(2)
+		timens, err := t.timensForChildren.Clone(t.k, creds.UserNamespace)
+		if err != nil {
+			return err
+		}
+		timens.SetInode(nsfs.NewInode(t, t.k.nsfsMount, timens))
+		oldTimeNS := t.timensForChildren
+		t.timensForChildren = timens
+		cu.Add(func() { oldTimeNS.DecRef(t) })
+	}
 	if flags&linux.CLONE_NEWIPC != 0 {
 		if !haveCapSysAdmin {
 			return linuxerr.EPERM
@@ -713,6 +873,20 @@ func (t *Task) Unshare(flags int32) error {
 		t.mountNamespace = mntns
 		cu.Add(func() { oldMountNS.DecRef(t) })
 	}
+	// "[CLONE_SYSVSEM] reverses the effect of the clone(2)
+	// CLONE_SYSVSEM flag. Unshare System V semaphore adjustment (semadj)
+	// values, so that the calling process has a new empty semadj list that
+	// is not shared with any other process. If this is the last process that
+	// has a reference to the process's current semadj list, then the
+	// adjustments in that list are applied to the corresponding semaphores"
+	// - unshare(2). CLONE_NEWIPC implies CLONE_SYSVSEM.
+	//
+	// Like Linux, this is only done once nothing else can fail. Since cu
+	// runs in reverse order, the list is released before the old IPC
+	// namespace.
+	if flags&(linux.CLONE_SYSVSEM|linux.CLONE_NEWIPC) != 0 {
+		cu.Add(t.releaseSemUndoList)
+	}
 	return nil
 }
 
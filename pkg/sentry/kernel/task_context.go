@@ -104,6 +104,12 @@ func (t *Task) contextValue(key any, isTaskGoroutine bool) any {
 		}
 		t.mountNamespace.IncRef()
 		return t.mountNamespace
+	case vfs.CtxLandlockDomain:
+		if !isTaskGoroutine {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+		}
+		return t.landlockDomain
 	case devutil.CtxDevGoferClient:
 		return t.k.GetDevGoferClient(t.k.ContainerName(t.containerID))
 	case inet.CtxStack:
@@ -251,7 +251,18 @@ func (r *runSyscallAfterExecStop) execute(t *Task) taskRunState {
 	t.updateCredsForExecLocked()
 	oldImage := t.image
 	t.image = *r.image
+	// Like Linux, enter the time namespace for children, since the new
+	// address space isn't shared with tasks in the current time namespace.
+	var oldTimeNS *TimeNamespace
+	if t.timens != t.timensForChildren {
+		oldTimeNS = t.timens
+		t.timens = t.timensForChildren
+		t.timens.IncRef()
+	}
 	t.mu.Unlock()
+	if oldTimeNS != nil {
+		oldTimeNS.DecRef(t)
+	}
 
 	// Don't hold t.mu while calling t.image.release(), that may
 	// attempt to acquire TaskImage.MemoryManager.mappingMu, a lock order
@@ -291,6 +291,9 @@ func (*runExitMain) execute(t *Task) taskRunState {
 	t.fsContext.DecRef(t)
 	t.fdTable.DecRef(t)
 
+	// Apply the adjustments of SEM_UNDO semaphore operations.
+	t.releaseSemUndoList()
+
 	// Detach task from all cgroups. This must happen before potentially the
 	// last ref to the cgroupfs mount is dropped below.
 	t.LeaveCgroups()
@@ -300,20 +303,31 @@ func (*runExitMain) execute(t *Task) taskRunState {
 	t.mountNamespace = nil
 	utsns := t.utsns
 	t.utsns = nil
+	timens := t.timens
+	t.timens = nil
+	timensForChildren := t.timensForChildren
+	t.timensForChildren = nil
 	ipcns := t.ipcns
 	t.ipcns = nil
 	netns := t.netns
 	t.netns = nil
 	childPIDNS := t.childPIDNamespace
 	t.childPIDNamespace = nil
+	landlockDomain := t.landlockDomain
+	t.landlockDomain = nil
 	t.mu.Unlock()
 	mntns.DecRef(t)
 	utsns.DecRef(t)
+	timens.DecRef(t)
+	timensForChildren.DecRef(t)
 	ipcns.DecRef(t)
 	netns.DecRef(t)
 	if childPIDNS != nil {
 		childPIDNS.DecRef(t)
 	}
+	if landlockDomain != nil {
+		landlockDomain.DecRef(t)
+	}
 
 	// If this is the last task to exit from the thread group, release the
 	// thread group's resources.
@@ -607,6 +621,9 @@ func (*runExitNotify) execute(t *Task) taskRunState {
 	defer t.tg.pidns.owner.mu.Unlock()
 	t.advanceExitStateLocked(TaskExitInitiated, TaskExitZombie)
 	t.tg.liveTasks--
+	if t.tg.liveTasks == 0 {
+		t.tg.exitQueue.Notify(waiter.ReadableEvents)
+	}
 	// Check if this completes a sibling's execve.
 	if t.tg.execing != nil && t.tg.liveTasks == 1 {
 		// execing blocks the addition of new tasks to the thread group, so
@@ -756,6 +773,7 @@ func (t *Task) exitNotifyLocked(fromPtraceDetach bool) {
 		} else if tc == 0 {
 			t.tg.pidWithinNS.Store(0)
 			t.tg.processGroup.decRefWithParent(t.tg.parentPG())
+			t.tg.exitQueue.Notify(waiter.EventHUp)
 		}
 		if t.parent != nil {
 			delete(t.parent.children, t)
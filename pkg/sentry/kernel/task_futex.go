@@ -89,55 +89,61 @@ func (t *Task) exitRobustList() {
 		return
 	}
 
-	next := primitive.Uint64(rl.List)
-	done := 0
-	var pendingLockAddr hostarch.Addr
-	if rl.ListOpPending != 0 {
-		pendingLockAddr = hostarch.Addr(rl.ListOpPending + rl.FutexOffset)
-	}
+	// Bit 0 of each list entry indicates a PI futex.
+	entry, pi := robustListEntry(rl.List)
+	pending, pendingPI := robustListEntry(rl.ListOpPending)
 
 	// Wake up normal elements.
-	for hostarch.Addr(next) != addr {
+	for limit := linux.ROBUST_LIST_LIMIT; entry != addr; {
 		// We traverse to the next element of the list before we
 		// actually wake anything. This prevents the race where waking
 		// this futex causes a modification of the list.
-		thisLockAddr := hostarch.Addr(uint64(next) + rl.FutexOffset)
-
+		//
 		// Try to decode the next element in the list before waking the
 		// current futex. But don't check the error until after we've
 		// woken the current futex. Linux does it in this order too
-		_, nextErr := next.CopyIn(t, hostarch.Addr(next))
+		var next primitive.Uint64
+		_, nextErr := next.CopyIn(t, entry)
 
 		// Wakeup the current futex if it's not pending.
-		if thisLockAddr != pendingLockAddr {
-			t.wakeRobustListOne(thisLockAddr)
+		if entry != pending {
+			t.handleFutexDeath(entry+hostarch.Addr(rl.FutexOffset), pi, false /* pendingOp */)
 		}
 
 		// If there was an error copying the next futex, we must bail.
 		if nextErr != nil {
-			break
+			return
 		}
+		entry, pi = robustListEntry(uint64(next))
 
 		// This is a user structure, so it could be a massive list, or
 		// even contain a loop if they are trying to mess with us. We
 		// cap traversal to prevent that.
-		done++
-		if done >= linux.ROBUST_LIST_LIMIT {
+		limit--
+		if limit == 0 {
 			break
 		}
 	}
 
 	// Is there a pending entry to wake?
-	if pendingLockAddr != 0 {
-		t.wakeRobustListOne(pendingLockAddr)
+	if pending != 0 {
+		t.handleFutexDeath(pending+hostarch.Addr(rl.FutexOffset), pendingPI, true /* pendingOp */)
 	}
 }
 
-// wakeRobustListOne wakes a single futex from the robust list.
-func (t *Task) wakeRobustListOne(addr hostarch.Addr) {
-	// Bit 0 in address signals PI futex.
-	pi := addr&1 == 1
-	addr = addr &^ 1
+// robustListEntry decodes a pointer to a robust list entry.
+func robustListEntry(ptr uint64) (entry hostarch.Addr, pi bool) {
+	return hostarch.Addr(ptr &^ 1), ptr&1 != 0
+}
+
+// handleFutexDeath marks the futex at addr, which may be held by t, as owned
+// by a dead task and wakes a waiter. pendingOp is true if addr is the robust
+// list's pending entry. It corresponds to Linux's handle_futex_death().
+func (t *Task) handleFutexDeath(addr hostarch.Addr, pi, pendingOp bool) {
+	// Futex words must be aligned.
+	if addr%4 != 0 {
+		return
+	}
 
 	// Load the futex.
 	f, err := t.LoadUint32(addr)
@@ -147,6 +153,16 @@ func (t *Task) wakeRobustListOne(addr hostarch.Addr) {
 		return
 	}
 
+	// A regular futex with a pending operation and no owner may have been
+	// released by t just before it could wake a waiter, or t may be a woken
+	// waiter that died before acquiring it. Either way, the TID check below
+	// would skip the wakeup and leave the waiters blocked forever, so wake
+	// one now.
+	if pendingOp && !pi && f == 0 {
+		t.Futex().Wake(t, addr, false /* private */, linux.FUTEX_BITSET_MATCH_ANY, 1)
+		return
+	}
+
 	tid := uint32(t.ThreadID())
 	for {
 		// Is this held by someone else?
@@ -164,18 +180,21 @@ func (t *Task) wakeRobustListOne(addr hostarch.Addr) {
 			f = curF
 			continue
 		}
+		break
+	}
+	if f&linux.FUTEX_WAITERS == 0 {
+		return
+	}
 
-		// Wake waiters if there are any.
-		if f&linux.FUTEX_WAITERS != 0 {
-			private := f&linux.FUTEX_PRIVATE_FLAG != 0
-			if pi {
-				t.Futex().UnlockPI(t, addr, tid, private)
-				return
-			}
-			t.Futex().Wake(t, addr, private, linux.FUTEX_BITSET_MATCH_ANY, 1)
-		}
-
-		// Done.
+	// Like Linux, wake waiters on robust futexes as if they were shared;
+	// userspace is expected to wait on robust futexes accordingly. PI
+	// waiters are handed the futex instead, regardless of how they're
+	// waiting on it.
+	if !pi {
+		t.Futex().Wake(t, addr, false /* private */, linux.FUTEX_BITSET_MATCH_ANY, 1)
 		return
 	}
+	if woken, err := t.Futex().HandOffPI(t, addr, false /* private */); err == nil && !woken {
+		t.Futex().HandOffPI(t, addr, true /* private */)
+	}
 }
@@ -149,6 +149,11 @@ func (k *Kernel) LoadTaskImage(ctx context.Context, args loader.LoadArgs) (*Task
 	m := mm.NewMemoryManager(k, k.mf, k.SleepForAddressSpaceActivation)
 	defer m.DecUsers(ctx)
 	args.MemoryManager = m
+	if t := TaskFromContext(ctx); t != nil && args.VDSOParamPage == nil {
+		// An execing task enters its time namespace for children; see
+		// runSyscallAfterExecStop.execute.
+		args.VDSOParamPage = t.timensForChildren.enter(k)
+	}
 
 	info, err := loader.Load(ctx, args, k.extraAuxv, k.vdso)
 	if err != nil {
@@ -0,0 +1,47 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kernel
+
+import (
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+)
+
+// LandlockDomain returns the Landlock domain enforced on t, which may be nil.
+// No reference is taken on the returned LandlockDomain.
+//
+// Preconditions: The caller must be running on the task goroutine.
+func (t *Task) LandlockDomain() *vfs.LandlockDomain {
+	return t.landlockDomain
+}
+
+// RestrictLandlock adds the rules of r to the Landlock domain enforced on t,
+// as for landlock_restrict_self(2). Like Linux, this only affects t and its
+// future children, not other threads in its thread group.
+//
+// Preconditions: The caller must be running on the task goroutine.
+func (t *Task) RestrictLandlock(r *vfs.LandlockRuleset) error {
+	d, err := t.landlockDomain.Restrict(r)
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	old := t.landlockDomain
+	t.landlockDomain = d
+	t.mu.Unlock()
+	if old != nil {
+		old.DecRef(t)
+	}
+	return nil
+}
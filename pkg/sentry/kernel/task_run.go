@@ -156,6 +156,11 @@ func (app *runApp) execute(t *Task) taskRunState {
 		return (*runInterrupt)(nil)
 	}
 
+	// Wait out CPU bandwidth throttling before returning to user space.
+	if !t.doCPUThrottle() {
+		return (*runInterrupt)(nil)
+	}
+
 	// Execute any task work callbacks before returning to user space.
 	if t.taskWorkCount.Load() > 0 {
 		t.taskWorkMu.Lock()
@@ -295,6 +300,12 @@ func (app *runApp) execute(t *Task) taskRunState {
 				return (*runApp)(nil)
 			}
 
+			// If the fault failed because t's memory cgroup is at its limit,
+			// its OOM killer frees memory and the fault can be retried.
+			if linuxerr.Equals(linuxerr.ENOMEM, err) && t.memoryCgroupOOM() {
+				return (*runApp)(nil)
+			}
+
 			// Is this a vsyscall that we need emulate?
 			//
 			// Note that we don't track vsyscalls as part of a
@@ -274,6 +274,13 @@ func (k *Kernel) runCPUClockTicker() {
 		rand.Shuffle(numIncTasks, func(i, j int) {
 			incTasks[i], incTasks[j] = incTasks[j], incTasks[i]
 		})
+		// CPU time only needs to be charged to cgroups if one of them has a
+		// CPU bandwidth quota.
+		chargeCPU := numIncTasks > 0 && k.cgroupRegistry != nil && k.cgroupRegistry.hasCPUQuotas()
+		var now int64
+		if chargeCPU {
+			now = k.MonotonicClock().Now().Nanoseconds()
+		}
 		for _, t := range incTasks[:numIncTasks] {
 			switch t.TaskGoroutineState() {
 			case TaskGoroutineRunningApp:
@@ -286,6 +293,12 @@ func (k *Kernel) runCPUClockTicker() {
 				t.tg.appSysCPUClockLast.Store(t)
 				t.tg.appSysCPUClock.Add(linux.ClockTick)
 			}
+			// Enforce CPU bandwidth limits. Like other CPU clocks, this is
+			// approximate: tasks in a throttled cgroup are only stopped once
+			// they are themselves charged a tick.
+			if chargeCPU {
+				t.chargeCPUTime(now, linux.ClockTick)
+			}
 		}
 
 		// Reset storage for the next iteration.
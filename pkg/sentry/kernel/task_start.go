@@ -78,6 +78,13 @@ type TaskConfig struct {
 	// UTSNamespace is the UTSNamespace of the new task.
 	UTSNamespace *UTSNamespace
 
+	// TimeNamespace is the TimeNamespace of the new task.
+	TimeNamespace *TimeNamespace
+
+	// TimeNamespaceForChildren is the TimeNamespace that children of the new
+	// task are created in.
+	TimeNamespaceForChildren *TimeNamespace
+
 	// IPCNamespace is the IPCNamespace of the new task.
 	IPCNamespace *IPCNamespace
 
@@ -120,6 +127,8 @@ func (ts *TaskSet) NewTask(ctx context.Context, cfg *TaskConfig) (*Task, error)
 		cfg.FSContext.DecRef(ctx)
 		cfg.FDTable.DecRef(ctx)
 		cfg.UTSNamespace.DecRef(ctx)
+		cfg.TimeNamespace.DecRef(ctx)
+		cfg.TimeNamespaceForChildren.DecRef(ctx)
 		cfg.IPCNamespace.DecRef(ctx)
 		cfg.NetworkNamespace.DecRef(ctx)
 		if cfg.MountNamespace != nil {
@@ -178,6 +187,8 @@ func (ts *TaskSet) newTask(ctx context.Context, cfg *TaskConfig) (*Task, error)
 		onDestroyAction: make(map[TaskDestroyAction]struct{}),
 	}
 	t.netns = cfg.NetworkNamespace
+	t.timens = cfg.TimeNamespace
+	t.timensForChildren = cfg.TimeNamespaceForChildren
 	t.creds.Store(cfg.Credentials)
 	t.endStopCond.L = &t.tg.signalHandlers.mu
 	// We don't construct t.blockingTimer until Task.run(); see that function
@@ -0,0 +1,327 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kernel
+
+import (
+	"math"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/sentry/fsimpl/nsfs"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/ktime"
+	"gvisor.dev/gvisor/pkg/sentry/mm"
+	"gvisor.dev/gvisor/pkg/sentry/pgalloc"
+	"gvisor.dev/gvisor/pkg/sentry/usage"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+// maxTimeNamespaceSec is the maximum value, in seconds, of a clock in a time
+// namespace. This is KTIME_SEC_MAX / 2 in Linux, which ensures that KTIME_MAX
+// is still unreachable.
+const maxTimeNamespaceSec = math.MaxInt64 / int64(time.Second) / 2
+
+// TimeNamespace represents a time namespace, which offsets the
+// CLOCK_MONOTONIC and CLOCK_BOOTTIME clocks observed by its tasks.
+//
+// The offsets of a time namespace can only be changed until a task enters
+// it, at which point they are frozen.
+//
+// +stateify savable
+type TimeNamespace struct {
+	// userns is the user namespace associated with the TimeNamespace.
+	// Privileged operations on this TimeNamespace must have appropriate
+	// capabilities in userns.
+	//
+	// userns is immutable.
+	userns *auth.UserNamespace
+
+	// paramPage is a VDSO parameter page whose clocks are never ready, which
+	// is mapped instead of the kernel's parameter page by tasks in the
+	// namespace if its offsets are not zero. This forces the VDSO to fall
+	// back to clock_gettime(2), which applies the offsets.
+	//
+	// paramPage is nil for the root time namespace. paramPage is immutable.
+	paramPage *mm.SpecialMappable
+
+	// mu protects the fields below.
+	mu sync.Mutex `state:"nosave"`
+
+	// monotonicOffset and boottimeOffset are the offsets added to
+	// CLOCK_MONOTONIC and CLOCK_BOOTTIME respectively.
+	monotonicOffset time.Duration
+	boottimeOffset  time.Duration
+
+	// frozen is true once a task has entered the namespace. The offsets are
+	// immutable once frozen is true.
+	frozen bool
+
+	// monotonicClock and boottimeClock are the clocks observed by tasks in
+	// the namespace. They are set when frozen becomes true, and are immutable
+	// thereafter.
+	monotonicClock ktime.SampledClock
+	boottimeClock  ktime.SampledClock
+
+	inode *nsfs.Inode
+}
+
+// NewRootTimeNamespace creates the root time namespace of k, whose offsets
+// are zero.
+func NewRootTimeNamespace(k *Kernel, userns *auth.UserNamespace) *TimeNamespace {
+	ns := &TimeNamespace{userns: userns}
+	ns.freezeLocked(k)
+	return ns
+}
+
+// TimeNamespace returns the task's time namespace.
+func (t *Task) TimeNamespace() *TimeNamespace {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.timens
+}
+
+// GetTimeNamespace takes a reference on the task time namespace and returns
+// it. It will return nil if the task isn't alive.
+func (t *Task) GetTimeNamespace() *TimeNamespace {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.timens != nil {
+		t.timens.IncRef()
+	}
+	return t.timens
+}
+
+// GetTimeNamespaceForChildren takes a reference on the time namespace that
+// children of the task will be created in, and returns it. It will return nil
+// if the task isn't alive.
+func (t *Task) GetTimeNamespaceForChildren() *TimeNamespace {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.timensForChildren != nil {
+		t.timensForChildren.IncRef()
+	}
+	return t.timensForChildren
+}
+
+// UserNamespace returns the user namespace associated with this time
+// namespace.
+func (ns *TimeNamespace) UserNamespace() *auth.UserNamespace {
+	return ns.userns
+}
+
+// Offsets returns the offsets of CLOCK_MONOTONIC and CLOCK_BOOTTIME in ns.
+func (ns *TimeNamespace) Offsets() (monotonic, boottime time.Duration) {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	return ns.monotonicOffset, ns.boottimeOffset
+}
+
+// TimeOffset is the offset of a clock in a time namespace, as written to
+// /proc/[pid]/timens_offsets.
+type TimeOffset struct {
+	// ClockID is either CLOCK_MONOTONIC or CLOCK_BOOTTIME.
+	ClockID int32
+
+	// Offset is the offset of the clock.
+	Offset linux.Timespec
+}
+
+// SetOffsets sets the offsets of the clocks in offsets. It fails with EACCES
+// if a task has already entered ns.
+func (ns *TimeNamespace) SetOffsets(ctx context.Context, creds *auth.Credentials, offsets []TimeOffset) error {
+	if !creds.HasCapabilityIn(linux.CAP_SYS_TIME, ns.userns) {
+		return linuxerr.EPERM
+	}
+	// CLOCK_BOOTTIME is internally mapped to CLOCK_MONOTONIC; see
+	// syscalls/linux.getClock.
+	now := KernelFromContext(ctx).MonotonicClock().Now()
+	for _, o := range offsets {
+		if o.ClockID != linux.CLOCK_MONOTONIC && o.ClockID != linux.CLOCK_BOOTTIME {
+			return linuxerr.EINVAL
+		}
+		if o.Offset.Nsec < 0 || o.Offset.Nsec >= int64(time.Second) {
+			return linuxerr.EINVAL
+		}
+		// Like Linux, reject offsets that would make the clock negative or
+		// bring it close to overflowing.
+		if o.Offset.Sec < -maxTimeNamespaceSec || o.Offset.Sec > maxTimeNamespaceSec {
+			return linuxerr.EINVAL
+		}
+		if sec := (now.Nanoseconds() + o.Offset.ToNsec()) / int64(time.Second); sec < 0 || sec > maxTimeNamespaceSec {
+			return linuxerr.EINVAL
+		}
+	}
+
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	if ns.frozen {
+		return linuxerr.EACCES
+	}
+	for _, o := range offsets {
+		d := time.Duration(o.Offset.ToNsec())
+		if o.ClockID == linux.CLOCK_MONOTONIC {
+			ns.monotonicOffset = d
+		} else {
+			ns.boottimeOffset = d
+		}
+	}
+	return nil
+}
+
+// MonotonicClock returns the CLOCK_MONOTONIC clock of ns.
+//
+// Preconditions: A task must have entered ns.
+func (ns *TimeNamespace) MonotonicClock() ktime.SampledClock {
+	return ns.monotonicClock
+}
+
+// BoottimeClock returns the CLOCK_BOOTTIME clock of ns.
+//
+// Preconditions: A task must have entered ns.
+func (ns *TimeNamespace) BoottimeClock() ktime.SampledClock {
+	return ns.boottimeClock
+}
+
+// enter is called when a task enters ns. It freezes the offsets of ns, and
+// returns the VDSO parameter page that must be mapped by tasks in ns.
+func (ns *TimeNamespace) enter(k *Kernel) *mm.SpecialMappable {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	if !ns.frozen {
+		ns.freezeLocked(k)
+	}
+	return ns.vdsoParamPageLocked(k)
+}
+
+// vdsoParamPage returns the VDSO parameter page that must be mapped by tasks
+// in ns.
+func (ns *TimeNamespace) vdsoParamPage(k *Kernel) *mm.SpecialMappable {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	return ns.vdsoParamPageLocked(k)
+}
+
+// Preconditions: ns.mu must be locked.
+func (ns *TimeNamespace) vdsoParamPageLocked(k *Kernel) *mm.SpecialMappable {
+	if ns.paramPage == nil || (ns.monotonicOffset == 0 && ns.boottimeOffset == 0) {
+		return k.vdso.ParamPage
+	}
+	return ns.paramPage
+}
+
+// Preconditions: ns.mu must be locked, or ns must not be shared.
+func (ns *TimeNamespace) freezeLocked(k *Kernel) {
+	ns.frozen = true
+	ns.monotonicClock = newTimeNamespaceClock(k.MonotonicClock(), ns.monotonicOffset)
+	ns.boottimeClock = newTimeNamespaceClock(k.MonotonicClock(), ns.boottimeOffset)
+}
+
+// Type implements nsfs.Namespace.Type.
+func (ns *TimeNamespace) Type() string {
+	return "time"
+}
+
+// Destroy implements nsfs.Namespace.Destroy.
+func (ns *TimeNamespace) Destroy(ctx context.Context) {
+	if ns.paramPage != nil {
+		ns.paramPage.DecRef(ctx)
+	}
+}
+
+// SetInode sets the nsfs `inode` to the time namespace.
+func (ns *TimeNamespace) SetInode(inode *nsfs.Inode) {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	ns.inode = inode
+}
+
+// GetInode returns the nsfs inode associated with the time namespace.
+func (ns *TimeNamespace) GetInode() *nsfs.Inode {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	return ns.inode
+}
+
+// IncRef increments the Namespace's refcount.
+func (ns *TimeNamespace) IncRef() {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	ns.inode.IncRef()
+}
+
+// DecRef decrements the namespace's refcount.
+func (ns *TimeNamespace) DecRef(ctx context.Context) {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	ns.inode.DecRef(ctx)
+}
+
+// Clone makes a copy of this time namespace, associating the given user
+// namespace. Like Linux, the new namespace inherits the offsets of ns.
+func (ns *TimeNamespace) Clone(k *Kernel, userns *auth.UserNamespace) (*TimeNamespace, error) {
+	fr, err := k.mf.Allocate(hostarch.PageSize, pgalloc.AllocOpts{Kind: usage.System})
+	if err != nil {
+		return nil, err
+	}
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	return &TimeNamespace{
+		userns:          userns,
+		paramPage:       mm.NewSpecialMappable("[vvar]", k.mf, fr),
+		monotonicOffset: ns.monotonicOffset,
+		boottimeOffset:  ns.boottimeOffset,
+	}, nil
+}
+
+// timeNamespaceClock is a ktime.SampledClock that adds the offset of a time
+// namespace to a kernel clock.
+//
+// +stateify savable
+type timeNamespaceClock struct {
+	// SampledClock is the kernel clock.
+	ktime.SampledClock
+
+	// offset is the offset added to the kernel clock. offset is immutable.
+	offset time.Duration
+}
+
+// newTimeNamespaceClock returns a clock that offsets c by offset.
+func newTimeNamespaceClock(c ktime.SampledClock, offset time.Duration) ktime.SampledClock {
+	if offset == 0 {
+		return c
+	}
+	return &timeNamespaceClock{
+		SampledClock: c,
+		offset:       offset,
+	}
+}
+
+// Now implements ktime.Clock.Now.
+func (c *timeNamespaceClock) Now() ktime.Time {
+	return c.SampledClock.Now().Add(c.offset)
+}
+
+// WallTimeUntil implements ktime.SampledClock.WallTimeUntil.
+func (c *timeNamespaceClock) WallTimeUntil(t, now ktime.Time) time.Duration {
+	return c.SampledClock.WallTimeUntil(t.Add(-c.offset), now.Add(-c.offset))
+}
+
+// NewTimer implements ktime.Clock.NewTimer.
+func (c *timeNamespaceClock) NewTimer(l ktime.Listener) ktime.Timer {
+	return ktime.NewSampledTimer(c, l)
+}
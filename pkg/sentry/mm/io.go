@@ -505,6 +505,10 @@ func (mm *MemoryManager) handleASIOFault(ctx context.Context, addr hostarch.Addr
 	if pendaddr := pend.Start(); pendaddr < ar.End {
 		if pendaddr <= ar.Start {
 			mm.activeMu.Unlock()
+			if uf, ok := err.(*userfault); ok {
+				// The I/O is retried once the userfaultfd resolves the fault.
+				return translateIOError(ctx, uf.wait(ctx, true /* kernel */))
+			}
 			return translateIOError(ctx, err)
 		}
 		ar.End = pendaddr
@@ -540,6 +544,7 @@ func (mm *MemoryManager) withInternalMappings(ctx context.Context, ar hostarch.A
 		return int64(n), err
 	}
 	mm.activeMu.RUnlock()
+	ioar := ar
 
 	// Ensure that we have usable vmas.
 	mm.mappingMu.RLock()
@@ -556,6 +561,15 @@ func (mm *MemoryManager) withInternalMappings(ctx context.Context, ar hostarch.A
 	mm.activeMu.Lock()
 	pseg, pend, perr := mm.getPMAsLocked(ctx, vseg, ar, at, true /* callerIndirectCommit */)
 	mm.mappingMu.RUnlock()
+	if uf, ok := perr.(*userfault); ok {
+		// Wait for the userfaultfd to resolve the fault before doing any I/O,
+		// then start over.
+		mm.activeMu.Unlock()
+		if err := uf.wait(ctx, true /* kernel */); err != nil {
+			return 0, translateIOError(ctx, err)
+		}
+		return mm.withInternalMappings(ctx, ioar, at, ignorePermissions, f)
+	}
 	if pendaddr := pend.Start(); pendaddr < ar.End {
 		if pendaddr <= ar.Start {
 			mm.activeMu.Unlock()
@@ -630,6 +644,14 @@ func (mm *MemoryManager) withVecInternalMappings(ctx context.Context, ars hostar
 	mm.activeMu.Lock()
 	pars, perr := mm.getVecPMAsLocked(ctx, vars, at, true /* callerIndirectCommit */)
 	mm.mappingMu.RUnlock()
+	if uf, ok := perr.(*userfault); ok {
+		// See withInternalMappings.
+		mm.activeMu.Unlock()
+		if err := uf.wait(ctx, true /* kernel */); err != nil {
+			return 0, translateIOError(ctx, err)
+		}
+		return mm.withVecInternalMappings(ctx, ars, at, ignorePermissions, f)
+	}
 	if pars.NumBytes() == 0 {
 		mm.activeMu.Unlock()
 		return 0, translateIOError(ctx, perr)
@@ -324,6 +324,12 @@ type vma struct {
 	// This field can be read atomically, and written with mm.activeMu locked for
 	// writing and mm.mapping locked.
 	lastFault uintptr
+
+	// If uffd is not nil, this vma is registered with a userfaultfd, and
+	// uffdMode is the combination of linux.UFFDIO_REGISTER_MODE_* describing
+	// the faults reported to uffd. See userfaultfd.go.
+	uffd     UserfaultHandler
+	uffdMode uint64
 }
 
 func (v *vma) copy() vma {
@@ -344,6 +350,9 @@ func (v *vma) copy() vma {
 		name:           v.name,
 		nameMut:        v.nameMut,
 		lastFault:      atomic.LoadUintptr(&v.lastFault),
+		// uffd and uffdMode are deliberately not copied: like Linux without
+		// UFFD_FEATURE_EVENT_FORK and UFFD_FEATURE_EVENT_REMAP, copies of a vma
+		// made by fork() and mremap() are not registered with its userfaultfd.
 	}
 }
 
@@ -394,6 +403,13 @@ type pma struct {
 	// Invariant: If huge == true, then private == true.
 	huge bool
 
+	// If uffdWP is true, this pma has been write-protected by
+	// UFFDIO_WRITEPROTECT, and writes to it are reported to the userfaultfd
+	// registered with the corresponding vma.
+	//
+	// Invariant: If uffdWP == true, then effectivePerms.Write == false.
+	uffdWP bool
+
 	// If internalMappings is not empty, it is the cached return value of
 	// file.MapInternal for the memmap.FileRange mapped by this pma.
 	internalMappings safemem.BlockSeq `state:"nosave"`
@@ -19,6 +19,7 @@ import (
 	"sync"
 	"sync/atomic"
 
+	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/hostarch"
@@ -273,6 +274,15 @@ func (mm *MemoryManager) getPMAsInternalLocked(ctx context.Context, vseg vmaIter
 					}
 				}
 				if vma.mappable == nil {
+					if vma.uffdMode&linux.UFFDIO_REGISTER_MODE_MISSING != 0 {
+						// Missing pages in ranges registered with a
+						// userfaultfd are installed by its handler.
+						addr := pgap.Start()
+						if addr < ar.Start {
+							addr = ar.Start
+						}
+						return pstart, pgap, newUserfault(vma, addr, at, 0)
+					}
 					// Private anonymous mappings get pmas by allocating.
 					// The allocated range is limited to ar, expanded to
 					// hugepage alignment. This is done even if the allocation
@@ -385,6 +395,24 @@ func (mm *MemoryManager) getPMAsInternalLocked(ctx context.Context, vseg vmaIter
 
 			case pseg.Ok() && pseg.Start() < vsegAR.End:
 				oldpma := pseg.ValuePtr()
+				if at.Write && oldpma.uffdWP {
+					if vma.uffdMode&linux.UFFDIO_REGISTER_MODE_WP != 0 {
+						addr := pseg.Start()
+						if addr < ar.Start {
+							addr = ar.Start
+						}
+						return pstart, pseg.PrevGap(), newUserfault(vma, addr, at, linux.UFFD_PAGEFAULT_FLAG_WP)
+					}
+					// The vma is no longer registered for write-protect
+					// faults, e.g. because its userfaultfd was closed or the
+					// pma was moved by mremap(), so the write protection no
+					// longer applies.
+					oldpma.uffdWP = false
+					oldpma.effectivePerms = vma.effectivePerms.Intersect(oldpma.translatePerms)
+					if oldpma.needCOW {
+						oldpma.effectivePerms.Write = false
+					}
+				}
 				if at.Write && mm.isPMACopyOnWriteLocked(vseg, pseg) {
 					// Break copy-on-write by copying.
 					if checkInvariants {
@@ -732,6 +760,10 @@ func (mm *MemoryManager) Pin(ctx context.Context, ar hostarch.AddrRange, at host
 	mm.activeMu.Lock()
 	pseg, pend, perr := mm.getPMAsLocked(ctx, vseg, ar, at, false /* callerIndirectCommit */)
 	mm.mappingMu.RUnlock()
+	if _, ok := perr.(*userfault); ok {
+		// Pin doesn't wait for userfaultfds to populate pages.
+		perr = linuxerr.EFAULT
+	}
 	if pendaddr := pend.Start(); pendaddr < ar.End {
 		if pendaddr <= ar.Start {
 			mm.activeMu.Unlock()
@@ -947,7 +979,8 @@ func (pmaSetFunctions) Merge(ar1 hostarch.AddrRange, pma1 pma, ar2 hostarch.Addr
 		pma1.maxPerms != pma2.maxPerms ||
 		pma1.needCOW != pma2.needCOW ||
 		pma1.private != pma2.private ||
-		pma1.huge != pma2.huge {
+		pma1.huge != pma2.huge ||
+		pma1.uffdWP != pma2.uffdWP {
 		return pma{}, false
 	}
 
@@ -125,3 +125,38 @@ func (m *SpecialMappable) FileRange() memmap.FileRange {
 func (m *SpecialMappable) Length() uint64 {
 	return m.fr.Length()
 }
+
+// ReplaceSpecialMappable replaces all mappings of old in mm by mappings of
+// new, at the same addresses. This is used to switch the VDSO parameter page
+// of an address space whose tasks enter a different time namespace.
+//
+// Preconditions: old.Length() == new.Length().
+func (mm *MemoryManager) ReplaceSpecialMappable(ctx context.Context, old, new *SpecialMappable) {
+	var droppedIDs []memmap.MappingIdentity
+	// This must run after mm.mappingMu.Unlock().
+	defer func() {
+		for _, id := range droppedIDs {
+			id.DecRef(ctx)
+		}
+	}()
+
+	mm.mappingMu.Lock()
+	defer mm.mappingMu.Unlock()
+	for vseg := mm.vmas.FirstSegment(); vseg.Ok(); vseg = vseg.NextSegment() {
+		vma := vseg.ValuePtr()
+		if vma.mappable != old {
+			continue
+		}
+		// Remove existing translations of old, so that the next access
+		// faults in new.
+		mm.activeMu.Lock()
+		mm.invalidateLocked(vseg.Range(), true /* invalidatePrivate */, true /* invalidateShared */)
+		mm.activeMu.Unlock()
+		vma.mappable = new
+		if vma.id == old {
+			new.IncRef()
+			droppedIDs = append(droppedIDs, vma.id)
+			vma.id = new
+		}
+	}
+}
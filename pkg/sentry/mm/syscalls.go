@@ -58,6 +58,14 @@ func (mm *MemoryManager) HandleUserFault(ctx context.Context, addr hostarch.Addr
 	mm.mappingMu.RUnlock()
 	if err != nil {
 		mm.activeMu.Unlock()
+		if uf, ok := err.(*userfault); ok {
+			// Once the userfaultfd resolves the fault, or if we are
+			// interrupted by a signal, the application retries the access.
+			if err := uf.wait(ctx, false /* kernel */); err != nil && err != linuxerr.ErrInterrupted {
+				return err
+			}
+			return nil
+		}
 		return err
 	}
 
@@ -716,7 +724,7 @@ func (mm *MemoryManager) MProtect(addr hostarch.Addr, length uint64, realPerms h
 					didUnmapAS = true
 				}
 				pma.effectivePerms = effectivePerms.Intersect(pma.translatePerms)
-				if pma.needCOW {
+				if pma.needCOW || pma.uffdWP {
 					pma.effectivePerms.Write = false
 				}
 			}
@@ -0,0 +1,296 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mm
+
+import (
+	"fmt"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/safemem"
+	"gvisor.dev/gvisor/pkg/sentry/pgalloc"
+	"gvisor.dev/gvisor/pkg/sentry/usage"
+)
+
+// UserfaultHandler handles faults in address ranges registered with a
+// userfaultfd(2).
+//
+// Only private anonymous mappings may be registered with a UserfaultHandler.
+// In ranges registered for missing faults (UFFDIO_REGISTER_MODE_MISSING),
+// pages without a pma are not allocated on fault; instead, the handler must
+// install them with MemoryManager.FillUserfaultfd. In ranges registered for
+// write-protect faults (UFFDIO_REGISTER_MODE_WP), writes to pages
+// write-protected by MemoryManager.WriteProtectUserfaultfd are reported to
+// the handler.
+type UserfaultHandler interface {
+	// HandleUserfault blocks until the fault at page-aligned address addr is
+	// resolved, or until ctx is interrupted. flags is a combination of
+	// linux.UFFD_PAGEFAULT_FLAG_*. kernel is true if the fault occurred while
+	// the sentry was accessing application memory, rather than in the
+	// application itself.
+	//
+	// If HandleUserfault returns nil, the faulting access is retried.
+	HandleUserfault(ctx context.Context, addr hostarch.Addr, flags uint64, kernel bool) error
+}
+
+// userfault is the error returned by getPMAsLocked when it encounters a fault
+// that must be handled by a UserfaultHandler. Callers must unlock mm before
+// calling userfault.wait.
+type userfault struct {
+	handler UserfaultHandler
+	addr    hostarch.Addr
+	flags   uint64
+}
+
+// Error implements error.Error.
+func (uf *userfault) Error() string {
+	return fmt.Sprintf("userfault at %#x (flags %#x)", uf.addr, uf.flags)
+}
+
+// wait blocks until uf is resolved.
+func (uf *userfault) wait(ctx context.Context, kernel bool) error {
+	return uf.handler.HandleUserfault(ctx, uf.addr, uf.flags, kernel)
+}
+
+// newUserfault returns the error returned by getPMAsLocked for an access of
+// type at to addr in vma, which must be handled by vma.uffd.
+func newUserfault(vma *vma, addr hostarch.Addr, at hostarch.AccessType, flags uint64) error {
+	if !at.Any() {
+		// Populating pages (e.g. for MAP_POPULATE or mlock) is best-effort and
+		// doesn't block on the userfaultfd.
+		return linuxerr.EFAULT
+	}
+	if at.Write {
+		flags |= linux.UFFD_PAGEFAULT_FLAG_WRITE
+	}
+	return &userfault{
+		handler: vma.uffd,
+		addr:    addr,
+		flags:   flags,
+	}
+}
+
+// RegisterUserfaultfd registers the vmas in ar with the userfaultfd handler
+// h, such that the faults in mode (a combination of
+// linux.UFFDIO_REGISTER_MODE_*) are reported to h.
+//
+// Preconditions: ar must be page-aligned.
+func (mm *MemoryManager) RegisterUserfaultfd(ar hostarch.AddrRange, h UserfaultHandler, mode uint64) error {
+	mm.mappingMu.Lock()
+	defer mm.mappingMu.Unlock()
+
+	// Check all vmas before changing any, like Linux.
+	vseg, err := mm.checkUserfaultfdVMAsLocked(ar)
+	if err != nil {
+		return err
+	}
+	for seg := vseg; seg.Ok() && seg.Start() < ar.End; seg = seg.NextSegment() {
+		vma := seg.ValuePtr()
+		if !vma.maxPerms.Write {
+			return linuxerr.EPERM
+		}
+		if vma.uffd != nil && vma.uffd != h {
+			return linuxerr.EBUSY
+		}
+	}
+
+	for vseg.Ok() && vseg.Start() < ar.End {
+		vseg = mm.vmas.Isolate(vseg, ar)
+		vma := vseg.ValuePtr()
+		vma.uffd = h
+		vma.uffdMode = mode
+		vseg = vseg.NextSegment()
+	}
+	mm.vmas.MergeInsideRange(ar)
+	mm.vmas.MergeOutsideRange(ar)
+	return nil
+}
+
+// UnregisterUserfaultfd unregisters the vmas in ar from the userfaultfd
+// handler h.
+//
+// Preconditions: ar must be page-aligned.
+func (mm *MemoryManager) UnregisterUserfaultfd(ar hostarch.AddrRange, h UserfaultHandler) error {
+	mm.mappingMu.Lock()
+	defer mm.mappingMu.Unlock()
+
+	vseg, err := mm.checkUserfaultfdVMAsLocked(ar)
+	if err != nil {
+		return err
+	}
+	for vseg.Ok() && vseg.Start() < ar.End {
+		if vseg.ValuePtr().uffd == h {
+			vseg = mm.vmas.Isolate(vseg, ar)
+			vma := vseg.ValuePtr()
+			vma.uffd = nil
+			vma.uffdMode = 0
+		}
+		vseg = vseg.NextSegment()
+	}
+	mm.vmas.MergeInsideRange(ar)
+	mm.vmas.MergeOutsideRange(ar)
+	return nil
+}
+
+// ReleaseUserfaultfd unregisters all vmas registered with the userfaultfd
+// handler h. It is called when the userfaultfd is closed.
+func (mm *MemoryManager) ReleaseUserfaultfd(h UserfaultHandler) {
+	mm.mappingMu.Lock()
+	defer mm.mappingMu.Unlock()
+	for vseg := mm.vmas.FirstSegment(); vseg.Ok(); vseg = vseg.NextSegment() {
+		if vma := vseg.ValuePtr(); vma.uffd == h {
+			vma.uffd = nil
+			vma.uffdMode = 0
+		}
+	}
+	mm.vmas.MergeAll()
+}
+
+// checkUserfaultfdVMAsLocked returns an iterator to the first vma in ar, after
+// checking that all vmas in ar may be registered with a userfaultfd.
+//
+// Preconditions: mm.mappingMu must be locked.
+func (mm *MemoryManager) checkUserfaultfdVMAsLocked(ar hostarch.AddrRange) (vmaIterator, error) {
+	vseg := mm.vmas.LowerBoundSegment(ar.Start)
+	if !vseg.Ok() || vseg.Start() >= ar.End {
+		return vmaIterator{}, linuxerr.EINVAL
+	}
+	for seg := vseg; seg.Ok() && seg.Start() < ar.End; seg = seg.NextSegment() {
+		// Only private anonymous mappings are supported.
+		if seg.ValuePtr().mappable != nil {
+			return vmaIterator{}, linuxerr.EINVAL
+		}
+	}
+	return vseg, nil
+}
+
+// FillUserfaultfd installs pages in ar, which must be in a single vma
+// registered with the userfaultfd handler h and must not already be
+// populated. If src is nil, the pages are zero-filled; otherwise, they are
+// filled with the contents of src, which must be ar.Length() bytes long. If wp
+// is true, the installed pages are write-protected. FillUserfaultfd returns
+// the number of bytes installed; if this is less than ar.Length(), it also
+// returns a non-nil error explaining why.
+//
+// Preconditions: ar must be page-aligned and non-empty.
+func (mm *MemoryManager) FillUserfaultfd(ctx context.Context, h UserfaultHandler, ar hostarch.AddrRange, src []byte, wp bool) (uint64, error) {
+	mm.mappingMu.RLock()
+	defer mm.mappingMu.RUnlock()
+	vseg := mm.vmas.FindSegment(ar.Start)
+	if !vseg.Ok() || vseg.End() < ar.End {
+		return 0, linuxerr.ENOENT
+	}
+	vma := vseg.ValuePtr()
+	if vma.uffd != h {
+		return 0, linuxerr.ENOENT
+	}
+	if wp && vma.uffdMode&linux.UFFDIO_REGISTER_MODE_WP == 0 {
+		return 0, linuxerr.EINVAL
+	}
+
+	mm.activeMu.Lock()
+	defer mm.activeMu.Unlock()
+	memCgID := pgalloc.MemoryCgroupIDFromContext(ctx)
+	var done uint64
+	for addr := ar.Start; addr < ar.End; {
+		pseg, pgap := mm.pmas.Find(addr)
+		if pseg.Ok() {
+			return done, linuxerr.EEXIST
+		}
+		fillAR := pgap.Range().Intersect(hostarch.AddrRange{addr, ar.End})
+		opts := pgalloc.AllocOpts{
+			Kind:    usage.Anonymous,
+			MemCgID: memCgID,
+			Mode:    pgalloc.AllocateUncommitted,
+			Dir:     mm.getAllocationDirection(fillAR, vma),
+		}
+		if src != nil {
+			off := uint64(addr - ar.Start)
+			reader := safemem.BlockSeqReader{Blocks: safemem.BlockSeqOf(safemem.BlockFromSafeSlice(src[off : off+uint64(fillAR.Length())]))}
+			opts.Mode = pgalloc.AllocateAndWritePopulate
+			opts.ReaderFunc = reader.ReadToBlocks
+		}
+		fr, err := mm.mf.Allocate(uint64(fillAR.Length()), opts)
+		if fr.Length() == 0 {
+			return done, err
+		}
+		fillAR.End = fillAR.Start + hostarch.Addr(fr.Length())
+		newpma := pma{
+			file:           mm.mf,
+			off:            fr.Start,
+			translatePerms: hostarch.AnyAccess,
+			effectivePerms: vma.effectivePerms,
+			maxPerms:       vma.maxPerms,
+			private:        true,
+			uffdWP:         wp,
+		}
+		if wp {
+			newpma.effectivePerms.Write = false
+		}
+		mm.addRSSLocked(fillAR)
+		mm.pmas.Insert(pgap, fillAR, newpma)
+		done += uint64(fillAR.Length())
+		if err != nil {
+			return done, err
+		}
+		addr = fillAR.End
+	}
+	mm.pmas.MergeOutsideRange(ar)
+	return done, nil
+}
+
+// WriteProtectUserfaultfd write-protects the populated pages in ar if wp is
+// true, and removes their write protection otherwise. All vmas in ar must be
+// registered with the userfaultfd handler h for write-protect faults.
+//
+// Preconditions: ar must be page-aligned.
+func (mm *MemoryManager) WriteProtectUserfaultfd(h UserfaultHandler, ar hostarch.AddrRange, wp bool) error {
+	mm.mappingMu.RLock()
+	defer mm.mappingMu.RUnlock()
+	vseg := mm.vmas.LowerBoundSegment(ar.Start)
+	if !vseg.Ok() || vseg.Start() >= ar.End {
+		return linuxerr.ENOENT
+	}
+	for seg := vseg; seg.Ok() && seg.Start() < ar.End; seg = seg.NextSegment() {
+		if vma := seg.ValuePtr(); vma.uffd != h || vma.uffdMode&linux.UFFDIO_REGISTER_MODE_WP == 0 {
+			return linuxerr.ENOENT
+		}
+	}
+
+	mm.activeMu.Lock()
+	defer mm.activeMu.Unlock()
+	for ; vseg.Ok() && vseg.Start() < ar.End; vseg = vseg.NextSegment() {
+		vma := vseg.ValuePtr()
+		vsegAR := vseg.Range().Intersect(ar)
+		for pseg := mm.pmas.LowerBoundSegment(vsegAR.Start); pseg.Ok() && pseg.Start() < vsegAR.End; pseg = pseg.NextSegment() {
+			pseg = mm.pmas.Isolate(pseg, vsegAR)
+			pma := pseg.ValuePtr()
+			pma.uffdWP = wp
+			pma.effectivePerms = vma.effectivePerms.Intersect(pma.translatePerms)
+			if pma.needCOW || pma.uffdWP {
+				pma.effectivePerms.Write = false
+			}
+		}
+	}
+	if wp {
+		// Remove writable AddressSpace mappings of the write-protected pages.
+		mm.unmapASLocked(ar)
+	}
+	mm.pmas.MergeInsideRange(ar)
+	mm.pmas.MergeOutsideRange(ar)
+	return nil
+}
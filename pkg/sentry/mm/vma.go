@@ -474,7 +474,9 @@ func (vmaSetFunctions) Merge(ar1 hostarch.AddrRange, vma1 vma, ar2 hostarch.Addr
 		vma1.dontfork != vma2.dontfork ||
 		vma1.id != vma2.id ||
 		vma1.name != vma2.name ||
-		vma1.nameMut != vma2.nameMut {
+		vma1.nameMut != vma2.nameMut ||
+		vma1.uffd != vma2.uffd ||
+		vma1.uffdMode != vma2.uffdMode {
 		return vma{}, false
 	}
 
@@ -175,6 +175,12 @@ type MemoryFile struct {
 	// opts holds options passed to NewMemoryFile. opts is immutable.
 	opts MemoryFileOpts
 
+	// If memCgCharger is not nil, allocations with a non-zero
+	// AllocOpts.MemCgID are charged to it. memCgCharger is set by
+	// SetMemoryCgroupCharger before any such allocations and is immutable
+	// thereafter.
+	memCgCharger MemoryCgroupCharger
+
 	// savable is true if this MemoryFile will be saved via SaveTo() during
 	// the kernel's SaveTo operation. savable is protected by mu.
 	savable bool
@@ -277,6 +283,10 @@ type memAcctInfo struct {
 	// memCgID is the memory cgroup ID to which represented pages are accounted.
 	memCgID uint32
 
+	// If charged is true, represented pages were charged to memCgID through
+	// MemoryFile.memCgCharger and must be uncharged when they are freed.
+	charged bool
+
 	// knownCommitted is true if represented pages are definitely committed.
 	// (If knownCommitted is false, represented pages may or may not be
 	// committed; pages that are definitely not committed are represented by
@@ -628,6 +638,27 @@ type allocState struct {
 	willCommit bool // either us or our caller
 	recycled   bool
 	huge       bool
+	charged    bool
+}
+
+// MemoryCgroupCharger enforces memory cgroup limits on allocations.
+type MemoryCgroupCharger interface {
+	// TryCharge charges length bytes to the memory cgroup with ID memCgID. If
+	// doing so would exceed the cgroup's limit, TryCharge returns a non-nil
+	// error and charges nothing.
+	TryCharge(memCgID uint32, length uint64) error
+
+	// Uncharge reverses a previous successful call to TryCharge.
+	Uncharge(memCgID uint32, length uint64)
+}
+
+// SetMemoryCgroupCharger sets the MemoryCgroupCharger used to enforce memory
+// cgroup limits on allocations from f.
+//
+// Preconditions: No allocations with a non-zero AllocOpts.MemCgID may be in
+// progress.
+func (f *MemoryFile) SetMemoryCgroupCharger(c MemoryCgroupCharger) {
+	f.memCgCharger = c
 }
 
 // Allocate returns a range of initially-zeroed pages of the given length, with
@@ -651,8 +682,18 @@ func (f *MemoryFile) Allocate(length uint64, opts AllocOpts) (memmap.FileRange,
 		huge:       opts.Huge && f.opts.ExpectHugepages,
 	}
 
+	if opts.MemCgID != 0 && f.memCgCharger != nil {
+		if err := f.memCgCharger.TryCharge(opts.MemCgID, length); err != nil {
+			return memmap.FileRange{}, err
+		}
+		alloc.charged = true
+	}
+
 	fr, err := f.findAllocatableAndMarkUsed(&alloc)
 	if err != nil {
+		if alloc.charged {
+			f.memCgCharger.Uncharge(opts.MemCgID, length)
+		}
 		return fr, err
 	}
 
@@ -827,6 +868,7 @@ func (f *MemoryFile) findAllocatableAndMarkUsed(alloc *allocState) (fr memmap.Fi
 				}
 				ma.kind = alloc.opts.Kind
 				ma.memCgID = alloc.opts.MemCgID
+				ma.charged = alloc.charged
 				ma.wasteOrReleasing = false
 				return true
 			})
@@ -875,6 +917,7 @@ retryFree:
 	f.memAcct.InsertRange(fr, memAcctInfo{
 		kind:           alloc.opts.Kind,
 		memCgID:        alloc.opts.MemCgID,
+		charged:        alloc.charged,
 		knownCommitted: false,
 		commitSeq:      f.commitSeq,
 	})
@@ -1199,9 +1242,14 @@ func (f *MemoryFile) DecRef(fr memmap.FileRange) {
 		panic(fmt.Sprintf("invalid range: %v", fr))
 	}
 
-	f.mu.Lock()
-	defer f.mu.Unlock()
+	// uncharges accumulates charges to be reversed after f.mu is unlocked.
+	type uncharge struct {
+		memCgID uint32
+		length  uint64
+	}
+	var uncharges []uncharge
 
+	f.mu.Lock()
 	haveWaste := false
 	f.forEachChunk(fr, func(chunk *chunkInfo, chunkFR memmap.FileRange) bool {
 		unwaste := &f.unwasteSmall
@@ -1228,6 +1276,10 @@ func (f *MemoryFile) DecRef(fr memmap.FileRange) {
 					if !f.opts.DisableMemoryAccounting && ma.knownCommitted {
 						usage.MemoryAccounting.Move(maseg.Range().Length(), usage.System, ma.kind, ma.memCgID)
 					}
+					if ma.charged {
+						uncharges = append(uncharges, uncharge{ma.memCgID, maseg.Range().Length()})
+						ma.charged = false
+					}
 					ma.kind = usage.System
 					ma.wasteOrReleasing = true
 					return true
@@ -1248,6 +1300,11 @@ func (f *MemoryFile) DecRef(fr memmap.FileRange) {
 		f.haveWaste = true
 		f.releaseCond.Signal()
 	}
+	f.mu.Unlock()
+
+	for _, u := range uncharges {
+		f.memCgCharger.Uncharge(u.memCgID, u.length)
+	}
 }
 
 // releaserMain implements the releaser goroutine.
@@ -207,6 +207,24 @@ func PackTimestamp(t *kernel.Task, timestamp time.Time, buf []byte) []byte {
 	)
 }
 
+// PackTimestamping packs a SCM_TIMESTAMPING or SCM_TIMESTAMPING_NEW socket
+// control message holding a software timestamp.
+func PackTimestamping(t *kernel.Task, timestamp time.Time, isNew bool, buf []byte) []byte {
+	var ts linux.ScmTimestamping
+	ts.TS[0] = linux.NsecToTimespec(timestamp.UnixNano())
+	typ := uint32(linux.SCM_TIMESTAMPING)
+	if isNew {
+		typ = linux.SCM_TIMESTAMPING_NEW
+	}
+	return putCmsgStruct(
+		buf,
+		linux.SOL_SOCKET,
+		typ,
+		t.Arch().Width(),
+		&ts,
+	)
+}
+
 // PackInq packs a TCP_INQ socket control message.
 func PackInq(t *kernel.Task, inq int32, buf []byte) []byte {
 	return putCmsgStruct(
@@ -324,6 +342,10 @@ func PackControlMessages(t *kernel.Task, cmsgs socket.ControlMessages, buf []byt
 		buf = PackTimestamp(t, cmsgs.IP.Timestamp, buf)
 	}
 
+	if cmsgs.IP.HasTimestamping {
+		buf = PackTimestamping(t, cmsgs.IP.Timestamping, cmsgs.IP.TimestampingNew, buf)
+	}
+
 	if cmsgs.IP.HasInq {
 		// In Linux, TCP_CM_INQ is added after SO_TIMESTAMP.
 		buf = PackInq(t, cmsgs.IP.Inq, buf)
@@ -378,6 +400,10 @@ func CmsgsSpace(t *kernel.Task, cmsgs socket.ControlMessages) int {
 		space += cmsgSpace(t, linux.SizeOfTimeval)
 	}
 
+	if cmsgs.IP.HasTimestamping {
+		space += cmsgSpace(t, linux.SizeOfScmTimestamping)
+	}
+
 	if cmsgs.IP.HasInq {
 		space += cmsgSpace(t, linux.SizeOfControlMessageInq)
 	}
@@ -482,6 +508,15 @@ func Parse(t *kernel.Task, socketOrEndpoint any, buf []byte, width uint) (socket
 				cmsgs.IP.Timestamp = ts.ToTime()
 				cmsgs.IP.HasTimestamp = true
 
+			case linux.SCM_TXTIME:
+				if length != linux.SizeOfControlMessageTXTime {
+					return socket.ControlMessages{}, linuxerr.EINVAL
+				}
+				var txTime primitive.Uint64
+				txTime.UnmarshalUnsafe(buf)
+				cmsgs.IP.TXTime = uint64(txTime)
+				cmsgs.IP.HasTXTime = true
+
 			default:
 				// Unknown message type.
 				return socket.ControlMessages{}, linuxerr.EINVAL
@@ -687,6 +687,10 @@ func (s *Socket) SendMsg(t *kernel.Task, src usermem.IOSequence, to []byte, flag
 		return 0, syserr.ErrPermissionDenied
 	}
 
+	// MSG_ZEROCOPY is ignored, like in Linux for sockets without SO_ZEROCOPY,
+	// since application memory can't be lent to the host.
+	flags &^= unix.MSG_ZEROCOPY
+
 	// Only allow known and safe flags.
 	if flags&^allowedSendMsgFlags != 0 {
 		return 0, syserr.ErrInvalidArgument
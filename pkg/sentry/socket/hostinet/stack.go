@@ -54,6 +54,10 @@ type Stack struct {
 	tcpRecvBufSize inet.TCPBufferSize
 	tcpSendBufSize inet.TCPBufferSize
 	tcpSACKEnabled bool
+	tcpFastOpen    int32
+	tcpCC          string
+	tcpAvailCC     string
+	tcpECN         int32
 	netDevFile     *os.File
 	netSNMPFile    *os.File
 	// allowedSocketTypes is the list of allowed socket types
@@ -98,6 +102,39 @@ func (s *Stack) Configure(allowRawSockets bool) error {
 		log.Warningf("Failed to read if TCP SACK if enabled, setting to true")
 	}
 
+	// Client support is the default for TCP Fast Open.
+	s.tcpFastOpen = 1
+	if tfo, err := os.ReadFile("/proc/sys/net/ipv4/tcp_fastopen"); err == nil {
+		if v, err := strconv.ParseInt(strings.TrimSpace(string(tfo)), 10, 32); err == nil {
+			s.tcpFastOpen = int32(v)
+		}
+	} else {
+		log.Warningf("Failed to read TCP Fast Open mode, setting to 1")
+	}
+
+	s.tcpCC = "reno"
+	if cc, err := os.ReadFile("/proc/sys/net/ipv4/tcp_congestion_control"); err == nil {
+		s.tcpCC = strings.TrimSpace(string(cc))
+	} else {
+		log.Warningf("Failed to read TCP congestion control, setting to reno")
+	}
+	s.tcpAvailCC = s.tcpCC
+	if cc, err := os.ReadFile("/proc/sys/net/ipv4/tcp_available_congestion_control"); err == nil {
+		s.tcpAvailCC = strings.TrimSpace(string(cc))
+	} else {
+		log.Warningf("Failed to read available TCP congestion control algorithms, setting to %s", s.tcpCC)
+	}
+
+	// ECN is only accepted on incoming connections by default.
+	s.tcpECN = 2
+	if ecn, err := os.ReadFile("/proc/sys/net/ipv4/tcp_ecn"); err == nil {
+		if v, err := strconv.ParseInt(strings.TrimSpace(string(ecn)), 10, 32); err == nil {
+			s.tcpECN = int32(v)
+		}
+	} else {
+		log.Warningf("Failed to read TCP ECN mode, setting to 2")
+	}
+
 	if f, err := os.Open("/proc/net/dev"); err != nil {
 		log.Warningf("Failed to open /proc/net/dev: %v", err)
 	} else {
@@ -279,6 +316,42 @@ func (*Stack) SetTCPRecovery(inet.TCPLossRecovery) error {
 	return linuxerr.EACCES
 }
 
+// TCPFastOpen implements inet.Stack.TCPFastOpen.
+func (s *Stack) TCPFastOpen() (int32, error) {
+	return s.tcpFastOpen, nil
+}
+
+// SetTCPFastOpen implements inet.Stack.SetTCPFastOpen.
+func (*Stack) SetTCPFastOpen(int32) error {
+	return linuxerr.EACCES
+}
+
+// TCPCongestionControl implements inet.Stack.TCPCongestionControl.
+func (s *Stack) TCPCongestionControl() (string, error) {
+	return s.tcpCC, nil
+}
+
+// SetTCPCongestionControl implements inet.Stack.SetTCPCongestionControl.
+func (*Stack) SetTCPCongestionControl(string) error {
+	return linuxerr.EACCES
+}
+
+// TCPAvailableCongestionControl implements
+// inet.Stack.TCPAvailableCongestionControl.
+func (s *Stack) TCPAvailableCongestionControl() (string, error) {
+	return s.tcpAvailCC, nil
+}
+
+// TCPECN implements inet.Stack.TCPECN.
+func (s *Stack) TCPECN() (int32, error) {
+	return s.tcpECN, nil
+}
+
+// SetTCPECN implements inet.Stack.SetTCPECN.
+func (*Stack) SetTCPECN(int32) error {
+	return linuxerr.EACCES
+}
+
 // getLine reads one line from proc file, with specified prefix.
 // The last argument, withHeader, specifies if it contains line header.
 func getLine(f *os.File, prefix string, withHeader bool) string {
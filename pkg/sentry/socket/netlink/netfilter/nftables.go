@@ -0,0 +1,989 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netfilter
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/marshal/primitive"
+	"gvisor.dev/gvisor/pkg/sentry/socket/netlink/nlmsg"
+	"gvisor.dev/gvisor/pkg/syserr"
+	"gvisor.dev/gvisor/pkg/tcpip/nftables"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+)
+
+//
+// Tables
+//
+
+// newTable creates a new table for the given family.
+func (p *Protocol) newTable(nft *nftables.NFTables, attrs map[uint16]nlmsg.BytesView, family stack.AddressFamily, flags uint16) *syserr.AnnotatedError {
+	// The table name is required.
+	tabName, err := parseName(attrs, linux.NFTA_TABLE_NAME, "Table")
+	if err != nil {
+		return err
+	}
+
+	var dormant bool
+	fbytes, hasFlags := attrs[linux.NFTA_TABLE_FLAGS]
+	if hasFlags {
+		tflags, ok := fbytes.Uint32BE()
+		if !ok {
+			return syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("Nftables: Table flags attribute is malformed"))
+		}
+		if tflags&^linux.NFT_TABLE_F_DORMANT != 0 {
+			return syserr.NewAnnotatedError(syserr.ErrNotSupported, fmt.Sprintf("Nftables: Table flags %#x are not supported", tflags))
+		}
+		dormant = (tflags & linux.NFT_TABLE_F_DORMANT) == linux.NFT_TABLE_F_DORMANT
+	}
+
+	userData, hasUserData, err := parseUserData(attrs, linux.NFTA_TABLE_USERDATA)
+	if err != nil {
+		return err
+	}
+
+	tab, err := nft.GetTable(family, tabName)
+	if err != nil && err.GetError() != syserr.ErrNoFileOrDir {
+		return err
+	}
+
+	// If a table already exists, only update its dormant flags if NLM_F_EXCL and NLM_F_REPLACE
+	// are not set. From net/netfilter/nf_tables_api.c:nf_tables_newtable:nf_tables_updtable
+	if tab != nil {
+		if flags&linux.NLM_F_EXCL == linux.NLM_F_EXCL {
+			return syserr.NewAnnotatedError(syserr.ErrExists, fmt.Sprintf("Nftables: Table with name: %s already exists", tabName))
+		}
+
+		if flags&linux.NLM_F_REPLACE == linux.NLM_F_REPLACE {
+			return syserr.NewAnnotatedError(syserr.ErrNotSupported, fmt.Sprintf("Nftables: Table with name: %s already exists and NLM_F_REPLACE is not supported", tabName))
+		}
+
+		if hasFlags {
+			tab.SetDormant(dormant)
+		}
+		return nil
+	}
+
+	tab, err = nft.CreateTable(family, tabName)
+	if err != nil {
+		return err
+	}
+	tab.SetDormant(dormant)
+	if hasUserData {
+		tab.SetUserData(userData)
+	}
+	return nil
+}
+
+// delTable deletes the table given by name or handle. If neither is given, all
+// tables of the family are deleted, or all tables if the family is
+// NFPROTO_UNSPEC.
+func (p *Protocol) delTable(nft *nftables.NFTables, attrs map[uint16]nlmsg.BytesView, nfproto uint8) *syserr.AnnotatedError {
+	// From net/netfilter/nf_tables_api.c:nf_tables_deltable.
+	tabNameBytes, hasName := attrs[linux.NFTA_TABLE_NAME]
+	handleBytes, hasHandle := attrs[linux.NFTA_TABLE_HANDLE]
+	if !hasName && !hasHandle {
+		families, err := addressFamilies(nfproto)
+		if err != nil {
+			return err
+		}
+		for _, family := range families {
+			for _, tab := range nft.GetTables(family) {
+				if _, err := nft.DeleteTable(family, tab.GetName()); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	family, err := nftables.AddressFamilyFromNetlink(nfproto)
+	if err != nil {
+		return err
+	}
+
+	var tab *nftables.Table
+	if hasHandle {
+		handle, ok := handleBytes.Uint64BE()
+		if !ok {
+			return syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("Nftables: Table handle attribute is malformed"))
+		}
+		tab, err = nft.GetTableByHandle(family, handle)
+	} else {
+		tab, err = nft.GetTable(family, tabNameBytes.String())
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = nft.DeleteTable(family, tab.GetName())
+	return err
+}
+
+// getTable replies with the table of the given family and name.
+func (p *Protocol) getTable(nft *nftables.NFTables, attrs map[uint16]nlmsg.BytesView, family stack.AddressFamily, ms *nlmsg.MessageSet) *syserr.AnnotatedError {
+	// The table name is required.
+	tabNameBytes, ok := attrs[linux.NFTA_TABLE_NAME]
+	if !ok {
+		return syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("Nftables: Table name attribute is malformed or not found"))
+	}
+
+	tab, err := nft.GetTable(family, tabNameBytes.String())
+	if err != nil {
+		return err
+	}
+
+	fillTableInfo(nft, ms, tab)
+	return nil
+}
+
+// dumpTables replies with all tables of the given family.
+func (p *Protocol) dumpTables(nft *nftables.NFTables, attrs map[uint16]nlmsg.BytesView, nfproto uint8, ms *nlmsg.MessageSet) *syserr.AnnotatedError {
+	tables, err := tablesToDump(nft, attrs, nfproto, 0)
+	if err != nil {
+		return err
+	}
+	for _, tab := range tables {
+		fillTableInfo(nft, ms, tab)
+	}
+	return nil
+}
+
+// fillTableInfo adds a message describing the table to the message set.
+func fillTableInfo(nft *nftables.NFTables, ms *nlmsg.MessageSet, tab *nftables.Table) {
+	// From net/netfilter/nf_tables_api.c:nf_tables_fill_table_info.
+	m := newReply(nft, ms, linux.NFT_MSG_NEWTABLE, nftables.AddressFamilyToNetlink(tab.GetAddressFamily()))
+	m.PutAttrString(linux.NFTA_TABLE_NAME, tab.GetName())
+	var tflags uint32
+	if tab.IsDormant() {
+		tflags |= linux.NFT_TABLE_F_DORMANT
+	}
+	m.PutAttrUint32BE(linux.NFTA_TABLE_FLAGS, tflags)
+	m.PutAttrUint32BE(linux.NFTA_TABLE_USE, uint32(tab.ChainCount()))
+	m.PutAttrUint64BE(linux.NFTA_TABLE_HANDLE, tab.GetHandle())
+	if userData := tab.GetUserData(); len(userData) > 0 {
+		m.PutAttr(linux.NFTA_TABLE_USERDATA, primitive.AsByteSlice(userData))
+	}
+}
+
+//
+// Chains
+//
+
+// newChain creates a new chain, or updates the policy of an existing base
+// chain.
+func (p *Protocol) newChain(nft *nftables.NFTables, attrs map[uint16]nlmsg.BytesView, family stack.AddressFamily, flags uint16) *syserr.AnnotatedError {
+	// From net/netfilter/nf_tables_api.c:nf_tables_newchain.
+	tab, err := getTableByName(nft, attrs, family, linux.NFTA_CHAIN_TABLE)
+	if err != nil {
+		return err
+	}
+
+	var chain *nftables.Chain
+	var chainName string
+	if handleBytes, ok := attrs[linux.NFTA_CHAIN_HANDLE]; ok {
+		handle, ok := handleBytes.Uint64BE()
+		if !ok {
+			return syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("Nftables: Chain handle attribute is malformed"))
+		}
+		if chain, err = tab.GetChainByHandle(handle); err != nil {
+			return err
+		}
+	} else {
+		if chainName, err = parseName(attrs, linux.NFTA_CHAIN_NAME, "Chain"); err != nil {
+			return err
+		}
+		chain, _ = tab.GetChain(chainName)
+	}
+
+	if cflagsBytes, ok := attrs[linux.NFTA_CHAIN_FLAGS]; ok {
+		cflags, ok := cflagsBytes.Uint32BE()
+		if !ok {
+			return syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("Nftables: Chain flags attribute is malformed"))
+		}
+		if cflags&^linux.NFT_CHAIN_BASE != 0 {
+			return syserr.NewAnnotatedError(syserr.ErrNotSupported, fmt.Sprintf("Nftables: Chain flags %#x are not supported", cflags))
+		}
+	}
+	if _, ok := attrs[linux.NFTA_CHAIN_COUNTERS]; ok {
+		return syserr.NewAnnotatedError(syserr.ErrNotSupported, fmt.Sprintf("Nftables: Chain counters are not supported"))
+	}
+
+	policyDrop, hasPolicy, err := parsePolicy(attrs)
+	if err != nil {
+		return err
+	}
+	info, err := parseBaseChainInfo(attrs, family, policyDrop)
+	if err != nil {
+		return err
+	}
+	if hasPolicy && info == nil && (chain == nil || !chain.IsBaseChain()) {
+		return syserr.NewAnnotatedError(syserr.ErrNotSupported, fmt.Sprintf("Nftables: Policy can only be set for base chains"))
+	}
+
+	userData, hasUserData, err := parseUserData(attrs, linux.NFTA_CHAIN_USERDATA)
+	if err != nil {
+		return err
+	}
+
+	if chain != nil {
+		if flags&linux.NLM_F_EXCL == linux.NLM_F_EXCL {
+			return syserr.NewAnnotatedError(syserr.ErrExists, fmt.Sprintf("Nftables: Chain with name: %s already exists", chain.GetName()))
+		}
+
+		if flags&linux.NLM_F_REPLACE == linux.NLM_F_REPLACE {
+			return syserr.NewAnnotatedError(syserr.ErrNotSupported, fmt.Sprintf("Nftables: Chain with name: %s already exists and NLM_F_REPLACE is not supported", chain.GetName()))
+		}
+
+		// Only the policy of an existing base chain can be changed. From
+		// net/netfilter/nf_tables_api.c:nf_tables_updchain.
+		if info != nil {
+			old := chain.GetBaseChainInfo()
+			if old == nil || old.Hook != info.Hook || old.Priority.GetValue() != info.Priority.GetValue() || old.Device != info.Device {
+				return syserr.NewAnnotatedError(syserr.ErrExists, fmt.Sprintf("Nftables: Hook of chain %s cannot be changed", chain.GetName()))
+			}
+		}
+		if hasPolicy {
+			// The base chain info of the working copy of a transaction is not
+			// shared with the committed ruleset.
+			chain.GetBaseChainInfo().PolicyDrop = policyDrop
+		}
+		if hasUserData {
+			chain.SetUserData(userData)
+		}
+		return nil
+	}
+
+	if chainName == "" {
+		return syserr.NewAnnotatedError(syserr.ErrNoFileOrDir, fmt.Sprintf("Nftables: Chain to update was not found"))
+	}
+	chain, err = tab.AddChain(chainName, info, "", true)
+	if err != nil {
+		return err
+	}
+	if hasUserData {
+		chain.SetUserData(userData)
+	}
+	return nil
+}
+
+// delChain deletes the chain given by name or handle along with its rules.
+func (p *Protocol) delChain(nft *nftables.NFTables, attrs map[uint16]nlmsg.BytesView, family stack.AddressFamily, flags uint16) *syserr.AnnotatedError {
+	// From net/netfilter/nf_tables_api.c:nf_tables_delchain.
+	tab, err := getTableByName(nft, attrs, family, linux.NFTA_CHAIN_TABLE)
+	if err != nil {
+		return err
+	}
+	chain, err := getChain(tab, attrs, linux.NFTA_CHAIN_NAME, linux.NFTA_CHAIN_HANDLE)
+	if err != nil {
+		return err
+	}
+
+	if use := chain.UseCount(); use > 0 {
+		return syserr.NewAnnotatedError(syserr.ErrBusy, fmt.Sprintf("Nftables: Chain %s is the target of %d jump(s) or goto(s)", chain.GetName(), use))
+	}
+	if flags&linux.NLM_F_NONREC == linux.NLM_F_NONREC && chain.RuleCount() > 0 {
+		return syserr.NewAnnotatedError(syserr.ErrBusy, fmt.Sprintf("Nftables: Chain %s is not empty", chain.GetName()))
+	}
+
+	tab.DeleteChain(chain.GetName())
+	return nil
+}
+
+// getChain replies with the chain of the given table and name.
+func (p *Protocol) getChain(nft *nftables.NFTables, attrs map[uint16]nlmsg.BytesView, family stack.AddressFamily, ms *nlmsg.MessageSet) *syserr.AnnotatedError {
+	tab, err := getTableByName(nft, attrs, family, linux.NFTA_CHAIN_TABLE)
+	if err != nil {
+		return err
+	}
+	chainName, err := parseName(attrs, linux.NFTA_CHAIN_NAME, "Chain")
+	if err != nil {
+		return err
+	}
+	chain, err := tab.GetChain(chainName)
+	if err != nil {
+		return err
+	}
+
+	fillChainInfo(nft, ms, chain)
+	return nil
+}
+
+// dumpChains replies with all chains of the given family, optionally only
+// those of a single table.
+func (p *Protocol) dumpChains(nft *nftables.NFTables, attrs map[uint16]nlmsg.BytesView, nfproto uint8, ms *nlmsg.MessageSet) *syserr.AnnotatedError {
+	tables, err := tablesToDump(nft, attrs, nfproto, linux.NFTA_CHAIN_TABLE)
+	if err != nil {
+		return err
+	}
+	for _, tab := range tables {
+		for _, chain := range tab.GetChains() {
+			fillChainInfo(nft, ms, chain)
+		}
+	}
+	return nil
+}
+
+// fillChainInfo adds a message describing the chain to the message set.
+func fillChainInfo(nft *nftables.NFTables, ms *nlmsg.MessageSet, chain *nftables.Chain) {
+	// From net/netfilter/nf_tables_api.c:nf_tables_fill_chain_info.
+	family := chain.GetAddressFamily()
+	m := newReply(nft, ms, linux.NFT_MSG_NEWCHAIN, nftables.AddressFamilyToNetlink(family))
+	m.PutAttrString(linux.NFTA_CHAIN_TABLE, chain.GetTable().GetName())
+	m.PutAttrUint64BE(linux.NFTA_CHAIN_HANDLE, chain.GetHandle())
+	m.PutAttrString(linux.NFTA_CHAIN_NAME, chain.GetName())
+
+	var cflags uint32
+	if info := chain.GetBaseChainInfo(); info != nil {
+		var hook nlmsg.NestedAttr
+		hook.PutAttrUint32BE(linux.NFTA_HOOK_HOOKNUM, nftables.HookToNetlink(family, info.Hook))
+		hook.PutAttrUint32BE(linux.NFTA_HOOK_PRIORITY, uint32(int32(info.Priority.GetValue())))
+		if info.Device != "" {
+			hook.PutAttrString(linux.NFTA_HOOK_DEV, info.Device)
+		}
+		m.PutNestedAttr(linux.NFTA_CHAIN_HOOK, hook)
+
+		policy := uint32(linux.NF_ACCEPT)
+		if info.PolicyDrop {
+			policy = linux.NF_DROP
+		}
+		m.PutAttrUint32BE(linux.NFTA_CHAIN_POLICY, policy)
+		m.PutAttrString(linux.NFTA_CHAIN_TYPE, info.BcType.String())
+		cflags |= linux.NFT_CHAIN_BASE
+	}
+
+	m.PutAttrUint32BE(linux.NFTA_CHAIN_USE, uint32(chain.UseCount()))
+	m.PutAttrUint32BE(linux.NFTA_CHAIN_FLAGS, cflags)
+	if userData := chain.GetUserData(); len(userData) > 0 {
+		m.PutAttr(linux.NFTA_CHAIN_USERDATA, primitive.AsByteSlice(userData))
+	}
+}
+
+// parsePolicy parses the optional policy of a base chain, returning whether the
+// policy is to drop packets and whether the policy was given.
+func parsePolicy(attrs map[uint16]nlmsg.BytesView) (bool, bool, *syserr.AnnotatedError) {
+	policyBytes, ok := attrs[linux.NFTA_CHAIN_POLICY]
+	if !ok {
+		return false, false, nil
+	}
+	policy, ok := policyBytes.Uint32BE()
+	if !ok {
+		return false, false, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("Nftables: Chain policy attribute is malformed"))
+	}
+	switch policy {
+	case linux.NF_ACCEPT:
+		return false, true, nil
+	case linux.NF_DROP:
+		return true, true, nil
+	default:
+		return false, false, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("Nftables: Invalid chain policy: %d", policy))
+	}
+}
+
+// parseBaseChainInfo parses the hook of a base chain, returning nil if the
+// chain is not a base chain.
+func parseBaseChainInfo(attrs map[uint16]nlmsg.BytesView, family stack.AddressFamily, policyDrop bool) (*nftables.BaseChainInfo, *syserr.AnnotatedError) {
+	// From net/netfilter/nf_tables_api.c:nft_chain_parse_hook.
+	hookBytes, ok := attrs[linux.NFTA_CHAIN_HOOK]
+	if !ok {
+		return nil, nil
+	}
+	hookAttrs, ok := nlmsg.AttrsView(hookBytes).Parse()
+	if !ok {
+		return nil, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("Nftables: Chain hook attribute is malformed"))
+	}
+
+	hooknumBytes, ok := hookAttrs[linux.NFTA_HOOK_HOOKNUM]
+	if !ok {
+		return nil, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("Nftables: Chain hook number attribute is malformed or not found"))
+	}
+	hooknum, ok := hooknumBytes.Uint32BE()
+	if !ok {
+		return nil, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("Nftables: Chain hook number attribute is malformed"))
+	}
+	hook, err := nftables.HookFromNetlink(family, hooknum)
+	if err != nil {
+		return nil, err
+	}
+
+	priorityBytes, ok := hookAttrs[linux.NFTA_HOOK_PRIORITY]
+	if !ok {
+		return nil, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("Nftables: Chain priority attribute is malformed or not found"))
+	}
+	priority, ok := priorityBytes.Uint32BE()
+	if !ok {
+		return nil, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("Nftables: Chain priority attribute is malformed"))
+	}
+
+	var device string
+	if devBytes, ok := hookAttrs[linux.NFTA_HOOK_DEV]; ok {
+		device = devBytes.String()
+	}
+	if _, ok := hookAttrs[linux.NFTA_HOOK_DEVS]; ok {
+		return nil, syserr.NewAnnotatedError(syserr.ErrNotSupported, fmt.Sprintf("Nftables: Chains for multiple devices are not supported"))
+	}
+
+	bcType := nftables.BaseChainTypeFilter
+	if typeBytes, ok := attrs[linux.NFTA_CHAIN_TYPE]; ok {
+		if bcType, err = nftables.BaseChainTypeFromString(typeBytes.String()); err != nil {
+			return nil, err
+		}
+	}
+
+	return nftables.NewBaseChainInfo(bcType, hook, nftables.NewIntPriority(int(int32(priority))), device, policyDrop), nil
+}
+
+//
+// Rules
+//
+
+// newRule adds a new rule to a chain, or replaces an existing rule.
+func (p *Protocol) newRule(nft *nftables.NFTables, attrs map[uint16]nlmsg.BytesView, family stack.AddressFamily, flags uint16) *syserr.AnnotatedError {
+	// From net/netfilter/nf_tables_api.c:nf_tables_newrule.
+	tab, err := getTableByName(nft, attrs, family, linux.NFTA_RULE_TABLE)
+	if err != nil {
+		return err
+	}
+	if _, ok := attrs[linux.NFTA_RULE_CHAIN]; !ok {
+		if _, ok := attrs[linux.NFTA_RULE_CHAIN_ID]; ok {
+			return syserr.NewAnnotatedError(syserr.ErrNotSupported, fmt.Sprintf("Nftables: Chain IDs are not supported"))
+		}
+	}
+	chain, err := getChain(tab, attrs, linux.NFTA_RULE_CHAIN, 0)
+	if err != nil {
+		return err
+	}
+
+	replace := false
+	var index int
+	if handleBytes, ok := attrs[linux.NFTA_RULE_HANDLE]; ok {
+		handle, ok := handleBytes.Uint64BE()
+		if !ok {
+			return syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("Nftables: Rule handle attribute is malformed"))
+		}
+		_, idx, err := chain.GetRuleByHandle(handle)
+		if err != nil {
+			return err
+		}
+		if flags&linux.NLM_F_EXCL == linux.NLM_F_EXCL {
+			return syserr.NewAnnotatedError(syserr.ErrExists, fmt.Sprintf("Nftables: Rule with handle: %d already exists", handle))
+		}
+		if flags&linux.NLM_F_REPLACE != linux.NLM_F_REPLACE {
+			return syserr.NewAnnotatedError(syserr.ErrNotSupported, fmt.Sprintf("Nftables: Rule with handle: %d already exists and NLM_F_REPLACE is not set", handle))
+		}
+		replace, index = true, idx
+	} else {
+		if flags&linux.NLM_F_CREATE != linux.NLM_F_CREATE || flags&linux.NLM_F_REPLACE == linux.NLM_F_REPLACE {
+			return syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("Nftables: New rules require NLM_F_CREATE without NLM_F_REPLACE"))
+		}
+		if _, ok := attrs[linux.NFTA_RULE_POSITION_ID]; ok {
+			return syserr.NewAnnotatedError(syserr.ErrNotSupported, fmt.Sprintf("Nftables: Rule position IDs are not supported"))
+		}
+
+		// New rules are inserted before the rule at the given position (or
+		// the first rule), or appended after it (or the last rule).
+		appendRule := flags&linux.NLM_F_APPEND == linux.NLM_F_APPEND
+		if posBytes, ok := attrs[linux.NFTA_RULE_POSITION]; ok {
+			pos, ok := posBytes.Uint64BE()
+			if !ok {
+				return syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("Nftables: Rule position attribute is malformed"))
+			}
+			_, idx, err := chain.GetRuleByHandle(pos)
+			if err != nil {
+				return err
+			}
+			index = idx
+			if appendRule {
+				index++
+			}
+		} else if appendRule {
+			index = -1
+		}
+	}
+
+	var exprs nlmsg.AttrsView
+	if exprsBytes, ok := attrs[linux.NFTA_RULE_EXPRESSIONS]; ok {
+		exprs = nlmsg.AttrsView(exprsBytes)
+	}
+	rule, err := nftables.ParseRule(tab, exprs)
+	if err != nil {
+		return err
+	}
+	userData, hasUserData, err := parseUserData(attrs, linux.NFTA_RULE_USERDATA)
+	if err != nil {
+		return err
+	}
+	if hasUserData {
+		rule.SetUserData(userData)
+	}
+
+	if replace {
+		return chain.ReplaceRule(index, rule)
+	}
+	return chain.RegisterRule(rule, index)
+}
+
+// delRule deletes the rule given by handle, or all rules of the chain if no
+// handle is given, or all rules of the table if no chain is given.
+func (p *Protocol) delRule(nft *nftables.NFTables, attrs map[uint16]nlmsg.BytesView, family stack.AddressFamily) *syserr.AnnotatedError {
+	// From net/netfilter/nf_tables_api.c:nf_tables_delrule.
+	tab, err := getTableByName(nft, attrs, family, linux.NFTA_RULE_TABLE)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := attrs[linux.NFTA_RULE_CHAIN]; !ok {
+		for _, chain := range tab.GetChains() {
+			flushChain(chain)
+		}
+		return nil
+	}
+
+	chain, err := getChain(tab, attrs, linux.NFTA_RULE_CHAIN, 0)
+	if err != nil {
+		return err
+	}
+
+	handleBytes, ok := attrs[linux.NFTA_RULE_HANDLE]
+	if !ok {
+		if _, ok := attrs[linux.NFTA_RULE_ID]; ok {
+			return syserr.NewAnnotatedError(syserr.ErrNotSupported, fmt.Sprintf("Nftables: Rule IDs are not supported"))
+		}
+		flushChain(chain)
+		return nil
+	}
+	handle, ok := handleBytes.Uint64BE()
+	if !ok {
+		return syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("Nftables: Rule handle attribute is malformed"))
+	}
+	_, index, err := chain.GetRuleByHandle(handle)
+	if err != nil {
+		return err
+	}
+	_, err = chain.UnregisterRuleByIndex(index)
+	return err
+}
+
+// flushChain deletes all rules of the chain.
+func flushChain(chain *nftables.Chain) {
+	for chain.RuleCount() > 0 {
+		if _, err := chain.UnregisterRuleByIndex(-1); err != nil {
+			panic(fmt.Sprintf("failed to unregister last rule of chain %s: %v", chain.GetName(), err))
+		}
+	}
+}
+
+// getRule replies with the rule of the given chain and handle.
+func (p *Protocol) getRule(nft *nftables.NFTables, attrs map[uint16]nlmsg.BytesView, family stack.AddressFamily, ms *nlmsg.MessageSet) *syserr.AnnotatedError {
+	tab, err := getTableByName(nft, attrs, family, linux.NFTA_RULE_TABLE)
+	if err != nil {
+		return err
+	}
+	chain, err := getChain(tab, attrs, linux.NFTA_RULE_CHAIN, 0)
+	if err != nil {
+		return err
+	}
+	handleBytes, ok := attrs[linux.NFTA_RULE_HANDLE]
+	if !ok {
+		return syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("Nftables: Rule handle attribute is malformed or not found"))
+	}
+	handle, ok := handleBytes.Uint64BE()
+	if !ok {
+		return syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("Nftables: Rule handle attribute is malformed"))
+	}
+	rule, index, err := chain.GetRuleByHandle(handle)
+	if err != nil {
+		return err
+	}
+
+	var prev *nftables.Rule
+	if index > 0 {
+		prev, _ = chain.GetRule(index - 1)
+	}
+	fillRuleInfo(nft, ms, rule, prev)
+	return nil
+}
+
+// dumpRules replies with all rules of the given family, optionally only those
+// of a single table or chain.
+func (p *Protocol) dumpRules(nft *nftables.NFTables, attrs map[uint16]nlmsg.BytesView, nfproto uint8, ms *nlmsg.MessageSet) *syserr.AnnotatedError {
+	tables, err := tablesToDump(nft, attrs, nfproto, linux.NFTA_RULE_TABLE)
+	if err != nil {
+		return err
+	}
+	chainNameBytes, filterChain := attrs[linux.NFTA_RULE_CHAIN]
+	for _, tab := range tables {
+		for _, chain := range tab.GetChains() {
+			if filterChain && chain.GetName() != chainNameBytes.String() {
+				continue
+			}
+			var prev *nftables.Rule
+			for i := 0; i < chain.RuleCount(); i++ {
+				rule, _ := chain.GetRule(i)
+				fillRuleInfo(nft, ms, rule, prev)
+				prev = rule
+			}
+		}
+	}
+	return nil
+}
+
+// fillRuleInfo adds a message describing the rule to the message set. prev is
+// the rule preceding it in its chain, if any.
+func fillRuleInfo(nft *nftables.NFTables, ms *nlmsg.MessageSet, rule *nftables.Rule, prev *nftables.Rule) {
+	// From net/netfilter/nf_tables_api.c:nf_tables_fill_rule_info.
+	chain := rule.GetChain()
+	m := newReply(nft, ms, linux.NFT_MSG_NEWRULE, nftables.AddressFamilyToNetlink(chain.GetAddressFamily()))
+	m.PutAttrString(linux.NFTA_RULE_TABLE, chain.GetTable().GetName())
+	m.PutAttrString(linux.NFTA_RULE_CHAIN, chain.GetName())
+	m.PutAttrUint64BE(linux.NFTA_RULE_HANDLE, rule.GetHandle())
+	if prev != nil {
+		m.PutAttrUint64BE(linux.NFTA_RULE_POSITION, prev.GetHandle())
+	}
+	m.PutNestedAttr(linux.NFTA_RULE_EXPRESSIONS, rule.DumpExprs())
+	if userData := rule.GetUserData(); len(userData) > 0 {
+		m.PutAttr(linux.NFTA_RULE_USERDATA, primitive.AsByteSlice(userData))
+	}
+}
+
+//
+// Sets
+//
+
+// newSet creates a new set.
+func (p *Protocol) newSet(nft *nftables.NFTables, attrs map[uint16]nlmsg.BytesView, family stack.AddressFamily, flags uint16) *syserr.AnnotatedError {
+	// From net/netfilter/nf_tables_api.c:nf_tables_newset.
+	tab, err := getTableByName(nft, attrs, family, linux.NFTA_SET_TABLE)
+	if err != nil {
+		return err
+	}
+	setName, err := parseName(attrs, linux.NFTA_SET_NAME, "Set")
+	if err != nil {
+		return err
+	}
+	if _, ok := attrs[linux.NFTA_SET_KEY_LEN]; !ok {
+		return syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("Nftables: Set key length attribute is malformed or not found"))
+	}
+	for _, unsupported := range []uint16{linux.NFTA_SET_TIMEOUT, linux.NFTA_SET_GC_INTERVAL, linux.NFTA_SET_OBJ_TYPE, linux.NFTA_SET_EXPR, linux.NFTA_SET_EXPRESSIONS} {
+		if _, ok := attrs[unsupported]; ok {
+			return syserr.NewAnnotatedError(syserr.ErrNotSupported, fmt.Sprintf("Nftables: Set attribute %d is not supported", unsupported))
+		}
+	}
+
+	var info nftables.SetInfo
+	for attrType, field := range map[uint16]*uint32{
+		linux.NFTA_SET_FLAGS:     &info.Flags,
+		linux.NFTA_SET_KEY_TYPE:  &info.KeyType,
+		linux.NFTA_SET_KEY_LEN:   &info.KeyLen,
+		linux.NFTA_SET_DATA_TYPE: &info.DataType,
+		linux.NFTA_SET_DATA_LEN:  &info.DataLen,
+	} {
+		if *field, err = parseOptionalUint32(attrs, attrType); err != nil {
+			return err
+		}
+	}
+	if info.UserData, _, err = parseUserData(attrs, linux.NFTA_SET_USERDATA); err != nil {
+		return err
+	}
+	id, err := parseOptionalUint32(attrs, linux.NFTA_SET_ID)
+	if err != nil {
+		return err
+	}
+
+	if set, err := tab.GetSet(setName); err == nil {
+		if flags&linux.NLM_F_EXCL == linux.NLM_F_EXCL {
+			return syserr.NewAnnotatedError(syserr.ErrExists, fmt.Sprintf("Nftables: Set with name: %s already exists", setName))
+		}
+		if flags&linux.NLM_F_REPLACE == linux.NLM_F_REPLACE {
+			return syserr.NewAnnotatedError(syserr.ErrNotSupported, fmt.Sprintf("Nftables: Set with name: %s already exists and NLM_F_REPLACE is not supported", setName))
+		}
+		old := set.GetInfo()
+		if old.Flags != info.Flags || old.KeyType != info.KeyType || old.KeyLen != info.KeyLen || old.DataType != info.DataType || old.DataLen != info.DataLen {
+			return syserr.NewAnnotatedError(syserr.ErrExists, fmt.Sprintf("Nftables: Set with name: %s already exists with different properties", setName))
+		}
+		return nil
+	}
+
+	_, err = tab.AddSet(setName, id, info)
+	return err
+}
+
+// delSet deletes the set given by name or handle.
+func (p *Protocol) delSet(nft *nftables.NFTables, attrs map[uint16]nlmsg.BytesView, family stack.AddressFamily) *syserr.AnnotatedError {
+	// From net/netfilter/nf_tables_api.c:nf_tables_delset.
+	tab, err := getTableByName(nft, attrs, family, linux.NFTA_SET_TABLE)
+	if err != nil {
+		return err
+	}
+
+	var set *nftables.Set
+	if handleBytes, ok := attrs[linux.NFTA_SET_HANDLE]; ok {
+		handle, ok := handleBytes.Uint64BE()
+		if !ok {
+			return syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("Nftables: Set handle attribute is malformed"))
+		}
+		set, err = tab.GetSetByHandle(handle)
+	} else {
+		var setName string
+		if setName, err = parseName(attrs, linux.NFTA_SET_NAME, "Set"); err == nil {
+			set, err = tab.GetSet(setName)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return tab.DeleteSet(set.GetName())
+}
+
+// getSet replies with the set of the given table and name.
+func (p *Protocol) getSet(nft *nftables.NFTables, attrs map[uint16]nlmsg.BytesView, family stack.AddressFamily, ms *nlmsg.MessageSet) *syserr.AnnotatedError {
+	tab, err := getTableByName(nft, attrs, family, linux.NFTA_SET_TABLE)
+	if err != nil {
+		return err
+	}
+	setName, err := parseName(attrs, linux.NFTA_SET_NAME, "Set")
+	if err != nil {
+		return err
+	}
+	set, err := tab.GetSet(setName)
+	if err != nil {
+		return err
+	}
+
+	fillSetInfo(nft, ms, set)
+	return nil
+}
+
+// dumpSets replies with all sets of the given family, optionally only those of
+// a single table.
+func (p *Protocol) dumpSets(nft *nftables.NFTables, attrs map[uint16]nlmsg.BytesView, nfproto uint8, ms *nlmsg.MessageSet) *syserr.AnnotatedError {
+	tables, err := tablesToDump(nft, attrs, nfproto, linux.NFTA_SET_TABLE)
+	if err != nil {
+		return err
+	}
+	for _, tab := range tables {
+		for _, set := range tab.GetSets() {
+			fillSetInfo(nft, ms, set)
+		}
+	}
+	return nil
+}
+
+// fillSetInfo adds a message describing the set to the message set.
+func fillSetInfo(nft *nftables.NFTables, ms *nlmsg.MessageSet, set *nftables.Set) {
+	// From net/netfilter/nf_tables_api.c:nf_tables_fill_set.
+	info := set.GetInfo()
+	m := newReply(nft, ms, linux.NFT_MSG_NEWSET, nftables.AddressFamilyToNetlink(set.GetTable().GetAddressFamily()))
+	m.PutAttrString(linux.NFTA_SET_TABLE, set.GetTable().GetName())
+	m.PutAttrString(linux.NFTA_SET_NAME, set.GetName())
+	m.PutAttrUint64BE(linux.NFTA_SET_HANDLE, set.GetHandle())
+	m.PutAttrUint32BE(linux.NFTA_SET_FLAGS, info.Flags)
+	m.PutAttrUint32BE(linux.NFTA_SET_KEY_TYPE, info.KeyType)
+	m.PutAttrUint32BE(linux.NFTA_SET_KEY_LEN, info.KeyLen)
+	if info.Flags&linux.NFT_SET_MAP != 0 {
+		m.PutAttrUint32BE(linux.NFTA_SET_DATA_TYPE, info.DataType)
+		m.PutAttrUint32BE(linux.NFTA_SET_DATA_LEN, info.DataLen)
+	}
+	if len(info.UserData) > 0 {
+		m.PutAttr(linux.NFTA_SET_USERDATA, primitive.AsByteSlice(info.UserData))
+	}
+}
+
+//
+// Set Elements
+//
+
+// newSetElem adds elements to a set.
+func (p *Protocol) newSetElem(nft *nftables.NFTables, attrs map[uint16]nlmsg.BytesView, family stack.AddressFamily, flags uint16) *syserr.AnnotatedError {
+	// From net/netfilter/nf_tables_api.c:nf_tables_newsetelem.
+	set, err := getElemsSet(nft, attrs, family)
+	if err != nil {
+		return err
+	}
+	elemsBytes, ok := attrs[linux.NFTA_SET_ELEM_LIST_ELEMENTS]
+	if !ok {
+		return syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("Nftables: Set elements attribute is malformed or not found"))
+	}
+	return set.AddElems(nlmsg.AttrsView(elemsBytes), flags&linux.NLM_F_EXCL == linux.NLM_F_EXCL)
+}
+
+// delSetElem deletes elements from a set, or all elements if none are given.
+func (p *Protocol) delSetElem(nft *nftables.NFTables, attrs map[uint16]nlmsg.BytesView, family stack.AddressFamily) *syserr.AnnotatedError {
+	// From net/netfilter/nf_tables_api.c:nf_tables_delsetelem.
+	set, err := getElemsSet(nft, attrs, family)
+	if err != nil {
+		return err
+	}
+	elemsBytes, ok := attrs[linux.NFTA_SET_ELEM_LIST_ELEMENTS]
+	if !ok {
+		return set.Flush()
+	}
+	return set.DeleteElems(nlmsg.AttrsView(elemsBytes))
+}
+
+// dumpSetElems replies with the elements of a set.
+func (p *Protocol) dumpSetElems(nft *nftables.NFTables, attrs map[uint16]nlmsg.BytesView, family stack.AddressFamily, ms *nlmsg.MessageSet) *syserr.AnnotatedError {
+	// From net/netfilter/nf_tables_api.c:nf_tables_dump_set.
+	set, err := getElemsSet(nft, attrs, family)
+	if err != nil {
+		return err
+	}
+	ms.Multi = true
+	m := newReply(nft, ms, linux.NFT_MSG_NEWSETELEM, nftables.AddressFamilyToNetlink(family))
+	m.PutAttrString(linux.NFTA_SET_ELEM_LIST_TABLE, set.GetTable().GetName())
+	m.PutAttrString(linux.NFTA_SET_ELEM_LIST_SET, set.GetName())
+	m.PutNestedAttr(linux.NFTA_SET_ELEM_LIST_ELEMENTS, set.DumpElems())
+	return nil
+}
+
+// getElemsSet returns the set of a set element message, given by name or by
+// the ID it was given in the current batch.
+func getElemsSet(nft *nftables.NFTables, attrs map[uint16]nlmsg.BytesView, family stack.AddressFamily) (*nftables.Set, *syserr.AnnotatedError) {
+	tab, err := getTableByName(nft, attrs, family, linux.NFTA_SET_ELEM_LIST_TABLE)
+	if err != nil {
+		return nil, err
+	}
+	if setNameBytes, ok := attrs[linux.NFTA_SET_ELEM_LIST_SET]; ok {
+		return tab.GetSet(setNameBytes.String())
+	}
+	if idBytes, ok := attrs[linux.NFTA_SET_ELEM_LIST_SET_ID]; ok {
+		id, ok := idBytes.Uint32BE()
+		if !ok {
+			return nil, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("Nftables: Set ID attribute is malformed"))
+		}
+		return tab.GetSetByID(id)
+	}
+	return nil, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("Nftables: Set name attribute is malformed or not found"))
+}
+
+//
+// Attribute Helpers
+//
+
+// parseName returns the value of a required name attribute.
+func parseName(attrs map[uint16]nlmsg.BytesView, attrType uint16, kind string) (string, *syserr.AnnotatedError) {
+	nameBytes, ok := attrs[attrType]
+	if !ok {
+		return "", syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("Nftables: %s name attribute is malformed or not found", kind))
+	}
+	name := nameBytes.String()
+	if name == "" {
+		return "", syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("Nftables: %s name is empty", kind))
+	}
+	if len(name) >= linux.NFT_NAME_MAXLEN {
+		return "", syserr.NewAnnotatedError(syserr.ErrRange, fmt.Sprintf("Nftables: %s name is too long", kind))
+	}
+	return name, nil
+}
+
+// parseUserData returns a copy of the value of an optional user data
+// attribute and whether it was given.
+func parseUserData(attrs map[uint16]nlmsg.BytesView, attrType uint16) ([]byte, bool, *syserr.AnnotatedError) {
+	userData, ok := attrs[attrType]
+	if !ok {
+		return nil, false, nil
+	}
+	if len(userData) > linux.NFT_USERDATA_MAXLEN {
+		return nil, false, syserr.NewAnnotatedError(syserr.ErrRange, fmt.Sprintf("Nftables: User data is too long: %d bytes", len(userData)))
+	}
+	return append([]byte(nil), userData...), true, nil
+}
+
+// parseOptionalUint32 returns the value of an optional big-endian 32-bit
+// attribute, or 0 if it is not given.
+func parseOptionalUint32(attrs map[uint16]nlmsg.BytesView, attrType uint16) (uint32, *syserr.AnnotatedError) {
+	b, ok := attrs[attrType]
+	if !ok {
+		return 0, nil
+	}
+	v, ok := b.Uint32BE()
+	if !ok {
+		return 0, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("Nftables: Attribute %d is malformed", attrType))
+	}
+	return v, nil
+}
+
+// getTableByName returns the table of the given family named by the attribute.
+func getTableByName(nft *nftables.NFTables, attrs map[uint16]nlmsg.BytesView, family stack.AddressFamily, attrType uint16) (*nftables.Table, *syserr.AnnotatedError) {
+	tabNameBytes, ok := attrs[attrType]
+	if !ok {
+		return nil, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("Nftables: Table name attribute is malformed or not found"))
+	}
+	return nft.GetTable(family, tabNameBytes.String())
+}
+
+// getChain returns the chain of the table given by the handle attribute, if
+// any, or the name attribute. A handleType of 0 means chains can only be given
+// by name.
+func getChain(tab *nftables.Table, attrs map[uint16]nlmsg.BytesView, nameType, handleType uint16) (*nftables.Chain, *syserr.AnnotatedError) {
+	if handleType != 0 {
+		if handleBytes, ok := attrs[handleType]; ok {
+			handle, ok := handleBytes.Uint64BE()
+			if !ok {
+				return nil, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("Nftables: Chain handle attribute is malformed"))
+			}
+			return tab.GetChainByHandle(handle)
+		}
+	}
+	chainNameBytes, ok := attrs[nameType]
+	if !ok {
+		return nil, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("Nftables: Chain name attribute is malformed or not found"))
+	}
+	return tab.GetChain(chainNameBytes.String())
+}
+
+// tablesToDump returns the tables to dump for the given NFPROTO_* family in
+// the order they were created. If the table name attribute is given, only the
+// table by that name is dumped.
+func tablesToDump(nft *nftables.NFTables, attrs map[uint16]nlmsg.BytesView, nfproto uint8, tabNameType uint16) ([]*nftables.Table, *syserr.AnnotatedError) {
+	families, err := addressFamilies(nfproto)
+	if err != nil {
+		return nil, err
+	}
+	var tables []*nftables.Table
+	for _, family := range families {
+		tables = append(tables, nft.GetTables(family)...)
+	}
+	if tabNameBytes, ok := attrs[tabNameType]; ok && tabNameType != 0 {
+		tabName := tabNameBytes.String()
+		tables = slices.DeleteFunc(tables, func(tab *nftables.Table) bool {
+			return tab.GetName() != tabName
+		})
+	}
+	slices.SortFunc(tables, func(a, b *nftables.Table) int {
+		return cmp.Compare(a.GetHandle(), b.GetHandle())
+	})
+	return tables, nil
+}
+
+// addressFamilies returns the address families that a message for the given
+// NFPROTO_* family applies to, which is all of them for NFPROTO_UNSPEC.
+func addressFamilies(nfproto uint8) ([]stack.AddressFamily, *syserr.AnnotatedError) {
+	if nfproto == linux.NFPROTO_UNSPEC {
+		families := make([]stack.AddressFamily, 0, stack.NumAFs)
+		for family := range stack.NumAFs {
+			families = append(families, family)
+		}
+		return families, nil
+	}
+	family, err := nftables.AddressFamilyFromNetlink(nfproto)
+	if err != nil {
+		return nil, err
+	}
+	return []stack.AddressFamily{family}, nil
+}
@@ -23,12 +23,13 @@ import (
 	"gvisor.dev/gvisor/pkg/log"
 	"gvisor.dev/gvisor/pkg/sentry/inet"
 	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/socket"
 	"gvisor.dev/gvisor/pkg/sentry/socket/netlink"
 	"gvisor.dev/gvisor/pkg/sentry/socket/netlink/nlmsg"
 	"gvisor.dev/gvisor/pkg/sentry/socket/netstack"
 	"gvisor.dev/gvisor/pkg/syserr"
 	"gvisor.dev/gvisor/pkg/tcpip/nftables"
-	"gvisor.dev/gvisor/pkg/tcpip/stack"
 )
 
 // Protocol implements netlink.Protocol.
@@ -36,7 +37,7 @@ import (
 // +stateify savable
 type Protocol struct{}
 
-var _ netlink.Protocol = (*Protocol)(nil)
+var _ netlink.BatchProtocol = (*Protocol)(nil)
 
 // NewProtocol creates a NETLINK_NETFILTER netlink.Protocol.
 func NewProtocol(t *kernel.Task) (netlink.Protocol, *syserr.Error) {
@@ -70,113 +71,299 @@ func (p *Protocol) ProcessMessage(ctx context.Context, s *netlink.Socket, msg *n
 		return nil
 	}
 
-	msgType := hdr.NetFilterMsgType()
-	st := inet.StackFromContext(ctx).(*netstack.Stack).Stack
-	nft := (st.NFTables()).(*nftables.NFTables)
-	var nfGenMsg linux.NetFilterGenMsg
+	// All messages require CAP_NET_ADMIN, from
+	// net/netfilter/nfnetlink.c:nfnetlink_rcv.
+	creds := auth.CredentialsFromContext(ctx)
+	if !creds.HasCapability(linux.CAP_NET_ADMIN) {
+		return syserr.ErrPermissionDenied
+	}
 
-	// The payload of a message is its attributes.
-	atr, ok := msg.GetData(&nfGenMsg)
-	if !ok {
-		log.Debugf("Failed to get message data")
-		return syserr.ErrInvalidArgument
+	if subsysID := hdr.NetFilterSubsysID(); subsysID != linux.NFNL_SUBSYS_NFTABLES {
+		log.Debugf("Unsupported netfilter subsystem: %d", subsysID)
+		return syserr.ErrNotSupported
 	}
 
-	attrs, ok := atr.Parse()
-	if !ok {
-		log.Debugf("Failed to parse message attributes")
-		return syserr.ErrInvalidArgument
+	nfGenMsg, attrs, err := parseMessage(msg)
+	if err != nil {
+		log.Debugf("Nftables message error: %s", err)
+		return err.GetError()
 	}
 
-	// Nftables functions error check the address family value.
-	family := stack.AddressFamily(nfGenMsg.Family)
-	// TODO: b/421437663 - Match the message type and call the appropriate Nftables function.
-	switch msgType {
-	case linux.NFT_MSG_NEWTABLE:
-		if err := p.newTable(nft, attrs, family, hdr.Flags); err != nil {
-			log.Debugf("Nftables new table error: %s", err)
+	nft := nftablesFromContext(ctx)
+	if isGetMessage(hdr.NetFilterMsgType()) {
+		// Committed rulesets are never modified, so the snapshot remains
+		// consistent while the reply is built.
+		if err := p.processGetMessage(ctx, nft.Snapshot(), hdr, nfGenMsg, attrs, ms); err != nil {
+			log.Debugf("Nftables get message error: %s", err)
 			return err.GetError()
 		}
 		return nil
-	case linux.NFT_MSG_GETTABLE:
-		if err := p.getTable(nft, attrs, family, hdr.Flags, ms); err != nil {
-			log.Debugf("Nftables get table error: %s", err)
-			return err.GetError()
-		}
-		return nil
-	default:
-		log.Debugf("Unsupported message type: %d", msgType)
-		return syserr.ErrNotSupported
 	}
+
+	// Messages that modify the ruleset outside of a batch are applied as a
+	// batch of their own.
+	tx := nft.NewTransaction()
+	if err := p.applyMessage(tx.NFTables, hdr, nfGenMsg, attrs); err != nil {
+		tx.Abort()
+		log.Debugf("Nftables message error: %s", err)
+		return err.GetError()
+	}
+	tx.Commit()
+	return nil
 }
 
-// newTable creates a new table for the given family.
-func (p *Protocol) newTable(nft *nftables.NFTables, attrs map[uint16]nlmsg.BytesView, family stack.AddressFamily, flags uint16) *syserr.AnnotatedError {
-	// TODO: b/421437663 - Handle the case where the table name is set to empty string.
-	// The table name is required.
-	tabNameBytes, ok := attrs[linux.NFTA_TABLE_NAME]
-	if !ok {
-		return syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("Nftables: Table name attribute is malformed or not found"))
+// IsBatchBegin implements netlink.BatchProtocol.IsBatchBegin.
+func (p *Protocol) IsBatchBegin(hdr linux.NetlinkMessageHeader) bool {
+	return hdr.Type == linux.NFNL_MSG_BATCH_BEGIN
+}
+
+// IsBatchEnd implements netlink.BatchProtocol.IsBatchEnd.
+func (p *Protocol) IsBatchEnd(hdr linux.NetlinkMessageHeader) bool {
+	return hdr.Type == linux.NFNL_MSG_BATCH_END
+}
+
+// ProcessBatch implements netlink.BatchProtocol.ProcessBatch.
+//
+// The messages of the batch are applied to a single transaction, which is
+// committed only if every message succeeds and the batch was ended, from
+// net/netfilter/nfnetlink.c:nfnetlink_rcv_batch.
+func (p *Protocol) ProcessBatch(ctx context.Context, s *netlink.Socket, begin *nlmsg.Message, msgs []*nlmsg.Message, complete bool) (*syserr.Error, []*syserr.Error) {
+	creds := auth.CredentialsFromContext(ctx)
+	if !creds.HasCapability(linux.CAP_NET_ADMIN) {
+		return syserr.ErrPermissionDenied, nil
 	}
 
-	var dormant bool
-	if dbytes, ok := attrs[linux.NFTA_TABLE_FLAGS]; ok {
-		dflag, _ := dbytes.Uint32()
-		dormant = (dflag & linux.NFT_TABLE_F_DORMANT) == linux.NFT_TABLE_F_DORMANT
+	nfGenMsg, attrs, err := parseMessage(begin)
+	if err != nil {
+		log.Debugf("Nftables batch begin error: %s", err)
+		return err.GetError(), nil
 	}
 
-	tab, err := nft.GetTable(family, tabNameBytes.String())
-	if err != nil && err.GetError() != syserr.ErrNoFileOrDir {
-		return err
+	// Batches for no subsystem are treated as nf_tables batches for backwards
+	// compatibility, like in Linux.
+	subsysID := linux.SubsysID(socket.Ntohs(nfGenMsg.ResourceID))
+	if subsysID != linux.NFNL_SUBSYS_NONE && subsysID != linux.NFNL_SUBSYS_NFTABLES {
+		log.Debugf("Unsupported netfilter batch subsystem: %d", subsysID)
+		return syserr.ErrInvalidArgument, nil
 	}
 
-	// If a table already exists, only update its dormant flags if NLM_F_EXCL and NLM_F_REPLACE
-	// are not set. From net/netfilter/nf_tables_api.c:nf_tables_newtable:nf_tables_updtable
-	if tab != nil {
-		if flags&linux.NLM_F_EXCL == linux.NLM_F_EXCL {
-			return syserr.NewAnnotatedError(syserr.ErrExists, fmt.Sprintf("Nftables: Table with name: %s already exists", tabNameBytes.String()))
+	tx := nftablesFromContext(ctx).NewTransaction()
+
+	// The batch is rejected if the ruleset has changed since userspace
+	// retrieved the generation ID it expects.
+	if genIDBytes, ok := attrs[uint16(linux.NFNL_BATCH_GENID)]; ok {
+		if genID, ok := genIDBytes.Uint32BE(); ok && genID != 0 && genID != tx.GenID() {
+			tx.Abort()
+			log.Debugf("Nftables batch generation ID %d does not match %d", genID, tx.GenID())
+			return syserr.ErrShouldRestart, nil
 		}
+	}
 
-		if flags&linux.NLM_F_REPLACE == linux.NLM_F_REPLACE {
-			return syserr.NewAnnotatedError(syserr.ErrNotSupported, fmt.Sprintf("Nftables: Table with name: %s already exists and NLM_F_REPLACE is not supported", tabNameBytes.String()))
+	failed := !complete
+	errs := make([]*syserr.Error, 0, len(msgs))
+	for _, msg := range msgs {
+		// Batches can't be nested. The whole batch is discarded without
+		// reporting errors for any of its messages.
+		if p.IsBatchBegin(msg.Header()) {
+			tx.Abort()
+			log.Debugf("Nftables batch begins a nested batch")
+			return nil, nil
 		}
-	} else {
-		tab, err = nft.CreateTable(family, tabNameBytes.String())
-		if err != nil {
-			return err
+
+		if err := p.processBatchMessage(tx, msg); err != nil {
+			log.Debugf("Nftables batch message error: %s", err)
+			errs = append(errs, err.GetError())
+			failed = true
+			continue
 		}
+		errs = append(errs, nil)
 	}
 
-	tab.SetDormant(dormant)
-	return nil
+	if failed {
+		tx.Abort()
+	} else {
+		tx.Commit()
+	}
+	return nil, errs
 }
 
-// getTable returns a table for the given family.
-func (p *Protocol) getTable(nft *nftables.NFTables, attrs map[uint16]nlmsg.BytesView, family stack.AddressFamily, flags uint16, ms *nlmsg.MessageSet) *syserr.AnnotatedError {
-	// The table name is required.
-	tabNameBytes, ok := attrs[linux.NFTA_TABLE_NAME]
-	if !ok {
-		return syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("Nftables: Table name attribute is malformed or not found"))
+// processBatchMessage validates a message within a batch and applies it to
+// the transaction.
+func (p *Protocol) processBatchMessage(tx *nftables.Transaction, msg *nlmsg.Message) *syserr.AnnotatedError {
+	hdr := msg.Header()
+	if hdr.Type < linux.NLMSG_MIN_TYPE || hdr.Flags&linux.NLM_F_REQUEST == 0 {
+		return syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("Nftables: Message type %d in batch is not a request", hdr.Type))
+	}
+	if subsysID := hdr.NetFilterSubsysID(); subsysID != linux.NFNL_SUBSYS_NFTABLES {
+		return syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("Nftables: Message for subsystem %d in nf_tables batch", subsysID))
+	}
+	if msgType := hdr.NetFilterMsgType(); isGetMessage(msgType) {
+		return syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("Nftables: Message type %d cannot be batched", msgType))
+	}
+
+	nfGenMsg, attrs, err := parseMessage(msg)
+	if err != nil {
+		return err
+	}
+	return p.applyMessage(tx.NFTables, hdr, nfGenMsg, attrs)
+}
+
+// applyMessage applies a message that modifies the ruleset to the working
+// copy of a transaction.
+func (p *Protocol) applyMessage(nft *nftables.NFTables, hdr linux.NetlinkMessageHeader, nfGenMsg linux.NetFilterGenMsg, attrs map[uint16]nlmsg.BytesView) *syserr.AnnotatedError {
+	msgType := hdr.NetFilterMsgType()
+
+	// Tables can be deleted for all families at once.
+	if msgType == linux.NFT_MSG_DELTABLE {
+		return p.delTable(nft, attrs, nfGenMsg.Family)
 	}
 
-	tab, err := nft.GetTable(family, tabNameBytes.String())
+	family, err := nftables.AddressFamilyFromNetlink(nfGenMsg.Family)
 	if err != nil {
 		return err
 	}
 
-	tabName := tab.GetName()
+	switch msgType {
+	case linux.NFT_MSG_NEWTABLE:
+		return p.newTable(nft, attrs, family, hdr.Flags)
+	case linux.NFT_MSG_NEWCHAIN:
+		return p.newChain(nft, attrs, family, hdr.Flags)
+	case linux.NFT_MSG_DELCHAIN:
+		return p.delChain(nft, attrs, family, hdr.Flags)
+	case linux.NFT_MSG_NEWRULE:
+		return p.newRule(nft, attrs, family, hdr.Flags)
+	case linux.NFT_MSG_DELRULE:
+		return p.delRule(nft, attrs, family)
+	case linux.NFT_MSG_NEWSET:
+		return p.newSet(nft, attrs, family, hdr.Flags)
+	case linux.NFT_MSG_DELSET:
+		return p.delSet(nft, attrs, family)
+	case linux.NFT_MSG_NEWSETELEM:
+		return p.newSetElem(nft, attrs, family, hdr.Flags)
+	case linux.NFT_MSG_DELSETELEM:
+		return p.delSetElem(nft, attrs, family)
+	default:
+		return syserr.NewAnnotatedError(syserr.ErrNotSupported, fmt.Sprintf("Nftables: Unsupported message type: %d", msgType))
+	}
+}
+
+// processGetMessage replies to a message that retrieves the ruleset.
+func (p *Protocol) processGetMessage(ctx context.Context, nft *nftables.NFTables, hdr linux.NetlinkMessageHeader, nfGenMsg linux.NetFilterGenMsg, attrs map[uint16]nlmsg.BytesView, ms *nlmsg.MessageSet) *syserr.AnnotatedError {
+	msgType := hdr.NetFilterMsgType()
+	dump := hdr.Flags&linux.NLM_F_DUMP == linux.NLM_F_DUMP
+
+	switch msgType {
+	case linux.NFT_MSG_GETGEN:
+		return p.getGen(ctx, nft, ms)
+	case linux.NFT_MSG_GETOBJ, linux.NFT_MSG_GETOBJ_RESET, linux.NFT_MSG_GETFLOWTABLE:
+		// Stateful objects and flowtables are not supported, so there are
+		// never any to retrieve.
+		if dump {
+			ms.Multi = true
+			return nil
+		}
+		return syserr.NewAnnotatedError(syserr.ErrNoFileOrDir, fmt.Sprintf("Nftables: Message type %d found no objects", msgType))
+	}
+
+	if dump {
+		ms.Multi = true
+		switch msgType {
+		case linux.NFT_MSG_GETTABLE:
+			return p.dumpTables(nft, attrs, nfGenMsg.Family, ms)
+		case linux.NFT_MSG_GETCHAIN:
+			return p.dumpChains(nft, attrs, nfGenMsg.Family, ms)
+		case linux.NFT_MSG_GETRULE:
+			return p.dumpRules(nft, attrs, nfGenMsg.Family, ms)
+		case linux.NFT_MSG_GETSET:
+			return p.dumpSets(nft, attrs, nfGenMsg.Family, ms)
+		}
+	}
+
+	family, err := nftables.AddressFamilyFromNetlink(nfGenMsg.Family)
+	if err != nil {
+		return err
+	}
+
+	switch msgType {
+	case linux.NFT_MSG_GETTABLE:
+		return p.getTable(nft, attrs, family, ms)
+	case linux.NFT_MSG_GETCHAIN:
+		return p.getChain(nft, attrs, family, ms)
+	case linux.NFT_MSG_GETRULE:
+		return p.getRule(nft, attrs, family, ms)
+	case linux.NFT_MSG_GETSET:
+		return p.getSet(nft, attrs, family, ms)
+	case linux.NFT_MSG_GETSETELEM:
+		if !dump {
+			return syserr.NewAnnotatedError(syserr.ErrNotSupported, fmt.Sprintf("Nftables: Retrieving individual set elements is not supported"))
+		}
+		return p.dumpSetElems(nft, attrs, family, ms)
+	default:
+		return syserr.NewAnnotatedError(syserr.ErrNotSupported, fmt.Sprintf("Nftables: Unsupported message type: %d", msgType))
+	}
+}
+
+// getGen replies with the generation ID of the ruleset.
+func (p *Protocol) getGen(ctx context.Context, nft *nftables.NFTables, ms *nlmsg.MessageSet) *syserr.AnnotatedError {
+	// From net/netfilter/nf_tables_api.c:nf_tables_fill_gen_info.
+	m := newReply(nft, ms, linux.NFT_MSG_NEWGEN, linux.NFPROTO_UNSPEC)
+	m.PutAttrUint32BE(linux.NFTA_GEN_ID, nft.GenID())
+	if t := kernel.TaskFromContext(ctx); t != nil {
+		m.PutAttrUint32BE(linux.NFTA_GEN_PROC_PID, uint32(t.ThreadID()))
+		m.PutAttrString(linux.NFTA_GEN_PROC_NAME, t.Name())
+	}
+	return nil
+}
+
+// newReply adds a reply message of the given type for the given NFPROTO_*
+// family to the message set.
+func newReply(nft *nftables.NFTables, ms *nlmsg.MessageSet, msgType linux.NfTableMsgType, family uint8) *nlmsg.Message {
 	m := ms.AddMessage(linux.NetlinkMessageHeader{
-		Type: uint16(linux.NFNL_SUBSYS_NFTABLES)<<8 | uint16(linux.NFT_MSG_GETTABLE),
+		Type: uint16(linux.NFNL_SUBSYS_NFTABLES)<<8 | uint16(msgType),
 	})
 
 	m.Put(&linux.NetFilterGenMsg{
-		Family:  uint8(family),
+		Family:  family,
 		Version: uint8(linux.NFNETLINK_V0),
-		// Unused, set to 0.
-		ResourceID: uint16(0),
+		// The lower bits of the generation ID, like in Linux.
+		ResourceID: socket.Htons(uint16(nft.GenID())),
 	})
-	m.PutAttrString(linux.NFTA_TABLE_NAME, tabName)
-	return nil
+	return m
+}
+
+// parseMessage returns the genmsg and attributes of an nf_tables message.
+func parseMessage(msg *nlmsg.Message) (linux.NetFilterGenMsg, map[uint16]nlmsg.BytesView, *syserr.AnnotatedError) {
+	var nfGenMsg linux.NetFilterGenMsg
+
+	// The payload of a message is its attributes.
+	atr, ok := msg.GetData(&nfGenMsg)
+	if !ok {
+		return nfGenMsg, nil, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("Nftables: Failed to get message data"))
+	}
+
+	attrs, ok := atr.Parse()
+	if !ok {
+		return nfGenMsg, nil, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("Nftables: Failed to parse message attributes"))
+	}
+	return nfGenMsg, attrs, nil
+}
+
+// isGetMessage returns true if the message type only retrieves the ruleset.
+func isGetMessage(msgType linux.NfTableMsgType) bool {
+	switch msgType {
+	case linux.NFT_MSG_GETTABLE, linux.NFT_MSG_GETCHAIN, linux.NFT_MSG_GETRULE,
+		linux.NFT_MSG_GETSET, linux.NFT_MSG_GETSETELEM, linux.NFT_MSG_GETGEN,
+		linux.NFT_MSG_GETOBJ, linux.NFT_MSG_GETOBJ_RESET, linux.NFT_MSG_GETFLOWTABLE,
+		linux.NFT_MSG_GETRULE_RESET, linux.NFT_MSG_GETSETELEM_RESET:
+		return true
+	}
+	return false
+}
+
+// nftablesFromContext returns the NFTables object of the network stack.
+func nftablesFromContext(ctx context.Context) *nftables.NFTables {
+	st := inet.StackFromContext(ctx).(*netstack.Stack).Stack
+	return (st.NFTables()).(*nftables.NFTables)
 }
 
 func netLinkMessagePayloadSize(h *linux.NetlinkMessageHeader) int {
@@ -16,6 +16,8 @@
 package nlmsg
 
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
 	"math"
 
@@ -190,6 +192,64 @@ func (m *Message) PutAttrString(atype uint16, s string) {
 	m.putZeros(aligned - l)
 }
 
+// PutAttrUint32BE adds v to the message as a netlink attribute in network byte
+// order.
+func (m *Message) PutAttrUint32BE(atype uint16, v uint32) {
+	m.PutAttr(atype, primitive.AsByteSlice(binary.BigEndian.AppendUint32(nil, v)))
+}
+
+// PutAttrUint64BE adds v to the message as a netlink attribute in network byte
+// order.
+func (m *Message) PutAttrUint64BE(atype uint16, v uint64) {
+	m.PutAttr(atype, primitive.AsByteSlice(binary.BigEndian.AppendUint64(nil, v)))
+}
+
+// PutNestedAttr adds attrs to the message as a nested netlink attribute. The
+// attribute type is marked with NLA_F_NESTED, like nla_nest_start in Linux.
+func (m *Message) PutNestedAttr(atype uint16, attrs NestedAttr) {
+	m.PutAttr(atype|linux.NLA_F_NESTED, primitive.AsByteSlice(attrs))
+}
+
+// NestedAttr contains a series of serialized netlink attributes, to be added to
+// a message or another NestedAttr as the payload of a nested attribute.
+type NestedAttr []byte
+
+// PutAttr adds v to n as a netlink attribute.
+//
+// Preconditions: As for Message.PutAttr.
+func (n *NestedAttr) PutAttr(atype uint16, v marshal.Marshallable) {
+	l := linux.NetlinkAttrHeaderSize + v.SizeBytes()
+	if l > math.MaxUint16 {
+		panic(fmt.Sprintf("attribute too large: %d", l))
+	}
+	*n = append(*n, marshal.Marshal(&linux.NetlinkAttrHeader{
+		Type:   atype,
+		Length: uint16(l),
+	})...)
+	*n = append(*n, marshal.Marshal(v)...)
+	*n = append(*n, make([]byte, alignPad(l, linux.NLA_ALIGNTO))...)
+}
+
+// PutAttrString adds s to n as a NUL-terminated netlink attribute.
+func (n *NestedAttr) PutAttrString(atype uint16, s string) {
+	n.PutAttr(atype, primitive.AsByteSlice(append([]byte(s), 0)))
+}
+
+// PutAttrUint32BE adds v to n as a netlink attribute in network byte order.
+func (n *NestedAttr) PutAttrUint32BE(atype uint16, v uint32) {
+	n.PutAttr(atype, primitive.AsByteSlice(binary.BigEndian.AppendUint32(nil, v)))
+}
+
+// PutAttrUint64BE adds v to n as a netlink attribute in network byte order.
+func (n *NestedAttr) PutAttrUint64BE(atype uint16, v uint64) {
+	n.PutAttr(atype, primitive.AsByteSlice(binary.BigEndian.AppendUint64(nil, v)))
+}
+
+// PutNestedAttr adds attrs to n as a nested netlink attribute.
+func (n *NestedAttr) PutNestedAttr(atype uint16, attrs NestedAttr) {
+	n.PutAttr(atype|linux.NLA_F_NESTED, primitive.AsByteSlice(attrs))
+}
+
 // MessageSet contains a series of netlink messages.
 type MessageSet struct {
 	// Multi indicates that this a multi-part message, to be terminated by
@@ -280,7 +340,8 @@ func (v AttrsView) Parse() (map[uint16]BytesView, bool) {
 			return nil, false
 		}
 		attrsView = rest
-		attrs[ahdr.Type] = BytesView(value)
+		// Like nla_type(), ignore the nested and byte order flags.
+		attrs[ahdr.Type&linux.NLA_TYPE_MASK] = BytesView(value)
 	}
 	return attrs, true
 
@@ -300,14 +361,12 @@ func (v *BytesView) Extract(n int) ([]byte, bool) {
 	return extracted, true
 }
 
-// String converts the raw attribute value to string.
+// String converts the raw attribute value to string. Like Linux's
+// nla_strscpy, the string ends at the first NUL byte, if any.
 func (v *BytesView) String() string {
 	b := []byte(*v)
-	if len(b) == 0 {
-		return ""
-	}
-	if b[len(b)-1] == 0 {
-		b = b[:len(b)-1]
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
 	}
 	return string(b)
 }
@@ -323,6 +382,24 @@ func (v *BytesView) Uint32() (uint32, bool) {
 	return uint32(val), true
 }
 
+// Uint32BE converts the raw attribute value, in network byte order, to uint32.
+func (v *BytesView) Uint32BE() (uint32, bool) {
+	attr := []byte(*v)
+	if len(attr) != 4 {
+		return 0, false
+	}
+	return binary.BigEndian.Uint32(attr), true
+}
+
+// Uint64BE converts the raw attribute value, in network byte order, to uint64.
+func (v *BytesView) Uint64BE() (uint64, bool) {
+	attr := []byte(*v)
+	if len(attr) != 8 {
+		return 0, false
+	}
+	return binary.BigEndian.Uint64(attr), true
+}
+
 // Int32 converts the raw attribute value to int32.
 func (v *BytesView) Int32() (int32, bool) {
 	attr := []byte(*v)
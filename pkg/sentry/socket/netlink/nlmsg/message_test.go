@@ -317,6 +317,12 @@ func TestBytesView(t *testing.T) {
 			ok:    true,
 			value: "hello world",
 		},
+		bytesViewTest[string]{
+			desc:  "Convert NUL-padded BytesView to string",
+			input: nlmsg.BytesView([]byte("hello\x00\x00\x00")),
+			ok:    true,
+			value: "hello",
+		},
 		bytesViewTest[uint32]{
 			desc:  "Convert BytesView to uint32",
 			input: nlmsg.BytesView([]byte{7, 0, 0, 0}),
@@ -47,6 +47,29 @@ type Protocol interface {
 	ProcessMessage(ctx context.Context, s *Socket, msg *nlmsg.Message, ms *nlmsg.MessageSet) *syserr.Error
 }
 
+// BatchProtocol is a Protocol that processes some sequences of messages as a
+// single batch, such as the nf_tables batches of NETLINK_NETFILTER.
+type BatchProtocol interface {
+	Protocol
+
+	// IsBatchBegin returns true if the message begins a batch.
+	IsBatchBegin(hdr linux.NetlinkMessageHeader) bool
+
+	// IsBatchEnd returns true if the message ends a batch.
+	IsBatchEnd(hdr linux.NetlinkMessageHeader) bool
+
+	// ProcessBatch processes the messages of a batch from userspace, excluding
+	// the messages beginning and ending the batch. complete is false if the
+	// batch was not ended.
+	//
+	// If beginErr != nil, the batch was rejected as a whole and beginErr is
+	// reported for the message beginning the batch. Otherwise, errs holds the
+	// error (or nil) for each processed message in msgs. errs may be shorter
+	// than msgs if processing stopped early, in which case no response is sent
+	// for the remaining messages.
+	ProcessBatch(ctx context.Context, s *Socket, begin *nlmsg.Message, msgs []*nlmsg.Message, complete bool) (beginErr *syserr.Error, errs []*syserr.Error)
+}
+
 // Provider is a function that creates a new Protocol for a specific netlink
 // protocol.
 //
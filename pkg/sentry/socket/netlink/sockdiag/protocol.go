@@ -0,0 +1,480 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sockdiag provides a NETLINK_SOCK_DIAG socket protocol.
+//
+// NETLINK_SOCK_DIAG sockets report information about the sockets of a network
+// namespace, as used by ss(8). The inet_diag interface is supported for TCP,
+// UDP and raw sockets and the unix_diag interface for unix sockets.
+package sockdiag
+
+import (
+	"bytes"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/marshal/primitive"
+	"gvisor.dev/gvisor/pkg/sentry/inet"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/socket"
+	"gvisor.dev/gvisor/pkg/sentry/socket/netlink"
+	"gvisor.dev/gvisor/pkg/sentry/socket/netlink/nlmsg"
+	"gvisor.dev/gvisor/pkg/sentry/socket/unix"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/syserr"
+)
+
+// Protocol implements netlink.Protocol.
+//
+// +stateify savable
+type Protocol struct{}
+
+var _ netlink.Protocol = (*Protocol)(nil)
+
+// NewProtocol creates a NETLINK_SOCK_DIAG netlink.Protocol.
+func NewProtocol(t *kernel.Task) (netlink.Protocol, *syserr.Error) {
+	return &Protocol{}, nil
+}
+
+// Protocol implements netlink.Protocol.Protocol.
+func (p *Protocol) Protocol() int {
+	return linux.NETLINK_SOCK_DIAG
+}
+
+// CanSend implements netlink.Protocol.CanSend.
+func (p *Protocol) CanSend() bool {
+	return false
+}
+
+// ProcessMessage implements netlink.Protocol.ProcessMessage.
+func (p *Protocol) ProcessMessage(ctx context.Context, s *netlink.Socket, msg *nlmsg.Message, ms *nlmsg.MessageSet) *syserr.Error {
+	hdr := msg.Header()
+
+	// All requests start with a 1 byte protocol family.
+	var family primitive.Uint8
+	if _, ok := msg.GetData(&family); !ok {
+		return syserr.ErrInvalidArgument
+	}
+
+	switch hdr.Type {
+	case linux.SOCK_DIAG_BY_FAMILY:
+	case linux.SOCK_DESTROY:
+		// See net/core/sock_diag.c:sock_diag_destroy.
+		creds := auth.CredentialsFromContext(ctx)
+		if !creds.HasCapability(linux.CAP_NET_ADMIN) {
+			return syserr.ErrNotPermitted
+		}
+	case linux.TCPDIAG_GETSOCK, linux.DCCPDIAG_GETSOCK:
+		// The legacy inet_diag interface isn't supported.
+		return syserr.ErrNotSupported
+	default:
+		return syserr.ErrInvalidArgument
+	}
+
+	switch family {
+	case linux.AF_INET, linux.AF_INET6:
+		return p.processInet(ctx, s, msg, ms)
+	case linux.AF_UNIX:
+		return p.processUnix(ctx, s, msg, ms)
+	default:
+		return syserr.ErrInvalidArgument
+	}
+}
+
+// entry is a socket of the network namespace of a NETLINK_SOCK_DIAG socket.
+type entry struct {
+	fd   *vfs.FileDescription
+	sock socket.Socket
+	diag socket.Diagnosable
+
+	// cookie identifies the socket, see sock_gen_cookie() in Linux.
+	cookie uint64
+}
+
+// matchesCookie returns true if the socket is identified by cookie, or if
+// cookie matches any socket.
+func (e *entry) matchesCookie(cookie [2]uint32) bool {
+	if cookie[0] == linux.INET_DIAG_NOCOOKIE && cookie[1] == linux.INET_DIAG_NOCOOKIE {
+		return true
+	}
+	return cookie == e.cookieID()
+}
+
+// cookieID returns the cookie of the socket, as reported to userspace.
+func (e *entry) cookieID() [2]uint32 {
+	return [2]uint32{uint32(e.cookie), uint32(e.cookie >> 32)}
+}
+
+// stat returns the inode number and owner of the socket, with the owner
+// mapped into the user namespace of ctx.
+func (e *entry) stat(ctx context.Context) (ino, uid uint32) {
+	stat, err := e.fd.Stat(ctx, vfs.StatOptions{Mask: linux.STATX_UID | linux.STATX_INO})
+	if err != nil {
+		log.Warningf("Failed to retrieve inode and uid for socket file: %v", err)
+		return 0, 0
+	}
+	if stat.Mask&linux.STATX_INO != 0 {
+		ino = uint32(stat.Ino)
+	}
+	if stat.Mask&linux.STATX_UID != 0 {
+		creds := auth.CredentialsFromContext(ctx)
+		uid = uint32(auth.KUID(stat.UID).In(creds.UserNamespace).OrOverflow())
+	}
+	return ino, uid
+}
+
+// listSockets returns the sockets of family in the network namespace ns. The
+// caller must release the returned entries with releaseSockets.
+func listSockets(ctx context.Context, ns *inet.Namespace, family int) []entry {
+	var entries []entry
+	for _, se := range kernel.KernelFromContext(ctx).ListSockets() {
+		fd := se.Sock
+		if !fd.TryIncRef() {
+			// Racing with socket destruction, this is ok.
+			continue
+		}
+		sock, ok := fd.Impl().(socket.Socket)
+		if !ok {
+			fd.DecRef(ctx)
+			continue
+		}
+		diag, ok := fd.Impl().(socket.Diagnosable)
+		if fa, _, _ := sock.Type(); !ok || fa != family || diag.NetworkNamespace() != ns {
+			fd.DecRef(ctx)
+			continue
+		}
+		entries = append(entries, entry{
+			fd:     fd,
+			sock:   sock,
+			diag:   diag,
+			cookie: se.ID,
+		})
+	}
+	return entries
+}
+
+// releaseSockets releases the entries returned by listSockets.
+func releaseSockets(ctx context.Context, entries []entry) {
+	for _, e := range entries {
+		e.fd.DecRef(ctx)
+	}
+}
+
+// processInet handles an inet_diag request. See net/ipv4/inet_diag.c.
+func (p *Protocol) processInet(ctx context.Context, s *netlink.Socket, msg *nlmsg.Message, ms *nlmsg.MessageSet) *syserr.Error {
+	hdr := msg.Header()
+	var req linux.InetDiagReqV2
+	if _, ok := msg.GetData(&req); !ok {
+		return syserr.ErrInvalidArgument
+	}
+	switch req.Protocol {
+	case linux.IPPROTO_TCP, linux.IPPROTO_UDP, linux.IPPROTO_RAW:
+	default:
+		return syserr.ErrNoFileOrDir
+	}
+
+	entries := listSockets(ctx, s.NetworkNamespace(), int(req.Family))
+	defer releaseSockets(ctx, entries)
+
+	if hdr.Type == linux.SOCK_DIAG_BY_FAMILY && hdr.Flags&linux.NLM_F_DUMP == linux.NLM_F_DUMP {
+		ms.Multi = true
+		for i := range entries {
+			e := &entries[i]
+			id, state, ok := inetSockInfo(ctx, e, req.Protocol)
+			if !ok || req.States&(1<<state) == 0 {
+				continue
+			}
+			// Like Linux, only report sockets that are hashed, i.e. TCP
+			// sockets that aren't closed and bound UDP sockets.
+			if (req.Protocol == linux.IPPROTO_TCP && state == linux.TCP_CLOSE) || (req.Protocol == linux.IPPROTO_UDP && id.Sport == 0) {
+				continue
+			}
+			putInetDiagMsg(ctx, ms, e, &req, id, state)
+		}
+		return nil
+	}
+
+	// Otherwise, the request is for a single socket.
+	var found *entry
+	for i := range entries {
+		e := &entries[i]
+		id, _, ok := inetSockInfo(ctx, e, req.Protocol)
+		if !ok || !matchesInetID(&id, &req.ID, req.Family) {
+			continue
+		}
+		if req.Protocol == linux.IPPROTO_RAW && req.Pad != 0 {
+			// For raw sockets, the pad field holds the protocol of the
+			// socket. See struct inet_diag_req_raw.
+			if _, _, protocol := e.sock.Type(); protocol != int(req.Pad) {
+				continue
+			}
+		}
+		found = e
+		break
+	}
+	if found == nil {
+		return syserr.ErrNoFileOrDir
+	}
+	if !found.matchesCookie(req.ID.Cookie) {
+		return syserr.ErrStaleFileHandle
+	}
+
+	if hdr.Type == linux.SOCK_DESTROY {
+		return found.diag.Destroy(ctx)
+	}
+	id, state, _ := inetSockInfo(ctx, found, req.Protocol)
+	putInetDiagMsg(ctx, ms, found, &req, id, state)
+	return nil
+}
+
+// inetSockInfo returns the identity and state of e, if it is a socket of the
+// given protocol.
+func inetSockInfo(ctx context.Context, e *entry, protocol uint8) (linux.InetDiagSockID, uint32, bool) {
+	var id linux.InetDiagSockID
+	switch protocol {
+	case linux.IPPROTO_TCP:
+		if !socket.IsTCP(e.sock) {
+			return id, 0, false
+		}
+	case linux.IPPROTO_UDP:
+		if !socket.IsUDP(e.sock) {
+			return id, 0, false
+		}
+	case linux.IPPROTO_RAW:
+		if !socket.IsRaw(e.sock) {
+			return id, 0, false
+		}
+	default:
+		return id, 0, false
+	}
+
+	if t := kernel.TaskFromContext(ctx); t != nil {
+		if local, _, err := e.sock.GetSockName(t); err == nil {
+			id.Sport, id.Src = inetAddr(local)
+		}
+		if remote, _, err := e.sock.GetPeerName(t); err == nil {
+			id.Dport, id.Dst = inetAddr(remote)
+		}
+	}
+	id.Cookie = e.cookieID()
+
+	state := e.sock.State()
+	if state == 0 {
+		// Raw sockets don't report their state, but are always either
+		// closed or connected.
+		state = linux.TCP_CLOSE
+		if id.Dport != 0 || id.Dst != ([16]byte{}) {
+			state = linux.TCP_ESTABLISHED
+		}
+	}
+	return id, state, true
+}
+
+// inetAddr returns the port and address of addr, with the port in network
+// byte order.
+func inetAddr(addr linux.SockAddr) (uint16, [16]byte) {
+	var a [16]byte
+	switch addr := addr.(type) {
+	case *linux.SockAddrInet:
+		copy(a[:], addr.Addr[:])
+		return addr.Port, a
+	case *linux.SockAddrInet6:
+		copy(a[:], addr.Addr[:])
+		return addr.Port, a
+	default:
+		return 0, a
+	}
+}
+
+// matchesInetID returns true if id is the socket requested by req.
+func matchesInetID(id, req *linux.InetDiagSockID, family uint8) bool {
+	addrLen := 16
+	if family == linux.AF_INET {
+		addrLen = 4
+	}
+	return id.Sport == req.Sport && id.Dport == req.Dport &&
+		bytes.Equal(id.Src[:addrLen], req.Src[:addrLen]) &&
+		bytes.Equal(id.Dst[:addrLen], req.Dst[:addrLen])
+}
+
+// putInetDiagMsg adds an inet_diag_msg describing e to ms, along with the
+// attributes requested by req.
+func putInetDiagMsg(ctx context.Context, ms *nlmsg.MessageSet, e *entry, req *linux.InetDiagReqV2, id linux.InetDiagSockID, state uint32) {
+	ino, uid := e.stat(ctx)
+	rqueue, wqueue := e.diag.QueueSizes()
+	if state == linux.TCP_LISTEN {
+		// Listening sockets report the accept queue instead, which isn't
+		// supported.
+		rqueue, wqueue = 0, 0
+	}
+
+	m := ms.AddMessage(linux.NetlinkMessageHeader{
+		Type: linux.SOCK_DIAG_BY_FAMILY,
+	})
+	m.Put(&linux.InetDiagMsg{
+		Family: req.Family,
+		State:  uint8(state),
+		ID:     id,
+		RQueue: rqueue,
+		WQueue: wqueue,
+		UID:    uid,
+		Inode:  ino,
+	})
+
+	if req.Ext&(1<<(linux.INET_DIAG_MEMINFO-1)) != 0 {
+		m.PutAttr(linux.INET_DIAG_MEMINFO, &linux.InetDiagMeminfo{
+			RMem: rqueue,
+			WMem: wqueue,
+		})
+	}
+
+	// Like Linux, TCP sockets report TCP_INFO and the congestion control
+	// algorithm.
+	t := kernel.TaskFromContext(ctx)
+	if t == nil || req.Protocol != linux.IPPROTO_TCP {
+		return
+	}
+	if req.Ext&(1<<(linux.INET_DIAG_INFO-1)) != 0 {
+		if info, err := e.sock.GetSockOpt(t, linux.SOL_TCP, linux.TCP_INFO, 0, linux.SizeOfTCPInfo); err == nil {
+			m.PutAttr(linux.INET_DIAG_INFO, info)
+		}
+	}
+	if req.Ext&(1<<(linux.INET_DIAG_CONG-1)) != 0 {
+		// This is Linux's net/tcp.h TCP_CA_NAME_MAX.
+		const tcpCANameMax = 16
+		if cong, err := e.sock.GetSockOpt(t, linux.SOL_TCP, linux.TCP_CONGESTION, 0, tcpCANameMax); err == nil {
+			if b, ok := cong.(*primitive.ByteSlice); ok {
+				m.PutAttrString(linux.INET_DIAG_CONG, string(bytes.TrimRight(*b, "\x00")))
+			}
+		}
+	}
+	// Like Linux, the information of the congestion control algorithm is
+	// reported with either extension, if the algorithm has any.
+	if req.Ext&(1<<(linux.INET_DIAG_INFO-1)|1<<(linux.INET_DIAG_VEGASINFO-1)) != 0 {
+		if cc, err := e.sock.GetSockOpt(t, linux.SOL_TCP, linux.TCP_CC_INFO, 0, (*linux.TCPBBRInfo)(nil).SizeBytes()); err == nil && cc.SizeBytes() != 0 {
+			m.PutAttr(linux.INET_DIAG_BBRINFO, cc)
+		}
+	}
+}
+
+// processUnix handles a unix_diag request. See net/unix/diag.c.
+func (p *Protocol) processUnix(ctx context.Context, s *netlink.Socket, msg *nlmsg.Message, ms *nlmsg.MessageSet) *syserr.Error {
+	hdr := msg.Header()
+	var req linux.UnixDiagReq
+	if _, ok := msg.GetData(&req); !ok {
+		return syserr.ErrInvalidArgument
+	}
+	if hdr.Type == linux.SOCK_DESTROY {
+		// Unix sockets can't be destroyed.
+		return syserr.ErrNotSupported
+	}
+
+	entries := listSockets(ctx, s.NetworkNamespace(), linux.AF_UNIX)
+	defer releaseSockets(ctx, entries)
+
+	if hdr.Flags&linux.NLM_F_DUMP == linux.NLM_F_DUMP {
+		ms.Multi = true
+		for i := range entries {
+			e := &entries[i]
+			if req.States&(1<<unixState(e)) == 0 {
+				continue
+			}
+			putUnixDiagMsg(ctx, ms, entries, e, &req)
+		}
+		return nil
+	}
+
+	// Otherwise, the request is for a single socket.
+	for i := range entries {
+		e := &entries[i]
+		if ino, _ := e.stat(ctx); ino != req.Ino {
+			continue
+		}
+		if !e.matchesCookie(req.Cookie) {
+			return syserr.ErrStaleFileHandle
+		}
+		putUnixDiagMsg(ctx, ms, entries, e, &req)
+		return nil
+	}
+	return syserr.ErrNoFileOrDir
+}
+
+// unixState returns the state of the unix socket e, as represented by Linux in
+// struct sock.
+func unixState(e *entry) uint32 {
+	ep := e.sock.(*unix.Socket).Endpoint()
+	switch {
+	case ep.SocketOptions().GetAcceptConn():
+		return linux.TCP_LISTEN
+	case ep.Peer() != nil:
+		return linux.TCP_ESTABLISHED
+	default:
+		return linux.TCP_CLOSE
+	}
+}
+
+// putUnixDiagMsg adds a unix_diag_msg describing e to ms, along with the
+// attributes requested by req. entries are the unix sockets of the network
+// namespace, which are used to find the peer of e.
+func putUnixDiagMsg(ctx context.Context, ms *nlmsg.MessageSet, entries []entry, e *entry, req *linux.UnixDiagReq) {
+	ep := e.sock.(*unix.Socket).Endpoint()
+	_, stype, _ := e.sock.Type()
+	ino, uid := e.stat(ctx)
+
+	m := ms.AddMessage(linux.NetlinkMessageHeader{
+		Type: linux.SOCK_DIAG_BY_FAMILY,
+	})
+	m.Put(&linux.UnixDiagMsg{
+		Family: linux.AF_UNIX,
+		Type:   uint8(stype),
+		State:  uint8(unixState(e)),
+		Ino:    ino,
+		Cookie: e.cookieID(),
+	})
+
+	if req.Show&linux.UDIAG_SHOW_NAME != 0 {
+		if addr, err := ep.GetLocalAddress(); err == nil && addr.Addr != "" {
+			// The name isn't NUL-terminated, like in Linux.
+			m.PutAttr(linux.UNIX_DIAG_NAME, primitive.AsByteSlice([]byte(addr.Addr)))
+		}
+	}
+	if req.Show&linux.UDIAG_SHOW_PEER != 0 {
+		if peer := ep.Peer(); peer != nil {
+			for i := range entries {
+				if entries[i].sock.(*unix.Socket).Endpoint() == peer {
+					peerIno, _ := entries[i].stat(ctx)
+					m.PutAttr(linux.UNIX_DIAG_PEER, primitive.AllocateUint32(peerIno))
+					break
+				}
+			}
+		}
+	}
+	if req.Show&linux.UDIAG_SHOW_RQLEN != 0 {
+		rqueue, wqueue := e.diag.QueueSizes()
+		m.PutAttr(linux.UNIX_DIAG_RQLEN, &linux.UnixDiagRQlen{
+			RQueue: rqueue,
+			WQueue: wqueue,
+		})
+	}
+	if req.Show&linux.UDIAG_SHOW_UID != 0 {
+		m.PutAttr(linux.UNIX_DIAG_UID, primitive.AllocateUint32(uid))
+	}
+}
+
+// init registers the NETLINK_SOCK_DIAG provider.
+func init() {
+	netlink.RegisterProvider(linux.NETLINK_SOCK_DIAG, NewProtocol)
+}
@@ -155,6 +155,11 @@ func (s *Socket) Stack() inet.Stack {
 	return s.netns.Stack()
 }
 
+// NetworkNamespace returns the network namespace associated with the socket.
+func (s *Socket) NetworkNamespace() *inet.Namespace {
+	return s.netns
+}
+
 // Release implements vfs.FileDescriptionImpl.Release.
 func (s *Socket) Release(ctx context.Context) {
 	t := kernel.TaskFromContext(ctx)
@@ -716,6 +721,25 @@ func (s *Socket) sendResponse(ctx context.Context, ms *nlmsg.MessageSet) *syserr
 	return nil
 }
 
+// SendNotification delivers data to userspace as a single datagram from the
+// kernel, bypassing the protocol implementation. It is used to deliver
+// mq_notify(2) SIGEV_THREAD notifications, similar to Linux's
+// netlink_sendskb().
+func (s *Socket) SendNotification(ctx context.Context, data []byte) error {
+	cms := transport.ControlMessages{
+		Credentials: kernelCreds,
+	}
+	_, notify, err := s.connection.Send(ctx, [][]byte{data}, cms, transport.Address{})
+	// If the buffer is full, the notification is dropped, just like Linux.
+	if err != nil && err != syserr.ErrWouldBlock {
+		return err.ToError()
+	}
+	if notify {
+		s.connection.SendNotify()
+	}
+	return nil
+}
+
 func dumpErrorMessage(hdr linux.NetlinkMessageHeader, ms *nlmsg.MessageSet, err *syserr.Error) {
 	m := ms.AddMessage(linux.NetlinkMessageHeader{
 		Type: linux.NLMSG_ERROR,
@@ -754,6 +778,13 @@ func (s *Socket) processMessages(ctx context.Context, buf []byte) *syserr.Error
 			continue
 		}
 
+		if bp, ok := s.protocol.(BatchProtocol); ok && bp.IsBatchBegin(hdr) {
+			// Like Linux, the batch extends to the end of the buffer, so any
+			// messages following the end of the batch are ignored. See
+			// net/netfilter/nfnetlink.c:nfnetlink_rcv_batch.
+			return s.processBatch(ctx, bp, msg, buf)
+		}
+
 		ms := nlmsg.NewMessageSet(s.portID, hdr.Seq)
 		if err := s.protocol.ProcessMessage(ctx, s, msg, ms); err != nil {
 			dumpErrorMessage(hdr, ms, err)
@@ -769,6 +800,51 @@ func (s *Socket) processMessages(ctx context.Context, buf []byte) *syserr.Error
 	return nil
 }
 
+// processBatch handles the batch begun by the message begin, whose remaining
+// messages are in buf, passing it to the protocol handler for final handling.
+func (s *Socket) processBatch(ctx context.Context, bp BatchProtocol, begin *nlmsg.Message, buf []byte) *syserr.Error {
+	var msgs []*nlmsg.Message
+	complete := false
+	for len(buf) > 0 {
+		msg, rest, ok := nlmsg.ParseMessage(buf)
+		if !ok {
+			break
+		}
+		buf = rest
+		if bp.IsBatchEnd(msg.Header()) {
+			complete = true
+			break
+		}
+		msgs = append(msgs, msg)
+	}
+
+	beginErr, errs := bp.ProcessBatch(ctx, s, begin, msgs, complete)
+	if beginErr != nil {
+		hdr := begin.Header()
+		ms := nlmsg.NewMessageSet(s.portID, hdr.Seq)
+		dumpErrorMessage(hdr, ms, beginErr)
+		return s.sendResponse(ctx, ms)
+	}
+
+	// Each error or acknowledgement is sent in its own datagram, like Linux.
+	// See net/netfilter/nfnetlink.c:nfnl_err_deliver.
+	for i, err := range errs {
+		hdr := msgs[i].Header()
+		ms := nlmsg.NewMessageSet(s.portID, hdr.Seq)
+		if err != nil {
+			dumpErrorMessage(hdr, ms, err)
+		} else if hdr.Flags&linux.NLM_F_ACK == linux.NLM_F_ACK {
+			dumpAckMessage(hdr, ms)
+		} else {
+			continue
+		}
+		if err := s.sendResponse(ctx, ms); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // sendMsg is the core of message send, used for SendMsg and Write.
 func (s *Socket) sendMsg(ctx context.Context, src usermem.IOSequence, to []byte, flags int, controlMessages socket.ControlMessages) (int, *syserr.Error) {
 	dstPort := int32(0)
@@ -0,0 +1,114 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netstack
+
+import (
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/bpf"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/marshal"
+	"gvisor.dev/gvisor/pkg/marshal/primitive"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/syserr"
+	"gvisor.dev/gvisor/pkg/tcpip"
+)
+
+// sizeOfSockFprog is the size of struct sock_fprog on 64-bit architectures.
+const sizeOfSockFprog = 16
+
+// socketFilter is a classic BPF program attached with SO_ATTACH_FILTER.
+//
+// Loads from the special offsets of Linux's ancillary data extensions are out
+// of bounds, so the packets they are used on are dropped.
+//
+// +stateify savable
+type socketFilter struct {
+	// insns are the instructions of the program, as returned by
+	// SO_GET_FILTER.
+	insns []linux.BPFInstruction
+
+	prog bpf.Program
+}
+
+// Run implements tcpip.SocketFilter.Run.
+func (f *socketFilter) Run(pkt []byte) uint32 {
+	n, err := bpf.Exec[bpf.BigEndian](f.prog, bpf.Input(pkt))
+	if err != nil {
+		// Like in Linux, packets the filter fails on are dropped.
+		return 0
+	}
+	return n
+}
+
+// newSocketFilter returns the filter described by the struct sock_fprog in
+// optVal.
+func newSocketFilter(t *kernel.Task, optVal []byte) (*socketFilter, *syserr.Error) {
+	if len(optVal) < sizeOfSockFprog {
+		return nil, syserr.ErrInvalidArgument
+	}
+	n := hostarch.ByteOrder.Uint16(optVal[0:])
+	addr := hostarch.Addr(hostarch.ByteOrder.Uint64(optVal[8:]))
+	if n == 0 || n > bpf.MaxInstructions {
+		return nil, syserr.ErrInvalidArgument
+	}
+
+	insns := make([]linux.BPFInstruction, n)
+	if _, err := linux.CopyBPFInstructionSliceIn(t, addr, insns); err != nil {
+		return nil, syserr.FromError(err)
+	}
+	compiled := make([]bpf.Instruction, n)
+	for i, ins := range insns {
+		compiled[i] = bpf.Instruction(ins)
+	}
+	prog, err := bpf.Compile(compiled, false /* optimize */)
+	if err != nil {
+		return nil, syserr.ErrInvalidArgument
+	}
+	return &socketFilter{insns: insns, prog: prog}, nil
+}
+
+// getSocketFilter returns the instructions of the filter attached to ep for
+// SO_GET_FILTER. Like in Linux, outLen counts instructions rather than bytes,
+// and no instruction is copied if it is zero.
+func getSocketFilter(ep commonEndpoint, outLen int) (marshal.Marshallable, *syserr.Error) {
+	f, ok := ep.SocketOptions().GetFilter().(*socketFilter)
+	if !ok {
+		return &socketFilterValue{}, nil
+	}
+	v := &socketFilterValue{len: len(f.insns)}
+	if outLen == 0 {
+		return v, nil
+	}
+	if outLen < len(f.insns) {
+		return nil, syserr.ErrInvalidArgument
+	}
+	v.ByteSlice = make([]byte, len(f.insns)*(*linux.BPFInstruction)(nil).SizeBytes())
+	linux.MarshalUnsafeBPFInstructionSlice(f.insns, v.ByteSlice)
+	return v, nil
+}
+
+// socketFilterValue is the value returned by SO_GET_FILTER, whose length is
+// the number of instructions of the filter.
+type socketFilterValue struct {
+	primitive.ByteSlice
+	len int
+}
+
+// SizeBytes implements marshal.Marshallable.SizeBytes.
+func (v *socketFilterValue) SizeBytes() int {
+	return v.len
+}
+
+var _ tcpip.SocketFilter = (*socketFilter)(nil)
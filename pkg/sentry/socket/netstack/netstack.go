@@ -38,6 +38,7 @@ import (
 	"google.golang.org/protobuf/proto"
 	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/abi/linux/errno"
+	"gvisor.dev/gvisor/pkg/buffer"
 	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/eventchannel"
@@ -338,6 +339,9 @@ var Metrics = tcpip.Stats{
 		SpuriousRecovery:                   mustCreateMetric("/netstack/tcp/spurious_recovery", "Number of times the connection entered loss recovery spuriously."),
 		SpuriousRTORecovery:                mustCreateMetric("/netstack/tcp/spurious_rto_recovery", "Number of times the connection entered RTO spuriously."),
 		ForwardMaxInFlightDrop:             mustCreateMetric("/netstack/tcp/forward_max_in_flight_drop", "Number of connection requests dropped due to exceeding in-flight limit."),
+		MD5NotFound:                        mustCreateMetric("/netstack/tcp/md5_not_found", "Number of segments dropped because they didn't carry the expected TCP MD5 signature."),
+		MD5Unexpected:                      mustCreateMetric("/netstack/tcp/md5_unexpected", "Number of segments dropped because they carried an unexpected TCP MD5 signature."),
+		MD5Failure:                         mustCreateMetric("/netstack/tcp/md5_failure", "Number of segments dropped because their TCP MD5 signature didn't match."),
 	},
 	UDP: tcpip.UDPStats{
 		PacketsReceived:          mustCreateMetric("/netstack/udp/packets_received", "Number of UDP datagrams received via HandlePacket."),
@@ -436,9 +440,15 @@ type sock struct {
 	// TODO(b/153685824): Move this to SocketOptions.
 	// sockOptInq corresponds to TCP_INQ.
 	sockOptInq bool
+
+	// sockOptTimestampingNew is true if SO_TIMESTAMPING was last set with
+	// SO_TIMESTAMPING_NEW, in which case timestamps are reported with
+	// SCM_TIMESTAMPING_NEW. It is protected by readMu.
+	sockOptTimestampingNew bool
 }
 
 var _ = socket.Socket(&sock{})
+var _ = socket.Diagnosable(&sock{})
 
 // New creates a new endpoint socket.
 func New(t *kernel.Task, family int, skType linux.SockType, protocol int, queue *waiter.Queue, endpoint tcpip.Endpoint) (*vfs.FileDescription, *syserr.Error) {
@@ -559,6 +569,48 @@ func (s *sock) Write(ctx context.Context, src usermem.IOSequence, opts vfs.Write
 	return n, nil
 }
 
+// ReadToViewWriter implements pipe.ViewReader.ReadToViewWriter. For stream
+// sockets, received packet buffers are passed to w by reference, so splice(2)
+// from the socket to a pipe does not copy data.
+func (s *sock) ReadToViewWriter(ctx context.Context, w buffer.ViewWriter) (int64, error) {
+	if s.isPacketBased() {
+		return 0, linuxerr.EOPNOTSUPP
+	}
+
+	s.readMu.Lock()
+	defer s.readMu.Unlock()
+	res, terr := s.Endpoint.Read(w, tcpip.ReadOptions{})
+	if err := syserr.TranslateNetstackError(terr); err != nil {
+		if err == syserr.ErrWouldBlock {
+			return 0, linuxerr.ErrWouldBlock
+		}
+		return 0, err.ToError()
+	}
+	if n := res.Count; n != 0 {
+		s.Endpoint.ModerateRecvBuf(n)
+	}
+	return int64(res.Count), nil
+}
+
+// WriteFromBuffer implements pipe.BufferWriter.WriteFromBuffer. TCP sockets
+// take references on buf's data, so splice(2) from a pipe to the socket does
+// not copy data.
+func (s *sock) WriteFromBuffer(ctx context.Context, buf *buffer.Buffer) (int64, error) {
+	size := buf.Size()
+	r := buf.AsBufferReader()
+	n, err := s.Endpoint.Write(&r, tcpip.WriteOptions{})
+	if _, ok := err.(*tcpip.ErrWouldBlock); ok {
+		return 0, linuxerr.ErrWouldBlock
+	}
+	if err != nil {
+		return 0, syserr.TranslateNetstackError(err).ToError()
+	}
+	if n < size {
+		return n, linuxerr.ErrWouldBlock
+	}
+	return n, nil
+}
+
 // Accept implements the linux syscall accept(2) for sockets backed by
 // tcpip.Endpoint.
 func (s *sock) Accept(t *kernel.Task, peerRequested bool, flags int, blocking bool) (int32, linux.SockAddr, uint32, *syserr.Error) {
@@ -626,6 +678,9 @@ func (s *sock) GetSockOpt(t *kernel.Task, level, name int, outPtr hostarch.Addr,
 		}
 		return &val, nil
 	}
+	if level == linux.SOL_SOCKET && (name == linux.SO_TIMESTAMPING_OLD || name == linux.SO_TIMESTAMPING_NEW) {
+		return s.getTimestamping(name == linux.SO_TIMESTAMPING_NEW, outLen)
+	}
 	if level == linux.SOL_TCP && name == linux.TCP_INQ {
 		if outLen < sizeOfInt32 {
 			return nil, syserr.ErrInvalidArgument
@@ -659,6 +714,9 @@ func (s *sock) SetSockOpt(t *kernel.Task, level int, name int, optVal []byte) *s
 		s.sockOptTimestamp = hostarch.ByteOrder.Uint32(optVal) != 0
 		return nil
 	}
+	if level == linux.SOL_SOCKET && (name == linux.SO_TIMESTAMPING_OLD || name == linux.SO_TIMESTAMPING_NEW) {
+		return s.setTimestamping(name == linux.SO_TIMESTAMPING_NEW, optVal)
+	}
 	if level == linux.SOL_TCP && name == linux.TCP_INQ {
 		if len(optVal) < sizeOfInt32 {
 			return syserr.ErrInvalidArgument
@@ -672,6 +730,79 @@ func (s *sock) SetSockOpt(t *kernel.Task, level int, name int, optVal []byte) *s
 	return SetSockOpt(t, s, s.Endpoint, level, name, optVal)
 }
 
+// getTimestamping implements getsockopt(SO_TIMESTAMPING_OLD) and
+// getsockopt(SO_TIMESTAMPING_NEW), which returns a struct so_timestamping, or
+// only its flags if there is no room for it.
+func (s *sock) getTimestamping(isNew bool, outLen int) (marshal.Marshallable, *syserr.Error) {
+	s.readMu.Lock()
+	defer s.readMu.Unlock()
+	// Like in Linux, SO_TIMESTAMPING_NEW can only be read if it was set.
+	if isNew && !s.sockOptTimestampingNew {
+		return nil, syserr.ErrProtocolNotAvailable
+	}
+	flags := int32(s.Endpoint.SocketOptions().GetTimestamping())
+	switch {
+	case outLen >= linux.SizeOfSockTimestamping:
+		return &linux.SockTimestamping{Flags: flags}, nil
+	case outLen >= sizeOfInt32:
+		v := primitive.Int32(flags)
+		return &v, nil
+	default:
+		return nil, syserr.ErrInvalidArgument
+	}
+}
+
+// setTimestamping implements setsockopt(SO_TIMESTAMPING_OLD) and
+// setsockopt(SO_TIMESTAMPING_NEW), which take either the flags or a struct
+// so_timestamping.
+func (s *sock) setTimestamping(isNew bool, optVal []byte) *syserr.Error {
+	var v linux.SockTimestamping
+	switch {
+	case len(optVal) == linux.SizeOfSockTimestamping:
+		v.UnmarshalUnsafe(optVal)
+	case len(optVal) >= sizeOfInt32:
+		v.Flags = int32(hostarch.ByteOrder.Uint32(optVal))
+	default:
+		return syserr.ErrInvalidArgument
+	}
+	if v.Flags&^linux.SOF_TIMESTAMPING_MASK != 0 {
+		return syserr.ErrInvalidArgument
+	}
+	// There are no PTP hardware clocks to bind to.
+	if v.Flags&linux.SOF_TIMESTAMPING_BIND_PHC != 0 {
+		return syserr.ErrNotSupported
+	}
+
+	s.readMu.Lock()
+	defer s.readMu.Unlock()
+	if err := s.Endpoint.SocketOptions().SetTimestamping(tcpip.TimestampingFlags(v.Flags)); err != nil {
+		return syserr.TranslateNetstackError(err)
+	}
+	s.sockOptTimestampingNew = isNew
+	return nil
+}
+
+// txTime returns the transmit time set with SCM_TXTIME, in nanoseconds of the
+// clock of SO_TXTIME, as a time of the clock of the stack.
+func (s *sock) txTime(t *kernel.Task, ns uint64) (tcpip.MonotonicTime, *syserr.Error) {
+	opt := s.Endpoint.SocketOptions().GetTXTime()
+	if !opt.Enabled {
+		return tcpip.MonotonicTime{}, syserr.ErrInvalidArgument
+	}
+	// The stack's monotonic clock is the kernel's CLOCK_MONOTONIC.
+	monotonic := t.Kernel().MonotonicClock().Now().Nanoseconds()
+	at := int64(ns)
+	switch opt.ClockID {
+	case linux.CLOCK_REALTIME:
+		at += monotonic - t.Kernel().RealtimeClock().Now().Nanoseconds()
+	}
+	// In deadline mode, packets are sent as soon as possible.
+	if opt.DeadlineMode || at <= monotonic {
+		return tcpip.MonotonicTime{}, nil
+	}
+	return tcpip.MonotonicTime{}.Add(time.Duration(at)), nil
+}
+
 // minSockAddrLen returns the minimum length in bytes of a socket address for
 // the socket's family.
 func (s *sock) minSockAddrLen() int {
@@ -699,7 +830,13 @@ func (s *sock) isPacketBased() bool {
 
 // Readiness returns a mask of ready events for socket s.
 func (s *sock) Readiness(mask waiter.EventMask) waiter.EventMask {
-	return s.Endpoint.Readiness(mask)
+	r := s.Endpoint.Readiness(mask)
+	// Like in Linux, a non-empty error queue makes the socket report an error,
+	// which is how zero copy completions are waited for.
+	if mask&waiter.EventErr != 0 && s.Endpoint.SocketOptions().PeekErr() != nil {
+		r |= waiter.EventErr
+	}
+	return r
 }
 
 // checkFamily returns true iff the specified address family may be used with
@@ -1143,6 +1280,40 @@ func getSockOptSocket(t *kernel.Task, s socket.Socket, ep commonEndpoint, family
 
 		v := primitive.Int32(ep.SocketOptions().GetRcvlowat())
 		return &v, nil
+
+	case linux.SO_GET_FILTER:
+		return getSocketFilter(ep, outLen)
+
+	case linux.SO_LOCK_FILTER:
+		if outLen < sizeOfInt32 {
+			return nil, syserr.ErrInvalidArgument
+		}
+
+		v := primitive.Int32(boolToInt32(ep.SocketOptions().GetLockFilter()))
+		return &v, nil
+
+	case linux.SO_ZEROCOPY:
+		if outLen < sizeOfInt32 {
+			return nil, syserr.ErrInvalidArgument
+		}
+
+		v := primitive.Int32(boolToInt32(ep.SocketOptions().GetZeroCopy()))
+		return &v, nil
+
+	case linux.SO_TXTIME:
+		if outLen < linux.SizeOfSockTxtime {
+			return nil, syserr.ErrInvalidArgument
+		}
+
+		txTime := ep.SocketOptions().GetTXTime()
+		v := linux.SockTxtime{ClockID: txTime.ClockID}
+		if txTime.DeadlineMode {
+			v.Flags |= linux.SOF_TXTIME_DEADLINE_MODE
+		}
+		if txTime.ReportErrors {
+			v.Flags |= linux.SOF_TXTIME_REPORT_ERRORS
+		}
+		return &v, nil
 	default:
 		if v, err, handled := getSockOptSocketCustom(t, s, ep, name, outLen); handled {
 			return v, err
@@ -1249,12 +1420,64 @@ func getSockOptTCP(t *kernel.Task, s socket.Socket, ep commonEndpoint, name, out
 		}
 
 		info := linux.TCPInfo{
-			State:       uint8(v.State),
-			RTO:         uint32(v.RTO / time.Microsecond),
-			RTT:         uint32(v.RTT / time.Microsecond),
-			RTTVar:      uint32(v.RTTVar / time.Microsecond),
-			SndSsthresh: v.SndSsthresh,
-			SndCwnd:     v.SndCwnd,
+			State:         uint8(v.State),
+			Retransmits:   uint8(min(v.Backoff, math.MaxUint8)),
+			Probes:        uint8(min(v.Probes, math.MaxUint8)),
+			Backoff:       uint8(min(v.Backoff, math.MaxUint8)),
+			WindowScale:   v.SndWndScale&0xf | v.RcvWndScale<<4,
+			RTO:           uint32(v.RTO / time.Microsecond),
+			SndMss:        v.SndMSS,
+			RcvMss:        v.RcvMSS,
+			Unacked:       v.Unacked,
+			Sacked:        v.Sacked,
+			Lost:          v.Lost,
+			Retrans:       v.Retrans,
+			LastDataSent:  uint32(v.LastDataSent / time.Millisecond),
+			LastDataRecv:  uint32(v.LastDataRecv / time.Millisecond),
+			LastAckRecv:   uint32(v.LastAckRecv / time.Millisecond),
+			RTT:           uint32(v.RTT / time.Microsecond),
+			RTTVar:        uint32(v.RTTVar / time.Microsecond),
+			SndSsthresh:   v.SndSsthresh,
+			SndCwnd:       v.SndCwnd,
+			Advmss:        v.RcvMSS,
+			Reordering:    v.Reordering,
+			TotalRetrans:  v.TotalRetrans,
+			BytesAcked:    v.BytesAcked,
+			BytesReceived: v.BytesReceived,
+			SegsOut:       v.SegsOut,
+			SegsIn:        v.SegsIn,
+			NotSentBytes:  v.NotSentBytes,
+			MinRTT:        uint32(v.MinRTT / time.Microsecond),
+			DataSegsIn:    v.DataSegsIn,
+			DataSegsOut:   v.DataSegsOut,
+			DeliveryRate:  v.DeliveryRate,
+			PacingRate:    v.PacingRate,
+			Delivered:     v.Delivered,
+			BytesSent:     v.BytesSent,
+			BytesRetrans:  v.BytesRetrans,
+			DSACKDups:     v.DSACKDups,
+			// In netstack reordering is only detected when RACK is
+			// enabled, which is different than Linux where
+			// reordSeen is not specific to RACK.
+			ReordSeen: v.ReorderCount,
+		}
+		if v.TimestampsEnabled {
+			info.Options |= linux.TCPI_OPT_TIMESTAMPS
+		}
+		if v.SACKPermitted {
+			info.Options |= linux.TCPI_OPT_SACK
+		}
+		if v.ECN {
+			info.Options |= linux.TCPI_OPT_ECN
+		}
+		if v.ECNSeen {
+			info.Options |= linux.TCPI_OPT_ECN_SEEN
+		}
+		if v.SndWndScale != 0 || v.RcvWndScale != 0 {
+			info.Options |= linux.TCPI_OPT_WSCALE
+		}
+		if v.DeliveryRateAppLimited {
+			info.DeliveryRateAppLimited = 1
 		}
 		switch v.CcState {
 		case tcpip.RTORecovery:
@@ -1267,13 +1490,10 @@ func getSockOptTCP(t *kernel.Task, s socket.Socket, ep commonEndpoint, name, out
 			info.CaState = linux.TCP_CA_Open
 		}
 
-		// In netstack reorderSeen is updated only when RACK is enabled.
-		// We only track whether the reordering is seen, which is
-		// different than Linux where reorderSeen is not specific to
-		// RACK and is incremented when a reordering event is seen.
-		if v.ReorderSeen {
-			info.ReordSeen = 1
+		if v.SynDataAcked {
+			info.Options |= linux.TCPI_OPT_SYN_DATA
 		}
+		info.DeliveryRateAppLimited |= uint8(v.FastOpenClientFail) << 1
 
 		// Linux truncates the output binary to outLen.
 		buf := t.CopyScratchBuffer(info.SizeBytes())
@@ -1284,8 +1504,36 @@ func getSockOptTCP(t *kernel.Task, s socket.Socket, ep commonEndpoint, name, out
 		bufP := primitive.ByteSlice(buf)
 		return &bufP, nil
 
-	case linux.TCP_CC_INFO,
-		linux.TCP_NOTSENT_LOWAT,
+	case linux.TCP_CC_INFO:
+		var v tcpip.TCPCCInfoOption
+		if err := ep.GetSockOpt(&v); err != nil {
+			return nil, syserr.TranslateNetstackError(err)
+		}
+
+		// Like in Linux, only BBR reports information; CUBIC and
+		// NewReno report none, so the output is empty.
+		if v.Algorithm != "bbr" {
+			var buf primitive.ByteSlice
+			return &buf, nil
+		}
+		info := linux.TCPBBRInfo{
+			BwLo:       uint32(v.BBR.Bandwidth),
+			BwHi:       uint32(v.BBR.Bandwidth >> 32),
+			MinRTT:     uint32(v.BBR.MinRTT / time.Microsecond),
+			PacingGain: v.BBR.PacingGain,
+			CwndGain:   v.BBR.CwndGain,
+		}
+
+		// Linux truncates the output binary to outLen.
+		buf := t.CopyScratchBuffer(info.SizeBytes())
+		info.MarshalUnsafe(buf)
+		if len(buf) > outLen {
+			buf = buf[:outLen]
+		}
+		bufP := primitive.ByteSlice(buf)
+		return &bufP, nil
+
+	case linux.TCP_NOTSENT_LOWAT,
 		linux.TCP_ZEROCOPY_RECEIVE:
 
 		// Not supported.
@@ -1369,6 +1617,62 @@ func getSockOptTCP(t *kernel.Task, s socket.Socket, ep commonEndpoint, name, out
 		}
 		vP := primitive.Int32(v)
 		return &vP, nil
+
+	case linux.TCP_FASTOPEN:
+		if outLen < sizeOfInt32 {
+			return nil, syserr.ErrInvalidArgument
+		}
+
+		v, err := ep.GetSockOptInt(tcpip.TCPFastOpenOption)
+		if err != nil {
+			return nil, syserr.TranslateNetstackError(err)
+		}
+		vP := primitive.Int32(v)
+		return &vP, nil
+
+	case linux.TCP_FASTOPEN_CONNECT:
+		if outLen < sizeOfInt32 {
+			return nil, syserr.ErrInvalidArgument
+		}
+
+		v, err := ep.GetSockOptInt(tcpip.TCPFastOpenConnectOption)
+		if err != nil {
+			return nil, syserr.TranslateNetstackError(err)
+		}
+		vP := primitive.Int32(v)
+		return &vP, nil
+
+	case linux.TCP_FASTOPEN_NO_COOKIE:
+		if outLen < sizeOfInt32 {
+			return nil, syserr.ErrInvalidArgument
+		}
+
+		v, err := ep.GetSockOptInt(tcpip.TCPFastOpenNoCookieOption)
+		if err != nil {
+			return nil, syserr.TranslateNetstackError(err)
+		}
+		vP := primitive.Int32(v)
+		return &vP, nil
+
+	case linux.TCP_FASTOPEN_KEY:
+		if outLen < 0 {
+			return nil, syserr.ErrInvalidArgument
+		}
+
+		var v tcpip.TCPFastOpenKeyOption
+		if err := ep.GetSockOpt(&v); err != nil {
+			return nil, syserr.TranslateNetstackError(err)
+		}
+		key := append([]byte{}, v.Primary[:]...)
+		if v.HasBackup {
+			key = append(key, v.Backup[:]...)
+		}
+		// Linux truncates the key to outLen.
+		if len(key) > outLen {
+			key = key[:outLen]
+		}
+		keyP := primitive.ByteSlice(key)
+		return &keyP, nil
 	}
 	return nil, syserr.ErrProtocolNotAvailable
 }
@@ -2139,10 +2443,87 @@ func setSockOptSocket(t *kernel.Task, s socket.Socket, ep commonEndpoint, name i
 		})
 		return nil
 
+	case linux.SO_ATTACH_FILTER:
+		f, err := newSocketFilter(t, optVal)
+		if err != nil {
+			return err
+		}
+		return syserr.TranslateNetstackError(ep.SocketOptions().SetFilter(f))
+
+	case linux.SO_ATTACH_BPF:
+		if len(optVal) < sizeOfInt32 {
+			return syserr.ErrInvalidArgument
+		}
+
+		// There are no eBPF programs to attach.
+		fd := int32(hostarch.ByteOrder.Uint32(optVal))
+		file := t.GetFile(fd)
+		if file == nil {
+			return syserr.ErrBadFD
+		}
+		file.DecRef(t)
+		return syserr.ErrInvalidArgument
+
 	case linux.SO_DETACH_FILTER:
 		// optval is ignored.
-		var v tcpip.SocketDetachFilterOption
-		return syserr.TranslateNetstackError(ep.SetSockOpt(&v))
+		return syserr.TranslateNetstackError(ep.SocketOptions().DetachFilter())
+
+	case linux.SO_LOCK_FILTER:
+		if len(optVal) < sizeOfInt32 {
+			return syserr.ErrInvalidArgument
+		}
+
+		v := hostarch.ByteOrder.Uint32(optVal)
+		return syserr.TranslateNetstackError(ep.SocketOptions().SetLockFilter(v != 0))
+
+	case linux.SO_ZEROCOPY:
+		// Like in Linux, only TCP and UDP sockets support zero copy sends.
+		if !socket.IsTCP(s) && !socket.IsUDP(s) {
+			return syserr.ErrNotSupported
+		}
+		if len(optVal) < sizeOfInt32 {
+			return syserr.ErrInvalidArgument
+		}
+
+		v := int32(hostarch.ByteOrder.Uint32(optVal))
+		if v < 0 || v > 1 {
+			return syserr.ErrInvalidArgument
+		}
+		ep.SocketOptions().SetZeroCopy(v != 0)
+		return nil
+
+	case linux.SO_TXTIME:
+		if len(optVal) != linux.SizeOfSockTxtime {
+			return syserr.ErrInvalidArgument
+		}
+
+		var v linux.SockTxtime
+		v.UnmarshalUnsafe(optVal)
+		if v.Flags&^linux.SOF_TXTIME_FLAGS_MASK != 0 {
+			return syserr.ErrInvalidArgument
+		}
+		// Like in Linux, only CLOCK_MONOTONIC is allowed without
+		// CAP_NET_ADMIN.
+		if v.ClockID != linux.CLOCK_MONOTONIC {
+			if creds := auth.CredentialsFromContext(t); !creds.HasCapability(linux.CAP_NET_ADMIN) {
+				return syserr.ErrNotPermitted
+			}
+		}
+		switch v.ClockID {
+		case linux.CLOCK_MONOTONIC, linux.CLOCK_REALTIME, linux.CLOCK_BOOTTIME:
+		default:
+			// Transmit times can't be honored for other clocks.
+			// CLOCK_TAI is rejected too since it is unimplemented, so
+			// its offset from CLOCK_REALTIME is unknown.
+			return syserr.ErrInvalidArgument
+		}
+		ep.SocketOptions().SetTXTime(tcpip.TXTimeOption{
+			Enabled:      true,
+			ClockID:      v.ClockID,
+			DeadlineMode: v.Flags&linux.SOF_TXTIME_DEADLINE_MODE != 0,
+			ReportErrors: v.Flags&linux.SOF_TXTIME_REPORT_ERRORS != 0,
+		})
+		return nil
 
 	// TODO(b/226603727): Add support for SO_RCVLOWAT option. For now, only
 	// the unsupported syscall message is removed.
@@ -2162,7 +2543,6 @@ func setSockOptSocket(t *kernel.Task, s socket.Socket, ep commonEndpoint, name i
 		linux.SO_BSDCOMPAT,
 		linux.SO_PEERCRED,
 		linux.SO_SNDLOWAT,
-		linux.SO_ATTACH_FILTER,
 		linux.SO_PEERNAME,
 		linux.SO_TIMESTAMP,
 		linux.SO_ACCEPTCONN,
@@ -2171,20 +2551,17 @@ func setSockOptSocket(t *kernel.Task, s socket.Socket, ep commonEndpoint, name i
 		linux.SO_PASSSEC,
 		linux.SO_TIMESTAMPNS,
 		linux.SO_MARK,
-		linux.SO_TIMESTAMPING,
 		linux.SO_PROTOCOL,
 		linux.SO_DOMAIN,
 		linux.SO_RXQ_OVFL,
 		linux.SO_WIFI_STATUS,
 		linux.SO_PEEK_OFF,
 		linux.SO_NOFCS,
-		linux.SO_LOCK_FILTER,
 		linux.SO_SELECT_ERR_QUEUE,
 		linux.SO_BUSY_POLL,
 		linux.SO_MAX_PACING_RATE,
 		linux.SO_BPF_EXTENSIONS,
 		linux.SO_INCOMING_CPU,
-		linux.SO_ATTACH_BPF,
 		linux.SO_ATTACH_REUSEPORT_CBPF,
 		linux.SO_ATTACH_REUSEPORT_EBPF,
 		linux.SO_CNX_ADVICE,
@@ -2192,12 +2569,9 @@ func setSockOptSocket(t *kernel.Task, s socket.Socket, ep commonEndpoint, name i
 		linux.SO_INCOMING_NAPI_ID,
 		linux.SO_COOKIE,
 		linux.SO_PEERGROUPS,
-		linux.SO_ZEROCOPY,
-		linux.SO_TXTIME,
 		linux.SO_BINDTOIFINDEX,
 		linux.SO_TIMESTAMP_NEW,
 		linux.SO_TIMESTAMPNS_NEW,
-		linux.SO_TIMESTAMPING_NEW,
 		linux.SO_RCVTIMEO_NEW,
 		linux.SO_SNDTIMEO_NEW,
 		linux.SO_DETACH_REUSEPORT_BPF,
@@ -2357,30 +2731,57 @@ func setSockOptTCP(t *kernel.Task, s socket.Socket, ep commonEndpoint, name int,
 
 		return syserr.TranslateNetstackError(ep.SetSockOptInt(tcpip.TCPWindowClampOption, int(v)))
 
-	case linux.TCP_INFO,
-		linux.TCP_MD5SIG,
-		linux.TCP_THIN_LINEAR_TIMEOUTS,
+	case linux.TCP_FASTOPEN:
+		if len(optVal) < sizeOfInt32 {
+			return syserr.ErrInvalidArgument
+		}
+		v := int32(hostarch.ByteOrder.Uint32(optVal))
+
+		return syserr.TranslateNetstackError(ep.SetSockOptInt(tcpip.TCPFastOpenOption, int(v)))
+
+	case linux.TCP_FASTOPEN_CONNECT:
+		if len(optVal) < sizeOfInt32 {
+			return syserr.ErrInvalidArgument
+		}
+		v := int32(hostarch.ByteOrder.Uint32(optVal))
+
+		return syserr.TranslateNetstackError(ep.SetSockOptInt(tcpip.TCPFastOpenConnectOption, int(v)))
+
+	case linux.TCP_FASTOPEN_NO_COOKIE:
+		if len(optVal) < sizeOfInt32 {
+			return syserr.ErrInvalidArgument
+		}
+		v := int32(hostarch.ByteOrder.Uint32(optVal))
+
+		return syserr.TranslateNetstackError(ep.SetSockOptInt(tcpip.TCPFastOpenNoCookieOption, int(v)))
+
+	case linux.TCP_FASTOPEN_KEY:
+		// The key may be followed by a backup key.
+		if len(optVal) != linux.TCPFastOpenKeyLength && len(optVal) != 2*linux.TCPFastOpenKeyLength {
+			return syserr.ErrInvalidArgument
+		}
+		var opt tcpip.TCPFastOpenKeyOption
+		copy(opt.Primary[:], optVal)
+		if len(optVal) > linux.TCPFastOpenKeyLength {
+			copy(opt.Backup[:], optVal[linux.TCPFastOpenKeyLength:])
+			opt.HasBackup = true
+		}
+		return syserr.TranslateNetstackError(ep.SetSockOpt(&opt))
+
+	case linux.TCP_MD5SIG, linux.TCP_MD5SIG_EXT:
+		opt, err := copyInTCPMD5Sig(s, name, optVal)
+		if err != nil {
+			return err
+		}
+		return syserr.TranslateNetstackError(ep.SetSockOpt(opt))
+
+	case linux.TCP_THIN_LINEAR_TIMEOUTS,
 		linux.TCP_THIN_DUPACK,
-		linux.TCP_REPAIR,
-		linux.TCP_REPAIR_QUEUE,
-		linux.TCP_QUEUE_SEQ,
-		linux.TCP_REPAIR_OPTIONS,
-		linux.TCP_FASTOPEN,
-		linux.TCP_TIMESTAMP,
 		linux.TCP_NOTSENT_LOWAT,
-		linux.TCP_CC_INFO,
-		linux.TCP_SAVE_SYN,
-		linux.TCP_SAVED_SYN,
-		linux.TCP_REPAIR_WINDOW,
-		linux.TCP_FASTOPEN_CONNECT,
-		linux.TCP_ULP,
-		linux.TCP_MD5SIG_EXT,
-		linux.TCP_FASTOPEN_KEY,
-		linux.TCP_FASTOPEN_NO_COOKIE,
-		linux.TCP_ZEROCOPY_RECEIVE,
 		linux.TCP_INQ,
 		linux.TCP_TX_DELAY:
-		// Not supported.
+		// Not supported, but these are only hints, so they are
+		// accepted and ignored.
 		incrementBadSetSocketOptionMetric(t, &socketLevelTCPFieldValue, name)
 		return nil
 	default:
@@ -2388,8 +2789,61 @@ func setSockOptTCP(t *kernel.Task, s socket.Socket, ep commonEndpoint, name int,
 			return err
 		}
 	}
+	// Not supported. Silently succeeding would make the application
+	// believe that, e.g., TCP-AO protects its connections.
 	incrementBadSetSocketOptionMetric(t, &socketLevelTCPFieldValue, name)
-	return nil
+	return syserr.ErrUnknownProtocolOption
+}
+
+// copyInTCPMD5Sig converts the struct tcp_md5sig in optVal, as set with the
+// TCP_MD5SIG or TCP_MD5SIG_EXT socket option named name, to a netstack option.
+func copyInTCPMD5Sig(s socket.Socket, name int, optVal []byte) (*tcpip.TCPMD5SigOption, *syserr.Error) {
+	var cmd linux.TCPMD5Sig
+	if len(optVal) < cmd.SizeBytes() {
+		return nil, syserr.ErrInvalidArgument
+	}
+	cmd.UnmarshalUnsafe(optVal)
+	if int(cmd.KeyLen) > linux.TCP_MD5SIG_MAXKEYLEN {
+		return nil, syserr.ErrInvalidArgument
+	}
+
+	// Like in Linux, the address family must match the socket's, and keys
+	// for IPv4-mapped IPv6 addresses are used with IPv4 peers.
+	family, _, _ := s.Type()
+	var opt tcpip.TCPMD5SigOption
+	switch peerFamily := hostarch.ByteOrder.Uint16(cmd.Addr[:]); {
+	case family == linux.AF_INET && peerFamily == linux.AF_INET:
+		var a linux.SockAddrInet
+		a.UnmarshalUnsafe(cmd.Addr[:a.SizeBytes()])
+		opt.Addr = tcpip.AddrFrom4(a.Addr)
+	case family == linux.AF_INET6 && peerFamily == linux.AF_INET6:
+		var a linux.SockAddrInet6
+		a.UnmarshalUnsafe(cmd.Addr[:a.SizeBytes()])
+		opt.Addr = tcpip.AddrFrom16(a.Addr)
+		if header.IsV4MappedAddress(opt.Addr) {
+			opt.Addr = opt.Addr.To4()
+		}
+	default:
+		return nil, syserr.ErrInvalidArgument
+	}
+
+	opt.PrefixLen = opt.Addr.BitLen()
+	if name == linux.TCP_MD5SIG_EXT {
+		if cmd.Flags&linux.TCP_MD5SIG_FLAG_PREFIX != 0 {
+			if int(cmd.PrefixLen) > opt.PrefixLen {
+				return nil, syserr.ErrInvalidArgument
+			}
+			opt.PrefixLen = int(cmd.PrefixLen)
+		}
+		if cmd.Flags&linux.TCP_MD5SIG_FLAG_IFINDEX != 0 {
+			if cmd.IfIndex <= 0 {
+				return nil, syserr.ErrInvalidArgument
+			}
+			opt.NIC = tcpip.NICID(cmd.IfIndex)
+		}
+	}
+	opt.Key = cmd.Key[:cmd.KeyLen]
+	return &opt, nil
 }
 
 func setSockOptICMPv6(t *kernel.Task, s socket.Socket, ep commonEndpoint, name int, optVal []byte) *syserr.Error {
@@ -3160,10 +3614,14 @@ func (s *sock) nonBlockingRead(ctx context.Context, dst usermem.IOSequence, peek
 
 func (s *sock) netstackToLinuxControlMessages(cm tcpip.ReceivableControlMessages) socket.ControlMessages {
 	readCM := socket.NewIPControlMessages(s.family, cm)
+	const rxTimestamping = tcpip.TimestampingSoftware | tcpip.TimestampingRXSoftware
 	return socket.ControlMessages{
 		IP: socket.IPControlMessages{
 			HasTimestamp:       readCM.HasTimestamp && s.sockOptTimestamp,
 			Timestamp:          readCM.Timestamp,
+			HasTimestamping:    readCM.HasTimestamp && s.Endpoint.SocketOptions().GetTimestamping()&rxTimestamping == rxTimestamping,
+			TimestampingNew:    s.sockOptTimestampingNew,
+			Timestamping:       readCM.Timestamp,
 			HasInq:             readCM.HasInq,
 			Inq:                readCM.Inq,
 			HasTOS:             readCM.HasTOS,
@@ -3258,6 +3716,13 @@ func (s *sock) recvErr(t *kernel.Task, dst usermem.IOSequence) (int, int, linux.
 	// supplied via msg_name.  -- recvmsg(2)
 	dstAddr, dstAddrLen := socket.ConvertAddress(addrFamilyFromNetProto(sockErr.NetProto), sockErr.Dst)
 	cmgs := socket.ControlMessages{IP: socket.NewIPControlMessages(s.family, tcpip.ReceivableControlMessages{SockErr: sockErr})}
+	if ts, ok := sockErr.Cause.(*tcpip.TimestampingSockError); ok && s.Endpoint.SocketOptions().GetTimestamping()&tcpip.TimestampingSoftware != 0 {
+		s.readMu.Lock()
+		cmgs.IP.TimestampingNew = s.sockOptTimestampingNew
+		s.readMu.Unlock()
+		cmgs.IP.HasTimestamping = true
+		cmgs.IP.Timestamping = ts.Timestamp
+	}
 	return n, msgFlags, dstAddr, dstAddrLen, cmgs, syserr.FromError(err)
 }
 
@@ -3359,18 +3824,36 @@ func (s *sock) SendMsg(t *kernel.Task, src usermem.IOSequence, to []byte, flags
 		To:              addr,
 		More:            flags&linux.MSG_MORE != 0,
 		EndOfRecord:     flags&linux.MSG_EOR != 0,
+		FastOpen:        flags&linux.MSG_FASTOPEN != 0,
 		ControlMessages: s.linuxToNetstackControlMessages(controlMessages),
 	}
+	if controlMessages.IP.HasTXTime {
+		txTime, err := s.txTime(t, controlMessages.IP.TXTime)
+		if err != nil {
+			return 0, err
+		}
+		opts.ControlMessages.HasTXTime = true
+		opts.ControlMessages.TXTime = txTime
+	}
 
-	r := src.Reader(t)
 	var (
+		r     tcpip.Payloader = src.Reader(t)
 		total int64
 		entry waiter.Entry
 		ch    <-chan struct{}
 	)
+	// MSG_ZEROCOPY is ignored unless SO_ZEROCOPY is set, like in Linux.
+	if flags&linux.MSG_ZEROCOPY != 0 && s.Endpoint.SocketOptions().GetZeroCopy() {
+		zp := s.newZeroCopyPayload(t, src)
+		r = zp
+		defer func() { zp.done(total > 0) }()
+	}
 	for {
 		n, err := s.Endpoint.Write(r, opts)
 		total += n
+		// A TCP Fast Open connection is only started by the first write,
+		// the rest of the data is sent once it is established.
+		opts.FastOpen = false
 		if flags&linux.MSG_DONTWAIT != 0 {
 			return int(total), syserr.TranslateNetstackError(err)
 		}
@@ -3378,7 +3861,7 @@ func (s *sock) SendMsg(t *kernel.Task, src usermem.IOSequence, to []byte, flags
 		switch err.(type) {
 		case nil:
 			block = total != src.NumBytes()
-		case *tcpip.ErrWouldBlock:
+		case *tcpip.ErrWouldBlock, *tcpip.ErrConnectStarted:
 		default:
 			block = false
 		}
@@ -3817,6 +4300,46 @@ func (s *sock) Type() (family int, skType linux.SockType, protocol int) {
 	return s.family, s.skType, s.protocol
 }
 
+// NetworkNamespace implements socket.Diagnosable.NetworkNamespace.
+func (s *sock) NetworkNamespace() *inet.Namespace {
+	return s.namespace
+}
+
+// QueueSizes implements socket.Diagnosable.QueueSizes.
+func (s *sock) QueueSizes() (rqueue, wqueue uint32) {
+	// Errors are expected for endpoints that don't have queues, e.g.
+	// listening TCP sockets, which are reported as empty.
+	if v, err := s.Endpoint.GetSockOptInt(tcpip.ReceiveQueueSizeOption); err == nil {
+		rqueue = uint32(min(v, math.MaxUint32))
+	}
+	if v, err := s.Endpoint.GetSockOptInt(tcpip.SendQueueSizeOption); err == nil {
+		wqueue = uint32(min(v, math.MaxUint32))
+	}
+	return rqueue, wqueue
+}
+
+// Destroy implements socket.Diagnosable.Destroy.
+func (s *sock) Destroy(ctx context.Context) *syserr.Error {
+	if s.family != linux.AF_INET && s.family != linux.AF_INET6 {
+		return syserr.ErrNotSupported
+	}
+	if s.skType == linux.SOCK_STREAM {
+		// Like Linux's tcp_abort(), connected sockets are reset with
+		// ECONNABORTED and other sockets are closed.
+		s.Endpoint.Abort()
+		return nil
+	}
+	// Like Linux's udp_abort(), UDP sockets get a pending ECONNABORTED and
+	// are disconnected, but remain usable. Raw and ping sockets can't be
+	// destroyed, as their Abort closes them.
+	ep, ok := s.Endpoint.(tcpip.EndpointWithSoftAbort)
+	if !ok {
+		return syserr.ErrNotSupported
+	}
+	ep.SoftAbort(&tcpip.ErrConnectionAborted{})
+	return nil
+}
+
 // EventRegister implements waiter.Waitable.
 func (s *sock) EventRegister(e *waiter.Entry) error {
 	s.Queue.EventRegister(e)
@@ -590,6 +590,61 @@ func (s *Stack) SetTCPRecovery(recovery inet.TCPLossRecovery) error {
 	return syserr.TranslateNetstackError(s.Stack.SetTransportProtocolOption(tcp.ProtocolNumber, &opt)).ToError()
 }
 
+// TCPFastOpen implements inet.Stack.TCPFastOpen.
+func (s *Stack) TCPFastOpen() (int32, error) {
+	var mode tcpip.TCPFastOpen
+	if err := s.Stack.TransportProtocolOption(tcp.ProtocolNumber, &mode); err != nil {
+		return 0, syserr.TranslateNetstackError(err).ToError()
+	}
+	return int32(mode), nil
+}
+
+// SetTCPFastOpen implements inet.Stack.SetTCPFastOpen.
+func (s *Stack) SetTCPFastOpen(mode int32) error {
+	opt := tcpip.TCPFastOpen(mode)
+	return syserr.TranslateNetstackError(s.Stack.SetTransportProtocolOption(tcp.ProtocolNumber, &opt)).ToError()
+}
+
+// TCPCongestionControl implements inet.Stack.TCPCongestionControl.
+func (s *Stack) TCPCongestionControl() (string, error) {
+	var name tcpip.CongestionControlOption
+	if err := s.Stack.TransportProtocolOption(tcp.ProtocolNumber, &name); err != nil {
+		return "", syserr.TranslateNetstackError(err).ToError()
+	}
+	return string(name), nil
+}
+
+// SetTCPCongestionControl implements inet.Stack.SetTCPCongestionControl.
+func (s *Stack) SetTCPCongestionControl(name string) error {
+	opt := tcpip.CongestionControlOption(name)
+	return syserr.TranslateNetstackError(s.Stack.SetTransportProtocolOption(tcp.ProtocolNumber, &opt)).ToError()
+}
+
+// TCPAvailableCongestionControl implements
+// inet.Stack.TCPAvailableCongestionControl.
+func (s *Stack) TCPAvailableCongestionControl() (string, error) {
+	var names tcpip.TCPAvailableCongestionControlOption
+	if err := s.Stack.TransportProtocolOption(tcp.ProtocolNumber, &names); err != nil {
+		return "", syserr.TranslateNetstackError(err).ToError()
+	}
+	return string(names), nil
+}
+
+// TCPECN implements inet.Stack.TCPECN.
+func (s *Stack) TCPECN() (int32, error) {
+	var mode tcpip.TCPECNOption
+	if err := s.Stack.TransportProtocolOption(tcp.ProtocolNumber, &mode); err != nil {
+		return 0, syserr.TranslateNetstackError(err).ToError()
+	}
+	return int32(mode), nil
+}
+
+// SetTCPECN implements inet.Stack.SetTCPECN.
+func (s *Stack) SetTCPECN(mode int32) error {
+	opt := tcpip.TCPECNOption(mode)
+	return syserr.TranslateNetstackError(s.Stack.SetTransportProtocolOption(tcp.ProtocolNumber, &opt)).ToError()
+}
+
 // Statistics implements inet.Stack.Statistics.
 func (s *Stack) Statistics(stat any, arg string) error {
 	netStats := s.Stats()
@@ -0,0 +1,190 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netstack
+
+import (
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/atomicbitops"
+	"gvisor.dev/gvisor/pkg/buffer"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/sentry/memmap"
+	"gvisor.dev/gvisor/pkg/sentry/mm"
+	"gvisor.dev/gvisor/pkg/sync"
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+	"gvisor.dev/gvisor/pkg/usermem"
+	"gvisor.dev/gvisor/pkg/waiter"
+)
+
+// zeroCopyPayload is the payload of a send with MSG_ZEROCOPY. It lets the
+// endpoint reference the application's memory, which stays pinned until the
+// endpoint releases it, at which point a completion notification is queued on
+// the socket's error queue.
+//
+// Like in Linux, data is copied if it can't be pinned, or if the endpoint
+// asks for it, which is reported in the completion notification.
+type zeroCopyPayload struct {
+	ctx context.Context
+	src usermem.IOSequence
+
+	so       *tcpip.SocketOptions
+	queue    *waiter.Queue
+	netProto tcpip.NetworkProtocolNumber
+
+	// pending is the number of views referencing src, plus one until done is
+	// called.
+	pending atomicbitops.Int64
+
+	// copied is true if any data was copied.
+	copied atomicbitops.Bool
+
+	// id and sent are set by done.
+	id   uint32
+	sent bool
+
+	mu sync.Mutex
+
+	// prs are the ranges pinned for the views referencing src.
+	// +checklocks:mu
+	prs []mm.PinnedRange
+}
+
+func (s *sock) newZeroCopyPayload(ctx context.Context, src usermem.IOSequence) *zeroCopyPayload {
+	netProto := header.IPv4ProtocolNumber
+	if s.family == linux.AF_INET6 {
+		netProto = header.IPv6ProtocolNumber
+	}
+	p := &zeroCopyPayload{
+		ctx:      ctx,
+		src:      src,
+		so:       s.Endpoint.SocketOptions(),
+		queue:    s.Queue,
+		netProto: netProto,
+	}
+	p.pending.Store(1)
+	return p
+}
+
+// Len implements tcpip.Payloader.Len.
+func (p *zeroCopyPayload) Len() int {
+	return int(p.src.NumBytes())
+}
+
+// Read implements io.Reader.Read.
+func (p *zeroCopyPayload) Read(dst []byte) (int, error) {
+	n, err := p.src.CopyIn(p.ctx, dst)
+	if n > 0 {
+		p.copied.Store(true)
+	}
+	p.src = p.src.DropFirst(n)
+	return n, err
+}
+
+// ReadBuffer implements tcpip.ZeroCopyPayloader.ReadBuffer.
+func (p *zeroCopyPayload) ReadBuffer(n int, copyData bool) (buffer.Buffer, error) {
+	var buf buffer.Buffer
+	if l := p.Len(); n > l {
+		n = l
+	}
+	if mmgr, ok := p.src.IO.(*mm.MemoryManager); ok && !copyData {
+		pinned := 0
+		for ars := p.src.TakeFirst(n).Addrs; !ars.IsEmpty(); ars = ars.Tail() {
+			ar := ars.Head()
+			m := p.pin(mmgr, ar, &buf)
+			pinned += m
+			if m != int(ar.Length()) {
+				break
+			}
+		}
+		p.src = p.src.DropFirst(pinned)
+		n -= pinned
+	}
+	// Copy whatever couldn't be referenced.
+	if _, err := buf.WriteFromReader(p, int64(n)); err != nil {
+		return buf, err
+	}
+	return buf, nil
+}
+
+// pin appends views referencing the application memory at ar to buf, and
+// returns the number of bytes referenced, which is less than the length of ar
+// if some of it can't be.
+func (p *zeroCopyPayload) pin(mmgr *mm.MemoryManager, ar hostarch.AddrRange, buf *buffer.Buffer) int {
+	if ar.Length() == 0 {
+		return 0
+	}
+	end, ok := ar.End.RoundUp()
+	if !ok {
+		return 0
+	}
+	// If not all of ar is mapped, prs only covers its beginning.
+	prs, _ := mmgr.Pin(p.ctx, hostarch.AddrRange{ar.Start.RoundDown(), end}, hostarch.Read, false /* ignorePermissions */)
+	if len(prs) != 0 {
+		p.mu.Lock()
+		p.prs = append(p.prs, prs...)
+		p.mu.Unlock()
+	}
+
+	done := 0
+	for _, pr := range prs {
+		sub := pr.Source.Intersect(ar)
+		if sub.Length() == 0 {
+			continue
+		}
+		off := pr.Offset + uint64(sub.Start-pr.Source.Start)
+		ims, err := pr.File.MapInternal(memmap.FileRange{off, off + uint64(sub.Length())}, hostarch.Read)
+		if err != nil {
+			return done
+		}
+		for ; !ims.IsEmpty(); ims = ims.Tail() {
+			b := ims.Head()
+			if b.NeedSafecopy() {
+				return done
+			}
+			p.pending.Add(1)
+			buf.Append(buffer.NewViewWithExternalData(b.ToSlice(), p.decRef))
+			done += b.Len()
+		}
+	}
+	return done
+}
+
+// done must be called once the send is over, with sent set if any data was
+// sent, which consumes an ID for the completion notification.
+func (p *zeroCopyPayload) done(sent bool) {
+	if sent {
+		p.id = p.so.NextZeroCopyID()
+		p.sent = true
+	}
+	p.decRef()
+}
+
+func (p *zeroCopyPayload) decRef() {
+	if p.pending.Add(-1) != 0 {
+		return
+	}
+	p.mu.Lock()
+	prs := p.prs
+	p.prs = nil
+	p.mu.Unlock()
+	mm.Unpin(prs)
+	if p.sent {
+		p.so.QueueZeroCopyCompletion(p.id, p.copied.Load(), p.netProto)
+		p.queue.Notify(waiter.EventErr)
+	}
+}
+
+var _ tcpip.ZeroCopyPayloader = (*zeroCopyPayload)(nil)
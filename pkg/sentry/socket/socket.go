@@ -28,6 +28,7 @@ import (
 	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/hostarch"
 	"gvisor.dev/gvisor/pkg/marshal"
+	"gvisor.dev/gvisor/pkg/sentry/inet"
 	"gvisor.dev/gvisor/pkg/sentry/kernel"
 	"gvisor.dev/gvisor/pkg/sentry/ktime"
 	"gvisor.dev/gvisor/pkg/sentry/socket/unix/transport"
@@ -76,6 +77,10 @@ func errOriginToLinux(origin tcpip.SockErrOrigin) uint8 {
 		return linux.SO_EE_ORIGIN_ICMP
 	case tcpip.SockExtErrorOriginICMP6:
 		return linux.SO_EE_ORIGIN_ICMP6
+	case tcpip.SockExtErrorOriginTimestamping:
+		return linux.SO_EE_ORIGIN_TIMESTAMPING
+	case tcpip.SockExtErrorOriginZeroCopy:
+		return linux.SO_EE_ORIGIN_ZEROCOPY
 	default:
 		panic(fmt.Sprintf("unknown socket origin: %d", origin))
 	}
@@ -89,11 +94,18 @@ func sockErrCmsgToLinux(sockErr *tcpip.SockError) linux.SockErrCMsg {
 	}
 
 	ee := linux.SockExtendedErr{
-		Errno:  uint32(syserr.TranslateNetstackError(sockErr.Err).ToLinux()),
 		Origin: errOriginToLinux(sockErr.Cause.Origin()),
 		Type:   sockErr.Cause.Type(),
 		Code:   sockErr.Cause.Code(),
 		Info:   sockErr.Cause.Info(),
+		Data:   sockErr.Cause.Data(),
+	}
+	// Notifications such as zero copy completions carry no error, except TX
+	// timestamps which are reported with ENOMSG like in Linux.
+	if sockErr.Err != nil {
+		ee.Errno = uint32(syserr.TranslateNetstackError(sockErr.Err).ToLinux())
+	} else if sockErr.Cause.Origin() == tcpip.SockExtErrorOriginTimestamping {
+		ee.Errno = uint32(unix.ENOMSG)
 	}
 
 	switch sockErr.NetProto {
@@ -210,6 +222,24 @@ type IPControlMessages struct {
 
 	// SockErr is the dequeued socket error on recvmsg(MSG_ERRQUEUE).
 	SockErr linux.SockErrCMsg
+
+	// HasTimestamping indicates whether Timestamping is valid/set.
+	HasTimestamping bool
+
+	// TimestampingNew indicates whether Timestamping is reported with
+	// SCM_TIMESTAMPING_NEW rather than SCM_TIMESTAMPING.
+	TimestampingNew bool
+
+	// Timestamping is the software timestamp reported with
+	// SCM_TIMESTAMPING.
+	Timestamping time.Time `state:".(int64)"`
+
+	// HasTXTime indicates whether TXTime is valid/set.
+	HasTXTime bool
+
+	// TXTime is the transmit time of the associated packet set with
+	// SCM_TXTIME, in nanoseconds of the clock of SO_TXTIME.
+	TXTime uint64
 }
 
 // Release releases Unix domain socket credentials and rights.
@@ -300,6 +330,21 @@ type Socket interface {
 	Type() (family int, skType linux.SockType, protocol int)
 }
 
+// Diagnosable is implemented by sockets that can be inspected and destroyed
+// through NETLINK_SOCK_DIAG.
+type Diagnosable interface {
+	// NetworkNamespace returns the network namespace of the socket.
+	NetworkNamespace() *inet.Namespace
+
+	// QueueSizes returns the number of bytes in the receive and send queues of
+	// the socket.
+	QueueSizes() (rqueue, wqueue uint32)
+
+	// Destroy aborts the socket, as done by SOCK_DESTROY. It returns
+	// EOPNOTSUPP if the socket can't be aborted.
+	Destroy(ctx context.Context) *syserr.Error
+}
+
 // Provider is the interface implemented by providers of sockets for
 // specific address families (e.g., AF_INET).
 type Provider interface {
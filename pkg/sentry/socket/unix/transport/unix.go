@@ -213,6 +213,10 @@ type Endpoint interface {
 	// connected.
 	GetRemoteAddress() (Address, tcpip.Error)
 
+	// Peer returns the endpoint to which the endpoint is connected, or nil if
+	// it isn't connected.
+	Peer() Endpoint
+
 	// SetSockOpt sets a socket option.
 	SetSockOpt(opt tcpip.SettableSocketOption) tcpip.Error
 
@@ -1081,6 +1085,19 @@ func (e *baseEndpoint) GetRemoteAddress() (Address, tcpip.Error) {
 	return Address{}, &tcpip.ErrNotConnected{}
 }
 
+// Peer implements Endpoint.Peer.
+func (e *baseEndpoint) Peer() Endpoint {
+	e.Lock()
+	c := e.connected
+	e.Unlock()
+	ce, ok := c.(*connectedEndpoint)
+	if !ok {
+		return nil
+	}
+	peer, _ := ce.endpoint.(Endpoint)
+	return peer
+}
+
 // Release implements BoundEndpoint.Release.
 func (*baseEndpoint) Release(context.Context) {
 	// Binding a baseEndpoint doesn't take a reference.
@@ -19,6 +19,7 @@ package unix
 import (
 	"bytes"
 	"fmt"
+	"math"
 
 	"golang.org/x/sys/unix"
 	"gvisor.dev/gvisor/pkg/abi/linux"
@@ -39,6 +40,7 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/socket/unix/transport"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
 	"gvisor.dev/gvisor/pkg/syserr"
+	"gvisor.dev/gvisor/pkg/tcpip"
 	"gvisor.dev/gvisor/pkg/usermem"
 	"gvisor.dev/gvisor/pkg/waiter"
 )
@@ -67,6 +69,7 @@ type Socket struct {
 }
 
 var _ = socket.Socket(&Socket{})
+var _ = socket.Diagnosable(&Socket{})
 
 // NewSockfsFile creates a new socket file in the global sockfs mount and
 // returns a corresponding file description.
@@ -864,6 +867,29 @@ func (s *Socket) Type() (family int, skType linux.SockType, protocol int) {
 	return linux.AF_UNIX, s.stype, 0
 }
 
+// NetworkNamespace implements socket.Diagnosable.NetworkNamespace.
+func (s *Socket) NetworkNamespace() *inet.Namespace {
+	return s.namespace
+}
+
+// QueueSizes implements socket.Diagnosable.QueueSizes.
+func (s *Socket) QueueSizes() (rqueue, wqueue uint32) {
+	// Sockets that aren't connected have empty queues.
+	if v, err := s.ep.GetSockOptInt(tcpip.ReceiveQueueSizeOption); err == nil {
+		rqueue = uint32(min(v, math.MaxUint32))
+	}
+	if v, err := s.ep.GetSockOptInt(tcpip.SendQueueSizeOption); err == nil {
+		wqueue = uint32(min(v, math.MaxUint32))
+	}
+	return rqueue, wqueue
+}
+
+// Destroy implements socket.Diagnosable.Destroy.
+func (s *Socket) Destroy(ctx context.Context) *syserr.Error {
+	// Like Linux, unix sockets can't be destroyed.
+	return syserr.ErrNotSupported
+}
+
 func convertAddress(addr transport.Address) (linux.SockAddr, uint32) {
 	var out linux.SockAddrUnix
 	out.Family = linux.AF_UNIX
@@ -373,8 +373,17 @@ var linuxAMD64 = SyscallMap{
 	434: makeSyscallInfo("pidfd_open", Hex, Hex),
 	435: makeSyscallInfo("clone3", Hex, Hex),
 	436: makeSyscallInfo("close_range", FD, FD, CloseRangeFlags),
+	437: makeSyscallInfo("openat2", FD, Path, Hex, Hex),
+	438: makeSyscallInfo("pidfd_getfd", FD, FD, Hex),
 	439: makeSyscallInfo("faccessat2", FD, Path, Oct, Hex),
 	441: makeSyscallInfo("epoll_pwait2", FD, EpollEvents, Hex, Timespec, SigSet),
+	444: makeSyscallInfo("landlock_create_ruleset", Hex, Hex, Hex),
+	445: makeSyscallInfo("landlock_add_rule", FD, Hex, Hex, Hex),
+	446: makeSyscallInfo("landlock_restrict_self", FD, Hex),
+	449: makeSyscallInfo("futex_waitv", Hex, Hex, Hex, Timespec, Hex),
+	454: makeSyscallInfo("futex_wake", Hex, Hex, Hex, Hex),
+	455: makeSyscallInfo("futex_wait", Hex, Hex, Hex, Hex, Timespec, Hex),
+	456: makeSyscallInfo("futex_requeue", Hex, Hex, Hex, Hex),
 }
 
 func init() {
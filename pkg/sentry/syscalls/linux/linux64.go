@@ -108,7 +108,7 @@ var AMD64 = &kernel.SyscallTable{
 		53:  syscalls.SupportedPoint("socketpair", SocketPair, PointSocketpair),
 		54:  syscalls.Supported("setsockopt", SetSockOpt),
 		55:  syscalls.Supported("getsockopt", GetSockOpt),
-		56:  syscalls.PartiallySupportedPoint("clone", Clone, PointClone, "Options CLONE_PIDFD, CLONE_NEWCGROUP, CLONE_PARENT, CLONE_NEWTIME, CLONE_CLEAR_SIGHAND, and CLONE_SYSVSEM not supported.", nil),
+		56:  syscalls.PartiallySupportedPoint("clone", Clone, PointClone, "Options CLONE_NEWCGROUP, CLONE_PARENT, and CLONE_CLEAR_SIGHAND not supported.", nil),
 		57:  syscalls.SupportedPoint("fork", Fork, PointFork),
 		58:  syscalls.SupportedPoint("vfork", Vfork, PointVfork),
 		59:  syscalls.SupportedPoint("execve", Execve, PointExecve),
@@ -117,7 +117,7 @@ var AMD64 = &kernel.SyscallTable{
 		62:  syscalls.Supported("kill", Kill),
 		63:  syscalls.Supported("uname", Uname),
 		64:  syscalls.Supported("semget", Semget),
-		65:  syscalls.PartiallySupported("semop", Semop, "Option SEM_UNDO not supported.", nil),
+		65:  syscalls.Supported("semop", Semop),
 		66:  syscalls.Supported("semctl", Semctl),
 		67:  syscalls.Supported("shmdt", Shmdt),
 		68:  syscalls.Supported("msgget", Msgget),
@@ -254,7 +254,7 @@ var AMD64 = &kernel.SyscallTable{
 		199: syscalls.Supported("fremovexattr", Fremovexattr),
 		200: syscalls.Supported("tkill", Tkill),
 		201: syscalls.Supported("time", Time),
-		202: syscalls.PartiallySupported("futex", Futex, "Robust futexes not supported.", nil),
+		202: syscalls.Supported("futex", Futex),
 		203: syscalls.PartiallySupported("sched_setaffinity", SchedSetaffinity, "Stub implementation.", nil),
 		204: syscalls.PartiallySupported("sched_getaffinity", SchedGetaffinity, "Stub implementation.", nil),
 		205: syscalls.Error("set_thread_area", linuxerr.ENOSYS, "Expected to return ENOSYS on 64-bit", nil),
@@ -294,10 +294,10 @@ var AMD64 = &kernel.SyscallTable{
 		239: syscalls.PartiallySupported("get_mempolicy", GetMempolicy, "Stub implementation.", nil),
 		240: syscalls.Supported("mq_open", MqOpen),
 		241: syscalls.Supported("mq_unlink", MqUnlink),
-		242: syscalls.ErrorWithEvent("mq_timedsend", linuxerr.ENOSYS, "", []string{"gvisor.dev/issue/136"}),    // TODO(b/29354921)
-		243: syscalls.ErrorWithEvent("mq_timedreceive", linuxerr.ENOSYS, "", []string{"gvisor.dev/issue/136"}), // TODO(b/29354921)
-		244: syscalls.ErrorWithEvent("mq_notify", linuxerr.ENOSYS, "", []string{"gvisor.dev/issue/136"}),       // TODO(b/29354921)
-		245: syscalls.ErrorWithEvent("mq_getsetattr", linuxerr.ENOSYS, "", []string{"gvisor.dev/issue/136"}),   // TODO(b/29354921)
+		242: syscalls.Supported("mq_timedsend", MqTimedsend),
+		243: syscalls.Supported("mq_timedreceive", MqTimedreceive),
+		244: syscalls.Supported("mq_notify", MqNotify),
+		245: syscalls.Supported("mq_getsetattr", MqGetsetattr),
 		246: syscalls.CapError("kexec_load", linux.CAP_SYS_BOOT, "", nil),
 		247: syscalls.Supported("waitid", Waitid),
 		248: syscalls.Error("add_key", linuxerr.EACCES, "Not available to user.", nil),
@@ -324,14 +324,14 @@ var AMD64 = &kernel.SyscallTable{
 		269: syscalls.Supported("faccessat", Faccessat),
 		270: syscalls.Supported("pselect6", Pselect6),
 		271: syscalls.Supported("ppoll", Ppoll),
-		272: syscalls.PartiallySupported("unshare", Unshare, "Time, cgroup namespaces not supported.", nil),
+		272: syscalls.PartiallySupported("unshare", Unshare, "Cgroup namespaces not supported.", nil),
 		273: syscalls.Supported("set_robust_list", SetRobustList),
 		274: syscalls.Supported("get_robust_list", GetRobustList),
 		275: syscalls.Supported("splice", Splice),
 		276: syscalls.Supported("tee", Tee),
 		277: syscalls.Supported("sync_file_range", SyncFileRange),
-		278: syscalls.ErrorWithEvent("vmsplice", linuxerr.ENOSYS, "", []string{"gvisor.dev/issue/138"}), // TODO(b/29354098)
-		279: syscalls.CapError("move_pages", linux.CAP_SYS_NICE, "", nil),                               // requires cap_sys_nice (mostly)
+		278: syscalls.Supported("vmsplice", Vmsplice),
+		279: syscalls.CapError("move_pages", linux.CAP_SYS_NICE, "", nil), // requires cap_sys_nice (mostly)
 		280: syscalls.Supported("utimensat", Utimensat),
 		281: syscalls.Supported("epoll_pwait", EpollPwait),
 		282: syscalls.SupportedPoint("signalfd", Signalfd, PointSignalfd),
@@ -375,13 +375,13 @@ var AMD64 = &kernel.SyscallTable{
 		320: syscalls.CapError("kexec_file_load", linux.CAP_SYS_BOOT, "", nil),
 		321: syscalls.CapError("bpf", linux.CAP_SYS_ADMIN, "", nil),
 		322: syscalls.SupportedPoint("execveat", Execveat, PointExecveat),
-		323: syscalls.ErrorWithEvent("userfaultfd", linuxerr.ENOSYS, "", []string{"gvisor.dev/issue/266"}), // TODO(b/118906345)
+		323: syscalls.PartiallySupported("userfaultfd", Userfaultfd, "Only private anonymous mappings can be registered; UFFD_FEATURE_EVENT_* are not supported.", nil),
 		324: syscalls.PartiallySupported("membarrier", Membarrier, "Not supported on all platforms.", nil),
 		325: syscalls.PartiallySupported("mlock2", Mlock2, "Stub implementation. The sandbox lacks appropriate permissions.", nil),
 
 		// Syscalls implemented after 325 are "backports" from versions
 		// of Linux after 4.4.
-		326: syscalls.ErrorWithEvent("copy_file_range", linuxerr.ENOSYS, "", nil),
+		326: syscalls.Supported("copy_file_range", CopyFileRange),
 		327: syscalls.SupportedPoint("preadv2", Preadv2, PointPreadv2),
 		328: syscalls.SupportedPoint("pwritev2", Pwritev2, PointPwritev2),
 		329: syscalls.ErrorWithEvent("pkey_mprotect", linuxerr.ENOSYS, "", nil),
@@ -392,21 +392,31 @@ var AMD64 = &kernel.SyscallTable{
 		334: syscalls.PartiallySupported("rseq", RSeq, "Not supported on all platforms.", nil),
 
 		// Linux skips ahead to syscall 424 to sync numbers between arches.
-		424: syscalls.ErrorWithEvent("pidfd_send_signal", linuxerr.ENOSYS, "", nil),
+		424: syscalls.PartiallySupported("pidfd_send_signal", PidfdSendSignal, "Flags PIDFD_SIGNAL_* are not supported.", nil),
 		425: syscalls.PartiallySupported("io_uring_setup", IOUringSetup, "Not all flags and functionality supported.", nil),
 		426: syscalls.PartiallySupported("io_uring_enter", IOUringEnter, "Not all flags and functionality supported.", nil),
-		427: syscalls.ErrorWithEvent("io_uring_register", linuxerr.ENOSYS, "", nil),
-		428: syscalls.ErrorWithEvent("open_tree", linuxerr.ENOSYS, "", nil),
-		429: syscalls.ErrorWithEvent("move_mount", linuxerr.ENOSYS, "", nil),
-		430: syscalls.ErrorWithEvent("fsopen", linuxerr.ENOSYS, "", nil),
-		431: syscalls.ErrorWithEvent("fsconfig", linuxerr.ENOSYS, "", nil),
-		432: syscalls.ErrorWithEvent("fsmount", linuxerr.ENOSYS, "", nil),
-		433: syscalls.ErrorWithEvent("fspick", linuxerr.ENOSYS, "", nil),
-		434: syscalls.ErrorWithEvent("pidfd_open", linuxerr.ENOSYS, "", nil),
-		435: syscalls.PartiallySupported("clone3", Clone3, "Options CLONE_PIDFD, CLONE_NEWCGROUP, CLONE_INTO_CGROUP, CLONE_NEWTIME, CLONE_CLEAR_SIGHAND, CLONE_PARENT, CLONE_SYSVSEM and, SetTid are not supported.", nil),
+		427: syscalls.PartiallySupported("io_uring_register", IOUringRegister, "Not all opcodes supported.", nil),
+		428: syscalls.Supported("open_tree", OpenTree),
+		429: syscalls.PartiallySupported("move_mount", MoveMount, "Flags MOVE_MOUNT_SET_GROUP and MOVE_MOUNT_BENEATH are not supported.", nil),
+		430: syscalls.Supported("fsopen", Fsopen),
+		431: syscalls.PartiallySupported("fsconfig", Fsconfig, "Only flag and string parameters are supported.", nil),
+		432: syscalls.PartiallySupported("fsmount", Fsmount, "Attributes MOUNT_ATTR_NODIRATIME and MOUNT_ATTR_NOSYMFOLLOW are not supported.", nil),
+		433: syscalls.Supported("fspick", Fspick),
+		434: syscalls.PartiallySupported("pidfd_open", PidfdOpen, "Flag PIDFD_THREAD is not supported.", nil),
+		435: syscalls.PartiallySupported("clone3", Clone3, "Options CLONE_NEWCGROUP, CLONE_INTO_CGROUP, CLONE_CLEAR_SIGHAND, CLONE_PARENT and SetTid are not supported.", nil),
 		436: syscalls.Supported("close_range", CloseRange),
+		437: syscalls.PartiallySupported("openat2", Openat2, "RESOLVE_CACHED only fails lookups that require I/O on gofer filesystems.", nil),
+		438: syscalls.Supported("pidfd_getfd", PidfdGetfd),
 		439: syscalls.Supported("faccessat2", Faccessat2),
 		441: syscalls.Supported("epoll_pwait2", EpollPwait2),
+		442: syscalls.PartiallySupported("mount_setattr", MountSetattr, "Idmapped mounts and attributes MOUNT_ATTR_NODIRATIME and MOUNT_ATTR_NOSYMFOLLOW are not supported.", nil),
+		444: syscalls.PartiallySupported("landlock_create_ruleset", LandlockCreateRuleset, "Landlock ABI version 4 is supported; LANDLOCK_ACCESS_FS_IOCTL_DEV and scoping are not.", nil),
+		445: syscalls.Supported("landlock_add_rule", LandlockAddRule),
+		446: syscalls.Supported("landlock_restrict_self", LandlockRestrictSelf),
+		449: syscalls.Supported("futex_waitv", FutexWaitv),
+		454: syscalls.PartiallySupported("futex_wake", FutexWake, "FUTEX2_NUMA and FUTEX2_MPOL are not supported.", nil),
+		455: syscalls.PartiallySupported("futex_wait", FutexWait, "FUTEX2_NUMA and FUTEX2_MPOL are not supported.", nil),
+		456: syscalls.Supported("futex_requeue", FutexRequeue),
 	},
 	Emulate: map[hostarch.Addr]uintptr{
 		0xffffffffff600000: 96,  // vsyscall gettimeofday(2)
@@ -506,7 +516,7 @@ var ARM64 = &kernel.SyscallTable{
 		72:  syscalls.Supported("pselect6", Pselect6),
 		73:  syscalls.Supported("ppoll", Ppoll),
 		74:  syscalls.SupportedPoint("signalfd4", Signalfd4, PointSignalfd4),
-		75:  syscalls.ErrorWithEvent("vmsplice", linuxerr.ENOSYS, "", []string{"gvisor.dev/issue/138"}), // TODO(b/29354098)
+		75:  syscalls.Supported("vmsplice", Vmsplice),
 		76:  syscalls.Supported("splice", Splice),
 		77:  syscalls.Supported("tee", Tee),
 		78:  syscalls.Supported("readlinkat", Readlinkat),
@@ -528,8 +538,8 @@ var ARM64 = &kernel.SyscallTable{
 		94:  syscalls.Supported("exit_group", ExitGroup),
 		95:  syscalls.Supported("waitid", Waitid),
 		96:  syscalls.Supported("set_tid_address", SetTidAddress),
-		97:  syscalls.PartiallySupported("unshare", Unshare, "Time, cgroup namespaces not supported.", nil),
-		98:  syscalls.PartiallySupported("futex", Futex, "Robust futexes not supported.", nil),
+		97:  syscalls.PartiallySupported("unshare", Unshare, "Cgroup namespaces not supported.", nil),
+		98:  syscalls.Supported("futex", Futex),
 		99:  syscalls.Supported("set_robust_list", SetRobustList),
 		100: syscalls.Supported("get_robust_list", GetRobustList),
 		101: syscalls.Supported("nanosleep", Nanosleep),
@@ -613,10 +623,10 @@ var ARM64 = &kernel.SyscallTable{
 		179: syscalls.PartiallySupported("sysinfo", Sysinfo, "Fields loads, sharedram, bufferram, totalswap, freeswap, totalhigh, freehigh not supported.", nil),
 		180: syscalls.Supported("mq_open", MqOpen),
 		181: syscalls.Supported("mq_unlink", MqUnlink),
-		182: syscalls.ErrorWithEvent("mq_timedsend", linuxerr.ENOSYS, "", []string{"gvisor.dev/issue/136"}),    // TODO(b/29354921)
-		183: syscalls.ErrorWithEvent("mq_timedreceive", linuxerr.ENOSYS, "", []string{"gvisor.dev/issue/136"}), // TODO(b/29354921)
-		184: syscalls.ErrorWithEvent("mq_notify", linuxerr.ENOSYS, "", []string{"gvisor.dev/issue/136"}),       // TODO(b/29354921)
-		185: syscalls.ErrorWithEvent("mq_getsetattr", linuxerr.ENOSYS, "", []string{"gvisor.dev/issue/136"}),   // TODO(b/29354921)
+		182: syscalls.Supported("mq_timedsend", MqTimedsend),
+		183: syscalls.Supported("mq_timedreceive", MqTimedreceive),
+		184: syscalls.Supported("mq_notify", MqNotify),
+		185: syscalls.Supported("mq_getsetattr", MqGetsetattr),
 		186: syscalls.Supported("msgget", Msgget),
 		187: syscalls.Supported("msgctl", Msgctl),
 		188: syscalls.Supported("msgrcv", Msgrcv),
@@ -624,7 +634,7 @@ var ARM64 = &kernel.SyscallTable{
 		190: syscalls.Supported("semget", Semget),
 		191: syscalls.Supported("semctl", Semctl),
 		192: syscalls.Supported("semtimedop", Semtimedop),
-		193: syscalls.PartiallySupported("semop", Semop, "Option SEM_UNDO not supported.", nil),
+		193: syscalls.Supported("semop", Semop),
 		194: syscalls.PartiallySupported("shmget", Shmget, "Option SHM_HUGETLB is not supported.", nil),
 		195: syscalls.PartiallySupported("shmctl", Shmctl, "Options SHM_LOCK, SHM_UNLOCK are not supported.", nil),
 		196: syscalls.PartiallySupported("shmat", Shmat, "Option SHM_RND is not supported.", nil),
@@ -651,7 +661,7 @@ var ARM64 = &kernel.SyscallTable{
 		217: syscalls.Error("add_key", linuxerr.EACCES, "Not available to user.", nil),
 		218: syscalls.Error("request_key", linuxerr.EACCES, "Not available to user.", nil),
 		219: syscalls.PartiallySupported("keyctl", Keyctl, "Only supports session keyrings with zero keys in them.", nil),
-		220: syscalls.PartiallySupportedPoint("clone", Clone, PointClone, "Options CLONE_PIDFD, CLONE_NEWCGROUP, CLONE_PARENT, CLONE_NEWTIME, CLONE_CLEAR_SIGHAND, and CLONE_SYSVSEM not supported.", nil),
+		220: syscalls.PartiallySupportedPoint("clone", Clone, PointClone, "Options CLONE_NEWCGROUP, CLONE_PARENT, and CLONE_CLEAR_SIGHAND not supported.", nil),
 		221: syscalls.SupportedPoint("execve", Execve, PointExecve),
 		222: syscalls.Supported("mmap", Mmap),
 		223: syscalls.PartiallySupported("fadvise64", Fadvise64, "Not all options are supported.", nil),
@@ -697,12 +707,12 @@ var ARM64 = &kernel.SyscallTable{
 		279: syscalls.Supported("memfd_create", MemfdCreate),
 		280: syscalls.CapError("bpf", linux.CAP_SYS_ADMIN, "", nil),
 		281: syscalls.SupportedPoint("execveat", Execveat, PointExecveat),
-		282: syscalls.ErrorWithEvent("userfaultfd", linuxerr.ENOSYS, "", []string{"gvisor.dev/issue/266"}), // TODO(b/118906345)
+		282: syscalls.PartiallySupported("userfaultfd", Userfaultfd, "Only private anonymous mappings can be registered; UFFD_FEATURE_EVENT_* are not supported.", nil),
 		283: syscalls.PartiallySupported("membarrier", Membarrier, "Not supported on all platforms.", nil),
 		284: syscalls.PartiallySupported("mlock2", Mlock2, "Stub implementation. The sandbox lacks appropriate permissions.", nil),
 
 		// Syscalls after 284 are "backports" from versions of Linux after 4.4.
-		285: syscalls.ErrorWithEvent("copy_file_range", linuxerr.ENOSYS, "", nil),
+		285: syscalls.Supported("copy_file_range", CopyFileRange),
 		286: syscalls.SupportedPoint("preadv2", Preadv2, PointPreadv2),
 		287: syscalls.SupportedPoint("pwritev2", Pwritev2, PointPwritev2),
 		288: syscalls.ErrorWithEvent("pkey_mprotect", linuxerr.ENOSYS, "", nil),
@@ -713,21 +723,31 @@ var ARM64 = &kernel.SyscallTable{
 		293: syscalls.PartiallySupported("rseq", RSeq, "Not supported on all platforms.", nil),
 
 		// Linux skips ahead to syscall 424 to sync numbers between arches.
-		424: syscalls.ErrorWithEvent("pidfd_send_signal", linuxerr.ENOSYS, "", nil),
+		424: syscalls.PartiallySupported("pidfd_send_signal", PidfdSendSignal, "Flags PIDFD_SIGNAL_* are not supported.", nil),
 		425: syscalls.PartiallySupported("io_uring_setup", IOUringSetup, "Not all flags and functionality supported.", nil),
 		426: syscalls.PartiallySupported("io_uring_enter", IOUringEnter, "Not all flags and functionality supported.", nil),
-		427: syscalls.ErrorWithEvent("io_uring_register", linuxerr.ENOSYS, "", nil),
-		428: syscalls.ErrorWithEvent("open_tree", linuxerr.ENOSYS, "", nil),
-		429: syscalls.ErrorWithEvent("move_mount", linuxerr.ENOSYS, "", nil),
-		430: syscalls.ErrorWithEvent("fsopen", linuxerr.ENOSYS, "", nil),
-		431: syscalls.ErrorWithEvent("fsconfig", linuxerr.ENOSYS, "", nil),
-		432: syscalls.ErrorWithEvent("fsmount", linuxerr.ENOSYS, "", nil),
-		433: syscalls.ErrorWithEvent("fspick", linuxerr.ENOSYS, "", nil),
-		434: syscalls.ErrorWithEvent("pidfd_open", linuxerr.ENOSYS, "", nil),
-		435: syscalls.PartiallySupported("clone3", Clone3, "Options CLONE_PIDFD, CLONE_NEWCGROUP, CLONE_INTO_CGROUP, CLONE_NEWTIME, CLONE_CLEAR_SIGHAND, CLONE_PARENT, CLONE_SYSVSEM and clone_args.set_tid are not supported.", nil),
+		427: syscalls.PartiallySupported("io_uring_register", IOUringRegister, "Not all opcodes supported.", nil),
+		428: syscalls.Supported("open_tree", OpenTree),
+		429: syscalls.PartiallySupported("move_mount", MoveMount, "Flags MOVE_MOUNT_SET_GROUP and MOVE_MOUNT_BENEATH are not supported.", nil),
+		430: syscalls.Supported("fsopen", Fsopen),
+		431: syscalls.PartiallySupported("fsconfig", Fsconfig, "Only flag and string parameters are supported.", nil),
+		432: syscalls.PartiallySupported("fsmount", Fsmount, "Attributes MOUNT_ATTR_NODIRATIME and MOUNT_ATTR_NOSYMFOLLOW are not supported.", nil),
+		433: syscalls.Supported("fspick", Fspick),
+		434: syscalls.PartiallySupported("pidfd_open", PidfdOpen, "Flag PIDFD_THREAD is not supported.", nil),
+		435: syscalls.PartiallySupported("clone3", Clone3, "Options CLONE_NEWCGROUP, CLONE_INTO_CGROUP, CLONE_CLEAR_SIGHAND, CLONE_PARENT and clone_args.set_tid are not supported.", nil),
 		436: syscalls.Supported("close_range", CloseRange),
+		437: syscalls.PartiallySupported("openat2", Openat2, "RESOLVE_CACHED only fails lookups that require I/O on gofer filesystems.", nil),
+		438: syscalls.Supported("pidfd_getfd", PidfdGetfd),
 		439: syscalls.Supported("faccessat2", Faccessat2),
 		441: syscalls.Supported("epoll_pwait2", EpollPwait2),
+		442: syscalls.PartiallySupported("mount_setattr", MountSetattr, "Idmapped mounts and attributes MOUNT_ATTR_NODIRATIME and MOUNT_ATTR_NOSYMFOLLOW are not supported.", nil),
+		444: syscalls.PartiallySupported("landlock_create_ruleset", LandlockCreateRuleset, "Landlock ABI version 4 is supported; LANDLOCK_ACCESS_FS_IOCTL_DEV and scoping are not.", nil),
+		445: syscalls.Supported("landlock_add_rule", LandlockAddRule),
+		446: syscalls.Supported("landlock_restrict_self", LandlockRestrictSelf),
+		449: syscalls.Supported("futex_waitv", FutexWaitv),
+		454: syscalls.PartiallySupported("futex_wake", FutexWake, "FUTEX2_NUMA and FUTEX2_MPOL are not supported.", nil),
+		455: syscalls.PartiallySupported("futex_wait", FutexWait, "FUTEX2_NUMA and FUTEX2_MPOL are not supported.", nil),
+		456: syscalls.Supported("futex_requeue", FutexRequeue),
 	},
 	Emulate: map[hostarch.Addr]uintptr{},
 	Missing: func(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, error) {
@@ -77,7 +77,7 @@ func Open(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *
 	addr := args[0].Pointer()
 	flags := args[1].Uint()
 	mode := args[2].ModeT()
-	return openat(t, linux.AT_FDCWD, addr, flags, mode)
+	return openat(t, linux.AT_FDCWD, addr, flags, mode, 0 /* resolve */)
 }
 
 // Openat implements Linux syscall openat(2).
@@ -86,26 +86,100 @@ func Openat(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr,
 	addr := args[1].Pointer()
 	flags := args[2].Uint()
 	mode := args[3].ModeT()
-	return openat(t, dirfd, addr, flags, mode)
+	return openat(t, dirfd, addr, flags, mode, 0 /* resolve */)
+}
+
+// Openat2 implements Linux syscall openat2(2).
+func Openat2(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	dirfd := args[0].Int()
+	addr := args[1].Pointer()
+	howAddr := args[2].Pointer()
+	size := args[3].SizeT()
+
+	if size > hostarch.PageSize {
+		return 0, nil, linuxerr.E2BIG
+	}
+	if size < linux.OPEN_HOW_SIZE_VER0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	var how linux.OpenHow
+	if _, err := how.CopyIn(t, howAddr); err != nil {
+		return 0, nil, err
+	}
+	if size > linux.OPEN_HOW_SIZE_VER0 {
+		// Fields from newer versions of struct open_how must be zero.
+		rest := make([]byte, size-linux.OPEN_HOW_SIZE_VER0)
+		if _, err := t.CopyInBytes(howAddr+linux.OPEN_HOW_SIZE_VER0, rest); err != nil {
+			return 0, nil, err
+		}
+		for _, b := range rest {
+			if b != 0 {
+				return 0, nil, linuxerr.E2BIG
+			}
+		}
+	}
+
+	// Unlike open(2) and openat(2), openat2(2) rejects unknown flags and
+	// meaningless modes.
+	const validFlags = linux.O_ACCMODE | linux.O_CREAT | linux.O_EXCL | linux.O_NOCTTY | linux.O_TRUNC | linux.O_APPEND | linux.O_NONBLOCK | linux.O_DSYNC | linux.O_ASYNC | linux.O_DIRECT | linux.O_LARGEFILE | linux.O_DIRECTORY | linux.O_NOFOLLOW | linux.O_NOATIME | linux.O_CLOEXEC | linux.O_SYNC | linux.O_PATH | linux.O_TMPFILE
+	const validResolve = linux.RESOLVE_NO_XDEV | linux.RESOLVE_NO_MAGICLINKS | linux.RESOLVE_NO_SYMLINKS | linux.RESOLVE_BENEATH | linux.RESOLVE_IN_ROOT | linux.RESOLVE_CACHED
+	if how.Flags&^validFlags != 0 || how.Resolve&^validResolve != 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	// RESOLVE_BENEATH and RESOLVE_IN_ROOT are mutually exclusive.
+	if how.Resolve&linux.RESOLVE_BENEATH != 0 && how.Resolve&linux.RESOLVE_IN_ROOT != 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	if how.Flags&(linux.O_CREAT|linux.O_TMPFILE) != 0 {
+		if how.Mode&^(0777|linux.S_ISUID|linux.S_ISGID|linux.S_ISVTX) != 0 {
+			return 0, nil, linuxerr.EINVAL
+		}
+	} else if how.Mode != 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	// O_PATH only permits O_DIRECTORY, O_NOFOLLOW and O_CLOEXEC.
+	if how.Flags&linux.O_PATH != 0 && how.Flags&^(linux.O_PATH|linux.O_DIRECTORY|linux.O_NOFOLLOW|linux.O_CLOEXEC) != 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	// Opens that may modify the filesystem can't be done with only cached
+	// state.
+	if how.Resolve&linux.RESOLVE_CACHED != 0 && how.Flags&(linux.O_TRUNC|linux.O_CREAT|linux.O_TMPFILE) != 0 {
+		return 0, nil, linuxerr.EAGAIN
+	}
+	return openat(t, dirfd, addr, uint32(how.Flags), uint(how.Mode), how.Resolve)
 }
 
 // Creat implements Linux syscall creat(2).
 func Creat(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
 	addr := args[0].Pointer()
 	mode := args[1].ModeT()
-	return openat(t, linux.AT_FDCWD, addr, linux.O_WRONLY|linux.O_CREAT|linux.O_TRUNC, mode)
+	return openat(t, linux.AT_FDCWD, addr, linux.O_WRONLY|linux.O_CREAT|linux.O_TRUNC, mode, 0 /* resolve */)
 }
 
-func openat(t *kernel.Task, dirfd int32, pathAddr hostarch.Addr, flags uint32, mode uint) (uintptr, *kernel.SyscallControl, error) {
+// openat implements open(2), openat(2) and openat2(2). resolve is a bitmask of
+// linux.RESOLVE_* flags.
+func openat(t *kernel.Task, dirfd int32, pathAddr hostarch.Addr, flags uint32, mode uint, resolve uint64) (uintptr, *kernel.SyscallControl, error) {
 	path, err := copyInPath(t, pathAddr)
 	if err != nil {
 		return 0, nil, err
 	}
-	tpop, err := getTaskPathOperation(t, dirfd, path, disallowEmptyPath, shouldFollowFinalSymlink(flags&linux.O_NOFOLLOW == 0))
+	allowEmpty := disallowEmptyPath
+	if path.Absolute {
+		if resolve&linux.RESOLVE_BENEATH != 0 {
+			return 0, nil, linuxerr.EXDEV
+		}
+		if resolve&linux.RESOLVE_IN_ROOT != 0 {
+			// Absolute paths are resolved relative to dirfd, which is "/".
+			path.Absolute = false
+			allowEmpty = allowEmptyPath
+		}
+	}
+	tpop, err := getTaskPathOperation(t, dirfd, path, allowEmpty, shouldFollowFinalSymlink(flags&linux.O_NOFOLLOW == 0))
 	if err != nil {
 		return 0, nil, err
 	}
 	defer tpop.Release(t)
+	tpop.pop.ResolveFlags = resolve
 
 	file, err := t.Kernel().VFS().OpenAt(t, t.Credentials(), &tpop.pop, &vfs.OpenOptions{
 		Flags: flags | linux.O_LARGEFILE,
@@ -22,6 +22,7 @@ import (
 	"gvisor.dev/gvisor/pkg/hostarch"
 	"gvisor.dev/gvisor/pkg/sentry/arch"
 	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/futex"
 	"gvisor.dev/gvisor/pkg/sentry/ktime"
 )
 
@@ -117,7 +118,9 @@ func futexWaitDuration(t *kernel.Task, duration time.Duration, forever bool, add
 	return 0, linuxerr.ERESTART_RESTARTBLOCK
 }
 
-func futexLockPI(t *kernel.Task, ts linux.Timespec, forever bool, addr hostarch.Addr, private bool) error {
+// futexLockPI performs a FUTEX_LOCK_PI{2}, blocking until the futex is
+// acquired or the absolute timeout ts expires.
+func futexLockPI(t *kernel.Task, clockRealtime bool, ts linux.Timespec, forever bool, addr hostarch.Addr, private bool) error {
 	w := t.FutexWaiter()
 	locked, err := t.Futex().LockPI(w, t, addr, uint32(t.ThreadID()), private, false)
 	if err != nil {
@@ -130,14 +133,50 @@ func futexLockPI(t *kernel.Task, ts linux.Timespec, forever bool, addr hostarch.
 
 	if forever {
 		err = t.Block(w.C)
-	} else {
+	} else if clockRealtime {
 		err = t.BlockWithDeadlineFrom(w.C, t.Kernel().RealtimeClock(), true, ktime.FromTimespec(ts))
+	} else {
+		err = t.BlockWithDeadline(w.C, true, ktime.FromTimespec(ts))
 	}
 
 	t.Futex().WaitComplete(w, t)
 	return linuxerr.ConvertIntr(err, linuxerr.ERESTARTSYS)
 }
 
+// futexWaitRequeuePI performs a FUTEX_WAIT_REQUEUE_PI, blocking until the
+// waiter is requeued to and acquires the PI futex at naddr, or the absolute
+// timeout ts expires.
+func futexWaitRequeuePI(t *kernel.Task, clockRealtime bool, ts linux.Timespec, forever bool, addr, naddr hostarch.Addr, private bool, val uint32) error {
+	w := t.FutexWaiter()
+	if err := t.Futex().WaitRequeuePIPrepare(w, t, addr, naddr, private, val, uint32(t.ThreadID())); err != nil {
+		return err
+	}
+
+	var err error
+	if forever {
+		err = t.Block(w.C)
+	} else if clockRealtime {
+		err = t.BlockWithDeadlineFrom(w.C, t.Kernel().RealtimeClock(), true, ktime.FromTimespec(ts))
+	} else {
+		err = t.BlockWithDeadline(w.C, true, ktime.FromTimespec(ts))
+	}
+
+	requeued, locked := t.Futex().WaitRequeuePIComplete(w, t)
+	switch {
+	case locked:
+		return nil
+	case err == nil:
+		// Woken by FUTEX_WAKE rather than requeued.
+		return linuxerr.EWOULDBLOCK
+	case err == linuxerr.ErrInterrupted && requeued:
+		// Like Linux, don't restart since the waiter was already requeued:
+		// the restarted wait would only fail as the futex value changed.
+		return linuxerr.EWOULDBLOCK
+	default:
+		return linuxerr.ConvertIntr(err, linuxerr.ERESTARTNOINTR)
+	}
+}
+
 func tryLockPI(t *kernel.Task, addr hostarch.Addr, private bool) error {
 	w := t.FutexWaiter()
 	locked, err := t.Futex().LockPI(w, t, addr, uint32(t.ThreadID()), private, true)
@@ -167,6 +206,17 @@ func Futex(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr,
 	clockRealtime := (futexOp & linux.FUTEX_CLOCK_REALTIME) == linux.FUTEX_CLOCK_REALTIME
 	mask := uint32(val3)
 
+	// FUTEX_CLOCK_REALTIME only applies to operations with an absolute
+	// timeout. FUTEX_WAIT is accepted for compatibility, but the flag has no
+	// effect on its relative timeout.
+	if clockRealtime {
+		switch cmd {
+		case linux.FUTEX_WAIT, linux.FUTEX_WAIT_BITSET, linux.FUTEX_WAIT_REQUEUE_PI, linux.FUTEX_LOCK_PI2:
+		default:
+			return 0, nil, linuxerr.ENOSYS
+		}
+	}
+
 	switch cmd {
 	case linux.FUTEX_WAIT, linux.FUTEX_WAIT_BITSET:
 		// WAIT{_BITSET} wait forever if the timeout isn't passed.
@@ -241,7 +291,7 @@ func Futex(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr,
 		n, err := t.Futex().WakeOp(t, addr, naddr, private, val, nreq, op)
 		return uintptr(n), nil, err
 
-	case linux.FUTEX_LOCK_PI:
+	case linux.FUTEX_LOCK_PI, linux.FUTEX_LOCK_PI2:
 		forever := (timeout == 0)
 
 		var timespec linux.Timespec
@@ -252,7 +302,12 @@ func Futex(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr,
 				return 0, nil, err
 			}
 		}
-		err := futexLockPI(t, timespec, forever, addr, private)
+		// FUTEX_LOCK_PI always uses CLOCK_REALTIME, while FUTEX_LOCK_PI2
+		// defaults to CLOCK_MONOTONIC.
+		if cmd == linux.FUTEX_LOCK_PI {
+			clockRealtime = true
+		}
+		err := futexLockPI(t, clockRealtime, timespec, forever, addr, private)
 		return 0, nil, err
 
 	case linux.FUTEX_TRYLOCK_PI:
@@ -263,9 +318,29 @@ func Futex(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr,
 		err := t.Futex().UnlockPI(t, addr, uint32(t.ThreadID()), private)
 		return 0, nil, err
 
-	case linux.FUTEX_WAIT_REQUEUE_PI, linux.FUTEX_CMP_REQUEUE_PI:
-		t.Kernel().EmitUnimplementedEvent(t, sysno)
-		return 0, nil, linuxerr.ENOSYS
+	case linux.FUTEX_WAIT_REQUEUE_PI:
+		// WAIT_REQUEUE_PI uses an absolute timeout which is either
+		// CLOCK_MONOTONIC or CLOCK_REALTIME.
+		forever := (timeout == 0)
+
+		var timespec linux.Timespec
+		if !forever {
+			var err error
+			timespec, err = copyTimespecIn(t, timeout)
+			if err != nil {
+				return 0, nil, err
+			}
+		}
+		err := futexWaitRequeuePI(t, clockRealtime, timespec, forever, addr, naddr, private, uint32(val))
+		return 0, nil, err
+
+	case linux.FUTEX_CMP_REQUEUE_PI:
+		// Only one waiter can acquire the PI futex, so only one can be woken.
+		if val != 1 || nreq < 0 {
+			return 0, nil, linuxerr.EINVAL
+		}
+		n, err := t.Futex().CmpRequeuePI(t, addr, naddr, private, uint32(val3), nreq)
+		return uintptr(n), nil, err
 
 	default:
 		// We don't even know about this command.
@@ -322,3 +397,191 @@ func GetRobustList(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (u
 
 	return 0, nil, nil
 }
+
+// futex2Word returns the futex.Word for the futex2 word at addr with the given
+// FUTEX2_* flags, checking that val fits in the futex word.
+func futex2Word(addr hostarch.Addr, flags uint32, val uint64) (futex.Word, error) {
+	// FUTEX2_NUMA and FUTEX2_MPOL are not supported.
+	if flags&^(linux.FUTEX2_SIZE_MASK|linux.FUTEX2_PRIVATE) != 0 {
+		return futex.Word{}, linuxerr.EINVAL
+	}
+	size := uint(1) << (flags & linux.FUTEX2_SIZE_MASK)
+	if size < 8 && val>>(8*size) != 0 {
+		return futex.Word{}, linuxerr.EINVAL
+	}
+	return futex.Word{
+		Addr:    addr,
+		Size:    size,
+		Private: flags&linux.FUTEX2_PRIVATE != 0,
+	}, nil
+}
+
+// copyFutex2TimeoutIn copies in the absolute timeout of a futex2 syscall,
+// measured against clockid. forever is true if no timeout was given.
+func copyFutex2TimeoutIn(t *kernel.Task, addr hostarch.Addr, clockid int32) (ts linux.Timespec, forever bool, err error) {
+	if addr == 0 {
+		return linux.Timespec{}, true, nil
+	}
+	if clockid != linux.CLOCK_MONOTONIC && clockid != linux.CLOCK_REALTIME {
+		return linux.Timespec{}, false, linuxerr.EINVAL
+	}
+	ts, err = copyTimespecIn(t, addr)
+	if err != nil {
+		return linux.Timespec{}, false, err
+	}
+	if !ts.Valid() {
+		return linux.Timespec{}, false, linuxerr.EINVAL
+	}
+	return ts, false, nil
+}
+
+// futex2Block blocks until C receives, the absolute timeout ts on clockid
+// expires, or t is interrupted.
+func futex2Block(t *kernel.Task, C <-chan struct{}, ts linux.Timespec, forever bool, clockid int32) error {
+	if forever {
+		return t.Block(C)
+	}
+	if clockid == linux.CLOCK_REALTIME {
+		return t.BlockWithDeadlineFrom(C, t.Kernel().RealtimeClock(), true, ktime.FromTimespec(ts))
+	}
+	return t.BlockWithDeadline(C, true, ktime.FromTimespec(ts))
+}
+
+// copyFutexWaitvIn copies in and validates n struct futex_waitv at addr,
+// returning the futex words and the values they are expected to contain.
+func copyFutexWaitvIn(t *kernel.Task, addr hostarch.Addr, n int) ([]futex.Word, []uint64, error) {
+	waitv := make([]linux.FutexWaitv, n)
+	if _, err := linux.CopyFutexWaitvSliceIn(t, addr, waitv); err != nil {
+		return nil, nil, err
+	}
+	words := make([]futex.Word, n)
+	vals := make([]uint64, n)
+	for i := range waitv {
+		if waitv[i].Reserved != 0 {
+			return nil, nil, linuxerr.EINVAL
+		}
+		w, err := futex2Word(hostarch.Addr(waitv[i].Uaddr), waitv[i].Flags, waitv[i].Val)
+		if err != nil {
+			return nil, nil, err
+		}
+		words[i] = w
+		vals[i] = waitv[i].Val
+	}
+	return words, vals, nil
+}
+
+// FutexWaitv implements Linux syscall futex_waitv(2).
+func FutexWaitv(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	waitersAddr := args[0].Pointer()
+	nr := args[1].Uint()
+	flags := args[2].Uint()
+	timeout := args[3].Pointer()
+	clockid := args[4].Int()
+
+	if flags != 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	if nr == 0 || nr > linux.FUTEX_WAITV_MAX || waitersAddr == 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	ts, forever, err := copyFutex2TimeoutIn(t, timeout, clockid)
+	if err != nil {
+		return 0, nil, err
+	}
+	words, vals, err := copyFutexWaitvIn(t, waitersAddr, int(nr))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	ws := futex.NewWaiters(len(words))
+	woken, err := t.Futex().WaitvPrepare(ws, t, words, vals)
+	if err != nil {
+		return 0, nil, err
+	}
+	if woken >= 0 {
+		return uintptr(woken), nil, nil
+	}
+	err = futex2Block(t, ws[0].C, ts, forever, clockid)
+	// A wakeup takes precedence over a timeout or an interruption.
+	if woken := t.Futex().WaitvComplete(ws, t); woken >= 0 {
+		return uintptr(woken), nil, nil
+	}
+	return 0, nil, linuxerr.ConvertIntr(err, linuxerr.ERESTARTSYS)
+}
+
+// FutexWake implements Linux syscall futex_wake(2).
+func FutexWake(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	addr := args[0].Pointer()
+	mask := args[1].Uint64()
+	nr := int(args[2].Int())
+	flags := args[3].Uint()
+
+	w, err := futex2Word(addr, flags, mask)
+	if err != nil {
+		return 0, nil, err
+	}
+	// Like Linux, only the low 32 bits of mask are used.
+	if uint32(mask) == 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	// Unlike futex(FUTEX_WAKE), nr <= 0 wakes no waiters.
+	if nr <= 0 {
+		return 0, nil, nil
+	}
+	n, err := t.Futex().WakeWord(t, w, uint32(mask), nr)
+	return uintptr(n), nil, err
+}
+
+// FutexWait implements Linux syscall futex_wait(2).
+func FutexWait(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	addr := args[0].Pointer()
+	val := args[1].Uint64()
+	mask := args[2].Uint64()
+	flags := args[3].Uint()
+	timeout := args[4].Pointer()
+	clockid := args[5].Int()
+
+	w, err := futex2Word(addr, flags, val)
+	if err != nil {
+		return 0, nil, err
+	}
+	if _, err := futex2Word(addr, flags, mask); err != nil {
+		return 0, nil, err
+	}
+	if uint32(mask) == 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	ts, forever, err := copyFutex2TimeoutIn(t, timeout, clockid)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	waiter := t.FutexWaiter()
+	if err := t.Futex().WaitPrepareWord(waiter, t, w, val, uint32(mask)); err != nil {
+		return 0, nil, err
+	}
+	err = futex2Block(t, waiter.C, ts, forever, clockid)
+	t.Futex().WaitComplete(waiter, t)
+	return 0, nil, linuxerr.ConvertIntr(err, linuxerr.ERESTARTSYS)
+}
+
+// FutexRequeue implements Linux syscall futex_requeue(2).
+func FutexRequeue(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	waitersAddr := args[0].Pointer()
+	flags := args[1].Uint()
+	nrWake := int(args[2].Int())
+	nrRequeue := int(args[3].Int())
+
+	if flags != 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	if nrWake < 0 || nrRequeue < 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	words, vals, err := copyFutexWaitvIn(t, waitersAddr, 2)
+	if err != nil {
+		return 0, nil, err
+	}
+	n, err := t.Futex().RequeueWord(t, words[0], words[1], vals[0], nrWake, nrRequeue)
+	return uintptr(n), nil, err
+}
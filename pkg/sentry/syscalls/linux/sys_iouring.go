@@ -47,7 +47,7 @@ func IOUringSetup(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (ui
 	}
 
 	// List of currently supported flags in our IO_URING implementation.
-	const supportedFlags = 0 // Currently support none
+	const supportedFlags = linux.IORING_SETUP_SQPOLL | linux.IORING_SETUP_SQ_AFF | linux.IORING_SETUP_CQSIZE
 
 	// Since we don't implement everything, we fail explicitly on flags that are unimplemented.
 	if params.Flags|supportedFlags != supportedFlags {
@@ -58,8 +58,7 @@ func IOUringSetup(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (ui
 	iouringfd, err := iouringfs.New(t, vfsObj, entries, &params)
 
 	if err != nil {
-		// return 0, nil, err
-		return 0, nil, linuxerr.EPERM
+		return 0, nil, err
 	}
 	defer iouringfd.DecRef(t)
 
@@ -94,7 +93,7 @@ func IOUringEnter(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (ui
 	ret := -1
 
 	// List of currently supported flags for io_uring_enter(2).
-	const supportedFlags = linux.IORING_ENTER_GETEVENTS
+	const supportedFlags = linux.IORING_ENTER_GETEVENTS | linux.IORING_ENTER_SQ_WAKEUP | linux.IORING_ENTER_SQ_WAIT
 
 	// Since we don't implement everything, we fail explicitly on flags that are unimplemented.
 	if flags|supportedFlags != supportedFlags {
@@ -106,11 +105,6 @@ func IOUringEnter(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (ui
 		return uintptr(ret), nil, linuxerr.EFAULT
 	}
 
-	// If a user requested to submit zero SQEs, then we don't process any and return right away.
-	if toSubmit == 0 {
-		return uintptr(ret), nil, nil
-	}
-
 	file := t.GetFile(fd)
 	if file == nil {
 		return uintptr(ret), nil, linuxerr.EBADF
@@ -127,3 +121,30 @@ func IOUringEnter(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (ui
 
 	return uintptr(ret), nil, nil
 }
+
+// IOUringRegister implements linux syscall io_uring_register(2).
+func IOUringRegister(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	if !kernel.IOUringEnabled {
+		return 0, nil, linuxerr.ENOSYS
+	}
+
+	fd := int32(args[0].Int())
+	opcode := args[1].Uint()
+	arg := args[2].Pointer()
+	nrArgs := args[3].Uint()
+
+	file := t.GetFile(fd)
+	if file == nil {
+		return 0, nil, linuxerr.EBADF
+	}
+	defer file.DecRef(t)
+	iouringfd, ok := file.Impl().(*iouringfs.FileDescription)
+	if !ok {
+		return 0, nil, linuxerr.EOPNOTSUPP
+	}
+	ret, err := iouringfd.Register(t, opcode, arg, nrArgs)
+	if err != nil {
+		return 0, nil, err
+	}
+	return uintptr(ret), nil, nil
+}
@@ -0,0 +1,173 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linux
+
+import (
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/sentry/arch"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+)
+
+// landlockABIVersion is the Landlock ABI version returned by
+// landlock_create_ruleset(LANDLOCK_CREATE_RULESET_VERSION). Version 4 adds
+// network rules to version 3, which adds LANDLOCK_ACCESS_FS_TRUNCATE.
+const landlockABIVersion = 4
+
+// landlockRulesetAttrSizeVer0 is the size of the first version of struct
+// landlock_ruleset_attr, which only has the handled_access_fs field.
+const landlockRulesetAttrSizeVer0 = 8
+
+// LandlockCreateRuleset implements Linux syscall landlock_create_ruleset(2).
+func LandlockCreateRuleset(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	attrAddr := args[0].Pointer()
+	size := args[1].SizeT()
+	flags := args[2].Uint()
+
+	if flags == linux.LANDLOCK_CREATE_RULESET_VERSION {
+		if attrAddr != 0 || size != 0 {
+			return 0, nil, linuxerr.EINVAL
+		}
+		return landlockABIVersion, nil, nil
+	}
+	if flags != 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	if size > hostarch.PageSize {
+		return 0, nil, linuxerr.E2BIG
+	}
+	if size < landlockRulesetAttrSizeVer0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	var attr linux.LandlockRulesetAttr
+	// Fields that aren't provided by the caller are zero.
+	buf := make([]byte, attr.SizeBytes())
+	if int(size) > len(buf) {
+		buf = make([]byte, size)
+	}
+	if _, err := t.CopyInBytes(attrAddr, buf[:size]); err != nil {
+		return 0, nil, err
+	}
+	attr.UnmarshalBytes(buf)
+	// Fields from newer versions of struct landlock_ruleset_attr must be zero.
+	for _, b := range buf[attr.SizeBytes():] {
+		if b != 0 {
+			return 0, nil, linuxerr.E2BIG
+		}
+	}
+
+	if attr.HandledAccessFS&^vfs.LandlockAccessFS != 0 || attr.HandledAccessNet&^vfs.LandlockAccessNet != 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	if attr.HandledAccessFS == 0 && attr.HandledAccessNet == 0 {
+		return 0, nil, linuxerr.ENOMSG
+	}
+
+	file, err := vfs.NewLandlockRulesetFD(t, t.Kernel().VFS(), attr.HandledAccessFS, attr.HandledAccessNet)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer file.DecRef(t)
+	fd, err := t.NewFDFrom(0, file, kernel.FDFlags{
+		CloseOnExec: true,
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return uintptr(fd), nil, nil
+}
+
+// getLandlockRuleset returns the LandlockRuleset represented by fd. If
+// successful, the caller is responsible for releasing the reference on the
+// returned FileDescription.
+func getLandlockRuleset(t *kernel.Task, fd int32) (*vfs.LandlockRuleset, *vfs.FileDescription, error) {
+	file := t.GetFile(fd)
+	if file == nil {
+		return nil, nil, linuxerr.EBADF
+	}
+	r, ok := file.Impl().(*vfs.LandlockRuleset)
+	if !ok {
+		file.DecRef(t)
+		return nil, nil, linuxerr.EBADFD
+	}
+	return r, file, nil
+}
+
+// LandlockAddRule implements Linux syscall landlock_add_rule(2).
+func LandlockAddRule(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	rulesetFD := args[0].Int()
+	ruleType := args[1].Int()
+	attrAddr := args[2].Pointer()
+	flags := args[3].Uint()
+
+	if flags != 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	r, file, err := getLandlockRuleset(t, rulesetFD)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer file.DecRef(t)
+
+	switch ruleType {
+	case linux.LANDLOCK_RULE_PATH_BENEATH:
+		// struct landlock_path_beneath_attr is packed, so decode it by hand.
+		var buf [linux.SizeOfLandlockPathBeneathAttr]byte
+		if _, err := t.CopyInBytes(attrAddr, buf[:]); err != nil {
+			return 0, nil, err
+		}
+		access := hostarch.ByteOrder.Uint64(buf[0:])
+		parentFD := int32(hostarch.ByteOrder.Uint32(buf[8:]))
+		if access == 0 {
+			return 0, nil, linuxerr.ENOMSG
+		}
+		parent := t.GetFile(parentFD)
+		if parent == nil {
+			return 0, nil, linuxerr.EBADF
+		}
+		defer parent.DecRef(t)
+		return 0, nil, r.AddPathRule(t, parent.VirtualDentry(), access)
+
+	case linux.LANDLOCK_RULE_NET_PORT:
+		var attr linux.LandlockNetPortAttr
+		if _, err := attr.CopyIn(t, attrAddr); err != nil {
+			return 0, nil, err
+		}
+		return 0, nil, r.AddNetRule(attr.AllowedAccess, attr.Port)
+
+	default:
+		return 0, nil, linuxerr.EINVAL
+	}
+}
+
+// LandlockRestrictSelf implements Linux syscall landlock_restrict_self(2).
+func LandlockRestrictSelf(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	rulesetFD := args[0].Int()
+	flags := args[1].Uint()
+
+	if flags != 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	// No need to check for no_new_privs, which is assumed to always be set.
+	// See kernel.Task.updateCredsForExecLocked.
+	r, file, err := getLandlockRuleset(t, rulesetFD)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer file.DecRef(t)
+	return 0, nil, t.RestrictLandlock(r)
+}
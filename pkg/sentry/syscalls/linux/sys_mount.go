@@ -166,3 +166,402 @@ func Umount2(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr
 
 	return 0, nil, t.Kernel().VFS().UmountAt(t, creds, &tpop.pop, &opts)
 }
+
+// mayMount returns true if t may change the mounts in its mount namespace. It
+// is analogous to Linux's fs/namespace.c:may_mount().
+func mayMount(t *kernel.Task) bool {
+	return t.Credentials().HasCapabilityIn(linux.CAP_SYS_ADMIN, t.MountNamespace().Owner)
+}
+
+// OpenTree implements Linux syscall open_tree(2).
+func OpenTree(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	dirfd := args[0].Int()
+	pathAddr := args[1].Pointer()
+	flags := args[2].Uint()
+
+	const validFlags = linux.AT_EMPTY_PATH | linux.AT_NO_AUTOMOUNT | linux.AT_RECURSIVE | linux.AT_SYMLINK_NOFOLLOW | linux.OPEN_TREE_CLONE | linux.OPEN_TREE_CLOEXEC
+	if flags&^validFlags != 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	if flags&(linux.AT_RECURSIVE|linux.OPEN_TREE_CLONE) == linux.AT_RECURSIVE {
+		return 0, nil, linuxerr.EINVAL
+	}
+	clone := flags&linux.OPEN_TREE_CLONE != 0
+	if clone && !mayMount(t) {
+		return 0, nil, linuxerr.EPERM
+	}
+
+	path, err := copyInPath(t, pathAddr)
+	if err != nil {
+		return 0, nil, err
+	}
+	tpop, err := getTaskPathOperation(t, dirfd, path, shouldAllowEmptyPath(flags&linux.AT_EMPTY_PATH != 0), shouldFollowFinalSymlink(flags&linux.AT_SYMLINK_NOFOLLOW == 0))
+	if err != nil {
+		return 0, nil, err
+	}
+	defer tpop.Release(t)
+
+	var file *vfs.FileDescription
+	if clone {
+		file, err = t.Kernel().VFS().OpenTreeAt(t, t.Credentials(), &tpop.pop, flags&linux.AT_RECURSIVE != 0)
+	} else {
+		file, err = t.Kernel().VFS().OpenAt(t, t.Credentials(), &tpop.pop, &vfs.OpenOptions{
+			Flags: linux.O_PATH,
+		})
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	defer file.DecRef(t)
+
+	fd, err := t.NewFDFrom(0, file, kernel.FDFlags{
+		CloseOnExec: flags&linux.OPEN_TREE_CLOEXEC != 0,
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return uintptr(fd), nil, nil
+}
+
+// MoveMount implements Linux syscall move_mount(2).
+func MoveMount(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	fromDirfd := args[0].Int()
+	fromPathAddr := args[1].Pointer()
+	toDirfd := args[2].Int()
+	toPathAddr := args[3].Pointer()
+	flags := args[4].Uint()
+
+	if !mayMount(t) {
+		return 0, nil, linuxerr.EPERM
+	}
+	// MOVE_MOUNT_SET_GROUP and MOVE_MOUNT_BENEATH are not supported.
+	const validFlags = linux.MOVE_MOUNT_F_SYMLINKS | linux.MOVE_MOUNT_F_AUTOMOUNTS | linux.MOVE_MOUNT_F_EMPTY_PATH | linux.MOVE_MOUNT_T_SYMLINKS | linux.MOVE_MOUNT_T_AUTOMOUNTS | linux.MOVE_MOUNT_T_EMPTY_PATH
+	if flags&^validFlags != 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+
+	fromPath, err := copyInPath(t, fromPathAddr)
+	if err != nil {
+		return 0, nil, err
+	}
+	from, err := getTaskPathOperation(t, fromDirfd, fromPath, shouldAllowEmptyPath(flags&linux.MOVE_MOUNT_F_EMPTY_PATH != 0), shouldFollowFinalSymlink(flags&linux.MOVE_MOUNT_F_SYMLINKS != 0))
+	if err != nil {
+		return 0, nil, err
+	}
+	defer from.Release(t)
+	toPath, err := copyInPath(t, toPathAddr)
+	if err != nil {
+		return 0, nil, err
+	}
+	to, err := getTaskPathOperation(t, toDirfd, toPath, shouldAllowEmptyPath(flags&linux.MOVE_MOUNT_T_EMPTY_PATH != 0), shouldFollowFinalSymlink(flags&linux.MOVE_MOUNT_T_SYMLINKS != 0))
+	if err != nil {
+		return 0, nil, err
+	}
+	defer to.Release(t)
+
+	return 0, nil, t.Kernel().VFS().MoveMountAt(t, t.Credentials(), &from.pop, &to.pop)
+}
+
+// Fsopen implements Linux syscall fsopen(2).
+func Fsopen(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	fsNameAddr := args[0].Pointer()
+	flags := args[1].Uint()
+
+	if !mayMount(t) {
+		return 0, nil, linuxerr.EPERM
+	}
+	if flags&^linux.FSOPEN_CLOEXEC != 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	fsName, err := t.CopyInString(fsNameAddr, hostarch.PageSize)
+	if err != nil {
+		return 0, nil, err
+	}
+	file, err := t.Kernel().VFS().NewFilesystemContextFD(t, fsName)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer file.DecRef(t)
+
+	fd, err := t.NewFDFrom(0, file, kernel.FDFlags{
+		CloseOnExec: flags&linux.FSOPEN_CLOEXEC != 0,
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return uintptr(fd), nil, nil
+}
+
+// Fspick implements Linux syscall fspick(2).
+func Fspick(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	dirfd := args[0].Int()
+	pathAddr := args[1].Pointer()
+	flags := args[2].Uint()
+
+	if !mayMount(t) {
+		return 0, nil, linuxerr.EPERM
+	}
+	const validFlags = linux.FSPICK_CLOEXEC | linux.FSPICK_SYMLINK_NOFOLLOW | linux.FSPICK_NO_AUTOMOUNT | linux.FSPICK_EMPTY_PATH
+	if flags&^validFlags != 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+
+	path, err := copyInPath(t, pathAddr)
+	if err != nil {
+		return 0, nil, err
+	}
+	tpop, err := getTaskPathOperation(t, dirfd, path, shouldAllowEmptyPath(flags&linux.FSPICK_EMPTY_PATH != 0), shouldFollowFinalSymlink(flags&linux.FSPICK_SYMLINK_NOFOLLOW == 0))
+	if err != nil {
+		return 0, nil, err
+	}
+	defer tpop.Release(t)
+
+	file, err := t.Kernel().VFS().PickFilesystemContextFD(t, t.Credentials(), &tpop.pop)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer file.DecRef(t)
+
+	fd, err := t.NewFDFrom(0, file, kernel.FDFlags{
+		CloseOnExec: flags&linux.FSPICK_CLOEXEC != 0,
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return uintptr(fd), nil, nil
+}
+
+// fsconfigKeyMax is the maximum length of a parameter name passed to
+// fsconfig(2), including the terminating NUL.
+const fsconfigKeyMax = 256
+
+// Fsconfig implements Linux syscall fsconfig(2).
+func Fsconfig(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	fd := args[0].Int()
+	cmd := args[1].Uint()
+	keyAddr := args[2].Pointer()
+	valueAddr := args[3].Pointer()
+	aux := args[4].Int()
+
+	if fd < 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	switch cmd {
+	case linux.FSCONFIG_SET_FLAG:
+		if keyAddr == 0 || valueAddr != 0 || aux != 0 {
+			return 0, nil, linuxerr.EINVAL
+		}
+	case linux.FSCONFIG_SET_STRING:
+		if keyAddr == 0 || valueAddr == 0 || aux != 0 {
+			return 0, nil, linuxerr.EINVAL
+		}
+	case linux.FSCONFIG_SET_BINARY:
+		if keyAddr == 0 || valueAddr == 0 || aux <= 0 || aux > 1024*1024 {
+			return 0, nil, linuxerr.EINVAL
+		}
+	case linux.FSCONFIG_SET_PATH, linux.FSCONFIG_SET_PATH_EMPTY:
+		if keyAddr == 0 || valueAddr == 0 || (aux != linux.AT_FDCWD && aux < 0) {
+			return 0, nil, linuxerr.EINVAL
+		}
+	case linux.FSCONFIG_SET_FD:
+		if keyAddr == 0 || valueAddr != 0 || aux < 0 {
+			return 0, nil, linuxerr.EINVAL
+		}
+	case linux.FSCONFIG_CMD_CREATE, linux.FSCONFIG_CMD_CREATE_EXCL, linux.FSCONFIG_CMD_RECONFIGURE:
+		if keyAddr != 0 || valueAddr != 0 || aux != 0 {
+			return 0, nil, linuxerr.EINVAL
+		}
+	default:
+		return 0, nil, linuxerr.EOPNOTSUPP
+	}
+
+	file := t.GetFile(fd)
+	if file == nil {
+		return 0, nil, linuxerr.EBADF
+	}
+	defer file.DecRef(t)
+	fc, ok := file.Impl().(*vfs.FilesystemContext)
+	if !ok {
+		return 0, nil, linuxerr.EINVAL
+	}
+
+	var key string
+	if keyAddr != 0 {
+		var err error
+		key, err = t.CopyInString(keyAddr, fsconfigKeyMax)
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+	switch cmd {
+	case linux.FSCONFIG_SET_FLAG:
+		return 0, nil, fc.SetFlag(key)
+	case linux.FSCONFIG_SET_STRING:
+		value, err := t.CopyInString(valueAddr, hostarch.PageSize)
+		if err != nil {
+			return 0, nil, err
+		}
+		return 0, nil, fc.SetString(key, value)
+	case linux.FSCONFIG_CMD_CREATE, linux.FSCONFIG_CMD_CREATE_EXCL:
+		// Filesystems are never shared between contexts, so
+		// FSCONFIG_CMD_CREATE_EXCL is the same as FSCONFIG_CMD_CREATE.
+		return 0, nil, fc.Create(t, t.Credentials())
+	case linux.FSCONFIG_CMD_RECONFIGURE:
+		return 0, nil, fc.Reconfigure(t)
+	default:
+		// Like Linux's legacy filesystem contexts, only flag and string
+		// parameters are supported.
+		return 0, nil, linuxerr.EINVAL
+	}
+}
+
+// mountAttrAtime returns an error if attr contains an invalid access time
+// attribute.
+func mountAttrAtime(attr uint64) error {
+	switch attr & linux.MOUNT_ATTR__ATIME {
+	case linux.MOUNT_ATTR_RELATIME, linux.MOUNT_ATTR_NOATIME, linux.MOUNT_ATTR_STRICTATIME:
+		return nil
+	default:
+		return linuxerr.EINVAL
+	}
+}
+
+// Fsmount implements Linux syscall fsmount(2).
+func Fsmount(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	fsfd := args[0].Int()
+	flags := args[1].Uint()
+	attrFlags := uint64(args[2].Uint())
+
+	if !mayMount(t) {
+		return 0, nil, linuxerr.EPERM
+	}
+	if flags&^linux.FSMOUNT_CLOEXEC != 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	// MOUNT_ATTR_NODIRATIME and MOUNT_ATTR_NOSYMFOLLOW are not supported.
+	const validAttrs = linux.MOUNT_ATTR_RDONLY | linux.MOUNT_ATTR_NOSUID | linux.MOUNT_ATTR_NODEV | linux.MOUNT_ATTR_NOEXEC | linux.MOUNT_ATTR__ATIME
+	if attrFlags&^validAttrs != 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	if err := mountAttrAtime(attrFlags); err != nil {
+		return 0, nil, err
+	}
+
+	file := t.GetFile(fsfd)
+	if file == nil {
+		return 0, nil, linuxerr.EBADF
+	}
+	defer file.DecRef(t)
+	fc, ok := file.Impl().(*vfs.FilesystemContext)
+	if !ok {
+		return 0, nil, linuxerr.EINVAL
+	}
+
+	mntFile, err := fc.Mount(t, vfs.MountOptions{
+		Flags: vfs.MountFlags{
+			NoSUID:  attrFlags&linux.MOUNT_ATTR_NOSUID != 0,
+			NoDev:   attrFlags&linux.MOUNT_ATTR_NODEV != 0,
+			NoExec:  attrFlags&linux.MOUNT_ATTR_NOEXEC != 0,
+			NoATime: attrFlags&linux.MOUNT_ATTR__ATIME == linux.MOUNT_ATTR_NOATIME,
+		},
+		ReadOnly: attrFlags&linux.MOUNT_ATTR_RDONLY != 0,
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	defer mntFile.DecRef(t)
+
+	fd, err := t.NewFDFrom(0, mntFile, kernel.FDFlags{
+		CloseOnExec: flags&linux.FSMOUNT_CLOEXEC != 0,
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return uintptr(fd), nil, nil
+}
+
+// MountSetattr implements Linux syscall mount_setattr(2).
+func MountSetattr(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	dirfd := args[0].Int()
+	pathAddr := args[1].Pointer()
+	flags := args[2].Uint()
+	attrAddr := args[3].Pointer()
+	size := args[4].SizeT()
+
+	const validFlags = linux.AT_EMPTY_PATH | linux.AT_RECURSIVE | linux.AT_SYMLINK_NOFOLLOW | linux.AT_NO_AUTOMOUNT
+	if flags&^validFlags != 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	if size > hostarch.PageSize {
+		return 0, nil, linuxerr.E2BIG
+	}
+	if size < linux.MOUNT_ATTR_SIZE_VER0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	if !mayMount(t) {
+		return 0, nil, linuxerr.EPERM
+	}
+	var attr linux.MountAttr
+	if _, err := attr.CopyIn(t, attrAddr); err != nil {
+		return 0, nil, err
+	}
+	if size > linux.MOUNT_ATTR_SIZE_VER0 {
+		// Fields from newer versions of struct mount_attr must be zero.
+		rest := make([]byte, size-linux.MOUNT_ATTR_SIZE_VER0)
+		if _, err := t.CopyInBytes(attrAddr+linux.MOUNT_ATTR_SIZE_VER0, rest); err != nil {
+			return 0, nil, err
+		}
+		for _, b := range rest {
+			if b != 0 {
+				return 0, nil, linuxerr.E2BIG
+			}
+		}
+	}
+
+	// MS_UNBINDABLE is not supported.
+	switch attr.Propagation {
+	case 0, linux.MS_SHARED, linux.MS_PRIVATE, linux.MS_SLAVE:
+	default:
+		return 0, nil, linuxerr.EINVAL
+	}
+	// Idmapped mounts (MOUNT_ATTR_IDMAP) are not supported.
+	const validAttrs = linux.MOUNT_ATTR_RDONLY | linux.MOUNT_ATTR_NOSUID | linux.MOUNT_ATTR_NODEV | linux.MOUNT_ATTR_NOEXEC | linux.MOUNT_ATTR__ATIME | linux.MOUNT_ATTR_NODIRATIME | linux.MOUNT_ATTR_NOSYMFOLLOW
+	if (attr.AttrSet|attr.AttrClr)&^validAttrs != 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	// The access time attributes are an enumeration, so they can only be
+	// changed by clearing all of them and setting one.
+	if attr.AttrClr&linux.MOUNT_ATTR__ATIME != 0 {
+		if attr.AttrClr&linux.MOUNT_ATTR__ATIME != linux.MOUNT_ATTR__ATIME {
+			return 0, nil, linuxerr.EINVAL
+		}
+		if err := mountAttrAtime(attr.AttrSet); err != nil {
+			return 0, nil, err
+		}
+	} else if attr.AttrSet&linux.MOUNT_ATTR__ATIME != 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	if attr.AttrSet&(linux.MOUNT_ATTR_NODIRATIME|linux.MOUNT_ATTR_NOSYMFOLLOW) != 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	if attr.AttrSet == 0 && attr.AttrClr == 0 && attr.Propagation == 0 {
+		return 0, nil, nil
+	}
+
+	path, err := copyInPath(t, pathAddr)
+	if err != nil {
+		return 0, nil, err
+	}
+	tpop, err := getTaskPathOperation(t, dirfd, path, shouldAllowEmptyPath(flags&linux.AT_EMPTY_PATH != 0), shouldFollowFinalSymlink(flags&linux.AT_SYMLINK_NOFOLLOW == 0))
+	if err != nil {
+		return 0, nil, err
+	}
+	defer tpop.Release(t)
+
+	return 0, nil, t.Kernel().VFS().SetMountAttrAt(t, t.Credentials(), &tpop.pop, &vfs.SetMountAttrOptions{
+		AttrSet:     attr.AttrSet,
+		AttrClr:     attr.AttrClr,
+		Propagation: uint32(attr.Propagation),
+		Recursive:   flags&linux.AT_RECURSIVE != 0,
+	})
+}
@@ -16,9 +16,18 @@ package linux
 
 import (
 	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/marshal/primitive"
 	"gvisor.dev/gvisor/pkg/sentry/arch"
+	"gvisor.dev/gvisor/pkg/sentry/fsimpl/mqfs"
 	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/mq"
+	"gvisor.dev/gvisor/pkg/sentry/ktime"
+	"gvisor.dev/gvisor/pkg/sentry/socket"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
 )
 
 // MqOpen implements mq_open(2).
@@ -78,6 +87,352 @@ func MqUnlink(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintpt
 	return 0, nil, t.IPCNamespace().PosixQueues().Remove(t, name)
 }
 
+// MqTimedsend implements mq_timedsend(2).
+func MqTimedsend(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	mqdes := args[0].Int()
+	msgAddr := args[1].Pointer()
+	msgLen := args[2].SizeT()
+	msgPrio := args[3].Uint()
+	timeoutAddr := args[4].Pointer()
+
+	b, err := newMqBlocker(t, timeoutAddr)
+	if err != nil {
+		return 0, nil, err
+	}
+	if msgPrio >= linux.MQ_PRIO_MAX {
+		return 0, nil, linuxerr.EINVAL
+	}
+
+	file, view, err := getMqueue(t, mqdes)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer file.DecRef(t)
+	if !file.IsWritable() {
+		return 0, nil, linuxerr.EBADF
+	}
+
+	// Check the size before copying in the message to avoid allocating an
+	// arbitrarily large buffer.
+	if uint64(msgLen) > uint64(view.Attr().MqMsgsize) {
+		return 0, nil, linuxerr.EMSGSIZE
+	}
+	text := make([]byte, msgLen)
+	if _, err := t.CopyInBytes(msgAddr, text); err != nil {
+		return 0, nil, err
+	}
+
+	msg := &mq.Message{
+		Text:     string(text),
+		Size:     uint64(msgLen),
+		Priority: msgPrio,
+	}
+	block := file.StatusFlags()&linux.O_NONBLOCK == 0
+	return 0, nil, view.Send(t, msg, b, block)
+}
+
+// MqTimedreceive implements mq_timedreceive(2).
+func MqTimedreceive(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	mqdes := args[0].Int()
+	msgAddr := args[1].Pointer()
+	msgLen := args[2].SizeT()
+	prioAddr := args[3].Pointer()
+	timeoutAddr := args[4].Pointer()
+
+	b, err := newMqBlocker(t, timeoutAddr)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	file, view, err := getMqueue(t, mqdes)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer file.DecRef(t)
+	if !file.IsReadable() {
+		return 0, nil, linuxerr.EBADF
+	}
+
+	block := file.StatusFlags()&linux.O_NONBLOCK == 0
+	msg, err := view.Receive(t, b, uint64(msgLen), block)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	// Similar to Linux, the message is lost if it can't be copied out.
+	if _, err := t.CopyOutBytes(msgAddr, []byte(msg.Text)); err != nil {
+		return 0, nil, err
+	}
+	if prioAddr != 0 {
+		if _, err := primitive.CopyUint32Out(t, prioAddr, msg.Priority); err != nil {
+			return 0, nil, err
+		}
+	}
+	return uintptr(msg.Size), nil, nil
+}
+
+// MqNotify implements mq_notify(2).
+func MqNotify(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	mqdes := args[0].Int()
+	sevpAddr := args[1].Pointer()
+
+	var sev linux.Sigevent
+	if sevpAddr != 0 {
+		if _, err := sev.CopyIn(t, sevpAddr); err != nil {
+			return 0, nil, err
+		}
+		switch sev.Notify {
+		case linux.SIGEV_NONE, linux.SIGEV_THREAD:
+		case linux.SIGEV_SIGNAL:
+			// Like Linux, accept a signal number of 0, in which case no
+			// signal is sent.
+			if sev.Signo != 0 && !linux.Signal(sev.Signo).IsValid() {
+				return 0, nil, linuxerr.EINVAL
+			}
+		default:
+			return 0, nil, linuxerr.EINVAL
+		}
+	}
+
+	file, view, err := getMqueue(t, mqdes)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer file.DecRef(t)
+
+	if sevpAddr == 0 {
+		// Remove the calling process's registration, if any.
+		view.Flush(t)
+		return 0, nil, nil
+	}
+
+	switch sev.Notify {
+	case linux.SIGEV_NONE:
+		return 0, nil, view.Subscribe(t, sev.Notify, sev.Signo, nil)
+	case linux.SIGEV_SIGNAL:
+		n := &mqSignalNotifier{
+			tg:     t.ThreadGroup(),
+			userNS: t.UserNamespace(),
+			signo:  linux.Signal(sev.Signo),
+			value:  sev.Value,
+		}
+		return 0, nil, view.Subscribe(t, sev.Notify, sev.Signo, n)
+	default: // linux.SIGEV_THREAD
+		n, err := newMqThreadNotifier(t, sev.Signo, hostarch.Addr(sev.Value))
+		if err != nil {
+			return 0, nil, err
+		}
+		if err := view.Subscribe(t, sev.Notify, sev.Signo, n); err != nil {
+			n.file.DecRef(t)
+			return 0, nil, err
+		}
+		return 0, nil, nil
+	}
+}
+
+// MqGetsetattr implements mq_getsetattr(2).
+func MqGetsetattr(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	mqdes := args[0].Int()
+	newAttrAddr := args[1].Pointer()
+	oldAttrAddr := args[2].Pointer()
+
+	var newAttr linux.MqAttr
+	if newAttrAddr != 0 {
+		if _, err := newAttr.CopyIn(t, newAttrAddr); err != nil {
+			return 0, nil, err
+		}
+		// Only O_NONBLOCK can be changed, all other fields are ignored.
+		if newAttr.MqFlags&^linux.O_NONBLOCK != 0 {
+			return 0, nil, linuxerr.EINVAL
+		}
+	}
+
+	file, view, err := getMqueue(t, mqdes)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer file.DecRef(t)
+
+	flags := file.StatusFlags()
+	oldAttr := view.Attr()
+	oldAttr.MqFlags = int64(flags & linux.O_NONBLOCK)
+
+	if newAttrAddr != 0 {
+		flags = flags&^linux.O_NONBLOCK | uint32(newAttr.MqFlags)
+		if err := file.SetStatusFlags(t, t.Credentials(), flags); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	if oldAttrAddr != 0 {
+		if _, err := oldAttr.CopyOut(t, oldAttrAddr); err != nil {
+			return 0, nil, err
+		}
+	}
+	return 0, nil, nil
+}
+
+// getMqueue returns the file description and message queue view for mqdes.
+// On success, the caller must release the returned file description.
+func getMqueue(t *kernel.Task, mqdes int32) (*vfs.FileDescription, mq.View, error) {
+	file := t.GetFile(mqdes)
+	if file == nil {
+		return nil, nil, linuxerr.EBADF
+	}
+	view, ok := mqfs.ViewFromFD(file)
+	if !ok {
+		file.DecRef(t)
+		return nil, nil, linuxerr.EBADF
+	}
+	return file, view, nil
+}
+
+// mqBlocker implements mq.Blocker, enforcing the absolute CLOCK_REALTIME
+// timeout passed to mq_timedsend(2) and mq_timedreceive(2).
+type mqBlocker struct {
+	t            *kernel.Task
+	haveDeadline bool
+	deadline     ktime.Time
+}
+
+// newMqBlocker returns a new mqBlocker using the timeout at timeoutAddr, if
+// any.
+func newMqBlocker(t *kernel.Task, timeoutAddr hostarch.Addr) (*mqBlocker, error) {
+	b := &mqBlocker{t: t}
+	if timeoutAddr != 0 {
+		var ts linux.Timespec
+		if _, err := ts.CopyIn(t, timeoutAddr); err != nil {
+			return nil, err
+		}
+		if !ts.Valid() {
+			return nil, linuxerr.EINVAL
+		}
+		b.haveDeadline = true
+		b.deadline = ktime.FromTimespec(ts)
+	}
+	return b, nil
+}
+
+// Block implements mq.Blocker.Block.
+func (b *mqBlocker) Block(C <-chan struct{}) error {
+	err := b.t.BlockWithDeadlineFrom(C, b.t.Kernel().RealtimeClock(), b.haveDeadline, b.deadline)
+	if err == linuxerr.ErrInterrupted {
+		// The timeout is absolute, so the syscall can be restarted.
+		return linuxerr.ERESTARTSYS
+	}
+	return err
+}
+
+// mqSignalNotifier implements mq.Notifier for SIGEV_SIGNAL notifications.
+//
+// +stateify savable
+type mqSignalNotifier struct {
+	// tg is the thread group that registered for notification.
+	tg *kernel.ThreadGroup
+
+	// userNS is the user namespace of the registering task, used to
+	// translate the sender's UID.
+	userNS *auth.UserNamespace
+
+	// signo is the signal to send. If signo is 0, no signal is sent.
+	signo linux.Signal
+
+	// value is sent as the signal's si_value.
+	value uint64
+}
+
+// Notify implements mq.Notifier.Notify.
+func (n *mqSignalNotifier) Notify(ctx context.Context) {
+	if n.signo == 0 {
+		return
+	}
+	info := &linux.SignalInfo{
+		Signo: int32(n.signo),
+		Code:  linux.SI_MESGQ,
+	}
+	info.SetSigval(n.value)
+	if sender := kernel.TaskFromContext(ctx); sender != nil {
+		info.SetPID(int32(n.tg.PIDNamespace().IDOfThreadGroup(sender.ThreadGroup())))
+		info.SetUID(int32(sender.Credentials().RealKUID.In(n.userNS).OrOverflow()))
+	}
+	// The registering process may have exited, in which case the
+	// notification is silently dropped.
+	n.tg.SendSignal(info)
+}
+
+// Remove implements mq.Notifier.Remove.
+func (n *mqSignalNotifier) Remove(context.Context) {}
+
+// mqNotificationSocket is implemented by netlink sockets, which receive
+// SIGEV_THREAD notifications.
+type mqNotificationSocket interface {
+	SendNotification(ctx context.Context, data []byte) error
+}
+
+// mqThreadNotifier implements mq.Notifier for SIGEV_THREAD notifications.
+// The C library implements SIGEV_THREAD by waiting on a netlink socket for a
+// cookie sent by the kernel, and running the notification function in a new
+// thread.
+//
+// +stateify savable
+type mqThreadNotifier struct {
+	// file is the netlink socket's file description. mqThreadNotifier holds a
+	// reference on file until the notification is delivered or removed.
+	file *vfs.FileDescription
+
+	// cookie is sent to the socket. Its last byte is set to
+	// NOTIFY_WOKENUP or NOTIFY_REMOVED.
+	cookie [linux.NOTIFY_COOKIE_LEN]byte
+}
+
+// newMqThreadNotifier returns a new mqThreadNotifier using the netlink socket
+// sockFD and the cookie at cookieAddr.
+func newMqThreadNotifier(t *kernel.Task, sockFD int32, cookieAddr hostarch.Addr) (*mqThreadNotifier, error) {
+	n := &mqThreadNotifier{}
+	if _, err := t.CopyInBytes(cookieAddr, n.cookie[:]); err != nil {
+		return nil, err
+	}
+
+	file := t.GetFile(sockFD)
+	if file == nil {
+		return nil, linuxerr.EBADF
+	}
+	s, ok := file.Impl().(socket.Socket)
+	if !ok {
+		file.DecRef(t)
+		return nil, linuxerr.ENOTSOCK
+	}
+	if family, _, _ := s.Type(); family != linux.AF_NETLINK {
+		file.DecRef(t)
+		return nil, linuxerr.EINVAL
+	}
+	if _, ok := file.Impl().(mqNotificationSocket); !ok {
+		file.DecRef(t)
+		return nil, linuxerr.EINVAL
+	}
+	n.file = file
+	return n, nil
+}
+
+// Notify implements mq.Notifier.Notify.
+func (n *mqThreadNotifier) Notify(ctx context.Context) {
+	n.send(ctx, linux.NOTIFY_WOKENUP)
+}
+
+// Remove implements mq.Notifier.Remove.
+func (n *mqThreadNotifier) Remove(ctx context.Context) {
+	n.send(ctx, linux.NOTIFY_REMOVED)
+}
+
+// send delivers the cookie with the given code to the socket, and releases
+// the reference on the socket.
+func (n *mqThreadNotifier) send(ctx context.Context, code byte) {
+	n.cookie[linux.NOTIFY_COOKIE_LEN-1] = code
+	// Delivery is best effort, like Linux.
+	n.file.Impl().(mqNotificationSocket).SendNotification(ctx, n.cookie[:])
+	n.file.DecRef(ctx)
+}
+
 func openOpts(name string, rOnly, wOnly, readWrite, create, exclusive, block bool) mq.OpenOpts {
 	var access mq.AccessType
 	switch {
@@ -0,0 +1,182 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linux
+
+import (
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/sentry/arch"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+)
+
+// PidfdOpen implements Linux syscall pidfd_open(2).
+func PidfdOpen(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	pid := kernel.ThreadID(args[0].Int())
+	flags := args[1].Uint()
+
+	if flags&^linux.PIDFD_NONBLOCK != 0 || pid <= 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	target := t.PIDNamespace().TaskWithID(pid)
+	if target == nil {
+		return 0, nil, linuxerr.ESRCH
+	}
+	// pidfds can only refer to thread groups, through their leader.
+	tg := target.ThreadGroup()
+	if tg.Leader() != target {
+		return 0, nil, linuxerr.EINVAL
+	}
+
+	file, err := t.Kernel().NewPIDFD(t, tg, flags&linux.PIDFD_NONBLOCK)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer file.DecRef(t)
+
+	// "The close-on-exec flag is set on the file descriptor." - pidfd_open(2)
+	fd, err := t.NewFDFrom(0, file, kernel.FDFlags{
+		CloseOnExec: true,
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return uintptr(fd), nil, nil
+}
+
+// PidfdSendSignal implements Linux syscall pidfd_send_signal(2).
+func PidfdSendSignal(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	pidfd := args[0].Int()
+	sig := linux.Signal(args[1].Int())
+	infoAddr := args[2].Pointer()
+	flags := args[3].Uint()
+
+	if flags != 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	tg, _, err := getPIDFDThreadGroup(t, pidfd)
+	if err != nil {
+		return 0, nil, err
+	}
+	if tg.ID() == 0 {
+		// The thread group has been reaped.
+		return 0, nil, linuxerr.ESRCH
+	}
+	// The thread group must be in t's PID namespace or one of its
+	// descendants.
+	if t.PIDNamespace().IDOfThreadGroup(tg) == 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	if sig != 0 && !sig.IsValid() {
+		return 0, nil, linuxerr.EINVAL
+	}
+	target := tg.Leader()
+
+	var info *linux.SignalInfo
+	if infoAddr != 0 {
+		info = &linux.SignalInfo{}
+		if _, err := info.CopyIn(t, infoAddr); err != nil {
+			return 0, nil, err
+		}
+		// Unlike rt_sigqueueinfo(2), the signal number in the info must match.
+		if info.Signo != int32(sig) {
+			return 0, nil, linuxerr.EINVAL
+		}
+		// If the sender is not the receiver, it can't use si_codes used by
+		// the kernel or SI_TKILL. See RtSigqueueinfo.
+		if (info.Code >= 0 || info.Code == linux.SI_TKILL) && tg != t.ThreadGroup() {
+			return 0, nil, linuxerr.EPERM
+		}
+	} else {
+		info = &linux.SignalInfo{
+			Signo: int32(sig),
+			Code:  linux.SI_USER,
+		}
+		info.SetPID(int32(tg.PIDNamespace().IDOfTask(t)))
+		info.SetUID(int32(t.Credentials().RealKUID.In(target.UserNamespace()).OrOverflow()))
+	}
+
+	if !mayKill(t, target, sig) {
+		return 0, nil, linuxerr.EPERM
+	}
+	return 0, nil, tg.SendSignal(info)
+}
+
+// PidfdGetfd implements Linux syscall pidfd_getfd(2).
+func PidfdGetfd(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	pidfd := args[0].Int()
+	targetfd := args[1].Int()
+	flags := args[2].Uint()
+
+	if flags != 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	tg, _, err := getPIDFDThreadGroup(t, pidfd)
+	if err != nil {
+		return 0, nil, err
+	}
+	target := tg.Leader()
+	if tg.ID() == 0 || target == nil {
+		return 0, nil, linuxerr.ESRCH
+	}
+
+	// "Permission to duplicate another process's file descriptor is governed
+	// by a ptrace access mode PTRACE_MODE_ATTACH_REALCREDS check" -
+	// pidfd_getfd(2)
+	if !t.CanTrace(target, true /* attach */) {
+		return 0, nil, linuxerr.EPERM
+	}
+
+	var fdTable *kernel.FDTable
+	target.WithMuLocked(func(target *kernel.Task) {
+		if fdTable = target.FDTable(); fdTable != nil {
+			fdTable.IncRef()
+		}
+	})
+	if fdTable == nil {
+		// The target has exited.
+		return 0, nil, linuxerr.EBADF
+	}
+	file, _ := fdTable.Get(targetfd)
+	fdTable.DecRef(t)
+	if file == nil {
+		return 0, nil, linuxerr.EBADF
+	}
+	defer file.DecRef(t)
+
+	// "The close-on-exec flag (FD_CLOEXEC; see fcntl(2)) is set on the file
+	// descriptor returned by pidfd_getfd()." - pidfd_getfd(2)
+	fd, err := t.NewFDFrom(0, file, kernel.FDFlags{
+		CloseOnExec: true,
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return uintptr(fd), nil, nil
+}
+
+// getPIDFDThreadGroup returns the thread group referred to by the pidfd fd,
+// and whether the pidfd is non-blocking.
+func getPIDFDThreadGroup(t *kernel.Task, fd int32) (*kernel.ThreadGroup, bool, error) {
+	file := t.GetFile(fd)
+	if file == nil {
+		return nil, false, linuxerr.EBADF
+	}
+	defer file.DecRef(t)
+	pidfd, ok := file.Impl().(*kernel.PIDFD)
+	if !ok {
+		return nil, false, linuxerr.EBADF
+	}
+	return pidfd.ThreadGroup(), file.StatusFlags()&linux.O_NONBLOCK != 0, nil
+}
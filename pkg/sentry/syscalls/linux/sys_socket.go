@@ -275,10 +275,29 @@ func Connect(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr
 		return 0, nil, err
 	}
 
+	if err := checkLandlockNet(t, s, a, linux.LANDLOCK_ACCESS_NET_CONNECT_TCP); err != nil {
+		return 0, nil, err
+	}
+
 	blocking := (file.StatusFlags() & linux.SOCK_NONBLOCK) == 0
 	return 0, nil, linuxerr.ConvertIntr(s.Connect(t, a, blocking).ToError(), linuxerr.ERESTARTSYS)
 }
 
+// checkLandlockNet returns EACCES if t's Landlock domain denies access to the
+// TCP port in addr. Only TCP sockets are restricted, and invalid addresses are
+// left for the socket to reject.
+func checkLandlockNet(t *kernel.Task, s socket.Socket, addr []byte, access uint64) error {
+	d := t.LandlockDomain()
+	if d == nil || !socket.IsTCP(s) {
+		return nil
+	}
+	a, family, err := socket.AddressAndFamily(addr)
+	if err != nil || (family != linux.AF_INET && family != linux.AF_INET6) {
+		return nil
+	}
+	return d.CheckNet(access, a.Port)
+}
+
 // accept is the implementation of the accept syscall. It is called by accept
 // and accept4 syscall handlers.
 func accept(t *kernel.Task, fd int32, addr hostarch.Addr, addrLen hostarch.Addr, flags int) (uintptr, error) {
@@ -365,6 +384,10 @@ func Bind(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *
 		return 0, nil, err
 	}
 
+	if err := checkLandlockNet(t, s, a, linux.LANDLOCK_ACCESS_NET_BIND_TCP); err != nil {
+		return 0, nil, err
+	}
+
 	return 0, nil, s.Bind(t, a).ToError()
 }
 
@@ -974,7 +997,7 @@ func SendMsg(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr
 	}
 
 	// Reject flags that we don't handle yet.
-	if flags & ^(linux.MSG_DONTWAIT|linux.MSG_EOR|linux.MSG_MORE|linux.MSG_NOSIGNAL) != 0 {
+	if flags & ^(linux.MSG_DONTWAIT|linux.MSG_EOR|linux.MSG_MORE|linux.MSG_NOSIGNAL|linux.MSG_FASTOPEN|linux.MSG_ZEROCOPY) != 0 {
 		return 0, nil, linuxerr.EINVAL
 	}
 
@@ -1016,7 +1039,7 @@ func SendMMsg(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintpt
 	}
 
 	// Reject flags that we don't handle yet.
-	if flags & ^(linux.MSG_DONTWAIT|linux.MSG_EOR|linux.MSG_MORE|linux.MSG_NOSIGNAL) != 0 {
+	if flags & ^(linux.MSG_DONTWAIT|linux.MSG_EOR|linux.MSG_MORE|linux.MSG_NOSIGNAL|linux.MSG_FASTOPEN|linux.MSG_ZEROCOPY) != 0 {
 		return 0, nil, linuxerr.EINVAL
 	}
 
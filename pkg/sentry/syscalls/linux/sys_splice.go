@@ -19,6 +19,7 @@ import (
 
 	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/hostarch"
 	"gvisor.dev/gvisor/pkg/log"
 	"gvisor.dev/gvisor/pkg/marshal/primitive"
 	"gvisor.dev/gvisor/pkg/sentry/arch"
@@ -266,6 +267,71 @@ func Tee(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *k
 	return uintptr(n), nil, HandleIOError(t, n != 0, err, linuxerr.ERESTARTSYS, "tee", inFile)
 }
 
+// Vmsplice implements Linux syscall vmsplice(2).
+func Vmsplice(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	fd := args[0].Int()
+	addr := args[1].Pointer()
+	iovcnt := int(args[2].Int())
+	flags := args[3].Int()
+
+	// Check for invalid flags.
+	if flags&^(linux.SPLICE_F_MOVE|linux.SPLICE_F_NONBLOCK|linux.SPLICE_F_MORE|linux.SPLICE_F_GIFT) != 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+
+	file := t.GetFile(fd)
+	if file == nil {
+		return 0, nil, linuxerr.EBADF
+	}
+	defer file.DecRef(t)
+
+	// The file description must represent a pipe.
+	if _, ok := file.Impl().(*pipe.VFSPipeFD); !ok {
+		return 0, nil, linuxerr.EBADF
+	}
+	nonBlock := file.StatusFlags()&linux.O_NONBLOCK != 0 || flags&linux.SPLICE_F_NONBLOCK != 0
+
+	// As in Linux, data is moved into the pipe if it is writable, and out of
+	// it otherwise.
+	toPipe := file.IsWritable()
+	ioseq, err := t.IovecsIOSequence(addr, iovcnt, usermem.IOOpts{
+		AddressSpaceActive: true,
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	// Unlike Linux, the data moved into the pipe is copied rather than
+	// referenced, so the application may reuse its memory as soon as
+	// vmsplice returns whether or not SPLICE_F_GIFT is set; the pipe's
+	// copy is then spliced onwards without copying.
+	mask := eventMaskRead
+	if toPipe {
+		mask = eventMaskWrite
+	}
+	w, ch := waiter.NewChannelEntry(mask)
+	if err := file.EventRegister(&w); err != nil {
+		return 0, nil, err
+	}
+	defer file.EventUnregister(&w)
+
+	var n int64
+	for {
+		if toPipe {
+			n, err = file.Write(t, ioseq, vfs.WriteOptions{})
+		} else {
+			n, err = file.Read(t, ioseq, vfs.ReadOptions{})
+		}
+		if n != 0 || !linuxerr.Equals(linuxerr.ErrWouldBlock, err) || nonBlock {
+			break
+		}
+		if err = t.Block(ch); err != nil {
+			break
+		}
+	}
+	return uintptr(n), nil, HandleIOError(t, n != 0, err, linuxerr.ERESTARTSYS, "vmsplice", file)
+}
+
 // Sendfile implements linux system call sendfile(2).
 func Sendfile(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
 	outFD := args[0].Int()
@@ -471,6 +537,182 @@ func Sendfile(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintpt
 	return uintptr(total), nil, HandleIOError(t, total != 0, err, linuxerr.ERESTARTSYS, "sendfile", inFile)
 }
 
+// CopyFileRange implements linux system call copy_file_range(2).
+func CopyFileRange(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	inFD := args[0].Int()
+	inOffsetAddr := args[1].Pointer()
+	outFD := args[2].Int()
+	outOffsetAddr := args[3].Pointer()
+	count := int64(args[4].SizeT())
+	flags := args[5].Uint()
+
+	if flags != 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+
+	inFile := t.GetFile(inFD)
+	if inFile == nil {
+		return 0, nil, linuxerr.EBADF
+	}
+	defer inFile.DecRef(t)
+	if !inFile.IsReadable() {
+		return 0, nil, linuxerr.EBADF
+	}
+
+	outFile := t.GetFile(outFD)
+	if outFile == nil {
+		return 0, nil, linuxerr.EBADF
+	}
+	defer outFile.DecRef(t)
+	if !outFile.IsWritable() || outFile.StatusFlags()&linux.O_APPEND != 0 {
+		return 0, nil, linuxerr.EBADF
+	}
+
+	// Both files must be regular files, as in Linux
+	// (mm/filemap.c:generic_file_rw_checks).
+	inStat, err := copyFileRangeStat(t, inFile)
+	if err != nil {
+		return 0, nil, err
+	}
+	outStat, err := copyFileRangeStat(t, outFile)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	inOffset, err := copyFileRangeOffset(t, inFile, inOffsetAddr)
+	if err != nil {
+		return 0, nil, err
+	}
+	outOffset, err := copyFileRangeOffset(t, outFile, outOffsetAddr)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if count < 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	if inOffset+count < 0 || outOffset+count < 0 {
+		return 0, nil, linuxerr.EOVERFLOW
+	}
+	// Copying between overlapping ranges of the same file is not allowed.
+	if inStat.DevMajor == outStat.DevMajor && inStat.DevMinor == outStat.DevMinor && inStat.Ino == outStat.Ino &&
+		inOffset < outOffset+count && outOffset < inOffset+count {
+		return 0, nil, linuxerr.EINVAL
+	}
+	if count > int64(kernel.MAX_RW_COUNT) {
+		count = int64(kernel.MAX_RW_COUNT)
+	}
+	if count == 0 {
+		return 0, nil, nil
+	}
+
+	total, err := outFile.CopyFileRange(t, inFile, inOffset, outOffset, count)
+	if linuxerr.Equals(linuxerr.EXDEV, err) || linuxerr.Equals(linuxerr.EOPNOTSUPP, err) {
+		// The files can't be copied between directly; fall back to
+		// copying through a buffer, as Linux does with
+		// splice_file_range().
+		total, err = copyFileRangeBuffered(t, inFile, outFile, inOffset, outOffset, count)
+	}
+
+	if total != 0 {
+		if err := copyFileRangeUpdateOffset(t, inFile, inOffsetAddr, inOffset+total); err != nil {
+			return 0, nil, err
+		}
+		if err := copyFileRangeUpdateOffset(t, outFile, outOffsetAddr, outOffset+total); err != nil {
+			return 0, nil, err
+		}
+		if err != nil && err != io.EOF {
+			// If a partial copy is completed, the error is dropped. Log it here.
+			log.Debugf("copy_file_range completed a partial copy with error: %v", err)
+			err = nil
+		}
+	}
+	return uintptr(total), nil, HandleIOError(t, total != 0, err, linuxerr.ERESTARTSYS, "copy_file_range", inFile)
+}
+
+// copyFileRangeStat returns the type and identity of file, which must be a
+// regular file.
+func copyFileRangeStat(t *kernel.Task, file *vfs.FileDescription) (linux.Statx, error) {
+	stat, err := file.Stat(t, vfs.StatOptions{Mask: linux.STATX_TYPE | linux.STATX_INO})
+	if err != nil {
+		return linux.Statx{}, err
+	}
+	switch stat.Mode & linux.S_IFMT {
+	case linux.S_IFREG:
+		return stat, nil
+	case linux.S_IFDIR:
+		return linux.Statx{}, linuxerr.EISDIR
+	default:
+		return linux.Statx{}, linuxerr.EINVAL
+	}
+}
+
+// copyFileRangeOffset returns the offset in file at which copy_file_range
+// starts: the offset at offsetAddr if it is non-zero, and the file offset
+// otherwise.
+func copyFileRangeOffset(t *kernel.Task, file *vfs.FileDescription, offsetAddr hostarch.Addr) (int64, error) {
+	if offsetAddr == 0 {
+		return file.Seek(t, 0, linux.SEEK_CUR)
+	}
+	var offsetP primitive.Int64
+	if _, err := offsetP.CopyIn(t, offsetAddr); err != nil {
+		return 0, err
+	}
+	if offsetP < 0 {
+		return 0, linuxerr.EINVAL
+	}
+	return int64(offsetP), nil
+}
+
+// copyFileRangeUpdateOffset stores the offset in file at which
+// copy_file_range ended to offsetAddr if it is non-zero, and to the file
+// offset otherwise.
+func copyFileRangeUpdateOffset(t *kernel.Task, file *vfs.FileDescription, offsetAddr hostarch.Addr, offset int64) error {
+	if offsetAddr == 0 {
+		_, err := file.Seek(t, offset, linux.SEEK_SET)
+		return err
+	}
+	offsetP := primitive.Int64(offset)
+	_, err := offsetP.CopyOut(t, offsetAddr)
+	return err
+}
+
+// copyFileRangeBuffered copies up to count bytes from inFile at inOffset to
+// outFile at outOffset by reading into and writing from a buffer.
+func copyFileRangeBuffered(t *kernel.Task, inFile, outFile *vfs.FileDescription, inOffset, outOffset, count int64) (int64, error) {
+	// As for sendfile, the buffer size is limited by the size of a pipe.
+	bufSize := count
+	if bufSize > pipe.MaximumPipeSize {
+		bufSize = pipe.MaximumPipeSize
+	}
+	buf := make([]byte, bufSize)
+	var total int64
+	for total < count {
+		if int64(len(buf)) > count-total {
+			buf = buf[:count-total]
+		}
+		readN, err := inFile.PRead(t, usermem.BytesIOSequence(buf), inOffset+total, vfs.ReadOptions{})
+		if readN == 0 {
+			return total, err
+		}
+		writeN, werr := outFile.PWrite(t, usermem.BytesIOSequence(buf[:readN]), outOffset+total, vfs.WriteOptions{})
+		total += writeN
+		if werr != nil {
+			return total, werr
+		}
+		if writeN != readN {
+			return total, nil
+		}
+		if err != nil {
+			return total, err
+		}
+		if t.Interrupted() {
+			return total, linuxerr.ErrInterrupted
+		}
+	}
+	return total, nil
+}
+
 // dualWaiter is used to wait on one or both vfs.FileDescriptions. It is not
 // thread-safe, and does not take a reference on the vfs.FileDescriptions.
 //
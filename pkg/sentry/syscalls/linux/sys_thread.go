@@ -212,6 +212,13 @@ func clone(t *kernel.Task, flags int, stack hostarch.Addr, parentTID hostarch.Ad
 		Stack:      uint64(stack),
 		TLS:        uint64(tls),
 	}
+	// "CLONE_PIDFD ... a PID file descriptor referring to the child process
+	// is allocated and placed at a specified location in the parent's memory
+	// ... In the case of clone(), the PID file descriptor is placed at the
+	// location pointed to by parent_tid" - clone(2)
+	if flags&linux.CLONE_PIDFD != 0 {
+		args.Pidfd = uint64(parentTID)
+	}
 	ntid, ctrl, err := t.Clone(&args)
 	return uintptr(ntid), ctrl, err
 }
@@ -375,12 +382,30 @@ func Waitid(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr,
 		Events:       kernel.EventTraceeStop,
 		ConsumeEvent: options&linux.WNOWAIT == 0,
 	}
+	pidfdNonblock := false
 	switch idtype {
 	case linux.P_ALL:
 	case linux.P_PID:
 		wopts.SpecificTID = kernel.ThreadID(id)
 	case linux.P_PGID:
 		wopts.SpecificPGID = kernel.ProcessGroupID(id)
+	case linux.P_PIDFD:
+		tg, nonblock, err := getPIDFDThreadGroup(t, id)
+		if err != nil {
+			return 0, nil, err
+		}
+		tid := t.PIDNamespace().IDOfThreadGroup(tg)
+		if tid == 0 {
+			// The thread group isn't visible in t's PID namespace, or has
+			// already been reaped.
+			return 0, nil, linuxerr.ECHILD
+		}
+		wopts.SpecificTID = tid
+		// If the pidfd is non-blocking, fail with EAGAIN rather than block.
+		if nonblock && options&linux.WNOHANG == 0 {
+			options |= linux.WNOHANG
+			pidfdNonblock = true
+		}
 	default:
 		return 0, nil, linuxerr.EINVAL
 	}
@@ -397,6 +422,9 @@ func Waitid(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr,
 
 	wr, err := t.Wait(&wopts)
 	if err != nil {
+		if err == kernel.ErrNoWaitableEvent && pidfdNonblock {
+			return 0, nil, linuxerr.EAGAIN
+		}
 		if err == kernel.ErrNoWaitableEvent {
 			err = nil
 			// "If WNOHANG was specified in options and there were no children
@@ -0,0 +1,52 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linux
+
+import (
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/sentry/arch"
+	"gvisor.dev/gvisor/pkg/sentry/fsimpl/userfaultfd"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+)
+
+// Userfaultfd implements Linux syscall userfaultfd(2).
+func Userfaultfd(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	flags := args[0].Uint()
+	if flags&^(linux.O_CLOEXEC|linux.O_NONBLOCK|linux.UFFD_USER_MODE_ONLY) != 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	userModeOnly := flags&linux.UFFD_USER_MODE_ONLY != 0
+
+	// Like Linux with vm.unprivileged_userfaultfd = 0, handling faults in the
+	// kernel requires CAP_SYS_PTRACE.
+	if !userModeOnly && !t.HasCapability(linux.CAP_SYS_PTRACE) {
+		return 0, nil, linuxerr.EPERM
+	}
+
+	file, err := userfaultfd.New(t, t.Kernel().VFS(), t.MemoryManager(), linux.O_RDWR|flags&linux.O_NONBLOCK, userModeOnly)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer file.DecRef(t)
+
+	fd, err := t.NewFDFrom(0, file, kernel.FDFlags{
+		CloseOnExec: flags&linux.O_CLOEXEC != 0,
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return uintptr(fd), nil, nil
+}
@@ -34,6 +34,10 @@ const (
 	// mapping filesystem unique IDs (cf. gofer.InternalFilesystemOptions.UniqueID)
 	// to host FDs.
 	CtxRestoreFilesystemFDMap
+
+	// CtxLandlockDomain is a Context.Value key for the *LandlockDomain
+	// enforced on a task.
+	CtxLandlockDomain
 )
 
 // MountNamespaceFromContext returns the MountNamespace used by ctx. If ctx is
@@ -57,6 +61,18 @@ func RestoreFilesystemFDMapFromContext(ctx goContext.Context) map[RestoreID]int
 	return fdmap
 }
 
+// LandlockDomainFromContext returns the LandlockDomain enforced on ctx. If ctx
+// is not restricted by Landlock, LandlockDomainFromContext returns nil.
+//
+// No reference is taken on the returned LandlockDomain; it remains valid for
+// as long as the task associated with ctx doesn't restrict itself further.
+func LandlockDomainFromContext(ctx goContext.Context) *LandlockDomain {
+	if d, ok := ctx.Value(CtxLandlockDomain).(*LandlockDomain); ok {
+		return d
+	}
+	return nil
+}
+
 type mountNamespaceContext struct {
 	context.Context
 	mntns *MountNamespace
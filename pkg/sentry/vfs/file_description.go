@@ -84,6 +84,11 @@ type FileDescription struct {
 	// writable is analogous to Linux's FMODE_WRITE.
 	writable bool
 
+	// landlockDenyTruncate is true if the Landlock domain of the task that
+	// opened the FileDescription denied truncating the file.
+	// landlockDenyTruncate is immutable.
+	landlockDenyTruncate bool
+
 	usedLockBSD atomicbitops.Uint32
 
 	// impl is the FileDescriptionImpl associated with this Filesystem. impl is
@@ -424,6 +429,23 @@ type FileDescriptionImpl interface {
 	// Preconditions: The FileDescription was opened for writing.
 	Write(ctx context.Context, src usermem.IOSequence, opts WriteOptions) (int64, error)
 
+	// CopyFileRange copies up to length bytes from src, starting at offset
+	// srcOff, to the file represented by the FileDescription, starting at
+	// offset dstOff. It returns the number of bytes copied, which may be less
+	// than length if src ends before srcOff+length.
+	//
+	// CopyFileRange allows implementations to copy data without a round trip
+	// through a buffer in the sentry. If the implementation can't copy from
+	// src directly, it returns EXDEV or EOPNOTSUPP, and the caller falls back
+	// to copying with PRead and PWrite.
+	//
+	// Preconditions:
+	//	* The FileDescription was opened for writing, and not with O_APPEND.
+	//	* src was opened for reading.
+	//	* The source and destination ranges do not overlap if src and the
+	//	  FileDescription represent the same file.
+	CopyFileRange(ctx context.Context, src *FileDescription, srcOff, dstOff, length int64) (int64, error)
+
 	// IterDirents invokes cb on each entry in the directory represented by the
 	// FileDescription. If IterDirents has been called since the last call to
 	// Seek, it continues iteration from the end of the last call.
@@ -549,6 +571,11 @@ func (fd *FileDescription) Stat(ctx context.Context, opts StatOptions) (linux.St
 
 // SetStat updates metadata for the file represented by fd.
 func (fd *FileDescription) SetStat(ctx context.Context, opts SetStatOptions) error {
+	// Like Linux, Landlock checks truncation through a file description when
+	// it is opened rather than when it is truncated.
+	if opts.Stat.Mask&linux.STATX_SIZE != 0 && fd.landlockDenyTruncate {
+		return linuxerr.EACCES
+	}
 	if fd.opts.UseDentryMetadata {
 		vfsObj := fd.vd.mount.vfs
 		rp := vfsObj.getResolvingPath(auth.CredentialsFromContext(ctx), &PathOperation{
@@ -686,6 +713,22 @@ func (fd *FileDescription) Write(ctx context.Context, src usermem.IOSequence, op
 	return n, err
 }
 
+// CopyFileRange copies up to length bytes from src, starting at offset
+// srcOff, to the file represented by fd, starting at offset dstOff, and
+// returns the number of bytes copied. If the FileDescriptionImpl can't copy
+// from src directly, CopyFileRange returns EXDEV or EOPNOTSUPP.
+func (fd *FileDescription) CopyFileRange(ctx context.Context, src *FileDescription, srcOff, dstOff, length int64) (int64, error) {
+	if !src.readable || !fd.writable {
+		return 0, linuxerr.EBADF
+	}
+	n, err := fd.impl.CopyFileRange(ctx, src, srcOff, dstOff, length)
+	if n > 0 {
+		src.Dentry().InotifyWithParent(ctx, linux.IN_ACCESS, 0, PathEvent)
+		fd.Dentry().InotifyWithParent(ctx, linux.IN_MODIFY, 0, PathEvent)
+	}
+	return n, err
+}
+
 // IterDirents invokes cb on each entry in the directory represented by fd. If
 // IterDirents has been called since the last call to Seek, it continues
 // iteration from the end of the last call.
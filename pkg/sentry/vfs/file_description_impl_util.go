@@ -117,6 +117,12 @@ func (FileDescriptionDefaultImpl) Write(ctx context.Context, src usermem.IOSeque
 	return 0, linuxerr.EINVAL
 }
 
+// CopyFileRange implements FileDescriptionImpl.CopyFileRange analogously to
+// file_operations::copy_file_range == NULL in Linux.
+func (FileDescriptionDefaultImpl) CopyFileRange(ctx context.Context, src *FileDescription, srcOff, dstOff, length int64) (int64, error) {
+	return 0, linuxerr.EOPNOTSUPP
+}
+
 // IterDirents implements FileDescriptionImpl.IterDirents analogously to
 // file_operations::iterate == file_operations::iterate_shared == NULL in
 // Linux.
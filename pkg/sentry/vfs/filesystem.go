@@ -523,6 +523,16 @@ type FilesystemImpl interface {
 	MountOptions() string
 }
 
+// FilesystemImplCachedResolutionExtension is an optional extension to
+// FilesystemImpl, implemented by FilesystemImpls that honor
+// ResolvingPath.Cached(). Path resolution with RESOLVE_CACHED fails with
+// EAGAIN in filesystems that don't implement it.
+type FilesystemImplCachedResolutionExtension interface {
+	// SupportsCachedResolution returns true if this filesystem can resolve
+	// paths using only cached state, returning EAGAIN when it can't.
+	SupportsCachedResolution() bool
+}
+
 // PrependPathAtVFSRootError is returned by implementations of
 // FilesystemImpl.PrependPath() when they encounter the contextual VFS root.
 //
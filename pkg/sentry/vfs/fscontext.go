@@ -0,0 +1,301 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vfs
+
+import (
+	"strings"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sync"
+	"gvisor.dev/gvisor/pkg/usermem"
+)
+
+// fsContextPhase is the state of a FilesystemContext. It is analogous to
+// Linux's enum fs_context_phase.
+type fsContextPhase int
+
+const (
+	// fsContextCreateParams indicates that the context is accepting
+	// parameters for a new filesystem.
+	fsContextCreateParams fsContextPhase = iota
+
+	// fsContextAwaitingMount indicates that the filesystem has been created
+	// and may be mounted.
+	fsContextAwaitingMount
+
+	// fsContextReconfParams indicates that the context is accepting
+	// parameters to reconfigure an existing filesystem.
+	fsContextReconfParams
+
+	// fsContextFailed indicates that creating the filesystem failed, and the
+	// context can no longer be used.
+	fsContextFailed
+)
+
+// FilesystemContext is the file description returned by fsopen(2) and
+// fspick(2). It collects the parameters set by fsconfig(2), then uses them to
+// create a Filesystem or to reconfigure an existing Mount. It is analogous to
+// Linux's struct fs_context.
+//
+// Parameters other than "source", "ro" and "rw" are passed to
+// FilesystemType.GetFilesystem as a comma-separated list in
+// GetFilesystemOptions.Data, as for mount(2).
+//
+// +stateify savable
+type FilesystemContext struct {
+	vfsfd FileDescription
+	FileDescriptionDefaultImpl
+	DentryMetadataFileDescriptionImpl
+	NoLockFD
+
+	// vfs is immutable.
+	vfs *VirtualFilesystem
+
+	// fsTypeName is the name of the filesystem type to create. fsTypeName is
+	// immutable, and is empty for a context returned by fspick(2).
+	fsTypeName string
+
+	// mnt is the Mount to reconfigure, or nil for a context returned by
+	// fsopen(2). mnt is immutable, and a reference is held on it if it is not
+	// nil.
+	mnt *Mount
+
+	// mu protects the fields below.
+	mu sync.Mutex `state:"nosave"`
+
+	phase fsContextPhase
+
+	// source is the value of the "source" parameter.
+	source string
+
+	// params holds the filesystem-specific parameters as "key" or "key=value"
+	// strings, in the order in which they were set.
+	params []string
+
+	// If readOnlySet is true, readOnly is the value of the last "ro" or "rw"
+	// parameter.
+	readOnly    bool
+	readOnlySet bool
+
+	// fs and root are the Filesystem and root Dentry created by Create.
+	// References are held on fs and root if they are not nil.
+	fs   *Filesystem
+	root *Dentry
+}
+
+// NewFilesystemContextFD returns a file description representing a new
+// FilesystemContext for the filesystem type with the given name, as for
+// fsopen(2). A reference is taken on the returned FileDescription.
+func (vfs *VirtualFilesystem) NewFilesystemContextFD(ctx context.Context, fsTypeName string) (*FileDescription, error) {
+	rft := vfs.getFilesystemType(fsTypeName)
+	if rft == nil || !rft.opts.AllowUserMount {
+		return nil, linuxerr.ENODEV
+	}
+	fc := &FilesystemContext{
+		vfs:        vfs,
+		fsTypeName: fsTypeName,
+		phase:      fsContextCreateParams,
+	}
+	if err := fc.init(ctx); err != nil {
+		return nil, err
+	}
+	return &fc.vfsfd, nil
+}
+
+// PickFilesystemContextFD returns a file description representing a new
+// FilesystemContext for reconfiguring the mount at the path represented by
+// pop, as for fspick(2). A reference is taken on the returned
+// FileDescription.
+func (vfs *VirtualFilesystem) PickFilesystemContextFD(ctx context.Context, creds *auth.Credentials, pop *PathOperation) (*FileDescription, error) {
+	vd, err := vfs.GetDentryAt(ctx, creds, pop, &GetDentryOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer vd.DecRef(ctx)
+	if vd.dentry != vd.mount.root {
+		return nil, linuxerr.EINVAL
+	}
+	vd.mount.IncRef()
+	fc := &FilesystemContext{
+		vfs:   vfs,
+		mnt:   vd.mount,
+		phase: fsContextReconfParams,
+	}
+	if err := fc.init(ctx); err != nil {
+		vd.mount.DecRef(ctx)
+		return nil, err
+	}
+	return &fc.vfsfd, nil
+}
+
+func (fc *FilesystemContext) init(ctx context.Context) error {
+	vd := fc.vfs.NewAnonVirtualDentry("fscontext")
+	defer vd.DecRef(ctx)
+	return fc.vfsfd.Init(fc, linux.O_RDWR, vd.Mount(), vd.Dentry(), &FileDescriptionOptions{
+		DenyPRead:         true,
+		DenyPWrite:        true,
+		UseDentryMetadata: true,
+	})
+}
+
+// Release implements FileDescriptionImpl.Release.
+func (fc *FilesystemContext) Release(ctx context.Context) {
+	if fc.root != nil {
+		fc.root.DecRef(ctx)
+	}
+	if fc.fs != nil {
+		fc.fs.DecRef(ctx)
+	}
+	if fc.mnt != nil {
+		fc.mnt.DecRef(ctx)
+	}
+}
+
+// Read implements FileDescriptionImpl.Read.
+func (fc *FilesystemContext) Read(ctx context.Context, dst usermem.IOSequence, opts ReadOptions) (int64, error) {
+	// Linux returns messages logged by the filesystem while parsing parameters
+	// here. We never log any.
+	return 0, linuxerr.ENODATA
+}
+
+// SetFlag sets the parameter key without a value, as for fsconfig(2) with
+// FSCONFIG_SET_FLAG.
+func (fc *FilesystemContext) SetFlag(key string) error {
+	return fc.setParam(key, key)
+}
+
+// SetString sets the parameter key to value, as for fsconfig(2) with
+// FSCONFIG_SET_STRING.
+func (fc *FilesystemContext) SetString(key, value string) error {
+	if key == "source" {
+		fc.mu.Lock()
+		defer fc.mu.Unlock()
+		if fc.phase != fsContextCreateParams && fc.phase != fsContextReconfParams {
+			return linuxerr.EBUSY
+		}
+		if fc.source != "" {
+			return linuxerr.EINVAL
+		}
+		fc.source = value
+		return nil
+	}
+	if strings.Contains(value, ",") {
+		return linuxerr.EINVAL
+	}
+	return fc.setParam(key, key+"="+value)
+}
+
+// setParam records the parameter key, formatted as param.
+func (fc *FilesystemContext) setParam(key, param string) error {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	if fc.phase != fsContextCreateParams && fc.phase != fsContextReconfParams {
+		return linuxerr.EBUSY
+	}
+	switch key {
+	case "source":
+		return linuxerr.EINVAL
+	case "ro", "rw":
+		fc.readOnly = key == "ro"
+		fc.readOnlySet = true
+		return nil
+	}
+	// Like Linux's fs/fs_context.c:legacy_parse_param(), reject parameters
+	// that can't be represented in a comma-separated list, and limit their
+	// total size to a page.
+	if strings.Contains(key, ",") {
+		return linuxerr.EINVAL
+	}
+	size := len(param)
+	for _, p := range fc.params {
+		size += len(p) + 1
+	}
+	if size > hostarch.PageSize-2 {
+		return linuxerr.EINVAL
+	}
+	fc.params = append(fc.params, param)
+	return nil
+}
+
+// Create creates a Filesystem from the parameters set on fc, as for
+// fsconfig(2) with FSCONFIG_CMD_CREATE.
+func (fc *FilesystemContext) Create(ctx context.Context, creds *auth.Credentials) error {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	if fc.phase != fsContextCreateParams {
+		return linuxerr.EBUSY
+	}
+	opts := MountOptions{
+		GetFilesystemOptions: GetFilesystemOptions{
+			Data: strings.Join(fc.params, ","),
+		},
+	}
+	fs, root, err := fc.vfs.NewFilesystem(ctx, creds, fc.source, fc.fsTypeName, &opts)
+	if err != nil {
+		fc.phase = fsContextFailed
+		return err
+	}
+	fc.fs = fs
+	fc.root = root
+	fc.phase = fsContextAwaitingMount
+	return nil
+}
+
+// Reconfigure applies the parameters set on fc to the Mount it was picked
+// from, as for fsconfig(2) with FSCONFIG_CMD_RECONFIGURE.
+//
+// As with mount(2) and MS_REMOUNT, only the read-only state is changed, and
+// since gVisor does not distinguish between read-only filesystems and
+// read-only mounts, it is applied to the picked Mount.
+func (fc *FilesystemContext) Reconfigure(ctx context.Context) error {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	if fc.phase != fsContextReconfParams {
+		return linuxerr.EBUSY
+	}
+	if fc.readOnlySet {
+		if err := fc.vfs.SetMountReadOnly(fc.mnt, fc.readOnly); err != nil {
+			return err
+		}
+	}
+	fc.source = ""
+	fc.params = nil
+	fc.readOnlySet = false
+	return nil
+}
+
+// Mount returns an O_PATH file description for the root of a new detached
+// Mount of the Filesystem created by Create, as for fsmount(2). The Mount can
+// be attached with VirtualFilesystem.MoveMountAt, and is unmounted when the
+// file description is released otherwise.
+func (fc *FilesystemContext) Mount(ctx context.Context, opts MountOptions) (*FileDescription, error) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	if fc.phase != fsContextAwaitingMount {
+		return nil, linuxerr.EBUSY
+	}
+	if fc.readOnlySet && fc.readOnly {
+		opts.ReadOnly = true
+	}
+	mnt := fc.vfs.NewDisconnectedMount(fc.fs, fc.root, &opts)
+	fc.vfs.lockMounts()
+	defer fc.vfs.unlockMounts(ctx)
+	fc.vfs.delayDecRef(mnt)
+	return fc.vfs.newMountFD(mnt)
+}
@@ -0,0 +1,741 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vfs
+
+import (
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/fspath"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+const (
+	// LandlockAccessFS is the set of supported Landlock filesystem access
+	// rights. LANDLOCK_ACCESS_FS_IOCTL_DEV is not supported.
+	LandlockAccessFS = linux.LANDLOCK_ACCESS_FS_TRUNCATE<<1 - 1
+
+	// LandlockAccessNet is the set of supported Landlock network access
+	// rights.
+	LandlockAccessNet = linux.LANDLOCK_ACCESS_NET_BIND_TCP | linux.LANDLOCK_ACCESS_NET_CONNECT_TCP
+
+	// landlockAccessFSFile is the set of Landlock filesystem access rights
+	// that apply to non-directory files.
+	landlockAccessFSFile = linux.LANDLOCK_ACCESS_FS_EXECUTE | linux.LANDLOCK_ACCESS_FS_WRITE_FILE | linux.LANDLOCK_ACCESS_FS_READ_FILE | linux.LANDLOCK_ACCESS_FS_TRUNCATE
+
+	// landlockMaxLayers is the maximum number of rulesets that can be stacked
+	// in a LandlockDomain, from security/landlock/limits.h:LANDLOCK_MAX_NUM_LAYERS.
+	landlockMaxLayers = 16
+)
+
+// landlockLayer is an immutable set of Landlock rules.
+//
+// +stateify savable
+type landlockLayer struct {
+	// handledFS and handledNet are the access rights restricted by the layer.
+	handledFS  uint64
+	handledNet uint64
+
+	// fsRules maps files to the access rights granted on them and, for
+	// directories, on their descendants. A reference is held on each
+	// landlockFSRule.vd.
+	fsRules map[*Dentry]landlockFSRule
+
+	// netRules maps TCP ports to the access rights granted on them.
+	netRules map[uint16]uint64
+}
+
+// landlockFSRule is a Landlock rule of type LANDLOCK_RULE_PATH_BENEATH.
+//
+// +stateify savable
+type landlockFSRule struct {
+	vd     VirtualDentry
+	access uint64
+}
+
+func (l *landlockLayer) incRefs() {
+	for _, rule := range l.fsRules {
+		rule.vd.IncRef()
+	}
+}
+
+func (l *landlockLayer) decRefs(ctx context.Context) {
+	for _, rule := range l.fsRules {
+		rule.vd.DecRef(ctx)
+	}
+}
+
+// LandlockRuleset represents a Landlock ruleset created by
+// landlock_create_ruleset(2). LandlockRuleset implements FileDescriptionImpl.
+//
+// +stateify savable
+type LandlockRuleset struct {
+	vfsfd FileDescription
+	FileDescriptionDefaultImpl
+	DentryMetadataFileDescriptionImpl
+	NoLockFD
+
+	// handledFS and handledNet are the access rights restricted by the
+	// ruleset. They are immutable.
+	handledFS  uint64
+	handledNet uint64
+
+	mu sync.Mutex `state:"nosave"`
+
+	// rules holds the rules added by landlock_add_rule(2). Its handledFS and
+	// handledNet fields are unused.
+	//
+	// +checklocks:mu
+	rules landlockLayer
+}
+
+var _ FileDescriptionImpl = (*LandlockRuleset)(nil)
+
+// NewLandlockRulesetFD returns a file description representing a new
+// LandlockRuleset restricting the given access rights.
+func NewLandlockRulesetFD(ctx context.Context, vfsObj *VirtualFilesystem, handledFS, handledNet uint64) (*FileDescription, error) {
+	vd := vfsObj.NewAnonVirtualDentry("[landlock-ruleset]")
+	defer vd.DecRef(ctx)
+	r := &LandlockRuleset{
+		handledFS:  handledFS,
+		handledNet: handledNet,
+		rules: landlockLayer{
+			fsRules:  make(map[*Dentry]landlockFSRule),
+			netRules: make(map[uint16]uint64),
+		},
+	}
+	if err := r.vfsfd.Init(r, linux.O_RDWR, vd.Mount(), vd.Dentry(), &FileDescriptionOptions{
+		UseDentryMetadata: true,
+		DenyPRead:         true,
+		DenyPWrite:        true,
+	}); err != nil {
+		return nil, err
+	}
+	return &r.vfsfd, nil
+}
+
+// Release implements FileDescriptionImpl.Release.
+func (r *LandlockRuleset) Release(ctx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rules.decRefs(ctx)
+	r.rules.fsRules = nil
+}
+
+// AddPathRule grants access on the file at vd and, if it is a directory, on
+// its descendants.
+func (r *LandlockRuleset) AddPathRule(ctx context.Context, vd VirtualDentry, access uint64) error {
+	if access == 0 {
+		return linuxerr.ENOMSG
+	}
+	if access&^r.handledFS != 0 {
+		return linuxerr.EINVAL
+	}
+	// Like Linux, reject files that aren't reachable from the filesystem
+	// hierarchy, such as pipes and sockets.
+	if vd.mount == vd.mount.vfs.anonMount {
+		return linuxerr.EBADFD
+	}
+	if access&^landlockAccessFSFile != 0 {
+		stat, err := vd.mount.vfs.StatAt(ctx, auth.CredentialsFromContext(ctx), &PathOperation{
+			Root:  vd,
+			Start: vd,
+		}, &StatOptions{Mask: linux.STATX_TYPE})
+		if err != nil {
+			return err
+		}
+		if stat.Mode&linux.S_IFMT != linux.S_IFDIR {
+			return linuxerr.EINVAL
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rule, ok := r.rules.fsRules[vd.dentry]
+	if !ok {
+		vd.IncRef()
+		rule.vd = vd
+	}
+	rule.access |= access
+	r.rules.fsRules[vd.dentry] = rule
+	return nil
+}
+
+// AddNetRule grants access on the given TCP port.
+func (r *LandlockRuleset) AddNetRule(access, port uint64) error {
+	if access == 0 {
+		return linuxerr.ENOMSG
+	}
+	if access&^r.handledNet != 0 {
+		return linuxerr.EINVAL
+	}
+	if port > 0xffff {
+		return linuxerr.EINVAL
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rules.netRules[uint16(port)] |= access
+	return nil
+}
+
+// snapshot returns a landlockLayer holding the current rules of r.
+func (r *LandlockRuleset) snapshot() *landlockLayer {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	l := &landlockLayer{
+		handledFS:  r.handledFS,
+		handledNet: r.handledNet,
+		fsRules:    make(map[*Dentry]landlockFSRule, len(r.rules.fsRules)),
+		netRules:   make(map[uint16]uint64, len(r.rules.netRules)),
+	}
+	for d, rule := range r.rules.fsRules {
+		l.fsRules[d] = rule
+	}
+	for port, access := range r.rules.netRules {
+		l.netRules[port] = access
+	}
+	l.incRefs()
+	return l
+}
+
+// LandlockDomain is the set of Landlock rulesets enforced on a task. Each call
+// to landlock_restrict_self(2) adds a layer to the domain, and an access is
+// only allowed if every layer allows it.
+//
+// LandlockDomains are immutable. A nil *LandlockDomain restricts nothing.
+//
+// +stateify savable
+type LandlockDomain struct {
+	landlockDomainRefs
+
+	layers []*landlockLayer
+
+	// handledFS and handledNet are the union of the access rights restricted
+	// by layers.
+	handledFS  uint64
+	handledNet uint64
+}
+
+// DecRef decrements d's reference count.
+func (d *LandlockDomain) DecRef(ctx context.Context) {
+	d.landlockDomainRefs.DecRef(func() {
+		for _, l := range d.layers {
+			l.decRefs(ctx)
+		}
+	})
+}
+
+// Restrict returns a new LandlockDomain that enforces the rules of r in
+// addition to the rules of d. A reference is taken on the returned
+// LandlockDomain.
+func (d *LandlockDomain) Restrict(r *LandlockRuleset) (*LandlockDomain, error) {
+	var layers []*landlockLayer
+	if d != nil {
+		layers = d.layers
+	}
+	if len(layers) >= landlockMaxLayers {
+		return nil, linuxerr.E2BIG
+	}
+	nd := &LandlockDomain{
+		layers: make([]*landlockLayer, 0, len(layers)+1),
+	}
+	for _, l := range layers {
+		l.incRefs()
+		nd.layers = append(nd.layers, l)
+	}
+	nd.layers = append(nd.layers, r.snapshot())
+	for _, l := range nd.layers {
+		nd.handledFS |= l.handledFS
+		nd.handledNet |= l.handledNet
+	}
+	nd.InitRefs()
+	return nd, nil
+}
+
+// CheckNet returns EACCES if d denies access on the given TCP port.
+func (d *LandlockDomain) CheckNet(access uint64, port uint16) error {
+	if d == nil || d.handledNet&access == 0 {
+		return nil
+	}
+	for _, l := range d.layers {
+		if access&l.handledNet&^l.netRules[port] != 0 {
+			return linuxerr.EACCES
+		}
+	}
+	return nil
+}
+
+func (d *LandlockDomain) handlesFS(access uint64) bool {
+	return d != nil && d.handledFS&access != 0
+}
+
+// fsGranted returns the filesystem access rights granted by each layer of d on
+// vd or, if parentOf is true, on the directory containing vd. Access rights
+// that a layer doesn't handle are granted, except for
+// LANDLOCK_ACCESS_FS_REFER, which is only granted by rules in layers that
+// handle filesystem access.
+func (d *LandlockDomain) fsGranted(ctx context.Context, vd VirtualDentry, parentOf bool) []uint64 {
+	granted := make([]uint64, len(d.layers))
+	for i, l := range d.layers {
+		granted[i] = ^l.handledFS
+		if l.handledFS != 0 {
+			granted[i] &^= linux.LANDLOCK_ACCESS_FS_REFER
+		}
+	}
+
+	// Walk up from vd through the mounts it is reachable from, rather than
+	// through the path used to reach it, up to the root of the mount tree.
+	// Holding mountMu prevents mounts from moving during the walk, and
+	// FilesystemImpl.IsDescendant follows Dentry parents rather than path
+	// components, so the rules checked are those of vd's actual ancestors.
+	vfs := vd.mount.vfs
+	vfs.lockMounts()
+	defer vfs.unlockMounts(ctx)
+	mnt, dentry := vd.mount, vd.dentry
+	for mnt != nil {
+		impl := mnt.fs.impl
+		mntRoot := VirtualDentry{mnt, mnt.root}
+		at := VirtualDentry{mnt, dentry}
+		for i, l := range d.layers {
+			for ruleDentry, rule := range l.fsRules {
+				if rule.vd.mount.fs != mnt.fs || (parentOf && ruleDentry == dentry) {
+					continue
+				}
+				ruleVD := VirtualDentry{mnt, ruleDentry}
+				// The rule must apply to an ancestor of vd that is visible
+				// through mnt.
+				if impl.IsDescendant(ruleVD, at) && impl.IsDescendant(mntRoot, ruleVD) {
+					granted[i] |= rule.access
+				}
+			}
+		}
+		parentOf = false
+		mnt, dentry = mnt.parent(), mnt.point()
+	}
+	return granted
+}
+
+// checkFS returns EACCES if d denies access on the file at vd.
+func (d *LandlockDomain) checkFS(ctx context.Context, vd VirtualDentry, access uint64) error {
+	if !d.handlesFS(access) {
+		return nil
+	}
+	// Files that aren't reachable from the filesystem hierarchy are exempt.
+	if vd.mount == vd.mount.vfs.anonMount {
+		return nil
+	}
+	for _, granted := range d.fsGranted(ctx, vd, false /* parentOf */) {
+		if access&^granted != 0 {
+			return linuxerr.EACCES
+		}
+	}
+	return nil
+}
+
+// checkReparent returns EACCES or EXDEV if d denies linking (or moving, if
+// remove is true) a file with the given mode from a directory to which each
+// layer of d grants oldGranted, to the different directory newParent.
+func (d *LandlockDomain) checkReparent(ctx context.Context, oldGranted []uint64, newParent VirtualDentry, mode linux.FileMode, remove bool) error {
+	if newParent.mount == newParent.mount.vfs.anonMount {
+		return nil
+	}
+	makeAccess := landlockMakeAccess(mode)
+	var removeAccess uint64
+	if remove {
+		removeAccess = landlockRemoveAccess(mode)
+	}
+	newGranted := d.fsGranted(ctx, newParent, false /* parentOf */)
+	for i := range d.layers {
+		if makeAccess&^newGranted[i] != 0 || removeAccess&^oldGranted[i] != 0 {
+			return linuxerr.EACCES
+		}
+	}
+	// Like Linux, return EXDEV if the file could otherwise be moved, so that
+	// callers can fall back to copying it. The file must not gain any access
+	// rights by changing parents; only the access rights that apply to it are
+	// compared.
+	compared := uint64(landlockAccessFSFile)
+	if mode.FileType() == linux.ModeDirectory {
+		compared = LandlockAccessFS
+	}
+	for i, l := range d.layers {
+		if (oldGranted[i]&newGranted[i])&linux.LANDLOCK_ACCESS_FS_REFER == 0 {
+			return linuxerr.EXDEV
+		}
+		if newGranted[i]&^oldGranted[i]&l.handledFS&compared != 0 {
+			return linuxerr.EXDEV
+		}
+	}
+	return nil
+}
+
+// checkMove returns EACCES or EXDEV if d denies moving a file with the given
+// mode from oldParent to newParent.
+func (d *LandlockDomain) checkMove(ctx context.Context, oldParent, newParent VirtualDentry, mode linux.FileMode) error {
+	if oldParent == newParent {
+		return d.checkFS(ctx, newParent, landlockMakeAccess(mode)|landlockRemoveAccess(mode))
+	}
+	if oldParent.mount == oldParent.mount.vfs.anonMount {
+		return nil
+	}
+	return d.checkReparent(ctx, d.fsGranted(ctx, oldParent, false /* parentOf */), newParent, mode, true /* remove */)
+}
+
+// landlockMakeAccess returns the Landlock access right required to create a
+// file with the given mode.
+func landlockMakeAccess(mode linux.FileMode) uint64 {
+	switch mode.FileType() {
+	case linux.ModeDirectory:
+		return linux.LANDLOCK_ACCESS_FS_MAKE_DIR
+	case linux.ModeCharacterDevice:
+		return linux.LANDLOCK_ACCESS_FS_MAKE_CHAR
+	case linux.ModeBlockDevice:
+		return linux.LANDLOCK_ACCESS_FS_MAKE_BLOCK
+	case linux.ModeNamedPipe:
+		return linux.LANDLOCK_ACCESS_FS_MAKE_FIFO
+	case linux.ModeSocket:
+		return linux.LANDLOCK_ACCESS_FS_MAKE_SOCK
+	case linux.ModeSymlink:
+		return linux.LANDLOCK_ACCESS_FS_MAKE_SYM
+	default:
+		return linux.LANDLOCK_ACCESS_FS_MAKE_REG
+	}
+}
+
+// landlockRemoveAccess returns the Landlock access right required to remove a
+// file with the given mode.
+func landlockRemoveAccess(mode linux.FileMode) uint64 {
+	if mode.FileType() == linux.ModeDirectory {
+		return linux.LANDLOCK_ACCESS_FS_REMOVE_DIR
+	}
+	return linux.LANDLOCK_ACCESS_FS_REMOVE_FILE
+}
+
+// landlockResolveParent resolves the directory containing the last component
+// of pop if ctx's Landlock domain handles any of access. It then returns the
+// directory, which the caller must DecRef, and a PathOperation resolving the
+// last component of pop from it, which the caller must use instead of pop so
+// that Landlock access is checked on the directory that the operation
+// applies to. Otherwise, landlockResolveParent returns pop and an empty
+// VirtualDentry.
+//
+// Preconditions: pop.Path.Begin.Ok().
+func (vfs *VirtualFilesystem) landlockResolveParent(ctx context.Context, creds *auth.Credentials, pop *PathOperation, access uint64) (*PathOperation, VirtualDentry, error) {
+	if !LandlockDomainFromContext(ctx).handlesFS(access) {
+		return pop, VirtualDentry{}, nil
+	}
+	parentVD, name, err := vfs.getParentDirAndName(ctx, creds, pop)
+	if err != nil {
+		return nil, VirtualDentry{}, err
+	}
+	path := fspath.Parse(name)
+	path.Dir = pop.Path.Dir
+	return &PathOperation{
+		Root:               pop.Root,
+		Start:              parentVD,
+		Path:               path,
+		FollowFinalSymlink: pop.FollowFinalSymlink,
+	}, parentVD, nil
+}
+
+// landlockCheckParent is like landlockResolveParent, but also returns EACCES
+// if ctx's Landlock domain denies access on the directory.
+//
+// Preconditions: pop.Path.Begin.Ok().
+func (vfs *VirtualFilesystem) landlockCheckParent(ctx context.Context, creds *auth.Credentials, pop *PathOperation, access uint64) (*PathOperation, VirtualDentry, error) {
+	pop, parentVD, err := vfs.landlockResolveParent(ctx, creds, pop, access)
+	if err != nil || !parentVD.Ok() {
+		return pop, parentVD, err
+	}
+	if err := LandlockDomainFromContext(ctx).checkFS(ctx, parentVD, access); err != nil {
+		parentVD.DecRef(ctx)
+		return nil, VirtualDentry{}, err
+	}
+	return pop, parentVD, nil
+}
+
+// landlockOpenAt is OpenAt for tasks whose Landlock domain restricts
+// filesystem access. Access is checked on the file that was opened and, if
+// it was created, on the directory it was created in.
+func (vfs *VirtualFilesystem) landlockOpenAt(ctx context.Context, creds *auth.Credentials, pop *PathOperation, opts *OpenOptions) (*FileDescription, error) {
+	d := LandlockDomainFromContext(ctx)
+	// Like Linux, only truncate the file once it is known that the domain
+	// allows opening it.
+	fsOpts := *opts
+	fsOpts.Flags &^= linux.O_TRUNC
+	truncate := opts.Flags&linux.O_TRUNC != 0
+	var (
+		fd      *FileDescription
+		created bool
+		err     error
+	)
+	if opts.Flags&linux.O_CREAT != 0 && pop.Path.Begin.Ok() {
+		fd, created, err = vfs.landlockCreateAt(ctx, creds, d, pop, &fsOpts)
+	} else {
+		fd, err = vfs.openAt(ctx, creds, pop, &fsOpts)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := fd.Stat(ctx, StatOptions{Mask: linux.STATX_TYPE})
+	if err != nil {
+		fd.DecRef(ctx)
+		return nil, err
+	}
+	fileType := stat.Mode & linux.S_IFMT
+	if err := d.checkOpen(ctx, fd, fileType, opts); err != nil {
+		fd.DecRef(ctx)
+		return nil, err
+	}
+	if truncate && !created && fileType == linux.S_IFREG {
+		if err := fd.SetStat(ctx, SetStatOptions{
+			Stat: linux.Statx{Mask: linux.STATX_SIZE},
+			// Opening the file for writing already required write
+			// permission.
+			NeedWritePerm: !fd.IsWritable(),
+		}); err != nil {
+			fd.DecRef(ctx)
+			return nil, err
+		}
+	}
+	return fd, nil
+}
+
+// landlockCreateAt opens the file at pop, creating it if it doesn't exist.
+// It returns EACCES if the file would be created and d denies creating it.
+// The returned bool is true if the file was created.
+//
+// Preconditions:
+//   - opts.Flags&linux.O_CREAT != 0.
+//   - pop.Path.Begin.Ok().
+func (vfs *VirtualFilesystem) landlockCreateAt(ctx context.Context, creds *auth.Credentials, d *LandlockDomain, pop *PathOperation, opts *OpenOptions) (*FileDescription, bool, error) {
+	if pop.Path.Dir {
+		return nil, false, linuxerr.EISDIR
+	}
+	pop, parentVD, err := vfs.landlockResolveParent(ctx, creds, pop, LandlockAccessFS)
+	if err != nil {
+		return nil, false, err
+	}
+	defer parentVD.DecRef(ctx)
+
+	existingOpts := *opts
+	existingOpts.Flags &^= linux.O_CREAT
+	createOpts := *opts
+	createOpts.Flags |= linux.O_EXCL
+	for attempt := 0; ; attempt++ {
+		if opts.Flags&linux.O_EXCL == 0 {
+			fd, err := vfs.openAt(ctx, creds, pop, &existingOpts)
+			if err == nil {
+				stat, err := fd.Stat(ctx, StatOptions{Mask: linux.STATX_TYPE})
+				if err == nil && stat.Mode&linux.S_IFMT == linux.S_IFDIR {
+					err = linuxerr.EISDIR
+				}
+				if err != nil {
+					fd.DecRef(ctx)
+					return nil, false, err
+				}
+				return fd, false, nil
+			}
+			if !linuxerr.Equals(linuxerr.ENOENT, err) {
+				return nil, false, err
+			}
+		}
+		if err := d.checkFS(ctx, parentVD, linux.LANDLOCK_ACCESS_FS_MAKE_REG); err != nil {
+			// Like Linux, report that files opened with O_EXCL exist rather
+			// than that they can't be created.
+			if opts.Flags&linux.O_EXCL != 0 {
+				statPop := *pop
+				statPop.FollowFinalSymlink = false
+				if _, statErr := vfs.StatAt(ctx, creds, &statPop, &StatOptions{}); statErr == nil {
+					return nil, false, linuxerr.EEXIST
+				}
+			}
+			return nil, false, err
+		}
+		fd, err := vfs.openAt(ctx, creds, pop, &createOpts)
+		if err == nil {
+			return fd, true, nil
+		}
+		if opts.Flags&linux.O_EXCL != 0 || !linuxerr.Equals(linuxerr.EEXIST, err) {
+			return nil, false, err
+		}
+		if attempt > 0 {
+			// The file is a dangling symlink, or keeps being replaced. The
+			// symlink's target would be created in a directory on which
+			// access wasn't checked, so fail closed.
+			return nil, false, linuxerr.EACCES
+		}
+	}
+}
+
+// checkOpen returns EACCES if d denies opening fd, a file of the given type,
+// with opts. Otherwise, it records on fd whether d allows truncating it.
+func (d *LandlockDomain) checkOpen(ctx context.Context, fd *FileDescription, fileType uint16, opts *OpenOptions) error {
+	var access uint64
+	if fd.IsReadable() {
+		if fileType == linux.S_IFDIR {
+			access |= linux.LANDLOCK_ACCESS_FS_READ_DIR
+		} else {
+			access |= linux.LANDLOCK_ACCESS_FS_READ_FILE
+		}
+	}
+	if fd.IsWritable() {
+		access |= linux.LANDLOCK_ACCESS_FS_WRITE_FILE
+	}
+	if opts.Flags&linux.O_TRUNC != 0 && fileType == linux.S_IFREG {
+		access |= linux.LANDLOCK_ACCESS_FS_TRUNCATE
+	}
+	if opts.FileExec {
+		access |= linux.LANDLOCK_ACCESS_FS_EXECUTE
+	}
+	if err := d.checkFS(ctx, fd.VirtualDentry(), access); err != nil {
+		return err
+	}
+	if fileType != linux.S_IFDIR {
+		fd.landlockDenyTruncate = d.checkFS(ctx, fd.VirtualDentry(), linux.LANDLOCK_ACCESS_FS_TRUNCATE) != nil
+	}
+	return nil
+}
+
+// landlockCheckTruncateAt returns the file at pop and EACCES if ctx's Landlock
+// domain denies truncating it. If the domain doesn't handle truncation, it
+// returns an empty VirtualDentry instead.
+func (vfs *VirtualFilesystem) landlockCheckTruncateAt(ctx context.Context, creds *auth.Credentials, pop *PathOperation) (VirtualDentry, error) {
+	d := LandlockDomainFromContext(ctx)
+	if !d.handlesFS(linux.LANDLOCK_ACCESS_FS_TRUNCATE) {
+		return VirtualDentry{}, nil
+	}
+	vd, err := vfs.GetDentryAt(ctx, creds, pop, &GetDentryOptions{})
+	if err != nil {
+		return VirtualDentry{}, err
+	}
+	if err := d.checkFS(ctx, vd, linux.LANDLOCK_ACCESS_FS_TRUNCATE); err != nil {
+		vd.DecRef(ctx)
+		return VirtualDentry{}, err
+	}
+	return vd, nil
+}
+
+// landlockGetLinkSource returns the file at oldpop, like GetDentryAt. If
+// ctx's Landlock domain restricts filesystem access and the file was resolved
+// as a child of a directory rather than through a symlink or a file
+// descriptor, landlockGetLinkSource also returns the directory, which the
+// caller must DecRef.
+func (vfs *VirtualFilesystem) landlockGetLinkSource(ctx context.Context, creds *auth.Credentials, oldpop *PathOperation) (VirtualDentry, VirtualDentry, error) {
+	if !oldpop.Path.Begin.Ok() || !LandlockDomainFromContext(ctx).handlesFS(LandlockAccessFS) {
+		oldVD, err := vfs.GetDentryAt(ctx, creds, oldpop, &GetDentryOptions{})
+		return VirtualDentry{}, oldVD, err
+	}
+	pop, oldParentVD, err := vfs.landlockResolveParent(ctx, creds, oldpop, LandlockAccessFS)
+	if err != nil {
+		return VirtualDentry{}, VirtualDentry{}, err
+	}
+	noFollowPop := *pop
+	noFollowPop.FollowFinalSymlink = false
+	oldVD, err := vfs.GetDentryAt(ctx, creds, &noFollowPop, &GetDentryOptions{})
+	if err != nil {
+		oldParentVD.DecRef(ctx)
+		return VirtualDentry{}, VirtualDentry{}, err
+	}
+	if !pop.FollowFinalSymlink {
+		return oldParentVD, oldVD, nil
+	}
+	stat, err := vfs.StatAt(ctx, creds, &PathOperation{
+		Root:  oldVD,
+		Start: oldVD,
+	}, &StatOptions{Mask: linux.STATX_TYPE})
+	if err == nil && stat.Mode&linux.S_IFMT != linux.S_IFLNK {
+		return oldParentVD, oldVD, nil
+	}
+	oldVD.DecRef(ctx)
+	oldParentVD.DecRef(ctx)
+	if err != nil {
+		return VirtualDentry{}, VirtualDentry{}, err
+	}
+	oldVD, err = vfs.GetDentryAt(ctx, creds, pop, &GetDentryOptions{})
+	return VirtualDentry{}, oldVD, err
+}
+
+// landlockCheckLink returns EACCES or EXDEV if ctx's Landlock domain denies
+// creating a hard link in newParentVD to the file at oldVD. If
+// oldParentVD.Ok(), oldVD is a child of oldParentVD.
+func (vfs *VirtualFilesystem) landlockCheckLink(ctx context.Context, creds *auth.Credentials, oldParentVD, oldVD, newParentVD VirtualDentry) error {
+	d := LandlockDomainFromContext(ctx)
+	if d == nil || d.handledFS == 0 {
+		return nil
+	}
+	stat, err := vfs.StatAt(ctx, creds, &PathOperation{
+		Root:  oldVD,
+		Start: oldVD,
+	}, &StatOptions{Mask: linux.STATX_TYPE})
+	if err != nil {
+		return err
+	}
+	mode := linux.FileMode(stat.Mode)
+	if oldParentVD == newParentVD {
+		return d.checkFS(ctx, newParentVD, landlockMakeAccess(mode))
+	}
+	if oldVD.mount == oldVD.mount.vfs.anonMount {
+		return nil
+	}
+	// oldVD may have been specified by file descriptor, or reached through a
+	// symlink, so get the access rights on the directory containing it
+	// without resolving that directory.
+	return d.checkReparent(ctx, d.fsGranted(ctx, oldVD, true /* parentOf */), newParentVD, mode, false /* remove */)
+}
+
+// landlockCheckRename returns EACCES or EXDEV if ctx's Landlock domain denies
+// renaming the file oldName in oldParentVD to newName in newParentVD.
+func (vfs *VirtualFilesystem) landlockCheckRename(ctx context.Context, creds *auth.Credentials, oldParentVD VirtualDentry, oldName string, newParentVD VirtualDentry, newName string, opts *RenameOptions) error {
+	d := LandlockDomainFromContext(ctx)
+	if d == nil || d.handledFS == 0 {
+		return nil
+	}
+	if newName == "." || newName == ".." {
+		// The rename fails with EBUSY.
+		return nil
+	}
+	oldStat, err := vfs.StatAt(ctx, creds, &PathOperation{
+		Root:  oldParentVD,
+		Start: oldParentVD,
+		Path:  fspath.Parse(oldName),
+	}, &StatOptions{Mask: linux.STATX_TYPE})
+	if err != nil {
+		return err
+	}
+	if err := d.checkMove(ctx, oldParentVD, newParentVD, linux.FileMode(oldStat.Mode)); err != nil {
+		return err
+	}
+	newStat, err := vfs.StatAt(ctx, creds, &PathOperation{
+		Root:  newParentVD,
+		Start: newParentVD,
+		Path:  fspath.Parse(newName),
+	}, &StatOptions{Mask: linux.STATX_TYPE})
+	if linuxerr.Equals(linuxerr.ENOENT, err) {
+		// Nothing is replaced or exchanged.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if opts.Flags&linux.RENAME_EXCHANGE != 0 {
+		return d.checkMove(ctx, newParentVD, oldParentVD, linux.FileMode(newStat.Mode))
+	}
+	// The file at newName is replaced.
+	return d.checkFS(ctx, newParentVD, landlockRemoveAccess(linux.FileMode(newStat.Mode)))
+}
@@ -122,6 +122,11 @@ type Mount struct {
 	// namespace. It is analogous to MNT_LOCKED in Linux.
 	locked bool
 
+	// detached is true if mnt is the root of a mount tree created by fsmount(2)
+	// or open_tree(2) that has not yet been attached to a mount namespace by
+	// move_mount(2). detached is protected by VirtualFilesystem.mountMu.
+	detached bool
+
 	// The lower 63 bits of writers is the number of calls to
 	// Mount.CheckBeginWrite() that have not yet been paired with a call to
 	// Mount.EndWrite(). The MSB of writers is set if MS_RDONLY is in effect.
@@ -205,6 +210,13 @@ func (mnt *Mount) neverConnected() bool {
 	return mnt.ns == nil
 }
 
+// supportsCachedResolution returns true if mnt's filesystem honors
+// RESOLVE_CACHED.
+func (mnt *Mount) supportsCachedResolution() bool {
+	ext, ok := mnt.fs.impl.(FilesystemImplCachedResolutionExtension)
+	return ok && ext.SupportsCachedResolution()
+}
+
 // coveringMount returns a mount that completely covers mnt if it exists and nil
 // otherwise. A mount that covers another is one that is the only child of its
 // parent and whose mountpoint is its parent's root.
@@ -606,6 +618,170 @@ func (vfs *VirtualFilesystem) BindAt(ctx context.Context, creds *auth.Credential
 	return nil
 }
 
+// OpenTreeAt returns an O_PATH file description for the root of a detached
+// copy of the mount at the path represented by pop, as for open_tree(2) with
+// OPEN_TREE_CLONE. If recursive is true, the mounts beneath pop are copied as
+// well. The copy is unmounted when the file description is released, unless it
+// has been attached by MoveMountAt.
+func (vfs *VirtualFilesystem) OpenTreeAt(ctx context.Context, creds *auth.Credentials, pop *PathOperation, recursive bool) (*FileDescription, error) {
+	vd, err := vfs.GetDentryAt(ctx, creds, pop, &GetDentryOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer vd.DecRef(ctx)
+
+	vfs.lockMounts()
+	defer vfs.unlockMounts(ctx)
+	fsName := vd.mount.Filesystem().FilesystemType().Name()
+	if !vfs.validInMountNS(ctx, vd.mount) && fsName != nsfsName {
+		return nil, linuxerr.EINVAL
+	}
+	var clone *Mount
+	if recursive {
+		clone, err = vfs.cloneMountTree(ctx, vd.mount, vd.dentry, 0, nil)
+	} else {
+		if vfs.mountHasLockedChildren(vd.mount, vd) {
+			return nil, linuxerr.EINVAL
+		}
+		clone, err = vfs.cloneMount(vd.mount, vd.dentry, nil, 0)
+	}
+	if err != nil {
+		return nil, err
+	}
+	clone.locked = false
+	fd, err := vfs.newMountFD(clone)
+	if err != nil {
+		vfs.abortUncommitedMount(ctx, clone)
+		return nil, err
+	}
+	vfs.delayDecRef(clone)
+	return fd, nil
+}
+
+// MoveMountAt moves the mount at the path represented by from to the path
+// represented by target. If from refers to a detached mount tree returned by
+// fsmount(2) or open_tree(2), the tree is attached at target instead. It is
+// analogous to Linux's fs/namespace.c:do_move_mount().
+func (vfs *VirtualFilesystem) MoveMountAt(ctx context.Context, creds *auth.Credentials, from, target *PathOperation) error {
+	fromVd, err := vfs.GetDentryAt(ctx, creds, from, &GetDentryOptions{})
+	if err != nil {
+		return err
+	}
+	defer fromVd.DecRef(ctx)
+	targetVd, err := vfs.GetDentryAt(ctx, creds, target, &GetDentryOptions{})
+	if err != nil {
+		return err
+	}
+
+	vfs.lockMounts()
+	defer vfs.unlockMounts(ctx)
+	mp, err := vfs.lockMountpoint(targetVd)
+	if err != nil {
+		return err
+	}
+	cleanup := cleanup.Make(func() {
+		mp.dentry.mu.Unlock()
+		vfs.delayDecRef(mp) // +checklocksforce
+	})
+	defer cleanup.Clean()
+	mnt := fromVd.mount
+	if fromVd.dentry != mnt.root || mnt.locked {
+		return linuxerr.EINVAL
+	}
+	if !vfs.validInMountNS(ctx, mp.mount) {
+		return linuxerr.EINVAL
+	}
+	if mnt.detached {
+		cleanup.Release()
+		if err := vfs.attachTreeLocked(ctx, mnt, mp); err != nil {
+			return err
+		}
+		mnt.detached = false
+		return nil
+	}
+
+	if !vfs.validInMountNS(ctx, mnt) || mnt.parent() == nil {
+		return linuxerr.EINVAL
+	}
+	// Don't move a mount residing in a shared parent.
+	if mnt.parent().isShared {
+		return linuxerr.EINVAL
+	}
+	// TODO(b/305893463): Support propagating moved mounts to the peers and
+	// followers of a shared destination.
+	if mp.mount.isShared {
+		return linuxerr.EINVAL
+	}
+	// The mount can't be moved beneath itself.
+	for m := mp.mount; m != nil; m = m.parent() {
+		if m == mnt {
+			return linuxerr.ELOOP
+		}
+	}
+	cleanup.Release()
+
+	vfs.mounts.seq.BeginWrite()
+	vfs.delayDecRef(vfs.disconnectLocked(mnt))
+	vfs.delayDecRef(mnt)
+	vfs.connectLocked(mnt, mp, mp.mount.ns)
+	vfs.mounts.seq.EndWrite()
+	mp.dentry.mu.Unlock()
+	return nil
+}
+
+// SetMountAttrAt changes the attributes of the mount at the path represented
+// by pop, as for mount_setattr(2).
+func (vfs *VirtualFilesystem) SetMountAttrAt(ctx context.Context, creds *auth.Credentials, pop *PathOperation, opts *SetMountAttrOptions) error {
+	vd, err := vfs.GetDentryAt(ctx, creds, pop, &GetDentryOptions{})
+	if err != nil {
+		return err
+	}
+	defer vd.DecRef(ctx)
+
+	vfs.lockMounts()
+	defer vfs.unlockMounts(ctx)
+	mnt := vd.mount
+	if vd.dentry != mnt.root {
+		return linuxerr.EINVAL
+	}
+	if !mnt.detached && !vfs.validInMountNS(ctx, mnt) {
+		return linuxerr.EINVAL
+	}
+	mnts := []*Mount{mnt}
+	if opts.Recursive {
+		mnts = mnt.submountsLocked()
+	}
+
+	// Only allocating group IDs and changing the read-only state can fail, so
+	// do them first and roll them back on failure.
+	if opts.Propagation == linux.MS_SHARED {
+		if err := vfs.allocMountGroupIDs(mnt, opts.Recursive); err != nil {
+			return err
+		}
+	}
+	if (opts.AttrSet|opts.AttrClr)&linux.MOUNT_ATTR_RDONLY != 0 {
+		ro := opts.AttrSet&linux.MOUNT_ATTR_RDONLY != 0
+		wasRO := make([]bool, len(mnts))
+		for i, m := range mnts {
+			wasRO[i] = m.ReadOnlyLocked()
+			if err := m.setReadOnlyLocked(ro); err != nil {
+				for j := 0; j < i; j++ {
+					mnts[j].setReadOnlyLocked(wasRO[j])
+				}
+				vfs.cleanupGroupIDs(mnts)
+				return err
+			}
+		}
+	}
+	for _, m := range mnts {
+		m.flags.update(opts.AttrSet, opts.AttrClr)
+		if opts.Propagation != 0 {
+			vfs.setPropagation(m, opts.Propagation)
+		}
+	}
+	return nil
+}
+
 // RemountAt changes the mountflags and data of an existing mount without having to unmount and remount the filesystem.
 func (vfs *VirtualFilesystem) RemountAt(ctx context.Context, creds *auth.Credentials, pop *PathOperation, opts *MountOptions) error {
 	vd, err := vfs.getMountpoint(ctx, creds, pop)
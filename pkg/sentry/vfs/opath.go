@@ -166,3 +166,42 @@ func (vfs *VirtualFilesystem) openOPathFD(ctx context.Context, creds *auth.Crede
 	}
 	return &fd.vfsfd, err
 }
+
+// mountFD implements FileDescriptionImpl for the O_PATH file descriptions
+// returned by fsmount(2) and open_tree(2) for detached mount trees.
+//
+// +stateify savable
+type mountFD struct {
+	opathFD
+}
+
+// newMountFD returns a mountFD for the root of mnt, which must be the root of
+// a mount tree that is not connected to any mount namespace, and marks mnt as
+// detached.
+//
+// +checklocks:vfs.mountMu
+func (vfs *VirtualFilesystem) newMountFD(mnt *Mount) (*FileDescription, error) {
+	fd := &mountFD{}
+	if err := fd.vfsfd.Init(fd, linux.O_PATH, mnt, mnt.root, &FileDescriptionOptions{}); err != nil {
+		return nil, err
+	}
+	mnt.detached = true
+	return &fd.vfsfd, nil
+}
+
+// Release implements FileDescriptionImpl.Release.
+func (fd *mountFD) Release(ctx context.Context) {
+	// Like Linux's fs/namespace.c:dissolve_on_fput(), unmount the tree if it
+	// was never attached. The root Mount itself is released along with
+	// fd.vfsfd's reference on it.
+	mnt := fd.vfsfd.vd.mount
+	vfs := mnt.vfs
+	vfs.lockMounts()
+	defer vfs.unlockMounts(ctx)
+	if !mnt.detached {
+		return
+	}
+	mnt.detached = false
+	vfs.setPropagation(mnt, linux.MS_PRIVATE)
+	vfs.abortUncomittedChildren(ctx, mnt)
+}
@@ -100,6 +100,31 @@ type MountFlags struct {
 	NoSUID bool
 }
 
+// update sets the flags corresponding to the linux.MOUNT_ATTR_* attributes in
+// set and clears those in clr.
+func (f *MountFlags) update(set, clr uint64) {
+	for _, attr := range []struct {
+		flag *bool
+		mask uint64
+	}{
+		{&f.NoSUID, linux.MOUNT_ATTR_NOSUID},
+		{&f.NoDev, linux.MOUNT_ATTR_NODEV},
+		{&f.NoExec, linux.MOUNT_ATTR_NOEXEC},
+	} {
+		if clr&attr.mask != 0 {
+			*attr.flag = false
+		}
+		if set&attr.mask != 0 {
+			*attr.flag = true
+		}
+	}
+	// The access time attributes are an enumeration rather than a bitmask,
+	// and are only changed if all of them are cleared.
+	if clr&linux.MOUNT_ATTR__ATIME == linux.MOUNT_ATTR__ATIME {
+		f.NoATime = set&linux.MOUNT_ATTR__ATIME == linux.MOUNT_ATTR_NOATIME
+	}
+}
+
 // MountOptions contains options to VirtualFilesystem.MountAt(), and VirtualFilesystem.RemountAt()
 //
 // +stateify savable
@@ -263,6 +288,24 @@ type StatOptions struct {
 	Sync uint32
 }
 
+// SetMountAttrOptions contains options to VirtualFilesystem.SetMountAttrAt().
+//
+// +stateify savable
+type SetMountAttrOptions struct {
+	// AttrSet and AttrClr are the linux.MOUNT_ATTR_* attributes to set and
+	// clear respectively. linux.MOUNT_ATTR_IDMAP is not supported.
+	AttrSet uint64
+	AttrClr uint64
+
+	// Propagation is the propagation type to apply: one of linux.MS_SHARED,
+	// linux.MS_PRIVATE or linux.MS_SLAVE, or 0 to leave it unchanged.
+	Propagation uint32
+
+	// If Recursive is true, the attributes are also applied to all mounts
+	// beneath the target mount.
+	Recursive bool
+}
+
 // UmountOptions contains options to VirtualFilesystem.UmountAt().
 //
 // +stateify savable
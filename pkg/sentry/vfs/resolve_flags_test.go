@@ -0,0 +1,238 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vfs_test
+
+import (
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/fspath"
+	"gvisor.dev/gvisor/pkg/sentry/contexttest"
+	"gvisor.dev/gvisor/pkg/sentry/fsimpl/tmpfs"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+)
+
+// newResolveTree creates the following tree on tmpfs, with a second tmpfs
+// mounted at /dir/mnt:
+//
+//	/outside
+//	/dir/file
+//	/dir/sub/
+//	/dir/link_rel -> file
+//	/dir/link_up -> ../outside
+//	/dir/link_abs -> /outside
+//	/dir/mnt/inner
+//
+// It returns the VirtualFilesystem, the root, and a cleanup function.
+func newResolveTree(ctx context.Context, t *testing.T) (*vfs.VirtualFilesystem, vfs.VirtualDentry, func()) {
+	t.Helper()
+	creds := auth.CredentialsFromContext(ctx)
+
+	vfsObj := &vfs.VirtualFilesystem{}
+	if err := vfsObj.Init(ctx); err != nil {
+		t.Fatalf("VFS init: %v", err)
+	}
+	vfsObj.MustRegisterFilesystemType("tmpfs", tmpfs.FilesystemType{}, &vfs.RegisterFilesystemTypeOptions{
+		AllowUserMount: true,
+	})
+	mntns, err := vfsObj.NewMountNamespace(ctx, creds, "", "tmpfs", &vfs.MountOptions{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create tmpfs root mount: %v", err)
+	}
+	root := mntns.Root(ctx)
+	cleanup := func() {
+		root.DecRef(ctx)
+		mntns.DecRef(ctx)
+	}
+	pop := func(path string) *vfs.PathOperation {
+		return &vfs.PathOperation{
+			Root:  root,
+			Start: root,
+			Path:  fspath.Parse(path),
+		}
+	}
+	create := func(path string) {
+		fd, err := vfsObj.OpenAt(ctx, creds, pop(path), &vfs.OpenOptions{
+			Flags: linux.O_RDWR | linux.O_CREAT | linux.O_EXCL,
+			Mode:  0644,
+		})
+		if err != nil {
+			cleanup()
+			t.Fatalf("failed to create file %q: %v", path, err)
+		}
+		fd.DecRef(ctx)
+	}
+	mkdir := func(path string) {
+		if err := vfsObj.MkdirAt(ctx, creds, pop(path), &vfs.MkdirOptions{Mode: 0755}); err != nil {
+			cleanup()
+			t.Fatalf("failed to create directory %q: %v", path, err)
+		}
+	}
+	symlink := func(path, target string) {
+		if err := vfsObj.SymlinkAt(ctx, creds, pop(path), target); err != nil {
+			cleanup()
+			t.Fatalf("failed to create symlink %q: %v", path, err)
+		}
+	}
+
+	create("outside")
+	mkdir("dir")
+	create("dir/file")
+	mkdir("dir/sub")
+	symlink("dir/link_rel", "file")
+	symlink("dir/link_up", "../outside")
+	symlink("dir/link_abs", "/outside")
+	mkdir("dir/mnt")
+	if _, err := vfsObj.MountAt(ctx, creds, "", pop("dir/mnt"), "tmpfs", &vfs.MountOptions{}); err != nil {
+		cleanup()
+		t.Fatalf("failed to mount tmpfs submount: %v", err)
+	}
+	create("dir/mnt/inner")
+	return vfsObj, root, cleanup
+}
+
+func TestResolveFlags(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		start   string
+		path    string
+		resolve uint64
+		wantErr *errors.Error
+	}{
+		{
+			name: "NoFlagsDotDotEscape",
+			path: "../outside",
+		},
+		{
+			name:    "BeneathDotDotEscape",
+			path:    "../outside",
+			resolve: linux.RESOLVE_BENEATH,
+			wantErr: linuxerr.EXDEV,
+		},
+		{
+			name:    "BeneathDotDotInside",
+			path:    "sub/../file",
+			resolve: linux.RESOLVE_BENEATH,
+		},
+		{
+			name:    "BeneathRelativeSymlinkEscape",
+			path:    "link_up",
+			resolve: linux.RESOLVE_BENEATH,
+			wantErr: linuxerr.EXDEV,
+		},
+		{
+			name:    "BeneathAbsoluteSymlink",
+			path:    "link_abs",
+			resolve: linux.RESOLVE_BENEATH,
+			wantErr: linuxerr.EXDEV,
+		},
+		{
+			// ".." at the starting point stays there, so this resolves to
+			// /dir/file.
+			name:    "InRootDotDotEscape",
+			path:    "../file",
+			resolve: linux.RESOLVE_IN_ROOT,
+		},
+		{
+			// The symlink is resolved relative to the starting point, where
+			// "outside" doesn't exist.
+			name:    "InRootAbsoluteSymlink",
+			path:    "link_abs",
+			resolve: linux.RESOLVE_IN_ROOT,
+			wantErr: linuxerr.ENOENT,
+		},
+		{
+			name:    "NoSymlinks",
+			path:    "link_rel",
+			resolve: linux.RESOLVE_NO_SYMLINKS,
+			wantErr: linuxerr.ELOOP,
+		},
+		{
+			name:    "NoMagicLinksFollowsSymlinks",
+			path:    "link_rel",
+			resolve: linux.RESOLVE_NO_MAGICLINKS,
+		},
+		{
+			name: "MountCrossing",
+			path: "mnt/../mnt/inner",
+		},
+		{
+			name:    "NoXDevIntoMount",
+			path:    "mnt/inner",
+			resolve: linux.RESOLVE_NO_XDEV,
+			wantErr: linuxerr.EXDEV,
+		},
+		{
+			name:    "NoXDevOutOfMount",
+			start:   "dir/mnt",
+			path:    "../file",
+			resolve: linux.RESOLVE_NO_XDEV,
+			wantErr: linuxerr.EXDEV,
+		},
+		{
+			name:    "NoXDevWithinMount",
+			start:   "dir/mnt",
+			path:    "inner",
+			resolve: linux.RESOLVE_NO_XDEV,
+		},
+		{
+			name:    "CachedMountCrossing",
+			path:    "mnt/inner",
+			resolve: linux.RESOLVE_CACHED,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := contexttest.Context(t)
+			creds := auth.CredentialsFromContext(ctx)
+			vfsObj, root, cleanup := newResolveTree(ctx, t)
+			defer cleanup()
+
+			if test.start == "" {
+				test.start = "dir"
+			}
+			start, err := vfsObj.GetDentryAt(ctx, creds, &vfs.PathOperation{
+				Root:  root,
+				Start: root,
+				Path:  fspath.Parse(test.start),
+			}, &vfs.GetDentryOptions{})
+			if err != nil {
+				t.Fatalf("failed to walk to %q: %v", test.start, err)
+			}
+			defer start.DecRef(ctx)
+
+			fd, err := vfsObj.OpenAt(ctx, creds, &vfs.PathOperation{
+				Root:               root,
+				Start:              start,
+				Path:               fspath.Parse(test.path),
+				FollowFinalSymlink: true,
+				ResolveFlags:       test.resolve,
+			}, &vfs.OpenOptions{Flags: linux.O_RDONLY})
+			if err == nil {
+				fd.DecRef(ctx)
+			}
+			if test.wantErr == nil {
+				if err != nil {
+					t.Errorf("OpenAt(%q, %#x) failed: %v", test.path, test.resolve, err)
+				}
+			} else if !linuxerr.Equals(test.wantErr, err) {
+				t.Errorf("OpenAt(%q, %#x) got error %v, want %v", test.path, test.resolve, err, test.wantErr)
+			}
+		})
+	}
+}
@@ -49,6 +49,9 @@ type ResolvingPath struct {
 	symlinks  uint8 // number of symlinks traversed
 	curPart   uint8 // index into parts
 
+	// resolve is the set of linux.RESOLVE_* flags restricting resolution.
+	resolve uint64
+
 	creds *auth.Credentials
 
 	// Data associated with resolve*Errors, stored in ResolvingPath so that
@@ -118,6 +121,11 @@ func (vfs *VirtualFilesystem) getResolvingPath(creds *auth.Credentials, pop *Pat
 	rp := resolvingPathPool.Get().(*ResolvingPath)
 	rp.vfs = vfs
 	rp.root = pop.Root
+	if pop.ResolveFlags&(linux.RESOLVE_BENEATH|linux.RESOLVE_IN_ROOT) != 0 {
+		// Path resolution is scoped to the starting point, which behaves like
+		// the VFS root.
+		rp.root = pop.Start
+	}
 	rp.mount = pop.Start.mount
 	rp.start = pop.Start.dentry
 	rp.pit = pop.Path.Begin
@@ -128,6 +136,7 @@ func (vfs *VirtualFilesystem) getResolvingPath(creds *auth.Credentials, pop *Pat
 	rp.mustBeDir = pop.Path.Dir
 	rp.symlinks = 0
 	rp.curPart = 0
+	rp.resolve = pop.ResolveFlags
 	rp.creds = creds
 	rp.parts[0] = pop.Path.Begin
 	return rp
@@ -283,15 +292,31 @@ func (rp *ResolvingPath) GetComponents(excludeLast bool, emit func(string) bool)
 // Mount, CheckRoot returns (unspecified, non-nil error). Otherwise, path
 // resolution should resolve d's parent normally, and CheckRoot returns (false,
 // nil).
+//
+// If RESOLVE_BENEATH or RESOLVE_NO_XDEV forbid resolving d's parent, CheckRoot
+// returns EXDEV. If RESOLVE_CACHED is set and d's parent is on a filesystem
+// that can't honor it, CheckRoot returns EAGAIN.
 func (rp *ResolvingPath) CheckRoot(ctx context.Context, d *Dentry) (bool, error) {
 	if d == rp.root.dentry && rp.mount == rp.root.mount {
 		// At contextual VFS root (due to e.g. chroot(2)).
+		if rp.resolve&linux.RESOLVE_BENEATH != 0 {
+			// ".." would escape the starting point.
+			return false, linuxerr.EXDEV
+		}
 		return true, nil
 	} else if d == rp.mount.root {
 		// At mount root ...
 		vd := rp.vfs.getMountpointAt(ctx, rp.mount, rp.root)
 		if vd.Ok() {
 			// ... of non-root mount.
+			if rp.resolve&linux.RESOLVE_NO_XDEV != 0 {
+				vd.DecRef(ctx)
+				return false, linuxerr.EXDEV
+			}
+			if err := rp.checkCached(vd.mount); err != nil {
+				vd.DecRef(ctx)
+				return false, err
+			}
 			rp.nextMount = vd.mount
 			rp.nextStart = vd.dentry
 			return false, resolveMountRootOrJumpError{}
@@ -311,6 +336,14 @@ func (rp *ResolvingPath) CheckMount(ctx context.Context, d *Dentry) error {
 		return nil
 	}
 	if mnt := rp.vfs.getMountAt(ctx, rp.mount, d); mnt != nil {
+		if rp.resolve&linux.RESOLVE_NO_XDEV != 0 {
+			mnt.DecRef(ctx)
+			return linuxerr.EXDEV
+		}
+		if err := rp.checkCached(mnt); err != nil {
+			mnt.DecRef(ctx)
+			return err
+		}
 		rp.nextMount = mnt
 		return resolveMountPointError{}
 	}
@@ -351,14 +384,28 @@ func (rp *ResolvingPath) ShouldFollowSymlink() bool {
 //
 // Postconditions: If HandleSymlink returns a nil error, then !rp.Done().
 func (rp *ResolvingPath) HandleSymlink(target string) (bool, error) {
+	if rp.resolve&linux.RESOLVE_NO_SYMLINKS != 0 {
+		return false, linuxerr.ELOOP
+	}
 	if rp.symlinks >= linux.MaxSymlinkTraversals {
 		return false, linuxerr.ELOOP
 	}
 	if len(target) == 0 {
 		return false, linuxerr.ENOENT
 	}
-	rp.symlinks++
 	targetPath := fspath.Parse(target)
+	if targetPath.Absolute {
+		if rp.resolve&linux.RESOLVE_BENEATH != 0 {
+			return false, linuxerr.EXDEV
+		}
+		if rp.resolve&linux.RESOLVE_NO_XDEV != 0 && rp.mount != rp.root.mount {
+			return false, linuxerr.EXDEV
+		}
+		if err := rp.checkCached(rp.root.mount); err != nil {
+			return false, err
+		}
+	}
+	rp.symlinks++
 	if targetPath.Absolute {
 		rp.absSymlinkTarget = targetPath
 		return true, resolveAbsSymlinkError{}
@@ -403,6 +450,19 @@ func (rp *ResolvingPath) relpathPrepend(path fspath.Path) {
 //
 // Preconditions: !rp.Done().
 func (rp *ResolvingPath) HandleJump(target VirtualDentry) (bool, error) {
+	if rp.resolve&(linux.RESOLVE_NO_SYMLINKS|linux.RESOLVE_NO_MAGICLINKS) != 0 {
+		return false, linuxerr.ELOOP
+	}
+	if rp.resolve&linux.RESOLVE_NO_XDEV != 0 && target.mount != rp.mount {
+		return false, linuxerr.EXDEV
+	}
+	if rp.resolve&(linux.RESOLVE_BENEATH|linux.RESOLVE_IN_ROOT) != 0 {
+		// Like Linux, don't let magic links escape the starting point.
+		return false, linuxerr.EXDEV
+	}
+	if err := rp.checkCached(target.mount); err != nil {
+		return false, err
+	}
 	if rp.symlinks >= linux.MaxSymlinkTraversals {
 		return false, linuxerr.ELOOP
 	}
@@ -487,3 +547,20 @@ func (rp *ResolvingPath) canHandleError(err error) bool {
 func (rp *ResolvingPath) MustBeDir() bool {
 	return rp.mustBeDir
 }
+
+// Cached returns true if path resolution must only use cached state, as
+// requested by RESOLVE_CACHED. FilesystemImpls that would need to block to
+// resolve rp should return EAGAIN instead. Only FilesystemImpls that implement
+// FilesystemImplCachedResolutionExtension are consulted with RESOLVE_CACHED.
+func (rp *ResolvingPath) Cached() bool {
+	return rp.resolve&linux.RESOLVE_CACHED != 0
+}
+
+// checkCached returns EAGAIN if path resolution must only use cached state
+// and would continue on mnt, whose filesystem can't honor RESOLVE_CACHED.
+func (rp *ResolvingPath) checkCached(mnt *Mount) error {
+	if !rp.Cached() || mnt.supportsCachedResolution() {
+		return nil
+	}
+	return linuxerr.EAGAIN
+}
@@ -0,0 +1,134 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vfs
+
+import (
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/errors"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+)
+
+// cachedFilesystem is an anonFilesystem that honors RESOLVE_CACHED.
+type cachedFilesystem struct {
+	anonFilesystem
+}
+
+// SupportsCachedResolution implements
+// FilesystemImplCachedResolutionExtension.SupportsCachedResolution.
+func (*cachedFilesystem) SupportsCachedResolution() bool {
+	return true
+}
+
+func newTestMount(impl FilesystemImpl) *Mount {
+	return &Mount{fs: &Filesystem{impl: impl}}
+}
+
+// Magic links can only be created by kernfs, so exercise HandleJump directly.
+func TestHandleJumpResolveFlags(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		resolve   uint64
+		sameMount bool
+		wantErr   *errors.Error
+	}{
+		{
+			name:      "NoMagicLinks",
+			resolve:   linux.RESOLVE_NO_MAGICLINKS,
+			sameMount: true,
+			wantErr:   linuxerr.ELOOP,
+		},
+		{
+			name:      "NoSymlinks",
+			resolve:   linux.RESOLVE_NO_SYMLINKS,
+			sameMount: true,
+			wantErr:   linuxerr.ELOOP,
+		},
+		{
+			name:    "NoXDev",
+			resolve: linux.RESOLVE_NO_XDEV,
+			wantErr: linuxerr.EXDEV,
+		},
+		{
+			name:      "Beneath",
+			resolve:   linux.RESOLVE_BENEATH,
+			sameMount: true,
+			wantErr:   linuxerr.EXDEV,
+		},
+		{
+			name:      "InRoot",
+			resolve:   linux.RESOLVE_IN_ROOT,
+			sameMount: true,
+			wantErr:   linuxerr.EXDEV,
+		},
+		{
+			name:    "CachedUnsupported",
+			resolve: linux.RESOLVE_CACHED,
+			wantErr: linuxerr.EAGAIN,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			rp := &ResolvingPath{
+				mount:   newTestMount(&cachedFilesystem{}),
+				resolve: test.resolve,
+			}
+			target := VirtualDentry{mount: newTestMount(&anonFilesystem{})}
+			if test.sameMount {
+				target.mount = rp.mount
+			}
+			if _, err := rp.HandleJump(target); !linuxerr.Equals(test.wantErr, err) {
+				t.Errorf("HandleJump got error %v, want %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckCached(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		resolve uint64
+		impl    FilesystemImpl
+		wantErr *errors.Error
+	}{
+		{
+			name: "NotCached",
+			impl: &anonFilesystem{},
+		},
+		{
+			name:    "Supported",
+			resolve: linux.RESOLVE_CACHED,
+			impl:    &cachedFilesystem{},
+		},
+		{
+			name:    "Unsupported",
+			resolve: linux.RESOLVE_CACHED,
+			impl:    &anonFilesystem{},
+			wantErr: linuxerr.EAGAIN,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			rp := &ResolvingPath{resolve: test.resolve}
+			err := rp.checkCached(newTestMount(test.impl))
+			if test.wantErr == nil {
+				if err != nil {
+					t.Errorf("checkCached failed: %v", err)
+				}
+			} else if !linuxerr.Equals(test.wantErr, err) {
+				t.Errorf("checkCached got error %v, want %v", err, test.wantErr)
+			}
+		})
+	}
+}
@@ -234,6 +234,10 @@ type PathOperation struct {
 	// path component represents a symbolic link, the symbolic link should be
 	// followed.
 	FollowFinalSymlink bool
+
+	// ResolveFlags is a bitmask of linux.RESOLVE_* flags restricting path
+	// resolution, as passed to openat2(2).
+	ResolveFlags uint64
 }
 
 // AccessAt checks whether a user with creds has access to the file at
@@ -308,10 +312,13 @@ func (vfs *VirtualFilesystem) getParentDirAndName(ctx context.Context, creds *au
 // LinkAt creates a hard link at newpop representing the existing file at
 // oldpop.
 func (vfs *VirtualFilesystem) LinkAt(ctx context.Context, creds *auth.Credentials, oldpop, newpop *PathOperation) error {
-	oldVD, err := vfs.GetDentryAt(ctx, creds, oldpop, &GetDentryOptions{})
+	oldParentVD, oldVD, err := vfs.landlockGetLinkSource(ctx, creds, oldpop)
 	if err != nil {
 		return err
 	}
+	if oldParentVD.Ok() {
+		defer oldParentVD.DecRef(ctx)
+	}
 
 	if !newpop.Path.Begin.Ok() {
 		oldVD.DecRef(ctx)
@@ -325,6 +332,18 @@ func (vfs *VirtualFilesystem) LinkAt(ctx context.Context, creds *auth.Credential
 		ctx.Warningf("VirtualFilesystem.LinkAt: file creation paths can't follow final symlink")
 		return linuxerr.EINVAL
 	}
+	newpop, newParentVD, err := vfs.landlockResolveParent(ctx, creds, newpop, LandlockAccessFS)
+	if err != nil {
+		oldVD.DecRef(ctx)
+		return err
+	}
+	if newParentVD.Ok() {
+		defer newParentVD.DecRef(ctx)
+		if err := vfs.landlockCheckLink(ctx, creds, oldParentVD, oldVD, newParentVD); err != nil {
+			oldVD.DecRef(ctx)
+			return err
+		}
+	}
 
 	rp := vfs.getResolvingPath(creds, newpop)
 	for {
@@ -365,6 +384,13 @@ func (vfs *VirtualFilesystem) MkdirAt(ctx context.Context, creds *auth.Credentia
 	// "Under Linux, apart from the permission bits, the S_ISVTX mode bit is
 	// also honored." - mkdir(2)
 	opts.Mode &= 0777 | linux.S_ISVTX
+	pop, parentVD, err := vfs.landlockCheckParent(ctx, creds, pop, linux.LANDLOCK_ACCESS_FS_MAKE_DIR)
+	if err != nil {
+		return err
+	}
+	if parentVD.Ok() {
+		defer parentVD.DecRef(ctx)
+	}
 
 	rp := vfs.getResolvingPath(creds, pop)
 	for {
@@ -401,6 +427,13 @@ func (vfs *VirtualFilesystem) MknodAt(ctx context.Context, creds *auth.Credentia
 		ctx.Warningf("VirtualFilesystem.MknodAt: file creation paths can't follow final symlink")
 		return linuxerr.EINVAL
 	}
+	pop, parentVD, err := vfs.landlockCheckParent(ctx, creds, pop, landlockMakeAccess(opts.Mode))
+	if err != nil {
+		return err
+	}
+	if parentVD.Ok() {
+		defer parentVD.DecRef(ctx)
+	}
 
 	rp := vfs.getResolvingPath(creds, pop)
 	for {
@@ -463,9 +496,31 @@ func (vfs *VirtualFilesystem) OpenAt(ctx context.Context, creds *auth.Credential
 	if opts.Flags&linux.O_NOFOLLOW != 0 {
 		pop.FollowFinalSymlink = false
 	}
+	if pop.ResolveFlags&linux.RESOLVE_CACHED != 0 && !pop.Start.mount.supportsCachedResolution() {
+		return nil, linuxerr.EAGAIN
+	}
 	if opts.Flags&linux.O_PATH != 0 {
 		return vfs.openOPathFD(ctx, creds, pop, opts.Flags)
 	}
+
+	var (
+		fd  *FileDescription
+		err error
+	)
+	if LandlockDomainFromContext(ctx).handlesFS(LandlockAccessFS) {
+		fd, err = vfs.landlockOpenAt(ctx, creds, pop, opts)
+	} else {
+		fd, err = vfs.openAt(ctx, creds, pop, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	fd.Dentry().InotifyWithParent(ctx, linux.IN_OPEN, 0, PathEvent)
+	return fd, nil
+}
+
+// openAt implements OpenAt after its options have been validated.
+func (vfs *VirtualFilesystem) openAt(ctx context.Context, creds *auth.Credentials, pop *PathOperation, opts *OpenOptions) (*FileDescription, error) {
 	rp := vfs.getResolvingPath(creds, pop)
 	if opts.Flags&linux.O_DIRECTORY != 0 {
 		rp.mustBeDir = true
@@ -494,7 +549,6 @@ func (vfs *VirtualFilesystem) OpenAt(ctx context.Context, creds *auth.Credential
 				}
 			}
 
-			fd.Dentry().InotifyWithParent(ctx, linux.IN_OPEN, 0, PathEvent)
 			return fd, nil
 		}
 		if !rp.handleError(ctx, err) {
@@ -559,6 +613,18 @@ func (vfs *VirtualFilesystem) RenameAt(ctx context.Context, creds *auth.Credenti
 		ctx.Warningf("VirtualFilesystem.RenameAt: destination path can't follow final symlink")
 		return linuxerr.EINVAL
 	}
+	newpop, newParentVD, err := vfs.landlockResolveParent(ctx, creds, newpop, LandlockAccessFS)
+	if err != nil {
+		oldParentVD.DecRef(ctx)
+		return err
+	}
+	if newParentVD.Ok() {
+		defer newParentVD.DecRef(ctx)
+		if err := vfs.landlockCheckRename(ctx, creds, oldParentVD, oldName, newParentVD, newpop.Path.Begin.String(), opts); err != nil {
+			oldParentVD.DecRef(ctx)
+			return err
+		}
+	}
 
 	rp := vfs.getResolvingPath(creds, newpop)
 	renameOpts := *opts
@@ -600,6 +666,13 @@ func (vfs *VirtualFilesystem) RmdirAt(ctx context.Context, creds *auth.Credentia
 		ctx.Warningf("VirtualFilesystem.RmdirAt: file deletion paths can't follow final symlink")
 		return linuxerr.EINVAL
 	}
+	pop, parentVD, err := vfs.landlockCheckParent(ctx, creds, pop, linux.LANDLOCK_ACCESS_FS_REMOVE_DIR)
+	if err != nil {
+		return err
+	}
+	if parentVD.Ok() {
+		defer parentVD.DecRef(ctx)
+	}
 
 	rp := vfs.getResolvingPath(creds, pop)
 	for {
@@ -623,6 +696,20 @@ func (vfs *VirtualFilesystem) RmdirAt(ctx context.Context, creds *auth.Credentia
 
 // SetStatAt changes metadata for the file at the given path.
 func (vfs *VirtualFilesystem) SetStatAt(ctx context.Context, creds *auth.Credentials, pop *PathOperation, opts *SetStatOptions) error {
+	if opts.Stat.Mask&linux.STATX_SIZE != 0 {
+		vd, err := vfs.landlockCheckTruncateAt(ctx, creds, pop)
+		if err != nil {
+			return err
+		}
+		if vd.Ok() {
+			// Change the file that was checked.
+			defer vd.DecRef(ctx)
+			pop = &PathOperation{
+				Root:  vd,
+				Start: vd,
+			}
+		}
+	}
 	rp := vfs.getResolvingPath(creds, pop)
 	for {
 		vfs.maybeBlockOnMountPromise(ctx, rp)
@@ -688,6 +775,13 @@ func (vfs *VirtualFilesystem) SymlinkAt(ctx context.Context, creds *auth.Credent
 		ctx.Warningf("VirtualFilesystem.SymlinkAt: file creation paths can't follow final symlink")
 		return linuxerr.EINVAL
 	}
+	pop, parentVD, err := vfs.landlockCheckParent(ctx, creds, pop, linux.LANDLOCK_ACCESS_FS_MAKE_SYM)
+	if err != nil {
+		return err
+	}
+	if parentVD.Ok() {
+		defer parentVD.DecRef(ctx)
+	}
 
 	rp := vfs.getResolvingPath(creds, pop)
 	for {
@@ -723,6 +817,13 @@ func (vfs *VirtualFilesystem) UnlinkAt(ctx context.Context, creds *auth.Credenti
 		ctx.Warningf("VirtualFilesystem.UnlinkAt: file deletion paths can't follow final symlink")
 		return linuxerr.EINVAL
 	}
+	pop, parentVD, err := vfs.landlockCheckParent(ctx, creds, pop, linux.LANDLOCK_ACCESS_FS_REMOVE_FILE)
+	if err != nil {
+		return err
+	}
+	if parentVD.Ok() {
+		defer parentVD.DecRef(ctx)
+	}
 
 	rp := vfs.getResolvingPath(creds, pop)
 	for {
@@ -0,0 +1,65 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package header
+
+// The ECN codepoints, carried in the two least significant bits of the IPv4
+// TOS and IPv6 Traffic Class fields, as defined in RFC 3168 section 5.
+const (
+	// ECNMask is the mask of the ECN field.
+	ECNMask = 0x3
+
+	// ECNNotECT indicates the transport isn't ECN-capable.
+	ECNNotECT = 0x0
+
+	// ECNECT1 is the ECT(1) ECN-Capable Transport codepoint.
+	ECNECT1 = 0x1
+
+	// ECNECT0 is the ECT(0) ECN-Capable Transport codepoint.
+	ECNECT0 = 0x2
+
+	// ECNCE is the Congestion Experienced codepoint.
+	ECNCE = 0x3
+)
+
+// IsECT returns true if the TOS or Traffic Class value tos indicates an
+// ECN-capable transport, including packets already marked CE.
+func IsECT(tos uint8) bool {
+	return tos&ECNMask != ECNNotECT
+}
+
+// IsCE returns true if the TOS or Traffic Class value tos carries the
+// Congestion Experienced codepoint.
+func IsCE(tos uint8) bool {
+	return tos&ECNMask == ECNCE
+}
+
+// MarkCE sets the Congestion Experienced codepoint in the IP header of a
+// packet, updating the IPv4 header checksum. It returns false if the packet
+// isn't ECN-capable, in which case it is left unchanged.
+func MarkCE(h Network) bool {
+	tos, label := h.TOS()
+	if !IsECT(tos) {
+		return false
+	}
+	if IsCE(tos) {
+		return true
+	}
+	h.SetTOS(tos|ECNCE, label)
+	if ipv4, ok := h.(IPv4); ok {
+		ipv4.SetChecksum(0)
+		ipv4.SetChecksum(^ipv4.CalculateChecksum())
+	}
+	return true
+}
@@ -91,6 +91,8 @@ const (
 	TCPOptionTS            = 8
 	TCPOptionSACKPermitted = 4
 	TCPOptionSACK          = 5
+	TCPOptionMD5Sig        = 19
+	TCPOptionFastOpen      = 34
 )
 
 // Option Lengths.
@@ -99,6 +101,22 @@ const (
 	TCPOptionTSLength            = 10
 	TCPOptionWSLength            = 3
 	TCPOptionSackPermittedLength = 2
+	TCPOptionMD5SigLength        = 18
+)
+
+// TCPMD5DigestSize is the size of the digest carried by the TCP MD5 signature
+// option, from RFC 2385 section 3.0.
+const TCPMD5DigestSize = 16
+
+// TCP Fast Open cookie lengths, from RFC 7413 section 4.1.1.
+const (
+	// TCPFastOpenCookieMinLength is the minimum length of a TCP Fast Open
+	// cookie.
+	TCPFastOpenCookieMinLength = 4
+
+	// TCPFastOpenCookieMaxLength is the maximum length of a TCP Fast Open
+	// cookie.
+	TCPFastOpenCookieMaxLength = 16
 )
 
 // TCPFields contains the fields of a TCP packet. It is used to describe the
@@ -158,6 +176,19 @@ type TCPSynOptions struct {
 	// SACKPermitted is true if the SACK option was provided in the SYN/SYN-ACK.
 	SACKPermitted bool
 
+	// FastOpen is true if the TCP Fast Open option was provided in the
+	// SYN/SYN-ACK.
+	FastOpen bool
+
+	// FastOpenCookie is the cookie carried by the TCP Fast Open option. An
+	// empty cookie in a SYN is a request for a cookie.
+	FastOpenCookie []byte
+
+	// MD5Sig is true if room for the TCP MD5 signature option must be made
+	// in an outgoing SYN/SYN-ACK. The digest is filled in when the segment
+	// is sent.
+	MD5Sig bool
+
 	// Flags if specified are set on the outgoing SYN. The SYN flag is
 	// always set.
 	Flags TCPFlags
@@ -201,6 +232,10 @@ type TCPOptions struct {
 
 	// SACKBlocks are the SACK blocks specified in the segment.
 	SACKBlocks []SACKBlock
+
+	// MD5Sig is the digest carried by the TCP MD5 signature option, or nil
+	// if the option isn't present.
+	MD5Sig []byte
 }
 
 // TCP represents a TCP header stored in a byte array.
@@ -507,6 +542,22 @@ func ParseSynOptions(opts []byte, isAck bool) TCPSynOptions {
 			synOpts.SACKPermitted = true
 			i += 2
 
+		case TCPOptionFastOpen:
+			if i+2 > limit {
+				return synOpts
+			}
+			l := int(opts[i+1])
+			if l < 2 || i+l > limit {
+				return synOpts
+			}
+			// Per RFC 7413 section 4.1.1, cookies with an invalid length
+			// are ignored.
+			if cookieLen := l - 2; cookieLen == 0 || (cookieLen >= TCPFastOpenCookieMinLength && cookieLen <= TCPFastOpenCookieMaxLength && cookieLen%2 == 0) {
+				synOpts.FastOpen = true
+				synOpts.FastOpenCookie = append([]byte{}, opts[i+2:i+l]...)
+			}
+			i += l
+
 		default:
 			// We don't recognize this option, just skip over it.
 			if i+2 > limit {
@@ -565,6 +616,12 @@ func ParseTCPOptions(b []byte) TCPOptions {
 				})
 			}
 			i += sackOptionLen
+		case TCPOptionMD5Sig:
+			if i+TCPOptionMD5SigLength > limit || b[i+1] != TCPOptionMD5SigLength {
+				return opts
+			}
+			opts.MD5Sig = b[i+2 : i+TCPOptionMD5SigLength]
+			i += TCPOptionMD5SigLength
 		default:
 			// We don't recognize this option, just skip over it.
 			if i+2 > limit {
@@ -633,6 +690,35 @@ func EncodeSACKPermittedOption(b []byte) int {
 	return int(b[1])
 }
 
+// EncodeFastOpenOption encodes a TCP Fast Open option carrying the provided
+// cookie into the provided buffer. An empty cookie is encoded as a cookie
+// request. If the buffer is smaller than required it just returns without
+// encoding anything. It returns the number of bytes written to the provided
+// buffer.
+func EncodeFastOpenOption(cookie []byte, b []byte) int {
+	l := 2 + len(cookie)
+	if len(b) < l {
+		return 0
+	}
+	b[0], b[1] = TCPOptionFastOpen, byte(l)
+	copy(b[2:], cookie)
+	return l
+}
+
+// EncodeMD5SigOption encodes a TCP MD5 signature option with a zero digest
+// into the provided buffer. The digest is meant to be filled in once the
+// segment is complete. If the buffer is smaller than required it just returns
+// without encoding anything. It returns the number of bytes written to the
+// provided buffer.
+func EncodeMD5SigOption(b []byte) int {
+	if len(b) < TCPOptionMD5SigLength {
+		return 0
+	}
+	b[0], b[1] = TCPOptionMD5Sig, TCPOptionMD5SigLength
+	clear(b[2:TCPOptionMD5SigLength])
+	return TCPOptionMD5SigLength
+}
+
 // EncodeSACKBlocks encodes the provided SACK blocks as a TCP SACK option block
 // in the provided slice. It tries to fit in as many blocks as possible based on
 // number of bytes available in the provided buffer. It returns the number of
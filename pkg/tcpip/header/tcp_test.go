@@ -169,3 +169,50 @@ func TestTCPFlags(t *testing.T) {
 		}
 	}
 }
+
+func TestParseSynOptionsFastOpen(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		cookie       []byte
+		wantFastOpen bool
+	}{
+		{
+			name:         "cookie request",
+			cookie:       []byte{},
+			wantFastOpen: true,
+		},
+		{
+			name:         "valid cookie",
+			cookie:       []byte{1, 2, 3, 4, 5, 6, 7, 8},
+			wantFastOpen: true,
+		},
+		{
+			name:   "cookie too short",
+			cookie: []byte{1, 2},
+		},
+		{
+			name:   "cookie of odd length",
+			cookie: []byte{1, 2, 3, 4, 5},
+		},
+		{
+			name:   "cookie too long",
+			cookie: make([]byte, header.TCPFastOpenCookieMaxLength+2),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			b := make([]byte, header.TCPOptionsMaximumSize)
+			n := header.EncodeMSSOption(1460, b)
+			n += header.EncodeFastOpenOption(tc.cookie, b[n:])
+			opts := header.ParseSynOptions(b[:n], false /* isAck */)
+			if opts.MSS != 1460 {
+				t.Errorf("got opts.MSS = %d, want = 1460", opts.MSS)
+			}
+			if opts.FastOpen != tc.wantFastOpen {
+				t.Fatalf("got opts.FastOpen = %t, want = %t", opts.FastOpen, tc.wantFastOpen)
+			}
+			if tc.wantFastOpen && !slices.Equal(opts.FastOpenCookie, tc.cookie) {
+				t.Errorf("got opts.FastOpenCookie = %x, want = %x", opts.FastOpenCookie, tc.cookie)
+			}
+		})
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"gvisor.dev/gvisor/pkg/sleep"
 	"gvisor.dev/gvisor/pkg/sync"
 	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
 	"gvisor.dev/gvisor/pkg/tcpip/stack"
 )
 
@@ -47,6 +48,11 @@ type discipline struct {
 	wg          sync.WaitGroup `state:"nosave"`
 	dispatchers []queueDispatcher
 
+	// markThreshold is the queue length from which ECN-capable packets are
+	// marked with congestion experienced, or zero if they are never
+	// marked. It is immutable.
+	markThreshold int
+
 	closed atomicbitops.Int32
 }
 
@@ -71,8 +77,19 @@ type queueDispatcher struct {
 //
 // +checklocksignore: we don't have to hold locks during initialization.
 func New(lower stack.LinkWriter, n int, queueLen int) stack.QueueingDiscipline {
+	return NewWithECN(lower, n, queueLen, 0)
+}
+
+// NewWithECN creates a new fifo queuing discipline like New, which also marks
+// ECN-capable packets with congestion experienced instead of only dropping
+// packets once full, when a queue holds at least markThreshold packets. Packets
+// are never marked if markThreshold is zero.
+//
+// +checklocksignore: we don't have to hold locks during initialization.
+func NewWithECN(lower stack.LinkWriter, n int, queueLen int, markThreshold int) stack.QueueingDiscipline {
 	d := &discipline{
-		dispatchers: make([]queueDispatcher, n),
+		dispatchers:   make([]queueDispatcher, n),
+		markThreshold: markThreshold,
 	}
 	// Create the required dispatchers
 	for i := range d.dispatchers {
@@ -117,6 +134,9 @@ func (qd *queueDispatcher) dispatchLoop() {
 				continue
 			}
 			qd.mu.Unlock()
+			for _, p := range batch.AsSlice() {
+				p.TXTimestamps.Record(tcpip.TXTimestampSent, p)
+			}
 			_, _ = qd.lower.WritePackets(batch)
 			batch.Reset()
 			qd.mu.Lock()
@@ -139,6 +159,9 @@ func (d *discipline) WritePacket(pkt *stack.PacketBuffer) tcpip.Error {
 	qd.mu.Lock()
 	haveSpace := qd.queue.hasSpace()
 	if haveSpace {
+		if d.markThreshold > 0 && qd.queue.length() >= d.markThreshold {
+			markCE(pkt)
+		}
 		qd.queue.pushBack(pkt.IncRef())
 	}
 	qd.mu.Unlock()
@@ -149,6 +172,21 @@ func (d *discipline) WritePacket(pkt *stack.PacketBuffer) tcpip.Error {
 	return nil
 }
 
+// markCE marks pkt with congestion experienced if it's ECN-capable, as
+// described in RFC 3168 section 5.
+func markCE(pkt *stack.PacketBuffer) {
+	switch h := pkt.NetworkHeader().Slice(); pkt.NetworkProtocolNumber {
+	case header.IPv4ProtocolNumber:
+		if len(h) >= header.IPv4MinimumSize {
+			header.MarkCE(header.IPv4(h))
+		}
+	case header.IPv6ProtocolNumber:
+		if len(h) >= header.IPv6MinimumSize {
+			header.MarkCE(header.IPv6(h))
+		}
+	}
+}
+
 func (d *discipline) Close() {
 	d.closed.Store(qDiscClosed)
 	for i := range d.dispatchers {
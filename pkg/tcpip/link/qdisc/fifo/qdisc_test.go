@@ -24,6 +24,7 @@ import (
 	"gvisor.dev/gvisor/pkg/refs"
 	"gvisor.dev/gvisor/pkg/sync"
 	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
 	"gvisor.dev/gvisor/pkg/tcpip/link/qdisc/fifo"
 	"gvisor.dev/gvisor/pkg/tcpip/stack"
 )
@@ -114,6 +115,115 @@ func TestWriteMorePacketsThanBatchSize(t *testing.T) {
 	}
 }
 
+// blockingWriter implements LinkWriter. It blocks the first write until
+// released, and records the TOS of the IPv4 packets written.
+type blockingWriter struct {
+	started  chan struct{}
+	release  chan struct{}
+	once     sync.Once
+	mu       sync.Mutex
+	tos      []uint8
+	csumOK   bool
+	expected int
+	done     chan struct{}
+}
+
+func (bw *blockingWriter) WritePackets(pkts stack.PacketBufferList) (int, tcpip.Error) {
+	bw.once.Do(func() {
+		close(bw.started)
+		<-bw.release
+	})
+	bw.mu.Lock()
+	defer bw.mu.Unlock()
+	for _, pkt := range pkts.AsSlice() {
+		ip := header.IPv4(pkt.NetworkHeader().Slice())
+		tos, _ := ip.TOS()
+		bw.tos = append(bw.tos, tos)
+		bw.csumOK = bw.csumOK && ip.IsChecksumValid()
+	}
+	if len(bw.tos) == bw.expected {
+		close(bw.done)
+	}
+	return pkts.Len(), nil
+}
+
+func newIPv4Packet(tos uint8) *stack.PacketBuffer {
+	pkt := stack.NewPacketBuffer(stack.PacketBufferOptions{
+		ReserveHeaderBytes: header.IPv4MinimumSize,
+		Payload:            buffer.MakeWithData(make([]byte, 1)),
+	})
+	ip := header.IPv4(pkt.NetworkHeader().Push(header.IPv4MinimumSize))
+	ip.Encode(&header.IPv4Fields{
+		TOS:         tos,
+		TotalLength: header.IPv4MinimumSize + 1,
+		TTL:         64,
+		Protocol:    uint8(header.UDPProtocolNumber),
+		SrcAddr:     tcpip.AddrFrom4([4]byte{10, 0, 0, 1}),
+		DstAddr:     tcpip.AddrFrom4([4]byte{10, 0, 0, 2}),
+	})
+	ip.SetChecksum(^ip.CalculateChecksum())
+	pkt.NetworkProtocolNumber = header.IPv4ProtocolNumber
+	return pkt
+}
+
+func TestECNMarking(t *testing.T) {
+	const markThreshold = 2
+	toWrite := []uint8{
+		header.ECNECT0,
+		// The queue is empty while the first packet is being written.
+		header.ECNECT0,
+		header.ECNECT1,
+		// The following packets are queued above the threshold.
+		header.ECNECT0,
+		header.ECNNotECT,
+		header.ECNCE,
+	}
+	want := []uint8{
+		header.ECNECT0,
+		header.ECNECT0,
+		header.ECNECT1,
+		header.ECNCE,
+		header.ECNNotECT,
+		header.ECNCE,
+	}
+	lower := &blockingWriter{
+		started:  make(chan struct{}),
+		release:  make(chan struct{}),
+		csumOK:   true,
+		expected: len(toWrite),
+		done:     make(chan struct{}),
+	}
+	linkEp := fifo.NewWithECN(lower, 1, 1000, markThreshold)
+	defer linkEp.Close()
+	for i, tos := range toWrite {
+		pkt := newIPv4Packet(tos)
+		if err := linkEp.WritePacket(pkt); err != nil {
+			t.Fatalf("WritePacket(_): %s", err)
+		}
+		pkt.DecRef()
+		if i == 0 {
+			<-lower.started
+		}
+	}
+	close(lower.release)
+	select {
+	case <-lower.done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %d packets", len(toWrite))
+	}
+
+	lower.mu.Lock()
+	defer lower.mu.Unlock()
+	for i := range want {
+		if got := lower.tos[i] & header.ECNMask; got != want[i] {
+			t.Errorf("packet %d: got ECN codepoint %d, want %d", i, got, want[i])
+		}
+	}
+	if !lower.csumOK {
+		t.Errorf("got packets with invalid checksums")
+	}
+}
+
 func TestMain(m *testing.M) {
 	refs.SetLeakMode(refs.LeaksPanic)
 	code := m.Run()
@@ -46,6 +46,11 @@ func (*icmpv4DestinationUnreachableSockError) Info() uint32 {
 	return 0
 }
 
+// Data implements tcpip.SockErrorCause.
+func (*icmpv4DestinationUnreachableSockError) Data() uint32 {
+	return 0
+}
+
 var _ stack.TransportError = (*icmpv4DestinationHostUnreachableSockError)(nil)
 
 // icmpv4DestinationHostUnreachableSockError is an ICMPv4 Destination Host
@@ -539,6 +539,12 @@ func (e *endpoint) writePacket(r *stack.Route, pkt *stack.PacketBuffer) tcpip.Er
 		return nil
 	}
 
+	if nft := e.protocol.stack.NFTables(); nft != nil && !nft.CheckOutput(pkt, stack.IP) {
+		// nftables is telling us to drop the packet.
+		e.stats.ip.IPTablesOutputDropped.Increment()
+		return nil
+	}
+
 	// If the packet is manipulated as per DNAT Output rules, handle packet
 	// based on destination address and do not send the packet to link
 	// layer.
@@ -578,6 +584,12 @@ func (e *endpoint) writePacketPostRouting(r *stack.Route, pkt *stack.PacketBuffe
 		return nil
 	}
 
+	if nft := e.protocol.stack.NFTables(); nft != nil && !nft.CheckPostrouting(pkt, stack.IP) {
+		// nftables is telling us to drop the packet.
+		e.stats.ip.IPTablesPostroutingDropped.Increment()
+		return nil
+	}
+
 	stats := e.stats.ip
 
 	networkMTU, err := calculateNetworkMTU(e.nic.MTU(), uint32(len(pkt.NetworkHeader().Slice())))
@@ -688,6 +700,12 @@ func (e *endpoint) forwardPacketWithRoute(route *stack.Route, pkt *stack.PacketB
 		return nil
 	}
 
+	if nft := stk.NFTables(); nft != nil && !nft.CheckForward(pkt, stack.IP) {
+		// nftables is telling us to drop the packet.
+		e.stats.ip.IPTablesForwardDropped.Increment()
+		return nil
+	}
+
 	// We need to do a deep copy of the IP packet because
 	// WriteHeaderIncludedPacket may modify the packet buffer, but we do
 	// not own it.
@@ -788,6 +806,12 @@ func (e *endpoint) forwardUnicastPacket(pkt *stack.PacketBuffer) ip.ForwardingEr
 			return nil
 		}
 
+		if nft := stk.NFTables(); nft != nil && !nft.CheckForward(pkt, stack.IP) {
+			// nftables is telling us to drop the packet.
+			e.stats.ip.IPTablesForwardDropped.Increment()
+			return nil
+		}
+
 		// The packet originally arrived on e so provide its NIC as the input NIC.
 		ep.handleValidatedPacket(h, pkt, e.nic.Name() /* inNICName */)
 		return nil
@@ -875,6 +899,12 @@ func (e *endpoint) HandlePacket(pkt *stack.PacketBuffer) {
 			stats.IPTablesPreroutingDropped.Increment()
 			return
 		}
+
+		if nft := e.protocol.stack.NFTables(); nft != nil && !nft.CheckPrerouting(pkt, stack.IP) {
+			// nftables is telling us to drop the packet.
+			stats.IPTablesPreroutingDropped.Increment()
+			return
+		}
 	}
 	// CheckPrerouting can modify the backing storage of the packet, so refresh
 	// the header.
@@ -1216,6 +1246,12 @@ func (e *endpoint) deliverPacketLocally(h header.IPv4, pkt *stack.PacketBuffer,
 		return
 	}
 
+	if nft := e.protocol.stack.NFTables(); nft != nil && !nft.CheckInput(pkt, stack.IP) {
+		// nftables is telling us to drop the packet.
+		stats.ip.IPTablesInputDropped.Increment()
+		return
+	}
+
 	if h.More() || h.FragmentOffset() != 0 {
 		if pkt.Data().Size()+len(pkt.TransportHeader().Slice()) == 0 {
 			// Drop the packet as it's marked as a fragment but has
@@ -44,6 +44,11 @@ func (*icmpv6DestinationUnreachableSockError) Info() uint32 {
 	return 0
 }
 
+// Data implements tcpip.SockErrorCause.
+func (*icmpv6DestinationUnreachableSockError) Data() uint32 {
+	return 0
+}
+
 var _ stack.TransportError = (*icmpv6DestinationNetworkUnreachableSockError)(nil)
 
 // icmpv6DestinationNetworkUnreachableSockError is an ICMPv6 Destination Network
@@ -143,6 +148,11 @@ func (e *icmpv6PacketTooBigSockError) Info() uint32 {
 	return e.mtu
 }
 
+// Data implements tcpip.SockErrorCause.
+func (*icmpv6PacketTooBigSockError) Data() uint32 {
+	return 0
+}
+
 // Kind implements stack.TransportError.
 func (*icmpv6PacketTooBigSockError) Kind() stack.TransportErrorKind {
 	return stack.PacketTooBigTransportError
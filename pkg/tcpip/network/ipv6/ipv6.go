@@ -826,6 +826,12 @@ func (e *endpoint) WritePacket(r *stack.Route, params stack.NetworkHeaderParams,
 		return nil
 	}
 
+	if nft := e.protocol.stack.NFTables(); nft != nil && !nft.CheckOutput(pkt, stack.IP6) {
+		// nftables is telling us to drop the packet.
+		e.stats.ip.IPTablesOutputDropped.Increment()
+		return nil
+	}
+
 	// If the packet is manipulated as per DNAT Output rules, handle packet
 	// based on destination address and do not send the packet to link
 	// layer.
@@ -865,6 +871,12 @@ func (e *endpoint) writePacket(r *stack.Route, pkt *stack.PacketBuffer, protocol
 		return nil
 	}
 
+	if nft := e.protocol.stack.NFTables(); nft != nil && !nft.CheckPostrouting(pkt, stack.IP6) {
+		// nftables is telling us to drop the packet.
+		e.stats.ip.IPTablesPostroutingDropped.Increment()
+		return nil
+	}
+
 	stats := e.stats.ip
 	networkMTU, err := calculateNetworkMTU(e.nic.MTU(), uint32(len(pkt.NetworkHeader().Slice())))
 	if err != nil {
@@ -1005,6 +1017,12 @@ func (e *endpoint) forwardUnicastPacket(pkt *stack.PacketBuffer) ip.ForwardingEr
 			return nil
 		}
 
+		if nft := stk.NFTables(); nft != nil && !nft.CheckForward(pkt, stack.IP6) {
+			// nftables is telling us to drop the packet.
+			e.stats.ip.IPTablesForwardDropped.Increment()
+			return nil
+		}
+
 		// The packet originally arrived on e so provide its NIC as the input NIC.
 		ep.handleValidatedPacket(h, pkt, e.nic.Name() /* inNICName */)
 		return nil
@@ -1048,6 +1066,12 @@ func (e *endpoint) forwardPacketWithRoute(route *stack.Route, pkt *stack.PacketB
 		return nil
 	}
 
+	if nft := stk.NFTables(); nft != nil && !nft.CheckForward(pkt, stack.IP6) {
+		// nftables is telling us to drop the packet.
+		e.stats.ip.IPTablesForwardDropped.Increment()
+		return nil
+	}
+
 	hopLimit := h.HopLimit()
 
 	// We need to do a deep copy of the IP packet because
@@ -1141,6 +1165,12 @@ func (e *endpoint) HandlePacket(pkt *stack.PacketBuffer) {
 			stats.IPTablesPreroutingDropped.Increment()
 			return
 		}
+
+		if nft := e.protocol.stack.NFTables(); nft != nil && !nft.CheckPrerouting(pkt, stack.IP6) {
+			// nftables is telling us to drop the packet.
+			stats.IPTablesPreroutingDropped.Increment()
+			return
+		}
 	}
 
 	// CheckPrerouting can modify the backing storage of the packet, so refresh
@@ -1395,6 +1425,12 @@ func (e *endpoint) deliverPacketLocally(h header.IPv6, pkt *stack.PacketBuffer,
 		return
 	}
 
+	if nft := e.protocol.stack.NFTables(); nft != nil && !nft.CheckInput(pkt, stack.IP6) {
+		// nftables is telling us to drop the packet.
+		stats.IPTablesInputDropped.Increment()
+		return
+	}
+
 	// Any returned error is only useful for terminating execution early, but
 	// we have nothing left to do, so we can drop it.
 	_ = e.processExtensionHeaders(h, pkt, false /* forwarding */)
@@ -19,6 +19,7 @@ import (
 	"fmt"
 
 	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/sentry/socket/netlink/nlmsg"
 	"gvisor.dev/gvisor/pkg/syserr"
 	"gvisor.dev/gvisor/pkg/tcpip/stack"
 )
@@ -190,3 +191,84 @@ func (op bitwise) evaluate(regs *registerSet, pkt *stack.PacketBuffer, rule *Rul
 	}
 
 }
+
+// exprName for Bitwise returns the netlink expression name.
+func (op bitwise) exprName() string {
+	return "bitwise"
+}
+
+// dump for Bitwise returns the netlink attributes of the operation.
+func (op bitwise) dump(rule *Rule) nlmsg.NestedAttr {
+	var attrs nlmsg.NestedAttr
+	attrs.PutAttrUint32BE(linux.NFTA_BITWISE_SREG, uint32(op.sreg))
+	attrs.PutAttrUint32BE(linux.NFTA_BITWISE_DREG, uint32(op.dreg))
+	attrs.PutAttrUint32BE(linux.NFTA_BITWISE_LEN, uint32(op.blen))
+	attrs.PutAttrUint32BE(linux.NFTA_BITWISE_OP, uint32(op.bop))
+	if op.bop == linux.NFT_BITWISE_BOOL {
+		attrs.PutNestedAttr(linux.NFTA_BITWISE_MASK, dumpDataValue(op.mask.data))
+		attrs.PutNestedAttr(linux.NFTA_BITWISE_XOR, dumpDataValue(op.xor.data))
+	} else {
+		attrs.PutNestedAttr(linux.NFTA_BITWISE_DATA, dumpDataValue(binary.NativeEndian.AppendUint32(nil, op.shift)))
+	}
+	return attrs
+}
+
+// parseBitwise parses the netlink attributes of a bitwise expression.
+func parseBitwise(tab *Table, attrs map[uint16]nlmsg.BytesView) (operation, *syserr.AnnotatedError) {
+	// From net/netfilter/nft_bitwise.c:nft_bitwise_init.
+	if err := requireAttrs("bitwise", attrs, linux.NFTA_BITWISE_SREG, linux.NFTA_BITWISE_DREG, linux.NFTA_BITWISE_LEN); err != nil {
+		return nil, err
+	}
+	sreg, err := parseNetlinkRegister(attrs[linux.NFTA_BITWISE_SREG])
+	if err != nil {
+		return nil, err
+	}
+	dreg, err := parseNetlinkRegister(attrs[linux.NFTA_BITWISE_DREG])
+	if err != nil {
+		return nil, err
+	}
+	blen, err := parseNetlinkUint8(attrs[linux.NFTA_BITWISE_LEN])
+	if err != nil {
+		return nil, err
+	}
+	bop := uint32(linux.NFT_BITWISE_BOOL)
+	if attr, ok := attrs[linux.NFTA_BITWISE_OP]; ok {
+		if bop, ok = attr.Uint32BE(); !ok {
+			return nil, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("malformed bitwise operator"))
+		}
+	}
+
+	switch bop {
+	case linux.NFT_BITWISE_BOOL:
+		if err := requireAttrs("bitwise", attrs, linux.NFTA_BITWISE_MASK, linux.NFTA_BITWISE_XOR); err != nil {
+			return nil, err
+		}
+		mask, err := parseDataValue(attrs[linux.NFTA_BITWISE_MASK], linux.NFT_REG_SIZE)
+		if err != nil {
+			return nil, err
+		}
+		xor, err := parseDataValue(attrs[linux.NFTA_BITWISE_XOR], linux.NFT_REG_SIZE)
+		if err != nil {
+			return nil, err
+		}
+		if len(mask) != int(blen) {
+			return nil, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("bitwise mask length %d does not match length %d", len(mask), blen))
+		}
+		return newBitwiseBool(sreg, dreg, mask, xor)
+	case linux.NFT_BITWISE_LSHIFT, linux.NFT_BITWISE_RSHIFT:
+		if err := requireAttrs("bitwise", attrs, linux.NFTA_BITWISE_DATA); err != nil {
+			return nil, err
+		}
+		data, err := parseDataValue(attrs[linux.NFTA_BITWISE_DATA], linux.NFT_REG32_SIZE)
+		if err != nil {
+			return nil, err
+		}
+		if len(data) != linux.NFT_REG32_SIZE {
+			return nil, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("bitwise shift must be %d bytes", linux.NFT_REG32_SIZE))
+		}
+		// The shift is in host byte order.
+		return newBitwiseShift(sreg, dreg, blen, binary.NativeEndian.Uint32(data), bop == linux.NFT_BITWISE_RSHIFT)
+	default:
+		return nil, syserr.NewAnnotatedError(syserr.ErrNotSupported, fmt.Sprintf("bitwise operator %d is not supported", bop))
+	}
+}
@@ -19,6 +19,7 @@ import (
 	"fmt"
 
 	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/sentry/socket/netlink/nlmsg"
 	"gvisor.dev/gvisor/pkg/syserr"
 	"gvisor.dev/gvisor/pkg/tcpip/stack"
 )
@@ -147,3 +148,48 @@ func (op byteorder) evaluate(regs *registerSet, pkt *stack.PacketBuffer, rule *R
 		clear(dst[op.blen : op.blen+4-rem])
 	}
 }
+
+// exprName for Byteorder returns the netlink expression name.
+func (op byteorder) exprName() string {
+	return "byteorder"
+}
+
+// dump for Byteorder returns the netlink attributes of the operation.
+func (op byteorder) dump(rule *Rule) nlmsg.NestedAttr {
+	var attrs nlmsg.NestedAttr
+	attrs.PutAttrUint32BE(linux.NFTA_BYTEORDER_SREG, uint32(op.sreg))
+	attrs.PutAttrUint32BE(linux.NFTA_BYTEORDER_DREG, uint32(op.dreg))
+	attrs.PutAttrUint32BE(linux.NFTA_BYTEORDER_OP, uint32(op.bop))
+	attrs.PutAttrUint32BE(linux.NFTA_BYTEORDER_LEN, uint32(op.blen))
+	attrs.PutAttrUint32BE(linux.NFTA_BYTEORDER_SIZE, uint32(op.size))
+	return attrs
+}
+
+// parseByteorder parses the netlink attributes of a byteorder expression.
+func parseByteorder(tab *Table, attrs map[uint16]nlmsg.BytesView) (operation, *syserr.AnnotatedError) {
+	if err := requireAttrs("byteorder", attrs, linux.NFTA_BYTEORDER_SREG, linux.NFTA_BYTEORDER_DREG, linux.NFTA_BYTEORDER_OP, linux.NFTA_BYTEORDER_LEN, linux.NFTA_BYTEORDER_SIZE); err != nil {
+		return nil, err
+	}
+	sreg, err := parseNetlinkRegister(attrs[linux.NFTA_BYTEORDER_SREG])
+	if err != nil {
+		return nil, err
+	}
+	dreg, err := parseNetlinkRegister(attrs[linux.NFTA_BYTEORDER_DREG])
+	if err != nil {
+		return nil, err
+	}
+	opAttr := attrs[linux.NFTA_BYTEORDER_OP]
+	bop, ok := opAttr.Uint32BE()
+	if !ok {
+		return nil, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("malformed byteorder operator"))
+	}
+	blen, err := parseNetlinkUint8(attrs[linux.NFTA_BYTEORDER_LEN])
+	if err != nil {
+		return nil, err
+	}
+	size, err := parseNetlinkUint8(attrs[linux.NFTA_BYTEORDER_SIZE])
+	if err != nil {
+		return nil, err
+	}
+	return newByteorder(sreg, dreg, byteorderOp(bop), blen, size)
+}
@@ -19,6 +19,7 @@ import (
 	"fmt"
 
 	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/sentry/socket/netlink/nlmsg"
 	"gvisor.dev/gvisor/pkg/syserr"
 	"gvisor.dev/gvisor/pkg/tcpip/stack"
 )
@@ -115,3 +116,38 @@ func (op comparison) evaluate(regs *registerSet, pkt *stack.PacketBuffer, rule *
 		regs.verdict = stack.NFVerdict{Code: VC(linux.NFT_BREAK)}
 	}
 }
+
+// exprName for Comparison returns the netlink expression name.
+func (op comparison) exprName() string {
+	return "cmp"
+}
+
+// dump for Comparison returns the netlink attributes of the operation.
+func (op comparison) dump(rule *Rule) nlmsg.NestedAttr {
+	var attrs nlmsg.NestedAttr
+	attrs.PutAttrUint32BE(linux.NFTA_CMP_SREG, uint32(op.sreg))
+	attrs.PutAttrUint32BE(linux.NFTA_CMP_OP, uint32(op.cop))
+	attrs.PutNestedAttr(linux.NFTA_CMP_DATA, dumpDataValue(op.data.data))
+	return attrs
+}
+
+// parseComparison parses the netlink attributes of a cmp expression.
+func parseComparison(tab *Table, attrs map[uint16]nlmsg.BytesView) (operation, *syserr.AnnotatedError) {
+	if err := requireAttrs("cmp", attrs, linux.NFTA_CMP_SREG, linux.NFTA_CMP_OP, linux.NFTA_CMP_DATA); err != nil {
+		return nil, err
+	}
+	sreg, err := parseNetlinkRegister(attrs[linux.NFTA_CMP_SREG])
+	if err != nil {
+		return nil, err
+	}
+	opAttr := attrs[linux.NFTA_CMP_OP]
+	cop, ok := opAttr.Uint32BE()
+	if !ok {
+		return nil, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("malformed comparison operator"))
+	}
+	data, err := parseDataValue(attrs[linux.NFTA_CMP_DATA], linux.NFT_REG_SIZE)
+	if err != nil {
+		return nil, err
+	}
+	return newComparison(sreg, int(cop), data)
+}
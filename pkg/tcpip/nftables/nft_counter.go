@@ -17,6 +17,9 @@ package nftables
 import (
 	"sync/atomic"
 
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/sentry/socket/netlink/nlmsg"
+	"gvisor.dev/gvisor/pkg/syserr"
 	"gvisor.dev/gvisor/pkg/tcpip/stack"
 )
 
@@ -43,3 +46,29 @@ func (op *counter) evaluate(regs *registerSet, pkt *stack.PacketBuffer, rule *Ru
 	op.bytes.Add(int64(pkt.Size()))
 	op.packets.Add(1)
 }
+
+// exprName for counter returns the netlink expression name.
+func (op *counter) exprName() string {
+	return "counter"
+}
+
+// dump for counter returns the netlink attributes of the operation.
+func (op *counter) dump(rule *Rule) nlmsg.NestedAttr {
+	var attrs nlmsg.NestedAttr
+	attrs.PutAttrUint64BE(linux.NFTA_COUNTER_BYTES, uint64(op.bytes.Load()))
+	attrs.PutAttrUint64BE(linux.NFTA_COUNTER_PACKETS, uint64(op.packets.Load()))
+	return attrs
+}
+
+// parseCounter parses the netlink attributes of a counter expression. The
+// initial values of the counter are optional.
+func parseCounter(tab *Table, attrs map[uint16]nlmsg.BytesView) (operation, *syserr.AnnotatedError) {
+	var bytes, packets uint64
+	if attr, ok := attrs[linux.NFTA_COUNTER_BYTES]; ok {
+		bytes, _ = attr.Uint64BE()
+	}
+	if attr, ok := attrs[linux.NFTA_COUNTER_PACKETS]; ok {
+		packets, _ = attr.Uint64BE()
+	}
+	return newCounter(int64(bytes), int64(packets)), nil
+}
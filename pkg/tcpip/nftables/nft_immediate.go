@@ -15,6 +15,8 @@
 package nftables
 
 import (
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/sentry/socket/netlink/nlmsg"
 	"gvisor.dev/gvisor/pkg/syserr"
 	"gvisor.dev/gvisor/pkg/tcpip/stack"
 )
@@ -37,3 +39,32 @@ func newImmediate(dreg uint8, data registerData) (*immediate, *syserr.AnnotatedE
 func (op immediate) evaluate(regs *registerSet, pkt *stack.PacketBuffer, rule *Rule) {
 	op.data.storeData(regs, op.dreg)
 }
+
+// exprName for Immediate returns the netlink expression name.
+func (op immediate) exprName() string {
+	return "immediate"
+}
+
+// dump for Immediate returns the netlink attributes of the operation.
+func (op immediate) dump(rule *Rule) nlmsg.NestedAttr {
+	var attrs nlmsg.NestedAttr
+	attrs.PutAttrUint32BE(linux.NFTA_IMMEDIATE_DREG, uint32(op.dreg))
+	attrs.PutNestedAttr(linux.NFTA_IMMEDIATE_DATA, dumpRegisterData(op.data))
+	return attrs
+}
+
+// parseImmediate parses the netlink attributes of an immediate expression.
+func parseImmediate(tab *Table, attrs map[uint16]nlmsg.BytesView) (operation, *syserr.AnnotatedError) {
+	if err := requireAttrs("immediate", attrs, linux.NFTA_IMMEDIATE_DREG, linux.NFTA_IMMEDIATE_DATA); err != nil {
+		return nil, err
+	}
+	dreg, err := parseNetlinkRegister(attrs[linux.NFTA_IMMEDIATE_DREG])
+	if err != nil {
+		return nil, err
+	}
+	data, err := parseNetlinkRegisterData(tab, attrs[linux.NFTA_IMMEDIATE_DATA])
+	if err != nil {
+		return nil, err
+	}
+	return newImmediate(dreg, data)
+}
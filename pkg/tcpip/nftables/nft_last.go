@@ -17,6 +17,9 @@ package nftables
 import (
 	"sync/atomic"
 
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/sentry/socket/netlink/nlmsg"
+	"gvisor.dev/gvisor/pkg/syserr"
 	"gvisor.dev/gvisor/pkg/tcpip/stack"
 )
 
@@ -45,3 +48,39 @@ func (op *last) evaluate(regs *registerSet, pkt *stack.PacketBuffer, rule *Rule)
 	op.timestampMS.Store(clock.Now().UnixMilli())
 	op.set.CompareAndSwap(false, true)
 }
+
+// exprName for last returns the netlink expression name.
+func (op *last) exprName() string {
+	return "last"
+}
+
+// dump for last returns the netlink attributes of the operation, reporting the
+// time elapsed since the last evaluation like in Linux.
+func (op *last) dump(rule *Rule) nlmsg.NestedAttr {
+	var attrs nlmsg.NestedAttr
+	if op.set.Load() {
+		clock := rule.chain.table.afFilter.nftState.clock
+		attrs.PutAttrUint32BE(linux.NFTA_LAST_SET, 1)
+		attrs.PutAttrUint64BE(linux.NFTA_LAST_MSECS, uint64(max(clock.Now().UnixMilli()-op.timestampMS.Load(), 0)))
+	} else {
+		attrs.PutAttrUint32BE(linux.NFTA_LAST_SET, 0)
+		attrs.PutAttrUint64BE(linux.NFTA_LAST_MSECS, 0)
+	}
+	return attrs
+}
+
+// parseLast parses the netlink attributes of a last expression, where the
+// time is given as the time elapsed since the last evaluation.
+func parseLast(tab *Table, attrs map[uint16]nlmsg.BytesView) (operation, *syserr.AnnotatedError) {
+	op := &last{}
+	if attr, ok := attrs[linux.NFTA_LAST_SET]; ok {
+		if set, _ := attr.Uint32BE(); set != 0 {
+			msecsAttr := attrs[linux.NFTA_LAST_MSECS]
+			msecs, _ := msecsAttr.Uint64BE()
+			clock := tab.afFilter.nftState.clock
+			op.timestampMS.Store(clock.Now().UnixMilli() - int64(msecs))
+			op.set.Store(true)
+		}
+	}
+	return op, nil
+}
@@ -19,6 +19,7 @@ import (
 	"fmt"
 
 	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/sentry/socket/netlink/nlmsg"
 	"gvisor.dev/gvisor/pkg/syserr"
 	"gvisor.dev/gvisor/pkg/tcpip/header"
 	"gvisor.dev/gvisor/pkg/tcpip/stack"
@@ -241,3 +242,49 @@ func (op metaLoad) evaluate(regs *registerSet, pkt *stack.PacketBuffer, rule *Ru
 	// Copies target data into the destination register.
 	copy(dst, target)
 }
+
+// exprName for MetaLoad returns the netlink expression name.
+func (op metaLoad) exprName() string {
+	return "meta"
+}
+
+// dump for MetaLoad returns the netlink attributes of the operation.
+func (op metaLoad) dump(rule *Rule) nlmsg.NestedAttr {
+	var attrs nlmsg.NestedAttr
+	attrs.PutAttrUint32BE(linux.NFTA_META_KEY, uint32(op.key))
+	attrs.PutAttrUint32BE(linux.NFTA_META_DREG, uint32(op.dreg))
+	return attrs
+}
+
+// parseMeta parses the netlink attributes of a meta expression, which is a
+// meta set if it has a source register and a meta load otherwise.
+func parseMeta(tab *Table, attrs map[uint16]nlmsg.BytesView) (operation, *syserr.AnnotatedError) {
+	if err := requireAttrs("meta", attrs, linux.NFTA_META_KEY); err != nil {
+		return nil, err
+	}
+	keyAttr := attrs[linux.NFTA_META_KEY]
+	k, ok := keyAttr.Uint32BE()
+	if !ok {
+		return nil, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("malformed meta key"))
+	}
+	key := metaKey(k)
+	if _, ok := metaKeyStrings[key]; !ok {
+		return nil, syserr.NewAnnotatedError(syserr.ErrNotSupported, fmt.Sprintf("unknown meta key: %d", k))
+	}
+
+	if sregAttr, ok := attrs[linux.NFTA_META_SREG]; ok {
+		sreg, err := parseNetlinkRegister(sregAttr)
+		if err != nil {
+			return nil, err
+		}
+		return newMetaSet(key, sreg)
+	}
+	if err := requireAttrs("meta", attrs, linux.NFTA_META_DREG); err != nil {
+		return nil, err
+	}
+	dreg, err := parseNetlinkRegister(attrs[linux.NFTA_META_DREG])
+	if err != nil {
+		return nil, err
+	}
+	return newMetaLoad(key, dreg)
+}
@@ -18,6 +18,7 @@ import (
 	"fmt"
 
 	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/sentry/socket/netlink/nlmsg"
 	"gvisor.dev/gvisor/pkg/syserr"
 	"gvisor.dev/gvisor/pkg/tcpip"
 	"gvisor.dev/gvisor/pkg/tcpip/stack"
@@ -85,3 +86,16 @@ func (op metaSet) evaluate(regs *registerSet, pkt *stack.PacketBuffer, rule *Rul
 	regs.verdict = stack.NFVerdict{Code: VC(linux.NFT_BREAK)}
 	return
 }
+
+// exprName for MetaSet returns the netlink expression name.
+func (op metaSet) exprName() string {
+	return "meta"
+}
+
+// dump for MetaSet returns the netlink attributes of the operation.
+func (op metaSet) dump(rule *Rule) nlmsg.NestedAttr {
+	var attrs nlmsg.NestedAttr
+	attrs.PutAttrUint32BE(linux.NFTA_META_KEY, uint32(op.key))
+	attrs.PutAttrUint32BE(linux.NFTA_META_SREG, uint32(op.sreg))
+	return attrs
+}
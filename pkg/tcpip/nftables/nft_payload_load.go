@@ -18,6 +18,7 @@ import (
 	"fmt"
 
 	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/sentry/socket/netlink/nlmsg"
 	"gvisor.dev/gvisor/pkg/syserr"
 	"gvisor.dev/gvisor/pkg/tcpip/header"
 	"gvisor.dev/gvisor/pkg/tcpip/stack"
@@ -123,3 +124,75 @@ func (op payloadLoad) evaluate(regs *registerSet, pkt *stack.PacketBuffer, rule
 	data := newBytesData(payload[op.offset : op.offset+op.blen])
 	data.storeData(regs, op.dreg)
 }
+
+// exprName for PayloadLoad returns the netlink expression name.
+func (op payloadLoad) exprName() string {
+	return "payload"
+}
+
+// dump for PayloadLoad returns the netlink attributes of the operation.
+func (op payloadLoad) dump(rule *Rule) nlmsg.NestedAttr {
+	var attrs nlmsg.NestedAttr
+	attrs.PutAttrUint32BE(linux.NFTA_PAYLOAD_DREG, uint32(op.dreg))
+	attrs.PutAttrUint32BE(linux.NFTA_PAYLOAD_BASE, uint32(op.base))
+	attrs.PutAttrUint32BE(linux.NFTA_PAYLOAD_OFFSET, uint32(op.offset))
+	attrs.PutAttrUint32BE(linux.NFTA_PAYLOAD_LEN, uint32(op.blen))
+	return attrs
+}
+
+// parsePayload parses the netlink attributes of a payload expression, which
+// is a payload set if it has a source register and a payload load otherwise.
+func parsePayload(tab *Table, attrs map[uint16]nlmsg.BytesView) (operation, *syserr.AnnotatedError) {
+	if err := requireAttrs("payload", attrs, linux.NFTA_PAYLOAD_BASE, linux.NFTA_PAYLOAD_OFFSET, linux.NFTA_PAYLOAD_LEN); err != nil {
+		return nil, err
+	}
+	baseAttr := attrs[linux.NFTA_PAYLOAD_BASE]
+	base, ok := baseAttr.Uint32BE()
+	if !ok {
+		return nil, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("malformed payload base"))
+	}
+	offset, err := parseNetlinkUint8(attrs[linux.NFTA_PAYLOAD_OFFSET])
+	if err != nil {
+		return nil, err
+	}
+	blen, err := parseNetlinkUint8(attrs[linux.NFTA_PAYLOAD_LEN])
+	if err != nil {
+		return nil, err
+	}
+	if int(offset)+int(blen) > 0xff {
+		return nil, syserr.NewAnnotatedError(syserr.ErrRange, fmt.Sprintf("payload offset %d and length %d are out of range", offset, blen))
+	}
+
+	if _, ok := attrs[linux.NFTA_PAYLOAD_SREG]; !ok {
+		if err := requireAttrs("payload", attrs, linux.NFTA_PAYLOAD_DREG); err != nil {
+			return nil, err
+		}
+		dreg, err := parseNetlinkRegister(attrs[linux.NFTA_PAYLOAD_DREG])
+		if err != nil {
+			return nil, err
+		}
+		return newPayloadLoad(payloadBase(base), offset, blen, dreg)
+	}
+
+	sreg, err := parseNetlinkRegister(attrs[linux.NFTA_PAYLOAD_SREG])
+	if err != nil {
+		return nil, err
+	}
+	var csumType, csumOffset, csumFlags uint8
+	if attr, ok := attrs[linux.NFTA_PAYLOAD_CSUM_TYPE]; ok {
+		if csumType, err = parseNetlinkUint8(attr); err != nil {
+			return nil, err
+		}
+	}
+	if attr, ok := attrs[linux.NFTA_PAYLOAD_CSUM_OFFSET]; ok {
+		if csumOffset, err = parseNetlinkUint8(attr); err != nil {
+			return nil, err
+		}
+	}
+	if attr, ok := attrs[linux.NFTA_PAYLOAD_CSUM_FLAGS]; ok {
+		if csumFlags, err = parseNetlinkUint8(attr); err != nil {
+			return nil, err
+		}
+	}
+	return newPayloadSet(payloadBase(base), offset, blen, sreg, csumType, csumOffset, csumFlags)
+}
@@ -20,6 +20,7 @@ import (
 	"slices"
 
 	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/sentry/socket/netlink/nlmsg"
 	"gvisor.dev/gvisor/pkg/syserr"
 	"gvisor.dev/gvisor/pkg/tcpip/checksum"
 	"gvisor.dev/gvisor/pkg/tcpip/header"
@@ -182,3 +183,21 @@ func (op payloadSet) evaluate(regs *registerSet, pkt *stack.PacketBuffer, rule *
 		}
 	}
 }
+
+// exprName for PayloadSet returns the netlink expression name.
+func (op payloadSet) exprName() string {
+	return "payload"
+}
+
+// dump for PayloadSet returns the netlink attributes of the operation.
+func (op payloadSet) dump(rule *Rule) nlmsg.NestedAttr {
+	var attrs nlmsg.NestedAttr
+	attrs.PutAttrUint32BE(linux.NFTA_PAYLOAD_SREG, uint32(op.sreg))
+	attrs.PutAttrUint32BE(linux.NFTA_PAYLOAD_BASE, uint32(op.base))
+	attrs.PutAttrUint32BE(linux.NFTA_PAYLOAD_OFFSET, uint32(op.offset))
+	attrs.PutAttrUint32BE(linux.NFTA_PAYLOAD_LEN, uint32(op.blen))
+	attrs.PutAttrUint32BE(linux.NFTA_PAYLOAD_CSUM_TYPE, uint32(op.csumType))
+	attrs.PutAttrUint32BE(linux.NFTA_PAYLOAD_CSUM_OFFSET, uint32(op.csumOffset))
+	attrs.PutAttrUint32BE(linux.NFTA_PAYLOAD_CSUM_FLAGS, uint32(op.csumFlags))
+	return attrs
+}
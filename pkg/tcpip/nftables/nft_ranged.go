@@ -19,6 +19,7 @@ import (
 	"fmt"
 
 	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/sentry/socket/netlink/nlmsg"
 	"gvisor.dev/gvisor/pkg/syserr"
 	"gvisor.dev/gvisor/pkg/tcpip/stack"
 )
@@ -111,3 +112,43 @@ func (op ranged) evaluate(regs *registerSet, pkt *stack.PacketBuffer, rule *Rule
 		regs.verdict = stack.NFVerdict{Code: VC(linux.NFT_BREAK)}
 	}
 }
+
+// exprName for Ranged returns the netlink expression name.
+func (op ranged) exprName() string {
+	return "range"
+}
+
+// dump for Ranged returns the netlink attributes of the operation.
+func (op ranged) dump(rule *Rule) nlmsg.NestedAttr {
+	var attrs nlmsg.NestedAttr
+	attrs.PutAttrUint32BE(linux.NFTA_RANGE_SREG, uint32(op.sreg))
+	attrs.PutAttrUint32BE(linux.NFTA_RANGE_OP, uint32(op.rop))
+	attrs.PutNestedAttr(linux.NFTA_RANGE_FROM_DATA, dumpDataValue(op.low.data))
+	attrs.PutNestedAttr(linux.NFTA_RANGE_TO_DATA, dumpDataValue(op.high.data))
+	return attrs
+}
+
+// parseRanged parses the netlink attributes of a range expression.
+func parseRanged(tab *Table, attrs map[uint16]nlmsg.BytesView) (operation, *syserr.AnnotatedError) {
+	if err := requireAttrs("range", attrs, linux.NFTA_RANGE_SREG, linux.NFTA_RANGE_OP, linux.NFTA_RANGE_FROM_DATA, linux.NFTA_RANGE_TO_DATA); err != nil {
+		return nil, err
+	}
+	sreg, err := parseNetlinkRegister(attrs[linux.NFTA_RANGE_SREG])
+	if err != nil {
+		return nil, err
+	}
+	opAttr := attrs[linux.NFTA_RANGE_OP]
+	rop, ok := opAttr.Uint32BE()
+	if !ok {
+		return nil, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("malformed range operator"))
+	}
+	low, err := parseDataValue(attrs[linux.NFTA_RANGE_FROM_DATA], linux.NFT_REG_SIZE)
+	if err != nil {
+		return nil, err
+	}
+	high, err := parseDataValue(attrs[linux.NFTA_RANGE_TO_DATA], linux.NFT_REG_SIZE)
+	if err != nil {
+		return nil, err
+	}
+	return newRanged(sreg, int(rop), low, high)
+}
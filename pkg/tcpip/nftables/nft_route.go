@@ -19,6 +19,7 @@ import (
 	"fmt"
 
 	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/sentry/socket/netlink/nlmsg"
 	"gvisor.dev/gvisor/pkg/syserr"
 	"gvisor.dev/gvisor/pkg/tcpip/header"
 	"gvisor.dev/gvisor/pkg/tcpip/stack"
@@ -134,3 +135,33 @@ func (op route) evaluate(regs *registerSet, pkt *stack.PacketBuffer, rule *Rule)
 	data := newBytesData(target)
 	data.storeData(regs, op.dreg)
 }
+
+// exprName for Route returns the netlink expression name.
+func (op route) exprName() string {
+	return "rt"
+}
+
+// dump for Route returns the netlink attributes of the operation.
+func (op route) dump(rule *Rule) nlmsg.NestedAttr {
+	var attrs nlmsg.NestedAttr
+	attrs.PutAttrUint32BE(linux.NFTA_RT_DREG, uint32(op.dreg))
+	attrs.PutAttrUint32BE(linux.NFTA_RT_KEY, uint32(op.key))
+	return attrs
+}
+
+// parseRoute parses the netlink attributes of an rt expression.
+func parseRoute(tab *Table, attrs map[uint16]nlmsg.BytesView) (operation, *syserr.AnnotatedError) {
+	if err := requireAttrs("rt", attrs, linux.NFTA_RT_DREG, linux.NFTA_RT_KEY); err != nil {
+		return nil, err
+	}
+	dreg, err := parseNetlinkRegister(attrs[linux.NFTA_RT_DREG])
+	if err != nil {
+		return nil, err
+	}
+	keyAttr := attrs[linux.NFTA_RT_KEY]
+	key, ok := keyAttr.Uint32BE()
+	if !ok {
+		return nil, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("malformed route key"))
+	}
+	return newRoute(routeKey(key), dreg)
+}
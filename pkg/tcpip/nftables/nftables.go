@@ -15,6 +15,7 @@
 package nftables
 
 import (
+	"cmp"
 	"fmt"
 	"slices"
 
@@ -73,12 +74,28 @@ func (nf *NFTables) checkHook(pkt *stack.PacketBuffer, af stack.AddressFamily, h
 	if !IsNFTablesEnabled() {
 		return true
 	}
-	v, err := nf.EvaluateHook(af, hook, pkt)
 
-	if err != nil {
+	nf.mu.RLock()
+	defer nf.mu.RUnlock()
+	if !nf.acceptsAtHook(pkt, af, hook) {
 		return false
 	}
 
+	// Base chains of inet tables see both IPv4 and IPv6 packets.
+	if af == stack.IP || af == stack.IP6 {
+		return nf.acceptsAtHook(pkt, stack.Inet, hook)
+	}
+	return true
+}
+
+// acceptsAtHook returns true if the base chains of the given address family
+// and hook accept the packet.
+// Precondition: nf.mu must be locked.
+func (nf *NFTables) acceptsAtHook(pkt *stack.PacketBuffer, af stack.AddressFamily, hook stack.NFHook) bool {
+	v, err := nf.EvaluateHook(af, hook, pkt)
+	if err != nil {
+		return false
+	}
 	return v.Code == VC(linux.NF_ACCEPT)
 }
 
@@ -244,6 +261,29 @@ func NewNFTables(clock tcpip.Clock, rng rand.RNG) *NFTables {
 	return &NFTables{clock: clock, startTime: clock.Now(), rng: rng}
 }
 
+// GenID returns the generation ID of the committed ruleset.
+func (nf *NFTables) GenID() uint32 {
+	nf.mu.RLock()
+	defer nf.mu.RUnlock()
+	return nf.genID
+}
+
+// Snapshot returns a read-only view of the committed ruleset. Since committed
+// rulesets are never modified in place, the view remains consistent even if
+// transactions are committed while it is in use.
+func (nf *NFTables) Snapshot() *NFTables {
+	nf.mu.RLock()
+	defer nf.mu.RUnlock()
+	return &NFTables{
+		filters:     nf.filters,
+		clock:       nf.clock,
+		startTime:   nf.startTime,
+		rng:         nf.rng,
+		tableHandle: nf.tableHandle,
+		genID:       nf.genID,
+	}
+}
+
 // Flush clears entire ruleset and all data for all address families.
 func (nf *NFTables) Flush() {
 	for family := range stack.NumAFs {
@@ -324,11 +364,14 @@ func (nf *NFTables) AddTable(family stack.AddressFamily, name string,
 	}
 
 	// Creates the new table and add it to the table map.
+	nf.tableHandle++
 	t := &Table{
 		name:     name,
 		afFilter: nf.filters[family],
 		chains:   make(map[string]*Chain),
 		flagSet:  make(map[TableFlag]struct{}),
+		sets:     make(map[string]*Set),
+		handle:   nf.tableHandle,
 	}
 	tableMap[name] = t
 
@@ -358,10 +401,11 @@ func (nf *NFTables) DeleteTable(family stack.AddressFamily, tableName string) (b
 		return false, err
 	}
 
-	// Deletes all chains in the table.
+	// Deletes all chains and sets in the table.
 	for chainName := range t.chains {
 		t.DeleteChain(chainName)
 	}
+	clear(t.sets)
 
 	// Deletes the table from the table map.
 	delete(nf.filters[family].tables, tableName)
@@ -424,6 +468,33 @@ func (nf *NFTables) TableCount() int {
 	return len(nf.filters)
 }
 
+// GetTables returns the tables of the given address family ordered by handle,
+// which is the order in which they were created.
+func (nf *NFTables) GetTables(family stack.AddressFamily) []*Table {
+	if validateAddressFamily(family) != nil || nf.filters[family] == nil {
+		return nil
+	}
+	tables := make([]*Table, 0, len(nf.filters[family].tables))
+	for _, t := range nf.filters[family].tables {
+		tables = append(tables, t)
+	}
+	slices.SortFunc(tables, func(a, b *Table) int {
+		return cmp.Compare(a.handle, b.handle)
+	})
+	return tables
+}
+
+// GetTableByHandle returns the table of the given address family with the
+// given handle if it exists, error otherwise.
+func (nf *NFTables) GetTableByHandle(family stack.AddressFamily, handle uint64) (*Table, *syserr.AnnotatedError) {
+	for _, t := range nf.GetTables(family) {
+		if t.handle == handle {
+			return t, nil
+		}
+	}
+	return nil, syserr.NewAnnotatedError(syserr.ErrNoFileOrDir, fmt.Sprintf("table with handle %d not found for address family %v", handle, family))
+}
+
 //
 // Table Functions
 //
@@ -438,6 +509,27 @@ func (t *Table) GetAddressFamily() stack.AddressFamily {
 	return t.afFilter.family
 }
 
+// GetHandle returns the handle of the table.
+func (t *Table) GetHandle() uint64 {
+	return t.handle
+}
+
+// GetUserData returns the user data attached to the table.
+func (t *Table) GetUserData() []byte {
+	return t.userData
+}
+
+// SetUserData attaches user data to the table.
+func (t *Table) SetUserData(userData []byte) {
+	t.userData = userData
+}
+
+// allocHandle returns a new handle for a chain, rule or set in the table.
+func (t *Table) allocHandle() uint64 {
+	t.lastHandle++
+	return t.lastHandle
+}
+
 // IsDormant returns whether the table is dormant.
 func (t *Table) IsDormant() bool {
 	_, dormant := t.flagSet[TableFlagDormant]
@@ -492,6 +584,7 @@ func (t *Table) AddChain(name string, info *BaseChainInfo, comment string, error
 	}
 
 	// Adds the chain to the chain map (after successfully doing everything else).
+	c.handle = t.allocHandle()
 	t.chains[name] = c
 
 	return c, nil
@@ -517,6 +610,11 @@ func (t *Table) DeleteChain(name string) bool {
 		}
 	}
 
+	// Releases the sets referenced by the chain's rules.
+	for _, r := range c.rules {
+		r.releaseSets()
+	}
+
 	// Deletes chain.
 	delete(t.chains, name)
 	return true
@@ -527,6 +625,30 @@ func (t *Table) ChainCount() int {
 	return len(t.chains)
 }
 
+// GetChains returns the chains of the table ordered by handle, which is the
+// order in which they were created.
+func (t *Table) GetChains() []*Chain {
+	chains := make([]*Chain, 0, len(t.chains))
+	for _, c := range t.chains {
+		chains = append(chains, c)
+	}
+	slices.SortFunc(chains, func(a, b *Chain) int {
+		return cmp.Compare(a.handle, b.handle)
+	})
+	return chains
+}
+
+// GetChainByHandle returns the chain with the specified handle if it exists,
+// error otherwise.
+func (t *Table) GetChainByHandle(handle uint64) (*Chain, *syserr.AnnotatedError) {
+	for _, c := range t.chains {
+		if c.handle == handle {
+			return c, nil
+		}
+	}
+	return nil, syserr.NewAnnotatedError(syserr.ErrNoFileOrDir, fmt.Sprintf("chain with handle %d not found for table %s", handle, t.name))
+}
+
 //
 // Chain Functions
 //
@@ -546,6 +668,44 @@ func (c *Chain) GetTable() *Table {
 	return c.table
 }
 
+// GetHandle returns the handle of the chain.
+func (c *Chain) GetHandle() uint64 {
+	return c.handle
+}
+
+// GetUserData returns the user data attached to the chain.
+func (c *Chain) GetUserData() []byte {
+	return c.userData
+}
+
+// SetUserData attaches user data to the chain.
+func (c *Chain) SetUserData(userData []byte) {
+	c.userData = userData
+}
+
+// UseCount returns the number of jump and goto operations and verdict map
+// elements in the chain's table that target the chain.
+func (c *Chain) UseCount() int {
+	count := 0
+	for _, other := range c.table.chains {
+		for _, r := range other.rules {
+			for _, op := range r.ops {
+				if isJumpOrGoto, target := isJumpOrGotoOperation(op); isJumpOrGoto && target == c.name {
+					count++
+				}
+			}
+		}
+	}
+	for _, s := range c.table.sets {
+		for _, e := range s.elems {
+			if v, ok := e.data.(verdictData); ok && v.data.ChainName == c.name {
+				count++
+			}
+		}
+	}
+	return count
+}
+
 // IsBaseChain returns whether the chain is a base chain.
 func (c *Chain) IsBaseChain() bool {
 	return c.baseChainInfo != nil
@@ -637,6 +797,8 @@ func (c *Chain) RegisterRule(rule *Rule, index int) *syserr.AnnotatedError {
 
 	// Assigns chain to rule and adds rule to chain's rule list at given index.
 	rule.chain = c
+	rule.handle = c.table.allocHandle()
+	rule.acquireSets()
 
 	// Adds the rule to the chain's rule list at the correct index.
 	if index == -1 || index == c.RuleCount() {
@@ -660,10 +822,41 @@ func (c *Chain) UnregisterRuleByIndex(index int) (*Rule, *syserr.AnnotatedError)
 		index = c.RuleCount() - 1
 	}
 	c.rules = append(c.rules[:index], c.rules[index+1:]...)
+	rule.releaseSets()
 	rule.chain = nil
 	return rule, nil
 }
 
+// ReplaceRule replaces the rule at the given index in the chain's rule list
+// with the given unregistered rule, which takes over the handle of the replaced
+// rule. Valid indices are [0, len-1]. Errors on invalid index.
+func (c *Chain) ReplaceRule(index int, rule *Rule) *syserr.AnnotatedError {
+	if index < 0 || index > c.RuleCount()-1 {
+		return syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("invalid index %d for rule replacement with %d rule(s)", index, c.RuleCount()))
+	}
+	handle := c.rules[index].handle
+	if err := c.RegisterRule(rule, index); err != nil {
+		return err
+	}
+	// The new rule was inserted before the rule it replaces.
+	if _, err := c.UnregisterRuleByIndex(index + 1); err != nil {
+		panic(fmt.Sprintf("failed to unregister replaced rule at index %d: %v", index+1, err))
+	}
+	rule.handle = handle
+	return nil
+}
+
+// GetRuleByHandle returns the rule with the given handle and its index in the
+// chain's rule list if it exists, error otherwise.
+func (c *Chain) GetRuleByHandle(handle uint64) (*Rule, int, *syserr.AnnotatedError) {
+	for i, r := range c.rules {
+		if r.handle == handle {
+			return r, i, nil
+		}
+	}
+	return nil, 0, syserr.NewAnnotatedError(syserr.ErrNoFileOrDir, fmt.Sprintf("rule with handle %d not found for chain %s", handle, c.name))
+}
+
 // GetRule returns the rule at the given index in the chain's rule list.
 // Valid indices are -1 (last) and [0, len-1]. Errors on invalid index.
 func (c *Chain) GetRule(index int) (*Rule, *syserr.AnnotatedError) {
@@ -750,6 +943,62 @@ func (c *Chain) checkLoops(source *Chain, depth int) *syserr.AnnotatedError {
 // Rule Functions
 //
 
+// GetHandle returns the handle of the rule. Rules are assigned a handle when
+// they are registered to a chain.
+func (r *Rule) GetHandle() uint64 {
+	return r.handle
+}
+
+// GetChain returns the chain that the rule is registered to, or nil if the
+// rule is not registered.
+func (r *Rule) GetChain() *Chain {
+	return r.chain
+}
+
+// GetUserData returns the user data attached to the rule.
+func (r *Rule) GetUserData() []byte {
+	return r.userData
+}
+
+// SetUserData attaches user data to the rule.
+func (r *Rule) SetUserData(userData []byte) {
+	r.userData = userData
+}
+
+// acquireSets marks the sets referenced by the rule's lookup operations as
+// in use.
+// Precondition: the rule is registered to a chain.
+func (r *Rule) acquireSets() {
+	for _, op := range r.ops {
+		if lu, ok := op.(*lookup); ok {
+			if s, exists := r.chain.table.sets[lu.setName]; exists {
+				s.use++
+			}
+		}
+	}
+}
+
+// releaseSets releases the sets referenced by the rule's lookup operations,
+// deleting anonymous sets that are no longer in use, like the kernel does when
+// unbinding anonymous sets.
+// Precondition: the rule is registered to a chain.
+func (r *Rule) releaseSets() {
+	for _, op := range r.ops {
+		lu, ok := op.(*lookup)
+		if !ok {
+			continue
+		}
+		s, exists := r.chain.table.sets[lu.setName]
+		if !exists {
+			continue
+		}
+		s.use--
+		if s.use == 0 && s.IsAnonymous() {
+			delete(r.chain.table.sets, s.name)
+		}
+	}
+}
+
 // addOperation adds an operation to the rule. Adding operations is only allowed
 // before the rule is registered to a chain. Returns an error if the operation
 // is nil or if the rule is already registered to a chain.
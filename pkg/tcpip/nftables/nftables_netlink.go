@@ -0,0 +1,504 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nftables
+
+// This file implements the translation between the netlink attributes of the
+// nf_tables API (from include/uapi/linux/netfilter/nf_tables.h) and the
+// internal representation of rules and sets. All integers in nf_tables
+// attributes are in network byte order.
+
+import (
+	"fmt"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/marshal/primitive"
+	"gvisor.dev/gvisor/pkg/sentry/socket/netlink/nlmsg"
+	"gvisor.dev/gvisor/pkg/syserr"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+)
+
+// maxRuleExprs is the maximum number of expressions in a rule, corresponding
+// to NFT_RULE_MAXEXPRS in net/netfilter/nf_tables_api.c.
+const maxRuleExprs = 128
+
+//
+// Address Family and Hook Translation
+//
+
+// netlinkFamilies maps NFPROTO_* values to the supported address families.
+var netlinkFamilies = map[uint8]stack.AddressFamily{
+	linux.NFPROTO_IPV4:   stack.IP,
+	linux.NFPROTO_IPV6:   stack.IP6,
+	linux.NFPROTO_INET:   stack.Inet,
+	linux.NFPROTO_ARP:    stack.Arp,
+	linux.NFPROTO_BRIDGE: stack.Bridge,
+	linux.NFPROTO_NETDEV: stack.Netdev,
+}
+
+// AddressFamilyFromNetlink returns the address family for the NFPROTO_* value
+// of a netlink message, returning an error if it is not supported.
+func AddressFamilyFromNetlink(family uint8) (stack.AddressFamily, *syserr.AnnotatedError) {
+	if af, ok := netlinkFamilies[family]; ok {
+		return af, nil
+	}
+	return 0, syserr.NewAnnotatedError(syserr.ErrNotSupported, fmt.Sprintf("unsupported address family: %d", family))
+}
+
+// AddressFamilyToNetlink returns the NFPROTO_* value for the address family.
+func AddressFamilyToNetlink(family stack.AddressFamily) uint8 {
+	for proto, af := range netlinkFamilies {
+		if af == family {
+			return proto
+		}
+	}
+	panic(fmt.Sprintf("invalid address family: %d", int(family)))
+}
+
+// HookFromNetlink returns the hook for the hook number of a base chain in the
+// given address family, whose meaning depends on the address family (e.g.
+// NF_INET_LOCAL_IN for inet families or NF_ARP_IN for the arp family).
+func HookFromNetlink(family stack.AddressFamily, hooknum uint32) (stack.NFHook, *syserr.AnnotatedError) {
+	var hook stack.NFHook
+	switch family {
+	case stack.Arp:
+		// From include/uapi/linux/netfilter_arp.h.
+		hooks := []stack.NFHook{stack.NFInput, stack.NFOutput}
+		if hooknum >= uint32(len(hooks)) {
+			return 0, syserr.NewAnnotatedError(syserr.ErrNotSupported, fmt.Sprintf("arp hook %d is not supported", hooknum))
+		}
+		hook = hooks[hooknum]
+	case stack.Netdev:
+		// From include/uapi/linux/netfilter.h:nf_dev_hooks.
+		hooks := []stack.NFHook{stack.NFIngress, stack.NFEgress}
+		if hooknum >= uint32(len(hooks)) {
+			return 0, syserr.NewAnnotatedError(syserr.ErrNotSupported, fmt.Sprintf("netdev hook %d is not supported", hooknum))
+		}
+		hook = hooks[hooknum]
+	default:
+		// NF_INET_* hooks have the same values as the corresponding NFHook.
+		if hooknum > uint32(stack.NFIngress) {
+			return 0, syserr.NewAnnotatedError(syserr.ErrNotSupported, fmt.Sprintf("hook %d is not supported", hooknum))
+		}
+		hook = stack.NFHook(hooknum)
+	}
+	if err := validateHook(hook, family); err != nil {
+		return 0, err
+	}
+	return hook, nil
+}
+
+// HookToNetlink returns the hook number of the hook in the given address
+// family. It is the inverse of HookFromNetlink.
+func HookToNetlink(family stack.AddressFamily, hook stack.NFHook) uint32 {
+	switch family {
+	case stack.Arp:
+		if hook == stack.NFOutput {
+			return 1
+		}
+		return 0
+	case stack.Netdev:
+		if hook == stack.NFEgress {
+			return 1
+		}
+		return 0
+	default:
+		return uint32(hook)
+	}
+}
+
+// BaseChainTypeFromString returns the base chain type with the given name.
+func BaseChainTypeFromString(name string) (BaseChainType, *syserr.AnnotatedError) {
+	for bcType, str := range baseChainTypeStrings {
+		if str == name {
+			return bcType, nil
+		}
+	}
+	return 0, syserr.NewAnnotatedError(syserr.ErrNoFileOrDir, fmt.Sprintf("unknown chain type: %s", name))
+}
+
+//
+// Register and Data Attributes
+//
+
+// parseNetlinkRegister parses a register number attribute.
+func parseNetlinkRegister(attr nlmsg.BytesView) (uint8, *syserr.AnnotatedError) {
+	// From net/netfilter/nf_tables_api.c:nft_parse_register.
+	reg, ok := attr.Uint32BE()
+	if !ok || reg > linux.NFT_REG32_15 || !isRegister(uint8(reg)) {
+		return 0, syserr.NewAnnotatedError(syserr.ErrRange, fmt.Sprintf("invalid register"))
+	}
+	return uint8(reg), nil
+}
+
+// requireAttrs returns an error if any of the given attributes of an
+// expression is missing.
+func requireAttrs(exprName string, attrs map[uint16]nlmsg.BytesView, types ...uint16) *syserr.AnnotatedError {
+	for _, t := range types {
+		if _, ok := attrs[t]; !ok {
+			return syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("%s expression is missing attribute %d", exprName, t))
+		}
+	}
+	return nil
+}
+
+// parseNetlinkUint8 parses a 32-bit attribute whose value must fit in 8 bits.
+func parseNetlinkUint8(attr nlmsg.BytesView) (uint8, *syserr.AnnotatedError) {
+	v, ok := attr.Uint32BE()
+	if !ok || v > 0xff {
+		return 0, syserr.NewAnnotatedError(syserr.ErrRange, fmt.Sprintf("attribute value out of range"))
+	}
+	return uint8(v), nil
+}
+
+// parseDataValue parses an NFTA_DATA_* nested attribute holding a value of at
+// most maxLen bytes.
+func parseDataValue(attr nlmsg.BytesView, maxLen int) ([]byte, *syserr.AnnotatedError) {
+	attrs, ok := nlmsg.AttrsView(attr).Parse()
+	if !ok {
+		return nil, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("malformed data attribute"))
+	}
+	value, ok := attrs[linux.NFTA_DATA_VALUE]
+	if !ok {
+		return nil, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("data attribute has no value"))
+	}
+	if len(value) == 0 || len(value) > maxLen {
+		return nil, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("invalid data length: %d", len(value)))
+	}
+	return append([]byte(nil), value...), nil
+}
+
+// parseNetlinkVerdict parses an NFTA_VERDICT_* nested attribute, ensuring the
+// target chain of jumps and gotos exists in the table.
+func parseNetlinkVerdict(tab *Table, attr nlmsg.BytesView) (stack.NFVerdict, *syserr.AnnotatedError) {
+	// From net/netfilter/nf_tables_api.c:nft_verdict_init.
+	attrs, ok := nlmsg.AttrsView(attr).Parse()
+	if !ok {
+		return stack.NFVerdict{}, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("malformed verdict attribute"))
+	}
+	codeAttr, ok := attrs[linux.NFTA_VERDICT_CODE]
+	if !ok {
+		return stack.NFVerdict{}, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("verdict has no code"))
+	}
+	code, ok := codeAttr.Uint32BE()
+	if !ok {
+		return stack.NFVerdict{}, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("malformed verdict code"))
+	}
+
+	v := stack.NFVerdict{Code: code}
+	switch code {
+	case VC(linux.NF_ACCEPT), VC(linux.NF_DROP), VC(linux.NFT_CONTINUE), VC(linux.NFT_BREAK), VC(linux.NFT_RETURN):
+	case VC(linux.NF_QUEUE):
+		return stack.NFVerdict{}, syserr.NewAnnotatedError(syserr.ErrNotSupported, fmt.Sprintf("queue verdict is not supported"))
+	case VC(linux.NFT_JUMP), VC(linux.NFT_GOTO):
+		chainAttr, ok := attrs[linux.NFTA_VERDICT_CHAIN]
+		if !ok {
+			return stack.NFVerdict{}, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("jump or goto verdict has no target chain"))
+		}
+		chain, err := tab.GetChain(chainAttr.String())
+		if err != nil {
+			return stack.NFVerdict{}, err
+		}
+		if chain.IsBaseChain() {
+			return stack.NFVerdict{}, syserr.NewAnnotatedError(syserr.ErrNotSupported, fmt.Sprintf("cannot jump or goto base chain %s", chain.name))
+		}
+		v.ChainName = chain.name
+	default:
+		return stack.NFVerdict{}, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("invalid verdict code: %d", int32(code)))
+	}
+	return v, nil
+}
+
+// parseNetlinkRegisterData parses an NFTA_DATA_* nested attribute holding
+// either a value that fits in a register or a verdict.
+func parseNetlinkRegisterData(tab *Table, attr nlmsg.BytesView) (registerData, *syserr.AnnotatedError) {
+	attrs, ok := nlmsg.AttrsView(attr).Parse()
+	if !ok {
+		return nil, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("malformed data attribute"))
+	}
+	if verdictAttr, ok := attrs[linux.NFTA_DATA_VERDICT]; ok {
+		v, err := parseNetlinkVerdict(tab, verdictAttr)
+		if err != nil {
+			return nil, err
+		}
+		return newVerdictData(v), nil
+	}
+	value, err := parseDataValue(attr, linux.NFT_REG_SIZE)
+	if err != nil {
+		return nil, err
+	}
+	return newBytesData(value), nil
+}
+
+// dumpDataValue returns an NFTA_DATA_* nested attribute holding a value.
+func dumpDataValue(value []byte) nlmsg.NestedAttr {
+	var attrs nlmsg.NestedAttr
+	attrs.PutAttr(linux.NFTA_DATA_VALUE, primitive.AsByteSlice(value))
+	return attrs
+}
+
+// dumpRegisterData returns an NFTA_DATA_* nested attribute holding the data.
+func dumpRegisterData(data registerData) nlmsg.NestedAttr {
+	switch data := data.(type) {
+	case bytesData:
+		return dumpDataValue(data.data)
+	case verdictData:
+		var verdict nlmsg.NestedAttr
+		verdict.PutAttrUint32BE(linux.NFTA_VERDICT_CODE, data.data.Code)
+		if data.data.ChainName != "" {
+			verdict.PutAttrString(linux.NFTA_VERDICT_CHAIN, data.data.ChainName)
+		}
+		var attrs nlmsg.NestedAttr
+		attrs.PutNestedAttr(linux.NFTA_DATA_VERDICT, verdict)
+		return attrs
+	default:
+		panic(fmt.Sprintf("unknown register data type: %T", data))
+	}
+}
+
+//
+// Rule Expressions
+//
+
+// exprParsers maps netlink expression names to the functions that parse their
+// NFTA_EXPR_DATA attributes into operations.
+var exprParsers = map[string]func(tab *Table, attrs map[uint16]nlmsg.BytesView) (operation, *syserr.AnnotatedError){
+	"immediate": parseImmediate,
+	"cmp":       parseComparison,
+	"range":     parseRanged,
+	"payload":   parsePayload,
+	"bitwise":   parseBitwise,
+	"byteorder": parseByteorder,
+	"counter":   parseCounter,
+	"last":      parseLast,
+	"meta":      parseMeta,
+	"rt":        parseRoute,
+	"lookup":    parseLookup,
+}
+
+// ParseRule creates a new unregistered rule for the table from the list of
+// expressions in an NFTA_RULE_EXPRESSIONS attribute.
+func ParseRule(tab *Table, exprs nlmsg.AttrsView) (*Rule, *syserr.AnnotatedError) {
+	rule := &Rule{}
+	for !exprs.Empty() {
+		hdr, value, rest, ok := exprs.ParseFirst()
+		if !ok || hdr.Type&linux.NLA_TYPE_MASK != linux.NFTA_LIST_ELEM {
+			return nil, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("malformed expression list"))
+		}
+		exprs = rest
+		if len(rule.ops) == maxRuleExprs {
+			return nil, syserr.NewAnnotatedError(syserr.ErrTooManyArgs, fmt.Sprintf("rule has more than %d expressions", maxRuleExprs))
+		}
+
+		op, err := parseExpr(tab, value)
+		if err != nil {
+			return nil, err
+		}
+		if err := rule.addOperation(op); err != nil {
+			return nil, err
+		}
+	}
+	return rule, nil
+}
+
+// parseExpr parses a single NFTA_EXPR_* nested attribute into an operation.
+func parseExpr(tab *Table, expr []byte) (operation, *syserr.AnnotatedError) {
+	attrs, ok := nlmsg.AttrsView(expr).Parse()
+	if !ok {
+		return nil, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("malformed expression"))
+	}
+	nameAttr, ok := attrs[linux.NFTA_EXPR_NAME]
+	if !ok {
+		return nil, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("expression has no name"))
+	}
+	name := nameAttr.String()
+	parse, ok := exprParsers[name]
+	if !ok {
+		// From net/netfilter/nf_tables_api.c:nft_expr_type_get.
+		return nil, syserr.NewAnnotatedError(syserr.ErrNoFileOrDir, fmt.Sprintf("expression %s is not supported", name))
+	}
+	var dataAttrs map[uint16]nlmsg.BytesView
+	if data, ok := attrs[linux.NFTA_EXPR_DATA]; ok {
+		if dataAttrs, ok = nlmsg.AttrsView(data).Parse(); !ok {
+			return nil, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("malformed %s expression data", name))
+		}
+	} else {
+		dataAttrs = make(map[uint16]nlmsg.BytesView)
+	}
+	return parse(tab, dataAttrs)
+}
+
+// DumpExprs returns the contents of the NFTA_RULE_EXPRESSIONS attribute
+// describing the rule's operations.
+func (r *Rule) DumpExprs() nlmsg.NestedAttr {
+	var exprs nlmsg.NestedAttr
+	for _, op := range r.ops {
+		var expr nlmsg.NestedAttr
+		expr.PutAttrString(linux.NFTA_EXPR_NAME, op.exprName())
+		expr.PutNestedAttr(linux.NFTA_EXPR_DATA, op.dump(r))
+		exprs.PutNestedAttr(linux.NFTA_LIST_ELEM, expr)
+	}
+	return exprs
+}
+
+//
+// Set Elements
+//
+
+// parseSetElem parses a single NFTA_SET_ELEM_* nested attribute for the set.
+// If keyOnly is true, the element's data is ignored (e.g. for deletions).
+func (s *Set) parseSetElem(attr []byte, keyOnly bool) (*setElem, *syserr.AnnotatedError) {
+	// From net/netfilter/nf_tables_api.c:nft_add_set_elem.
+	attrs, ok := nlmsg.AttrsView(attr).Parse()
+	if !ok {
+		return nil, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("malformed set element"))
+	}
+	elem := &setElem{}
+	if flagsAttr, ok := attrs[linux.NFTA_SET_ELEM_FLAGS]; ok {
+		if elem.flags, ok = flagsAttr.Uint32BE(); !ok {
+			return nil, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("malformed set element flags"))
+		}
+	}
+	if elem.flags&linux.NFT_SET_ELEM_CATCHALL != 0 {
+		return nil, syserr.NewAnnotatedError(syserr.ErrNotSupported, fmt.Sprintf("catch-all set elements are not supported"))
+	}
+	if elem.flags&^linux.NFT_SET_ELEM_INTERVAL_END != 0 {
+		return nil, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("invalid set element flags: %#x", elem.flags))
+	}
+	if elem.isIntervalEnd() && !s.isInterval() {
+		return nil, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("interval end element in non-interval set %s", s.name))
+	}
+	for _, unsupported := range []uint16{linux.NFTA_SET_ELEM_TIMEOUT, linux.NFTA_SET_ELEM_EXPR, linux.NFTA_SET_ELEM_OBJREF, linux.NFTA_SET_ELEM_KEY_END, linux.NFTA_SET_ELEM_EXPRESSIONS} {
+		if _, ok := attrs[unsupported]; ok {
+			return nil, syserr.NewAnnotatedError(syserr.ErrNotSupported, fmt.Sprintf("set element attribute %d is not supported", unsupported))
+		}
+	}
+
+	keyAttr, ok := attrs[linux.NFTA_SET_ELEM_KEY]
+	if !ok {
+		return nil, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("set element has no key"))
+	}
+	key, err := parseDataValue(keyAttr, linux.NFT_DATA_VALUE_MAXLEN)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != int(s.info.KeyLen) {
+		return nil, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("set element key length %d does not match set key length %d", len(key), s.info.KeyLen))
+	}
+	elem.key = key
+	if keyOnly {
+		return elem, nil
+	}
+
+	if userData, ok := attrs[linux.NFTA_SET_ELEM_USERDATA]; ok {
+		elem.userData = append([]byte(nil), userData...)
+	}
+
+	dataAttr, hasData := attrs[linux.NFTA_SET_ELEM_DATA]
+	if !s.isMap() || elem.isIntervalEnd() {
+		if hasData {
+			return nil, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("set element has unexpected data"))
+		}
+		return elem, nil
+	}
+	if !hasData {
+		return nil, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("map element has no data"))
+	}
+	data, err := parseNetlinkRegisterData(s.table, dataAttr)
+	if err != nil {
+		return nil, err
+	}
+	switch data := data.(type) {
+	case verdictData:
+		if s.info.DataType != linux.NFT_DATA_VERDICT {
+			return nil, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("map %s does not hold verdicts", s.name))
+		}
+	case bytesData:
+		if s.info.DataType == linux.NFT_DATA_VERDICT || len(data.data) != int(s.info.DataLen) {
+			return nil, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("map element data does not match the data of map %s", s.name))
+		}
+	}
+	elem.data = data
+	return elem, nil
+}
+
+// AddElems adds the elements in an NFTA_SET_ELEM_LIST_ELEMENTS attribute to
+// the set. If exclusive is true, it is an error for an element to exist.
+func (s *Set) AddElems(elems nlmsg.AttrsView, exclusive bool) *syserr.AnnotatedError {
+	if err := s.checkElemsModifiable(); err != nil {
+		return err
+	}
+	return s.forEachElem(elems, false, func(elem *setElem) *syserr.AnnotatedError {
+		return s.insertElem(elem, exclusive)
+	})
+}
+
+// DeleteElems removes the elements in an NFTA_SET_ELEM_LIST_ELEMENTS attribute
+// from the set.
+func (s *Set) DeleteElems(elems nlmsg.AttrsView) *syserr.AnnotatedError {
+	if err := s.checkElemsModifiable(); err != nil {
+		return err
+	}
+	return s.forEachElem(elems, true, s.removeElem)
+}
+
+// checkElemsModifiable returns an error if the elements of the set cannot be
+// changed because it is a constant or anonymous set that is in use.
+func (s *Set) checkElemsModifiable() *syserr.AnnotatedError {
+	// From net/netfilter/nf_tables_api.c:nf_tables_newsetelem.
+	if s.use > 0 && s.info.Flags&(linux.NFT_SET_CONSTANT|linux.NFT_SET_ANONYMOUS) != 0 {
+		return syserr.NewAnnotatedError(syserr.ErrBusy, fmt.Sprintf("elements of set %s cannot be changed while it is in use", s.name))
+	}
+	return nil
+}
+
+// forEachElem parses each element of an element list and calls fn on it.
+func (s *Set) forEachElem(elems nlmsg.AttrsView, keyOnly bool, fn func(*setElem) *syserr.AnnotatedError) *syserr.AnnotatedError {
+	for !elems.Empty() {
+		hdr, value, rest, ok := elems.ParseFirst()
+		if !ok || hdr.Type&linux.NLA_TYPE_MASK != linux.NFTA_LIST_ELEM {
+			return syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("malformed set element list"))
+		}
+		elems = rest
+		elem, err := s.parseSetElem(value, keyOnly)
+		if err != nil {
+			return err
+		}
+		if err := fn(elem); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DumpElems returns the contents of the NFTA_SET_ELEM_LIST_ELEMENTS attribute
+// describing the set's elements.
+func (s *Set) DumpElems() nlmsg.NestedAttr {
+	var elems nlmsg.NestedAttr
+	for _, e := range s.elems {
+		var elem nlmsg.NestedAttr
+		elem.PutNestedAttr(linux.NFTA_SET_ELEM_KEY, dumpDataValue(e.key))
+		if e.data != nil {
+			elem.PutNestedAttr(linux.NFTA_SET_ELEM_DATA, dumpRegisterData(e.data))
+		}
+		if e.flags != 0 {
+			elem.PutAttrUint32BE(linux.NFTA_SET_ELEM_FLAGS, e.flags)
+		}
+		if len(e.userData) > 0 {
+			elem.PutAttr(linux.NFTA_SET_ELEM_USERDATA, primitive.AsByteSlice(e.userData))
+		}
+		elems.PutNestedAttr(linux.NFTA_LIST_ELEM, elem)
+	}
+	return elems
+}
@@ -0,0 +1,361 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nftables
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/syserr"
+)
+
+// maxAnonymousSets is the maximum number of sets that can be named from a
+// single name format (e.g. "__set%d"), like in Linux.
+const maxAnonymousSets = 1024
+
+// Set is a named collection of keys that rules match packets against using the
+// lookup operation. If the set is a map, each element also holds data that is
+// loaded into a register (or the verdict register for verdict maps) when the
+// element's key is matched.
+// Note: corresponds to struct nft_set from include/net/netfilter/nf_tables.h.
+type Set struct {
+	// name is the name of the set.
+	name string
+
+	// table is the table that the set belongs to.
+	table *Table
+
+	// handle uniquely identifies the set within its table.
+	handle uint64
+
+	// id is the ID assigned to the set by userspace within the transaction that
+	// created it, or 0.
+	id uint32
+
+	// info holds the properties of the set given by userspace.
+	info SetInfo
+
+	// elems is the list of elements in the set, sorted by key. Elements are
+	// immutable once added to a set.
+	elems []*setElem
+
+	// use is the number of lookup operations that reference the set.
+	use int
+}
+
+// SetInfo holds the properties of a set given by userspace when the set is
+// created.
+type SetInfo struct {
+	// Flags is a bitmask of NFT_SET_* flags.
+	Flags uint32
+
+	// KeyType is the datatype of the keys. It is opaque to the kernel.
+	KeyType uint32
+
+	// KeyLen is the length of the keys in bytes.
+	KeyLen uint32
+
+	// DataType is the datatype of the map data, or NFT_DATA_VERDICT for verdict
+	// maps. Only valid for maps.
+	DataType uint32
+
+	// DataLen is the length of the map data in bytes. Only valid for maps.
+	DataLen uint32
+
+	// UserData is opaque data attached to the set by userspace.
+	UserData []byte
+}
+
+// setElem is a single element of a set.
+type setElem struct {
+	// key is the key of the element, in register (network) byte order.
+	key []byte
+
+	// data is the data associated with the key, if the set is a map.
+	data registerData
+
+	// flags is a bitmask of NFT_SET_ELEM_* flags.
+	flags uint32
+
+	// userData is opaque data attached to the element by userspace.
+	userData []byte
+}
+
+// isIntervalEnd returns whether the element marks the end of an interval.
+func (e *setElem) isIntervalEnd() bool {
+	return e.flags&linux.NFT_SET_ELEM_INTERVAL_END != 0
+}
+
+// compareSetElems orders elements by key. For interval sets, an element ending
+// an interval is ordered before an element starting an interval with the same
+// key, so adjacent intervals are matched correctly.
+func compareSetElems(a, b *setElem) int {
+	if c := bytes.Compare(a.key, b.key); c != 0 {
+		return c
+	}
+	return cmp.Compare(b.flags&linux.NFT_SET_ELEM_INTERVAL_END, a.flags&linux.NFT_SET_ELEM_INTERVAL_END)
+}
+
+// validateSetInfo ensures the set properties are supported.
+func validateSetInfo(info SetInfo) *syserr.AnnotatedError {
+	// From net/netfilter/nf_tables_api.c:nf_tables_newset.
+	const supportedFlags = linux.NFT_SET_ANONYMOUS | linux.NFT_SET_CONSTANT | linux.NFT_SET_INTERVAL | linux.NFT_SET_MAP | linux.NFT_SET_CONCAT
+	if info.Flags&^supportedFlags != 0 {
+		return syserr.NewAnnotatedError(syserr.ErrNotSupported, fmt.Sprintf("set flags %#x are not supported", info.Flags&^supportedFlags))
+	}
+	if info.KeyLen == 0 || info.KeyLen > linux.NFT_DATA_VALUE_MAXLEN {
+		return syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("invalid set key length: %d", info.KeyLen))
+	}
+	if info.Flags&linux.NFT_SET_MAP == 0 {
+		if info.DataType != 0 || info.DataLen != 0 {
+			return syserr.NewAnnotatedError(syserr.ErrInvalidArgument, "data type and length can only be specified for maps")
+		}
+		return nil
+	}
+	if info.DataType == linux.NFT_DATA_VERDICT {
+		return nil
+	}
+	if info.DataLen == 0 || info.DataLen > linux.NFT_REG_SIZE {
+		return syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("invalid map data length: %d", info.DataLen))
+	}
+	return nil
+}
+
+//
+// Table Set Functions
+//
+
+// GetSet returns the set with the specified name if it exists, error
+// otherwise.
+func (t *Table) GetSet(name string) (*Set, *syserr.AnnotatedError) {
+	s, exists := t.sets[name]
+	if !exists {
+		return nil, syserr.NewAnnotatedError(syserr.ErrNoFileOrDir, fmt.Sprintf("set %s not found for table %s", name, t.name))
+	}
+	return s, nil
+}
+
+// GetSetByHandle returns the set with the specified handle if it exists, error
+// otherwise.
+func (t *Table) GetSetByHandle(handle uint64) (*Set, *syserr.AnnotatedError) {
+	for _, s := range t.sets {
+		if s.handle == handle {
+			return s, nil
+		}
+	}
+	return nil, syserr.NewAnnotatedError(syserr.ErrNoFileOrDir, fmt.Sprintf("set with handle %d not found for table %s", handle, t.name))
+}
+
+// GetSetByID returns the set created in the current transaction with the
+// specified ID if it exists, error otherwise.
+func (t *Table) GetSetByID(id uint32) (*Set, *syserr.AnnotatedError) {
+	for _, s := range t.sets {
+		if id != 0 && s.id == id {
+			return s, nil
+		}
+	}
+	return nil, syserr.NewAnnotatedError(syserr.ErrNoFileOrDir, fmt.Sprintf("set with id %d not found for table %s", id, t.name))
+}
+
+// GetSets returns the sets of the table ordered by handle, which is the order
+// in which they were created.
+func (t *Table) GetSets() []*Set {
+	sets := make([]*Set, 0, len(t.sets))
+	for _, s := range t.sets {
+		sets = append(sets, s)
+	}
+	slices.SortFunc(sets, func(a, b *Set) int {
+		return cmp.Compare(a.handle, b.handle)
+	})
+	return sets
+}
+
+// SetCount returns the number of sets in the table.
+func (t *Table) SetCount() int {
+	return len(t.sets)
+}
+
+// AddSet makes a new set for the table with the given ID, returning an error
+// if a set by the same name already exists. If the name contains "%d", the set
+// is named by replacing it with the lowest number not used by another set, as
+// is done for anonymous sets.
+func (t *Table) AddSet(name string, id uint32, info SetInfo) (*Set, *syserr.AnnotatedError) {
+	if err := validateSetInfo(info); err != nil {
+		return nil, err
+	}
+
+	if strings.Contains(name, "%d") {
+		// From net/netfilter/nf_tables_api.c:nf_tables_set_alloc_name.
+		if strings.Count(name, "%") != 1 {
+			return nil, syserr.NewAnnotatedError(syserr.ErrInvalidArgument, fmt.Sprintf("invalid set name format: %s", name))
+		}
+		format := name
+		for n := 0; ; n++ {
+			if n == maxAnonymousSets {
+				return nil, syserr.NewAnnotatedError(syserr.ErrNoFileOrDir, fmt.Sprintf("no free set names for format %s", format))
+			}
+			name = fmt.Sprintf(format, n)
+			if _, exists := t.sets[name]; !exists {
+				break
+			}
+		}
+	}
+
+	if _, exists := t.sets[name]; exists {
+		return nil, syserr.NewAnnotatedError(syserr.ErrExists, fmt.Sprintf("set %s already exists for table %s", name, t.name))
+	}
+
+	s := &Set{
+		name:   name,
+		table:  t,
+		handle: t.allocHandle(),
+		id:     id,
+		info:   info,
+	}
+	t.sets[name] = s
+	return s, nil
+}
+
+// DeleteSet deletes the specified set from the table, returning an error if
+// the set doesn't exist or is referenced by a rule.
+func (t *Table) DeleteSet(name string) *syserr.AnnotatedError {
+	s, err := t.GetSet(name)
+	if err != nil {
+		return err
+	}
+	if s.use > 0 {
+		return syserr.NewAnnotatedError(syserr.ErrBusy, fmt.Sprintf("set %s is in use by %d rule(s)", name, s.use))
+	}
+	delete(t.sets, name)
+	return nil
+}
+
+//
+// Set Functions
+//
+
+// GetName returns the name of the set.
+func (s *Set) GetName() string {
+	return s.name
+}
+
+// GetTable returns the table that the set belongs to.
+func (s *Set) GetTable() *Table {
+	return s.table
+}
+
+// GetHandle returns the handle of the set.
+func (s *Set) GetHandle() uint64 {
+	return s.handle
+}
+
+// GetInfo returns the properties of the set.
+func (s *Set) GetInfo() SetInfo {
+	return s.info
+}
+
+// IsAnonymous returns whether the set is anonymous. Anonymous sets are bound to
+// a single rule and are deleted along with it.
+func (s *Set) IsAnonymous() bool {
+	return s.info.Flags&linux.NFT_SET_ANONYMOUS != 0
+}
+
+// isMap returns whether the set is a map.
+func (s *Set) isMap() bool {
+	return s.info.Flags&linux.NFT_SET_MAP != 0
+}
+
+// isInterval returns whether the set holds intervals.
+func (s *Set) isInterval() bool {
+	return s.info.Flags&linux.NFT_SET_INTERVAL != 0
+}
+
+// ElemCount returns the number of elements in the set.
+func (s *Set) ElemCount() int {
+	return len(s.elems)
+}
+
+// findElem returns the index of the element with the same key and interval
+// flag as elem, and whether it exists.
+func (s *Set) findElem(elem *setElem) (int, bool) {
+	return slices.BinarySearchFunc(s.elems, elem, compareSetElems)
+}
+
+// insertElem adds an element to the set. If an element with the same key
+// exists, an error is returned if exclusive is true or its data differs from
+// the new element's, like in Linux.
+func (s *Set) insertElem(elem *setElem, exclusive bool) *syserr.AnnotatedError {
+	i, exists := s.findElem(elem)
+	if !exists {
+		s.elems = slices.Insert(s.elems, i, elem)
+		return nil
+	}
+	if exclusive {
+		return syserr.NewAnnotatedError(syserr.ErrExists, fmt.Sprintf("element %x already exists in set %s", elem.key, s.name))
+	}
+	if old := s.elems[i]; (old.data == nil) != (elem.data == nil) || (old.data != nil && !old.data.equal(elem.data)) {
+		return syserr.NewAnnotatedError(syserr.ErrBusy, fmt.Sprintf("element %x already exists in set %s with different data", elem.key, s.name))
+	}
+	return nil
+}
+
+// removeElem removes the element with the same key and interval flag as elem
+// from the set, returning an error if it doesn't exist.
+func (s *Set) removeElem(elem *setElem) *syserr.AnnotatedError {
+	i, exists := s.findElem(elem)
+	if !exists {
+		return syserr.NewAnnotatedError(syserr.ErrNoFileOrDir, fmt.Sprintf("element %x not found in set %s", elem.key, s.name))
+	}
+	s.elems = slices.Delete(s.elems, i, i+1)
+	return nil
+}
+
+// Flush removes all elements from the set.
+func (s *Set) Flush() *syserr.AnnotatedError {
+	if err := s.checkElemsModifiable(); err != nil {
+		return err
+	}
+	s.elems = nil
+	return nil
+}
+
+// lookup returns the element matching the key if it exists. For interval
+// sets, the matching element is the one starting the interval that contains
+// the key.
+func (s *Set) lookup(key []byte) (*setElem, bool) {
+	if !s.isInterval() {
+		i, found := slices.BinarySearchFunc(s.elems, key, func(e *setElem, k []byte) int {
+			return bytes.Compare(e.key, k)
+		})
+		if !found {
+			return nil, false
+		}
+		return s.elems[i], true
+	}
+
+	// Finds the last element with a key less than or equal to the given key.
+	i := sort.Search(len(s.elems), func(i int) bool {
+		return bytes.Compare(s.elems[i].key, key) > 0
+	})
+	if i == 0 || s.elems[i-1].isIntervalEnd() {
+		return nil, false
+	}
+	return s.elems[i-1], true
+}
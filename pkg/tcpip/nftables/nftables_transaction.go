@@ -0,0 +1,169 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nftables
+
+import (
+	"maps"
+	"slices"
+
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+)
+
+// Transaction is a set of changes to the ruleset that are applied atomically,
+// corresponding to a batch of nf_tables netlink messages.
+//
+// Changes are made to a private copy of the committed ruleset, which replaces
+// the committed ruleset on Commit and is discarded on Abort, so packets are
+// never evaluated against a partially modified ruleset. Only one transaction
+// may be in progress at a time.
+type Transaction struct {
+	// NFTables is the working copy of the ruleset modified by the transaction.
+	*NFTables
+
+	// nf is the NFTables object that the transaction commits to.
+	nf *NFTables
+}
+
+// NewTransaction starts a new transaction, blocking until any transaction in
+// progress is committed or aborted. The caller must call exactly one of
+// Commit or Abort on the returned transaction.
+func (nf *NFTables) NewTransaction() *Transaction {
+	nf.txMu.Lock()
+	// Only transactions modify the committed ruleset, so it is safe to read it
+	// without holding nf.mu while holding nf.txMu.
+	return &Transaction{
+		NFTables: nf.clone(),
+		nf:       nf,
+	}
+}
+
+// Commit replaces the committed ruleset with the transaction's working copy
+// and ends the transaction.
+func (tx *Transaction) Commit() {
+	nf := tx.nf
+	nf.mu.Lock()
+	for _, afFilter := range tx.filters {
+		if afFilter != nil {
+			afFilter.nftState = nf
+		}
+	}
+	nf.filters = tx.filters
+	nf.tableHandle = tx.tableHandle
+	nf.genID++
+	nf.mu.Unlock()
+	nf.txMu.Unlock()
+}
+
+// Abort discards the transaction's changes and ends the transaction.
+func (tx *Transaction) Abort() {
+	tx.nf.txMu.Unlock()
+}
+
+// clone returns a deep copy of the ruleset. Operations are shared between the
+// copies since they are immutable once their rule is registered (other than
+// stateful operations such as counters, whose state is preserved across
+// transactions like in Linux).
+func (nf *NFTables) clone() *NFTables {
+	c := &NFTables{
+		clock:       nf.clock,
+		startTime:   nf.startTime,
+		rng:         nf.rng,
+		tableHandle: nf.tableHandle,
+		genID:       nf.genID,
+	}
+	for family, afFilter := range nf.filters {
+		if afFilter == nil {
+			continue
+		}
+		cAfFilter := &addressFamilyFilter{
+			family:   afFilter.family,
+			nftState: c,
+			tables:   make(map[string]*Table, len(afFilter.tables)),
+			hfStacks: make(map[stack.NFHook]*hookFunctionStack, len(afFilter.hfStacks)),
+		}
+
+		// Maps each chain to its copy, to rebuild the hook function stacks.
+		chains := make(map[*Chain]*Chain)
+		for name, t := range afFilter.tables {
+			cAfFilter.tables[name] = t.clone(cAfFilter, chains)
+		}
+		for hook, hfStack := range afFilter.hfStacks {
+			cHfStack := &hookFunctionStack{
+				hook:       hook,
+				baseChains: make([]*Chain, 0, len(hfStack.baseChains)),
+			}
+			for _, bc := range hfStack.baseChains {
+				cHfStack.baseChains = append(cHfStack.baseChains, chains[bc])
+			}
+			cAfFilter.hfStacks[hook] = cHfStack
+		}
+		c.filters[family] = cAfFilter
+	}
+	return c
+}
+
+// clone returns a deep copy of the table for the given address family filter,
+// recording the copy of each chain in chains.
+func (t *Table) clone(afFilter *addressFamilyFilter, chains map[*Chain]*Chain) *Table {
+	ct := &Table{
+		name:       t.name,
+		afFilter:   afFilter,
+		chains:     make(map[string]*Chain, len(t.chains)),
+		flagSet:    maps.Clone(t.flagSet),
+		sets:       make(map[string]*Set, len(t.sets)),
+		handle:     t.handle,
+		lastHandle: t.lastHandle,
+		userData:   t.userData,
+	}
+	for name, s := range t.sets {
+		ct.sets[name] = s.clone(ct)
+	}
+	for name, c := range t.chains {
+		cc := &Chain{
+			name:     c.name,
+			table:    ct,
+			rules:    make([]*Rule, 0, len(c.rules)),
+			comment:  c.comment,
+			handle:   c.handle,
+			userData: c.userData,
+		}
+		if c.baseChainInfo != nil {
+			info := *c.baseChainInfo
+			cc.baseChainInfo = &info
+		}
+		for _, r := range c.rules {
+			cc.rules = append(cc.rules, &Rule{
+				chain:    cc,
+				ops:      r.ops,
+				handle:   r.handle,
+				userData: r.userData,
+			})
+		}
+		ct.chains[name] = cc
+		chains[c] = cc
+	}
+	return ct
+}
+
+// clone returns a copy of the set for the given table. Set IDs are only
+// meaningful within the transaction that created the set, so they are not
+// copied.
+func (s *Set) clone(t *Table) *Set {
+	cs := *s
+	cs.table = t
+	cs.id = 0
+	cs.elems = slices.Clone(s.elems)
+	return &cs
+}
@@ -48,6 +48,8 @@ import (
 	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/atomicbitops"
 	"gvisor.dev/gvisor/pkg/rand"
+	"gvisor.dev/gvisor/pkg/sentry/socket/netlink/nlmsg"
+	"gvisor.dev/gvisor/pkg/sync"
 	"gvisor.dev/gvisor/pkg/syserr"
 	"gvisor.dev/gvisor/pkg/tcpip"
 	"gvisor.dev/gvisor/pkg/tcpip/stack"
@@ -143,6 +145,23 @@ type NFTables struct {
 	clock     tcpip.Clock                        // Clock for timing evaluations.
 	startTime time.Time                          // Time NFTables object was created.
 	rng       rand.RNG                           // Random number generator.
+
+	// tableHandle is the handle assigned to the most recently created table.
+	// Handles are unique across all address families.
+	tableHandle uint64
+
+	// genID is the generation of the ruleset, incremented each time a
+	// transaction is committed.
+	genID uint32
+
+	// mu protects filters and genID from concurrent packet evaluation and
+	// transaction commits. Committed rulesets are never modified in place, so
+	// mu does not need to be held while reading a ruleset returned by Snapshot.
+	mu sync.RWMutex
+
+	// txMu serializes transactions. It is locked by NewTransaction and unlocked
+	// by Transaction.Commit or Transaction.Abort.
+	txMu sync.Mutex
 }
 
 // Ensures NFTables implements the NFTablesInterface.
@@ -182,6 +201,20 @@ type Table struct {
 	// flags is the set of optional flags for the table.
 	// Note: currently nftables only has the single Dormant flag.
 	flagSet map[TableFlag]struct{}
+
+	// sets is a map of named sets for the table.
+	sets map[string]*Set
+
+	// handle uniquely identifies the table.
+	handle uint64
+
+	// lastHandle is the handle assigned to the most recently created chain,
+	// rule or set in the table.
+	lastHandle uint64
+
+	// userData is opaque data attached to the table by userspace (e.g. a
+	// comment).
+	userData []byte
 }
 
 // hookFunctionStack represents the list of base chains for a specific hook.
@@ -223,6 +256,12 @@ type Chain struct {
 
 	// comment is the optional comment for the table.
 	comment string
+
+	// handle uniquely identifies the chain within its table.
+	handle uint64
+
+	// userData is opaque data attached to the chain by userspace.
+	userData []byte
 }
 
 // TODO(b/345684870): BaseChainInfo Implementation. Encode how bcType affects
@@ -501,6 +540,14 @@ func validateBaseChainInfo(info *BaseChainInfo, family stack.AddressFamily) *sys
 type Rule struct {
 	chain *Chain
 	ops   []operation
+
+	// handle uniquely identifies the rule within its table. It is assigned
+	// when the rule is registered to a chain.
+	handle uint64
+
+	// userData is opaque data attached to the rule by userspace (e.g. a
+	// comment).
+	userData []byte
 }
 
 // operation represents a single operation in a rule.
@@ -510,6 +557,15 @@ type operation interface {
 	// changing the register set and possibly the packet in place. We pass the
 	// assigned rule to allow the operation to access parts of the NFTables state.
 	evaluate(regs *registerSet, pkt *stack.PacketBuffer, rule *Rule)
+
+	// exprName returns the name of the netlink expression that corresponds to
+	// the operation.
+	exprName() string
+
+	// dump returns the netlink attributes describing the operation, as the
+	// payload of the NFTA_EXPR_DATA attribute. Like evaluate, it is passed the
+	// assigned rule to allow access to parts of the NFTables state.
+	dump(rule *Rule) nlmsg.NestedAttr
 }
 
 // Ensures all operations implement the Operation interface at compile time.
@@ -525,6 +581,8 @@ var (
 	_ operation = (*route)(nil)
 	_ operation = (*byteorder)(nil)
 	_ operation = (*metaLoad)(nil)
+	_ operation = (*metaSet)(nil)
+	_ operation = (*lookup)(nil)
 )
 
 //
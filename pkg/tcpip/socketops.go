@@ -15,6 +15,9 @@
 package tcpip
 
 import (
+	"sync/atomic"
+	"time"
+
 	"gvisor.dev/gvisor/pkg/atomicbitops"
 	"gvisor.dev/gvisor/pkg/buffer"
 	"gvisor.dev/gvisor/pkg/sync"
@@ -67,6 +70,17 @@ type SocketOptionsHandler interface {
 	// GetAcceptConn returns true if the socket is a TCP socket and is in
 	// listening state.
 	GetAcceptConn() bool
+
+	// OnTimestampingIDSet is invoked when SO_TIMESTAMPING is set with
+	// TimestampingOptID for an endpoint which didn't have it. It returns the
+	// key from which the IDs of TX timestamps are computed. fromWriteSeq is
+	// true if TimestampingOptIDTCP is set.
+	OnTimestampingIDSet(fromWriteSeq bool) (uint32, Error)
+
+	// WakeupErrQueueReaders is invoked when a notification which doesn't
+	// originate from the endpoint itself, such as a TX timestamp, is queued
+	// on the error queue.
+	WakeupErrQueueReaders()
 }
 
 // DefaultSocketOptionsHandler is an embeddable type that implements no-op
@@ -121,6 +135,24 @@ func (*DefaultSocketOptionsHandler) GetAcceptConn() bool {
 	return false
 }
 
+// OnTimestampingIDSet implements SocketOptionsHandler.OnTimestampingIDSet.
+func (*DefaultSocketOptionsHandler) OnTimestampingIDSet(bool) (uint32, Error) {
+	return 0, nil
+}
+
+// WakeupErrQueueReaders implements SocketOptionsHandler.WakeupErrQueueReaders.
+func (*DefaultSocketOptionsHandler) WakeupErrQueueReaders() {}
+
+// SocketFilter is a classic BPF program attached to a socket with
+// SO_ATTACH_FILTER, which decides which of the packets received by the socket
+// are queued, like sk_filter in Linux.
+type SocketFilter interface {
+	// Run runs the filter on pkt, whose first header depends on the kind of
+	// socket. It returns the number of bytes of pkt to keep, which is zero
+	// if pkt must be dropped.
+	Run(pkt []byte) uint32
+}
+
 // StackHandler holds methods to access the stack options. These must be
 // implemented by the stack.
 type StackHandler interface {
@@ -264,9 +296,37 @@ type SocketOptions struct {
 	// received to indicate the socket as readable.
 	rcvlowat atomicbitops.Int32
 
+	// filter points to the filter attached with SO_ATTACH_FILTER, or is nil.
+	// It is loaded without holding mu on the receive path, but is only
+	// stored with mu held.
+	filter atomic.Pointer[SocketFilter] `state:".(SocketFilter)"`
+
+	// filterLocked is true if the filter can't be changed anymore, as set
+	// with SO_LOCK_FILTER.
+	filterLocked bool
+
 	// experimentOptionValue is the value set for the IP option experiment header
 	// if it is not zero.
 	experimentOptionValue atomicbitops.Uint32
+
+	// zeroCopyEnabled determines whether MSG_ZEROCOPY is honored by sends.
+	zeroCopyEnabled atomicbitops.Uint32
+
+	// zeroCopyNextID is the ID of the next send with MSG_ZEROCOPY.
+	zeroCopyNextID atomicbitops.Uint32
+
+	// timestamping holds the TimestampingFlags set with SO_TIMESTAMPING.
+	timestamping atomicbitops.Uint32
+
+	// timestampingKey is the key from which the IDs of TX timestamps are
+	// computed when TimestampingOptID is set. For datagram sockets, it is the
+	// ID of the next send. For stream sockets, it is the sequence number from
+	// which the IDs of sent bytes are counted.
+	timestampingKey atomicbitops.Uint32
+
+	// txTime is the value set for the SO_TXTIME option. It is protected by
+	// mu.
+	txTime TXTimeOption
 }
 
 // InitHandler initializes the handler. This must be called before using the
@@ -488,6 +548,22 @@ func (so *SocketOptions) SetReceiveOriginalDstAddress(v bool) {
 	storeAtomicBool(&so.receiveOriginalDstAddress, v)
 }
 
+// GetZeroCopy gets value for SO_ZEROCOPY option.
+func (so *SocketOptions) GetZeroCopy() bool {
+	return so.zeroCopyEnabled.Load() != 0
+}
+
+// SetZeroCopy sets value for SO_ZEROCOPY option.
+func (so *SocketOptions) SetZeroCopy(v bool) {
+	storeAtomicBool(&so.zeroCopyEnabled, v)
+}
+
+// NextZeroCopyID returns the ID of the next send with MSG_ZEROCOPY, which is
+// reported in its completion notification.
+func (so *SocketOptions) NextZeroCopyID() uint32 {
+	return so.zeroCopyNextID.Add(1) - 1
+}
+
 // GetIPv4RecvError gets value for IP_RECVERR option.
 func (so *SocketOptions) GetIPv4RecvError() bool {
 	return so.ipv4RecvErrEnabled.Load() != 0
@@ -543,6 +619,174 @@ func (so *SocketOptions) SetLinger(linger LingerOption) {
 	so.mu.Unlock()
 }
 
+// GetFilter returns the filter attached with SO_ATTACH_FILTER, or nil.
+func (so *SocketOptions) GetFilter() SocketFilter {
+	if f := so.filter.Load(); f != nil {
+		return *f
+	}
+	return nil
+}
+
+// SetFilter attaches f with SO_ATTACH_FILTER, replacing the previous filter.
+func (so *SocketOptions) SetFilter(f SocketFilter) Error {
+	so.mu.Lock()
+	defer so.mu.Unlock()
+	if so.filterLocked {
+		return &ErrNotPermitted{}
+	}
+	if f == nil {
+		so.filter.Store(nil)
+	} else {
+		so.filter.Store(&f)
+	}
+	return nil
+}
+
+// DetachFilter detaches the filter attached with SO_ATTACH_FILTER, as done
+// with SO_DETACH_FILTER.
+func (so *SocketOptions) DetachFilter() Error {
+	so.mu.Lock()
+	defer so.mu.Unlock()
+	if so.filterLocked {
+		return &ErrNotPermitted{}
+	}
+	if so.filter.Load() == nil {
+		return &ErrNoSuchFile{}
+	}
+	so.filter.Store(nil)
+	return nil
+}
+
+// GetLockFilter gets value for SO_LOCK_FILTER option.
+func (so *SocketOptions) GetLockFilter() bool {
+	so.mu.Lock()
+	defer so.mu.Unlock()
+	return so.filterLocked
+}
+
+// SetLockFilter sets value for SO_LOCK_FILTER option. Like in Linux, the
+// filter can't be unlocked once locked.
+func (so *SocketOptions) SetLockFilter(v bool) Error {
+	so.mu.Lock()
+	defer so.mu.Unlock()
+	if so.filterLocked && !v {
+		return &ErrNotPermitted{}
+	}
+	so.filterLocked = v
+	return nil
+}
+
+// TimestampingFlags are the flags of the SO_TIMESTAMPING option, which have
+// the same values as the SOF_TIMESTAMPING_* flags in Linux.
+type TimestampingFlags uint32
+
+// The flags of the SO_TIMESTAMPING option. Hardware timestamps are accepted
+// but never generated.
+const (
+	TimestampingTXHardware  TimestampingFlags = 1 << 0
+	TimestampingTXSoftware  TimestampingFlags = 1 << 1
+	TimestampingRXHardware  TimestampingFlags = 1 << 2
+	TimestampingRXSoftware  TimestampingFlags = 1 << 3
+	TimestampingSoftware    TimestampingFlags = 1 << 4
+	TimestampingSysHardware TimestampingFlags = 1 << 5
+	TimestampingRawHardware TimestampingFlags = 1 << 6
+	TimestampingOptID       TimestampingFlags = 1 << 7
+	TimestampingTXSched     TimestampingFlags = 1 << 8
+	TimestampingTXAck       TimestampingFlags = 1 << 9
+	TimestampingOptCmsg     TimestampingFlags = 1 << 10
+	TimestampingOptTSOnly   TimestampingFlags = 1 << 11
+	TimestampingOptStats    TimestampingFlags = 1 << 12
+	TimestampingOptPktInfo  TimestampingFlags = 1 << 13
+	TimestampingOptTXSWHW   TimestampingFlags = 1 << 14
+	TimestampingBindPHC     TimestampingFlags = 1 << 15
+	TimestampingOptIDTCP    TimestampingFlags = 1 << 16
+
+	// TimestampingMask is the set of valid flags.
+	TimestampingMask TimestampingFlags = 1<<17 - 1
+
+	// TimestampingTXFlags are the flags requesting TX timestamps.
+	TimestampingTXFlags = TimestampingTXSoftware | TimestampingTXSched | TimestampingTXAck
+)
+
+// GetTimestamping gets value for SO_TIMESTAMPING option.
+func (so *SocketOptions) GetTimestamping() TimestampingFlags {
+	return TimestampingFlags(so.timestamping.Load())
+}
+
+// SetTimestamping sets value for SO_TIMESTAMPING option. Like in Linux, the
+// key of the IDs of TX timestamps is only reset when TimestampingOptID is
+// newly set.
+func (so *SocketOptions) SetTimestamping(v TimestampingFlags) Error {
+	if v&^TimestampingMask != 0 {
+		return &ErrInvalidOptionValue{}
+	}
+	if v&TimestampingOptIDTCP != 0 && v&TimestampingOptID == 0 {
+		return &ErrInvalidOptionValue{}
+	}
+	if v&TimestampingOptID != 0 && so.GetTimestamping()&TimestampingOptID == 0 {
+		key, err := so.handler.OnTimestampingIDSet(v&TimestampingOptIDTCP != 0)
+		if err != nil {
+			return err
+		}
+		so.timestampingKey.Store(key)
+	}
+	so.timestamping.Store(uint32(v))
+	return nil
+}
+
+// NextTimestampingID returns the ID of the TX timestamps of the next send of
+// a datagram socket, which is zero if TimestampingOptID isn't set.
+func (so *SocketOptions) NextTimestampingID() uint32 {
+	if so.GetTimestamping()&TimestampingOptID == 0 {
+		return 0
+	}
+	return so.timestampingKey.Add(1) - 1
+}
+
+// TimestampingID returns the ID of the TX timestamps of the byte of a stream
+// socket with the given sequence number, which is zero if TimestampingOptID
+// isn't set.
+func (so *SocketOptions) TimestampingID(seq uint32) uint32 {
+	if so.GetTimestamping()&TimestampingOptID == 0 {
+		return 0
+	}
+	return seq - so.timestampingKey.Load()
+}
+
+// TXTimeOption is used by SetSockOpt/GetSockOpt for SO_TXTIME.
+//
+// +stateify savable
+type TXTimeOption struct {
+	// Enabled is true if SO_TXTIME is set, in which case packets are sent at
+	// the time given with the SCM_TXTIME control message.
+	Enabled bool
+
+	// ClockID is the clock of the transmit times.
+	ClockID int32
+
+	// DeadlineMode is true if transmit times are deadlines rather than
+	// earliest transmit times.
+	DeadlineMode bool
+
+	// ReportErrors is true if packets which miss their transmit time are
+	// reported on the error queue.
+	ReportErrors bool
+}
+
+// GetTXTime gets value for SO_TXTIME option.
+func (so *SocketOptions) GetTXTime() TXTimeOption {
+	so.mu.Lock()
+	defer so.mu.Unlock()
+	return so.txTime
+}
+
+// SetTXTime sets value for SO_TXTIME option.
+func (so *SocketOptions) SetTXTime(v TXTimeOption) {
+	so.mu.Lock()
+	defer so.mu.Unlock()
+	so.txTime = v
+}
+
 // GetExperimentOptionValue gets value for the experiment IP option header.
 func (so *SocketOptions) GetExperimentOptionValue() uint16 {
 	v := so.experimentOptionValue.Load()
@@ -569,6 +813,13 @@ const (
 
 	// SockExtErrorOriginICMP6 indicates an IPv6 ICMP error.
 	SockExtErrorOriginICMP6
+
+	// SockExtErrorOriginTimestamping indicates a TX timestamp.
+	SockExtErrorOriginTimestamping SockErrOrigin = 4
+
+	// SockExtErrorOriginZeroCopy indicates the completion of sends with
+	// MSG_ZEROCOPY.
+	SockExtErrorOriginZeroCopy SockErrOrigin = 5
 )
 
 // IsICMPErr indicates if the error originated from an ICMP error.
@@ -589,6 +840,9 @@ type SockErrorCause interface {
 
 	// Info is any extra information about the error.
 	Info() uint32
+
+	// Data is any other extra information about the error.
+	Data() uint32
 }
 
 // LocalSockError is a socket error that originated from the local host.
@@ -618,6 +872,114 @@ func (l *LocalSockError) Info() uint32 {
 	return l.info
 }
 
+// Data implements SockErrorCause.
+func (*LocalSockError) Data() uint32 {
+	return 0
+}
+
+// ZeroCopySockError is the completion notification of a range of sends with
+// MSG_ZEROCOPY.
+//
+// +stateify savable
+type ZeroCopySockError struct {
+	// Lo and Hi are the IDs of the first and last completed sends.
+	Lo uint32
+	Hi uint32
+
+	// Copied is true if the data of any of the sends was copied.
+	Copied bool
+}
+
+// ZeroCopyCodeCopied is the code of zero copy completions whose data was
+// copied, like SO_EE_CODE_ZEROCOPY_COPIED in Linux.
+const ZeroCopyCodeCopied = 1
+
+// Origin implements SockErrorCause.
+func (*ZeroCopySockError) Origin() SockErrOrigin {
+	return SockExtErrorOriginZeroCopy
+}
+
+// Type implements SockErrorCause.
+func (*ZeroCopySockError) Type() uint8 {
+	return 0
+}
+
+// Code implements SockErrorCause.
+func (z *ZeroCopySockError) Code() uint8 {
+	if z.Copied {
+		return ZeroCopyCodeCopied
+	}
+	return 0
+}
+
+// Info implements SockErrorCause.
+func (z *ZeroCopySockError) Info() uint32 {
+	return z.Lo
+}
+
+// Data implements SockErrorCause.
+func (z *ZeroCopySockError) Data() uint32 {
+	return z.Hi
+}
+
+// TXTimestampKind is the point of the transmit path at which a TX timestamp
+// was taken, like the SCM_TSTAMP_* values in Linux.
+type TXTimestampKind uint32
+
+const (
+	// TXTimestampSent is taken when the packet is handed to the link
+	// endpoint.
+	TXTimestampSent TXTimestampKind = iota
+
+	// TXTimestampSched is taken when the packet enters the queueing
+	// discipline.
+	TXTimestampSched
+
+	// TXTimestampAck is taken when all the data of the packet is
+	// acknowledged, which only applies to TCP.
+	TXTimestampAck
+)
+
+// TimestampingSockError is a TX timestamp requested with SO_TIMESTAMPING.
+//
+// +stateify savable
+type TimestampingSockError struct {
+	// Kind is the point of the transmit path at which the timestamp was
+	// taken.
+	Kind TXTimestampKind
+
+	// ID identifies the send the timestamp is for.
+	ID uint32
+
+	// Timestamp is the software timestamp.
+	Timestamp time.Time `state:".(int64)"`
+}
+
+// Origin implements SockErrorCause.
+func (*TimestampingSockError) Origin() SockErrOrigin {
+	return SockExtErrorOriginTimestamping
+}
+
+// Type implements SockErrorCause.
+func (*TimestampingSockError) Type() uint8 {
+	return 0
+}
+
+// Code implements SockErrorCause.
+func (*TimestampingSockError) Code() uint8 {
+	return 0
+}
+
+// Info implements SockErrorCause.
+func (t *TimestampingSockError) Info() uint32 {
+	return uint32(t.Kind)
+}
+
+// Data implements SockErrorCause.
+func (t *TimestampingSockError) Data() uint32 {
+	return t.ID
+}
+
 // SockError represents a queue entry in the per-socket error queue.
 //
 // +stateify savable
@@ -687,6 +1049,37 @@ func (so *SocketOptions) QueueLocalErr(err Error, net NetworkProtocolNumber, inf
 	})
 }
 
+// QueueZeroCopyCompletion queues the completion notification of the send with
+// MSG_ZEROCOPY with the given ID. Like in Linux, it is merged with the
+// notification at the back of the queue if their IDs are consecutive.
+func (so *SocketOptions) QueueZeroCopyCompletion(id uint32, copied bool, net NetworkProtocolNumber) {
+	so.errQueueMu.Lock()
+	defer so.errQueueMu.Unlock()
+	if last := so.errQueue.Back(); last != nil {
+		if z, ok := last.Cause.(*ZeroCopySockError); ok && z.Hi+1 == id && id != z.Lo {
+			z.Hi = id
+			z.Copied = z.Copied || copied
+			return
+		}
+	}
+	so.errQueue.PushBack(&SockError{
+		Cause:    &ZeroCopySockError{Lo: id, Hi: id, Copied: copied},
+		NetProto: net,
+	})
+}
+
+// QueueTXTimestamp queues a TX timestamp of a packet whose payload, if
+// TimestampingOptTSOnly isn't set, is given. Unlike other errors, TX
+// timestamps are queued regardless of IP_RECVERR.
+func (so *SocketOptions) QueueTXTimestamp(ts *TimestampingSockError, payload *buffer.View, net NetworkProtocolNumber) {
+	so.QueueErr(&SockError{
+		Cause:    ts,
+		Payload:  payload,
+		NetProto: net,
+	})
+	so.handler.WakeupErrQueueReaders()
+}
+
 // GetBindToDevice gets value for SO_BINDTODEVICE option.
 func (so *SocketOptions) GetBindToDevice() int32 {
 	return so.bindToDevice.Load()
@@ -0,0 +1,193 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stack
+
+import (
+	"container/heap"
+
+	"gvisor.dev/gvisor/pkg/tcpip"
+)
+
+var _ QueueingDiscipline = (*etfQueueingDiscipline)(nil)
+
+// etfQueueingDiscipline is a QueueingDiscipline that holds the packets with a
+// transmit time set with SCM_TXTIME until then, and passes them to the
+// queueing discipline it wraps in order of transmit time, like the Earliest
+// TxTime First (ETF) queueing discipline of Linux. Other packets are passed
+// through right away.
+//
+// +stateify savable
+type etfQueueingDiscipline struct {
+	// QueueingDiscipline is the queueing discipline packets are passed to
+	// once they are due. It is immutable.
+	QueueingDiscipline
+
+	// clock is the clock of the stack. It is immutable.
+	clock tcpip.Clock
+
+	mu etfQueueMutex `state:"nosave"`
+
+	// queue holds the packets that aren't due yet, by transmit time.
+	//
+	// +checklocks:mu
+	queue txTimeHeap
+
+	// seq is the sequence number of the next packet queued, which orders
+	// packets with the same transmit time.
+	//
+	// +checklocks:mu
+	seq uint64
+
+	// timer fires at the transmit time of the first packet of queue. It is
+	// nil until a packet is first queued.
+	//
+	// +checklocks:mu
+	timer tcpip.Timer `state:"nosave"`
+
+	// +checklocks:mu
+	closed bool
+}
+
+// newETFQueueingDiscipline returns an etfQueueingDiscipline passing packets to
+// qDisc.
+func newETFQueueingDiscipline(clock tcpip.Clock, qDisc QueueingDiscipline) *etfQueueingDiscipline {
+	return &etfQueueingDiscipline{
+		QueueingDiscipline: qDisc,
+		clock:              clock,
+	}
+}
+
+// WritePacket implements QueueingDiscipline.WritePacket.
+func (q *etfQueueingDiscipline) WritePacket(pkt *PacketBuffer) tcpip.Error {
+	if pkt.TXTime == (tcpip.MonotonicTime{}) || !q.clock.NowMonotonic().Before(pkt.TXTime) {
+		return q.QueueingDiscipline.WritePacket(pkt)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return &tcpip.ErrClosedForSend{}
+	}
+	heap.Push(&q.queue, txTimeEntry{pkt: pkt.IncRef(), seq: q.seq})
+	q.seq++
+	if q.queue[0].pkt == pkt {
+		q.armTimerLocked()
+	}
+	return nil
+}
+
+// Close implements QueueingDiscipline.Close.
+func (q *etfQueueingDiscipline) Close() {
+	q.mu.Lock()
+	q.closed = true
+	if q.timer != nil {
+		q.timer.Stop()
+	}
+	for _, e := range q.queue {
+		e.pkt.DecRef()
+	}
+	q.queue = nil
+	q.mu.Unlock()
+
+	q.QueueingDiscipline.Close()
+}
+
+// armTimerLocked sets the timer to fire at the transmit time of the first
+// packet of the queue.
+//
+// +checklocks:q.mu
+func (q *etfQueueingDiscipline) armTimerLocked() {
+	d := q.queue[0].pkt.TXTime.Sub(q.clock.NowMonotonic())
+	if q.timer == nil {
+		q.timer = q.clock.AfterFunc(d, q.dispatch)
+		return
+	}
+	q.timer.Stop()
+	q.timer.Reset(d)
+}
+
+// dispatch passes the packets that are due to the wrapped queueing
+// discipline.
+func (q *etfQueueingDiscipline) dispatch() {
+	var pkts PacketBufferList
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return
+	}
+	now := q.clock.NowMonotonic()
+	for len(q.queue) > 0 && !now.Before(q.queue[0].pkt.TXTime) {
+		pkts.PushBack(heap.Pop(&q.queue).(txTimeEntry).pkt)
+	}
+	if len(q.queue) > 0 {
+		q.armTimerLocked()
+	}
+	q.mu.Unlock()
+
+	// Write outside the lock, since the packets may be looped back and
+	// answered with packets queued here.
+	for _, pkt := range pkts.AsSlice() {
+		_ = q.QueueingDiscipline.WritePacket(pkt)
+	}
+	pkts.Reset()
+}
+
+// txTimeEntry is a packet held by an etfQueueingDiscipline.
+//
+// +stateify savable
+type txTimeEntry struct {
+	pkt *PacketBuffer
+	seq uint64
+}
+
+// txTimeHeap is a heap of packets ordered by transmit time, and then by the
+// order in which they were queued.
+type txTimeHeap []txTimeEntry
+
+var _ heap.Interface = (*txTimeHeap)(nil)
+
+// Len returns the length of h.
+func (h *txTimeHeap) Len() int {
+	return len(*h)
+}
+
+// Less determines whether the i-th element of h is less than the j-th element.
+func (h *txTimeHeap) Less(i, j int) bool {
+	a, b := (*h)[i], (*h)[j]
+	if a.pkt.TXTime != b.pkt.TXTime {
+		return a.pkt.TXTime.Before(b.pkt.TXTime)
+	}
+	return a.seq < b.seq
+}
+
+// Swap swaps the i-th and j-th elements of h.
+func (h *txTimeHeap) Swap(i, j int) {
+	(*h)[i], (*h)[j] = (*h)[j], (*h)[i]
+}
+
+// Push adds x as the last element of h.
+func (h *txTimeHeap) Push(x any) {
+	*h = append(*h, x.(txTimeEntry))
+}
+
+// Pop removes the last element of h and returns it.
+func (h *txTimeHeap) Pop() any {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	old[n-1] = txTimeEntry{}
+	*h = old[:n-1]
+	return x
+}
@@ -0,0 +1,98 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stack
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/faketime"
+)
+
+// recordingQueueingDiscipline is a QueueingDiscipline recording the hashes of
+// the packets written to it.
+type recordingQueueingDiscipline struct {
+	written []uint32
+	closed  bool
+}
+
+func (r *recordingQueueingDiscipline) WritePacket(pkt *PacketBuffer) tcpip.Error {
+	r.written = append(r.written, pkt.Hash)
+	return nil
+}
+
+func (r *recordingQueueingDiscipline) Close() {
+	r.closed = true
+}
+
+func TestETFQueueingDiscipline(t *testing.T) {
+	clock := faketime.NewManualClock()
+	var inner recordingQueueingDiscipline
+	q := newETFQueueingDiscipline(clock, &inner)
+
+	start := clock.NowMonotonic()
+	for i, txTime := range []time.Duration{
+		3 * time.Millisecond,
+		0,
+		1 * time.Millisecond,
+		2 * time.Millisecond,
+		1 * time.Millisecond,
+	} {
+		pkt := NewPacketBuffer(PacketBufferOptions{})
+		pkt.Hash = uint32(i)
+		if txTime != 0 {
+			pkt.TXTime = start.Add(txTime)
+		}
+		if err := q.WritePacket(pkt); err != nil {
+			t.Fatalf("WritePacket(packet %d): %s", i, err)
+		}
+		pkt.DecRef()
+	}
+
+	// Packets without a transmit time aren't held.
+	if diff := cmp.Diff([]uint32{1}, inner.written); diff != "" {
+		t.Fatalf("written packets mismatch before any transmit time (-want +got):\n%s", diff)
+	}
+
+	// Held packets are written at their transmit time, in order of transmit
+	// time and then of writes.
+	clock.Advance(time.Millisecond)
+	if diff := cmp.Diff([]uint32{1, 2, 4}, inner.written); diff != "" {
+		t.Fatalf("written packets mismatch after 1ms (-want +got):\n%s", diff)
+	}
+	clock.Advance(2 * time.Millisecond)
+	if diff := cmp.Diff([]uint32{1, 2, 4, 3, 0}, inner.written); diff != "" {
+		t.Fatalf("written packets mismatch after 3ms (-want +got):\n%s", diff)
+	}
+
+	// Packets held when closing are dropped.
+	pkt := NewPacketBuffer(PacketBufferOptions{})
+	pkt.Hash = 5
+	pkt.TXTime = clock.NowMonotonic().Add(time.Millisecond)
+	if err := q.WritePacket(pkt); err != nil {
+		t.Fatalf("WritePacket(packet 5): %s", err)
+	}
+	pkt.DecRef()
+	q.Close()
+	if !inner.closed {
+		t.Errorf("wrapped queueing discipline wasn't closed")
+	}
+	clock.Advance(time.Millisecond)
+	if diff := cmp.Diff([]uint32{1, 2, 4, 3, 0}, inner.written); diff != "" {
+		t.Errorf("written packets mismatch after close (-want +got):\n%s", diff)
+	}
+}
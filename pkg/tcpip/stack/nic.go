@@ -159,6 +159,7 @@ func (*delegatingQueueingDiscipline) Close() {}
 
 // WritePacket passes the packet through to the underlying LinkWriter's WritePackets.
 func (qDisc *delegatingQueueingDiscipline) WritePacket(pkt *PacketBuffer) tcpip.Error {
+	pkt.TXTimestamps.Record(tcpip.TXTimestampSent, pkt)
 	var pkts PacketBufferList
 	pkts.PushBack(pkt)
 	_, err := qDisc.LinkWriter.WritePackets(pkts)
@@ -177,6 +178,9 @@ func newNIC(stack *Stack, id tcpip.NICID, ep LinkEndpoint, opts NICOptions) *nic
 	if qDisc == nil {
 		qDisc = &delegatingQueueingDiscipline{LinkWriter: ep}
 	}
+	// Packets with a transmit time set with SCM_TXTIME are held until then
+	// before reaching the queueing discipline.
+	qDisc = newETFQueueingDiscipline(stack.Clock(), qDisc)
 
 	// TODO(b/143357959): RFC 8200 section 5 requires that IPv6 endpoints
 	// observe an MTU of at least 1280 bytes. Ensure that this requirement
@@ -429,6 +433,7 @@ func (n *nic) writeRawPacket(pkt *PacketBuffer) tcpip.Error {
 		n.DeliverLinkPacket(pkt.NetworkProtocolNumber, pkt)
 	}
 
+	pkt.TXTimestamps.Record(tcpip.TXTimestampSched, pkt)
 	if err := n.qDisc.WritePacket(pkt); err != nil {
 		if _, ok := err.(*tcpip.ErrNoBufferSpace); ok {
 			n.stats.txPacketsDroppedNoBufferSpace.Increment()
@@ -777,6 +782,9 @@ func (n *nic) DeliverNetworkPacket(protocol tcpip.NetworkProtocolNumber, pkt *Pa
 	}
 
 	pkt.RXChecksumValidated = n.NetworkLinkEndpoint.Capabilities()&CapabilityRXChecksumOffload != 0
+	if pkt.RXTimestamp.IsZero() {
+		pkt.RXTimestamp = n.stack.Clock().Now()
+	}
 
 	if n.deliverLinkPackets {
 		n.DeliverLinkPacket(protocol, pkt)
@@ -16,6 +16,7 @@ package stack
 import (
 	"fmt"
 	"io"
+	"time"
 
 	"gvisor.dev/gvisor/pkg/buffer"
 	"gvisor.dev/gvisor/pkg/sync"
@@ -161,6 +162,18 @@ type PacketBuffer struct {
 	// NetworkPacketInfo holds an incoming packet's network-layer information.
 	NetworkPacketInfo NetworkPacketInfo
 
+	// RXTimestamp is the time at which an incoming packet was received by
+	// the NIC, which is zero if it wasn't taken yet.
+	RXTimestamp time.Time `state:".(int64)"`
+
+	// TXTimestamps is the request of the sender of an outgoing packet for
+	// its TX timestamps, if any.
+	TXTimestamps *TXTimestampRequest
+
+	// TXTime is the earliest time at which an outgoing packet may be sent,
+	// as set with SCM_TXTIME. It is ignored if zero.
+	TXTime tcpip.MonotonicTime
+
 	tuple *tuple
 
 	// onRelease is a function to be run when the packet buffer is no longer
@@ -390,11 +403,23 @@ func (pk *PacketBuffer) Clone() *PacketBuffer {
 	newPk.NICID = pk.NICID
 	newPk.RXChecksumValidated = pk.RXChecksumValidated
 	newPk.NetworkPacketInfo = pk.NetworkPacketInfo
+	newPk.RXTimestamp = pk.RXTimestamp
+	newPk.TXTimestamps = pk.TXTimestamps
+	newPk.TXTime = pk.TXTime
 	newPk.tuple = pk.tuple
 	newPk.InitRefs()
 	return newPk
 }
 
+// ReceivedAt returns the time at which pk was received by the NIC, or the
+// current time if it didn't go through one.
+func (pk *PacketBuffer) ReceivedAt(clock tcpip.Clock) time.Time {
+	if pk.RXTimestamp.IsZero() {
+		return clock.Now()
+	}
+	return pk.RXTimestamp
+}
+
 // ReserveHeaderBytes prepends reserved space for headers at the front
 // of the underlying buf. Can only be called once per packet.
 func (pk *PacketBuffer) ReserveHeaderBytes(reserved int) {
@@ -549,19 +574,26 @@ func (d PacketData) Consume(size int) ([]byte, bool) {
 }
 
 // ReadTo reads bytes from d to dst. It also removes these bytes from d
-// unless peek is true.
+// unless peek is true. If dst implements buffer.ViewWriter, the bytes are
+// passed to it by reference rather than copied.
 func (d PacketData) ReadTo(dst io.Writer, peek bool) (int, error) {
 	var (
 		err  error
 		done int
 	)
+	// If possible, transfer references on the data rather than copying it.
+	vw, isViewWriter := dst.(buffer.ViewWriter)
 	offset := d.pk.dataOffset()
 	d.pk.buf.SubApply(offset, int(d.pk.buf.Size())-offset, func(v *buffer.View) {
 		if err != nil {
 			return
 		}
 		var n int
-		n, err = dst.Write(v.AsSlice())
+		if isViewWriter {
+			n, err = vw.WriteView(v.Clone())
+		} else {
+			n, err = dst.Write(v.AsSlice())
+		}
 		done += n
 		if err != nil {
 			return
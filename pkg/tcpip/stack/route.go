@@ -457,6 +457,12 @@ func (r *Route) local() bool {
 	return r.Loop() == PacketLoop || r.outgoingNIC.IsLoopback()
 }
 
+// DeliversLocally returns true if packets written to the route may be delivered
+// to local endpoints.
+func (r *Route) DeliversLocally() bool {
+	return r.Loop()&PacketLoop != 0 || r.outgoingNIC.IsLoopback()
+}
+
 // IsResolutionRequired returns true if Resolve() must be called to resolve
 // the link address before the route can be written to.
 //
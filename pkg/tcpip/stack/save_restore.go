@@ -27,3 +27,16 @@ func (s *Stack) afterLoad(context.Context) {
 	s.insecureRNG = rand.New(rand.NewSource(time.Now().UnixNano()))
 	s.secureRNG = cryptorand.RNGFrom(cryptorand.Reader)
 }
+
+func (pk *PacketBuffer) saveRXTimestamp() int64 {
+	if pk.RXTimestamp.IsZero() {
+		return 0
+	}
+	return pk.RXTimestamp.UnixNano()
+}
+
+func (pk *PacketBuffer) loadRXTimestamp(_ context.Context, nsec int64) {
+	if nsec != 0 {
+		pk.RXTimestamp = time.Unix(0, nsec)
+	}
+}
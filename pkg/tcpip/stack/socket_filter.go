@@ -0,0 +1,69 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stack
+
+import (
+	"gvisor.dev/gvisor/pkg/tcpip"
+)
+
+// SocketFilterStart is the header at which the packets seen by a socket filter
+// start.
+type SocketFilterStart int
+
+const (
+	// SocketFilterLinkHeader is used by packet sockets of type SOCK_RAW.
+	SocketFilterLinkHeader SocketFilterStart = iota
+
+	// SocketFilterNetworkHeader is used by packet sockets of type
+	// SOCK_DGRAM and IPv4 raw sockets.
+	SocketFilterNetworkHeader
+
+	// SocketFilterTransportHeader is used by IPv6 raw sockets, and UDP and
+	// TCP sockets.
+	SocketFilterTransportHeader
+)
+
+// RunSocketFilter runs the filter attached to a socket with options so, if
+// any, on pkt starting at its header start. It returns the number of bytes
+// from start to keep, and false if pkt must be dropped.
+func RunSocketFilter(so *tcpip.SocketOptions, pkt *PacketBuffer, start SocketFilterStart) (int, bool) {
+	var hdrs [3][]byte
+	switch start {
+	case SocketFilterLinkHeader:
+		hdrs[0] = pkt.LinkHeader().Slice()
+		fallthrough
+	case SocketFilterNetworkHeader:
+		hdrs[1] = pkt.NetworkHeader().Slice()
+		fallthrough
+	case SocketFilterTransportHeader:
+		hdrs[2] = pkt.TransportHeader().Slice()
+	}
+	size := len(hdrs[0]) + len(hdrs[1]) + len(hdrs[2]) + pkt.Data().Size()
+
+	f := so.GetFilter()
+	if f == nil {
+		return size, true
+	}
+	in := make([]byte, 0, size)
+	for _, h := range hdrs {
+		in = append(in, h...)
+	}
+	in = append(in, pkt.Data().AsRange().ToSlice()...)
+	n := int(f.Run(in))
+	if n == 0 {
+		return 0, false
+	}
+	return min(n, size), true
+}
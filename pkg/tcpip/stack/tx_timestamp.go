@@ -0,0 +1,88 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stack
+
+import (
+	"gvisor.dev/gvisor/pkg/buffer"
+	"gvisor.dev/gvisor/pkg/tcpip"
+)
+
+// TXTimestampRequest is attached to an outgoing packet whose sender asked for
+// TX timestamps with SO_TIMESTAMPING.
+//
+// +stateify savable
+type TXTimestampRequest struct {
+	// Ops are the socket options of the sender, on whose error queue the
+	// timestamps are queued.
+	Ops *tcpip.SocketOptions
+
+	// Flags are the flags of SO_TIMESTAMPING when the packet was sent.
+	Flags tcpip.TimestampingFlags
+
+	// ID identifies the send the packet is for.
+	ID uint32
+
+	// NetProto is the network protocol of the sender.
+	NetProto tcpip.NetworkProtocolNumber
+
+	// Clock is the clock timestamps are taken from.
+	Clock tcpip.Clock
+}
+
+// NewTXTimestampRequest returns the request for the TX timestamps of a packet
+// sent by a socket with options so, or nil if the socket doesn't want any. The
+// caller is responsible for setting its ID.
+func NewTXTimestampRequest(so *tcpip.SocketOptions, clock tcpip.Clock, netProto tcpip.NetworkProtocolNumber) *TXTimestampRequest {
+	flags := so.GetTimestamping()
+	if flags&tcpip.TimestampingTXFlags == 0 {
+		return nil
+	}
+	return &TXTimestampRequest{
+		Ops:      so,
+		Flags:    flags,
+		NetProto: netProto,
+		Clock:    clock,
+	}
+}
+
+// Record queues a TX timestamp of the given kind for pkt on the error queue
+// of the sender, if it asked for it.
+func (r *TXTimestampRequest) Record(kind tcpip.TXTimestampKind, pkt *PacketBuffer) {
+	if r == nil || r.Flags&txTimestampFlag(kind) == 0 {
+		return
+	}
+	var payload *buffer.View
+	if r.Flags&tcpip.TimestampingOptTSOnly == 0 {
+		payload = pkt.Data().AsRange().ToView()
+	}
+	r.Ops.QueueTXTimestamp(&tcpip.TimestampingSockError{
+		Kind:      kind,
+		ID:        r.ID,
+		Timestamp: r.Clock.Now(),
+	}, payload, r.NetProto)
+}
+
+func txTimestampFlag(kind tcpip.TXTimestampKind) tcpip.TimestampingFlags {
+	switch kind {
+	case tcpip.TXTimestampSent:
+		return tcpip.TimestampingTXSoftware
+	case tcpip.TXTimestampSched:
+		return tcpip.TimestampingTXSched
+	case tcpip.TXTimestampAck:
+		return tcpip.TimestampingTXAck
+	default:
+		return 0
+	}
+}
@@ -42,6 +42,7 @@ import (
 	"time"
 
 	"gvisor.dev/gvisor/pkg/atomicbitops"
+	"gvisor.dev/gvisor/pkg/buffer"
 	"gvisor.dev/gvisor/pkg/rand"
 	"gvisor.dev/gvisor/pkg/sync"
 	"gvisor.dev/gvisor/pkg/waiter"
@@ -560,6 +561,17 @@ type Payloader interface {
 	Len() int
 }
 
+// ZeroCopyPayloader is a Payloader whose data may be referenced by the
+// endpoint instead of being copied, as requested with MSG_ZEROCOPY.
+type ZeroCopyPayloader interface {
+	Payloader
+
+	// ReadBuffer reads up to n bytes into a buffer. The returned buffer may
+	// reference the payloader's data directly, unless copy is true, in which
+	// case the data is copied.
+	ReadBuffer(n int, copy bool) (buffer.Buffer, error)
+}
+
 var _ Payloader = (*bytes.Buffer)(nil)
 var _ Payloader = (*bytes.Reader)(nil)
 
@@ -623,6 +635,13 @@ type SendableControlMessages struct {
 
 	// IPv6PacketInfo holds interface and address data on an incoming packet.
 	IPv6PacketInfo IPv6PacketInfo
+
+	// HasTXTime indicates whether TXTime is set.
+	HasTXTime bool
+
+	// TXTime is the earliest time at which the associated packet may be
+	// sent, as set with SCM_TXTIME.
+	TXTime MonotonicTime
 }
 
 // ReceivableControlMessages contains socket control messages that can be
@@ -878,6 +897,15 @@ type EndpointWithPreflight interface {
 	Preflight(WriteOptions) Error
 }
 
+// EndpointWithSoftAbort is an optional interface implemented by connectionless
+// endpoints that can be aborted without being closed.
+type EndpointWithSoftAbort interface {
+	// SoftAbort reports err as a pending error and disconnects the
+	// endpoint, which remains usable. It is equivalent to Linux's
+	// udp_abort().
+	SoftAbort(err Error)
+}
+
 // LinkPacketInfo holds Link layer information for a received packet.
 //
 // +stateify savable
@@ -922,6 +950,11 @@ type WriteOptions struct {
 
 	// ControlMessages contains optional overrides used when writing a packet.
 	ControlMessages SendableControlMessages
+
+	// FastOpen has the same semantics as Linux's MSG_FASTOPEN: if the TCP
+	// endpoint is not connected, it connects to To with TCP Fast Open and
+	// sends the written data in the SYN if possible.
+	FastOpen bool
 }
 
 // SockOptInt represents socket options which values have the int type.
@@ -993,6 +1026,21 @@ const (
 	// NOTE: This option is currently only stubed out and is a no-op
 	TCPWindowClampOption
 
+	// TCPFastOpenOption is used by SetSockOptInt/GetSockOptInt to specify
+	// the maximum number of pending TCP Fast Open connections of a listening
+	// endpoint. Zero disables TCP Fast Open on the endpoint.
+	TCPFastOpenOption
+
+	// TCPFastOpenConnectOption is used by SetSockOptInt/GetSockOptInt to
+	// defer connecting until the first write so that data can be sent in the
+	// SYN with TCP Fast Open.
+	TCPFastOpenConnectOption
+
+	// TCPFastOpenNoCookieOption is used by SetSockOptInt/GetSockOptInt to
+	// allow data to be sent or accepted in the SYN without a TCP Fast Open
+	// cookie.
+	TCPFastOpenNoCookieOption
+
 	// IPv6Checksum is used to request the stack to populate and validate the IPv6
 	// checksum for transport level headers.
 	IPv6Checksum
@@ -1093,6 +1141,58 @@ func (*TCPAlwaysUseSynCookies) isGettableTransportProtocolOption() {}
 
 func (*TCPAlwaysUseSynCookies) isSettableTransportProtocolOption() {}
 
+// TCPFastOpen is the TCP Fast Open setting of the stack, a bitmask of the
+// TCPFastOpen* values below. It is analogous to /proc/sys/net/ipv4/tcp_fastopen
+// in Linux.
+type TCPFastOpen int32
+
+func (*TCPFastOpen) isGettableTransportProtocolOption() {}
+
+func (*TCPFastOpen) isSettableTransportProtocolOption() {}
+
+const (
+	// TCPFastOpenClient enables sending data in the SYN when connecting.
+	TCPFastOpenClient TCPFastOpen = 0x1
+
+	// TCPFastOpenServer enables accepting data in the SYN on listening
+	// endpoints that set a TCP Fast Open queue length.
+	TCPFastOpenServer TCPFastOpen = 0x2
+
+	// TCPFastOpenClientNoCookie enables sending data in the SYN without a
+	// cookie.
+	TCPFastOpenClientNoCookie TCPFastOpen = 0x4
+
+	// TCPFastOpenServerNoCookie enables accepting data in the SYN without a
+	// cookie.
+	TCPFastOpenServerNoCookie TCPFastOpen = 0x200
+
+	// TCPFastOpenServerAnyListener enables TCP Fast Open on all listening
+	// endpoints, using the listen backlog as the TCP Fast Open queue length of
+	// endpoints that don't set one.
+	TCPFastOpenServerAnyListener TCPFastOpen = 0x400
+)
+
+// TCPECNOption is the Explicit Congestion Notification setting of the stack,
+// one of the TCPECN* values below. It is analogous to
+// /proc/sys/net/ipv4/tcp_ecn in Linux.
+type TCPECNOption int32
+
+func (*TCPECNOption) isGettableTransportProtocolOption() {}
+
+func (*TCPECNOption) isSettableTransportProtocolOption() {}
+
+const (
+	// TCPECNDisabled disables ECN.
+	TCPECNDisabled TCPECNOption = iota
+
+	// TCPECNEnabled requests ECN when connecting, and accepts it when
+	// requested by incoming connections.
+	TCPECNEnabled
+
+	// TCPECNServer only accepts ECN when requested by incoming connections.
+	TCPECNServer
+)
+
 const (
 	// TCPRACKLossDetection indicates RACK is used for loss detection and
 	// recovery.
@@ -1268,10 +1368,234 @@ type TCPInfoOption struct {
 
 	// ReorderSeen indicates if reordering is seen in the endpoint.
 	ReorderSeen bool
+
+	// ReorderCount is the number of segments that were detected as
+	// delivered out of order.
+	ReorderCount uint32
+
+	// Reordering is the number of duplicate ACKs after which a segment is
+	// considered lost.
+	Reordering uint32
+
+	// Backoff is the number of consecutive retransmission timeouts since
+	// new data was last acknowledged.
+	Backoff uint32
+
+	// Probes is the number of unacknowledged zero window probes.
+	Probes uint32
+
+	// TimestampsEnabled indicates if the timestamp option was negotiated.
+	TimestampsEnabled bool
+
+	// SACKPermitted indicates if the SACK permitted option was negotiated.
+	SACKPermitted bool
+
+	// ECN indicates if ECN was negotiated.
+	ECN bool
+
+	// ECNSeen indicates if a segment marked with congestion experienced
+	// was received.
+	ECNSeen bool
+
+	// SndWndScale is the scale applied to the windows sent by the peer.
+	SndWndScale uint8
+
+	// RcvWndScale is the scale applied to the windows sent to the peer.
+	RcvWndScale uint8
+
+	// SndMSS is the maximum payload size of segments sent.
+	SndMSS uint32
+
+	// RcvMSS is the MSS advertised to the peer.
+	RcvMSS uint32
+
+	// Unacked is the number of packets sent but not acknowledged.
+	Unacked uint32
+
+	// Sacked is the number of packets selectively acknowledged.
+	Sacked uint32
+
+	// Lost is the number of packets marked as lost.
+	Lost uint32
+
+	// Retrans is the number of retransmitted packets that are not yet
+	// acknowledged.
+	Retrans uint32
+
+	// TotalRetrans is the number of retransmitted packets since the start
+	// of the connection.
+	TotalRetrans uint32
+
+	// LastDataSent is the time elapsed since the last segment was sent.
+	LastDataSent time.Duration
+
+	// LastDataRecv is the time elapsed since the last segment carrying
+	// data was received.
+	LastDataRecv time.Duration
+
+	// LastAckRecv is the time elapsed since the last acknowledgement was
+	// received.
+	LastAckRecv time.Duration
+
+	// MinRTT is the minimum round trip time measured.
+	MinRTT time.Duration
+
+	// BytesAcked is the number of bytes acknowledged by the peer.
+	BytesAcked uint64
+
+	// BytesReceived is the number of bytes received in order.
+	BytesReceived uint64
+
+	// BytesSent is the number of data bytes sent, including
+	// retransmissions.
+	BytesSent uint64
+
+	// BytesRetrans is the number of data bytes retransmitted.
+	BytesRetrans uint64
+
+	// SegsOut is the number of segments sent.
+	SegsOut uint32
+
+	// SegsIn is the number of segments received.
+	SegsIn uint32
+
+	// DataSegsOut is the number of packets carrying data sent, including
+	// retransmissions.
+	DataSegsOut uint32
+
+	// DataSegsIn is the number of segments carrying data received.
+	DataSegsIn uint32
+
+	// NotSentBytes is the number of bytes written but not yet sent.
+	NotSentBytes uint32
+
+	// Delivered is the number of packets delivered to the peer.
+	Delivered uint32
+
+	// DeliveryRate is the most recent delivery rate, in bytes per second.
+	DeliveryRate uint64
+
+	// DeliveryRateAppLimited indicates if DeliveryRate was measured while
+	// the endpoint was limited by the application.
+	DeliveryRateAppLimited bool
+
+	// PacingRate is the rate at which the endpoint paces data, in bytes per
+	// second, or zero if it isn't paced.
+	PacingRate uint64
+
+	// DSACKDups is the number of packets reported as received more than
+	// once with DSACK.
+	DSACKDups uint32
+
+	// SynDataAcked indicates if data sent or received in the SYN with TCP
+	// Fast Open was acknowledged.
+	SynDataAcked bool
+
+	// FastOpenClientFail is the reason the data sent with TCP Fast Open was
+	// not acknowledged in the SYN-ACK, if any.
+	FastOpenClientFail TCPFastOpenClientFail
 }
 
 func (*TCPInfoOption) isGettableSocketOption() {}
 
+// TCPCCInfoOption is used by GetSockOpt to get information specific to the
+// congestion control algorithm of a TCP endpoint. Like in Linux, only some
+// algorithms report such information.
+type TCPCCInfoOption struct {
+	// Algorithm is the congestion control algorithm in use.
+	Algorithm CongestionControlOption
+
+	// BBR is the state of the BBR algorithm, valid if Algorithm is "bbr".
+	BBR TCPBBRInfo
+}
+
+// TCPBBRInfo is the information reported by the BBR congestion control
+// algorithm.
+type TCPBBRInfo struct {
+	// Bandwidth is the estimated bottleneck bandwidth, in bytes per second.
+	Bandwidth uint64
+
+	// MinRTT is the estimated minimum round-trip time.
+	MinRTT time.Duration
+
+	// PacingGain is the current pacing gain, scaled by 256.
+	PacingGain uint32
+
+	// CwndGain is the current congestion window gain, scaled by 256.
+	CwndGain uint32
+}
+
+func (*TCPCCInfoOption) isGettableSocketOption() {}
+
+// TCPFastOpenClientFail is the reason data could not be sent with TCP Fast
+// Open.
+type TCPFastOpenClientFail uint8
+
+// The possible values of TCPFastOpenClientFail, from enum
+// tcp_fastopen_client_fail in Linux.
+const (
+	// TCPFastOpenStatusUnspec indicates TCP Fast Open didn't fail, or the
+	// failure is unknown.
+	TCPFastOpenStatusUnspec TCPFastOpenClientFail = iota
+
+	// TCPFastOpenCookieUnavailable indicates no cookie was available to send
+	// data in the SYN.
+	TCPFastOpenCookieUnavailable
+
+	// TCPFastOpenDataNotAcked indicates the data sent in the SYN was not
+	// acknowledged by the SYN-ACK.
+	TCPFastOpenDataNotAcked
+
+	// TCPFastOpenSynRetransmitted indicates the SYN carrying data was
+	// retransmitted without it.
+	TCPFastOpenSynRetransmitted
+)
+
+// TCPFastOpenKeyLength is the length of a TCP Fast Open key.
+const TCPFastOpenKeyLength = 16
+
+// TCPFastOpenKeyOption is used by SetSockOpt/GetSockOpt to set/get the keys
+// used by a listening endpoint to generate and validate TCP Fast Open cookies.
+type TCPFastOpenKeyOption struct {
+	// Primary is the key used to generate and validate cookies.
+	Primary [TCPFastOpenKeyLength]byte
+
+	// Backup is a key only used to validate cookies. It is only valid if
+	// HasBackup is true.
+	Backup [TCPFastOpenKeyLength]byte
+
+	// HasBackup indicates if Backup is valid.
+	HasBackup bool
+}
+
+func (*TCPFastOpenKeyOption) isGettableSocketOption() {}
+
+func (*TCPFastOpenKeyOption) isSettableSocketOption() {}
+
+// TCPMD5SigKeyMaxLength is the maximum length of a TCP MD5 signature key.
+const TCPMD5SigKeyMaxLength = 80
+
+// TCPMD5SigOption is used by SetSockOpt to add, replace or remove the key used
+// to sign and verify the segments exchanged with a peer with the TCP MD5
+// signature option, as described in RFC 2385.
+type TCPMD5SigOption struct {
+	// Addr is the address of the peer.
+	Addr Address
+
+	// PrefixLen is the length of the prefix of Addr matched against the
+	// address of peers.
+	PrefixLen int
+
+	// NIC is the NIC through which the peer must be reached for the key to
+	// apply, or zero if the key applies to any NIC.
+	NIC NICID
+
+	// Key is the key. The key of the peer is removed if it's empty.
+	Key []byte
+}
+
+func (*TCPMD5SigOption) isSettableSocketOption() {}
+
 // KeepaliveIdleOption is used by SetSockOpt/GetSockOpt to specify the time a
 // connection must remain idle before the first TCP keepalive packet is sent.
 // Once this time is reached, KeepaliveIntervalOption is used instead.
@@ -1423,12 +1747,6 @@ type RemoveMembershipOption MembershipOption
 
 func (*RemoveMembershipOption) isSettableSocketOption() {}
 
-// SocketDetachFilterOption is used by SetSockOpt to detach a previously attached
-// classic BPF filter on a given endpoint.
-type SocketDetachFilterOption int
-
-func (*SocketDetachFilterOption) isSettableSocketOption() {}
-
 // OriginalDestinationOption is used to get the original destination address
 // and port of a redirected packet.
 type OriginalDestinationOption FullAddress
@@ -2282,6 +2600,18 @@ type TCPStats struct {
 	// dropped due to exceeding the maximum number of in-flight connection
 	// requests.
 	ForwardMaxInFlightDrop *StatCounter
+
+	// MD5NotFound is the number of segments dropped because they didn't
+	// carry the TCP MD5 signature option expected from the peer.
+	MD5NotFound *StatCounter
+
+	// MD5Unexpected is the number of segments dropped because they carried
+	// the TCP MD5 signature option but no key is set for the peer.
+	MD5Unexpected *StatCounter
+
+	// MD5Failure is the number of segments dropped because their TCP MD5
+	// signature didn't match.
+	MD5Failure *StatCounter
 }
 
 // UDPStats collects UDP-specific stats.
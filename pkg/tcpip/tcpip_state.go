@@ -26,3 +26,21 @@ func (c *ReceivableControlMessages) saveTimestamp() int64 {
 func (c *ReceivableControlMessages) loadTimestamp(_ context.Context, nsec int64) {
 	c.Timestamp = time.Unix(0, nsec)
 }
+
+func (t *TimestampingSockError) saveTimestamp() int64 {
+	return t.Timestamp.UnixNano()
+}
+
+func (t *TimestampingSockError) loadTimestamp(_ context.Context, nsec int64) {
+	t.Timestamp = time.Unix(0, nsec)
+}
+
+func (so *SocketOptions) saveFilter() SocketFilter {
+	return so.GetFilter()
+}
+
+func (so *SocketOptions) loadFilter(_ context.Context, f SocketFilter) {
+	if f != nil {
+		so.filter.Store(&f)
+	}
+}
@@ -766,7 +766,7 @@ func (e *endpoint) HandlePacket(id stack.TransportEndpointID, pkt *stack.PacketB
 	e.rcvList.PushBack(packet)
 	e.rcvBufSize += packet.data.Data().Size()
 
-	packet.receivedAt = e.stack.Clock().Now()
+	packet.receivedAt = pkt.ReceivedAt(e.stack.Clock())
 
 	e.rcvMu.Unlock()
 	e.stats.PacketsReceived.Increment()
@@ -291,6 +291,16 @@ func (c *WriteContext) TryNewPacketBufferFromPayloader(reserveHdrBytes int, payl
 	if !e.hasSendSpaceRLocked() {
 		return nil
 	}
+	if zp, ok := payloader.(tcpip.ZeroCopyPayloader); ok {
+		// Like in Linux, data that is delivered locally is always copied since
+		// it may be held by the receiver indefinitely.
+		data, err := zp.ReadBuffer(payloader.Len(), c.route.DeliversLocally())
+		if err != nil {
+			data.Release()
+			return nil
+		}
+		return c.newPacketBufferLocked(reserveHdrBytes, data)
+	}
 	var data buffer.Buffer
 	if _, err := data.WriteFromReader(payloader, int64(payloader.Len())); err != nil {
 		data.Release()
@@ -1021,9 +1031,6 @@ func (e *Endpoint) SetSockOpt(opt tcpip.SettableSocketOption) tcpip.Error {
 		}
 
 		delete(e.multicastMemberships, memToRemove)
-
-	case *tcpip.SocketDetachFilterOption:
-		return nil
 	}
 	return nil
 }
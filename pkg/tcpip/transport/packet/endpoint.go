@@ -389,8 +389,6 @@ func (ep *endpoint) Readiness(mask waiter.EventMask) waiter.EventMask {
 // SetSockOpt implements tcpip.Endpoint.SetSockOpt.
 func (ep *endpoint) SetSockOpt(opt tcpip.SettableSocketOption) tcpip.Error {
 	switch opt.(type) {
-	case *tcpip.SocketDetachFilterOption:
-		return nil
 	case *tcpip.TpacketReq:
 		ep.rcvMu.Lock()
 		defer ep.rcvMu.Unlock()
@@ -490,6 +488,18 @@ func (ep *endpoint) GetSockOptInt(opt tcpip.SockOptInt) (int, tcpip.Error) {
 
 // handlePacket implements stack.PacketEndpoint.HandlePacket
 func (ep *endpoint) HandlePacket(nicID tcpip.NICID, netProto tcpip.NetworkProtocolNumber, pkt *stack.PacketBuffer) {
+	// Cooked packet endpoints don't include the link-headers in the packets
+	// seen by the attached socket filter either.
+	start := stack.SocketFilterLinkHeader
+	if ep.cooked {
+		start = stack.SocketFilterNetworkHeader
+	}
+	snapLen, ok := stack.RunSocketFilter(&ep.ops, pkt, start)
+	if !ok {
+		ep.stack.Stats().DroppedPackets.Increment()
+		return
+	}
+
 	ep.packetMmapMu.RLock()
 	if ep.packetMMapEp != nil {
 		if handled := ep.packetMMapEp.HandlePacket(nicID, netProto, pkt); handled {
@@ -499,7 +509,7 @@ func (ep *endpoint) HandlePacket(nicID tcpip.NICID, netProto tcpip.NetworkProtoc
 	}
 	ep.packetMmapMu.RUnlock()
 
-	wasEmpty := ep.handlePacketInner(nicID, netProto, pkt)
+	wasEmpty := ep.handlePacketInner(nicID, netProto, pkt, snapLen)
 
 	ep.stats.PacketsReceived.Increment()
 	// Notify waiters that there's data to be read.
@@ -509,10 +519,12 @@ func (ep *endpoint) HandlePacket(nicID tcpip.NICID, netProto tcpip.NetworkProtoc
 }
 
 func (ep *endpoint) HandlePacketMMapCopy(nicID tcpip.NICID, netProto tcpip.NetworkProtocolNumber, pkt *stack.PacketBuffer) {
-	_ = ep.handlePacketInner(nicID, netProto, pkt)
+	_ = ep.handlePacketInner(nicID, netProto, pkt, pkt.Size())
 }
 
-func (ep *endpoint) handlePacketInner(nicID tcpip.NICID, netProto tcpip.NetworkProtocolNumber, pkt *stack.PacketBuffer) bool {
+// handlePacketInner queues pkt, truncated to snapLen bytes from the headers
+// seen by the attached socket filter.
+func (ep *endpoint) handlePacketInner(nicID tcpip.NICID, netProto tcpip.NetworkProtocolNumber, pkt *stack.PacketBuffer, snapLen int) bool {
 	ep.rcvMu.Lock()
 
 	// Drop the packet if our buffer is currently full.
@@ -541,7 +553,7 @@ func (ep *endpoint) handlePacketInner(nicID tcpip.NICID, netProto tcpip.NetworkP
 		senderAddr: tcpip.FullAddress{
 			NIC: nicID,
 		},
-		receivedAt: ep.stack.Clock().Now(),
+		receivedAt: pkt.ReceivedAt(ep.stack.Clock()),
 	}
 
 	if len(pkt.LinkHeader().Slice()) != 0 {
@@ -555,6 +567,9 @@ func (ep *endpoint) handlePacketInner(nicID tcpip.NICID, netProto tcpip.NetworkP
 		// Cooked packet endpoints don't include the link-headers in received
 		// packets.
 		pktBuf.TrimFront(int64(len(pkt.LinkHeader().Slice()) + len(pkt.VirtioNetHeader().Slice())))
+		pktBuf.Truncate(int64(snapLen))
+	} else {
+		pktBuf.Truncate(int64(len(pkt.VirtioNetHeader().Slice()) + snapLen))
 	}
 	rcvdPkt.data = stack.NewPacketBuffer(stack.PacketBufferOptions{Payload: pktBuf})
 
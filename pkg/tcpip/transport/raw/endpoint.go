@@ -487,9 +487,6 @@ func (e *endpoint) Readiness(mask waiter.EventMask) waiter.EventMask {
 // SetSockOpt implements tcpip.Endpoint.SetSockOpt.
 func (e *endpoint) SetSockOpt(opt tcpip.SettableSocketOption) tcpip.Error {
 	switch opt := opt.(type) {
-	case *tcpip.SocketDetachFilterOption:
-		return nil
-
 	case *tcpip.ICMPv6Filter:
 		if e.net.NetProto() != header.IPv6ProtocolNumber {
 			return &tcpip.ErrUnknownProtocolOption{}
@@ -724,8 +721,19 @@ func (e *endpoint) HandlePacket(pkt *stack.PacketBuffer) {
 			panic(fmt.Sprintf("unrecognized protocol number = %d", info.NetProto))
 		}
 
+		// The attached socket filter sees the same bytes as the reader.
+		start := stack.SocketFilterNetworkHeader
+		if info.NetProto == header.IPv6ProtocolNumber {
+			start = stack.SocketFilterTransportHeader
+		}
+		snapLen, ok := stack.RunSocketFilter(&e.ops, pkt, start)
+		if !ok {
+			return false
+		}
+		combinedBuf.Truncate(int64(snapLen))
+
 		packet.data = stack.NewPacketBuffer(stack.PacketBufferOptions{Payload: combinedBuf.Clone()})
-		packet.receivedAt = e.stack.Clock().Now()
+		packet.receivedAt = pkt.ReceivedAt(e.stack.Clock())
 
 		e.rcvList.PushBack(packet)
 		e.rcvBufSize += packet.data.Data().Size()
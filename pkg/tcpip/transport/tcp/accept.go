@@ -207,6 +207,9 @@ func (l *listenContext) createConnectingEndpoint(s *segment, rcvdSynOpts header.
 
 	n.maybeEnableTimestamp(rcvdSynOpts)
 	n.maybeEnableSACKPermitted(rcvdSynOpts)
+	if l.listenEP != nil {
+		n.inheritMD5Key(l.listenEP, s)
+	}
 
 	n.initGSO()
 
@@ -231,7 +234,8 @@ func (l *listenContext) createConnectingEndpoint(s *segment, rcvdSynOpts header.
 // NOTE: h.ep.mu is not held and must be acquired if any state needs to be
 // modified.
 //
-// Precondition: if l.listenEP != nil, l.listenEP.mu must be locked.
+// Precondition: if l.listenEP != nil, l.listenEP.mu and l.listenEP.acceptMu
+// must be locked.
 func (l *listenContext) startHandshake(s *segment, opts header.TCPSynOptions, queue *waiter.Queue, owner tcpip.PacketOwner) (h *handshake, _ tcpip.Error) {
 	// Create new endpoint.
 	irs := s.sequenceNumber
@@ -291,7 +295,11 @@ func (l *listenContext) startHandshake(s *segment, opts header.TCPSynOptions, qu
 
 	// Initialize and start the handshake.
 	h = ep.newPassiveHandshake(isn, irs, opts, deferAccept)
+	h.acceptECN(s)
 	h.listenEP = l.listenEP
+	if l.listenEP != nil {
+		l.listenEP.acceptFastOpenLocked(h, s, opts) // +checklocksforce
+	}
 	h.start()
 	h.ep.mu.Unlock()
 	return h, nil
@@ -362,6 +370,9 @@ func (e *Endpoint) propagateInheritableOptionsLocked(n *Endpoint) {
 	n.boundBindToDevice = e.boundBindToDevice
 	n.boundPortFlags = e.boundPortFlags
 	n.userMSS = e.userMSS
+	// The new endpoint isn't locked yet, so this can't fail.
+	_ = n.ops.SetFilter(e.ops.GetFilter())
+	_ = n.ops.SetLockFilter(e.ops.GetLockFilter())
 }
 
 // reserveTupleLocked reserves an accepted endpoint's tuple.
@@ -418,8 +429,13 @@ type acceptQueue struct {
 	endpoints list.List `state:".([]*Endpoint)"`
 
 	// pendingEndpoints is a set of all endpoints for which a handshake is
-	// in progress.
-	pendingEndpoints map[*Endpoint]struct{}
+	// in progress, mapped to whether data in their SYN was accepted using
+	// TCP Fast Open.
+	pendingEndpoints map[*Endpoint]bool
+
+	// fastOpenPending is the number of endpoints in pendingEndpoints that
+	// accepted data in their SYN using TCP Fast Open.
+	fastOpenPending int
 
 	// capacity is the maximum number of endpoints that can be in endpoints.
 	capacity int
@@ -429,6 +445,26 @@ func (a *acceptQueue) isFull() bool {
 	return a.endpoints.Len() >= a.capacity
 }
 
+// addPending adds ep to the set of endpoints for which a handshake is in
+// progress.
+func (a *acceptQueue) addPending(ep *Endpoint, fastOpen bool) {
+	a.pendingEndpoints[ep] = fastOpen
+	if fastOpen {
+		a.fastOpenPending++
+	}
+}
+
+// removePending removes ep from the set of endpoints for which a handshake is
+// in progress.
+func (a *acceptQueue) removePending(ep *Endpoint) {
+	if fastOpen, ok := a.pendingEndpoints[ep]; ok {
+		delete(a.pendingEndpoints, ep)
+		if fastOpen {
+			a.fastOpenPending--
+		}
+	}
+}
+
 // handleListenSegment is called when a listening endpoint receives a segment
 // and needs to handle it.
 //
@@ -443,7 +479,7 @@ func (e *Endpoint) handleListenSegment(ctx *listenContext, s *segment) tcpip.Err
 		// RFC 793 section 3.4 page 35 (figure 12) outlines that a RST
 		// must be sent in response to a SYN-ACK while in the listen
 		// state to prevent completing a handshake from an old SYN.
-		return replyWithReset(e.stack, s, e.sendTOS, e.ipv4TTL, e.ipv6HopLimit)
+		return replyWithReset(e.stack, s, e.sendTOS, e.ipv4TTL, e.ipv6HopLimit, e.md5KeyFor(s.pkt.Network().SourceAddress(), s.pkt.NICID))
 	}
 
 	switch {
@@ -486,7 +522,7 @@ func (e *Endpoint) handleListenSegment(ctx *listenContext, s *segment) tcpip.Err
 				e.stats.FailedConnectionAttempts.Increment()
 				return false, err
 			}
-			e.acceptQueue.pendingEndpoints[h.ep] = struct{}{}
+			e.acceptQueue.addPending(h.ep, h.fastOpen)
 
 			return false, nil
 		}()
@@ -513,10 +549,11 @@ func (e *Endpoint) handleListenSegment(ctx *listenContext, s *segment) tcpip.Err
 		// Use the user supplied MSS on the listening socket for
 		// new connections, if available.
 		synOpts := header.TCPSynOptions{
-			WS:    -1,
-			TS:    opts.TS,
-			TSEcr: opts.TSVal,
-			MSS:   calculateAdvertisedMSS(e.userMSS, route),
+			WS:     -1,
+			TS:     opts.TS,
+			TSEcr:  opts.TSVal,
+			MSS:    calculateAdvertisedMSS(e.userMSS, route),
+			MD5Sig: e.md5KeyForRoute(route) != nil,
 		}
 		if opts.TS {
 			offset := e.protocol.tsOffset(net.DestinationAddress(), net.SourceAddress())
@@ -607,7 +644,7 @@ func (e *Endpoint) handleListenSegment(ctx *listenContext, s *segment) tcpip.Err
 			// The only time we should reach here when a connection
 			// was opened and closed really quickly and a delayed
 			// ACK was received from the sender.
-			return replyWithReset(e.stack, s, e.sendTOS, e.ipv4TTL, e.ipv6HopLimit)
+			return replyWithReset(e.stack, s, e.sendTOS, e.ipv4TTL, e.ipv6HopLimit, e.md5KeyFor(s.pkt.Network().SourceAddress(), s.pkt.NICID))
 		}
 
 		// Keep hold of acceptMu until the new endpoint is in the accept queue (or
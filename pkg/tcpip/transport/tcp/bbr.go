@@ -0,0 +1,652 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcp
+
+import (
+	"math"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/tcpip"
+)
+
+// This file implements version 1 of the BBR congestion control algorithm, as
+// described in https://datatracker.ietf.org/doc/html/draft-cardwell-iccrg-bbr-congestion-control-00
+// and implemented by Linux in net/ipv4/tcp_bbr.c. The long-term bandwidth
+// sampling used by Linux to detect policers is not implemented.
+
+const (
+	// bbrHighGain is the gain used in startup to double the sending rate
+	// every round trip: 2/ln(2).
+	bbrHighGain = 2.885
+
+	// bbrDrainGain is the pacing gain used to drain the queue created in
+	// startup in a single round trip.
+	bbrDrainGain = 1 / bbrHighGain
+
+	// bbrCwndGain is the congestion window gain used in probe bandwidth
+	// mode, to tolerate delayed and aggregated ACKs.
+	bbrCwndGain = 2.0
+
+	// bbrBWRounds is the length, in round trips, of the window of the
+	// maximum bandwidth filter.
+	bbrBWRounds = bbrCycleLen + 2
+
+	// bbrMinRTTWindow is the length of the window of the minimum RTT
+	// filter.
+	bbrMinRTTWindow = 10 * time.Second
+
+	// bbrProbeRTTDuration is the minimum time spent in probe RTT mode.
+	bbrProbeRTTDuration = 200 * time.Millisecond
+
+	// bbrCwndMinTarget is the minimum congestion window, used in probe RTT
+	// mode.
+	bbrCwndMinTarget = 4
+
+	// bbrFullBWThresh is the growth of the bandwidth over a round trip
+	// below which the pipe is considered full in startup.
+	bbrFullBWThresh = 1.25
+
+	// bbrFullBWCount is the number of rounds without bandwidth growth after
+	// which the pipe is considered full.
+	bbrFullBWCount = 3
+
+	// bbrPacingMargin is the percentage by which the pacing rate is kept
+	// below the estimated bandwidth, to reduce queues at the bottleneck.
+	bbrPacingMargin = 1
+
+	// bbrSegsGoal is the number of segments the sender is expected to send
+	// at once, used to budget the congestion window.
+	bbrSegsGoal = 2
+
+	// bbrCycleRand is the number of phases from which the initial phase of
+	// the gain cycle is randomly picked.
+	bbrCycleRand = 7
+)
+
+// bbrPacingGain is the cycle of pacing gains used in probe bandwidth mode.
+var bbrPacingGain = [...]float64{1.25, 0.75, 1, 1, 1, 1, 1, 1}
+
+// bbrCycleLen is the number of phases in the gain cycle.
+const bbrCycleLen = len(bbrPacingGain)
+
+// bbrMode is the state of the BBR state machine.
+type bbrMode uint8
+
+const (
+	// bbrStartup ramps up the sending rate rapidly to fill the pipe.
+	bbrStartup bbrMode = iota
+
+	// bbrDrain drains the queue created in startup.
+	bbrDrain
+
+	// bbrProbeBW cycles the pacing gain to discover more bandwidth.
+	bbrProbeBW
+
+	// bbrProbeRTT reduces the congestion window to measure the minimum RTT.
+	bbrProbeRTT
+)
+
+// windowedMaxSample is a sample of a windowedMax.
+//
+// +stateify savable
+type windowedMaxSample struct {
+	t uint64
+	v uint64
+}
+
+// windowedMax tracks the maximum of the values measured over a window of
+// time, using the algorithm of Kathleen Nichols also implemented by Linux in
+// lib/win_minmax.c. It keeps the best, second best and third best samples of
+// the window.
+//
+// +stateify savable
+type windowedMax struct {
+	s [3]windowedMaxSample
+}
+
+// get returns the maximum value of the window.
+func (m *windowedMax) get() uint64 {
+	return m.s[0].v
+}
+
+// reset resets the window to a single sample.
+func (m *windowedMax) reset(t, v uint64) {
+	val := windowedMaxSample{t: t, v: v}
+	m.s[0], m.s[1], m.s[2] = val, val, val
+}
+
+// update records value v measured at t, for a window of length win.
+func (m *windowedMax) update(win, t, v uint64) {
+	val := windowedMaxSample{t: t, v: v}
+	if v >= m.s[0].v || t-m.s[2].t > win {
+		// The new sample is the new maximum, or nothing was measured in
+		// the whole window.
+		m.reset(t, v)
+		return
+	}
+	if v >= m.s[1].v {
+		m.s[1], m.s[2] = val, val
+	} else if v >= m.s[2].v {
+		m.s[2] = val
+	}
+
+	// Age the samples.
+	dt := t - m.s[0].t
+	switch {
+	case dt > win:
+		// The maximum expired, so the second best becomes the maximum.
+		m.s[0], m.s[1], m.s[2] = m.s[1], m.s[2], val
+		if t-m.s[0].t > win {
+			m.s[0], m.s[1], m.s[2] = m.s[1], m.s[2], val
+		}
+	case m.s[1].t == m.s[0].t && dt > win/4:
+		// A quarter of the window passed without a second best sample,
+		// so take one from the second quarter.
+		m.s[1], m.s[2] = val, val
+	case m.s[2].t == m.s[1].t && dt > win/2:
+		// Half of the window passed without a third best sample, so take
+		// one from the second half.
+		m.s[2] = val
+	}
+}
+
+// bbrState stores the variables related to the BBR congestion control
+// algorithm.
+//
+// +stateify savable
+type bbrState struct {
+	s *sender
+
+	// mode is the current state of the state machine.
+	mode bbrMode
+
+	// bw is the filter of the maximum bandwidth in bytes per second, over
+	// round trips.
+	bw windowedMax
+
+	// minRTT is the minimum RTT measured in the last bbrMinRTTWindow.
+	minRTT time.Duration
+
+	// minRTTStamp is the time at which minRTT was measured.
+	minRTTStamp tcpip.MonotonicTime
+
+	// probeRTTDoneStamp is the time at which probe RTT mode ends, or zero
+	// if the congestion window hasn't been drained yet.
+	probeRTTDoneStamp tcpip.MonotonicTime
+
+	// probeRTTRoundDone indicates that a round trip passed in probe RTT
+	// mode.
+	probeRTTRoundDone bool
+
+	// roundCount is the number of round trips since initialization.
+	roundCount uint64
+
+	// nextRoundDelivered is the number of packets delivered at which the
+	// next round trip starts.
+	nextRoundDelivered uint64
+
+	// roundStart indicates that the current ACK started a new round trip.
+	roundStart bool
+
+	// pacingGain is the current pacing gain.
+	pacingGain float64
+
+	// cwndGain is the current congestion window gain.
+	cwndGain float64
+
+	// cycleIdx is the current phase of the gain cycle in probe bandwidth
+	// mode.
+	cycleIdx int
+
+	// cycleStamp is the time at which the current phase started.
+	cycleStamp tcpip.MonotonicTime
+
+	// fullBW is the bandwidth at which the pipe was last known to grow.
+	fullBW uint64
+
+	// fullBWCount is the number of rounds without bandwidth growth.
+	fullBWCount int
+
+	// fullBWReached indicates that the pipe was estimated to be full.
+	fullBWReached bool
+
+	// priorCwnd is the congestion window saved before recovery or probe
+	// RTT mode.
+	priorCwnd int
+
+	// prevInRecovery indicates that the sender was in recovery when the
+	// previous ACK was processed.
+	prevInRecovery bool
+
+	// packetConservation indicates that packet conservation is used in the
+	// first round trip of fast recovery.
+	packetConservation bool
+
+	// hasSeenRTT indicates that the initial pacing rate was computed from a
+	// measured RTT.
+	hasSeenRTT bool
+}
+
+// newBBRCC initializes the state for the BBR congestion control algorithm.
+//
+// +checklocks:s.ep.mu
+func newBBRCC(s *sender) *bbrState {
+	now := s.ep.stack.Clock().NowMonotonic()
+	b := &bbrState{
+		s:                  s,
+		minRTT:             s.minRTT,
+		minRTTStamp:        now,
+		nextRoundDelivered: s.rate.delivered,
+		cycleStamp:         now,
+	}
+	if b.minRTT == 0 {
+		b.minRTT = effectivelyInfinity
+	}
+	b.initPacingRate()
+	b.resetStartupMode()
+	b.updateGains()
+	return b
+}
+
+// initPacingRate sets the initial pacing rate from the congestion window and
+// the smoothed RTT, or an RTT of 1ms if none was measured yet.
+//
+// +checklocks:b.s.ep.mu
+func (b *bbrState) initPacingRate() {
+	b.s.rtt.Lock()
+	srtt, inited := b.s.rtt.TCPRTTState.SRTT, b.s.rtt.TCPRTTState.SRTTInited
+	b.s.rtt.Unlock()
+	if inited && srtt > 0 {
+		b.hasSeenRTT = true
+	} else {
+		srtt = time.Millisecond
+	}
+	bw := uint64(b.s.SndCwnd) * uint64(b.s.MaxPayloadSize) * uint64(time.Second) / uint64(srtt)
+	b.s.pacingRate = b.pacingRate(bw, bbrHighGain)
+}
+
+// pacingRate returns the pacing rate for bandwidth bw and gain, in bytes per
+// second.
+func (b *bbrState) pacingRate(bw uint64, gain float64) uint64 {
+	return uint64(float64(bw) * gain * (100 - bbrPacingMargin) / 100)
+}
+
+// inRecovery returns true if the sender is recovering from a loss.
+//
+// +checklocks:b.s.ep.mu
+func (b *bbrState) inRecovery() bool {
+	return b.s.FastRecovery.Active || b.s.state == tcpip.RTORecovery
+}
+
+// saveCwnd saves the congestion window, to be restored after recovery or
+// probe RTT mode.
+//
+// +checklocks:b.s.ep.mu
+func (b *bbrState) saveCwnd() {
+	if !b.prevInRecovery && b.mode != bbrProbeRTT {
+		b.priorCwnd = b.s.SndCwnd
+	} else {
+		b.priorCwnd = max(b.priorCwnd, b.s.SndCwnd)
+	}
+}
+
+// resetStartupMode enters startup mode.
+func (b *bbrState) resetStartupMode() {
+	b.mode = bbrStartup
+}
+
+// resetProbeBWMode enters probe bandwidth mode, at a random phase of the gain
+// cycle other than the one draining the queue.
+//
+// +checklocks:b.s.ep.mu
+func (b *bbrState) resetProbeBWMode() {
+	b.mode = bbrProbeBW
+	b.cycleIdx = bbrCycleLen - 1 - b.s.ep.stack.InsecureRNG().Intn(bbrCycleRand)
+	b.advanceCyclePhase()
+}
+
+// advanceCyclePhase moves to the next phase of the gain cycle.
+//
+// +checklocks:b.s.ep.mu
+func (b *bbrState) advanceCyclePhase() {
+	b.cycleIdx = (b.cycleIdx + 1) % bbrCycleLen
+	b.cycleStamp = b.s.ep.stack.Clock().NowMonotonic()
+}
+
+// bdp returns the bandwidth-delay product for bandwidth bw scaled by gain, in
+// packets.
+//
+// +checklocks:b.s.ep.mu
+func (b *bbrState) bdp(bw uint64, gain float64) int {
+	if b.minRTT == effectivelyInfinity {
+		// No RTT was measured yet.
+		return InitialCwnd
+	}
+	bytes := float64(bw) * b.minRTT.Seconds() * gain
+	return int(math.Ceil(bytes / float64(b.s.MaxPayloadSize)))
+}
+
+// quantizationBudget returns the congestion window needed to reach cwnd
+// while the sender transmits bursts of segments.
+func (b *bbrState) quantizationBudget(cwnd int) int {
+	// Allow enough segments in flight for the sender, the receiver and the
+	// network to each hold a burst.
+	cwnd += 3 * bbrSegsGoal
+	// Round up to an even number to allow delayed ACKs to be sent.
+	cwnd = (cwnd + 1) &^ 1
+	// Make sure the probing phase actually puts more data in flight.
+	if b.mode == bbrProbeBW && b.cycleIdx == 0 {
+		cwnd += 2
+	}
+	return cwnd
+}
+
+// inflight returns the target number of packets in flight for bandwidth bw
+// scaled by gain.
+//
+// +checklocks:b.s.ep.mu
+func (b *bbrState) inflight(bw uint64, gain float64) int {
+	return b.quantizationBudget(b.bdp(bw, gain))
+}
+
+// updateBW starts new round trips and updates the bandwidth filter with the
+// sample.
+//
+// +checklocks:b.s.ep.mu
+func (b *bbrState) updateBW(rs *rateSample) {
+	b.roundStart = false
+	if rs.interval <= 0 {
+		return
+	}
+	if rs.priorDelivered >= b.nextRoundDelivered {
+		b.nextRoundDelivered = b.s.rate.delivered
+		b.roundCount++
+		b.roundStart = true
+		b.packetConservation = false
+	}
+	// Application limited samples underestimate the bandwidth, so they are
+	// only used if they increase the estimate.
+	bw := rs.bandwidth(b.s.MaxPayloadSize)
+	if !rs.appLimited || bw >= b.bw.get() {
+		b.bw.update(uint64(bbrBWRounds), b.roundCount, bw)
+	}
+}
+
+// isNextCyclePhase returns true if the current phase of the gain cycle is
+// done.
+//
+// +checklocks:b.s.ep.mu
+func (b *bbrState) isNextCyclePhase(rs *rateSample) bool {
+	isFullLength := b.s.ep.stack.Clock().NowMonotonic().Sub(b.cycleStamp) > b.minRTT
+	if b.pacingGain == 1 {
+		return isFullLength
+	}
+	// Probe until a full round trip passes and either inflight reached its
+	// target or losses are detected.
+	if b.pacingGain > 1 {
+		return isFullLength && (b.inRecovery() || rs.priorInFlight >= b.inflight(b.bw.get(), b.pacingGain))
+	}
+	// Drain until a full round trip passes or the queue is gone.
+	return isFullLength || rs.priorInFlight <= b.inflight(b.bw.get(), 1)
+}
+
+// updateCyclePhase advances the gain cycle in probe bandwidth mode.
+//
+// +checklocks:b.s.ep.mu
+func (b *bbrState) updateCyclePhase(rs *rateSample) {
+	if b.mode == bbrProbeBW && b.isNextCyclePhase(rs) {
+		b.advanceCyclePhase()
+	}
+}
+
+// checkFullBWReached estimates whether the pipe is full, which is the case
+// when the bandwidth stops growing for bbrFullBWCount round trips.
+//
+// +checklocks:b.s.ep.mu
+func (b *bbrState) checkFullBWReached(rs *rateSample) {
+	if b.fullBWReached || !b.roundStart || rs.appLimited {
+		return
+	}
+	if bw := b.bw.get(); float64(bw) >= float64(b.fullBW)*bbrFullBWThresh {
+		b.fullBW = bw
+		b.fullBWCount = 0
+		return
+	}
+	b.fullBWCount++
+	b.fullBWReached = b.fullBWCount >= bbrFullBWCount
+}
+
+// checkDrain moves from startup to drain mode once the pipe is full, and from
+// drain to probe bandwidth mode once the queue is drained.
+//
+// +checklocks:b.s.ep.mu
+func (b *bbrState) checkDrain() {
+	if b.mode == bbrStartup && b.fullBWReached {
+		b.mode = bbrDrain
+		b.s.Ssthresh = b.inflight(b.bw.get(), 1)
+	}
+	if b.mode == bbrDrain && b.s.Outstanding <= b.inflight(b.bw.get(), 1) {
+		b.resetProbeBWMode()
+	}
+}
+
+// checkProbeRTTDone leaves probe RTT mode once it lasted long enough.
+//
+// +checklocks:b.s.ep.mu
+func (b *bbrState) checkProbeRTTDone() {
+	now := b.s.ep.stack.Clock().NowMonotonic()
+	if b.probeRTTDoneStamp == (tcpip.MonotonicTime{}) || !now.After(b.probeRTTDoneStamp) {
+		return
+	}
+	b.minRTTStamp = now
+	b.s.SndCwnd = max(b.s.SndCwnd, b.priorCwnd)
+	if b.fullBWReached {
+		b.resetProbeBWMode()
+	} else {
+		b.resetStartupMode()
+	}
+}
+
+// updateMinRTT updates the minimum RTT filter, and enters probe RTT mode when
+// the minimum RTT wasn't measured for bbrMinRTTWindow.
+//
+// +checklocks:b.s.ep.mu
+func (b *bbrState) updateMinRTT(rs *rateSample) {
+	now := b.s.ep.stack.Clock().NowMonotonic()
+	expired := now.After(b.minRTTStamp.Add(bbrMinRTTWindow))
+	if rs.rtt >= 0 && (rs.rtt < b.minRTT || expired) {
+		b.minRTT = rs.rtt
+		b.minRTTStamp = now
+	}
+
+	if expired && b.mode != bbrProbeRTT {
+		b.mode = bbrProbeRTT
+		b.saveCwnd()
+		b.probeRTTDoneStamp = tcpip.MonotonicTime{}
+	}
+	if b.mode != bbrProbeRTT {
+		return
+	}
+	// Don't count the reduced sending rate as a bandwidth decrease.
+	b.s.rate.appLimitedUntil = b.s.rate.delivered + uint64(b.s.Outstanding)
+	if b.s.rate.appLimitedUntil == 0 {
+		b.s.rate.appLimitedUntil = 1
+	}
+	if b.probeRTTDoneStamp == (tcpip.MonotonicTime{}) && b.s.Outstanding <= bbrCwndMinTarget {
+		// The congestion window is drained, so the probe starts now.
+		b.probeRTTDoneStamp = now.Add(bbrProbeRTTDuration)
+		b.probeRTTRoundDone = false
+		b.nextRoundDelivered = b.s.rate.delivered
+	} else if b.probeRTTDoneStamp != (tcpip.MonotonicTime{}) {
+		if b.roundStart {
+			b.probeRTTRoundDone = true
+		}
+		if b.probeRTTRoundDone {
+			b.checkProbeRTTDone()
+		}
+	}
+}
+
+// updateGains sets the pacing and congestion window gains of the current
+// mode.
+func (b *bbrState) updateGains() {
+	switch b.mode {
+	case bbrStartup:
+		b.pacingGain = bbrHighGain
+		b.cwndGain = bbrHighGain
+	case bbrDrain:
+		b.pacingGain = bbrDrainGain
+		b.cwndGain = bbrHighGain
+	case bbrProbeBW:
+		b.pacingGain = bbrPacingGain[b.cycleIdx]
+		b.cwndGain = bbrCwndGain
+	case bbrProbeRTT:
+		b.pacingGain = 1
+		b.cwndGain = 1
+	}
+}
+
+// setPacingRate sets the pacing rate of the sender from the bandwidth
+// estimate. Until the pipe is full, the pacing rate is only increased.
+//
+// +checklocks:b.s.ep.mu
+func (b *bbrState) setPacingRate() {
+	if !b.hasSeenRTT {
+		b.s.rtt.Lock()
+		inited := b.s.rtt.TCPRTTState.SRTTInited
+		b.s.rtt.Unlock()
+		if inited {
+			b.initPacingRate()
+		}
+	}
+	rate := b.pacingRate(b.bw.get(), b.pacingGain)
+	if b.fullBWReached || rate > b.s.pacingRate {
+		b.s.pacingRate = rate
+	}
+}
+
+// setCwndToRecoverOrRestore uses packet conservation in the first round trip
+// of fast recovery and restores the congestion window after recovery. It
+// returns the new congestion window, and whether it must be used as is.
+//
+// +checklocks:b.s.ep.mu
+func (b *bbrState) setCwndToRecoverOrRestore(acked int) (int, bool) {
+	cwnd := b.s.SndCwnd
+	inRecovery := b.inRecovery()
+	if b.s.FastRecovery.Active && !b.prevInRecovery {
+		// Starting fast recovery: send one packet per packet delivered
+		// for a round trip.
+		b.packetConservation = true
+		b.nextRoundDelivered = b.s.rate.delivered
+		cwnd = b.s.Outstanding + acked
+	} else if b.prevInRecovery && !inRecovery {
+		// Exiting recovery: restore the congestion window.
+		cwnd = max(cwnd, b.priorCwnd)
+		b.packetConservation = false
+	}
+	b.prevInRecovery = inRecovery
+	if b.packetConservation {
+		return max(cwnd, b.s.Outstanding+acked), true
+	}
+	return cwnd, false
+}
+
+// setCwnd grows the congestion window towards the target inflight.
+//
+// +checklocks:b.s.ep.mu
+func (b *bbrState) setCwnd(rs *rateSample) {
+	if acked := rs.ackedSacked; acked > 0 {
+		cwnd, done := b.setCwndToRecoverOrRestore(acked)
+		if !done {
+			target := b.inflight(b.bw.get(), b.cwndGain)
+			if b.fullBWReached {
+				cwnd = min(cwnd+acked, target)
+			} else if cwnd < target || b.s.rate.delivered < InitialCwnd {
+				cwnd += acked
+			}
+			cwnd = max(cwnd, bbrCwndMinTarget)
+		}
+		b.s.SndCwnd = max(cwnd, 1)
+	}
+	if b.mode == bbrProbeRTT {
+		b.s.SndCwnd = min(b.s.SndCwnd, bbrCwndMinTarget)
+	}
+}
+
+// OnRateSample implements rateBasedCongestionControl.OnRateSample.
+//
+// +checklocks:b.s.ep.mu
+func (b *bbrState) OnRateSample(rs *rateSample) {
+	b.updateBW(rs)
+	b.updateCyclePhase(rs)
+	b.checkFullBWReached(rs)
+	b.checkDrain()
+	b.updateMinRTT(rs)
+	b.updateGains()
+	b.setPacingRate()
+	b.setCwnd(rs)
+}
+
+// Update implements congestionControl.Update.
+func (*bbrState) Update(int, time.Duration) {
+	// The congestion window is set by OnRateSample.
+}
+
+// HandleLossDetected implements congestionControl.HandleLossDetected.
+//
+// +checklocks:b.s.ep.mu
+func (b *bbrState) HandleLossDetected() {
+	b.saveCwnd()
+	// BBR doesn't use the slow start threshold, but the sender uses it to
+	// set the congestion window when entering fast recovery. Packet
+	// conservation then takes over with the next rate sample.
+	b.s.Ssthresh = max(b.s.Outstanding, bbrCwndMinTarget)
+}
+
+// HandleRTOExpired implements congestionControl.HandleRTOExpired.
+//
+// +checklocks:b.s.ep.mu
+func (b *bbrState) HandleRTOExpired() {
+	b.saveCwnd()
+	b.prevInRecovery = true
+	b.fullBW = 0
+	b.roundStart = true
+	b.s.SndCwnd = 1
+}
+
+// HandleCongestionExperienced implements
+// congestionControl.HandleCongestionExperienced.
+func (*bbrState) HandleCongestionExperienced() {
+	// Like in Linux, BBR doesn't react to ECN marks.
+}
+
+// PostRecovery implements congestionControl.PostRecovery.
+//
+// +checklocks:b.s.ep.mu
+func (b *bbrState) PostRecovery() {
+	b.s.SndCwnd = max(b.s.SndCwnd, b.priorCwnd)
+}
+
+// GetInfo implements congestionControl.GetInfo.
+func (b *bbrState) GetInfo(info *tcpip.TCPCCInfoOption) {
+	info.BBR = tcpip.TCPBBRInfo{
+		Bandwidth:  b.bw.get(),
+		PacingGain: uint32(b.pacingGain * 256),
+		CwndGain:   uint32(b.cwndGain * 256),
+	}
+	if b.minRTT != effectivelyInfinity {
+		info.BBR.MinRTT = b.minRTT
+	}
+}
@@ -0,0 +1,251 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcp
+
+import (
+	"testing"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/faketime"
+	"gvisor.dev/gvisor/pkg/tcpip/seqnum"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+)
+
+func TestWindowedMax(t *testing.T) {
+	var m windowedMax
+	for _, tc := range []struct {
+		t    uint64
+		v    uint64
+		want uint64
+	}{
+		{t: 0, v: 10, want: 10},
+		{t: 1, v: 5, want: 10},
+		{t: 4, v: 8, want: 10},
+		{t: 9, v: 3, want: 10},
+		// The maximum expires, and the best of the remaining samples
+		// takes over.
+		{t: 11, v: 2, want: 8},
+		{t: 12, v: 20, want: 20},
+		// Nothing was measured in the whole window.
+		{t: 40, v: 1, want: 1},
+	} {
+		m.update(10, tc.t, tc.v)
+		if got := m.get(); got != tc.want {
+			t.Fatalf("after update(10, %d, %d): got max %d, want %d", tc.t, tc.v, got, tc.want)
+		}
+	}
+}
+
+func newBBRTestSender(clock tcpip.Clock) *sender {
+	s := stack.New(stack.Options{
+		TransportProtocols: []stack.TransportProtocolFactory{NewProtocol},
+		Clock:              clock,
+	})
+	ep := &Endpoint{
+		stack: s,
+		cc:    tcpip.CongestionControlOption(ccBBR),
+	}
+	iss := seqnum.Value(0)
+	return &sender{
+		ep: ep,
+		TCPSenderState: TCPSenderState{
+			SndUna:         iss + 1,
+			SndNxt:         iss + 1,
+			SndCwnd:        InitialCwnd,
+			Ssthresh:       InitialSsthresh,
+			MaxPayloadSize: 1000,
+		},
+	}
+}
+
+// TestBBRStartupToProbeBW tests that BBR leaves startup once the bandwidth
+// stops growing, and enters probe bandwidth mode once the queue is drained.
+func TestBBRStartupToProbeBW(t *testing.T) {
+	clock := faketime.NewManualClock()
+	snd := newBBRTestSender(clock)
+	snd.ep.mu.Lock()
+	defer snd.ep.mu.Unlock()
+	b := newBBRCC(snd)
+	snd.cc = b
+
+	if b.mode != bbrStartup {
+		t.Fatalf("got initial mode %d, want %d", b.mode, bbrStartup)
+	}
+	if snd.pacingRate == 0 {
+		t.Fatalf("got initial pacing rate 0, want non-zero")
+	}
+
+	// Deliver 10 packets of 1000 bytes every 10ms: 1MB/s.
+	const (
+		packets  = 10
+		interval = 10 * time.Millisecond
+		wantBW   = 1000000
+	)
+	sample := func() {
+		clock.Advance(interval)
+		rs := rateSample{
+			valid:          true,
+			priorDelivered: snd.rate.delivered,
+			rtt:            interval,
+			ackedSacked:    packets,
+			interval:       interval,
+		}
+		snd.rate.delivered += packets
+		rs.delivered = packets
+		b.OnRateSample(&rs)
+	}
+
+	// The first round sets the bandwidth of the full pipe, and BBR only
+	// leaves startup after bbrFullBWCount rounds without growth.
+	for i := 0; i < bbrFullBWCount; i++ {
+		sample()
+		if b.mode != bbrStartup {
+			t.Fatalf("round %d: got mode %d, want %d", i, b.mode, bbrStartup)
+		}
+	}
+	if got := b.bw.get(); got != wantBW {
+		t.Fatalf("got bandwidth %d, want %d", got, wantBW)
+	}
+	if got, want := snd.pacingRate, b.pacingRate(wantBW, bbrHighGain); got < want {
+		t.Fatalf("got pacing rate %d in startup, want at least %d", got, want)
+	}
+	if b.minRTT != interval {
+		t.Fatalf("got min RTT %s, want %s", b.minRTT, interval)
+	}
+
+	// Nothing is in flight, so the queue is drained as soon as the pipe is
+	// full.
+	sample()
+	if !b.fullBWReached {
+		t.Fatalf("full bandwidth not reached after %d rounds", bbrFullBWCount+1)
+	}
+	if b.mode != bbrProbeBW {
+		t.Fatalf("got mode %d, want %d", b.mode, bbrProbeBW)
+	}
+	if b.cycleIdx == 1 {
+		t.Errorf("probe bandwidth mode started with the drain phase")
+	}
+	if b.cwndGain != bbrCwndGain {
+		t.Errorf("got cwnd gain %f, want %f", b.cwndGain, bbrCwndGain)
+	}
+	if got, want := snd.pacingRate, b.pacingRate(wantBW, b.pacingGain); got != want {
+		t.Errorf("got pacing rate %d, want %d", got, want)
+	}
+	if want := b.inflight(wantBW, bbrCwndGain); snd.SndCwnd > want {
+		t.Errorf("got cwnd %d, want at most %d", snd.SndCwnd, want)
+	}
+}
+
+// TestBBRProbeRTT tests that BBR enters probe RTT mode when the minimum RTT
+// wasn't measured for bbrMinRTTWindow, and stops capping the congestion window
+// when it leaves.
+func TestBBRProbeRTT(t *testing.T) {
+	clock := faketime.NewManualClock()
+	snd := newBBRTestSender(clock)
+	snd.ep.mu.Lock()
+	defer snd.ep.mu.Unlock()
+	b := newBBRCC(snd)
+	snd.cc = b
+
+	const rtt = 10 * time.Millisecond
+	sample := func(rtt time.Duration) {
+		clock.Advance(rtt)
+		rs := rateSample{
+			valid:          true,
+			priorDelivered: snd.rate.delivered,
+			rtt:            rtt,
+			ackedSacked:    1,
+			delivered:      1,
+			interval:       rtt,
+		}
+		snd.rate.delivered++
+		b.OnRateSample(&rs)
+	}
+	sample(rtt)
+	snd.SndCwnd = 100
+
+	// Once the minimum RTT expires, the next sample replaces it and starts
+	// the probe.
+	clock.Advance(bbrMinRTTWindow)
+	sample(2 * rtt)
+	if b.mode != bbrProbeRTT {
+		t.Fatalf("got mode %d, want %d", b.mode, bbrProbeRTT)
+	}
+	if snd.SndCwnd != bbrCwndMinTarget {
+		t.Fatalf("got cwnd %d in probe RTT mode, want %d", snd.SndCwnd, bbrCwndMinTarget)
+	}
+
+	// Nothing is in flight, so the probe starts right away and lasts for
+	// at least bbrProbeRTTDuration and a round trip.
+	sample(rtt)
+	clock.Advance(bbrProbeRTTDuration)
+	sample(rtt)
+	if b.mode == bbrProbeRTT {
+		t.Fatalf("still in probe RTT mode after %s", bbrProbeRTTDuration)
+	}
+	if snd.SndCwnd <= bbrCwndMinTarget {
+		t.Errorf("got cwnd %d after probe RTT mode, want more than %d", snd.SndCwnd, bbrCwndMinTarget)
+	}
+}
+
+// TestBBRGetInfo tests the information reported by BBR through TCP_CC_INFO.
+func TestBBRGetInfo(t *testing.T) {
+	clock := faketime.NewManualClock()
+	snd := newBBRTestSender(clock)
+	snd.ep.mu.Lock()
+	defer snd.ep.mu.Unlock()
+	b := newBBRCC(snd)
+	snd.cc = b
+
+	// bbrHighGain, scaled by 256.
+	const startupGain = 738
+
+	// Nothing was measured yet.
+	var info tcpip.TCPCCInfoOption
+	b.GetInfo(&info)
+	want := tcpip.TCPBBRInfo{
+		PacingGain: startupGain,
+		CwndGain:   startupGain,
+	}
+	if info.BBR != want {
+		t.Fatalf("got initial info %+v, want %+v", info.BBR, want)
+	}
+
+	// Deliver 10 packets of 1000 bytes in 10ms: 1MB/s.
+	const interval = 10 * time.Millisecond
+	clock.Advance(interval)
+	rs := rateSample{
+		valid:          true,
+		priorDelivered: snd.rate.delivered,
+		rtt:            interval,
+		ackedSacked:    10,
+		delivered:      10,
+		interval:       interval,
+	}
+	snd.rate.delivered += 10
+	b.OnRateSample(&rs)
+
+	b.GetInfo(&info)
+	want = tcpip.TCPBBRInfo{
+		Bandwidth:  1000000,
+		MinRTT:     interval,
+		PacingGain: startupGain,
+		CwndGain:   startupGain,
+	}
+	if info.BBR != want {
+		t.Errorf("got info %+v, want %+v", info.BBR, want)
+	}
+}
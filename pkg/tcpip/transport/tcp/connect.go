@@ -21,6 +21,7 @@ import (
 	"math"
 	"time"
 
+	"gvisor.dev/gvisor/pkg/buffer"
 	"gvisor.dev/gvisor/pkg/sync"
 	"gvisor.dev/gvisor/pkg/tcpip"
 	"gvisor.dev/gvisor/pkg/tcpip/checksum"
@@ -120,6 +121,27 @@ type handshake struct {
 	// retransmitTimer is used to retransmit SYN/SYN-ACK with exponential backoff
 	// till handshake is either completed or timesout.
 	retransmitTimer *backoffTimer `state:"nosave"`
+
+	// fastOpenCookie is the TCP Fast Open cookie to send to the peer in the
+	// SYN-ACK of a passive handshake, or nil.
+	fastOpenCookie []byte
+
+	// fastOpen is true if the data in the SYN of a passive handshake was
+	// accepted using TCP Fast Open.
+	fastOpen bool
+
+	// synData is the data received in the SYN of a passive handshake that
+	// was accepted using TCP Fast Open. It is delivered to the endpoint
+	// when the handshake completes.
+	synData buffer.Buffer `state:"nosave"`
+
+	// synDataSent is the number of bytes of data sent in the SYN of an
+	// active handshake using TCP Fast Open.
+	synDataSent int
+
+	// synDataAcked is the number of bytes of data sent in the SYN that
+	// were acknowledged by the peer's SYN-ACK.
+	synDataAcked seqnum.Size
 }
 
 // timerHandler takes a handler function for a timer and returns a function that
@@ -213,8 +235,10 @@ func FindWndScale(wnd seqnum.Size) int {
 func (h *handshake) resetState() {
 	h.state = handshakeSynSent
 	h.flags = header.TCPFlagSyn
+	h.requestECN()
 	h.ackNum = 0
 	h.mss = 0
+	h.synDataSent = 0
 	h.iss = generateSecureISN(h.ep.TransportEndpointInfo.ID, h.ep.stack.Clock(), h.ep.protocol.seqnumSecret)
 }
 
@@ -276,8 +300,10 @@ func (h *handshake) resetToSynRcvd(iss seqnum.Value, irs seqnum.Value, opts head
 
 // checkAck checks if the ACK number, if present, of a segment received during
 // a TCP 3-way handshake is valid.
+//
+// The ACK may also acknowledge any data sent in the SYN using TCP Fast Open.
 func (h *handshake) checkAck(s *segment) bool {
-	return !(s.flags.Contains(header.TCPFlagAck) && s.ackNumber != h.iss+1)
+	return !s.flags.Contains(header.TCPFlagAck) || s.ackNumber.InWindow(h.iss+1, seqnum.Size(h.synDataSent)+1)
 }
 
 // synSentState handles a segment received when the TCP 3-way handshake is in
@@ -287,7 +313,7 @@ func (h *handshake) synSentState(s *segment) tcpip.Error {
 	// RFC 793, page 37, states that in the SYN-SENT state, a reset is
 	// acceptable if the ack field acknowledges the SYN.
 	if s.flags.Contains(header.TCPFlagRst) {
-		if s.flags.Contains(header.TCPFlagAck) && s.ackNumber == h.iss+1 {
+		if s.flags.Contains(header.TCPFlagAck) && h.checkAck(s) {
 			// RFC 793, page 67, states that "If the RST bit is set [and] If the ACK
 			// was acceptable then signal the user "error: connection reset", drop
 			// the segment, enter CLOSED state, delete TCB, and return."
@@ -325,6 +351,9 @@ func (h *handshake) synSentState(s *segment) tcpip.Error {
 	// Remember if the SACKPermitted option was negotiated.
 	h.ep.maybeEnableSACKPermitted(rcvSynOpts)
 
+	// Remember if ECN was negotiated.
+	h.negotiateECN(s)
+
 	// Remember the sequence we'll ack from now on.
 	h.ackNum = s.sequenceNumber + 1
 	h.flags |= header.TCPFlagAck
@@ -335,9 +364,12 @@ func (h *handshake) synSentState(s *segment) tcpip.Error {
 	// and the handshake is completed.
 	if s.flags.Contains(header.TCPFlagAck) {
 		h.state = handshakeCompleted
+		h.synDataAcked = (h.iss + 1).Size(s.ackNumber)
+		h.ep.fastOpenSynAckLocked(h, rcvSynOpts)
 		h.transitionToStateEstablishedLocked(s)
 
-		h.ep.sendEmptyRaw(header.TCPFlagAck, h.iss+1, h.ackNum, h.rcvWnd>>h.effectiveRcvWndScale())
+		h.ep.sendEmptyRaw(header.TCPFlagAck, h.ep.snd.SndNxt, h.ackNum, h.rcvWnd>>h.effectiveRcvWndScale())
+		h.ep.requeueFastOpenDataLocked(h.synDataAcked)
 		return nil
 	}
 
@@ -359,6 +391,7 @@ func (h *handshake) synSentState(s *segment) tcpip.Error {
 		// this is the behaviour implemented by Linux.
 		SACKPermitted: rcvSynOpts.SACKPermitted,
 		MSS:           amss,
+		MD5Sig:        h.sendSYNOpts.MD5Sig,
 	}
 	if ttl == 0 {
 		ttl = h.ep.route.DefaultTTL()
@@ -426,7 +459,9 @@ func (h *handshake) synRcvdState(s *segment) tcpip.Error {
 		return nil
 	}
 
-	if s.flags.Contains(header.TCPFlagSyn) && s.sequenceNumber != h.ackNum-1 {
+	// The ACK number sent for the peer's SYN also covers any data accepted
+	// in it using TCP Fast Open.
+	if s.flags.Contains(header.TCPFlagSyn) && s.sequenceNumber != h.ackNum-1-seqnum.Value(h.synData.Size()) {
 		// We received two SYN segments with different sequence
 		// numbers, so we reset this and restart the whole
 		// process, except that we don't reset the timer.
@@ -449,6 +484,7 @@ func (h *handshake) synRcvdState(s *segment) tcpip.Error {
 			TSEcr:         h.ep.recentTimestamp(),
 			SACKPermitted: h.ep.SACKPermitted,
 			MSS:           h.ep.amss,
+			MD5Sig:        h.sendSYNOpts.MD5Sig,
 		}
 		h.ep.sendSynTCP(h.ep.route, tcpFields{
 			id:        h.ep.TransportEndpointInfo.ID,
@@ -498,6 +534,11 @@ func (h *handshake) synRcvdState(s *segment) tcpip.Error {
 
 		h.state = handshakeCompleted
 		h.transitionToStateEstablishedLocked(s)
+		if h.active {
+			// This was a simultaneous open, so any data sent in our
+			// SYN was not acknowledged.
+			h.ep.requeueFastOpenDataLocked(0)
+		}
 
 		// Requeue the segment if the ACK completing the handshake has more info
 		// to be processed by the newly established endpoint.
@@ -573,6 +614,7 @@ func (h *handshake) start() {
 		TSEcr:         h.ep.recentTimestamp(),
 		SACKPermitted: bool(sackEnabled),
 		MSS:           h.ep.amss,
+		MD5Sig:        h.ep.md5KeyForRoute(h.ep.route) != nil,
 	}
 
 	// start() is also called in a listen context so we want to make sure we only
@@ -587,8 +629,30 @@ func (h *handshake) start() {
 		}
 	}
 
+	// Add the TCP Fast Open cookie (or cookie request) and data, if any.
+	// There is no room left for the option in signed SYNs, so TCP Fast
+	// Open isn't used along with TCP MD5 signatures, like in Linux.
+	var synData buffer.Buffer
+	if synOpts.MD5Sig {
+		h.fastOpenCookie = nil
+	} else if h.fastOpenCookie != nil {
+		synOpts.FastOpen = true
+		synOpts.FastOpenCookie = h.fastOpenCookie
+	}
+	if req := h.ep.fastOpenReq; h.active && req != nil && !synOpts.MD5Sig {
+		synOpts.FastOpen = !req.noCookie
+		synOpts.FastOpenCookie = req.cookie
+		if req.cookie != nil || req.noCookie {
+			// The data must fit in a single segment along with the
+			// options.
+			h.synDataSent = min(int(req.data.Size()), int(h.ep.amss)-maxOptionSize)
+			synData = req.data.Clone()
+			synData.Truncate(int64(h.synDataSent))
+		}
+	}
+
 	h.sendSYNOpts = synOpts
-	h.ep.sendSynTCP(h.ep.route, tcpFields{
+	h.ep.sendSynDataTCP(h.ep.route, tcpFields{
 		id:        h.ep.TransportEndpointInfo.ID,
 		ttl:       calculateTTL(h.ep.route, h.ep.ipv4TTL, h.ep.ipv6HopLimit),
 		tos:       h.ep.sendTOS,
@@ -597,7 +661,7 @@ func (h *handshake) start() {
 		ack:       h.ackNum,
 		rcvWnd:    h.rcvWnd,
 		expOptVal: h.ep.getExperimentOptionValue(h.ep.route),
-	}, synOpts)
+	}, synOpts, synData)
 }
 
 // retransmitHandler handles retransmissions of un-acked SYNs.
@@ -625,6 +689,7 @@ func (h *handshake) retransmitHandlerLocked() tcpip.Error {
 	// the connection with another ACK or data (as ACKs are never
 	// retransmitted on their own).
 	if h.active || !h.acked || h.deferAccept != 0 && e.stack.Clock().NowMonotonic().Sub(h.startTime) > h.deferAccept {
+		h.clearECNRequest()
 		e.sendSynTCP(e.route, tcpFields{
 			id:        e.TransportEndpointInfo.ID,
 			ttl:       calculateTTL(e.route, e.ipv4TTL, e.ipv6HopLimit),
@@ -639,6 +704,11 @@ func (h *handshake) retransmitHandlerLocked() tcpip.Error {
 		// SYN segment, we should only measure RTT if
 		// TS option is present.
 		h.sampleRTTWithTSOnly = true
+		// Retransmitted SYNs don't carry data, which is instead sent
+		// once the handshake completes, like in Linux.
+		if h.active && h.synDataSent > 0 {
+			e.fastOpenClientFail = tcpip.TCPFastOpenSynRetransmitted
+		}
 	}
 	return nil
 }
@@ -657,7 +727,7 @@ func (h *handshake) transitionToStateEstablishedLocked(s *segment) {
 	// Transfer handshake state to TCP connection. We disable
 	// receive window scaling if the peer doesn't support it
 	// (indicated by a negative send window scale).
-	h.ep.snd = newSender(h.ep, h.iss, h.ackNum-1, h.sndWnd, h.mss, h.sndWndScale)
+	h.ep.snd = newSender(h.ep, h.iss.Add(h.synDataAcked), h.ackNum-1, h.sndWnd, h.mss, h.sndWndScale)
 
 	now := h.ep.stack.Clock().NowMonotonic()
 
@@ -683,6 +753,20 @@ func (h *handshake) transitionToStateEstablishedLocked(s *segment) {
 
 	h.ep.setEndpointState(StateEstablished)
 
+	// Deliver any data accepted in the SYN using TCP Fast Open. The receiver
+	// was initialized past it, as the data was acknowledged in the SYN-ACK.
+	// Unlike Linux, the endpoint is only made available to accept once the
+	// handshake completes.
+	if size := h.synData.Size(); size > 0 {
+		seg := newOutgoingSegment(h.ep.TransportEndpointInfo.ID, h.ep.stack.Clock(), h.synData)
+		h.synData = buffer.Buffer{}
+		seg.sequenceNumber = h.ackNum - seqnum.Value(size)
+		seg.setOwner(h.ep, recvQ)
+		h.ep.readyToRead(seg)
+		seg.DecRef()
+		h.ep.synDataAcked = true
+	}
+
 	// Completing the 3-way handshake is an indication that the route is valid
 	// and the remote is reachable as the only way we can complete a handshake
 	// is if our SYN reached the remote and their ACK reached us.
@@ -767,9 +851,17 @@ func makeSynOptions(opts header.TCPSynOptions) []byte {
 	//	cookie(variable) [padding to four bytes]
 	//
 	options := getOptions()
+	offset := 0
+
+	// The digest is filled in by buildTCPHdr.
+	if opts.MD5Sig {
+		offset += header.EncodeNOP(options[offset:])
+		offset += header.EncodeNOP(options[offset:])
+		offset += header.EncodeMD5SigOption(options[offset:])
+	}
 
 	// Always encode the mss.
-	offset := header.EncodeMSSOption(uint32(opts.MSS), options)
+	offset += header.EncodeMSSOption(uint32(opts.MSS), options[offset:])
 
 	// Special ordering is required here. If both TS and SACK are enabled,
 	// then the SACK option precedes TS, with no padding. If they are
@@ -793,12 +885,15 @@ func makeSynOptions(opts header.TCPSynOptions) []byte {
 		offset += header.EncodeWSOption(opts.WS, options[offset:])
 	}
 
-	// Padding to the end; note that this never apply unless we add a
-	// fastopen option, we always expect the offset to remain the same.
-	if delta := header.AddTCPOptionPadding(options, offset); delta != 0 {
-		panic("unexpected option encoding")
+	// Initialize the TCP Fast Open option. An empty cookie requests one.
+	if opts.FastOpen {
+		offset += header.EncodeFastOpenOption(opts.FastOpenCookie, options[offset:])
 	}
 
+	// Padding to the end; this only applies to the fastopen option as the
+	// other options are always quad aligned.
+	offset += header.AddTCPOptionPadding(options, offset)
+
 	return options[:offset]
 }
 
@@ -816,16 +911,30 @@ type tcpFields struct {
 	txHash    uint32
 	df        bool
 	expOptVal uint16
+
+	// md5Key is the key used to sign the segment with the TCP MD5
+	// signature option, which must then be the first option in opts.
+	md5Key []byte
 }
 
 func (e *Endpoint) sendSynTCP(r *stack.Route, tf tcpFields, opts header.TCPSynOptions) tcpip.Error {
+	return e.sendSynDataTCP(r, tf, opts, buffer.Buffer{})
+}
+
+// sendSynDataTCP is like sendSynTCP, but also sends data in the SYN as
+// done by TCP Fast Open. It takes ownership of data.
+func (e *Endpoint) sendSynDataTCP(r *stack.Route, tf tcpFields, opts header.TCPSynOptions, data buffer.Buffer) tcpip.Error {
+	if opts.MD5Sig {
+		tf.md5Key = e.md5KeyForRoute(r)
+		opts.MD5Sig = tf.md5Key != nil
+	}
 	tf.opts = makeSynOptions(opts)
 	// We ignore SYN send errors and let the callers re-attempt send.
 	hdrSize := header.TCPMinimumSize + int(r.MaxHeaderLength()) + len(tf.opts)
 	if r.NetProto() == header.IPv6ProtocolNumber && tf.expOptVal != 0 {
 		hdrSize += header.IPv6ExperimentHdrLength
 	}
-	p := stack.NewPacketBuffer(stack.PacketBufferOptions{ReserveHeaderBytes: hdrSize})
+	p := stack.NewPacketBuffer(stack.PacketBufferOptions{ReserveHeaderBytes: hdrSize, Payload: data})
 	defer p.DecRef()
 	if err := e.sendTCP(r, tf, p, stack.GSO{}); err != nil {
 		e.stats.SendErrors.SynSendToNetworkFailed.Increment()
@@ -859,6 +968,9 @@ func buildTCPHdr(r *stack.Route, tf tcpFields, pkt *stack.PacketBuffer, gso stac
 		WindowSize: uint16(tf.rcvWnd),
 	})
 	copy(tcp[header.TCPMinimumSize:], tf.opts)
+	if tf.md5Key != nil {
+		copy(tcp[md5DigestOffset:], md5Digest(tf.md5Key, r.LocalAddress(), r.RemoteAddress(), tcp, pkt.Data()))
+	}
 
 	xsum := r.PseudoHeaderChecksum(ProtocolNumber, uint16(pkt.Size()))
 	// Only calculate the checksum if offloading isn't supported.
@@ -966,14 +1078,20 @@ func sendTCP(r *stack.Route, tf tcpFields, pkt *stack.PacketBuffer, gso stack.GS
 	return nil
 }
 
-// makeOptions makes an options slice.
-func (e *Endpoint) makeOptions(sackBlocks []header.SACKBlock) []byte {
+// makeOptions makes an options slice. md5Sig is true if room must be made for
+// the TCP MD5 signature option.
+func (e *Endpoint) makeOptions(sackBlocks []header.SACKBlock, md5Sig bool) []byte {
 	options := getOptions()
 	offset := 0
 
 	// N.B. the ordering here matches the ordering used by Linux internally
 	// and described in the raw makeOptions function. We don't include
 	// unnecessary cases here (post connection.)
+	if md5Sig {
+		offset += header.EncodeNOP(options[offset:])
+		offset += header.EncodeNOP(options[offset:])
+		offset += header.EncodeMD5SigOption(options[offset:])
+	}
 	if e.SendTSOk {
 		// Embed the timestamp if timestamp has been enabled.
 		//
@@ -991,7 +1109,9 @@ func (e *Endpoint) makeOptions(sackBlocks []header.SACKBlock) []byte {
 		offset += header.EncodeNOP(options[offset:])
 		offset += header.EncodeTSOption(e.tsValNow(), e.recentTimestamp(), options[offset:])
 	}
-	if e.SACKPermitted && len(sackBlocks) > 0 {
+	// Only add SACK blocks if at least one fits, which is not the case
+	// when both the timestamp and MD5 signature options are used.
+	if e.SACKPermitted && len(sackBlocks) > 0 && len(options[offset:]) >= 4+8 {
 		offset += header.EncodeNOP(options[offset:])
 		offset += header.EncodeNOP(options[offset:])
 		offset += header.EncodeSACKBlocks(sackBlocks, options[offset:])
@@ -1012,20 +1132,22 @@ func (e *Endpoint) makeOptions(sackBlocks []header.SACKBlock) []byte {
 func (e *Endpoint) sendEmptyRaw(flags header.TCPFlags, seq, ack seqnum.Value, rcvWnd seqnum.Size) tcpip.Error {
 	pkt := stack.NewPacketBuffer(stack.PacketBufferOptions{})
 	defer pkt.DecRef()
-	return e.sendRaw(pkt, flags, seq, ack, rcvWnd)
+	return e.sendRaw(pkt, flags, seq, ack, rcvWnd, false /* ect */)
 }
 
 // sendRaw sends a TCP segment to the endpoint's peer. This method takes
-// ownership of pkt. pkt must not have any headers set.
+// ownership of pkt. pkt must not have any headers set. ect is true if the
+// segment carries new data, which is marked ECN-capable if ECN was negotiated.
 //
 // +checklocks:e.mu
 // +checklocksalias:e.snd.ep.mu=e.mu
-func (e *Endpoint) sendRaw(pkt *stack.PacketBuffer, flags header.TCPFlags, seq, ack seqnum.Value, rcvWnd seqnum.Size) tcpip.Error {
+func (e *Endpoint) sendRaw(pkt *stack.PacketBuffer, flags header.TCPFlags, seq, ack seqnum.Value, rcvWnd seqnum.Size, ect bool) tcpip.Error {
 	var sackBlocks []header.SACKBlock
 	if e.EndpointState() == StateEstablished && e.rcv.pendingRcvdSegments.Len() > 0 && (flags&header.TCPFlagAck != 0) {
 		sackBlocks = e.sack.Blocks[:e.sack.NumBlocks]
 	}
-	options := e.makeOptions(sackBlocks)
+	md5Key := e.md5KeyForRoute(e.route)
+	options := e.makeOptions(sackBlocks, md5Key != nil)
 	defer putOptions(options)
 	hdrSize := header.TCPMinimumSize + int(e.route.MaxHeaderLength()) + len(options)
 	expOptVal := e.getExperimentOptionValue(e.route)
@@ -1033,10 +1155,11 @@ func (e *Endpoint) sendRaw(pkt *stack.PacketBuffer, flags header.TCPFlags, seq,
 		hdrSize += header.IPv6ExperimentHdrLength
 	}
 	pkt.ReserveHeaderBytes(hdrSize)
+	flags, tos := e.ecnFields(flags, ect)
 	return e.sendTCP(e.route, tcpFields{
 		id:        e.TransportEndpointInfo.ID,
 		ttl:       calculateTTL(e.route, e.ipv4TTL, e.ipv6HopLimit),
-		tos:       e.sendTOS,
+		tos:       tos,
 		flags:     flags,
 		seq:       seq,
 		ack:       ack,
@@ -1044,6 +1167,7 @@ func (e *Endpoint) sendRaw(pkt *stack.PacketBuffer, flags header.TCPFlags, seq,
 		opts:      options,
 		df:        e.pmtud == tcpip.PMTUDiscoveryWant || e.pmtud == tcpip.PMTUDiscoveryDo,
 		expOptVal: expOptVal,
+		md5Key:    md5Key,
 	}, pkt, e.gso)
 }
 
@@ -1134,7 +1258,7 @@ func (e *Endpoint) tryDeliverSegmentFromClosedEndpoint(s *segment) {
 	}
 	if ep == nil {
 		if !s.flags.Contains(header.TCPFlagRst) {
-			replyWithReset(e.stack, s, stack.DefaultTOS, tcpip.UseDefaultIPv4TTL, tcpip.UseDefaultIPv6HopLimit)
+			replyWithReset(e.stack, s, stack.DefaultTOS, tcpip.UseDefaultIPv4TTL, tcpip.UseDefaultIPv6HopLimit, e.md5KeyFor(s.pkt.Network().SourceAddress(), s.pkt.NICID))
 		}
 		return
 	}
@@ -1435,6 +1559,13 @@ func (e *Endpoint) handshakeFailed(err tcpip.Error) {
 	if e.h != nil && e.h.retransmitTimer != nil {
 		e.h.retransmitTimer.stop()
 	}
+	if e.h != nil {
+		e.h.synData.Release()
+	}
+	if req := e.fastOpenReq; req != nil {
+		req.data.Release()
+		e.fastOpenReq = nil
+	}
 	e.hardError = err
 	e.cleanupLocked()
 	e.setEndpointState(StateError)
@@ -291,6 +291,17 @@ func (c *cubicState) HandleRTOExpired() {
 	c.s.SndCwnd = 1
 }
 
+// HandleCongestionExperienced implements
+// congestionControl.HandleCongestionExperienced.
+//
+// +checklocks:c.s.ep.mu
+func (c *cubicState) HandleCongestionExperienced() {
+	// Per RFC 3168 section 6.1.2, respond as if a packet was lost. The
+	// sender doesn't enter fast recovery, so reduce the window right away.
+	c.HandleLossDetected()
+	c.s.SndCwnd = c.s.Ssthresh
+}
+
 // fastConvergence implements the logic for Fast Convergence algorithm as
 // described in https://tools.ietf.org/html/rfc8312#section-4.6.
 func (c *cubicState) fastConvergence() {
@@ -309,6 +320,11 @@ func (c *cubicState) PostRecovery() {
 	c.T = c.s.ep.stack.Clock().NowMonotonic()
 }
 
+// GetInfo implements congestionControl.GetInfo.
+func (*cubicState) GetInfo(*tcpip.TCPCCInfoOption) {
+	// Like in Linux, CUBIC has no specific information to report.
+}
+
 // reduceSlowStartThreshold returns new SsThresh as described in
 // https://tools.ietf.org/html/rfc8312#section-4.7.
 //
@@ -107,7 +107,7 @@ func deliverAccepted(ep *Endpoint) bool {
 
 	// Remove endpoint from list of pendingEndpoints as the handshake is now
 	// complete.
-	delete(lEP.acceptQueue.pendingEndpoints, ep)
+	lEP.acceptQueue.removePending(ep)
 	// Deliver this endpoint to the listening socket's accept queue.
 	if lEP.acceptQueue.capacity == 0 {
 		lEP.acceptMu.Unlock()
@@ -154,7 +154,7 @@ func handleConnecting(ep *Endpoint) {
 		// state.
 		if lEP := ep.h.listenEP; lEP != nil {
 			lEP.acceptMu.Lock()
-			delete(lEP.acceptQueue.pendingEndpoints, ep)
+			lEP.acceptQueue.removePending(ep)
 			lEP.acceptMu.Unlock()
 		}
 		ep.handshakeFailed(err)
@@ -456,6 +456,24 @@ func (d *dispatcher) queuePacket(stackEP stack.TransportEndpoint, id stack.Trans
 		return
 	}
 
+	// Segments without the expected TCP MD5 signature are silently
+	// dropped, as described in RFC 2385 section 2.0.
+	if !ep.verifyMD5(s) {
+		ep.stack.Stats().DroppedPackets.Increment()
+		return
+	}
+
+	// The attached socket filter can't truncate the TCP header, like in
+	// Linux.
+	snapLen, ok := stack.RunSocketFilter(&ep.ops, s.pkt, stack.SocketFilterTransportHeader)
+	if !ok {
+		ep.stack.Stats().DroppedPackets.Increment()
+		return
+	}
+	if dataLen := max(snapLen-len(s.pkt.TransportHeader().Slice()), 0); dataLen < s.payloadSize() {
+		s.pkt.Data().CapLength(dataLen)
+	}
+
 	ep.stack.Stats().TCP.ValidSegmentsReceived.Increment()
 	ep.stats.SegmentsReceived.Increment()
 	if (s.flags & header.TCPFlagRst) != 0 {
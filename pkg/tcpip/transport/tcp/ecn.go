@@ -0,0 +1,147 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcp
+
+import (
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+)
+
+// ecnSetupFlags are the flags set in a SYN to request ECN, as described in
+// RFC 3168 section 6.1.1.
+const ecnSetupFlags = header.TCPFlagEce | header.TCPFlagCwr
+
+// ecnMode returns the ECN mode of the protocol, as set with the tcp_ecn
+// sysctl.
+func (p *protocol) ecnMode() tcpip.TCPECNOption {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.ecn
+}
+
+// requestECN adds the flags requesting ECN to the SYN of an active open if
+// the protocol is configured to do so.
+func (h *handshake) requestECN() {
+	if h.ep.protocol.ecnMode() == tcpip.TCPECNEnabled {
+		h.flags |= ecnSetupFlags
+	}
+}
+
+// clearECNRequest stops requesting ECN in retransmitted SYNs, like in Linux,
+// in case the SYN was dropped by a middlebox because of the ECN flags.
+func (h *handshake) clearECNRequest() {
+	if h.active && h.state == handshakeSynSent {
+		h.flags &^= ecnSetupFlags
+	}
+}
+
+// negotiateECN records whether ECN was negotiated from the SYN or SYN-ACK s
+// received in response to the SYN of an active open.
+//
+// +checklocks:h.ep.mu
+func (h *handshake) negotiateECN(s *segment) {
+	requested := h.flags.Contains(ecnSetupFlags)
+	h.flags &^= ecnSetupFlags
+	// ECN is only negotiated if the SYN-ACK has ECE set and CWR clear. It
+	// isn't negotiated in a simultaneous open, like in Linux.
+	h.ep.ecn = requested && s.flags.Contains(header.TCPFlagAck) && s.flags&ecnSetupFlags == header.TCPFlagEce
+}
+
+// acceptECN negotiates ECN for a passive open if the SYN s requested it and
+// the protocol allows it.
+//
+// +checklocks:h.ep.mu
+func (h *handshake) acceptECN(s *segment) {
+	if h.ep.protocol.ecnMode() == tcpip.TCPECNDisabled || !s.flags.Contains(ecnSetupFlags) {
+		return
+	}
+	h.flags |= header.TCPFlagEce
+	h.ep.ecn = true
+}
+
+// ecnFields returns the flags and the TOS or Traffic Class to use for a
+// segment with flags sent by an endpoint that negotiated ECN. ect is true if
+// the segment carries new data, which is the only kind that may be marked
+// ECN-capable.
+//
+// +checklocks:e.mu
+// +checklocksalias:e.snd.ep.mu=e.mu
+func (e *Endpoint) ecnFields(flags header.TCPFlags, ect bool) (header.TCPFlags, uint8) {
+	tos := e.sendTOS
+	if !e.ecn {
+		return flags, tos
+	}
+	if ect {
+		tos |= header.ECNECT0
+		// Signal that the congestion window was reduced in response to
+		// ECE, as described in RFC 3168 section 6.1.2.
+		if e.snd.ecnCWR {
+			flags |= header.TCPFlagCwr
+			e.snd.ecnCWR = false
+		}
+	}
+	// Echo congestion experienced marks until the peer reduces its
+	// congestion window, as described in RFC 3168 section 6.1.3.
+	if e.rcv != nil && e.rcv.ecnEcho && flags&(header.TCPFlagAck|header.TCPFlagSyn|header.TCPFlagRst) == header.TCPFlagAck {
+		flags |= header.TCPFlagEce
+	}
+	return flags, tos
+}
+
+// handleECN updates the state of the receiver for the ECN codepoint and flags
+// of the acceptable segment s.
+//
+// +checklocks:r.ep.mu
+func (r *receiver) handleECN(s *segment) {
+	if !r.ep.ecn {
+		return
+	}
+	if s.flags.Contains(header.TCPFlagCwr) {
+		r.ecnEcho = false
+	}
+	if s.ce {
+		r.ecnEcho = true
+		r.ecnSeen = true
+	}
+}
+
+// handleECE reduces the congestion window when the acknowledgement s echoes a
+// congestion experienced mark, at most once per window of data, as described
+// in RFC 3168 section 6.1.2.
+//
+// +checklocks:s.ep.mu
+func (s *sender) handleECE(seg *segment) {
+	if !s.ep.ecn || !seg.flags.Contains(header.TCPFlagEce) || s.FastRecovery.Active || s.state == tcpip.RTORecovery {
+		return
+	}
+	if !s.ecnRecover.LessThan(seg.ackNumber) {
+		return
+	}
+	s.cc.HandleCongestionExperienced()
+	s.ecnWindowReduced()
+}
+
+// ecnWindowReduced records that the congestion window was reduced, in response
+// to ECE or loss, so that the peer stops echoing congestion experienced marks
+// and further ECE is ignored for the current window of data.
+//
+// +checklocks:s.ep.mu
+func (s *sender) ecnWindowReduced() {
+	if !s.ep.ecn {
+		return
+	}
+	s.ecnRecover = s.SndNxt
+	s.ecnCWR = true
+}
@@ -607,6 +607,77 @@ type Endpoint struct {
 	//
 	// +checklocks:mu
 	alsoBindToV4 bool
+
+	// fastOpenQueueLen is the maximum number of pending connections that
+	// may carry data in their SYN on a listening endpoint, as set by the
+	// TCP_FASTOPEN socket option. TCP Fast Open is disabled on the
+	// listener when it is zero.
+	//
+	// +checklocks:mu
+	fastOpenQueueLen int
+
+	// fastOpenConnect is true if the TCP_FASTOPEN_CONNECT socket option is
+	// set, in which case connect defers the SYN to the first write when a
+	// cookie is available.
+	//
+	// +checklocks:mu
+	fastOpenConnect bool
+
+	// fastOpenNoCookie is true if the TCP_FASTOPEN_NO_COOKIE socket option
+	// is set, in which case data is sent in (or accepted from) a SYN
+	// without a cookie.
+	//
+	// +checklocks:mu
+	fastOpenNoCookie bool
+
+	// fastOpenKey is the key set with the TCP_FASTOPEN_KEY socket option on
+	// a listening endpoint. The protocol's key is used when it is nil.
+	//
+	// +checklocks:mu
+	fastOpenKey *tcpip.TCPFastOpenKeyOption
+
+	// fastOpenDeferred is true if a connect with TCP_FASTOPEN_CONNECT
+	// has been deferred to the first write, which will connect to
+	// fastOpenAddr. It is only modified with mu held.
+	fastOpenDeferred atomicbitops.Bool
+
+	// +checklocks:mu
+	fastOpenAddr tcpip.FullAddress
+
+	// fastOpenReq holds the data and cookie to send in the SYN of an active
+	// open using TCP Fast Open. It is nil once the handshake completes.
+	//
+	// +checklocks:mu
+	fastOpenReq *fastOpenRequest `state:"nosave"`
+
+	// synDataAcked is true if the data sent or received in the SYN was
+	// acknowledged, as reported by TCPI_OPT_SYN_DATA.
+	//
+	// +checklocks:mu
+	synDataAcked bool
+
+	// fastOpenClientFail records why an active open using TCP Fast Open
+	// fell back to a regular handshake.
+	//
+	// +checklocks:mu
+	fastOpenClientFail tcpip.TCPFastOpenClientFail
+
+	// ecn is true if ECN was negotiated during the handshake, as described
+	// in RFC 3168 section 6.1.1.
+	//
+	// +checklocks:mu
+	ecn bool
+
+	// md5Mu protects md5Keys, which are also looked up when segments are
+	// dispatched without holding mu.
+	md5Mu md5KeysRWMutex `state:"nosave"`
+
+	// md5Keys are the TCP MD5 signature keys set with TCP_MD5SIG or
+	// TCP_MD5SIG_EXT, or inherited from the listening endpoint, as
+	// described in RFC 2385.
+	//
+	// +checklocks:md5Mu
+	md5Keys []md5Key
 }
 
 // calculateAdvertisedMSS calculates the MSS to advertise.
@@ -931,6 +1002,12 @@ func (e *Endpoint) Readiness(mask waiter.EventMask) waiter.EventMask {
 
 	switch e.EndpointState() {
 	case StateInitial, StateBound:
+		if e.fastOpenDeferred.Load() {
+			// The connect was deferred to the first write by
+			// TCP_FASTOPEN_CONNECT, so the endpoint is writable.
+			result |= mask & waiter.WritableEvents
+			break
+		}
 		// This prevents blocking of new sockets which are not
 		// connected when SO_LINGER is set.
 		result |= waiter.EventHUp
@@ -1160,6 +1237,7 @@ func (e *Endpoint) closePendingAcceptableConnectionsLocked() {
 
 	pendingEndpoints := e.acceptQueue.pendingEndpoints
 	e.acceptQueue.pendingEndpoints = nil
+	e.acceptQueue.fastOpenPending = 0
 
 	completedEndpoints := make([]*Endpoint, 0, e.acceptQueue.endpoints.Len())
 	for n := e.acceptQueue.endpoints.Front(); n != nil; n = n.Next() {
@@ -1188,6 +1266,7 @@ func (e *Endpoint) cleanupLocked() {
 		e.snd.probeTimer.cleanup()
 		e.snd.reorderTimer.cleanup()
 		e.snd.corkTimer.cleanup()
+		e.snd.pacingTimer.cleanup()
 	}
 
 	if e.finWait2Timer != nil {
@@ -1415,6 +1494,9 @@ func (e *Endpoint) Read(dst io.Writer, opts tcpip.ReadOptions) (tcpip.ReadResult
 
 	var err error
 	done := 0
+	// rxTimestamp is the time at which the last segment read from was
+	// received.
+	var rxTimestamp time.Time
 	// N.B. Here we get the first segment to be processed. It is safe to not
 	// hold rcvQueueMu when processing, since we hold e.mu to ensure we only
 	// remove segments from the list through Read() and that new segments
@@ -1425,6 +1507,9 @@ func (e *Endpoint) Read(dst io.Writer, opts tcpip.ReadOptions) (tcpip.ReadResult
 		n, err = s.ReadTo(dst, opts.Peek)
 		// Book keeping first then error handling.
 		done += n
+		if n > 0 {
+			rxTimestamp = s.pkt.RXTimestamp
+		}
 
 		if opts.Peek {
 			s = s.Next()
@@ -1473,6 +1558,10 @@ func (e *Endpoint) Read(dst io.Writer, opts tcpip.ReadOptions) (tcpip.ReadResult
 	return tcpip.ReadResult{
 		Count: done,
 		Total: done,
+		ControlMessages: tcpip.ReceivableControlMessages{
+			HasTimestamp: !rxTimestamp.IsZero(),
+			Timestamp:    rxTimestamp,
+		},
 	}, nil
 }
 
@@ -1563,6 +1652,9 @@ func (e *Endpoint) readFromPayloader(p tcpip.Payloader, opts tcpip.WriteOptions,
 	// available buffer space to be consumed by some other caller while we
 	// are copying data in.
 	limRdr := e.limRdr
+	// Like in Linux, data that is delivered locally is always copied since
+	// it may be held by the receiver indefinitely.
+	copyData := e.route == nil || e.route.DeliversLocally()
 	if !opts.Atomic {
 		defer func() {
 			e.limRdr = limRdr
@@ -1584,6 +1676,14 @@ func (e *Endpoint) readFromPayloader(p tcpip.Payloader, opts tcpip.WriteOptions,
 	if avail == 0 {
 		return payload, nil
 	}
+	if zp, ok := p.(tcpip.ZeroCopyPayloader); ok {
+		buf, err := zp.ReadBuffer(avail, copyData)
+		if err != nil {
+			buf.Release()
+			return buffer.Buffer{}, &tcpip.ErrBadBuffer{}
+		}
+		return buf, nil
+	}
 	if _, err := payload.WriteFromReaderAndLimitedReader(p, int64(avail), limRdr); err != nil {
 		payload.Release()
 		return buffer.Buffer{}, &tcpip.ErrBadBuffer{}
@@ -1635,6 +1735,7 @@ func (e *Endpoint) queueSegment(p tcpip.Payloader, opts tcpip.WriteOptions) (*se
 	// Add data to the send queue.
 	size := int(buf.Size())
 	s := newOutgoingSegment(e.TransportEndpointInfo.ID, e.stack.Clock(), buf)
+	s.txTimestamps = stack.NewTXTimestampRequest(&e.ops, e.stack.Clock(), e.NetProto)
 	e.sndQueueInfo.SndBufUsed += size
 	e.snd.writeList.PushBack(s)
 
@@ -1650,6 +1751,10 @@ func (e *Endpoint) Write(p tcpip.Payloader, opts tcpip.WriteOptions) (int64, tcp
 	e.LockUser()
 	defer e.UnlockUser()
 
+	if opts.FastOpen || e.fastOpenDeferred.Load() {
+		return e.fastOpenWriteLocked(p, opts)
+	}
+
 	// Return if either we didn't queue anything or if an error occurred while
 	// attempting to queue data.
 	nextSeg, n, err := e.queueSegment(p, opts)
@@ -1834,6 +1939,31 @@ func (e *Endpoint) OnSetSendBufferSize(sz int64) int64 {
 	return sz
 }
 
+// OnTimestampingIDSet implements tcpip.SocketOptionsHandler.OnTimestampingIDSet.
+// Like in Linux, the IDs of TX timestamps count bytes from the first
+// unacknowledged one, or from the end of the send queue if fromWriteSeq is
+// true.
+func (e *Endpoint) OnTimestampingIDSet(fromWriteSeq bool) (uint32, tcpip.Error) {
+	e.LockUser()
+	defer e.UnlockUser()
+
+	if !e.EndpointState().connected() {
+		return 0, &tcpip.ErrInvalidEndpointState{}
+	}
+	key := e.snd.SndUna
+	if fromWriteSeq {
+		e.sndQueueInfo.sndQueueMu.Lock()
+		key = key.Add(seqnum.Size(e.sndQueueInfo.SndBufUsed))
+		e.sndQueueInfo.sndQueueMu.Unlock()
+	}
+	return uint32(key), nil
+}
+
+// WakeupErrQueueReaders implements tcpip.SocketOptionsHandler.WakeupErrQueueReaders.
+func (e *Endpoint) WakeupErrQueueReaders() {
+	e.waiterQueue.Notify(waiter.EventErr)
+}
+
 // WakeupWriters implements tcpip.SocketOptionsHandler.WakeupWriters.
 func (e *Endpoint) WakeupWriters() {
 	e.LockUser()
@@ -1851,9 +1981,6 @@ func (e *Endpoint) WakeupWriters() {
 
 // SetSockOptInt sets a socket option.
 func (e *Endpoint) SetSockOptInt(opt tcpip.SockOptInt, v int) tcpip.Error {
-	// Lower 2 bits represents ECN bits. RFC 3168, section 23.1
-	const inetECNMask = 3
-
 	switch opt {
 	case tcpip.KeepaliveCountOption:
 		e.LockUser()
@@ -1865,16 +1992,16 @@ func (e *Endpoint) SetSockOptInt(opt tcpip.SockOptInt, v int) tcpip.Error {
 
 	case tcpip.IPv4TOSOption:
 		e.LockUser()
-		// TODO(gvisor.dev/issue/995): ECN is not currently supported,
-		// ignore the bits for now.
-		e.sendTOS = uint8(v) & ^uint8(inetECNMask)
+		// Like in Linux, the ECN bits are controlled by the stack
+		// once ECN is negotiated, so ignore them.
+		e.sendTOS = uint8(v) &^ header.ECNMask
 		e.UnlockUser()
 
 	case tcpip.IPv6TrafficClassOption:
 		e.LockUser()
-		// TODO(gvisor.dev/issue/995): ECN is not currently supported,
-		// ignore the bits for now.
-		e.sendTOS = uint8(v) & ^uint8(inetECNMask)
+		// Like in Linux, the ECN bits are controlled by the stack
+		// once ECN is negotiated, so ignore them.
+		e.sendTOS = uint8(v) &^ header.ECNMask
 		e.UnlockUser()
 
 	case tcpip.MaxSegOption:
@@ -1940,6 +2067,48 @@ func (e *Endpoint) SetSockOptInt(opt tcpip.SockOptInt, v int) tcpip.Error {
 		e.LockUser()
 		e.windowClamp = uint32(v)
 		e.UnlockUser()
+
+	case tcpip.TCPFastOpenOption:
+		e.LockUser()
+		defer e.UnlockUser()
+		switch e.EndpointState() {
+		case StateInitial, StateBound, StateClose, StateListen:
+		default:
+			return &tcpip.ErrInvalidOptionValue{}
+		}
+		if v < 0 {
+			return &tcpip.ErrInvalidOptionValue{}
+		}
+		e.fastOpenQueueLen = v
+
+	case tcpip.TCPFastOpenConnectOption:
+		if v < 0 || v > 1 {
+			return &tcpip.ErrInvalidOptionValue{}
+		}
+		if e.protocol.fastOpenMode()&tcpip.TCPFastOpenClient == 0 {
+			return &tcpip.ErrNotSupported{}
+		}
+		e.LockUser()
+		defer e.UnlockUser()
+		switch e.EndpointState() {
+		case StateInitial, StateBound, StateClose:
+		default:
+			return &tcpip.ErrInvalidOptionValue{}
+		}
+		e.fastOpenConnect = v != 0
+
+	case tcpip.TCPFastOpenNoCookieOption:
+		if v < 0 || v > 1 {
+			return &tcpip.ErrInvalidOptionValue{}
+		}
+		e.LockUser()
+		defer e.UnlockUser()
+		switch e.EndpointState() {
+		case StateInitial, StateBound, StateClose, StateListen:
+		default:
+			return &tcpip.ErrInvalidOptionValue{}
+		}
+		e.fastOpenNoCookie = v != 0
 	}
 	return nil
 }
@@ -2033,8 +2202,16 @@ func (e *Endpoint) SetSockOpt(opt tcpip.SettableSocketOption) tcpip.Error {
 		e.deferAccept = time.Duration(*v)
 		e.UnlockUser()
 
-	case *tcpip.SocketDetachFilterOption:
-		return nil
+	case *tcpip.TCPFastOpenKeyOption:
+		e.LockUser()
+		key := *v
+		e.fastOpenKey = &key
+		e.UnlockUser()
+
+	case *tcpip.TCPMD5SigOption:
+		e.LockUser()
+		defer e.UnlockUser()
+		return e.setMD5Key(v)
 
 	default:
 		return nil
@@ -2125,6 +2302,30 @@ func (e *Endpoint) GetSockOptInt(opt tcpip.SockOptInt) (int, tcpip.Error) {
 		e.UnlockUser()
 		return v, nil
 
+	case tcpip.TCPFastOpenOption:
+		e.LockUser()
+		v := e.fastOpenQueueLen
+		e.UnlockUser()
+		return v, nil
+
+	case tcpip.TCPFastOpenConnectOption:
+		e.LockUser()
+		v := e.fastOpenConnect
+		e.UnlockUser()
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+
+	case tcpip.TCPFastOpenNoCookieOption:
+		e.LockUser()
+		v := e.fastOpenNoCookie
+		e.UnlockUser()
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+
 	case tcpip.MulticastTTLOption:
 		return 1, nil
 
@@ -2151,12 +2352,66 @@ func (e *Endpoint) getTCPInfo() tcpip.TCPInfoOption {
 		info.RTTVar = snd.rtt.TCPRTTState.RTTVar
 		snd.rtt.Unlock()
 
+		now := e.stack.Clock().NowMonotonic()
 		info.RTO = snd.RTO
 		info.CcState = snd.state
 		info.SndSsthresh = uint32(snd.Ssthresh)
 		info.SndCwnd = uint32(snd.SndCwnd)
 		info.ReorderSeen = snd.rc.Reord
+		info.ReorderCount = snd.rc.reordSeen
+		info.Reordering = nDupAckThreshold
+		info.Backoff = snd.rtoBackoff
+		info.Probes = snd.unackZeroWindowProbes
+		info.TimestampsEnabled = e.SendTSOk
+		info.SACKPermitted = e.SACKPermitted
+		info.ECN = e.ecn
+		info.SndWndScale = snd.SndWndScale
+		info.SndMSS = uint32(snd.MaxPayloadSize)
+		info.RcvMSS = uint32(e.amss)
+		info.Unacked = uint32(snd.Outstanding)
+		info.Sacked = uint32(snd.SackedOut)
+		info.TotalRetrans = uint32(e.stats.SendErrors.Retransmits.Value())
+		info.LastDataSent = now.Sub(snd.LastSendTime)
+		info.MinRTT = snd.minRTT
+		info.BytesAcked = snd.bytesAcked
+		info.BytesSent = snd.bytesSent
+		info.BytesRetrans = snd.bytesRetrans
+		info.DataSegsOut = snd.dataSegsOut
+		info.Delivered = uint32(snd.rate.delivered)
+		info.DeliveryRate = snd.rate.rate
+		info.DeliveryRateAppLimited = snd.rate.rateAppLimited
+		info.PacingRate = snd.pacingRate
+		info.DSACKDups = snd.dsackDups
+
+		// Sent segments precede writeNext in the write list.
+		seg := snd.writeList.Front()
+		for ; seg != nil && seg != snd.writeNext; seg = seg.Next() {
+			if seg.lost {
+				info.Lost += uint32(snd.pCount(seg, snd.MaxPayloadSize))
+			}
+			if seg.xmitCount > 1 && !seg.acked {
+				info.Retrans += uint32(snd.pCount(seg, snd.MaxPayloadSize))
+			}
+		}
+		for ; seg != nil; seg = seg.Next() {
+			info.NotSentBytes += uint32(seg.payloadSize())
+		}
+
+		if rcv := e.rcv; rcv != nil {
+			info.RcvWndScale = rcv.RcvWndScale
+			info.LastAckRecv = now.Sub(rcv.lastRcvdAckTime)
+			if rcv.lastRcvdDataTime != (tcpip.MonotonicTime{}) {
+				info.LastDataRecv = now.Sub(rcv.lastRcvdDataTime)
+			}
+			info.BytesReceived = rcv.bytesReceived
+			info.DataSegsIn = rcv.dataSegsIn
+			info.ECNSeen = rcv.ecnSeen
+		}
 	}
+	info.SegsOut = uint32(e.stats.SegmentsSent.Value())
+	info.SegsIn = uint32(e.stats.SegmentsReceived.Value())
+	info.SynDataAcked = e.synDataAcked
+	info.FastOpenClientFail = e.fastOpenClientFail
 	e.UnlockUser()
 	return info
 }
@@ -2167,6 +2422,14 @@ func (e *Endpoint) GetSockOpt(opt tcpip.GettableSocketOption) tcpip.Error {
 	case *tcpip.TCPInfoOption:
 		*o = e.getTCPInfo()
 
+	case *tcpip.TCPCCInfoOption:
+		e.LockUser()
+		*o = tcpip.TCPCCInfoOption{Algorithm: e.cc}
+		if snd := e.snd; snd != nil {
+			snd.cc.GetInfo(o)
+		}
+		e.UnlockUser()
+
 	case *tcpip.KeepaliveIdleOption:
 		e.keepalive.Lock()
 		*o = tcpip.KeepaliveIdleOption(e.keepalive.idle)
@@ -2197,6 +2460,11 @@ func (e *Endpoint) GetSockOpt(opt tcpip.GettableSocketOption) tcpip.Error {
 		*o = tcpip.TCPDeferAcceptOption(e.deferAccept)
 		e.UnlockUser()
 
+	case *tcpip.TCPFastOpenKeyOption:
+		e.LockUser()
+		o.Primary, o.Backup, o.HasBackup = e.fastOpenKeysLocked()
+		e.UnlockUser()
+
 	case *tcpip.OriginalDestinationOption:
 		e.LockUser()
 		ipt := e.stack.IPTables()
@@ -2236,6 +2504,12 @@ func (*Endpoint) Disconnect() tcpip.Error {
 func (e *Endpoint) Connect(addr tcpip.FullAddress) tcpip.Error {
 	e.LockUser()
 	defer e.UnlockUser()
+	if e.fastOpenDeferred.Load() {
+		return &tcpip.ErrAlreadyConnected{}
+	}
+	if e.fastOpenConnect && e.fastOpenConnectLocked(addr) {
+		return nil
+	}
 	err := e.connect(addr, true)
 	if err != nil {
 		if !err.IgnoreStats() {
@@ -2643,7 +2917,7 @@ func (e *Endpoint) listen(backlog int) tcpip.Error {
 		e.acceptQueue.capacity = backlog
 
 		if e.acceptQueue.pendingEndpoints == nil {
-			e.acceptQueue.pendingEndpoints = make(map[*Endpoint]struct{})
+			e.acceptQueue.pendingEndpoints = make(map[*Endpoint]bool)
 		}
 
 		e.shutdownFlags = 0
@@ -2688,7 +2962,7 @@ func (e *Endpoint) listen(backlog int) tcpip.Error {
 	// endpoints.
 	e.acceptMu.Lock()
 	if e.acceptQueue.pendingEndpoints == nil {
-		e.acceptQueue.pendingEndpoints = make(map[*Endpoint]struct{})
+		e.acceptQueue.pendingEndpoints = make(map[*Endpoint]bool)
 	}
 	if e.acceptQueue.capacity == 0 {
 		e.acceptQueue.capacity = backlog
@@ -2844,6 +3118,10 @@ func (e *Endpoint) GetRemoteAddress() (tcpip.FullAddress, tcpip.Error) {
 	e.LockUser()
 	defer e.UnlockUser()
 
+	if e.fastOpenDeferred.Load() {
+		return e.fastOpenAddr, nil
+	}
+
 	if !e.EndpointState().connected() {
 		return tcpip.FullAddress{}, &tcpip.ErrNotConnected{}
 	}
@@ -2920,7 +3198,7 @@ func (e *Endpoint) onICMPError(err tcpip.Error, transErr stack.TransportError, p
 		if lEP := e.h.listenEP; lEP != nil {
 			// Remove from listening endpoints pending list.
 			lEP.acceptMu.Lock()
-			delete(lEP.acceptQueue.pendingEndpoints, e)
+			lEP.acceptQueue.removePending(e)
 			lEP.acceptMu.Unlock()
 			lEP.stats.FailedConnectionAttempts.Increment()
 		}
@@ -3150,7 +3428,7 @@ func (e *Endpoint) maybeEnableSACKPermitted(synOpts header.TCPSynOptions) {
 // maxOptionSize return the maximum size of TCP options.
 func (e *Endpoint) maxOptionSize() (size int) {
 	var maxSackBlocks [header.TCPMaxSACKBlocks]header.SACKBlock
-	options := e.makeOptions(maxSackBlocks[:])
+	options := e.makeOptions(maxSackBlocks[:], e.md5KeyForRoute(e.route) != nil)
 	size = len(options)
 	putOptions(options)
 
@@ -3217,6 +3495,10 @@ func (e *Endpoint) initHostGSO() {
 }
 
 func (e *Endpoint) initGSO() {
+	// Segments signed with TCP MD5 can't be split.
+	if e.hasMD5Keys() {
+		return
+	}
 	if e.route.HasHostGSOCapability() {
 		e.initHostGSO()
 	} else if e.route.HasGVisorGSOCapability() {
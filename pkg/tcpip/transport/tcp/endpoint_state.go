@@ -148,6 +148,7 @@ func (e *Endpoint) Restore(s *stack.Stack) {
 		snd.reorderTimer.init(s.Clock(), timerHandler(e, e.snd.rc.reorderTimerExpired))
 		snd.probeTimer.init(s.Clock(), timerHandler(e, e.snd.probeTimerExpired))
 		snd.corkTimer.init(s.Clock(), timerHandler(e, e.snd.corkTimerExpired))
+		snd.pacingTimer.init(s.Clock(), timerHandler(e, e.snd.pacingTimerExpired))
 	}
 	saveRestoreEnabled := e.stack.IsSaveRestoreEnabled()
 	if !saveRestoreEnabled {
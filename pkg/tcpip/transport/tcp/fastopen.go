@@ -0,0 +1,302 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcp
+
+import (
+	"bytes"
+	"crypto/sha256"
+
+	"gvisor.dev/gvisor/pkg/buffer"
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+	"gvisor.dev/gvisor/pkg/tcpip/seqnum"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+)
+
+const (
+	// fastOpenCookieLen is the length of the TCP Fast Open cookies generated
+	// by listening endpoints, like in Linux.
+	fastOpenCookieLen = 8
+
+	// maxFastOpenCookies is the maximum number of TCP Fast Open cookies
+	// cached for servers. An arbitrary cookie is evicted when it is reached.
+	maxFastOpenCookies = 1024
+)
+
+// fastOpenRequest holds the state of an active open using TCP Fast Open (RFC
+// 7413) until the handshake completes.
+type fastOpenRequest struct {
+	// cookie is the cookie to send in the SYN. The SYN requests a cookie
+	// from the server if it is nil.
+	cookie []byte
+
+	// noCookie is true if data is sent in the SYN without a cookie.
+	noCookie bool
+
+	// data is the data written by the application when the handshake was
+	// started. Its prefix is sent in the SYN, and the rest once the
+	// handshake completes.
+	data buffer.Buffer
+}
+
+// fastOpenMode returns the TCP Fast Open mode of the protocol, as set with
+// the tcp_fastopen sysctl.
+func (p *protocol) fastOpenMode() tcpip.TCPFastOpen {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.fastOpen
+}
+
+// fastOpenCookie returns the cookie cached for the server at addr, or nil.
+func (p *protocol) fastOpenCookie(addr tcpip.Address) []byte {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.fastOpenCookies[addr]
+}
+
+// cacheFastOpenCookie caches the cookie received from the server at addr.
+func (p *protocol) cacheFastOpenCookie(addr tcpip.Address, cookie []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.fastOpenCookies[addr]; !ok && len(p.fastOpenCookies) >= maxFastOpenCookies {
+		for a := range p.fastOpenCookies {
+			delete(p.fastOpenCookies, a)
+			break
+		}
+	}
+	p.fastOpenCookies[addr] = cookie
+}
+
+// generateFastOpenCookie generates the TCP Fast Open cookie for the client of
+// the connection identified by id, as recommended by RFC 7413 section 4.1.2.
+func generateFastOpenCookie(key [tcpip.TCPFastOpenKeyLength]byte, id stack.TransportEndpointID) []byte {
+	h := sha256.New()
+
+	// Per hash.Hash.Writer:
+	//
+	// It never returns an error.
+	_, _ = h.Write(key[:])
+	_, _ = h.Write(id.RemoteAddress.AsSlice())
+	_, _ = h.Write(id.LocalAddress.AsSlice())
+	return h.Sum(nil)[:fastOpenCookieLen]
+}
+
+// fastOpenKeysLocked returns the keys used by a listening endpoint to generate
+// and validate cookies. The backup key is only used for validation.
+//
+// +checklocks:e.mu
+func (e *Endpoint) fastOpenKeysLocked() (primary, backup [tcpip.TCPFastOpenKeyLength]byte, hasBackup bool) {
+	if k := e.fastOpenKey; k != nil {
+		return k.Primary, k.Backup, k.HasBackup
+	}
+	return e.protocol.fastOpenKey, backup, false
+}
+
+// acceptFastOpenLocked decides how the passive handshake h started by SYN
+// segment s uses TCP Fast Open. The data carried by s is accepted if it has a
+// valid cookie (or cookies aren't required) and the listener's TCP Fast Open
+// queue isn't full. A cookie is sent in the SYN-ACK if the peer requested one
+// or sent a cookie that isn't valid with the primary key.
+//
+// Precondition: it must be called before the SYN-ACK is sent.
+//
+// +checklocks:e.mu
+// +checklocks:e.acceptMu
+func (e *Endpoint) acceptFastOpenLocked(h *handshake, s *segment, opts header.TCPSynOptions) {
+	mode := e.protocol.fastOpenMode()
+	if mode&tcpip.TCPFastOpenServer == 0 {
+		return
+	}
+	qlen := e.fastOpenQueueLen
+	if qlen == 0 && mode&tcpip.TCPFastOpenServerAnyListener != 0 {
+		// The listen backlog is used as the queue length.
+		qlen = e.acceptQueue.capacity - 1
+	}
+	if qlen <= 0 {
+		return
+	}
+	noCookie := e.fastOpenNoCookie || mode&tcpip.TCPFastOpenServerNoCookie != 0
+	if !opts.FastOpen && !noCookie {
+		return
+	}
+
+	primary, backup, hasBackup := e.fastOpenKeysLocked()
+	cookie := generateFastOpenCookie(primary, s.id)
+	valid := bytes.Equal(opts.FastOpenCookie, cookie)
+	validBackup := !valid && hasBackup && len(opts.FastOpenCookie) != 0 && bytes.Equal(opts.FastOpenCookie, generateFastOpenCookie(backup, s.id))
+
+	size := s.payloadSize()
+	accept := size > 0 && (valid || validBackup || noCookie) && e.acceptQueue.fastOpenPending < qlen && seqnum.Size(size) <= h.rcvWnd
+	if opts.FastOpen && !valid {
+		// Send the peer a cookie generated with the primary key.
+		h.fastOpenCookie = cookie
+	}
+	if !accept {
+		return
+	}
+	h.fastOpen = true
+	h.synData = s.pkt.Data().ToBuffer()
+	h.ackNum = h.ackNum.Add(seqnum.Size(size))
+}
+
+// fastOpenWriteLocked starts an active open using TCP Fast Open, sending the
+// data read from p in the SYN if a cookie is cached for the server (or cookies
+// aren't required). Otherwise, the SYN requests a cookie and no data is read.
+// It is used by writes with MSG_FASTOPEN and by the first write after a
+// connect deferred by TCP_FASTOPEN_CONNECT.
+//
+// +checklocks:e.mu
+// +checklocksalias:e.snd.ep.mu=e.mu
+func (e *Endpoint) fastOpenWriteLocked(p tcpip.Payloader, opts tcpip.WriteOptions) (int64, tcpip.Error) {
+	addr := e.fastOpenAddr
+	if !e.fastOpenDeferred.Load() {
+		mode := e.protocol.fastOpenMode()
+		if mode&tcpip.TCPFastOpenClient == 0 {
+			return 0, &tcpip.ErrNotSupported{}
+		}
+		switch state := e.EndpointState(); {
+		case state.connected():
+			return 0, &tcpip.ErrAlreadyConnected{}
+		case state.connecting():
+			return 0, &tcpip.ErrAlreadyConnecting{}
+		}
+		if opts.To == nil {
+			return 0, &tcpip.ErrDestinationRequired{}
+		}
+		addr = *opts.To
+	}
+	e.fastOpenDeferred.Store(false)
+
+	unwrapped, _, err := e.checkV4MappedLocked(addr, false /* bind */)
+	if err != nil {
+		return 0, err
+	}
+	mode := e.protocol.fastOpenMode()
+	req := &fastOpenRequest{
+		noCookie: e.fastOpenNoCookie || mode&tcpip.TCPFastOpenClientNoCookie != 0,
+	}
+	if !req.noCookie {
+		req.cookie = e.protocol.fastOpenCookie(unwrapped.Addr)
+	}
+	if req.cookie != nil || req.noCookie {
+		// Read the data while holding the locks, as the endpoint
+		// must not change state before the handshake starts.
+		opts.Atomic = true
+		e.sndQueueInfo.sndQueueMu.Lock()
+		buf, err := e.readFromPayloader(p, opts, e.getSendBufferSize()-e.sndQueueInfo.SndBufUsed)
+		if err != nil {
+			e.sndQueueInfo.sndQueueMu.Unlock()
+			return 0, err
+		}
+		e.sndQueueInfo.SndBufUsed += int(buf.Size())
+		e.sndQueueInfo.sndQueueMu.Unlock()
+		req.data = buf
+	} else {
+		e.fastOpenClientFail = tcpip.TCPFastOpenCookieUnavailable
+	}
+
+	n := int(req.data.Size())
+	e.fastOpenReq = req
+	if err := e.connect(addr, true); err != nil {
+		if _, ok := err.(*tcpip.ErrConnectStarted); !ok {
+			e.fastOpenReq = nil
+			e.sndQueueInfo.sndQueueMu.Lock()
+			e.sndQueueInfo.SndBufUsed -= n
+			e.sndQueueInfo.sndQueueMu.Unlock()
+			req.data.Release()
+			return 0, err
+		}
+	}
+	if n == 0 {
+		return 0, &tcpip.ErrConnectStarted{}
+	}
+	return int64(n), nil
+}
+
+// fastOpenConnectLocked handles a connect to addr with TCP_FASTOPEN_CONNECT
+// set. If a cookie is cached for the server (or cookies aren't required), the
+// connect is deferred to the first write so that its data can be sent in the
+// SYN, and true is returned. Otherwise, the SYN requests a cookie.
+//
+// +checklocks:e.mu
+func (e *Endpoint) fastOpenConnectLocked(addr tcpip.FullAddress) bool {
+	switch e.EndpointState() {
+	case StateInitial, StateBound:
+	default:
+		return false
+	}
+	unwrapped, _, err := e.checkV4MappedLocked(addr, false /* bind */)
+	if err != nil {
+		return false
+	}
+	mode := e.protocol.fastOpenMode()
+	if e.fastOpenNoCookie || mode&tcpip.TCPFastOpenClientNoCookie != 0 || e.protocol.fastOpenCookie(unwrapped.Addr) != nil {
+		e.fastOpenAddr = addr
+		e.fastOpenDeferred.Store(true)
+		return true
+	}
+	e.fastOpenReq = &fastOpenRequest{}
+	e.fastOpenClientFail = tcpip.TCPFastOpenCookieUnavailable
+	return false
+}
+
+// fastOpenSynAckLocked records the outcome of an active open using TCP Fast
+// Open given the options of the SYN-ACK that completed handshake h.
+//
+// +checklocks:e.mu
+func (e *Endpoint) fastOpenSynAckLocked(h *handshake, opts header.TCPSynOptions) {
+	if e.fastOpenReq == nil {
+		return
+	}
+	if opts.FastOpen && len(opts.FastOpenCookie) != 0 {
+		e.protocol.cacheFastOpenCookie(e.TransportEndpointInfo.ID.RemoteAddress, opts.FastOpenCookie)
+	}
+	if h.synDataSent == 0 {
+		return
+	}
+	if int(h.synDataAcked) == h.synDataSent {
+		e.synDataAcked = true
+	} else if e.fastOpenClientFail == tcpip.TCPFastOpenStatusUnspec {
+		e.fastOpenClientFail = tcpip.TCPFastOpenDataNotAcked
+	}
+}
+
+// requeueFastOpenDataLocked queues the data written before the handshake of an
+// active open using TCP Fast Open completed, except for the acked bytes that
+// were acknowledged in the SYN-ACK, and sends it.
+//
+// +checklocks:e.mu
+// +checklocksalias:e.snd.ep.mu=e.mu
+func (e *Endpoint) requeueFastOpenDataLocked(acked seqnum.Size) {
+	req := e.fastOpenReq
+	if req == nil {
+		return
+	}
+	e.fastOpenReq = nil
+	data := req.data
+	if acked > 0 {
+		data.TrimFront(int64(acked))
+		e.updateSndBufferUsage(int(acked))
+	}
+	if data.Size() == 0 {
+		data.Release()
+		return
+	}
+	e.sndQueueInfo.sndQueueMu.Lock()
+	s := newOutgoingSegment(e.TransportEndpointInfo.ID, e.stack.Clock(), data)
+	e.snd.writeList.PushBack(s)
+	e.sndQueueInfo.sndQueueMu.Unlock()
+	e.sendData(s)
+}
@@ -0,0 +1,219 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcp
+
+import (
+	"crypto/md5"
+	"crypto/subtle"
+	"encoding/binary"
+
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+)
+
+// md5DigestOffset is the offset of the digest of the TCP MD5 signature option
+// in the TCP header. The option is always the first one, preceded by two NOPs,
+// like in Linux.
+const md5DigestOffset = header.TCPMinimumSize + 4
+
+// md5Key is a key used to sign and verify the segments exchanged with the
+// peers matching addr/prefixLen, as set with TCP_MD5SIG or TCP_MD5SIG_EXT.
+//
+// +stateify savable
+type md5Key struct {
+	addr      tcpip.Address
+	prefixLen int
+
+	// nic restricts the key to peers reached through it, unless it is
+	// zero.
+	nic tcpip.NICID
+
+	// key is replaced rather than modified, so that it can be used after
+	// md5Mu is released.
+	key []byte
+}
+
+// matches returns true if k is used with the peer at addr reached through
+// nic.
+func (k *md5Key) matches(addr tcpip.Address, nic tcpip.NICID) bool {
+	if k.nic != 0 && k.nic != nic {
+		return false
+	}
+	if k.addr.Len() != addr.Len() {
+		return false
+	}
+	subnet := tcpip.AddressWithPrefix{Address: k.addr, PrefixLen: k.prefixLen}.Subnet()
+	return subnet.Contains(addr)
+}
+
+// setMD5Key adds, replaces or removes the key for the peers described by opt.
+//
+// +checklocks:e.mu
+func (e *Endpoint) setMD5Key(opt *tcpip.TCPMD5SigOption) tcpip.Error {
+	if len(opt.Key) > tcpip.TCPMD5SigKeyMaxLength {
+		return &tcpip.ErrInvalidOptionValue{}
+	}
+	if opt.PrefixLen < 0 || opt.PrefixLen > opt.Addr.BitLen() {
+		return &tcpip.ErrInvalidOptionValue{}
+	}
+	if opt.NIC != 0 && !e.stack.HasNIC(opt.NIC) {
+		return &tcpip.ErrInvalidOptionValue{}
+	}
+
+	e.md5Mu.Lock()
+	defer e.md5Mu.Unlock()
+	for i := range e.md5Keys {
+		k := &e.md5Keys[i]
+		if k.addr != opt.Addr || k.prefixLen != opt.PrefixLen || k.nic != opt.NIC {
+			continue
+		}
+		if len(opt.Key) == 0 {
+			e.md5Keys = append(e.md5Keys[:i], e.md5Keys[i+1:]...)
+			return nil
+		}
+		k.key = append([]byte(nil), opt.Key...)
+		return nil
+	}
+	if len(opt.Key) == 0 {
+		return &tcpip.ErrNoSuchFile{}
+	}
+	e.md5Keys = append(e.md5Keys, md5Key{
+		addr:      opt.Addr,
+		prefixLen: opt.PrefixLen,
+		nic:       opt.NIC,
+		key:       append([]byte(nil), opt.Key...),
+	})
+
+	// Signed segments can't be split by segmentation offload, like in
+	// Linux.
+	e.gso = stack.GSO{}
+	if e.snd != nil {
+		e.snd.gso = false
+	}
+	return nil
+}
+
+// hasMD5Keys returns true if any TCP MD5 signature key is set.
+func (e *Endpoint) hasMD5Keys() bool {
+	e.md5Mu.RLock()
+	defer e.md5Mu.RUnlock()
+	return len(e.md5Keys) != 0
+}
+
+// bestMD5Key returns the most specific key in keys used with the peer at addr
+// reached through nic, or nil.
+func bestMD5Key(keys []md5Key, addr tcpip.Address, nic tcpip.NICID) *md5Key {
+	var best *md5Key
+	for i := range keys {
+		k := &keys[i]
+		if !k.matches(addr, nic) {
+			continue
+		}
+		if best == nil || k.prefixLen > best.prefixLen || (k.prefixLen == best.prefixLen && best.nic == 0) {
+			best = k
+		}
+	}
+	return best
+}
+
+// md5KeyFor returns the key used with the peer at addr reached through nic,
+// or nil if the segments exchanged with it aren't signed.
+func (e *Endpoint) md5KeyFor(addr tcpip.Address, nic tcpip.NICID) []byte {
+	e.md5Mu.RLock()
+	defer e.md5Mu.RUnlock()
+	if k := bestMD5Key(e.md5Keys, addr, nic); k != nil {
+		return k.key
+	}
+	return nil
+}
+
+// md5KeyForRoute returns the key used with the peer reached through r, or
+// nil.
+func (e *Endpoint) md5KeyForRoute(r *stack.Route) []byte {
+	return e.md5KeyFor(r.RemoteAddress(), r.NICID())
+}
+
+// inheritMD5Key copies the key used with the peer that sent s from the
+// listening endpoint l, like in Linux.
+func (e *Endpoint) inheritMD5Key(l *Endpoint, s *segment) {
+	l.md5Mu.RLock()
+	defer l.md5Mu.RUnlock()
+	k := bestMD5Key(l.md5Keys, s.pkt.Network().SourceAddress(), s.pkt.NICID)
+	if k == nil {
+		return
+	}
+	e.md5Mu.Lock()
+	defer e.md5Mu.Unlock()
+	e.md5Keys = []md5Key{*k}
+}
+
+// verifyMD5 returns true if the segment s carries the TCP MD5 signature
+// expected from its sender, or no signature if none is expected, as described
+// in RFC 2385 section 3.0.
+func (e *Endpoint) verifyMD5(s *segment) bool {
+	net := s.pkt.Network()
+	key := e.md5KeyFor(net.SourceAddress(), s.pkt.NICID)
+	digest := s.parsedOptions.MD5Sig
+	switch {
+	case key == nil && digest == nil:
+		return true
+	case key == nil:
+		e.stack.Stats().TCP.MD5Unexpected.Increment()
+		return false
+	case digest == nil:
+		e.stack.Stats().TCP.MD5NotFound.Increment()
+		return false
+	}
+	want := md5Digest(key, net.SourceAddress(), net.DestinationAddress(), header.TCP(s.pkt.TransportHeader().Slice()), s.pkt.Data())
+	if subtle.ConstantTimeCompare(digest, want) != 1 {
+		e.stack.Stats().TCP.MD5Failure.Increment()
+		return false
+	}
+	return true
+}
+
+// md5Digest computes the TCP MD5 signature of the segment with header hdr,
+// including options, and payload data sent from src to dst, as described in
+// RFC 2385 section 2.0.
+func md5Digest(key []byte, src, dst tcpip.Address, hdr header.TCP, data stack.PacketData) []byte {
+	h := md5.New()
+
+	// The pseudo-header, as used to compute the checksum.
+	segLen := len(hdr) + data.Size()
+	var pseudo [2*header.IPv6AddressSize + 8]byte
+	n := copy(pseudo[:], src.AsSlice())
+	n += copy(pseudo[n:], dst.AsSlice())
+	if src.Len() == header.IPv4AddressSize {
+		pseudo[n+1] = uint8(ProtocolNumber)
+		binary.BigEndian.PutUint16(pseudo[n+2:], uint16(segLen))
+		n += 4
+	} else {
+		binary.BigEndian.PutUint32(pseudo[n:], uint32(segLen))
+		pseudo[n+7] = uint8(ProtocolNumber)
+		n += 8
+	}
+	h.Write(pseudo[:n])
+
+	// The TCP header, excluding options, with a zero checksum.
+	var tcp [header.TCPMinimumSize]byte
+	copy(tcp[:], hdr)
+	header.TCP(tcp[:]).SetChecksum(0)
+	h.Write(tcp[:])
+
+	data.ReadTo(h, true /* peek */)
+	h.Write(key)
+	return h.Sum(nil)
+}
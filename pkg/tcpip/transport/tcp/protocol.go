@@ -83,6 +83,7 @@ const (
 const (
 	ccReno  = "reno"
 	ccCubic = "cubic"
+	ccBBR   = "bbr"
 )
 
 // +stateify savable
@@ -106,8 +107,14 @@ type protocol struct {
 	maxRTO                     time.Duration
 	maxRetries                 uint32
 	synRetries                 uint8
+	fastOpen                   tcpip.TCPFastOpen
+	ecn                        tcpip.TCPECNOption
 	dispatcher                 dispatcher
 
+	// fastOpenCookies caches the TCP Fast Open cookies received from
+	// servers, keyed by server address.
+	fastOpenCookies map[tcpip.Address][]byte
+
 	// probe, if not nil, will be invoked any time an endpoint receives a
 	// TCP segment.
 	//
@@ -117,6 +124,7 @@ type protocol struct {
 	// The following secrets are initialized once and stay unchanged after.
 	seqnumSecret   [16]byte
 	tsOffsetSecret [16]byte
+	fastOpenKey    [tcpip.TCPFastOpenKeyLength]byte
 }
 
 // Number returns the tcp protocol number.
@@ -173,7 +181,7 @@ func (p *protocol) HandleUnknownDestinationPacket(id stack.TransportEndpointID,
 	}
 
 	if !s.flags.Contains(header.TCPFlagRst) {
-		replyWithReset(p.stack, s, stack.DefaultTOS, tcpip.UseDefaultIPv4TTL, tcpip.UseDefaultIPv6HopLimit)
+		replyWithReset(p.stack, s, stack.DefaultTOS, tcpip.UseDefaultIPv4TTL, tcpip.UseDefaultIPv6HopLimit, nil /* md5Key */)
 	}
 
 	return stack.UnknownDestinationPacketHandled
@@ -196,11 +204,12 @@ func (p *protocol) tsOffset(src, dst tcpip.Address) tcp.TSOffset {
 	return tcp.NewTSOffset(binary.LittleEndian.Uint32(h.Sum(nil)[:4]))
 }
 
-// replyWithReset replies to the given segment with a reset segment.
+// replyWithReset replies to the given segment with a reset segment, signed
+// with the TCP MD5 signature option if md5Key is not nil.
 //
 // If the relevant TTL has its reset value (0 for ipv4TTL, -1 for ipv6HopLimit),
 // then the route's default TTL will be used.
-func replyWithReset(st *stack.Stack, s *segment, tos, ipv4TTL uint8, ipv6HopLimit int16) tcpip.Error {
+func replyWithReset(st *stack.Stack, s *segment, tos, ipv4TTL uint8, ipv6HopLimit int16, md5Key []byte) tcpip.Error {
 	net := s.pkt.Network()
 	route, err := st.FindRoute(s.pkt.NICID, net.DestinationAddress(), net.SourceAddress(), s.pkt.NetworkProtocolNumber, false /* multicastLoop */)
 	if err != nil {
@@ -236,7 +245,16 @@ func replyWithReset(st *stack.Stack, s *segment, tos, ipv4TTL uint8, ipv6HopLimi
 	if s.ep != nil {
 		expOptVal = s.ep.getExperimentOptionValue(route)
 	}
-	hdrSize := header.TCPMinimumSize + int(route.MaxHeaderLength())
+	var options []byte
+	if md5Key != nil {
+		options = getOptions()
+		defer putOptions(options)
+		offset := header.EncodeNOP(options)
+		offset += header.EncodeNOP(options[offset:])
+		offset += header.EncodeMD5SigOption(options[offset:])
+		options = options[:offset]
+	}
+	hdrSize := header.TCPMinimumSize + int(route.MaxHeaderLength()) + len(options)
 	if route.NetProto() == header.IPv6ProtocolNumber && expOptVal != 0 {
 		hdrSize += header.IPv6ExperimentHdrLength
 	}
@@ -251,7 +269,9 @@ func replyWithReset(st *stack.Stack, s *segment, tos, ipv4TTL uint8, ipv6HopLimi
 		seq:       seq,
 		ack:       ack,
 		rcvWnd:    0,
+		opts:      options,
 		expOptVal: expOptVal,
+		md5Key:    md5Key,
 	}, p, stack.GSO{}, nil /* PacketOwner */)
 }
 
@@ -387,6 +407,21 @@ func (p *protocol) SetOption(option tcpip.SettableTransportProtocolOption) tcpip
 		p.mu.Unlock()
 		return nil
 
+	case *tcpip.TCPFastOpen:
+		p.mu.Lock()
+		p.fastOpen = *v
+		p.mu.Unlock()
+		return nil
+
+	case *tcpip.TCPECNOption:
+		if *v < tcpip.TCPECNDisabled || *v > tcpip.TCPECNServer {
+			return &tcpip.ErrInvalidOptionValue{}
+		}
+		p.mu.Lock()
+		p.ecn = *v
+		p.mu.Unlock()
+		return nil
+
 	default:
 		return &tcpip.ErrUnknownProtocolOption{}
 	}
@@ -491,6 +526,18 @@ func (p *protocol) Option(option tcpip.GettableTransportProtocolOption) tcpip.Er
 		p.mu.RUnlock()
 		return nil
 
+	case *tcpip.TCPFastOpen:
+		p.mu.RLock()
+		*v = p.fastOpen
+		p.mu.RUnlock()
+		return nil
+
+	case *tcpip.TCPECNOption:
+		p.mu.RLock()
+		*v = p.ecn
+		p.mu.RUnlock()
+		return nil
+
 	default:
 		return &tcpip.ErrUnknownProtocolOption{}
 	}
@@ -562,12 +609,16 @@ func newProtocol(s *stack.Stack, cc string, probe TCPProbeFunc) stack.TransportP
 	rng := s.SecureRNG()
 	var seqnumSecret [16]byte
 	var tsOffsetSecret [16]byte
+	var fastOpenKey [tcpip.TCPFastOpenKeyLength]byte
 	if n, err := rng.Reader.Read(seqnumSecret[:]); err != nil || n != len(seqnumSecret) {
 		panic(fmt.Sprintf("Read() failed: %v", err))
 	}
 	if n, err := rng.Reader.Read(tsOffsetSecret[:]); err != nil || n != len(tsOffsetSecret) {
 		panic(fmt.Sprintf("Read() failed: %v", err))
 	}
+	if n, err := rng.Reader.Read(fastOpenKey[:]); err != nil || n != len(fastOpenKey) {
+		panic(fmt.Sprintf("Read() failed: %v", err))
+	}
 	p := protocol{
 		stack: s,
 		sendBufferSize: tcpip.TCPSendBufferSizeRangeOption{
@@ -582,7 +633,7 @@ func newProtocol(s *stack.Stack, cc string, probe TCPProbeFunc) stack.TransportP
 		},
 		sackEnabled:                true,
 		congestionControl:          cc,
-		availableCongestionControl: []string{ccReno, ccCubic},
+		availableCongestionControl: []string{ccReno, ccCubic, ccBBR},
 		moderateReceiveBuffer:      true,
 		lingerTimeout:              DefaultTCPLingerTimeout,
 		timeWaitTimeout:            DefaultTCPTimeWaitTimeout,
@@ -592,8 +643,12 @@ func newProtocol(s *stack.Stack, cc string, probe TCPProbeFunc) stack.TransportP
 		maxRTO:                     MaxRTO,
 		maxRetries:                 MaxRetries,
 		recovery:                   tcpip.TCPRACKLossDetection,
+		fastOpen:                   tcpip.TCPFastOpenClient,
+		ecn:                        tcpip.TCPECNServer,
+		fastOpenCookies:            make(map[tcpip.Address][]byte),
 		seqnumSecret:               seqnumSecret,
 		tsOffsetSecret:             tsOffsetSecret,
+		fastOpenKey:                fastOpenKey,
 		probe:                      probe,
 	}
 	p.dispatcher.init(s.InsecureRNG(), runtime.GOMAXPROCS(0))
@@ -56,6 +56,10 @@ type rackControl struct {
 	// minRTT is the estimated minimum RTT of the connection.
 	minRTT time.Duration
 
+	// reordSeen is the number of segments detected as delivered out of
+	// order.
+	reordSeen uint32
+
 	// tlpRxtOut indicates whether there is an unacknowledged
 	// TLP retransmission.
 	tlpRxtOut bool
@@ -139,6 +143,7 @@ func (rc *rackControl) detectReorder(seg *segment) {
 
 	if endSeq.LessThan(rc.FACK) && seg.xmitCount == 1 {
 		rc.Reord = true
+		rc.reordSeen++
 	}
 }
 
@@ -0,0 +1,208 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcp
+
+import (
+	"time"
+
+	"gvisor.dev/gvisor/pkg/tcpip"
+)
+
+// The delivery rate of a connection is estimated as described in
+// https://datatracker.ietf.org/doc/html/draft-cheng-iccrg-delivery-rate-estimation,
+// which is also what Linux implements in net/ipv4/tcp_rate.c.
+
+// deliveryState is a snapshot of the delivery state of the sender, taken when
+// a segment is sent.
+//
+// +stateify savable
+type deliveryState struct {
+	// delivered is the number of packets delivered to the peer.
+	delivered uint64
+
+	// deliveredTime is the time at which delivered was last updated.
+	deliveredTime tcpip.MonotonicTime
+
+	// firstSentTime is the send time of the packet that was most recently
+	// delivered, used to compute the send interval of a sample.
+	firstSentTime tcpip.MonotonicTime
+
+	// appLimited indicates that the sender was application limited.
+	appLimited bool
+}
+
+// rateSample is a delivery rate sample, generated from the segments delivered
+// by a received ACK.
+//
+// +stateify savable
+type rateSample struct {
+	// priorDelivered is the value of deliveryState.delivered when the most
+	// recently sent of the delivered segments was sent.
+	priorDelivered uint64
+
+	// priorTime is the value of deliveryState.deliveredTime when the most
+	// recently sent of the delivered segments was sent.
+	priorTime tcpip.MonotonicTime
+
+	// sendInterval is the time elapsed between the transmission of the
+	// first and the last packet delivered in the sample.
+	sendInterval time.Duration
+
+	// appLimited indicates that the sample was taken while the sender was
+	// application limited.
+	appLimited bool
+
+	// valid indicates that the sample has at least one delivered segment.
+	valid bool
+
+	// rtt is the RTT of the most recently sent of the delivered segments, or
+	// negative if it was retransmitted.
+	rtt time.Duration
+
+	// ackedSacked is the number of packets newly delivered by the ACK.
+	ackedSacked int
+
+	// priorInFlight is the number of packets in flight before the ACK was
+	// processed.
+	priorInFlight int
+
+	// delivered is the number of packets delivered during the sample
+	// interval. It is set by rateEstimator.update.
+	delivered uint64
+
+	// interval is the duration of the sample, or zero if it is too short to
+	// estimate the delivery rate. It is set by rateEstimator.update.
+	interval time.Duration
+}
+
+// rateEstimator estimates the delivery rate of a sender.
+//
+// +stateify savable
+type rateEstimator struct {
+	deliveryState
+
+	// appLimitedUntil is the value of delivered after which the sender
+	// stops being application limited, or zero if it isn't.
+	appLimitedUntil uint64
+
+	// sample is the sample of the ACK being processed.
+	sample rateSample
+
+	// rate is the most recent delivery rate, in bytes per second.
+	rate uint64
+
+	// rateAppLimited indicates that rate was measured while the sender was
+	// application limited.
+	rateAppLimited bool
+}
+
+// onSent records the delivery state of the sender in seg, which is about to be
+// sent. inFlight is the number of packets in flight before seg is sent.
+func (re *rateEstimator) onSent(seg *segment, now tcpip.MonotonicTime, inFlight int) {
+	if inFlight == 0 {
+		// Nothing is in flight, so the next sample starts now.
+		re.firstSentTime = now
+		re.deliveredTime = now
+	}
+	seg.delivery = re.deliveryState
+	seg.delivery.appLimited = re.appLimitedUntil != 0
+}
+
+// onDelivered records that the packets of seg were delivered at now, either
+// cumulatively or selectively acknowledged.
+func (re *rateEstimator) onDelivered(seg *segment, now tcpip.MonotonicTime, packets int) {
+	re.delivered += uint64(packets)
+	re.deliveredTime = now
+	re.sample.ackedSacked += packets
+
+	// Use the most recently sent of the segments delivered by the ACK to
+	// generate the sample.
+	if re.sample.valid && seg.delivery.delivered <= re.sample.priorDelivered {
+		return
+	}
+	re.sample.priorDelivered = seg.delivery.delivered
+	re.sample.priorTime = seg.delivery.deliveredTime
+	re.sample.sendInterval = seg.xmitTime.Sub(seg.delivery.firstSentTime)
+	re.sample.appLimited = seg.delivery.appLimited
+	re.sample.valid = true
+	re.sample.rtt = -1
+	if seg.xmitCount == 1 {
+		re.sample.rtt = now.Sub(seg.xmitTime)
+	}
+	re.firstSentTime = seg.xmitTime
+}
+
+// onAck records the number of packets in flight before an ACK is processed.
+func (re *rateEstimator) onAck(inFlight int) {
+	re.sample.priorInFlight = inFlight
+}
+
+// update generates a sample from the segments delivered by the ACK that was
+// just processed, updates the delivery rate and returns the sample. mss is the
+// size of a packet and minRTT the minimum RTT of the connection.
+func (re *rateEstimator) update(mss int, minRTT time.Duration) rateSample {
+	if re.appLimitedUntil != 0 && re.delivered > re.appLimitedUntil {
+		re.appLimitedUntil = 0
+	}
+	sample := re.sample
+	re.sample = rateSample{}
+	if !sample.valid {
+		return sample
+	}
+	sample.delivered = re.delivered - sample.priorDelivered
+
+	// The interval is the longest of the send and ACK intervals, so that
+	// ACK compression doesn't overestimate the rate.
+	interval := sample.sendInterval
+	if ackInterval := re.deliveredTime.Sub(sample.priorTime); ackInterval > interval {
+		interval = ackInterval
+	}
+	// Intervals shorter than the minimum RTT are likely caused by
+	// stretched or compressed ACKs, so they are discarded like in Linux.
+	if interval <= 0 || interval < minRTT {
+		return sample
+	}
+	sample.interval = interval
+	rate := sample.bandwidth(mss)
+	// Application limited samples only update the rate if they are higher,
+	// as they underestimate the available bandwidth.
+	if !sample.appLimited || rate >= re.rate {
+		re.rate = rate
+		re.rateAppLimited = sample.appLimited
+	}
+	return sample
+}
+
+// bandwidth returns the delivery rate measured by the sample, in bytes per
+// second, or zero if the sample has no valid interval.
+func (rs *rateSample) bandwidth(mss int) uint64 {
+	if rs.interval <= 0 {
+		return 0
+	}
+	return rs.delivered * uint64(mss) * uint64(time.Second) / uint64(rs.interval)
+}
+
+// checkAppLimited marks the sender as application limited if it has no data
+// to send and isn't limited by the congestion window. inFlight is the number
+// of packets in flight.
+func (re *rateEstimator) checkAppLimited(inFlight int, cwnd int, hasData bool) {
+	if hasData || inFlight >= cwnd {
+		return
+	}
+	re.appLimitedUntil = re.delivered + uint64(inFlight)
+	if re.appLimitedUntil == 0 {
+		re.appLimitedUntil = 1
+	}
+}
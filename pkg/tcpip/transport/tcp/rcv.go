@@ -49,6 +49,24 @@ type receiver struct {
 
 	// Time when the last ack was received.
 	lastRcvdAckTime tcpip.MonotonicTime
+
+	// lastRcvdDataTime is the time when the last segment carrying data
+	// was received.
+	lastRcvdDataTime tcpip.MonotonicTime
+
+	// bytesReceived is the number of data bytes received in order.
+	bytesReceived uint64
+
+	// dataSegsIn is the number of segments carrying data received.
+	dataSegsIn uint32
+
+	// ecnEcho is true if ECE must be set in the segments sent to the peer,
+	// until it acknowledges it with CWR.
+	ecnEcho bool
+
+	// ecnSeen is true if a segment marked with congestion experienced was
+	// received.
+	ecnSeen bool
 }
 
 func newReceiver(ep *Endpoint, irs seqnum.Value, rcvWnd seqnum.Size, rcvWndScale uint8) *receiver {
@@ -220,6 +238,7 @@ func (r *receiver) consumeSegment(s *segment, segSeq seqnum.Value, segLen seqnum
 		}
 
 		// Move segment to ready-to-deliver list. Wakeup any waiters.
+		r.bytesReceived += uint64(segLen)
 		r.ep.readyToRead(s)
 
 	} else if segSeq != r.RcvNxt {
@@ -460,6 +479,11 @@ func (r *receiver) handleRcvdSegment(s *segment) (drop bool, err tcpip.Error) {
 	segLen := seqnum.Size(s.payloadSize())
 	segSeq := s.sequenceNumber
 
+	if segLen > 0 {
+		r.dataSegsIn++
+		r.lastRcvdDataTime = s.rcvdTime
+	}
+
 	// If the sequence number range is outside the acceptable range, just
 	// send an ACK and stop further processing of the segment.
 	// This is according to RFC 793, page 68.
@@ -475,6 +499,8 @@ func (r *receiver) handleRcvdSegment(s *segment) (drop bool, err tcpip.Error) {
 		}
 	}
 
+	r.handleECN(s)
+
 	// Store the time of the last ack.
 	r.lastRcvdAckTime = r.ep.stack.Clock().NowMonotonic()
 
@@ -16,6 +16,8 @@ package tcp
 
 import (
 	"time"
+
+	"gvisor.dev/gvisor/pkg/tcpip"
 )
 
 // renoState stores the variables related to TCP New Reno congestion
@@ -113,7 +115,23 @@ func (r *renoState) HandleRTOExpired() {
 	r.s.SndCwnd = 1
 }
 
+// HandleCongestionExperienced implements
+// congestionControl.HandleCongestionExperienced.
+//
+// +checklocks:r.s.ep.mu
+func (r *renoState) HandleCongestionExperienced() {
+	// Per RFC 3168 section 6.1.2, respond as if a packet was lost, but
+	// without retransmitting anything.
+	r.reduceSlowStartThreshold()
+	r.s.SndCwnd = r.s.Ssthresh
+}
+
 // PostRecovery implements congestionControl.PostRecovery.
 func (r *renoState) PostRecovery() {
 	// noop.
 }
+
+// GetInfo implements congestionControl.GetInfo.
+func (*renoState) GetInfo(*tcpip.TCPCCInfoOption) {
+	// Like in Linux, NewReno has no specific information to report.
+}
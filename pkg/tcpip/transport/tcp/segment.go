@@ -90,21 +90,36 @@ type segment struct {
 
 	// lost indicates if the segment is marked as lost by RACK.
 	lost bool
+
+	// delivery is the delivery state of the sender when the segment was
+	// last sent, used to estimate the delivery rate.
+	delivery deliveryState
+
+	// ce is true if the received segment was marked with congestion
+	// experienced.
+	ce bool
+
+	// txTimestamps is the request for the TX timestamps of the last byte of
+	// the segment, if any.
+	txTimestamps *stack.TXTimestampRequest
 }
 
 func newIncomingSegment(id stack.TransportEndpointID, clock tcpip.Clock, pkt *stack.PacketBuffer) (*segment, error) {
 	hdr := header.TCP(pkt.TransportHeader().Slice())
 	var srcAddr tcpip.Address
 	var dstAddr tcpip.Address
+	var tos uint8
 	switch netProto := pkt.NetworkProtocolNumber; netProto {
 	case header.IPv4ProtocolNumber:
 		hdr := header.IPv4(pkt.NetworkHeader().Slice())
 		srcAddr = hdr.SourceAddress()
 		dstAddr = hdr.DestinationAddress()
+		tos, _ = hdr.TOS()
 	case header.IPv6ProtocolNumber:
 		hdr := header.IPv6(pkt.NetworkHeader().Slice())
 		srcAddr = hdr.SourceAddress()
 		dstAddr = hdr.DestinationAddress()
+		tos, _ = hdr.TOS()
 	default:
 		panic(fmt.Sprintf("unknown network protocol number %d", netProto))
 	}
@@ -132,6 +147,7 @@ func newIncomingSegment(id stack.TransportEndpointID, clock tcpip.Clock, pkt *st
 	s.dataMemSize = pkt.MemSize()
 	s.pkt = pkt.Clone()
 	s.csumValid = csumValid
+	s.ce = header.IsCE(tos)
 
 	if !s.pkt.RXChecksumValidated {
 		s.csum = csum
@@ -158,6 +174,9 @@ func (s *segment) clone() *segment {
 	t.rcvdTime = s.rcvdTime
 	t.xmitTime = s.xmitTime
 	t.xmitCount = s.xmitCount
+	t.delivery = s.delivery
+	t.ce = s.ce
+	t.txTimestamps = s.txTimestamps
 	t.ep = s.ep
 	t.qFlags = s.qFlags
 	t.dataMemSize = s.dataMemSize
@@ -175,6 +194,8 @@ func newSegment() *segment {
 // merge merges data in oth and clears oth.
 func (s *segment) merge(oth *segment) {
 	s.pkt.Data().Merge(oth.pkt.Data())
+	s.txTimestamps = oth.txTimestamps
+	oth.txTimestamps = nil
 	s.dataMemSize = s.pkt.MemSize()
 	oth.dataMemSize = oth.pkt.MemSize()
 }
@@ -58,6 +58,10 @@ const (
 	// Algorithms (such as HyStart) that use the round-trip time should ignore
 	// such Updates.
 	unknownRTT = time.Duration(-1)
+
+	// pacingSlack is how far ahead of its pacing schedule a paced sender is
+	// allowed to send data.
+	pacingSlack = time.Millisecond
 )
 
 // congestionControl is an interface that must be implemented by any supported
@@ -71,6 +75,11 @@ type congestionControl interface {
 	// HandleRTOExpired is invoked when the retransmit timer expires.
 	HandleRTOExpired()
 
+	// HandleCongestionExperienced is invoked when the peer echoes a
+	// congestion experienced mark with ECE, at most once per window of
+	// data.
+	HandleCongestionExperienced()
+
 	// Update is invoked when processing inbound acks. It's passed the
 	// number of packet's that were acked by the most recent cumulative
 	// acknowledgement.  rtt is the round-trip time, or is set to unknownRTT
@@ -81,6 +90,20 @@ type congestionControl interface {
 	// recovery phase. This provides congestion control algorithms a way
 	// to adjust their state when exiting recovery.
 	PostRecovery()
+
+	// GetInfo fills info with the algorithm specific information returned
+	// by TCP_CC_INFO.
+	GetInfo(info *tcpip.TCPCCInfoOption)
+}
+
+// rateBasedCongestionControl is implemented by congestion control algorithms
+// that are driven by delivery rate samples, like BBR.
+type rateBasedCongestionControl interface {
+	congestionControl
+
+	// OnRateSample is invoked with the delivery rate sample generated by
+	// each ACK that delivers data, including during recovery.
+	OnRateSample(rs *rateSample)
 }
 
 // lossRecovery is an interface that must be implemented by any supported
@@ -181,6 +204,56 @@ type sender struct {
 	// corkTimer is used to drain the segments which are held when TCP_CORK
 	// option is enabled.
 	corkTimer timer `state:"nosave"`
+
+	// rate estimates the delivery rate of the connection.
+	rate rateEstimator
+
+	// minRTT is the minimum RTT measured on the connection.
+	minRTT time.Duration
+
+	// rtoBackoff is the number of consecutive retransmission timeouts since
+	// new data was last acknowledged.
+	rtoBackoff uint32
+
+	// bytesAcked is the number of bytes cumulatively acknowledged by the
+	// peer.
+	bytesAcked uint64
+
+	// bytesSent is the number of data bytes sent, including
+	// retransmissions.
+	bytesSent uint64
+
+	// bytesRetrans is the number of data bytes retransmitted.
+	bytesRetrans uint64
+
+	// dataSegsOut is the number of packets carrying data sent, including
+	// retransmissions.
+	dataSegsOut uint32
+
+	// dsackDups is the number of packets reported as received more than
+	// once with DSACK.
+	dsackDups uint32
+
+	// pacingRate is the rate at which data is sent, in bytes per second, or
+	// zero if the sender isn't paced. It is set by the congestion control
+	// algorithm.
+	pacingRate uint64
+
+	// nextSendTime is the earliest time at which the next data segment can
+	// be sent when the sender is paced.
+	nextSendTime tcpip.MonotonicTime
+
+	// pacingTimer is used to resume sending data held back by pacing.
+	pacingTimer timer `state:"nosave"`
+
+	// ecnRecover is the value of SndNxt when the congestion window was
+	// last reduced in response to ECE. ECE is ignored until it's
+	// acknowledged.
+	ecnRecover seqnum.Value
+
+	// ecnCWR is true if CWR must be set in the next new data segment to
+	// tell the peer that the congestion window was reduced.
+	ecnCWR bool
 }
 
 // protectedWriteList wraps the write list, checking for invalid state when
@@ -266,7 +339,8 @@ func newSender(ep *Endpoint, iss, irs seqnum.Value, sndWnd seqnum.Size, mss uint
 			},
 			RTO: 1 * time.Second,
 		},
-		gso: ep.gso.Type != stack.GSONone,
+		gso:        ep.gso.Type != stack.GSONone,
+		ecnRecover: iss,
 		writeList: protectedWriteList{
 			set: make(map[*segment]struct{}),
 		},
@@ -297,6 +371,7 @@ func newSenderHelper(ep *Endpoint, iss, irs seqnum.Value, sndWnd seqnum.Size, ms
 	s.reorderTimer.init(s.ep.stack.Clock(), timerHandler(s.ep, s.rc.reorderTimerExpired))
 	s.probeTimer.init(s.ep.stack.Clock(), timerHandler(s.ep, s.probeTimerExpired))
 	s.corkTimer.init(s.ep.stack.Clock(), timerHandler(s.ep, s.corkTimerExpired))
+	s.pacingTimer.init(s.ep.stack.Clock(), timerHandler(s.ep, s.pacingTimerExpired))
 
 	s.updateMaxPayloadSize(int(ep.route.MTU()), 0)
 	// Initialize SACK Scoreboard after updating max payload size as we use
@@ -334,8 +409,11 @@ func newSenderHelper(ep *Endpoint, iss, irs seqnum.Value, sndWnd seqnum.Size, ms
 func (s *sender) initCongestionControl(congestionControlName tcpip.CongestionControlOption) congestionControl {
 	s.SndCwnd = InitialCwnd
 	s.Ssthresh = InitialSsthresh
+	s.pacingRate = 0
 
 	switch congestionControlName {
+	case ccBBR:
+		return newBBRCC(s)
 	case ccCubic:
 		return newCubicCC(s)
 	case ccReno:
@@ -434,6 +512,10 @@ func (s *sender) sendAck() {
 //
 // +checklocks:s.ep.mu
 func (s *sender) updateRTO(rtt time.Duration) {
+	if s.minRTT == 0 || rtt < s.minRTT {
+		s.minRTT = rtt
+	}
+
 	s.rtt.Lock()
 	if !s.rtt.TCPRTTState.SRTTInited {
 		s.rtt.TCPRTTState.RTTVar = rtt / 2
@@ -584,6 +666,7 @@ func (s *sender) retransmitTimerExpired() tcpip.Error {
 
 	// Set new timeout. The timer will be restarted by the call to sendData
 	// below.
+	s.rtoBackoff++
 	s.RTO *= 2
 	// Cap the RTO as per RFC 1122 4.2.3.1, RFC 6298 5.5
 	if s.RTO > s.maxRTO {
@@ -613,6 +696,7 @@ func (s *sender) retransmitTimerExpired() tcpip.Error {
 	// Record retransmitTS if the sender is not in recovery as per:
 	// https://datatracker.ietf.org/doc/html/rfc3522#section-3.2 Step 2
 	s.recordRetransmitTS()
+	s.ecnWindowReduced()
 
 	s.state = tcpip.RTORecovery
 	s.cc.HandleRTOExpired()
@@ -691,6 +775,9 @@ func (s *sender) splitSeg(seg *segment, size int) {
 	nSeg.sequenceNumber.UpdateForward(seqnum.Size(size))
 	s.writeList.InsertAfter(seg, nSeg)
 
+	// The last byte, whose TX timestamps may be requested, is in nSeg.
+	seg.txTimestamps = nil
+
 	// The segment being split does not carry PUSH flag because it is
 	// followed by the newly split segment.
 	// RFC1122 section 4.2.2.2: MUST set the PSH bit in the last buffered
@@ -844,6 +931,11 @@ func (s *sender) maybeSendSegment(seg *segment, limit int, end seqnum.Value) (se
 			// implementations.
 			var nextTooBig bool
 			for nSeg := seg.Next(); nSeg != nil && nSeg.payloadSize() != 0; nSeg = seg.Next() {
+				// Like in Linux, data whose TX timestamps are
+				// requested isn't merged with the following data.
+				if seg.txTimestamps != nil {
+					break
+				}
 				if seg.payloadSize()+nSeg.payloadSize() > available {
 					nextTooBig = true
 					break
@@ -1015,7 +1107,7 @@ func (s *sender) sendZeroWindowProbe() {
 		Payload: buffer.MakeWithData(zeroProbeJunk),
 	})
 	defer pkt.DecRef()
-	s.sendSegmentFromPacketBuffer(pkt, header.TCPFlagAck, s.SndUna-1)
+	s.sendSegmentFromPacketBuffer(pkt, header.TCPFlagAck, s.SndUna-1, false /* ect */, nil /* txTimestamps */)
 
 	// Rearm the timer to continue probing.
 	s.resendTimer.enable(s.RTO)
@@ -1107,6 +1199,9 @@ func (s *sender) sendData() {
 			s.updateWriteNext(seg.Next())
 			continue
 		}
+		if s.paced() {
+			break
+		}
 		if sent := s.maybeSendSegment(seg, limit, end); !sent {
 			break
 		}
@@ -1114,6 +1209,7 @@ func (s *sender) sendData() {
 		s.Outstanding += s.pCount(seg, s.MaxPayloadSize)
 		s.updateWriteNext(seg.Next())
 	}
+	s.rate.checkAppLimited(s.Outstanding, s.SndCwnd, s.writeNext != nil)
 
 	s.postXmit(dataSent, true /* shouldScheduleProbe */)
 }
@@ -1145,6 +1241,7 @@ func (s *sender) enterRecovery() {
 	// Record retransmitTS if the sender is not in recovery as per:
 	// https://datatracker.ietf.org/doc/html/rfc3522#section-3.2 Step 2
 	s.recordRetransmitTS()
+	s.ecnWindowReduced()
 
 	if s.ep.SACKPermitted {
 		s.state = tcpip.SACKRecovery
@@ -1351,6 +1448,7 @@ func (s *sender) walkSACK(rcvdSeg *segment) bool {
 			numDSACK = 1
 		}
 		s.ep.stack.Stats().TCP.SegmentsAckedWithDSACK.IncrementBy(numDSACK)
+		s.dsackDups += uint32(numDSACK)
 		s.rc.setDSACKSeen(true)
 		idx = 1
 		n--
@@ -1377,6 +1475,7 @@ func (s *sender) walkSACK(rcvdSeg *segment) bool {
 				s.rc.detectReorder(seg)
 				seg.acked = true
 				s.SackedOut += s.pCount(seg, s.MaxPayloadSize)
+				s.rate.onDelivered(seg, rcvdSeg.rcvdTime, s.pCount(seg, s.MaxPayloadSize))
 			}
 			seg = seg.Next()
 		}
@@ -1521,6 +1620,7 @@ func (s *sender) inRecovery() bool {
 // +checklocksalias:s.rc.snd.ep.mu=s.ep.mu
 func (s *sender) handleRcvdSegment(rcvdSeg *segment) {
 	bestRTT := unknownRTT
+	s.rate.onAck(s.Outstanding)
 
 	// Check if we can extract an RTT measurement from this ack.
 	if !rcvdSeg.parsedOptions.TS && s.RTTMeasureSeqNum.LessThan(rcvdSeg.ackNumber) {
@@ -1589,6 +1689,10 @@ func (s *sender) handleRcvdSegment(rcvdSeg *segment) {
 		fastRetransmit = s.detectLoss(rcvdSeg)
 	}
 
+	// Reduce the congestion window if the peer echoed congestion
+	// experienced marks.
+	s.handleECE(rcvdSeg)
+
 	// See if TLP based recovery was successful.
 	if s.ep.tcpRecovery&tcpip.TCPRACKLossDetection != 0 {
 		s.detectTLPRecovery(ack, rcvdSeg)
@@ -1617,6 +1721,7 @@ func (s *sender) handleRcvdSegment(rcvdSeg *segment) {
 	// Ignore ack if it doesn't acknowledge any new data.
 	if (ack - 1).InRange(s.SndUna, s.SndNxt) {
 		s.DupAckCount = 0
+		s.rtoBackoff = 0
 
 		// See : https://tools.ietf.org/html/rfc1323#section-3.3.
 		// Specifically we should only update the RTO using TSEcr if the
@@ -1651,6 +1756,7 @@ func (s *sender) handleRcvdSegment(rcvdSeg *segment) {
 		// Remove all acknowledged data from the write list.
 		acked := s.SndUna.Size(ack)
 		s.SndUna = ack
+		s.bytesAcked += uint64(acked)
 		ackLeft := acked
 		originalOutstanding := s.Outstanding
 		for ackLeft > 0 {
@@ -1682,6 +1788,13 @@ func (s *sender) handleRcvdSegment(rcvdSeg *segment) {
 				s.rc.detectReorder(seg)
 			}
 
+			// Segments that were SACKed have already been
+			// accounted for as delivered.
+			if !seg.acked {
+				s.rate.onDelivered(seg, rcvdSeg.rcvdTime, s.pCount(seg, s.MaxPayloadSize))
+			}
+
+			seg.txTimestamps.Record(tcpip.TXTimestampAck, seg.pkt)
 			s.writeList.Remove(seg)
 
 			// If SACK is enabled then only reduce outstanding if
@@ -1743,6 +1856,14 @@ func (s *sender) handleRcvdSegment(rcvdSeg *segment) {
 		}
 	}
 
+	// Generate a delivery rate sample from the data delivered by the ACK,
+	// either cumulatively or selectively acknowledged.
+	if rs := s.rate.update(s.MaxPayloadSize, s.minRTT); rs.valid {
+		if rc, ok := s.cc.(rateBasedCongestionControl); ok {
+			rc.OnRateSample(&rs)
+		}
+	}
+
 	if s.ep.SACKPermitted && s.ep.tcpRecovery&tcpip.TCPRACKLossDetection != 0 {
 		// Update RACK reorder window.
 		// See: https://tools.ietf.org/html/draft-ietf-tcpm-rack-08#section-7.2
@@ -1795,12 +1916,29 @@ func (s *sender) sendSegment(seg *segment) tcpip.Error {
 		if s.SndCwnd < s.Ssthresh {
 			s.ep.stack.Stats().TCP.SlowStartRetransmits.Increment()
 		}
+		s.bytesRetrans += uint64(seg.payloadSize())
+	}
+	if size := seg.payloadSize(); size > 0 {
+		s.bytesSent += uint64(size)
+		s.dataSegsOut += uint32(s.pCount(seg, s.MaxPayloadSize))
 	}
 	seg.xmitTime = s.ep.stack.Clock().NowMonotonic()
+	s.rate.onSent(seg, seg.xmitTime, s.Outstanding)
+	s.updateNextSendTime(seg.payloadSize(), seg.xmitTime)
 	seg.xmitCount++
 	seg.lost = false
 
-	err := s.sendSegmentFromPacketBuffer(seg.pkt, seg.flags, seg.sequenceNumber)
+	// Only new data is marked ECN-capable, as described in RFC 3168
+	// section 6.1.5.
+	ect := seg.payloadSize() > 0 && seg.xmitCount == 1
+
+	// TX timestamps are only taken on the first transmission.
+	var txTimestamps *stack.TXTimestampRequest
+	if seg.xmitCount == 1 && seg.txTimestamps != nil {
+		txTimestamps = seg.txTimestamps
+		txTimestamps.ID = s.ep.ops.TimestampingID(uint32(seg.sequenceNumber.Add(seqnum.Size(seg.payloadSize() - 1))))
+	}
+	err := s.sendSegmentFromPacketBuffer(seg.pkt, seg.flags, seg.sequenceNumber, ect, txTimestamps)
 
 	// Every time a packet containing data is sent (including a
 	// retransmission), if SACK is enabled and we are retransmitting data
@@ -1820,11 +1958,12 @@ func (s *sender) sendSegment(seg *segment) tcpip.Error {
 }
 
 // sendSegmentFromPacketBuffer sends a new segment containing the given payload,
-// flags and sequence number.
+// flags and sequence number. ect is as in Endpoint.sendRaw. txTimestamps is the
+// request for the TX timestamps of the segment, if any.
 // +checklocks:s.ep.mu
 // +checklocksalias:s.ep.rcv.ep.mu=s.ep.mu
 // +checklocksalias:s.ep.rcv.ep.snd.ep.mu=s.ep.mu
-func (s *sender) sendSegmentFromPacketBuffer(pkt *stack.PacketBuffer, flags header.TCPFlags, seq seqnum.Value) tcpip.Error {
+func (s *sender) sendSegmentFromPacketBuffer(pkt *stack.PacketBuffer, flags header.TCPFlags, seq seqnum.Value, ect bool, txTimestamps *stack.TXTimestampRequest) tcpip.Error {
 	s.LastSendTime = s.ep.stack.Clock().NowMonotonic()
 	if seq == s.RTTMeasureSeqNum {
 		s.RTTMeasureTime = s.LastSendTime
@@ -1839,8 +1978,9 @@ func (s *sender) sendSegmentFromPacketBuffer(pkt *stack.PacketBuffer, flags head
 	// and pkt could be reprocessed later on (i.e retrasmission).
 	pkt = pkt.Clone()
 	defer pkt.DecRef()
+	pkt.TXTimestamps = txTimestamps
 
-	return s.ep.sendRaw(pkt, flags, seq, rcvNxt, rcvWnd)
+	return s.ep.sendRaw(pkt, flags, seq, rcvNxt, rcvWnd, ect)
 }
 
 // sendEmptySegment sends a new empty segment, flags and sequence number.
@@ -1883,6 +2023,54 @@ func (s *sender) updateWriteNext(seg *segment) {
 	s.writeNext = seg
 }
 
+// paced returns true if sending data must be delayed to respect the pacing
+// rate, in which case the pacing timer is armed to resume sending.
+//
+// +checklocks:s.ep.mu
+func (s *sender) paced() bool {
+	if s.pacingRate == 0 {
+		return false
+	}
+	// Like Linux, which sizes TSO bursts to about 1ms of data at the pacing
+	// rate, allow sending ahead of time by up to pacingSlack to avoid
+	// arming a timer for every segment.
+	wait := s.nextSendTime.Sub(s.ep.stack.Clock().NowMonotonic()) - pacingSlack
+	if wait <= 0 {
+		return false
+	}
+	if !s.pacingTimer.enabled() {
+		s.pacingTimer.enable(wait)
+	}
+	return true
+}
+
+// updateNextSendTime updates the earliest time at which the next data segment
+// can be sent, after size bytes of data were sent at now.
+//
+// +checklocks:s.ep.mu
+func (s *sender) updateNextSendTime(size int, now tcpip.MonotonicTime) {
+	if s.pacingRate == 0 || size == 0 {
+		return
+	}
+	if s.nextSendTime.Before(now) {
+		s.nextSendTime = now
+	}
+	s.nextSendTime = s.nextSendTime.Add(time.Duration(uint64(size) * uint64(time.Second) / s.pacingRate))
+}
+
+// pacingTimerExpired resumes sending data held back by pacing.
+//
+// +checklocks:s.ep.mu
+func (s *sender) pacingTimerExpired() tcpip.Error {
+	// Check if the timer actually expired or if it's a spurious wake due
+	// to a previously orphaned runtime timer.
+	if s.pacingTimer.isUninitialized() || !s.pacingTimer.checkExpiration() {
+		return nil
+	}
+	s.sendData()
+	return nil
+}
+
 // corkTimerExpired drains all the segments when TCP_CORK is enabled.
 // +checklocks:s.ep.mu
 func (s *sender) corkTimerExpired() tcpip.Error {
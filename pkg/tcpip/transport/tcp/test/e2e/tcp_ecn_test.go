@@ -0,0 +1,439 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcp_ecn_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/buffer"
+	"gvisor.dev/gvisor/pkg/refs"
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/checker"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+	"gvisor.dev/gvisor/pkg/tcpip/network/ipv4"
+	"gvisor.dev/gvisor/pkg/tcpip/seqnum"
+	"gvisor.dev/gvisor/pkg/tcpip/transport/tcp"
+	"gvisor.dev/gvisor/pkg/tcpip/transport/tcp/test/e2e"
+	"gvisor.dev/gvisor/pkg/tcpip/transport/tcp/testing/context"
+	"gvisor.dev/gvisor/pkg/waiter"
+)
+
+const (
+	// maxPayload is the payload size of the segments sent by the stack.
+	maxPayload = 32
+
+	// ecnSetupFlags are the flags of a SYN requesting ECN.
+	ecnSetupFlags = header.TCPFlagEce | header.TCPFlagCwr
+)
+
+func setECNMode(t *testing.T, c *context.Context, mode tcpip.TCPECNOption) {
+	t.Helper()
+
+	if err := c.Stack().SetTransportProtocolOption(tcp.ProtocolNumber, &mode); err != nil {
+		t.Fatalf("SetTransportProtocolOption(%d, &%d): %s", tcp.ProtocolNumber, mode, err)
+	}
+}
+
+func tcpInfo(t *testing.T, c *context.Context) tcpip.TCPInfoOption {
+	t.Helper()
+
+	var info tcpip.TCPInfoOption
+	if err := c.EP.GetSockOpt(&info); err != nil {
+		t.Fatalf("GetSockOpt(&%T): %s", info, err)
+	}
+	return info
+}
+
+// connectWithECN performs an active open of c.EP with ECN enabled, replying to
+// the SYN with a SYN-ACK carrying synAckFlags in addition to SYN and ACK.
+func connectWithECN(t *testing.T, c *context.Context, synAckFlags header.TCPFlags) {
+	t.Helper()
+
+	setECNMode(t, c, tcpip.TCPECNEnabled)
+	c.Create(-1 /* epRcvBuf */)
+
+	we, ch := waiter.NewChannelEntry(waiter.WritableEvents)
+	c.WQ.EventRegister(&we)
+	defer c.WQ.EventUnregister(&we)
+
+	if err := c.EP.Connect(tcpip.FullAddress{Addr: context.TestAddr, Port: context.TestPort}); err != nil {
+		if _, ok := err.(*tcpip.ErrConnectStarted); !ok {
+			t.Fatalf("Connect: %s", err)
+		}
+	}
+
+	// The SYN requests ECN.
+	b := c.GetPacket()
+	defer b.Release()
+	checker.IPv4(t, b,
+		checker.TOS(header.ECNNotECT, 0),
+		checker.TCP(
+			checker.DstPort(context.TestPort),
+			checker.TCPFlags(header.TCPFlagSyn|ecnSetupFlags),
+		),
+	)
+	tcpHdr := header.TCP(header.IPv4(b.AsSlice()).Payload())
+	c.IRS = seqnum.Value(tcpHdr.SequenceNumber())
+	c.Port = tcpHdr.SourcePort()
+
+	iss := seqnum.Value(context.TestInitialSequenceNumber)
+	c.SendPacket(nil, &context.Headers{
+		SrcPort: context.TestPort,
+		DstPort: c.Port,
+		Flags:   header.TCPFlagSyn | header.TCPFlagAck | synAckFlags,
+		SeqNum:  iss,
+		AckNum:  c.IRS + 1,
+		RcvWnd:  30000,
+	})
+
+	// The ACK completing the handshake carries neither ECN flag.
+	b = c.GetPacket()
+	defer b.Release()
+	checker.IPv4(t, b,
+		checker.TOS(header.ECNNotECT, 0),
+		checker.TCP(
+			checker.DstPort(context.TestPort),
+			checker.TCPFlags(header.TCPFlagAck),
+			checker.TCPSeqNum(uint32(c.IRS)+1),
+			checker.TCPAckNum(uint32(iss)+1),
+		),
+	)
+
+	select {
+	case <-ch:
+		if err := c.EP.LastError(); err != nil {
+			t.Fatalf("Unexpected error when connecting: %s", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Timed out waiting for connection")
+	}
+}
+
+// writeData writes size bytes to c.EP and returns them.
+func writeData(t *testing.T, c *context.Context, size int) []byte {
+	t.Helper()
+
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	var r bytes.Reader
+	r.Reset(data)
+	if _, err := c.EP.Write(&r, tcpip.WriteOptions{}); err != nil {
+		t.Fatalf("Write: %s", err)
+	}
+	return data
+}
+
+// receiveData receives the segment of maxPayload bytes of data at offset and
+// checks that it has the given TOS and, ignoring PSH, flags.
+func receiveData(t *testing.T, c *context.Context, data []byte, offset int, tos uint8, flags header.TCPFlags) {
+	t.Helper()
+
+	b := c.GetPacket()
+	defer b.Release()
+	checkData(t, c, b, data, offset, tos, flags)
+}
+
+// receiveTrain receives the segments of data sent from offset until the stack
+// stops sending, checking that they are ECN-capable and carry no CWR, and
+// returns the offset following the last one.
+func receiveTrain(t *testing.T, c *context.Context, data []byte, offset int) int {
+	t.Helper()
+
+	for {
+		b := c.GetPacketWithTimeout(50 * time.Millisecond)
+		if b == nil {
+			return offset
+		}
+		checkData(t, c, b, data, offset, header.ECNECT0, header.TCPFlagAck)
+		b.Release()
+		offset += maxPayload
+	}
+}
+
+// checkData checks that b is the segment of maxPayload bytes of data at offset
+// with the given TOS and, ignoring PSH, flags.
+func checkData(t *testing.T, c *context.Context, b *buffer.View, data []byte, offset int, tos uint8, flags header.TCPFlags) {
+	t.Helper()
+
+	checker.IPv4(t, b,
+		checker.PayloadLen(header.TCPMinimumSize+maxPayload),
+		checker.TOS(tos, 0),
+		checker.TCP(
+			checker.DstPort(context.TestPort),
+			checker.TCPSeqNum(uint32(c.IRS.Add(seqnum.Size(1+offset)))),
+			checker.TCPFlagsMatch(flags, ^header.TCPFlagPsh),
+			checker.Payload(data[offset:][:maxPayload]),
+		),
+	)
+}
+
+func TestECNActiveOpen(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		synAckFlags header.TCPFlags
+		wantECN     bool
+	}{
+		{name: "SYN-ACK with ECE", synAckFlags: header.TCPFlagEce, wantECN: true},
+		{name: "SYN-ACK without ECE", synAckFlags: 0, wantECN: false},
+		{name: "SYN-ACK with ECE and CWR", synAckFlags: ecnSetupFlags, wantECN: false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			c := context.New(t, uint32(header.TCPMinimumSize+header.IPv4MinimumSize+maxPayload))
+			defer c.Cleanup()
+
+			connectWithECN(t, c, test.synAckFlags)
+			if got := tcpInfo(t, c).ECN; got != test.wantECN {
+				t.Errorf("got TCPInfo.ECN = %t, want = %t", got, test.wantECN)
+			}
+
+			// Only segments with new data are ECN-capable, and only if
+			// ECN was negotiated.
+			wantTOS := uint8(header.ECNNotECT)
+			if test.wantECN {
+				wantTOS = header.ECNECT0
+			}
+			data := writeData(t, c, maxPayload)
+			receiveData(t, c, data, 0, wantTOS, header.TCPFlagAck)
+		})
+	}
+}
+
+func TestECNPassiveOpen(t *testing.T) {
+	for _, test := range []struct {
+		mode     tcpip.TCPECNOption
+		synFlags header.TCPFlags
+		wantECN  bool
+	}{
+		{mode: tcpip.TCPECNDisabled, synFlags: ecnSetupFlags, wantECN: false},
+		{mode: tcpip.TCPECNEnabled, synFlags: ecnSetupFlags, wantECN: true},
+		{mode: tcpip.TCPECNServer, synFlags: ecnSetupFlags, wantECN: true},
+		{mode: tcpip.TCPECNServer, synFlags: 0, wantECN: false},
+		{mode: tcpip.TCPECNServer, synFlags: header.TCPFlagEce, wantECN: false},
+	} {
+		t.Run(fmt.Sprintf("mode=%d synFlags=%s", test.mode, test.synFlags), func(t *testing.T) {
+			c := context.New(t, e2e.DefaultMTU)
+			defer c.Cleanup()
+
+			setECNMode(t, c, test.mode)
+
+			var err tcpip.Error
+			c.EP, err = c.Stack().NewEndpoint(tcp.ProtocolNumber, ipv4.ProtocolNumber, &c.WQ)
+			if err != nil {
+				t.Fatalf("NewEndpoint: %s", err)
+			}
+			if err := c.EP.Bind(tcpip.FullAddress{Port: context.StackPort}); err != nil {
+				t.Fatalf("Bind: %s", err)
+			}
+			if err := c.EP.Listen(10); err != nil {
+				t.Fatalf("Listen: %s", err)
+			}
+
+			irs := seqnum.Value(context.TestInitialSequenceNumber)
+			c.SendPacket(nil, &context.Headers{
+				SrcPort: context.TestPort,
+				DstPort: context.StackPort,
+				Flags:   header.TCPFlagSyn | test.synFlags,
+				SeqNum:  irs,
+				RcvWnd:  30000,
+			})
+
+			// The SYN-ACK accepts ECN with ECE only.
+			wantFlags := header.TCPFlagSyn | header.TCPFlagAck
+			if test.wantECN {
+				wantFlags |= header.TCPFlagEce
+			}
+			b := c.GetPacket()
+			defer b.Release()
+			checker.IPv4(t, b,
+				checker.TOS(header.ECNNotECT, 0),
+				checker.TCP(
+					checker.SrcPort(context.StackPort),
+					checker.DstPort(context.TestPort),
+					checker.TCPFlags(wantFlags),
+					checker.TCPAckNum(uint32(irs)+1),
+				),
+			)
+			iss := seqnum.Value(header.TCP(header.IPv4(b.AsSlice()).Payload()).SequenceNumber())
+
+			we, ch := waiter.NewChannelEntry(waiter.ReadableEvents)
+			c.WQ.EventRegister(&we)
+			defer c.WQ.EventUnregister(&we)
+
+			c.SendPacket(nil, &context.Headers{
+				SrcPort: context.TestPort,
+				DstPort: context.StackPort,
+				Flags:   header.TCPFlagAck,
+				SeqNum:  irs + 1,
+				AckNum:  iss + 1,
+				RcvWnd:  30000,
+			})
+
+			select {
+			case <-ch:
+			case <-time.After(time.Second):
+				t.Fatalf("Timed out waiting for accept")
+			}
+			newEP, _, err := c.EP.Accept(nil)
+			if err != nil {
+				t.Fatalf("Accept: %s", err)
+			}
+			defer newEP.Close()
+
+			var info tcpip.TCPInfoOption
+			if err := newEP.GetSockOpt(&info); err != nil {
+				t.Fatalf("GetSockOpt(&%T): %s", info, err)
+			}
+			if info.ECN != test.wantECN {
+				t.Errorf("got TCPInfo.ECN = %t, want = %t", info.ECN, test.wantECN)
+			}
+		})
+	}
+}
+
+// TestECNEchoUntilCWR tests that the receiver echoes a congestion experienced
+// mark with ECE on every ACK until the sender signals CWR, as described in
+// RFC 3168 section 6.1.3.
+func TestECNEchoUntilCWR(t *testing.T) {
+	c := context.New(t, e2e.DefaultMTU)
+	defer c.Cleanup()
+
+	connectWithECN(t, c, header.TCPFlagEce)
+
+	data := []byte{1, 2, 3}
+	seq := seqnum.Value(context.TestInitialSequenceNumber).Add(1)
+	for i, test := range []struct {
+		name      string
+		tos       uint8
+		flags     header.TCPFlags
+		wantFlags header.TCPFlags
+	}{
+		{name: "not marked", tos: header.ECNECT0, flags: header.TCPFlagAck, wantFlags: header.TCPFlagAck},
+		{name: "marked", tos: header.ECNCE, flags: header.TCPFlagAck, wantFlags: header.TCPFlagAck | header.TCPFlagEce},
+		{name: "after marked", tos: header.ECNECT0, flags: header.TCPFlagAck, wantFlags: header.TCPFlagAck | header.TCPFlagEce},
+		{name: "CWR", tos: header.ECNECT0, flags: header.TCPFlagAck | header.TCPFlagCwr, wantFlags: header.TCPFlagAck},
+		{name: "after CWR", tos: header.ECNECT0, flags: header.TCPFlagAck, wantFlags: header.TCPFlagAck},
+	} {
+		c.SendPacket(data, &context.Headers{
+			SrcPort: context.TestPort,
+			DstPort: c.Port,
+			Flags:   test.flags,
+			SeqNum:  seq,
+			AckNum:  c.IRS.Add(1),
+			RcvWnd:  30000,
+			TOS:     test.tos,
+		})
+		seq = seq.Add(seqnum.Size(len(data)))
+
+		// Pure ACKs are never ECN-capable.
+		b := c.GetPacket()
+		defer b.Release()
+		checker.IPv4(t, b,
+			checker.PayloadLen(header.TCPMinimumSize),
+			checker.TOS(header.ECNNotECT, 0),
+			checker.TCP(
+				checker.DstPort(context.TestPort),
+				checker.TCPAckNum(uint32(seq)),
+				checker.TCPFlags(test.wantFlags),
+			),
+		)
+		if t.Failed() {
+			t.Fatalf("%d: unexpected ACK after %s segment", i, test.name)
+		}
+	}
+
+	if info := tcpInfo(t, c); !info.ECNSeen {
+		t.Errorf("got TCPInfo.ECNSeen = false, want = true")
+	}
+}
+
+// TestECNWindowReduction tests that the sender reduces its congestion window
+// in response to ECE at most once per window of data, and signals the
+// reduction with CWR on the next segment carrying new data, as described in
+// RFC 3168 section 6.1.2.
+func TestECNWindowReduction(t *testing.T) {
+	c := context.New(t, uint32(header.TCPMinimumSize+header.IPv4MinimumSize+maxPayload))
+	defer c.Cleanup()
+
+	connectWithECN(t, c, header.TCPFlagEce)
+
+	data := writeData(t, c, 8*tcp.InitialCwnd*maxPayload)
+	bytesRead := 0
+	for i := 0; i < tcp.InitialCwnd; i++ {
+		receiveData(t, c, data, bytesRead, header.ECNECT0, header.TCPFlagAck)
+		bytesRead += maxPayload
+	}
+	c.CheckNoPacketTimeout("More packets received than the initial cwnd", 50*time.Millisecond)
+
+	sendAck := func(bytesReceived int, flags header.TCPFlags) {
+		c.SendPacket(nil, &context.Headers{
+			SrcPort: context.TestPort,
+			DstPort: c.Port,
+			Flags:   header.TCPFlagAck | flags,
+			SeqNum:  seqnum.Value(context.TestInitialSequenceNumber).Add(1),
+			AckNum:  c.IRS.Add(1 + seqnum.Size(bytesReceived)),
+			RcvWnd:  30000,
+		})
+	}
+
+	// Acknowledge the first segment with ECE. Without the reduction, slow
+	// start would send 2 new segments.
+	sendAck(maxPayload, header.TCPFlagEce)
+	c.CheckNoPacketTimeout("Packet received after ECE reduced the cwnd", 50*time.Millisecond)
+	reduced := tcpInfo(t, c)
+	if reduced.SndCwnd >= tcp.InitialCwnd {
+		t.Fatalf("got TCPInfo.SndCwnd = %d after ECE, want < %d", reduced.SndCwnd, tcp.InitialCwnd)
+	}
+
+	// Acknowledge the rest of the window with ECE. It was sent before the
+	// reduction, so it doesn't reduce the window again.
+	sendAck(bytesRead, header.TCPFlagEce)
+
+	// The first segment of new data signals the reduction with CWR, and only
+	// the first one.
+	receiveData(t, c, data, bytesRead, header.ECNECT0, header.TCPFlagAck|header.TCPFlagCwr)
+	bytesRead += maxPayload
+	bytesRead = receiveTrain(t, c, data, bytesRead)
+
+	info := tcpInfo(t, c)
+	if info.SndSsthresh != reduced.SndSsthresh {
+		t.Errorf("got TCPInfo.SndSsthresh = %d after ECE for the same window, want = %d", info.SndSsthresh, reduced.SndSsthresh)
+	}
+	if info.SndCwnd < reduced.SndCwnd {
+		t.Errorf("got TCPInfo.SndCwnd = %d after ECE for the same window, want >= %d", info.SndCwnd, reduced.SndCwnd)
+	}
+
+	// ECE for data sent after the reduction reduces the window again.
+	sendAck(bytesRead, header.TCPFlagEce)
+	receiveData(t, c, data, bytesRead, header.ECNECT0, header.TCPFlagAck|header.TCPFlagCwr)
+	if got := tcpInfo(t, c).SndSsthresh; got >= info.SndSsthresh {
+		t.Errorf("got TCPInfo.SndSsthresh = %d after ECE for a new window, want < %d", got, info.SndSsthresh)
+	}
+}
+
+func TestMain(m *testing.M) {
+	refs.SetLeakMode(refs.LeaksPanic)
+	code := m.Run()
+	// Allow TCP async work to complete to avoid false reports of leaks.
+	// TODO(gvisor.dev/issue/5940): Use fake clock in tests.
+	time.Sleep(1 * time.Second)
+	refs.DoLeakCheck()
+	os.Exit(code)
+}
@@ -2896,6 +2896,153 @@ func TestSimpleSend(t *testing.T) {
 	})
 }
 
+func TestTCPInfoCounters(t *testing.T) {
+	c := context.New(t, e2e.DefaultMTU)
+	defer c.Cleanup()
+
+	c.CreateConnected(context.TestInitialSequenceNumber, 30000, -1 /* epRcvBuf */)
+
+	sent := []byte{1, 2, 3}
+	var r bytes.Reader
+	r.Reset(sent)
+	if _, err := c.EP.Write(&r, tcpip.WriteOptions{}); err != nil {
+		t.Fatalf("Write failed: %s", err)
+	}
+	c.ReceiveAndCheckPacket(sent, 0, len(sent))
+
+	// Acknowledge the data, then send some data and wait for it to be
+	// acknowledged so that both segments have been processed.
+	iss := seqnum.Value(context.TestInitialSequenceNumber).Add(1)
+	c.SendAck(iss, len(sent))
+	rcvd := []byte{4, 5, 6, 7}
+	c.SendPacket(rcvd, &context.Headers{
+		SrcPort: context.TestPort,
+		DstPort: c.Port,
+		Flags:   header.TCPFlagAck,
+		SeqNum:  iss,
+		AckNum:  c.IRS.Add(1 + seqnum.Size(len(sent))),
+		RcvWnd:  30000,
+	})
+	b := c.GetPacket()
+	defer b.Release()
+	checker.IPv4(t, b,
+		checker.TCP(
+			checker.DstPort(context.TestPort),
+			checker.TCPAckNum(uint32(iss)+uint32(len(rcvd))),
+			checker.TCPFlags(header.TCPFlagAck),
+		),
+	)
+
+	var info tcpip.TCPInfoOption
+	if err := c.EP.GetSockOpt(&info); err != nil {
+		t.Fatalf("c.EP.GetSockOpt(&%T) = %s", info, err)
+	}
+	for _, tc := range []struct {
+		name string
+		got  uint64
+		want uint64
+	}{
+		{"BytesAcked", info.BytesAcked, uint64(len(sent))},
+		{"BytesSent", info.BytesSent, uint64(len(sent))},
+		{"BytesRetrans", info.BytesRetrans, 0},
+		{"BytesReceived", info.BytesReceived, uint64(len(rcvd))},
+		{"DataSegsOut", uint64(info.DataSegsOut), 1},
+		{"DataSegsIn", uint64(info.DataSegsIn), 1},
+		{"Delivered", uint64(info.Delivered), 1},
+		{"Unacked", uint64(info.Unacked), 0},
+		{"NotSentBytes", uint64(info.NotSentBytes), 0},
+		{"TotalRetrans", uint64(info.TotalRetrans), 0},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("got info.%s = %d, want = %d", tc.name, tc.got, tc.want)
+		}
+	}
+	if info.SegsOut < 3 {
+		t.Errorf("got info.SegsOut = %d, want >= 3", info.SegsOut)
+	}
+	if info.SegsIn < 3 {
+		t.Errorf("got info.SegsIn = %d, want >= 3", info.SegsIn)
+	}
+	if info.MinRTT == 0 {
+		t.Errorf("got info.MinRTT = 0, want > 0")
+	}
+}
+
+func TestTCPCCInfo(t *testing.T) {
+	for _, cc := range []tcpip.CongestionControlOption{"reno", "cubic", "bbr"} {
+		t.Run(string(cc), func(t *testing.T) {
+			c := context.New(t, e2e.DefaultMTU)
+			defer c.Cleanup()
+
+			c.CreateConnected(context.TestInitialSequenceNumber, 30000, -1 /* epRcvBuf */)
+			if err := c.EP.SetSockOpt(&cc); err != nil {
+				t.Fatalf("c.EP.SetSockOpt(&%T(%s)) = %s", cc, cc, err)
+			}
+
+			sent := []byte{1, 2, 3}
+			var r bytes.Reader
+			r.Reset(sent)
+			if _, err := c.EP.Write(&r, tcpip.WriteOptions{}); err != nil {
+				t.Fatalf("Write failed: %s", err)
+			}
+			c.ReceiveAndCheckPacket(sent, 0, len(sent))
+
+			// Acknowledge the data, then send some data and wait for it to
+			// be acknowledged so that the ACK has been processed.
+			iss := seqnum.Value(context.TestInitialSequenceNumber).Add(1)
+			c.SendAck(iss, len(sent))
+			rcvd := []byte{4, 5, 6, 7}
+			c.SendPacket(rcvd, &context.Headers{
+				SrcPort: context.TestPort,
+				DstPort: c.Port,
+				Flags:   header.TCPFlagAck,
+				SeqNum:  iss,
+				AckNum:  c.IRS.Add(1 + seqnum.Size(len(sent))),
+				RcvWnd:  30000,
+			})
+			b := c.GetPacket()
+			defer b.Release()
+			checker.IPv4(t, b,
+				checker.TCP(
+					checker.DstPort(context.TestPort),
+					checker.TCPAckNum(uint32(iss)+uint32(len(rcvd))),
+					checker.TCPFlags(header.TCPFlagAck),
+				),
+			)
+
+			var info tcpip.TCPCCInfoOption
+			if err := c.EP.GetSockOpt(&info); err != nil {
+				t.Fatalf("c.EP.GetSockOpt(&%T) = %s", info, err)
+			}
+			if info.Algorithm != cc {
+				t.Errorf("got info.Algorithm = %s, want = %s", info.Algorithm, cc)
+			}
+			if cc != "bbr" {
+				// Like in Linux, only BBR reports information.
+				if info.BBR != (tcpip.TCPBBRInfo{}) {
+					t.Errorf("got info.BBR = %+v, want zero value", info.BBR)
+				}
+				return
+			}
+			// BBR is still in startup mode, which uses a gain of 2.885,
+			// scaled by 256.
+			const startupGain = 738
+			if info.BBR.PacingGain != startupGain {
+				t.Errorf("got info.BBR.PacingGain = %d, want = %d", info.BBR.PacingGain, startupGain)
+			}
+			if info.BBR.CwndGain != startupGain {
+				t.Errorf("got info.BBR.CwndGain = %d, want = %d", info.BBR.CwndGain, startupGain)
+			}
+			if info.BBR.Bandwidth == 0 {
+				t.Errorf("got info.BBR.Bandwidth = 0, want > 0")
+			}
+			if info.BBR.MinRTT == 0 {
+				t.Errorf("got info.BBR.MinRTT = 0, want > 0")
+			}
+		})
+	}
+}
+
 func TestZeroWindowSend(t *testing.T) {
 	c := context.New(t, e2e.DefaultMTU)
 	defer c.Cleanup()
@@ -5823,6 +5970,7 @@ func TestStackSetCongestionControl(t *testing.T) {
 	}{
 		{"reno", nil},
 		{"cubic", nil},
+		{"bbr", nil},
 		{"blahblah", &tcpip.ErrNoSuchFile{}},
 	}
 
@@ -5875,7 +6023,7 @@ func TestStackAvailableCongestionControl(t *testing.T) {
 	if err := s.TransportProtocolOption(tcp.ProtocolNumber, &aCC); err != nil {
 		t.Fatalf("s.TransportProtocolOption(%v, %v) = %v", tcp.ProtocolNumber, &aCC, err)
 	}
-	if got, want := aCC, tcpip.TCPAvailableCongestionControlOption("reno cubic"); got != want {
+	if got, want := aCC, tcpip.TCPAvailableCongestionControlOption("reno cubic bbr"); got != want {
 		t.Fatalf("got tcpip.TCPAvailableCongestionControlOption: %v, want: %v", got, want)
 	}
 }
@@ -5897,7 +6045,7 @@ func TestStackSetAvailableCongestionControl(t *testing.T) {
 	if err := s.TransportProtocolOption(tcp.ProtocolNumber, &cc); err != nil {
 		t.Fatalf("s.TransportProtocolOptio(%d, &%T(%s)): %s", tcp.ProtocolNumber, cc, cc, err)
 	}
-	if got, want := cc, tcpip.TCPAvailableCongestionControlOption("reno cubic"); got != want {
+	if got, want := cc, tcpip.TCPAvailableCongestionControlOption("reno cubic bbr"); got != want {
 		t.Fatalf("got tcpip.TCPAvailableCongestionControlOption = %s, want = %s", got, want)
 	}
 }
@@ -5909,6 +6057,7 @@ func TestEndpointSetCongestionControl(t *testing.T) {
 	}{
 		{"reno", nil},
 		{"cubic", nil},
+		{"bbr", nil},
 		{"blahblah", &tcpip.ErrNoSuchFile{}},
 	}
 
@@ -135,6 +135,11 @@ func (e *endpoint) WakeupWriters() {
 	e.net.MaybeSignalWritable()
 }
 
+// WakeupErrQueueReaders implements tcpip.SocketOptionsHandler.
+func (e *endpoint) WakeupErrQueueReaders() {
+	e.waiterQueue.Notify(waiter.EventErr)
+}
+
 func (e *endpoint) LastError() tcpip.Error {
 	e.lastErrorMu.Lock()
 	defer e.lastErrorMu.Unlock()
@@ -468,6 +473,13 @@ func (e *endpoint) write(p tcpip.Payloader, opts tcpip.WriteOptions) (int64, tcp
 		return 0, &tcpip.ErrWouldBlock{}
 	}
 	defer pkt.DecRef()
+	if req := stack.NewTXTimestampRequest(e.SocketOptions(), e.stack.Clock(), pktInfo.NetProto); req != nil {
+		req.ID = e.SocketOptions().NextTimestampingID()
+		pkt.TXTimestamps = req
+	}
+	if opts.ControlMessages.HasTXTime {
+		pkt.TXTime = opts.ControlMessages.TXTime
+	}
 
 	// Initialize the UDP header.
 	udp := header.UDP(pkt.TransportHeader().Push(header.UDPMinimumSize))
@@ -585,11 +597,31 @@ type udpPacketInfo struct {
 	remotePort uint16
 }
 
+// SoftAbort implements tcpip.EndpointWithSoftAbort.
+func (e *endpoint) SoftAbort(err tcpip.Error) {
+	e.mu.Lock()
+	if e.net.State() == transport.DatagramEndpointStateClosed {
+		e.mu.Unlock()
+		return
+	}
+	e.UpdateLastError(err)
+	// Like Linux's __udp_disconnect(), ignore failures to rebind the local
+	// address.
+	_ = e.disconnectLocked()
+	e.mu.Unlock()
+
+	e.waiterQueue.Notify(waiter.EventErr)
+}
+
 // Disconnect implements tcpip.Endpoint.
 func (e *endpoint) Disconnect() tcpip.Error {
 	e.mu.Lock()
 	defer e.mu.Unlock()
+	return e.disconnectLocked()
+}
 
+// +checklocks:e.mu
+func (e *endpoint) disconnectLocked() tcpip.Error {
 	if e.net.State() != transport.DatagramEndpointStateConnected {
 		return nil
 	}
@@ -920,6 +952,14 @@ func (e *endpoint) HandlePacket(id stack.TransportEndpointID, pkt *stack.PacketB
 		return
 	}
 
+	// The attached socket filter can't truncate the UDP header, like in
+	// Linux.
+	snapLen, ok := stack.RunSocketFilter(&e.ops, pkt, stack.SocketFilterTransportHeader)
+	if !ok {
+		return
+	}
+	dataLen := max(snapLen-header.UDPMinimumSize, 0)
+
 	e.stack.Stats().UDP.PacketsReceived.Increment()
 	e.stats.PacketsReceived.Increment()
 
@@ -960,8 +1000,11 @@ func (e *endpoint) HandlePacket(id stack.TransportEndpointID, pkt *stack.PacketB
 		// the underlying buffer. Clone does not copy the data, just the metadata.
 		pkt: pkt.Clone(),
 	}
+	if dataLen < packet.pkt.Data().Size() {
+		packet.pkt.Data().CapLength(dataLen)
+	}
 	e.rcvList.PushBack(packet)
-	e.rcvBufSize += pkt.Data().Size()
+	e.rcvBufSize += packet.pkt.Data().Size()
 
 	// Save any useful information from the network header to the packet.
 	packet.tosOrTClass, _ = pkt.Network().TOS()
@@ -979,7 +1022,7 @@ func (e *endpoint) HandlePacket(id stack.TransportEndpointID, pkt *stack.PacketB
 	packet.packetInfo.LocalAddr = localAddr
 	packet.packetInfo.DestinationAddr = localAddr
 	packet.packetInfo.NIC = pkt.NICID
-	packet.receivedAt = e.stack.Clock().Now()
+	packet.receivedAt = pkt.ReceivedAt(e.stack.Clock())
 
 	e.rcvMu.Unlock()
 
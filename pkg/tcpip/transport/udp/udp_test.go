@@ -1951,6 +1951,47 @@ func TestShutdownRead(t *testing.T) {
 	}
 }
 
+// TestSoftAbort verifies that a soft-aborted endpoint reports the abort error
+// once, is disconnected, and remains usable.
+func TestSoftAbort(t *testing.T) {
+	c := context.New(t, []stack.TransportProtocolFactory{udp.NewProtocol, icmp.NewProtocol6, icmp.NewProtocol4})
+	defer c.Cleanup()
+
+	c.CreateEndpoint(ipv6.ProtocolNumber, udp.ProtocolNumber)
+
+	if err := c.EP.Bind(tcpip.FullAddress{Port: context.StackPort}); err != nil {
+		c.T.Fatalf("Bind failed: %s", err)
+	}
+
+	if err := c.EP.Connect(tcpip.FullAddress{Addr: context.TestV6Addr, Port: context.TestPort}); err != nil {
+		c.T.Fatalf("Connect failed: %s", err)
+	}
+
+	c.EP.(tcpip.EndpointWithSoftAbort).SoftAbort(&tcpip.ErrConnectionAborted{})
+
+	var buf bytes.Buffer
+	if _, err := c.EP.Read(&buf, tcpip.ReadOptions{}); err == nil {
+		t.Errorf("c.EP.Read(...) succeeded, want = %s", &tcpip.ErrConnectionAborted{})
+	} else if _, ok := err.(*tcpip.ErrConnectionAborted); !ok {
+		t.Errorf("got c.EP.Read(...) = %s, want = %s", err, &tcpip.ErrConnectionAborted{})
+	}
+	if _, err := c.EP.Read(&buf, tcpip.ReadOptions{}); err == nil {
+		t.Errorf("c.EP.Read(...) succeeded, want = %s", &tcpip.ErrWouldBlock{})
+	} else if _, ok := err.(*tcpip.ErrWouldBlock); !ok {
+		t.Errorf("got c.EP.Read(...) = %s, want = %s", err, &tcpip.ErrWouldBlock{})
+	}
+	if _, err := c.EP.GetRemoteAddress(); err == nil {
+		t.Errorf("c.EP.GetRemoteAddress() succeeded, want = %s", &tcpip.ErrNotConnected{})
+	} else if _, ok := err.(*tcpip.ErrNotConnected); !ok {
+		t.Errorf("got c.EP.GetRemoteAddress() = %s, want = %s", err, &tcpip.ErrNotConnected{})
+	}
+	if got, err := c.EP.GetLocalAddress(); err != nil {
+		t.Errorf("c.EP.GetLocalAddress() failed: %s", err)
+	} else if got.Port != context.StackPort {
+		t.Errorf("got local port = %d, want = %d", got.Port, context.StackPort)
+	}
+}
+
 // TestShutdownWrite verifies endpoint write shutdown and error
 // stats increment on packet write.
 func TestShutdownWrite(t *testing.T) {
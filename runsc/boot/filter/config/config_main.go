@@ -25,7 +25,19 @@ import (
 var allowedSyscalls = seccomp.MakeSyscallRules(map[uintptr]seccomp.SyscallRule{
 	unix.SYS_CLOCK_GETTIME: seccomp.MatchAll{},
 	unix.SYS_CLOSE:         seccomp.MatchAll{},
-	unix.SYS_DUP:           seccomp.MatchAll{},
+	// copy_file_range is used by gofer mounts to implement
+	// copy_file_range(2) between two files for which the gofer donated
+	// host FDs, without copying the data through the Sentry or asking the
+	// gofer to do it. The flags argument must be 0.
+	unix.SYS_COPY_FILE_RANGE: seccomp.PerArg{
+		seccomp.NonNegativeFD{},
+		seccomp.AnyValue{},
+		seccomp.NonNegativeFD{},
+		seccomp.AnyValue{},
+		seccomp.AnyValue{},
+		seccomp.EqualTo(0),
+	},
+	unix.SYS_DUP: seccomp.MatchAll{},
 	unix.SYS_DUP3: seccomp.PerArg{
 		seccomp.AnyValue{},
 		seccomp.AnyValue{},
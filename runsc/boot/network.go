@@ -69,6 +69,11 @@ var (
 	}
 )
 
+// fifoECNMarkThreshold is the length of the queues of the fifo qdisc from
+// which ECN-capable packets are marked with congestion experienced, when
+// config.QDiscFIFOECN is used.
+const fifoECNMarkThreshold = 100
+
 // Network exposes methods that can be used to configure a network stack.
 type Network struct {
 	Stack  *stack.Stack
@@ -397,6 +402,9 @@ func (n *Network) CreateLinksAndRoutes(args *CreateLinksAndRoutesArgs, _ *struct
 			case config.QDiscFIFO:
 				log.Infof("Enabling FIFO QDisc on %q", link.Name)
 				qDisc = fifo.New(linkEP, runtime.GOMAXPROCS(0), 1000)
+			case config.QDiscFIFOECN:
+				log.Infof("Enabling FIFO QDisc with ECN marking on %q", link.Name)
+				qDisc = fifo.NewWithECN(linkEP, runtime.GOMAXPROCS(0), 1000, fifoECNMarkThreshold)
 			}
 
 			log.Infof("Enabling interface %q with id %d on addresses %+v (%v) w/ %d channels", link.Name, nicID, link.Addresses, mac, link.NumChannels)
@@ -490,6 +498,9 @@ func (n *Network) CreateLinksAndRoutes(args *CreateLinksAndRoutesArgs, _ *struct
 		case config.QDiscFIFO:
 			log.Infof("Enabling FIFO QDisc on %q", link.Name)
 			qDisc = fifo.New(linkEP, runtime.GOMAXPROCS(0), 1000)
+		case config.QDiscFIFOECN:
+			log.Infof("Enabling FIFO QDisc with ECN marking on %q", link.Name)
+			qDisc = fifo.NewWithECN(linkEP, runtime.GOMAXPROCS(0), 1000, fifoECNMarkThreshold)
 		}
 
 		log.Infof("Enabling interface %q with id %d on addresses %+v (%v) w/ %d channels", link.Name, nicID, link.Addresses, mac, link.NumChannels)
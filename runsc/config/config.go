@@ -684,6 +684,11 @@ const (
 
 	// QDiscFIFO applies a simple fifo based queue to the underlying FD.
 	QDiscFIFO
+
+	// QDiscFIFOECN applies a fifo based queue to the underlying FD, which
+	// marks ECN-capable packets with congestion experienced when the queue
+	// builds up.
+	QDiscFIFOECN
 )
 
 func queueingDisciplinePtr(v QueueingDiscipline) *QueueingDiscipline {
@@ -697,6 +702,8 @@ func (q *QueueingDiscipline) Set(v string) error {
 		*q = QDiscNone
 	case "fifo":
 		*q = QDiscFIFO
+	case "fifo-ecn":
+		*q = QDiscFIFOECN
 	default:
 		return fmt.Errorf("invalid qdisc %q", v)
 	}
@@ -715,6 +722,8 @@ func (q QueueingDiscipline) String() string {
 		return "none"
 	case QDiscFIFO:
 		return "fifo"
+	case QDiscFIFOECN:
+		return "fifo-ecn"
 	}
 	panic(fmt.Sprintf("Invalid qdisc %d", q))
 }
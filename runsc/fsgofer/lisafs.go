@@ -1094,6 +1094,21 @@ func (fd *openFDLisa) Allocate(mode, off, length uint64) error {
 	return unix.Fallocate(fd.hostFD, uint32(mode), int64(off), int64(length))
 }
 
+// CopyFileRange implements lisafs.OpenFDImpl.CopyFileRange.
+func (fd *openFDLisa) CopyFileRange(src lisafs.OpenFDImpl, srcOff, dstOff, length uint64) (uint64, error) {
+	srcFD, ok := src.(*openFDLisa)
+	if !ok {
+		return 0, unix.EXDEV
+	}
+	rOff := int64(srcOff)
+	wOff := int64(dstOff)
+	n, err := unix.CopyFileRange(srcFD.hostFD, &rOff, fd.hostFD, &wOff, int(length), 0)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(n), nil
+}
+
 // Flush implements lisafs.OpenFDImpl.Flush.
 func (fd *openFDLisa) Flush() error {
 	return nil
@@ -17,8 +17,11 @@
 
 #include <limits.h>
 #include <linux/magic.h>
+#include <signal.h>
+#include <sys/mman.h>
 #include <sys/mount.h>
 #include <sys/statfs.h>
+#include <sys/wait.h>
 #include <unistd.h>
 
 #include <cerrno>
@@ -29,8 +32,11 @@
 #include "absl/container/flat_hash_map.h"
 #include "absl/container/flat_hash_set.h"
 #include "absl/strings/ascii.h"
+#include "absl/strings/match.h"
+#include "absl/strings/str_cat.h"
 #include "absl/strings/str_split.h"
 #include "absl/synchronization/notification.h"
+#include "absl/time/clock.h"
 #include "absl/time/time.h"
 #include "test/util/cgroup_util.h"
 #include "test/util/cleanup.h"
@@ -45,6 +51,12 @@ namespace gvisor {
 namespace testing {
 namespace {
 
+// IsThreadedController returns whether the cgroup v2 controller may be
+// enabled in threaded subtrees.
+bool IsThreadedController(absl::string_view name) {
+  return name == "cpu" || name == "cpuset" || name == "pids";
+}
+
 using ::testing::_;
 using ::testing::Contains;
 using ::testing::Each;
@@ -496,6 +508,39 @@ TEST(MemoryCgroup, MemoryUsageInBytes) {
   EXPECT_GE(usage, 0);
 }
 
+TEST(MemoryCgroup, LimitTriggersOOMKill) {
+  SKIP_IF(!CgroupsAvailable());
+
+  constexpr int64_t kLimit = 32 << 20;
+  constexpr size_t kAllocSize = 128 << 20;
+
+  Cgroup root = Cgroup::RootCgroup("/sys/fs/cgroup/memory");
+  Cgroup c = ASSERT_NO_ERRNO_AND_VALUE(root.CreateChild("oom"));
+  ASSERT_NO_ERRNO(c.WriteIntegerControlFile("memory.limit_in_bytes", kLimit));
+
+  const pid_t child = fork();
+  if (child == 0) {
+    TEST_CHECK_NO_ERRNO(c.Enter(getpid()));
+    char* p = static_cast<char*>(mmap(nullptr, kAllocSize,
+                                      PROT_READ | PROT_WRITE,
+                                      MAP_PRIVATE | MAP_ANONYMOUS, -1, 0));
+    TEST_PCHECK(p != MAP_FAILED);
+    // Touching every page exceeds the limit, so this process must be killed
+    // before the loop completes.
+    for (size_t i = 0; i < kAllocSize; i += kPageSize) {
+      p[i] = 1;
+    }
+    _exit(0);
+  }
+  ASSERT_THAT(child, SyscallSucceeds());
+
+  int status;
+  ASSERT_THAT(RetryEINTR(waitpid)(child, &status, 0),
+              SyscallSucceedsWithValue(child));
+  EXPECT_TRUE(WIFSIGNALED(status) && WTERMSIG(status) == SIGKILL)
+      << "status = " << status;
+}
+
 TEST(CPUCgroup, ControlFilesHaveDefaultValues) {
   SKIP_IF(!CgroupsAvailable());
 
@@ -508,6 +553,79 @@ TEST(CPUCgroup, ControlFilesHaveDefaultValues) {
               IsPosixErrorOkAndHolds(1024));
 }
 
+TEST(CPUCgroup, ChildrenDontInheritQuota) {
+  SKIP_IF(!CgroupsAvailable());
+
+  Cgroup root = Cgroup::RootCgroup("/sys/fs/cgroup/cpu");
+  Cgroup parent = ASSERT_NO_ERRNO_AND_VALUE(root.CreateChild("quota_parent"));
+  ASSERT_NO_ERRNO(parent.WriteIntegerControlFile("cpu.cfs_quota_us", 50000));
+  Cgroup child = ASSERT_NO_ERRNO_AND_VALUE(parent.CreateChild("quota_child"));
+  EXPECT_THAT(child.ReadIntegerControlFile("cpu.cfs_quota_us"),
+              IsPosixErrorOkAndHolds(-1));
+
+  // Quotas below the minimum period are rejected, and any negative quota
+  // removes the limit.
+  EXPECT_THAT(parent.WriteIntegerControlFile("cpu.cfs_quota_us", 999),
+              PosixErrorIs(EINVAL, _));
+  ASSERT_NO_ERRNO(parent.WriteIntegerControlFile("cpu.cfs_quota_us", -5));
+  EXPECT_THAT(parent.ReadIntegerControlFile("cpu.cfs_quota_us"),
+              IsPosixErrorOkAndHolds(-1));
+}
+
+// ParseStatFile parses the "key value" lines of a cgroup stat file.
+PosixErrorOr<absl::flat_hash_map<std::string, int64_t>> ParseStatFile(
+    const Cgroup& c, absl::string_view name) {
+  ASSIGN_OR_RETURN_ERRNO(std::string contents, c.ReadControlFile(name));
+  absl::flat_hash_map<std::string, int64_t> stats;
+  for (absl::string_view line :
+       absl::StrSplit(contents, '\n', absl::SkipEmpty())) {
+    std::vector<absl::string_view> fields =
+        absl::StrSplit(line, ' ', absl::SkipEmpty());
+    if (fields.size() != 2) {
+      return PosixError(EINVAL, absl::StrCat("malformed stat line: ", line));
+    }
+    ASSIGN_OR_RETURN_ERRNO(int64_t val, Atoi<int64_t>(fields[1]));
+    stats[std::string(fields[0])] = val;
+  }
+  return stats;
+}
+
+TEST(CPUCgroup, QuotaThrottlesTasks) {
+  SKIP_IF(!CgroupsAvailable());
+
+  Cgroup root = Cgroup::RootCgroup("/sys/fs/cgroup/cpu");
+  Cgroup c = ASSERT_NO_ERRNO_AND_VALUE(root.CreateChild("throttled"));
+  // Allow 10ms of CPU time every 100ms.
+  ASSERT_NO_ERRNO(c.WriteIntegerControlFile("cpu.cfs_period_us", 100000));
+  ASSERT_NO_ERRNO(c.WriteIntegerControlFile("cpu.cfs_quota_us", 10000));
+
+  auto initial = ASSERT_NO_ERRNO_AND_VALUE(ParseStatFile(c, "cpu.stat"));
+  EXPECT_EQ(initial["nr_throttled"], 0);
+  EXPECT_EQ(initial["throttled_time"], 0);
+
+  const pid_t child = fork();
+  if (child == 0) {
+    TEST_CHECK_NO_ERRNO(c.Enter(getpid()));
+    // Spin for a second of wall time.
+    const absl::Time deadline = absl::Now() + absl::Seconds(1);
+    while (absl::Now() < deadline) {
+    }
+    _exit(0);
+  }
+  ASSERT_THAT(child, SyscallSucceeds());
+
+  int status;
+  ASSERT_THAT(RetryEINTR(waitpid)(child, &status, 0),
+              SyscallSucceedsWithValue(child));
+  EXPECT_TRUE(WIFEXITED(status) && WEXITSTATUS(status) == 0)
+      << "status = " << status;
+
+  auto stats = ASSERT_NO_ERRNO_AND_VALUE(ParseStatFile(c, "cpu.stat"));
+  EXPECT_GT(stats["nr_periods"], 0);
+  EXPECT_GT(stats["nr_throttled"], 0);
+  EXPECT_GT(stats["throttled_time"], 0);
+}
+
 TEST(CPUAcctCgroup, CPUAcctUsage) {
   SKIP_IF(!CgroupsAvailable());
 
@@ -1086,6 +1204,253 @@ TEST(DevicesCgroup, IgnorePartialMatchRule) {
               IsPosixErrorOkAndHolds("c 7:* rw\n"));
 }
 
+TEST(Cgroup2, CoreInterfaceFiles) {
+  SKIP_IF(!CgroupsAvailable());
+
+  Mounter m(ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir()));
+  Cgroup c = ASSERT_NO_ERRNO_AND_VALUE(m.MountCgroup2fs(""));
+
+  for (const auto& file :
+       {"cgroup.controllers", "cgroup.subtree_control", "cgroup.procs",
+        "cgroup.threads", "cgroup.events"}) {
+    EXPECT_THAT(Exists(c.Relpath(file)), IsPosixErrorOkAndHolds(true))
+        << file;
+  }
+  // The v1 tasks file doesn't exist on the unified hierarchy.
+  EXPECT_THAT(Exists(c.Relpath("tasks")), IsPosixErrorOkAndHolds(false));
+
+  EXPECT_NO_ERRNO(c.ContainsCallingProcess());
+  EXPECT_THAT(c.ReadControlFile("cgroup.events"),
+              IsPosixErrorOkAndHolds("populated 1\nfrozen 0\n"));
+}
+
+TEST(Cgroup2, Statfs) {
+  SKIP_IF(!CgroupsAvailable());
+
+  Mounter m(ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir()));
+  Cgroup c = ASSERT_NO_ERRNO_AND_VALUE(m.MountCgroup2fs(""));
+
+  struct statfs st;
+  EXPECT_THAT(statfs(c.Path().c_str(), &st), SyscallSucceeds());
+  EXPECT_EQ(st.f_type, CGROUP_SUPER_MAGIC);
+}
+
+TEST(Cgroup2, InvalidMountOption) {
+  SKIP_IF(!CgroupsAvailable());
+
+  TempPath mountpoint = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  EXPECT_THAT(mount("none", mountpoint.path().c_str(), "cgroup2", 0, "memory"),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+TEST(Cgroup2, MountsShareHierarchy) {
+  SKIP_IF(!CgroupsAvailable());
+
+  Mounter m(ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir()));
+  Cgroup c1 = ASSERT_NO_ERRNO_AND_VALUE(m.MountCgroup2fs(""));
+  Cgroup c2 = ASSERT_NO_ERRNO_AND_VALUE(m.MountCgroup2fs("nsdelegate"));
+
+  Cgroup child = ASSERT_NO_ERRNO_AND_VALUE(c1.CreateChild("child"));
+  EXPECT_THAT(Exists(c2.Relpath("child")), IsPosixErrorOkAndHolds(true));
+}
+
+TEST(Cgroup2, ProcPIDCgroupEntry) {
+  SKIP_IF(!CgroupsAvailable());
+
+  Mounter m(ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir()));
+  Cgroup c = ASSERT_NO_ERRNO_AND_VALUE(m.MountCgroup2fs(""));
+
+  absl::flat_hash_map<std::string, PIDCgroupEntry> entries =
+      ASSERT_NO_ERRNO_AND_VALUE(ProcPIDCgroupEntries(getpid()));
+  ASSERT_TRUE(entries.contains(""));
+  EXPECT_EQ(entries[""].hierarchy, 0);
+  EXPECT_EQ(entries[""].path, "/");
+
+  Cgroup child = ASSERT_NO_ERRNO_AND_VALUE(c.CreateChild("child"));
+  ASSERT_NO_ERRNO(child.Enter(getpid()));
+  entries = ASSERT_NO_ERRNO_AND_VALUE(ProcPIDCgroupEntries(getpid()));
+  EXPECT_EQ(entries[""].path, "/child");
+
+  ASSERT_NO_ERRNO(c.Enter(getpid()));
+}
+
+TEST(Cgroup2, SubtreeControlInvalid) {
+  SKIP_IF(!CgroupsAvailable());
+
+  Mounter m(ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir()));
+  Cgroup c = ASSERT_NO_ERRNO_AND_VALUE(m.MountCgroup2fs(""));
+
+  EXPECT_THAT(c.WriteControlFile("cgroup.subtree_control", "+invalid"),
+              PosixErrorIs(EINVAL, _));
+  EXPECT_THAT(c.WriteControlFile("cgroup.subtree_control", "memory"),
+              PosixErrorIs(EINVAL, _));
+  // Cpuacct has no v2 interface.
+  EXPECT_THAT(c.WriteControlFile("cgroup.subtree_control", "+cpuacct"),
+              PosixErrorIs(EINVAL, _));
+  EXPECT_THAT(c.ReadControlFile("cgroup.subtree_control"),
+              IsPosixErrorOkAndHolds("\n"));
+}
+
+TEST(Cgroup2, SubtreeControlUnavailableController) {
+  SKIP_IF(!CgroupsAvailable());
+
+  Mounter m(ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir()));
+  Cgroup c = ASSERT_NO_ERRNO_AND_VALUE(m.MountCgroup2fs(""));
+
+  // Controllers already attached to a v1 hierarchy aren't available.
+  std::string controllers =
+      ASSERT_NO_ERRNO_AND_VALUE(c.ReadControlFile("cgroup.controllers"));
+  SKIP_IF(absl::StrContains(controllers, "memory"));
+  EXPECT_THAT(c.WriteControlFile("cgroup.subtree_control", "+memory"),
+              PosixErrorIs(ENOENT, _));
+}
+
+TEST(Cgroup2, ChildControllersFollowParentSubtreeControl) {
+  SKIP_IF(!CgroupsAvailable());
+
+  Mounter m(ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir()));
+  Cgroup c = ASSERT_NO_ERRNO_AND_VALUE(m.MountCgroup2fs(""));
+  Cgroup child = ASSERT_NO_ERRNO_AND_VALUE(c.CreateChild("child"));
+
+  EXPECT_THAT(child.ReadControlFile("cgroup.controllers"),
+              IsPosixErrorOkAndHolds("\n"));
+
+  std::string controllers =
+      ASSERT_NO_ERRNO_AND_VALUE(c.ReadControlFile("cgroup.controllers"));
+  std::vector<std::string> available =
+      absl::StrSplit(absl::StripAsciiWhitespace(controllers), ' ',
+                     absl::SkipEmpty());
+  SKIP_IF(available.empty());
+
+  // Prefer a domain controller, which is subject to the "no internal process"
+  // constraint.
+  std::string ctl = available[0];
+  for (const auto& name : available) {
+    if (!IsThreadedController(name)) {
+      ctl = name;
+      break;
+    }
+  }
+  ASSERT_NO_ERRNO(c.WriteControlFile("cgroup.subtree_control", "+" + ctl));
+  EXPECT_THAT(c.ReadControlFile("cgroup.subtree_control"),
+              IsPosixErrorOkAndHolds(ctl + "\n"));
+  EXPECT_THAT(child.ReadControlFile("cgroup.controllers"),
+              IsPosixErrorOkAndHolds(ctl + "\n"));
+
+  // A controller can't be disabled while a child still distributes it.
+  ASSERT_NO_ERRNO(child.WriteControlFile("cgroup.subtree_control", "+" + ctl));
+  EXPECT_THAT(c.WriteControlFile("cgroup.subtree_control", "-" + ctl),
+              PosixErrorIs(EBUSY, _));
+
+  if (!IsThreadedController(ctl)) {
+    // No internal processes: the child distributes resources to its
+    // children, so it can't contain processes.
+    EXPECT_THAT(child.Enter(getpid()), PosixErrorIs(EBUSY, _));
+  } else {
+    // Threaded controllers don't prevent the child from becoming a thread
+    // root, which may contain processes.
+    EXPECT_NO_ERRNO(child.Enter(getpid()));
+    ASSERT_NO_ERRNO(c.Enter(getpid()));
+  }
+
+  ASSERT_NO_ERRNO(child.WriteControlFile("cgroup.subtree_control", "-" + ctl));
+  ASSERT_NO_ERRNO(c.WriteControlFile("cgroup.subtree_control", "-" + ctl));
+}
+
+TEST(Cgroup2, ThreadsCannotMoveAcrossDomains) {
+  SKIP_IF(!CgroupsAvailable());
+
+  Mounter m(ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir()));
+  Cgroup c = ASSERT_NO_ERRNO_AND_VALUE(m.MountCgroup2fs(""));
+  Cgroup child = ASSERT_NO_ERRNO_AND_VALUE(c.CreateChild("child"));
+
+  const pid_t tid = syscall(SYS_gettid);
+  std::string threads =
+      ASSERT_NO_ERRNO_AND_VALUE(c.ReadControlFile("cgroup.threads"));
+  std::vector<std::string> tids = absl::StrSplit(threads, '\n');
+  EXPECT_THAT(tids, Contains(absl::StrCat(tid)));
+
+  EXPECT_THAT(child.WriteIntegerControlFile("cgroup.threads", tid),
+              PosixErrorIs(EOPNOTSUPP, _));
+  // Writing to the thread's current cgroup is a no-op.
+  EXPECT_NO_ERRNO(c.WriteIntegerControlFile("cgroup.threads", tid));
+}
+
+TEST(Cgroup2, CgroupType) {
+  SKIP_IF(!CgroupsAvailable());
+
+  Mounter m(ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir()));
+  Cgroup c = ASSERT_NO_ERRNO_AND_VALUE(m.MountCgroup2fs(""));
+  Cgroup dom = ASSERT_NO_ERRNO_AND_VALUE(c.CreateChild("dom"));
+  Cgroup threaded = ASSERT_NO_ERRNO_AND_VALUE(dom.CreateChild("threaded"));
+
+  // The root cgroup has no type.
+  EXPECT_THAT(Exists(c.Relpath("cgroup.type")), IsPosixErrorOkAndHolds(false));
+  EXPECT_THAT(dom.ReadControlFile("cgroup.type"),
+              IsPosixErrorOkAndHolds("domain\n"));
+
+  // Only conversion to a threaded cgroup is possible.
+  EXPECT_THAT(threaded.WriteControlFile("cgroup.type", "domain"),
+              PosixErrorIs(EINVAL, _));
+  ASSERT_NO_ERRNO(threaded.WriteControlFile("cgroup.type", "threaded"));
+  EXPECT_THAT(threaded.ReadControlFile("cgroup.type"),
+              IsPosixErrorOkAndHolds("threaded\n"));
+  EXPECT_THAT(dom.ReadControlFile("cgroup.type"),
+              IsPosixErrorOkAndHolds("domain threaded\n"));
+
+  // Domain cgroups under a threaded cgroup can't contain processes.
+  Cgroup invalid = ASSERT_NO_ERRNO_AND_VALUE(threaded.CreateChild("invalid"));
+  EXPECT_THAT(invalid.ReadControlFile("cgroup.type"),
+              IsPosixErrorOkAndHolds("domain invalid\n"));
+  EXPECT_THAT(invalid.Enter(getpid()), PosixErrorIs(EOPNOTSUPP, _));
+  ASSERT_NO_ERRNO(invalid.WriteControlFile("cgroup.type", "threaded"));
+  EXPECT_THAT(invalid.ReadControlFile("cgroup.type"),
+              IsPosixErrorOkAndHolds("threaded\n"));
+
+  // Domain controllers can't be enabled in a threaded subtree.
+  std::string controllers =
+      ASSERT_NO_ERRNO_AND_VALUE(c.ReadControlFile("cgroup.controllers"));
+  if (absl::StrContains(controllers, "memory")) {
+    ASSERT_NO_ERRNO(c.WriteControlFile("cgroup.subtree_control", "+memory"));
+    EXPECT_THAT(dom.WriteControlFile("cgroup.subtree_control", "+memory"),
+                PosixErrorIs(EOPNOTSUPP, _));
+    ASSERT_NO_ERRNO(c.WriteControlFile("cgroup.subtree_control", "-memory"));
+  }
+}
+
+TEST(Cgroup2, ThreadsMoveWithinThreadedSubtree) {
+  SKIP_IF(!CgroupsAvailable());
+
+  Mounter m(ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir()));
+  Cgroup c = ASSERT_NO_ERRNO_AND_VALUE(m.MountCgroup2fs(""));
+  Cgroup dom = ASSERT_NO_ERRNO_AND_VALUE(c.CreateChild("dom"));
+  Cgroup threaded = ASSERT_NO_ERRNO_AND_VALUE(dom.CreateChild("threaded"));
+  ASSERT_NO_ERRNO(threaded.WriteControlFile("cgroup.type", "threaded"));
+
+  ASSERT_NO_ERRNO(dom.Enter(getpid()));
+  auto cleanup = Cleanup([&] { EXPECT_NO_ERRNO(c.Enter(getpid())); });
+
+  const pid_t tid = syscall(SYS_gettid);
+  ASSERT_NO_ERRNO(threaded.WriteIntegerControlFile("cgroup.threads", tid));
+  std::string threads =
+      ASSERT_NO_ERRNO_AND_VALUE(threaded.ReadControlFile("cgroup.threads"));
+  std::vector<std::string> tids = absl::StrSplit(threads, '\n');
+  EXPECT_THAT(tids, Contains(absl::StrCat(tid)));
+
+  // The process still belongs to the domain of the threaded subtree.
+  std::string procs =
+      ASSERT_NO_ERRNO_AND_VALUE(dom.ReadControlFile("cgroup.procs"));
+  std::vector<std::string> pids = absl::StrSplit(procs, '\n');
+  EXPECT_THAT(pids, Contains(absl::StrCat(getpid())));
+  EXPECT_THAT(threaded.ReadControlFile("cgroup.procs"),
+              PosixErrorIs(EOPNOTSUPP, _));
+
+  // Threads can't leave the threaded domain.
+  EXPECT_THAT(c.WriteIntegerControlFile("cgroup.threads", tid),
+              PosixErrorIs(EOPNOTSUPP, _));
+  ASSERT_NO_ERRNO(dom.WriteIntegerControlFile("cgroup.threads", tid));
+}
+
 }  // namespace
 }  // namespace testing
 }  // namespace gvisor
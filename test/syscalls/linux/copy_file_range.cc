@@ -0,0 +1,213 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include <errno.h>
+#include <fcntl.h>
+#include <sys/syscall.h>
+#include <unistd.h>
+
+#include <string>
+
+#include "gtest/gtest.h"
+#include "absl/strings/str_cat.h"
+#include "test/util/file_descriptor.h"
+#include "test/util/fs_util.h"
+#include "test/util/temp_path.h"
+#include "test/util/test_util.h"
+
+namespace gvisor {
+namespace testing {
+
+namespace {
+
+#ifndef SYS_copy_file_range
+#if defined(__x86_64__)
+#define SYS_copy_file_range 326
+#elif defined(__aarch64__)
+#define SYS_copy_file_range 285
+#endif
+#endif  // SYS_copy_file_range
+
+int CopyFileRange(int fd_in, off_t* off_in, int fd_out, off_t* off_out,
+                  size_t len, unsigned int flags) {
+  return syscall(SYS_copy_file_range, fd_in, off_in, fd_out, off_out, len,
+                 flags);
+}
+
+constexpr char kData[] = "The quick brown fox jumps over the lazy dog.";
+constexpr size_t kDataSize = sizeof(kData) - 1;
+
+class CopyFileRangeTest : public ::testing::Test {
+ protected:
+  void SetUp() override {
+    in_file_ = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFileWith(
+        GetAbsoluteTestTmpdir(), kData, TempPath::kDefaultFileMode));
+    out_file_ = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFile());
+    in_ = ASSERT_NO_ERRNO_AND_VALUE(Open(in_file_.path(), O_RDONLY));
+    out_ = ASSERT_NO_ERRNO_AND_VALUE(Open(out_file_.path(), O_RDWR));
+  }
+
+  TempPath in_file_;
+  TempPath out_file_;
+  FileDescriptor in_;
+  FileDescriptor out_;
+};
+
+TEST_F(CopyFileRangeTest, Basic) {
+  EXPECT_THAT(CopyFileRange(in_.get(), nullptr, out_.get(), nullptr,
+                            kDataSize, 0),
+              SyscallSucceedsWithValue(kDataSize));
+  EXPECT_EQ(ASSERT_NO_ERRNO_AND_VALUE(GetContents(out_file_.path())), kData);
+
+  // Both file offsets are advanced.
+  EXPECT_THAT(lseek(in_.get(), 0, SEEK_CUR),
+              SyscallSucceedsWithValue(kDataSize));
+  EXPECT_THAT(lseek(out_.get(), 0, SEEK_CUR),
+              SyscallSucceedsWithValue(kDataSize));
+}
+
+TEST_F(CopyFileRangeTest, ExplicitOffsets) {
+  off_t off_in = 4;
+  off_t off_out = 10;
+  EXPECT_THAT(CopyFileRange(in_.get(), &off_in, out_.get(), &off_out, 5, 0),
+              SyscallSucceedsWithValue(5));
+  EXPECT_EQ(off_in, 9);
+  EXPECT_EQ(off_out, 15);
+
+  // The file offsets are not changed.
+  EXPECT_THAT(lseek(in_.get(), 0, SEEK_CUR), SyscallSucceedsWithValue(0));
+  EXPECT_THAT(lseek(out_.get(), 0, SEEK_CUR), SyscallSucceedsWithValue(0));
+
+  // The gap before the copied data reads as zeroes.
+  EXPECT_EQ(ASSERT_NO_ERRNO_AND_VALUE(GetContents(out_file_.path())),
+            absl::StrCat(std::string(10, '\0'), "quick"));
+}
+
+TEST_F(CopyFileRangeTest, ShortAtEOF) {
+  off_t off_in = kDataSize - 4;
+  EXPECT_THAT(
+      CopyFileRange(in_.get(), &off_in, out_.get(), nullptr, 100, 0),
+      SyscallSucceedsWithValue(4));
+  EXPECT_EQ(ASSERT_NO_ERRNO_AND_VALUE(GetContents(out_file_.path())), "dog.");
+
+  // Copying from EOF returns 0.
+  EXPECT_THAT(
+      CopyFileRange(in_.get(), &off_in, out_.get(), nullptr, 100, 0),
+      SyscallSucceedsWithValue(0));
+}
+
+TEST_F(CopyFileRangeTest, ZeroLength) {
+  EXPECT_THAT(CopyFileRange(in_.get(), nullptr, out_.get(), nullptr, 0, 0),
+              SyscallSucceedsWithValue(0));
+}
+
+TEST_F(CopyFileRangeTest, InvalidFlags) {
+  EXPECT_THAT(CopyFileRange(in_.get(), nullptr, out_.get(), nullptr,
+                            kDataSize, 1),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+TEST_F(CopyFileRangeTest, NegativeOffset) {
+  off_t off_in = -1;
+  EXPECT_THAT(CopyFileRange(in_.get(), &off_in, out_.get(), nullptr,
+                            kDataSize, 0),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+TEST_F(CopyFileRangeTest, BadFileModes) {
+  const FileDescriptor wronly =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(in_file_.path(), O_WRONLY));
+  EXPECT_THAT(CopyFileRange(wronly.get(), nullptr, out_.get(), nullptr,
+                            kDataSize, 0),
+              SyscallFailsWithErrno(EBADF));
+  EXPECT_THAT(CopyFileRange(in_.get(), nullptr, in_.get(), nullptr,
+                            kDataSize, 0),
+              SyscallFailsWithErrno(EBADF));
+  EXPECT_THAT(CopyFileRange(-1, nullptr, out_.get(), nullptr, kDataSize, 0),
+              SyscallFailsWithErrno(EBADF));
+}
+
+TEST_F(CopyFileRangeTest, Append) {
+  const FileDescriptor append =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(out_file_.path(), O_WRONLY | O_APPEND));
+  EXPECT_THAT(CopyFileRange(in_.get(), nullptr, append.get(), nullptr,
+                            kDataSize, 0),
+              SyscallFailsWithErrno(EBADF));
+}
+
+TEST_F(CopyFileRangeTest, Directory) {
+  const FileDescriptor dir = ASSERT_NO_ERRNO_AND_VALUE(
+      Open(GetAbsoluteTestTmpdir(), O_RDONLY | O_DIRECTORY));
+  EXPECT_THAT(CopyFileRange(dir.get(), nullptr, out_.get(), nullptr,
+                            kDataSize, 0),
+              SyscallFailsWithErrno(EISDIR));
+}
+
+TEST_F(CopyFileRangeTest, Pipe) {
+  int fds[2];
+  ASSERT_THAT(pipe(fds), SyscallSucceeds());
+  const FileDescriptor rfd(fds[0]);
+  const FileDescriptor wfd(fds[1]);
+  EXPECT_THAT(CopyFileRange(in_.get(), nullptr, wfd.get(), nullptr,
+                            kDataSize, 0),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+TEST_F(CopyFileRangeTest, SameFile) {
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(in_file_.path(), O_RDWR));
+
+  // Overlapping ranges are rejected.
+  off_t off_in = 0;
+  off_t off_out = 4;
+  EXPECT_THAT(CopyFileRange(fd.get(), &off_in, fd.get(), &off_out, 8, 0),
+              SyscallFailsWithErrno(EINVAL));
+
+  // Non-overlapping ranges are copied.
+  off_out = kDataSize;
+  EXPECT_THAT(CopyFileRange(fd.get(), &off_in, fd.get(), &off_out, 4, 0),
+              SyscallSucceedsWithValue(4));
+  EXPECT_EQ(ASSERT_NO_ERRNO_AND_VALUE(GetContents(in_file_.path())),
+            absl::StrCat(kData, "The "));
+}
+
+TEST_F(CopyFileRangeTest, LargeFile) {
+  // Copy more than a pipe buffer's worth of data, with a hole in the middle
+  // of the source.
+  constexpr size_t kChunk = 1 << 20;
+  const std::string chunk(kChunk, 'a');
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(in_file_.path(), O_RDWR | O_TRUNC));
+  ASSERT_THAT(pwrite(fd.get(), chunk.data(), kChunk, 0),
+              SyscallSucceedsWithValue(kChunk));
+  ASSERT_THAT(pwrite(fd.get(), chunk.data(), kChunk, 2 * kChunk),
+              SyscallSucceedsWithValue(kChunk));
+
+  size_t total = 0;
+  while (total < 3 * kChunk) {
+    int n;
+    ASSERT_THAT(n = CopyFileRange(fd.get(), nullptr, out_.get(), nullptr,
+                                  3 * kChunk - total, 0),
+                SyscallSucceeds());
+    ASSERT_GT(n, 0);
+    total += n;
+  }
+  EXPECT_EQ(ASSERT_NO_ERRNO_AND_VALUE(GetContents(out_file_.path())),
+            absl::StrCat(chunk, std::string(kChunk, '\0'), chunk));
+}
+
+}  // namespace
+
+}  // namespace testing
+}  // namespace gvisor
@@ -23,6 +23,7 @@
 
 #include <algorithm>
 #include <atomic>
+#include <climits>
 #include <memory>
 #include <vector>
 
@@ -45,6 +46,10 @@ namespace testing {
 
 namespace {
 
+#ifndef FUTEX_LOCK_PI2
+#define FUTEX_LOCK_PI2 13
+#endif
+
 // Amount of time we wait for threads doing futex_wait to start running before
 // doing futex_wake.
 constexpr auto kWaiterStartupDelay = absl::Seconds(3);
@@ -738,6 +743,93 @@ TEST_P(PrivateAndSharedFutexTest, PITryLockConcurrency) {
   }
 }
 
+// FUTEX_LOCK_PI2 measures its absolute timeout against CLOCK_MONOTONIC.
+TEST_P(PrivateAndSharedFutexTest, PILock2Timeout) {
+  std::atomic<int> a(0);
+  const bool is_priv = IsPrivate();
+  const int op = FUTEX_LOCK_PI2 | PrivateFlag();
+
+  ASSERT_THAT(futex_lock_pi(is_priv, &a), SyscallSucceeds());
+
+  ScopedThread th([op, &a] {
+    struct timespec now;
+    TEST_PCHECK(clock_gettime(CLOCK_MONOTONIC, &now) == 0);
+    auto const deadline_ts = absl::ToTimespec(absl::DurationFromTimespec(now) +
+                                              absl::Milliseconds(100));
+    EXPECT_THAT(syscall(SYS_futex, &a, op, 0, &deadline_ts),
+                SyscallFailsWithErrno(ETIMEDOUT));
+  });
+  th.Join();
+
+  ASSERT_THAT(futex_unlock_pi(is_priv, &a), SyscallSucceeds());
+}
+
+TEST_P(PrivateAndSharedFutexTest, RequeuePI) {
+  std::atomic<int> cond(0);
+  std::atomic<int> mutex(0);
+  const bool is_priv = IsPrivate();
+  const int flag = PrivateFlag();
+
+  // Hold the PI futex so that the waiter is requeued rather than woken.
+  ASSERT_THAT(futex_lock_pi(is_priv, &mutex), SyscallSucceeds());
+
+  ScopedThread th([is_priv, flag, &cond, &mutex] {
+    EXPECT_THAT(RetryEINTR(syscall)(SYS_futex, &cond,
+                                    FUTEX_WAIT_REQUEUE_PI | flag, 0, nullptr,
+                                    &mutex, 0),
+                SyscallSucceeds());
+    // The waiter returns owning the PI futex.
+    EXPECT_EQ(mutex.load() & FUTEX_TID_MASK, gettid());
+    EXPECT_THAT(futex_unlock_pi(is_priv, &mutex), SyscallSucceeds());
+  });
+
+  int ret;
+  auto start = absl::Now();
+  while ((ret = syscall(SYS_futex, &cond, FUTEX_CMP_REQUEUE_PI | flag, 1,
+                        INT_MAX, &mutex, 0)) == 0) {
+    ASSERT_LT(absl::Now() - start, absl::Seconds(5));
+    absl::SleepFor(absl::Milliseconds(100));
+  }
+  ASSERT_THAT(ret, SyscallSucceedsWithValue(1));
+  EXPECT_EQ(mutex.load(), static_cast<int>(gettid() | FUTEX_WAITERS));
+
+  // Unlocking hands the PI futex over to the requeued waiter.
+  ASSERT_THAT(futex_unlock_pi(is_priv, &mutex), SyscallSucceeds());
+  th.Join();
+  EXPECT_EQ(mutex.load(), 0);
+}
+
+TEST_P(PrivateAndSharedFutexTest, RequeuePIInvalid) {
+  std::atomic<int> cond(0);
+  std::atomic<int> mutex(0);
+  const int flag = PrivateFlag();
+
+  // The futexes must be different.
+  EXPECT_THAT(syscall(SYS_futex, &cond, FUTEX_WAIT_REQUEUE_PI | flag, 0,
+                      nullptr, &cond, 0),
+              SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(
+      syscall(SYS_futex, &cond, FUTEX_CMP_REQUEUE_PI | flag, 1, 1, &cond, 0),
+      SyscallFailsWithErrno(EINVAL));
+
+  // Only one waiter can be woken.
+  EXPECT_THAT(
+      syscall(SYS_futex, &cond, FUTEX_CMP_REQUEUE_PI | flag, 2, 1, &mutex, 0),
+      SyscallFailsWithErrno(EINVAL));
+
+  // The futex value is compared.
+  EXPECT_THAT(
+      syscall(SYS_futex, &cond, FUTEX_CMP_REQUEUE_PI | flag, 1, 1, &mutex, 1),
+      SyscallFailsWithErrno(EAGAIN));
+}
+
+TEST(PrivateFutexTest, ClockRealtimeInvalidOp) {
+  std::atomic<int> a(0);
+  EXPECT_THAT(syscall(SYS_futex, &a,
+                      FUTEX_WAKE | FUTEX_PRIVATE_FLAG | FUTEX_CLOCK_REALTIME, 1),
+              SyscallFailsWithErrno(ENOSYS));
+}
+
 // Robust mutex tests are disabled on Android because Bionic (Android's libc)
 // doesn't support robust pthread mutexes.
 #ifndef __ANDROID__
@@ -833,6 +925,55 @@ TEST(RobustFutexTest, PthreadMutexAttr) {
   }
 }
 
+// Locks a robust mutex with the given protocol in a thread that exits while
+// another thread is blocked on it.
+void TestRobustMutexOwnerDiedWakesWaiter(int protocol) {
+  pthread_mutexattr_t attr;
+  pthread_mutex_t mtx;
+  TEST_PCHECK(pthread_mutexattr_init(&attr) == 0);
+  TEST_PCHECK(pthread_mutexattr_setrobust(&attr, PTHREAD_MUTEX_ROBUST) == 0);
+  TEST_PCHECK(pthread_mutexattr_setprotocol(&attr, protocol) == 0);
+  TEST_PCHECK(pthread_mutex_init(&mtx, &attr) == 0);
+
+  std::atomic<bool> locked(false);
+  ScopedThread t([&] {
+    TEST_PCHECK(pthread_mutex_lock(&mtx) == 0);
+    locked.store(true);
+    // Give the main thread time to block on the mutex.
+    absl::SleepFor(kWaiterStartupDelay);
+    pthread_exit(NULL);
+  });
+  while (!locked.load()) {
+    absl::SleepFor(absl::Milliseconds(10));
+  }
+
+  // Blocks until the thread exits.
+  EXPECT_EQ(pthread_mutex_lock(&mtx), EOWNERDEAD);
+  EXPECT_EQ(pthread_mutex_consistent(&mtx), 0);
+  EXPECT_EQ(pthread_mutex_unlock(&mtx), 0);
+  t.Join();
+}
+
+TEST(RobustFutexTest, OwnerDiedWakesWaiter) {
+  TestRobustMutexOwnerDiedWakesWaiter(PTHREAD_PRIO_NONE);
+}
+
+TEST(RobustFutexTest, OwnerDiedWakesPIWaiter) {
+  TestRobustMutexOwnerDiedWakesWaiter(PTHREAD_PRIO_INHERIT);
+}
+
+// A circular robust list is only walked up to ROBUST_LIST_LIMIT entries.
+TEST(RobustFutexTest, CircularList) {
+  ScopedThread t([] {
+    struct robust_list entry = {};
+    entry.next = &entry;
+    struct robust_list_head hd = {};
+    hd.list.next = &entry;
+    TEST_PCHECK(set_robust_list(&hd, sizeof(hd)) == 0);
+  });
+  t.Join();
+}
+
 #endif  // __ANDROID__
 
 }  // namespace
@@ -0,0 +1,300 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include <errno.h>
+#include <linux/futex.h>
+#include <stdint.h>
+#include <sys/syscall.h>
+#include <time.h>
+#include <unistd.h>
+
+#include <atomic>
+
+#include "gtest/gtest.h"
+#include "absl/time/clock.h"
+#include "absl/time/time.h"
+#include "test/util/test_util.h"
+#include "test/util/thread_util.h"
+
+namespace gvisor {
+namespace testing {
+
+namespace {
+
+#ifndef SYS_futex_waitv
+#define SYS_futex_waitv 449
+#endif
+#ifndef SYS_futex_wake
+#define SYS_futex_wake 454
+#endif
+#ifndef SYS_futex_wait
+#define SYS_futex_wait 455
+#endif
+#ifndef SYS_futex_requeue
+#define SYS_futex_requeue 456
+#endif
+
+// Defined locally since older headers don't have them.
+constexpr uint32_t kFutex2SizeU8 = 0x00;
+constexpr uint32_t kFutex2SizeU16 = 0x01;
+constexpr uint32_t kFutex2SizeU32 = 0x02;
+constexpr uint32_t kFutex2SizeU64 = 0x03;
+constexpr uint32_t kFutex2Private = 128;
+constexpr int kFutexWaitvMax = 128;
+
+struct futex_waitv_t {
+  uint64_t val;
+  uint64_t uaddr;
+  uint32_t flags;
+  uint32_t reserved;
+};
+
+// Amount of time we wait for threads to start waiting before waking them.
+constexpr auto kWaiterStartupDelay = absl::Seconds(1);
+
+futex_waitv_t Waitv(void* addr, uint64_t val, uint32_t flags) {
+  futex_waitv_t w = {};
+  w.val = val;
+  w.uaddr = reinterpret_cast<uint64_t>(addr);
+  w.flags = flags;
+  return w;
+}
+
+// Returns an absolute CLOCK_MONOTONIC deadline timeout from now.
+struct timespec MonotonicDeadline(absl::Duration timeout) {
+  struct timespec now;
+  TEST_PCHECK(clock_gettime(CLOCK_MONOTONIC, &now) == 0);
+  return absl::ToTimespec(absl::DurationFromTimespec(now) + timeout);
+}
+
+int futex_waitv(futex_waitv_t* waiters, unsigned int nr, unsigned int flags,
+                struct timespec* timeout, clockid_t clockid) {
+  return syscall(SYS_futex_waitv, waiters, nr, flags, timeout, clockid);
+}
+
+int futex2_wake(void* addr, uint64_t mask, int nr, unsigned int flags) {
+  return syscall(SYS_futex_wake, addr, mask, nr, flags);
+}
+
+int futex2_wait(void* addr, uint64_t val, uint64_t mask, unsigned int flags,
+                struct timespec* timeout, clockid_t clockid) {
+  return syscall(SYS_futex_wait, addr, val, mask, flags, timeout, clockid);
+}
+
+// Wakes one waiter on addr, retrying until a waiter is found.
+void WakeOne(void* addr, unsigned int flags) {
+  absl::SleepFor(kWaiterStartupDelay);
+  int ret;
+  while ((ret = futex2_wake(addr, FUTEX_BITSET_MATCH_ANY, 1, flags)) == 0) {
+    absl::SleepFor(absl::Milliseconds(10));
+  }
+  TEST_PCHECK(ret == 1);
+}
+
+TEST(FutexWaitvTest, InvalidArguments) {
+  uint32_t word = 0;
+  futex_waitv_t w = Waitv(&word, 0, kFutex2SizeU32);
+
+  EXPECT_THAT(futex_waitv(&w, 1, 1, nullptr, CLOCK_MONOTONIC),
+              SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(futex_waitv(&w, 0, 0, nullptr, CLOCK_MONOTONIC),
+              SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(futex_waitv(&w, kFutexWaitvMax + 1, 0, nullptr, CLOCK_MONOTONIC),
+              SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(futex_waitv(nullptr, 1, 0, nullptr, CLOCK_MONOTONIC),
+              SyscallFailsWithErrno(EINVAL));
+
+  struct timespec ts = MonotonicDeadline(absl::Seconds(1));
+  EXPECT_THAT(futex_waitv(&w, 1, 0, &ts, CLOCK_BOOTTIME),
+              SyscallFailsWithErrno(EINVAL));
+
+  futex_waitv_t reserved = w;
+  reserved.reserved = 1;
+  EXPECT_THAT(futex_waitv(&reserved, 1, 0, nullptr, CLOCK_MONOTONIC),
+              SyscallFailsWithErrno(EINVAL));
+
+  futex_waitv_t bad_flags = w;
+  bad_flags.flags |= 0x40;
+  EXPECT_THAT(futex_waitv(&bad_flags, 1, 0, nullptr, CLOCK_MONOTONIC),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+TEST(FutexWaitvTest, ValueMismatch) {
+  uint32_t words[2] = {1, 2};
+  futex_waitv_t w[2] = {
+      Waitv(&words[0], 1, kFutex2SizeU32 | kFutex2Private),
+      Waitv(&words[1], 3, kFutex2SizeU32 | kFutex2Private),
+  };
+  EXPECT_THAT(futex_waitv(w, 2, 0, nullptr, CLOCK_MONOTONIC),
+              SyscallFailsWithErrno(EAGAIN));
+}
+
+TEST(FutexWaitvTest, Timeout) {
+  uint32_t word = 0;
+  futex_waitv_t w = Waitv(&word, 0, kFutex2SizeU32 | kFutex2Private);
+
+  struct timespec ts = MonotonicDeadline(absl::Milliseconds(100));
+  EXPECT_THAT(futex_waitv(&w, 1, 0, &ts, CLOCK_MONOTONIC),
+              SyscallFailsWithErrno(ETIMEDOUT));
+
+  ts = absl::ToTimespec(absl::Now() - absl::UnixEpoch());
+  EXPECT_THAT(futex_waitv(&w, 1, 0, &ts, CLOCK_REALTIME),
+              SyscallFailsWithErrno(ETIMEDOUT));
+}
+
+TEST(FutexWaitvTest, WakeReturnsIndex) {
+  uint32_t words[3] = {};
+  futex_waitv_t w[3];
+  for (int i = 0; i < 3; i++) {
+    w[i] = Waitv(&words[i], 0, kFutex2SizeU32 | kFutex2Private);
+  }
+
+  ScopedThread t([&] { WakeOne(&words[2], kFutex2SizeU32 | kFutex2Private); });
+  EXPECT_THAT(RetryEINTR(futex_waitv)(w, 3, 0, nullptr, CLOCK_MONOTONIC),
+              SyscallSucceedsWithValue(2));
+}
+
+TEST(FutexWaitvTest, Shared) {
+  uint32_t word = 0;
+  futex_waitv_t w = Waitv(&word, 0, kFutex2SizeU32);
+
+  ScopedThread t([&] { WakeOne(&word, kFutex2SizeU32); });
+  EXPECT_THAT(RetryEINTR(futex_waitv)(&w, 1, 0, nullptr, CLOCK_MONOTONIC),
+              SyscallSucceedsWithValue(0));
+}
+
+TEST(FutexWaitvTest, Sizes) {
+  SKIP_IF(!IsRunningOnGvisor());  // Linux only supports 32-bit futexes.
+
+  alignas(8) uint8_t buf[16] = {};
+  buf[1] = 0x12;
+  uint16_t* u16 = reinterpret_cast<uint16_t*>(&buf[2]);
+  *u16 = 0x3456;
+  uint64_t* u64 = reinterpret_cast<uint64_t*>(&buf[8]);
+  *u64 = 0x0123456789abcdefULL;
+
+  futex_waitv_t w[3] = {
+      Waitv(&buf[1], 0x12, kFutex2SizeU8 | kFutex2Private),
+      Waitv(u16, 0x3456, kFutex2SizeU16 | kFutex2Private),
+      Waitv(u64, 0x0123456789abcdefULL, kFutex2SizeU64 | kFutex2Private),
+  };
+
+  // Each word has its expected value, so the wait must time out.
+  struct timespec ts = MonotonicDeadline(absl::Milliseconds(100));
+  EXPECT_THAT(futex_waitv(w, 3, 0, &ts, CLOCK_MONOTONIC),
+              SyscallFailsWithErrno(ETIMEDOUT));
+
+  // Changing any one of them must fail the wait.
+  for (int i = 0; i < 3; i++) {
+    futex_waitv_t mismatch[3] = {w[0], w[1], w[2]};
+    mismatch[i].val ^= 1;
+    EXPECT_THAT(futex_waitv(mismatch, 3, 0, nullptr, CLOCK_MONOTONIC),
+                SyscallFailsWithErrno(EAGAIN));
+  }
+
+  // Values that don't fit in the futex word are invalid.
+  futex_waitv_t too_big = w[0];
+  too_big.val = 0x100;
+  EXPECT_THAT(futex_waitv(&too_big, 1, 0, nullptr, CLOCK_MONOTONIC),
+              SyscallFailsWithErrno(EINVAL));
+
+  // Futex words must be naturally aligned.
+  futex_waitv_t unaligned = Waitv(&buf[1], 0, kFutex2SizeU16);
+  EXPECT_THAT(futex_waitv(&unaligned, 1, 0, nullptr, CLOCK_MONOTONIC),
+              SyscallFailsWithErrno(EINVAL));
+
+  ScopedThread t([&] { WakeOne(u16, kFutex2SizeU16 | kFutex2Private); });
+  EXPECT_THAT(RetryEINTR(futex_waitv)(w, 3, 0, nullptr, CLOCK_MONOTONIC),
+              SyscallSucceedsWithValue(1));
+}
+
+TEST(Futex2Test, WakeInvalidArguments) {
+  uint32_t word = 0;
+  EXPECT_THAT(futex2_wake(&word, 0, 1, kFutex2SizeU32),
+              SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(futex2_wake(&word, FUTEX_BITSET_MATCH_ANY, 1, 0x40),
+              SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(futex2_wake(reinterpret_cast<char*>(&word) + 1,
+                          FUTEX_BITSET_MATCH_ANY, 1, kFutex2SizeU32),
+              SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(futex2_wake(&word, FUTEX_BITSET_MATCH_ANY, 0, kFutex2SizeU32),
+              SyscallSucceedsWithValue(0));
+}
+
+TEST(Futex2Test, WaitWake) {
+  uint32_t word = 0;
+  constexpr uint32_t kFlags = kFutex2SizeU32 | kFutex2Private;
+
+  EXPECT_THAT(futex2_wait(&word, 1, FUTEX_BITSET_MATCH_ANY, kFlags, nullptr,
+                          CLOCK_MONOTONIC),
+              SyscallFailsWithErrno(EAGAIN));
+  EXPECT_THAT(futex2_wait(&word, 0, 0, kFlags, nullptr, CLOCK_MONOTONIC),
+              SyscallFailsWithErrno(EINVAL));
+
+  struct timespec ts = MonotonicDeadline(absl::Milliseconds(100));
+  EXPECT_THAT(futex2_wait(&word, 0, FUTEX_BITSET_MATCH_ANY, kFlags, &ts,
+                          CLOCK_MONOTONIC),
+              SyscallFailsWithErrno(ETIMEDOUT));
+
+  ScopedThread t([&] { WakeOne(&word, kFlags); });
+  EXPECT_THAT(RetryEINTR(futex2_wait)(&word, 0, FUTEX_BITSET_MATCH_ANY, kFlags,
+                                      nullptr, CLOCK_MONOTONIC),
+              SyscallSucceeds());
+}
+
+TEST(Futex2Test, Requeue) {
+  uint32_t words[2] = {};
+  constexpr uint32_t kFlags = kFutex2SizeU32 | kFutex2Private;
+  futex_waitv_t w[2] = {
+      Waitv(&words[0], 0, kFlags),
+      Waitv(&words[1], 0, kFlags),
+  };
+
+  EXPECT_THAT(syscall(SYS_futex_requeue, w, 1, 0, 1),
+              SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(syscall(SYS_futex_requeue, w, 0, -1, 1),
+              SyscallFailsWithErrno(EINVAL));
+
+  futex_waitv_t mismatch[2] = {w[0], w[1]};
+  mismatch[0].val = 1;
+  EXPECT_THAT(syscall(SYS_futex_requeue, mismatch, 0, 0, 1),
+              SyscallFailsWithErrno(EAGAIN));
+
+  std::atomic<bool> woken(false);
+  ScopedThread t([&] {
+    TEST_PCHECK(RetryEINTR(futex2_wait)(&words[0], 0, FUTEX_BITSET_MATCH_ANY,
+                                        kFlags, nullptr, CLOCK_MONOTONIC) == 0);
+    woken.store(true);
+  });
+
+  // Move the waiter from words[0] to words[1], then wake it there.
+  absl::SleepFor(kWaiterStartupDelay);
+  int ret;
+  while ((ret = syscall(SYS_futex_requeue, w, 0, 0, 1)) == 0) {
+    absl::SleepFor(absl::Milliseconds(10));
+  }
+  EXPECT_EQ(ret, 1);
+  EXPECT_THAT(futex2_wake(&words[0], FUTEX_BITSET_MATCH_ANY, 1, kFlags),
+              SyscallSucceedsWithValue(0));
+  EXPECT_FALSE(woken.load());
+  EXPECT_THAT(futex2_wake(&words[1], FUTEX_BITSET_MATCH_ANY, 1, kFlags),
+              SyscallSucceedsWithValue(1));
+  t.Join();
+  EXPECT_TRUE(woken.load());
+}
+
+}  // namespace
+
+}  // namespace testing
+}  // namespace gvisor
@@ -15,12 +15,15 @@
 #include <asm-generic/errno-base.h>
 #include <errno.h>
 #include <fcntl.h>
+#include <poll.h>
 #include <pthread.h>
 #include <stdio.h>
 #include <stdlib.h>
 #include <string.h>
 #include <sys/epoll.h>
+#include <sys/eventfd.h>
 #include <sys/mman.h>
+#include <sys/socket.h>
 #include <sys/stat.h>
 #include <sys/types.h>
 #include <unistd.h>
@@ -28,8 +31,12 @@
 #include <cerrno>
 #include <cstddef>
 #include <cstdint>
+#include <vector>
 
 #include "gtest/gtest.h"
+#include "absl/time/clock.h"
+#include "absl/time/time.h"
+#include "test/util/eventfd_util.h"
 #include "test/util/io_uring_util.h"
 #include "test/util/memory_util.h"
 #include "test/util/multiprocess_util.h"
@@ -42,6 +49,10 @@ namespace testing {
 
 namespace {
 
+#ifndef STATX_SIZE
+#define STATX_SIZE 0x00000200U
+#endif  // STATX_SIZE
+
 bool IOUringAvailable() {
   if (IsRunningOnGvisor()) {
     return true;
@@ -91,6 +102,33 @@ MATCHER_P(IOVecContainsString, str, "") {
   return true;
 }
 
+// PrepareSqe returns the SQE for the i-th submission past the current SQ tail,
+// zeroed and added to the SQ array.
+IOUringSqe *PrepareSqe(IOUring *io_uring, uint32_t i) {
+  uint32_t index = (io_uring->load_sq_tail() + i) & io_uring->get_sq_mask();
+  IOUringSqe *sqe = &io_uring->get_sqes()[index];
+  memset(sqe, 0, sizeof(*sqe));
+  io_uring->get_sq_array()[index] = index;
+  return sqe;
+}
+
+// SubmitAndWait publishes the n SQEs prepared with PrepareSqe, submits them and
+// waits for wait completions.
+int SubmitAndWait(IOUring *io_uring, uint32_t n, uint32_t wait) {
+  io_uring->store_sq_tail(io_uring->load_sq_tail() + n);
+  return io_uring->Enter(n, wait, IORING_ENTER_GETEVENTS, nullptr);
+}
+
+// PopCqe returns the CQE at the CQ head and consumes it. Tests using PopCqe
+// post fewer completions than the size of the CQ ring, so that it never wraps
+// around.
+IOUringCqe PopCqe(IOUring *io_uring) {
+  uint32_t cq_head = io_uring->load_cq_head();
+  IOUringCqe cqe = io_uring->get_cqes()[cq_head];
+  io_uring->store_cq_head(cq_head + 1);
+  return cqe;
+}
+
 // Testing that io_uring_setup(2) successfully returns a valid file descriptor.
 TEST(IOUringTest, ValidFD) {
   SKIP_IF(!IOUringAvailable());
@@ -134,7 +172,7 @@ TEST(IOUringTest, UnsupportedFlags) {
 
   IOUringParams params = {};
   memset(&params, 0, sizeof(params));
-  params.flags |= IORING_SETUP_SQPOLL;
+  params.flags |= IORING_SETUP_IOPOLL;
   ASSERT_THAT(IOUringSetup(1, &params), SyscallFailsWithErrno(EINVAL));
 }
 
@@ -1213,6 +1251,581 @@ TEST(IOUringTest, READVWithInvalidSqeFieldValue) {
   io_uring->store_cq_head(cq_head + 1);
 }
 
+// Testing that IORING_OP_WRITE and IORING_OP_READ linked with IOSQE_IO_LINK
+// are executed in order.
+TEST(IOUringTest, LinkedWriteRead) {
+  SKIP_IF(!IOUringAvailable());
+
+  IOUringParams params = {};
+  std::unique_ptr<IOUring> io_uring =
+      ASSERT_NO_ERRNO_AND_VALUE(IOUring::InitIOUring(4, params));
+
+  TempPath file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFile());
+  FileDescriptor filefd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(file.path(), O_RDWR));
+
+  constexpr char kData[] = "DEADBEEF";
+  char buf[sizeof(kData)] = {};
+
+  IOUringSqe *sqe = PrepareSqe(io_uring.get(), 0);
+  sqe->opcode = IORING_OP_WRITE;
+  sqe->flags = IOSQE_IO_LINK;
+  sqe->fd = filefd.get();
+  sqe->addr = reinterpret_cast<uint64_t>(kData);
+  sqe->len = sizeof(kData);
+  sqe->off = 0;
+  sqe->user_data = 1;
+
+  sqe = PrepareSqe(io_uring.get(), 1);
+  sqe->opcode = IORING_OP_READ;
+  sqe->fd = filefd.get();
+  sqe->addr = reinterpret_cast<uint64_t>(buf);
+  sqe->len = sizeof(buf);
+  sqe->off = 0;
+  sqe->user_data = 2;
+
+  ASSERT_EQ(SubmitAndWait(io_uring.get(), 2, 2), 2);
+
+  IOUringCqe cqe = PopCqe(io_uring.get());
+  EXPECT_EQ(cqe.user_data, 1);
+  EXPECT_EQ(cqe.res, sizeof(kData));
+  cqe = PopCqe(io_uring.get());
+  EXPECT_EQ(cqe.user_data, 2);
+  EXPECT_EQ(cqe.res, sizeof(kData));
+  EXPECT_STREQ(buf, kData);
+}
+
+// Testing that IORING_OP_WRITEV writes all the given iovecs.
+TEST(IOUringTest, Writev) {
+  SKIP_IF(!IOUringAvailable());
+
+  IOUringParams params = {};
+  std::unique_ptr<IOUring> io_uring =
+      ASSERT_NO_ERRNO_AND_VALUE(IOUring::InitIOUring(1, params));
+
+  TempPath file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFile());
+  FileDescriptor filefd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(file.path(), O_RDWR));
+
+  char part1[] = "DEAD";
+  char part2[] = "BEEF";
+  struct iovec iov[2] = {{part1, 4}, {part2, 4}};
+
+  IOUringSqe *sqe = PrepareSqe(io_uring.get(), 0);
+  sqe->opcode = IORING_OP_WRITEV;
+  sqe->fd = filefd.get();
+  sqe->addr = reinterpret_cast<uint64_t>(iov);
+  sqe->len = 2;
+  sqe->off = 0;
+
+  ASSERT_EQ(SubmitAndWait(io_uring.get(), 1, 1), 1);
+  EXPECT_EQ(PopCqe(io_uring.get()).res, 8);
+
+  char buf[9] = {};
+  ASSERT_THAT(pread(filefd.get(), buf, 8, 0), SyscallSucceedsWithValue(8));
+  EXPECT_STREQ(buf, "DEADBEEF");
+}
+
+// Testing that the remaining requests of a chain are cancelled when a request
+// fails.
+TEST(IOUringTest, LinkFailureCancelsChain) {
+  SKIP_IF(!IOUringAvailable());
+
+  IOUringParams params = {};
+  std::unique_ptr<IOUring> io_uring =
+      ASSERT_NO_ERRNO_AND_VALUE(IOUring::InitIOUring(4, params));
+
+  char buf[8];
+  IOUringSqe *sqe = PrepareSqe(io_uring.get(), 0);
+  sqe->opcode = IORING_OP_READ;
+  sqe->flags = IOSQE_IO_LINK;
+  sqe->fd = -1;
+  sqe->addr = reinterpret_cast<uint64_t>(buf);
+  sqe->len = sizeof(buf);
+  sqe->user_data = 1;
+
+  sqe = PrepareSqe(io_uring.get(), 1);
+  sqe->opcode = IORING_OP_NOP;
+  sqe->user_data = 2;
+
+  ASSERT_EQ(SubmitAndWait(io_uring.get(), 2, 2), 2);
+
+  IOUringCqe cqe = PopCqe(io_uring.get());
+  EXPECT_EQ(cqe.user_data, 1);
+  EXPECT_EQ(cqe.res, -EBADF);
+  cqe = PopCqe(io_uring.get());
+  EXPECT_EQ(cqe.user_data, 2);
+  EXPECT_EQ(cqe.res, -ECANCELED);
+}
+
+// Testing that IORING_REGISTER_PROBE reports the supported opcodes.
+TEST(IOUringTest, RegisterProbe) {
+  SKIP_IF(!IOUringAvailable());
+
+  IOUringParams params = {};
+  std::unique_ptr<IOUring> io_uring =
+      ASSERT_NO_ERRNO_AND_VALUE(IOUring::InitIOUring(1, params));
+
+  constexpr int kOps = 256;
+  std::vector<char> buf(sizeof(struct io_uring_probe) +
+                        kOps * sizeof(struct io_uring_probe_op));
+  auto probe = reinterpret_cast<struct io_uring_probe *>(buf.data());
+  ASSERT_THAT(io_uring->Register(IORING_REGISTER_PROBE, probe, kOps),
+              SyscallSucceeds());
+
+  ASSERT_GE(probe->last_op, IORING_OP_RECV);
+  for (int op : {IORING_OP_NOP, IORING_OP_READV, IORING_OP_WRITEV,
+                 IORING_OP_POLL_ADD, IORING_OP_TIMEOUT, IORING_OP_OPENAT,
+                 IORING_OP_STATX, IORING_OP_READ, IORING_OP_WRITE,
+                 IORING_OP_SEND, IORING_OP_RECV}) {
+    EXPECT_EQ(probe->ops[op].op, op);
+    EXPECT_TRUE(probe->ops[op].flags & IO_URING_OP_SUPPORTED) << op;
+  }
+
+  // The probe must be zeroed.
+  EXPECT_THAT(io_uring->Register(IORING_REGISTER_PROBE, probe, kOps),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+// Testing that io_uring_register(2) fails on files that aren't io_uring files.
+TEST(IOUringTest, RegisterNonIOUringFD) {
+  SKIP_IF(!IOUringAvailable());
+
+  TempPath file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFile());
+  FileDescriptor filefd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(file.path(), O_RDONLY));
+  EXPECT_THAT(
+      IOUringRegister(filefd.get(), IORING_UNREGISTER_BUFFERS, nullptr, 0),
+      SyscallFailsWithErrno(EOPNOTSUPP));
+}
+
+// Testing that IORING_OP_WRITE_FIXED and IORING_OP_READ_FIXED use registered
+// buffers.
+TEST(IOUringTest, FixedBuffers) {
+  SKIP_IF(!IOUringAvailable());
+
+  IOUringParams params = {};
+  std::unique_ptr<IOUring> io_uring =
+      ASSERT_NO_ERRNO_AND_VALUE(IOUring::InitIOUring(4, params));
+
+  TempPath file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFile());
+  FileDescriptor filefd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(file.path(), O_RDWR));
+
+  char bufs[2][16] = {"DEADBEEF", ""};
+  struct iovec iov[2] = {{bufs[0], sizeof(bufs[0])},
+                         {bufs[1], sizeof(bufs[1])}};
+  ASSERT_THAT(io_uring->Register(IORING_REGISTER_BUFFERS, iov, 2),
+              SyscallSucceeds());
+  EXPECT_THAT(io_uring->Register(IORING_REGISTER_BUFFERS, iov, 2),
+              SyscallFailsWithErrno(EBUSY));
+
+  IOUringSqe *sqe = PrepareSqe(io_uring.get(), 0);
+  sqe->opcode = IORING_OP_WRITE_FIXED;
+  sqe->flags = IOSQE_IO_LINK;
+  sqe->fd = filefd.get();
+  sqe->addr = reinterpret_cast<uint64_t>(bufs[0]);
+  sqe->len = 8;
+  sqe->buf_index = 0;
+
+  sqe = PrepareSqe(io_uring.get(), 1);
+  sqe->opcode = IORING_OP_READ_FIXED;
+  sqe->fd = filefd.get();
+  sqe->addr = reinterpret_cast<uint64_t>(bufs[1]);
+  sqe->len = 8;
+  sqe->buf_index = 1;
+
+  ASSERT_EQ(SubmitAndWait(io_uring.get(), 2, 2), 2);
+  EXPECT_EQ(PopCqe(io_uring.get()).res, 8);
+  EXPECT_EQ(PopCqe(io_uring.get()).res, 8);
+  EXPECT_STREQ(bufs[1], "DEADBEEF");
+
+  // Buffer ranges outside of the registered buffer are rejected.
+  sqe = PrepareSqe(io_uring.get(), 0);
+  sqe->opcode = IORING_OP_READ_FIXED;
+  sqe->fd = filefd.get();
+  sqe->addr = reinterpret_cast<uint64_t>(bufs[1]);
+  sqe->len = sizeof(bufs[1]) + 1;
+  sqe->buf_index = 1;
+  ASSERT_EQ(SubmitAndWait(io_uring.get(), 1, 1), 1);
+  EXPECT_EQ(PopCqe(io_uring.get()).res, -EFAULT);
+
+  ASSERT_THAT(io_uring->Register(IORING_UNREGISTER_BUFFERS, nullptr, 0),
+              SyscallSucceeds());
+  EXPECT_THAT(io_uring->Register(IORING_UNREGISTER_BUFFERS, nullptr, 0),
+              SyscallFailsWithErrno(ENXIO));
+}
+
+// Testing that IOSQE_FIXED_FILE uses the registered files, and that
+// IORING_REGISTER_FILES_UPDATE replaces them.
+TEST(IOUringTest, FixedFiles) {
+  SKIP_IF(!IOUringAvailable());
+
+  IOUringParams params = {};
+  std::unique_ptr<IOUring> io_uring =
+      ASSERT_NO_ERRNO_AND_VALUE(IOUring::InitIOUring(1, params));
+
+  TempPath file = ASSERT_NO_ERRNO_AND_VALUE(
+      TempPath::CreateFileWith(GetAbsoluteTestTmpdir(), "DEADBEEF", 0666));
+  FileDescriptor filefd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(file.path(), O_RDONLY));
+
+  int fds[1] = {filefd.get()};
+  ASSERT_THAT(io_uring->Register(IORING_REGISTER_FILES, fds, 1),
+              SyscallSucceeds());
+
+  // The registered file remains usable after its descriptor is closed.
+  filefd.reset();
+
+  char buf[9] = {};
+  IOUringSqe *sqe = PrepareSqe(io_uring.get(), 0);
+  sqe->opcode = IORING_OP_READ;
+  sqe->flags = IOSQE_FIXED_FILE;
+  sqe->fd = 0;
+  sqe->addr = reinterpret_cast<uint64_t>(buf);
+  sqe->len = 8;
+  ASSERT_EQ(SubmitAndWait(io_uring.get(), 1, 1), 1);
+  EXPECT_EQ(PopCqe(io_uring.get()).res, 8);
+  EXPECT_STREQ(buf, "DEADBEEF");
+
+  // Clear the slot.
+  fds[0] = -1;
+  struct io_uring_files_update update = {};
+  update.offset = 0;
+  update.fds = reinterpret_cast<uint64_t>(fds);
+  ASSERT_THAT(io_uring->Register(IORING_REGISTER_FILES_UPDATE, &update, 1),
+              SyscallSucceedsWithValue(1));
+
+  sqe = PrepareSqe(io_uring.get(), 0);
+  sqe->opcode = IORING_OP_READ;
+  sqe->flags = IOSQE_FIXED_FILE;
+  sqe->fd = 0;
+  sqe->addr = reinterpret_cast<uint64_t>(buf);
+  sqe->len = 8;
+  ASSERT_EQ(SubmitAndWait(io_uring.get(), 1, 1), 1);
+  EXPECT_EQ(PopCqe(io_uring.get()).res, -EBADF);
+
+  ASSERT_THAT(io_uring->Register(IORING_UNREGISTER_FILES, nullptr, 0),
+              SyscallSucceeds());
+}
+
+// Testing that a registered eventfd is signalled when completions are posted.
+TEST(IOUringTest, RegisterEventfd) {
+  SKIP_IF(!IOUringAvailable());
+
+  IOUringParams params = {};
+  std::unique_ptr<IOUring> io_uring =
+      ASSERT_NO_ERRNO_AND_VALUE(IOUring::InitIOUring(1, params));
+
+  FileDescriptor efd =
+      ASSERT_NO_ERRNO_AND_VALUE(NewEventFD(0, EFD_NONBLOCK));
+  int fd = efd.get();
+  ASSERT_THAT(io_uring->Register(IORING_REGISTER_EVENTFD, &fd, 1),
+              SyscallSucceeds());
+  EXPECT_THAT(io_uring->Register(IORING_REGISTER_EVENTFD, &fd, 1),
+              SyscallFailsWithErrno(EBUSY));
+
+  IOUringSqe *sqe = PrepareSqe(io_uring.get(), 0);
+  sqe->opcode = IORING_OP_NOP;
+  ASSERT_EQ(SubmitAndWait(io_uring.get(), 1, 1), 1);
+  PopCqe(io_uring.get());
+
+  uint64_t val = 0;
+  ASSERT_THAT(read(efd.get(), &val, sizeof(val)),
+              SyscallSucceedsWithValue(sizeof(val)));
+  EXPECT_EQ(val, 1);
+
+  ASSERT_THAT(io_uring->Register(IORING_UNREGISTER_EVENTFD, nullptr, 0),
+              SyscallSucceeds());
+}
+
+// Testing that IORING_OP_POLL_ADD completes once the file becomes ready.
+TEST(IOUringTest, PollAdd) {
+  SKIP_IF(!IOUringAvailable());
+
+  IOUringParams params = {};
+  std::unique_ptr<IOUring> io_uring =
+      ASSERT_NO_ERRNO_AND_VALUE(IOUring::InitIOUring(1, params));
+
+  int pipefds[2];
+  ASSERT_THAT(pipe(pipefds), SyscallSucceeds());
+  FileDescriptor rfd(pipefds[0]);
+  FileDescriptor wfd(pipefds[1]);
+
+  IOUringSqe *sqe = PrepareSqe(io_uring.get(), 0);
+  sqe->opcode = IORING_OP_POLL_ADD;
+  sqe->fd = rfd.get();
+  sqe->poll32_events = POLLIN;
+  sqe->user_data = 1;
+  ASSERT_EQ(SubmitAndWait(io_uring.get(), 1, 0), 1);
+  EXPECT_EQ(io_uring->load_cq_tail(), 0);
+
+  ASSERT_THAT(WriteFd(wfd.get(), "x", 1), SyscallSucceedsWithValue(1));
+  ASSERT_THAT(io_uring->Enter(0, 1, IORING_ENTER_GETEVENTS, nullptr),
+              SyscallSucceeds());
+
+  IOUringCqe cqe = PopCqe(io_uring.get());
+  EXPECT_EQ(cqe.user_data, 1);
+  EXPECT_EQ(cqe.res & POLLIN, POLLIN);
+}
+
+// Testing that IORING_OP_POLL_REMOVE cancels a pending IORING_OP_POLL_ADD.
+TEST(IOUringTest, PollRemove) {
+  SKIP_IF(!IOUringAvailable());
+
+  IOUringParams params = {};
+  std::unique_ptr<IOUring> io_uring =
+      ASSERT_NO_ERRNO_AND_VALUE(IOUring::InitIOUring(2, params));
+
+  int pipefds[2];
+  ASSERT_THAT(pipe(pipefds), SyscallSucceeds());
+  FileDescriptor rfd(pipefds[0]);
+  FileDescriptor wfd(pipefds[1]);
+
+  IOUringSqe *sqe = PrepareSqe(io_uring.get(), 0);
+  sqe->opcode = IORING_OP_POLL_ADD;
+  sqe->fd = rfd.get();
+  sqe->poll32_events = POLLIN;
+  sqe->user_data = 1;
+  ASSERT_EQ(SubmitAndWait(io_uring.get(), 1, 0), 1);
+
+  sqe = PrepareSqe(io_uring.get(), 0);
+  sqe->opcode = IORING_OP_POLL_REMOVE;
+  sqe->fd = -1;
+  sqe->addr = 1;
+  sqe->user_data = 2;
+  ASSERT_EQ(SubmitAndWait(io_uring.get(), 1, 2), 1);
+
+  // The order of the two completions isn't specified.
+  for (int i = 0; i < 2; i++) {
+    IOUringCqe cqe = PopCqe(io_uring.get());
+    if (cqe.user_data == 1) {
+      EXPECT_EQ(cqe.res, -ECANCELED);
+    } else {
+      EXPECT_EQ(cqe.user_data, 2);
+      EXPECT_EQ(cqe.res, 0);
+    }
+  }
+
+  // Removing an unknown request fails.
+  sqe = PrepareSqe(io_uring.get(), 0);
+  sqe->opcode = IORING_OP_POLL_REMOVE;
+  sqe->fd = -1;
+  sqe->addr = 1;
+  ASSERT_EQ(SubmitAndWait(io_uring.get(), 1, 1), 1);
+  EXPECT_EQ(PopCqe(io_uring.get()).res, -ENOENT);
+}
+
+// Testing that IORING_OP_TIMEOUT completes with ETIME once it expires, or
+// with 0 once the given number of completions have been posted.
+TEST(IOUringTest, Timeout) {
+  SKIP_IF(!IOUringAvailable());
+
+  IOUringParams params = {};
+  std::unique_ptr<IOUring> io_uring =
+      ASSERT_NO_ERRNO_AND_VALUE(IOUring::InitIOUring(2, params));
+
+  struct io_uring_timespec ts = {0, 10 * 1000 * 1000};
+  IOUringSqe *sqe = PrepareSqe(io_uring.get(), 0);
+  sqe->opcode = IORING_OP_TIMEOUT;
+  sqe->fd = -1;
+  sqe->addr = reinterpret_cast<uint64_t>(&ts);
+  sqe->len = 1;
+  sqe->user_data = 1;
+  ASSERT_EQ(SubmitAndWait(io_uring.get(), 1, 1), 1);
+  IOUringCqe cqe = PopCqe(io_uring.get());
+  EXPECT_EQ(cqe.user_data, 1);
+  EXPECT_EQ(cqe.res, -ETIME);
+
+  ts.tv_sec = 100;
+  sqe = PrepareSqe(io_uring.get(), 0);
+  sqe->opcode = IORING_OP_TIMEOUT;
+  sqe->fd = -1;
+  sqe->addr = reinterpret_cast<uint64_t>(&ts);
+  sqe->len = 1;
+  sqe->off = 1;
+  sqe->user_data = 2;
+  sqe = PrepareSqe(io_uring.get(), 1);
+  sqe->opcode = IORING_OP_NOP;
+  sqe->user_data = 3;
+  ASSERT_EQ(SubmitAndWait(io_uring.get(), 2, 2), 2);
+  cqe = PopCqe(io_uring.get());
+  EXPECT_EQ(cqe.user_data, 3);
+  cqe = PopCqe(io_uring.get());
+  EXPECT_EQ(cqe.user_data, 2);
+  EXPECT_EQ(cqe.res, 0);
+}
+
+// Testing that IORING_OP_LINK_TIMEOUT cancels the request it is linked to.
+TEST(IOUringTest, LinkTimeout) {
+  SKIP_IF(!IOUringAvailable());
+
+  IOUringParams params = {};
+  std::unique_ptr<IOUring> io_uring =
+      ASSERT_NO_ERRNO_AND_VALUE(IOUring::InitIOUring(2, params));
+
+  int pipefds[2];
+  ASSERT_THAT(pipe(pipefds), SyscallSucceeds());
+  FileDescriptor rfd(pipefds[0]);
+  FileDescriptor wfd(pipefds[1]);
+
+  char buf[8];
+  IOUringSqe *sqe = PrepareSqe(io_uring.get(), 0);
+  sqe->opcode = IORING_OP_READ;
+  sqe->flags = IOSQE_IO_LINK;
+  sqe->fd = rfd.get();
+  sqe->addr = reinterpret_cast<uint64_t>(buf);
+  sqe->len = sizeof(buf);
+  sqe->user_data = 1;
+
+  struct io_uring_timespec ts = {0, 10 * 1000 * 1000};
+  sqe = PrepareSqe(io_uring.get(), 1);
+  sqe->opcode = IORING_OP_LINK_TIMEOUT;
+  sqe->fd = -1;
+  sqe->addr = reinterpret_cast<uint64_t>(&ts);
+  sqe->len = 1;
+  sqe->user_data = 2;
+
+  ASSERT_EQ(SubmitAndWait(io_uring.get(), 2, 2), 2);
+  for (int i = 0; i < 2; i++) {
+    IOUringCqe cqe = PopCqe(io_uring.get());
+    if (cqe.user_data == 1) {
+      EXPECT_EQ(cqe.res, -ECANCELED);
+    } else {
+      EXPECT_EQ(cqe.user_data, 2);
+      EXPECT_EQ(cqe.res, -ETIME);
+    }
+  }
+}
+
+// Testing that IORING_OP_SEND and IORING_OP_RECV transfer data over a socket.
+TEST(IOUringTest, SendRecv) {
+  SKIP_IF(!IOUringAvailable());
+
+  IOUringParams params = {};
+  std::unique_ptr<IOUring> io_uring =
+      ASSERT_NO_ERRNO_AND_VALUE(IOUring::InitIOUring(2, params));
+
+  int sockfds[2];
+  ASSERT_THAT(socketpair(AF_UNIX, SOCK_STREAM, 0, sockfds), SyscallSucceeds());
+  FileDescriptor s1(sockfds[0]);
+  FileDescriptor s2(sockfds[1]);
+
+  constexpr char kData[] = "DEADBEEF";
+  char buf[sizeof(kData)] = {};
+
+  // The receive is submitted first, and waits for the data to be sent.
+  IOUringSqe *sqe = PrepareSqe(io_uring.get(), 0);
+  sqe->opcode = IORING_OP_RECV;
+  sqe->fd = s2.get();
+  sqe->addr = reinterpret_cast<uint64_t>(buf);
+  sqe->len = sizeof(buf);
+  sqe->user_data = 1;
+  ASSERT_EQ(SubmitAndWait(io_uring.get(), 1, 0), 1);
+
+  sqe = PrepareSqe(io_uring.get(), 0);
+  sqe->opcode = IORING_OP_SEND;
+  sqe->fd = s1.get();
+  sqe->addr = reinterpret_cast<uint64_t>(kData);
+  sqe->len = sizeof(kData);
+  sqe->user_data = 2;
+  ASSERT_EQ(SubmitAndWait(io_uring.get(), 1, 2), 1);
+
+  for (int i = 0; i < 2; i++) {
+    IOUringCqe cqe = PopCqe(io_uring.get());
+    EXPECT_EQ(cqe.res, sizeof(kData)) << cqe.user_data;
+  }
+  EXPECT_STREQ(buf, kData);
+}
+
+// Testing that IORING_OP_OPENAT, IORING_OP_STATX and IORING_OP_CLOSE operate
+// on the file table of the submitter.
+TEST(IOUringTest, OpenatStatxClose) {
+  SKIP_IF(!IOUringAvailable());
+
+  IOUringParams params = {};
+  std::unique_ptr<IOUring> io_uring =
+      ASSERT_NO_ERRNO_AND_VALUE(IOUring::InitIOUring(1, params));
+
+  TempPath file = ASSERT_NO_ERRNO_AND_VALUE(
+      TempPath::CreateFileWith(GetAbsoluteTestTmpdir(), "DEADBEEF", 0666));
+
+  IOUringSqe *sqe = PrepareSqe(io_uring.get(), 0);
+  sqe->opcode = IORING_OP_OPENAT;
+  sqe->fd = AT_FDCWD;
+  sqe->addr = reinterpret_cast<uint64_t>(file.path().c_str());
+  sqe->open_flags = O_RDONLY;
+  ASSERT_EQ(SubmitAndWait(io_uring.get(), 1, 1), 1);
+  int fd = PopCqe(io_uring.get()).res;
+  ASSERT_GE(fd, 0);
+  EXPECT_THAT(fcntl(fd, F_GETFD), SyscallSucceedsWithValue(0));
+
+  // struct statx, see include/uapi/linux/stat.h. stx_size is at offset 40.
+  alignas(8) char statx_buf[256] = {};
+  sqe = PrepareSqe(io_uring.get(), 0);
+  sqe->opcode = IORING_OP_STATX;
+  sqe->fd = fd;
+  sqe->addr = reinterpret_cast<uint64_t>("");
+  sqe->statx_flags = AT_EMPTY_PATH;
+  sqe->len = STATX_SIZE;
+  sqe->off = reinterpret_cast<uint64_t>(statx_buf);
+  ASSERT_EQ(SubmitAndWait(io_uring.get(), 1, 1), 1);
+  EXPECT_EQ(PopCqe(io_uring.get()).res, 0);
+  uint64_t size;
+  memcpy(&size, statx_buf + 40, sizeof(size));
+  EXPECT_EQ(size, 8);
+
+  sqe = PrepareSqe(io_uring.get(), 0);
+  sqe->opcode = IORING_OP_CLOSE;
+  sqe->fd = fd;
+  ASSERT_EQ(SubmitAndWait(io_uring.get(), 1, 1), 1);
+  EXPECT_EQ(PopCqe(io_uring.get()).res, 0);
+  EXPECT_THAT(fcntl(fd, F_GETFD), SyscallFailsWithErrno(EBADF));
+
+  // io_uring files can't be closed through io_uring.
+  sqe = PrepareSqe(io_uring.get(), 0);
+  sqe->opcode = IORING_OP_CLOSE;
+  sqe->fd = io_uring->Fd();
+  ASSERT_EQ(SubmitAndWait(io_uring.get(), 1, 1), 1);
+  EXPECT_EQ(PopCqe(io_uring.get()).res, -EBADF);
+}
+
+// Testing that SQEs are consumed without io_uring_enter(2) with
+// IORING_SETUP_SQPOLL, and that the SQPOLL thread can be woken up after it
+// went idle.
+TEST(IOUringTest, SQPoll) {
+  SKIP_IF(!IOUringAvailable());
+
+  IOUringParams params = {};
+  params.flags = IORING_SETUP_SQPOLL;
+  params.sq_thread_idle = 10;
+  auto io_uring_or = IOUring::InitIOUring(1, params);
+  // Linux < 5.11 requires CAP_SYS_ADMIN for SQPOLL.
+  SKIP_IF(!io_uring_or.ok() && io_uring_or.error().errno_value() == EPERM);
+  std::unique_ptr<IOUring> io_uring =
+      ASSERT_NO_ERRNO_AND_VALUE(std::move(io_uring_or));
+
+  for (int i = 0; i < 2; i++) {
+    IOUringSqe *sqe = PrepareSqe(io_uring.get(), 0);
+    sqe->opcode = IORING_OP_NOP;
+    sqe->user_data = 42 + i;
+    io_uring->store_sq_tail(io_uring->load_sq_tail() + 1);
+    if (io_uring->load_sq_flags() & IORING_SQ_NEED_WAKEUP) {
+      ASSERT_THAT(io_uring->Enter(0, 0, IORING_ENTER_SQ_WAKEUP, nullptr),
+                  SyscallSucceeds());
+    }
+    ASSERT_THAT(io_uring->Enter(0, 1, IORING_ENTER_GETEVENTS, nullptr),
+                SyscallSucceeds());
+    IOUringCqe cqe = PopCqe(io_uring.get());
+    EXPECT_EQ(cqe.user_data, 42 + i);
+    EXPECT_EQ(cqe.res, 0);
+
+    // Wait for the SQPOLL thread to go idle.
+    absl::SleepFor(absl::Milliseconds(100));
+  }
+  EXPECT_EQ(io_uring->load_sq_head(), 2);
+}
+
 }  // namespace
 
 }  // namespace testing
@@ -0,0 +1,503 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include <fcntl.h>
+#include <netinet/in.h>
+#include <sys/socket.h>
+#include <sys/stat.h>
+#include <sys/syscall.h>
+#include <sys/wait.h>
+#include <unistd.h>
+
+#include <cerrno>
+#include <cstdint>
+#include <string>
+
+#include "gtest/gtest.h"
+#include "test/util/file_descriptor.h"
+#include "test/util/fs_util.h"
+#include "test/util/logging.h"
+#include "test/util/multiprocess_util.h"
+#include "test/util/posix_error.h"
+#include "test/util/socket_util.h"
+#include "test/util/temp_path.h"
+#include "test/util/test_util.h"
+
+#ifndef SYS_landlock_create_ruleset
+#if defined(__x86_64__) || defined(__aarch64__)
+#define SYS_landlock_create_ruleset 444
+#define SYS_landlock_add_rule 445
+#define SYS_landlock_restrict_self 446
+#else
+#error "Unknown architecture"
+#endif
+#endif  // SYS_landlock_create_ruleset
+
+#define LANDLOCK_CREATE_RULESET_VERSION (1U << 0)
+#define LANDLOCK_RULE_PATH_BENEATH 1
+#define LANDLOCK_RULE_NET_PORT 2
+
+#define LANDLOCK_ACCESS_FS_WRITE_FILE (1ULL << 1)
+#define LANDLOCK_ACCESS_FS_READ_FILE (1ULL << 2)
+#define LANDLOCK_ACCESS_FS_READ_DIR (1ULL << 3)
+#define LANDLOCK_ACCESS_FS_REMOVE_DIR (1ULL << 4)
+#define LANDLOCK_ACCESS_FS_REMOVE_FILE (1ULL << 5)
+#define LANDLOCK_ACCESS_FS_MAKE_DIR (1ULL << 7)
+#define LANDLOCK_ACCESS_FS_MAKE_REG (1ULL << 8)
+#define LANDLOCK_ACCESS_FS_TRUNCATE (1ULL << 14)
+
+#define LANDLOCK_ACCESS_NET_BIND_TCP (1ULL << 0)
+#define LANDLOCK_ACCESS_NET_CONNECT_TCP (1ULL << 1)
+
+namespace gvisor {
+namespace testing {
+
+namespace {
+
+// RulesetAttr is struct landlock_ruleset_attr.
+struct RulesetAttr {
+  uint64_t handled_access_fs;
+  uint64_t handled_access_net;
+};
+
+// PathBeneathAttr is struct landlock_path_beneath_attr.
+struct PathBeneathAttr {
+  uint64_t allowed_access;
+  int32_t parent_fd;
+} __attribute__((packed));
+
+// NetPortAttr is struct landlock_net_port_attr.
+struct NetPortAttr {
+  uint64_t allowed_access;
+  uint64_t port;
+};
+
+constexpr uint64_t kReadAccess =
+    LANDLOCK_ACCESS_FS_READ_FILE | LANDLOCK_ACCESS_FS_READ_DIR;
+constexpr uint64_t kHandledFS =
+    kReadAccess | LANDLOCK_ACCESS_FS_WRITE_FILE |
+    LANDLOCK_ACCESS_FS_REMOVE_DIR | LANDLOCK_ACCESS_FS_REMOVE_FILE |
+    LANDLOCK_ACCESS_FS_MAKE_DIR | LANDLOCK_ACCESS_FS_MAKE_REG;
+
+int landlock_create_ruleset(const RulesetAttr* attr, size_t size,
+                            uint32_t flags) {
+  return syscall(SYS_landlock_create_ruleset, attr, size, flags);
+}
+
+int landlock_add_rule(int ruleset_fd, int rule_type, const void* attr,
+                      uint32_t flags) {
+  return syscall(SYS_landlock_add_rule, ruleset_fd, rule_type, attr, flags);
+}
+
+int landlock_restrict_self(int ruleset_fd, uint32_t flags) {
+  return syscall(SYS_landlock_restrict_self, ruleset_fd, flags);
+}
+
+int LandlockABIVersion() {
+  return landlock_create_ruleset(nullptr, 0, LANDLOCK_CREATE_RULESET_VERSION);
+}
+
+PosixErrorOr<FileDescriptor> CreateRuleset(uint64_t handled_fs,
+                                           uint64_t handled_net) {
+  RulesetAttr attr = {};
+  attr.handled_access_fs = handled_fs;
+  attr.handled_access_net = handled_net;
+  int fd = landlock_create_ruleset(&attr, sizeof(attr), 0);
+  if (fd < 0) {
+    return PosixError(errno, "landlock_create_ruleset");
+  }
+  return FileDescriptor(fd);
+}
+
+PosixError AddPathRule(int ruleset_fd, uint64_t access, int parent_fd) {
+  PathBeneathAttr attr = {};
+  attr.allowed_access = access;
+  attr.parent_fd = parent_fd;
+  RETURN_ERROR_IF_SYSCALL_FAIL(
+      landlock_add_rule(ruleset_fd, LANDLOCK_RULE_PATH_BENEATH, &attr, 0));
+  return NoError();
+}
+
+PosixError AddNetRule(int ruleset_fd, uint64_t access, uint64_t port) {
+  NetPortAttr attr = {};
+  attr.allowed_access = access;
+  attr.port = port;
+  RETURN_ERROR_IF_SYSCALL_FAIL(
+      landlock_add_rule(ruleset_fd, LANDLOCK_RULE_NET_PORT, &attr, 0));
+  return NoError();
+}
+
+sockaddr_in LoopbackAddr(uint16_t port) {
+  sockaddr_in addr = {};
+  addr.sin_family = AF_INET;
+  addr.sin_addr.s_addr = htonl(INADDR_LOOPBACK);
+  addr.sin_port = htons(port);
+  return addr;
+}
+
+class LandlockTest : public ::testing::Test {
+ protected:
+  void SetUp() override {
+    const int version = LandlockABIVersion();
+    SKIP_IF(version < 0 && (errno == ENOSYS || errno == EOPNOTSUPP));
+    ASSERT_GE(version, 1);
+    version_ = version;
+  }
+
+  int version_ = 0;
+};
+
+TEST_F(LandlockTest, Version) {
+  EXPECT_THAT(landlock_create_ruleset(nullptr, 1,
+                                      LANDLOCK_CREATE_RULESET_VERSION),
+              SyscallFailsWithErrno(EINVAL));
+  RulesetAttr attr = {};
+  EXPECT_THAT(landlock_create_ruleset(&attr, 0,
+                                      LANDLOCK_CREATE_RULESET_VERSION),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+TEST_F(LandlockTest, CreateRulesetInvalid) {
+  RulesetAttr attr = {};
+  attr.handled_access_fs = LANDLOCK_ACCESS_FS_READ_FILE;
+  EXPECT_THAT(landlock_create_ruleset(&attr, sizeof(attr), 1 << 5),
+              SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(landlock_create_ruleset(&attr, sizeof(uint32_t), 0),
+              SyscallFailsWithErrno(EINVAL));
+
+  // Nothing handled.
+  EXPECT_THAT(CreateRuleset(0, 0), PosixErrorIs(ENOMSG));
+  // Unknown access right.
+  EXPECT_THAT(CreateRuleset(1ULL << 62, 0), PosixErrorIs(EINVAL));
+
+  // Larger structs are accepted as long as the extra bytes are zero.
+  struct {
+    RulesetAttr attr;
+    uint64_t extra;
+  } big = {};
+  big.attr.handled_access_fs = LANDLOCK_ACCESS_FS_READ_FILE;
+  const int fd = landlock_create_ruleset(&big.attr, sizeof(big), 0);
+  ASSERT_THAT(fd, SyscallSucceeds());
+  EXPECT_THAT(close(fd), SyscallSucceeds());
+  big.extra = 1;
+  EXPECT_THAT(landlock_create_ruleset(&big.attr, sizeof(big), 0),
+              SyscallFailsWithErrno(E2BIG));
+}
+
+TEST_F(LandlockTest, RulesetIsCloseOnExec) {
+  const FileDescriptor ruleset = ASSERT_NO_ERRNO_AND_VALUE(
+      CreateRuleset(LANDLOCK_ACCESS_FS_READ_FILE, 0));
+  EXPECT_THAT(fcntl(ruleset.get(), F_GETFD),
+              SyscallSucceedsWithValue(FD_CLOEXEC));
+}
+
+TEST_F(LandlockTest, AddRuleInvalid) {
+  const FileDescriptor ruleset = ASSERT_NO_ERRNO_AND_VALUE(
+      CreateRuleset(LANDLOCK_ACCESS_FS_READ_FILE, 0));
+  const TempPath dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  const FileDescriptor dirfd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(dir.path(), O_PATH));
+  const TempPath file =
+      ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFileIn(dir.path()));
+  const FileDescriptor filefd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(file.path(), O_PATH));
+
+  PathBeneathAttr attr = {};
+  attr.allowed_access = LANDLOCK_ACCESS_FS_READ_FILE;
+  attr.parent_fd = dirfd.get();
+  EXPECT_THAT(landlock_add_rule(ruleset.get(), LANDLOCK_RULE_PATH_BENEATH,
+                                &attr, 1),
+              SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(landlock_add_rule(ruleset.get(), 42, &attr, 0),
+              SyscallFailsWithErrno(EINVAL));
+  // Not a ruleset.
+  EXPECT_THAT(landlock_add_rule(dirfd.get(), LANDLOCK_RULE_PATH_BENEATH,
+                                &attr, 0),
+              SyscallFailsWithErrno(EBADFD));
+
+  // No access.
+  EXPECT_THAT(AddPathRule(ruleset.get(), 0, dirfd.get()),
+              PosixErrorIs(ENOMSG));
+  // Access that the ruleset doesn't handle.
+  EXPECT_THAT(
+      AddPathRule(ruleset.get(), LANDLOCK_ACCESS_FS_WRITE_FILE, dirfd.get()),
+      PosixErrorIs(EINVAL));
+  EXPECT_THAT(AddPathRule(ruleset.get(), LANDLOCK_ACCESS_FS_READ_FILE, -1),
+              PosixErrorIs(EBADF));
+  EXPECT_NO_ERRNO(
+      AddPathRule(ruleset.get(), LANDLOCK_ACCESS_FS_READ_FILE, filefd.get()));
+
+  // Pipes aren't part of the filesystem hierarchy.
+  int pipefds[2];
+  ASSERT_THAT(pipe(pipefds), SyscallSucceeds());
+  const FileDescriptor rfd(pipefds[0]);
+  const FileDescriptor wfd(pipefds[1]);
+  EXPECT_THAT(
+      AddPathRule(ruleset.get(), LANDLOCK_ACCESS_FS_READ_FILE, rfd.get()),
+      PosixErrorIs(EBADFD));
+}
+
+TEST_F(LandlockTest, DirectoryRightsOnFile) {
+  const FileDescriptor ruleset =
+      ASSERT_NO_ERRNO_AND_VALUE(CreateRuleset(kHandledFS, 0));
+  const TempPath file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFile());
+  const FileDescriptor filefd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(file.path(), O_PATH));
+  EXPECT_THAT(
+      AddPathRule(ruleset.get(), LANDLOCK_ACCESS_FS_READ_DIR, filefd.get()),
+      PosixErrorIs(EINVAL));
+  EXPECT_THAT(
+      AddPathRule(ruleset.get(), LANDLOCK_ACCESS_FS_MAKE_REG, filefd.get()),
+      PosixErrorIs(EINVAL));
+}
+
+TEST_F(LandlockTest, RestrictSelfInvalid) {
+  const FileDescriptor ruleset = ASSERT_NO_ERRNO_AND_VALUE(
+      CreateRuleset(LANDLOCK_ACCESS_FS_READ_FILE, 0));
+  EXPECT_THAT(landlock_restrict_self(ruleset.get(), 1),
+              SyscallFailsWithErrno(EINVAL));
+  const FileDescriptor fd = ASSERT_NO_ERRNO_AND_VALUE(Open("/", O_PATH));
+  EXPECT_THAT(landlock_restrict_self(fd.get(), 0),
+              SyscallFailsWithErrno(EBADFD));
+  EXPECT_THAT(landlock_restrict_self(-1, 0), SyscallFailsWithErrno(EBADF));
+}
+
+TEST_F(LandlockTest, Filesystem) {
+  const TempPath allowed = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  const TempPath denied = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  const TempPath allowed_file =
+      ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFileIn(allowed.path()));
+  const TempPath denied_file =
+      ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFileIn(denied.path()));
+  const TempPath denied_subdir =
+      ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDirIn(denied.path()));
+
+  const FileDescriptor ruleset =
+      ASSERT_NO_ERRNO_AND_VALUE(CreateRuleset(kHandledFS, 0));
+  const FileDescriptor allowed_fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(allowed.path(), O_PATH));
+  ASSERT_NO_ERRNO(AddPathRule(ruleset.get(), kHandledFS, allowed_fd.get()));
+
+  const std::string allowed_new = JoinPath(allowed.path(), "new");
+  const std::string denied_new = JoinPath(denied.path(), "new");
+  const auto rest = [&] {
+    TEST_CHECK_SUCCESS(landlock_restrict_self(ruleset.get(), 0));
+
+    // Reads and writes are only allowed beneath allowed.
+    int fd;
+    TEST_CHECK_SUCCESS(fd = open(allowed_file.path().c_str(), O_RDWR));
+    TEST_CHECK_SUCCESS(close(fd));
+    TEST_CHECK_SUCCESS(fd = open(allowed.path().c_str(), O_RDONLY));
+    TEST_CHECK_SUCCESS(close(fd));
+    TEST_CHECK_ERRNO(open(denied_file.path().c_str(), O_RDONLY), EACCES);
+    TEST_CHECK_ERRNO(open(denied_file.path().c_str(), O_WRONLY), EACCES);
+    TEST_CHECK_ERRNO(open(denied.path().c_str(), O_RDONLY), EACCES);
+
+    // O_PATH file descriptors are allowed.
+    TEST_CHECK_SUCCESS(fd = open(denied_file.path().c_str(), O_PATH));
+    TEST_CHECK_SUCCESS(close(fd));
+
+    // So is access to metadata.
+    struct stat st;
+    TEST_CHECK_SUCCESS(stat(denied_file.path().c_str(), &st));
+
+    // Creating and removing files.
+    TEST_CHECK_SUCCESS(fd = open(allowed_new.c_str(), O_RDWR | O_CREAT, 0644));
+    TEST_CHECK_SUCCESS(close(fd));
+    TEST_CHECK_SUCCESS(unlink(allowed_new.c_str()));
+    TEST_CHECK_SUCCESS(mkdir(allowed_new.c_str(), 0755));
+    TEST_CHECK_SUCCESS(rmdir(allowed_new.c_str()));
+    TEST_CHECK_ERRNO(open(denied_new.c_str(), O_RDWR | O_CREAT, 0644), EACCES);
+    TEST_CHECK_ERRNO(mkdir(denied_new.c_str(), 0755), EACCES);
+    TEST_CHECK_ERRNO(unlink(denied_file.path().c_str()), EACCES);
+    TEST_CHECK_ERRNO(rmdir(denied_subdir.path().c_str()), EACCES);
+
+    // Files can't be moved out of allowed.
+    TEST_CHECK_ERRNO(rename(allowed_file.path().c_str(), denied_new.c_str()),
+                     EACCES);
+  };
+  EXPECT_THAT(InForkedProcess(rest), IsPosixErrorOkAndHolds(0));
+}
+
+TEST_F(LandlockTest, Truncate) {
+  SKIP_IF(version_ < 3);
+  const TempPath allowed = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  const TempPath denied = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  const TempPath allowed_file = ASSERT_NO_ERRNO_AND_VALUE(
+      TempPath::CreateFileWith(allowed.path(), "data", 0644));
+  const TempPath denied_file = ASSERT_NO_ERRNO_AND_VALUE(
+      TempPath::CreateFileWith(denied.path(), "data", 0644));
+
+  constexpr uint64_t kReadWrite =
+      LANDLOCK_ACCESS_FS_READ_FILE | LANDLOCK_ACCESS_FS_WRITE_FILE;
+  const FileDescriptor ruleset = ASSERT_NO_ERRNO_AND_VALUE(
+      CreateRuleset(kReadWrite | LANDLOCK_ACCESS_FS_TRUNCATE, 0));
+  const FileDescriptor allowed_fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(allowed.path(), O_PATH));
+  ASSERT_NO_ERRNO(AddPathRule(ruleset.get(),
+                              kReadWrite | LANDLOCK_ACCESS_FS_TRUNCATE,
+                              allowed_fd.get()));
+  const FileDescriptor denied_fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(denied.path(), O_PATH));
+  ASSERT_NO_ERRNO(AddPathRule(ruleset.get(), kReadWrite, denied_fd.get()));
+
+  // File descriptors opened before restricting can still be truncated.
+  const FileDescriptor opened_before =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(denied_file.path(), O_RDWR));
+
+  const auto rest = [&] {
+    TEST_CHECK_SUCCESS(landlock_restrict_self(ruleset.get(), 0));
+
+    int fd;
+    TEST_CHECK_ERRNO(open(denied_file.path().c_str(), O_WRONLY | O_TRUNC),
+                     EACCES);
+    struct stat st;
+    TEST_CHECK_SUCCESS(stat(denied_file.path().c_str(), &st));
+    TEST_CHECK(st.st_size == 4);
+    TEST_CHECK_ERRNO(truncate(denied_file.path().c_str(), 0), EACCES);
+    TEST_CHECK_SUCCESS(fd = open(denied_file.path().c_str(), O_RDWR));
+    TEST_CHECK_ERRNO(ftruncate(fd, 0), EACCES);
+    TEST_CHECK_SUCCESS(close(fd));
+    TEST_CHECK_SUCCESS(ftruncate(opened_before.get(), 2));
+
+    TEST_CHECK_SUCCESS(
+        fd = open(allowed_file.path().c_str(), O_WRONLY | O_TRUNC));
+    TEST_CHECK_SUCCESS(fstat(fd, &st));
+    TEST_CHECK(st.st_size == 0);
+    TEST_CHECK_SUCCESS(ftruncate(fd, 1));
+    TEST_CHECK_SUCCESS(close(fd));
+    TEST_CHECK_SUCCESS(truncate(allowed_file.path().c_str(), 2));
+  };
+  EXPECT_THAT(InForkedProcess(rest), IsPosixErrorOkAndHolds(0));
+}
+
+TEST_F(LandlockTest, Stacking) {
+  const TempPath dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  const TempPath file =
+      ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFileIn(dir.path()));
+  const FileDescriptor dirfd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(dir.path(), O_PATH));
+
+  // The first ruleset allows reading and writing, the second only reading.
+  const FileDescriptor rw = ASSERT_NO_ERRNO_AND_VALUE(
+      CreateRuleset(kReadAccess | LANDLOCK_ACCESS_FS_WRITE_FILE, 0));
+  ASSERT_NO_ERRNO(AddPathRule(
+      rw.get(), LANDLOCK_ACCESS_FS_READ_FILE | LANDLOCK_ACCESS_FS_WRITE_FILE,
+      dirfd.get()));
+  const FileDescriptor ro = ASSERT_NO_ERRNO_AND_VALUE(
+      CreateRuleset(LANDLOCK_ACCESS_FS_READ_FILE |
+                        LANDLOCK_ACCESS_FS_WRITE_FILE,
+                    0));
+  ASSERT_NO_ERRNO(
+      AddPathRule(ro.get(), LANDLOCK_ACCESS_FS_READ_FILE, dirfd.get()));
+
+  const auto rest = [&] {
+    TEST_CHECK_SUCCESS(landlock_restrict_self(rw.get(), 0));
+    int fd;
+    TEST_CHECK_SUCCESS(fd = open(file.path().c_str(), O_RDWR));
+    TEST_CHECK_SUCCESS(close(fd));
+
+    TEST_CHECK_SUCCESS(landlock_restrict_self(ro.get(), 0));
+    TEST_CHECK_SUCCESS(fd = open(file.path().c_str(), O_RDONLY));
+    TEST_CHECK_SUCCESS(close(fd));
+    TEST_CHECK_ERRNO(open(file.path().c_str(), O_RDWR), EACCES);
+  };
+  EXPECT_THAT(InForkedProcess(rest), IsPosixErrorOkAndHolds(0));
+}
+
+TEST_F(LandlockTest, InheritedByChildren) {
+  const TempPath file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFile());
+  const FileDescriptor ruleset = ASSERT_NO_ERRNO_AND_VALUE(
+      CreateRuleset(LANDLOCK_ACCESS_FS_READ_FILE, 0));
+
+  const auto rest = [&] {
+    TEST_CHECK_SUCCESS(landlock_restrict_self(ruleset.get(), 0));
+    pid_t pid = fork();
+    if (pid == 0) {
+      TEST_CHECK_ERRNO(open(file.path().c_str(), O_RDONLY), EACCES);
+      _exit(0);
+    }
+    TEST_CHECK_SUCCESS(pid);
+    int status;
+    TEST_CHECK_SUCCESS(RetryEINTR(waitpid)(pid, &status, 0));
+    TEST_CHECK(WIFEXITED(status) && WEXITSTATUS(status) == 0);
+  };
+  EXPECT_THAT(InForkedProcess(rest), IsPosixErrorOkAndHolds(0));
+
+  // The parent isn't restricted.
+  ASSERT_NO_ERRNO(Open(file.path(), O_RDONLY));
+}
+
+TEST_F(LandlockTest, Network) {
+  SKIP_IF(version_ < 4);
+
+  // Find an available port to use.
+  const FileDescriptor probe =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_STREAM, 0));
+  sockaddr_in addr = LoopbackAddr(0);
+  ASSERT_THAT(
+      bind(probe.get(), reinterpret_cast<sockaddr*>(&addr), sizeof(addr)),
+      SyscallSucceeds());
+  socklen_t addrlen = sizeof(addr);
+  ASSERT_THAT(getsockname(probe.get(), reinterpret_cast<sockaddr*>(&addr),
+                          &addrlen),
+              SyscallSucceeds());
+  ASSERT_THAT(listen(probe.get(), 1), SyscallSucceeds());
+  const uint16_t listen_port = ntohs(addr.sin_port);
+
+  const FileDescriptor ruleset = ASSERT_NO_ERRNO_AND_VALUE(CreateRuleset(
+      0, LANDLOCK_ACCESS_NET_BIND_TCP | LANDLOCK_ACCESS_NET_CONNECT_TCP));
+  ASSERT_NO_ERRNO(
+      AddNetRule(ruleset.get(), LANDLOCK_ACCESS_NET_CONNECT_TCP, listen_port));
+  EXPECT_THAT(AddNetRule(ruleset.get(), LANDLOCK_ACCESS_NET_CONNECT_TCP,
+                         1 << 16),
+              PosixErrorIs(EINVAL));
+
+  const auto rest = [&] {
+    TEST_CHECK_SUCCESS(landlock_restrict_self(ruleset.get(), 0));
+
+    // Binding is denied on all ports.
+    int s;
+    TEST_CHECK_SUCCESS(s = socket(AF_INET, SOCK_STREAM, 0));
+    sockaddr_in bind_addr = LoopbackAddr(listen_port + 1);
+    TEST_CHECK_ERRNO(
+        bind(s, reinterpret_cast<sockaddr*>(&bind_addr), sizeof(bind_addr)),
+        EACCES);
+    TEST_CHECK_SUCCESS(close(s));
+
+    // Connecting is only allowed to listen_port.
+    TEST_CHECK_SUCCESS(s = socket(AF_INET, SOCK_STREAM, 0));
+    sockaddr_in other = LoopbackAddr(listen_port + 1);
+    TEST_CHECK_ERRNO(
+        connect(s, reinterpret_cast<sockaddr*>(&other), sizeof(other)),
+        EACCES);
+    sockaddr_in target = LoopbackAddr(listen_port);
+    TEST_CHECK_SUCCESS(
+        connect(s, reinterpret_cast<sockaddr*>(&target), sizeof(target)));
+    TEST_CHECK_SUCCESS(close(s));
+
+    // UDP isn't restricted.
+    TEST_CHECK_SUCCESS(s = socket(AF_INET, SOCK_DGRAM, 0));
+    sockaddr_in udp_addr = LoopbackAddr(0);
+    TEST_CHECK_SUCCESS(
+        bind(s, reinterpret_cast<sockaddr*>(&udp_addr), sizeof(udp_addr)));
+    TEST_CHECK_SUCCESS(close(s));
+  };
+  EXPECT_THAT(InForkedProcess(rest), IsPosixErrorOkAndHolds(0));
+}
+
+}  // namespace
+
+}  // namespace testing
+}  // namespace gvisor
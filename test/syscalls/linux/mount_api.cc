@@ -0,0 +1,492 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include <errno.h>
+#include <fcntl.h>
+#include <linux/capability.h>
+#include <sys/mount.h>
+#include <sys/stat.h>
+#include <sys/syscall.h>
+#include <unistd.h>
+
+#include <cstdint>
+#include <string>
+
+#include "gtest/gtest.h"
+#include "test/util/capability_util.h"
+#include "test/util/cleanup.h"
+#include "test/util/file_descriptor.h"
+#include "test/util/fs_util.h"
+#include "test/util/linux_capability_util.h"
+#include "test/util/mount_util.h"
+#include "test/util/posix_error.h"
+#include "test/util/temp_path.h"
+#include "test/util/test_util.h"
+
+namespace gvisor {
+namespace testing {
+
+namespace {
+
+#ifndef SYS_open_tree
+#define SYS_open_tree 428
+#endif
+#ifndef SYS_move_mount
+#define SYS_move_mount 429
+#endif
+#ifndef SYS_fsopen
+#define SYS_fsopen 430
+#endif
+#ifndef SYS_fsconfig
+#define SYS_fsconfig 431
+#endif
+#ifndef SYS_fsmount
+#define SYS_fsmount 432
+#endif
+#ifndef SYS_fspick
+#define SYS_fspick 433
+#endif
+#ifndef SYS_mount_setattr
+#define SYS_mount_setattr 442
+#endif
+
+// Constants from include/uapi/linux/mount.h, which may not be available in
+// older headers.
+constexpr unsigned int kOpenTreeClone = 1;
+constexpr unsigned int kAtRecursive = 0x8000;
+constexpr unsigned int kMoveMountFEmptyPath = 0x4;
+constexpr unsigned int kFsconfigSetFlag = 0;
+constexpr unsigned int kFsconfigSetString = 1;
+constexpr unsigned int kFsconfigCmdCreate = 6;
+constexpr unsigned int kFsconfigCmdReconfigure = 7;
+constexpr unsigned int kMountAttrRdonly = 0x1;
+constexpr unsigned int kMountAttrNoexec = 0x8;
+constexpr unsigned int kMountAttrNoatime = 0x10;
+constexpr unsigned int kMountAttrAtime = 0x70;
+
+struct MountAttr {
+  uint64_t attr_set;
+  uint64_t attr_clr;
+  uint64_t propagation;
+  uint64_t userns_fd;
+};
+
+constexpr char kTmpfs[] = "tmpfs";
+
+int OpenTree(int dirfd, const char* path, unsigned int flags) {
+  return syscall(SYS_open_tree, dirfd, path, flags);
+}
+
+int MoveMount(int from_dirfd, const char* from_path, int to_dirfd,
+              const char* to_path, unsigned int flags) {
+  return syscall(SYS_move_mount, from_dirfd, from_path, to_dirfd, to_path,
+                 flags);
+}
+
+int Fsopen(const char* fsname, unsigned int flags) {
+  return syscall(SYS_fsopen, fsname, flags);
+}
+
+int Fsconfig(int fd, unsigned int cmd, const char* key, const char* value,
+             int aux) {
+  return syscall(SYS_fsconfig, fd, cmd, key, value, aux);
+}
+
+int Fsmount(int fd, unsigned int flags, unsigned int attr_flags) {
+  return syscall(SYS_fsmount, fd, flags, attr_flags);
+}
+
+int Fspick(int dirfd, const char* path, unsigned int flags) {
+  return syscall(SYS_fspick, dirfd, path, flags);
+}
+
+int MountSetattr(int dirfd, const char* path, unsigned int flags,
+                 MountAttr* attr, size_t size) {
+  return syscall(SYS_mount_setattr, dirfd, path, flags, attr, size);
+}
+
+// CreateTmpfs returns a detached tmpfs mount created with the new mount API.
+PosixErrorOr<FileDescriptor> CreateTmpfs(const std::string& mode,
+                                         unsigned int attr_flags) {
+  int fd = Fsopen(kTmpfs, 0);
+  if (fd < 0) {
+    return PosixError(errno, "fsopen");
+  }
+  FileDescriptor fsfd(fd);
+  if (Fsconfig(fsfd.get(), kFsconfigSetString, "mode", mode.c_str(), 0) < 0) {
+    return PosixError(errno, "fsconfig(FSCONFIG_SET_STRING)");
+  }
+  if (Fsconfig(fsfd.get(), kFsconfigCmdCreate, nullptr, nullptr, 0) < 0) {
+    return PosixError(errno, "fsconfig(FSCONFIG_CMD_CREATE)");
+  }
+  fd = Fsmount(fsfd.get(), 0, attr_flags);
+  if (fd < 0) {
+    return PosixError(errno, "fsmount");
+  }
+  return FileDescriptor(fd);
+}
+
+// UmountCleanup returns a Cleanup that unmounts path.
+Cleanup UmountCleanup(const std::string& path) {
+  return Cleanup([path] {
+    EXPECT_THAT(umount2(path.c_str(), MNT_DETACH), SyscallSucceeds());
+  });
+}
+
+TEST(MountAPITest, FsmountAndMoveMount) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  auto const dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  const FileDescriptor mntfd =
+      ASSERT_NO_ERRNO_AND_VALUE(CreateTmpfs("0700", 0));
+
+  // The detached mount can be used through its file descriptor.
+  EXPECT_NO_ERRNO(OpenAt(mntfd.get(), "foo", O_CREAT | O_RDWR, 0644));
+
+  ASSERT_THAT(MoveMount(mntfd.get(), "", AT_FDCWD, dir.path().c_str(),
+                        kMoveMountFEmptyPath),
+              SyscallSucceeds());
+  auto const cleanup = UmountCleanup(dir.path());
+
+  const struct stat s = ASSERT_NO_ERRNO_AND_VALUE(Stat(dir.path()));
+  EXPECT_EQ(s.st_mode, S_IFDIR | 0700);
+  EXPECT_NO_ERRNO(Stat(JoinPath(dir.path(), "foo")));
+}
+
+TEST(MountAPITest, FsmountReadOnly) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  auto const dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  const FileDescriptor mntfd =
+      ASSERT_NO_ERRNO_AND_VALUE(CreateTmpfs("0777", kMountAttrRdonly));
+  ASSERT_THAT(MoveMount(mntfd.get(), "", AT_FDCWD, dir.path().c_str(),
+                        kMoveMountFEmptyPath),
+              SyscallSucceeds());
+  auto const cleanup = UmountCleanup(dir.path());
+
+  EXPECT_THAT(access(dir.path().c_str(), W_OK), SyscallFailsWithErrno(EROFS));
+}
+
+TEST(MountAPITest, FsopenUnknownFilesystem) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  EXPECT_THAT(Fsopen("foobar", 0), SyscallFailsWithErrno(ENODEV));
+}
+
+TEST(MountAPITest, FsopenPermDenied) {
+  AutoCapability cap(CAP_SYS_ADMIN, false);
+
+  EXPECT_THAT(Fsopen(kTmpfs, 0), SyscallFailsWithErrno(EPERM));
+}
+
+TEST(MountAPITest, FsconfigPhases) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  int fd;
+  ASSERT_THAT(fd = Fsopen(kTmpfs, 0), SyscallSucceeds());
+  const FileDescriptor fsfd(fd);
+
+  // The filesystem must be created before it is mounted.
+  EXPECT_THAT(Fsmount(fsfd.get(), 0, 0), SyscallFailsWithErrno(EBUSY));
+
+  ASSERT_THAT(Fsconfig(fsfd.get(), kFsconfigCmdCreate, nullptr, nullptr, 0),
+              SyscallSucceeds());
+
+  // Parameters can't be changed after the filesystem is created.
+  EXPECT_THAT(Fsconfig(fsfd.get(), kFsconfigSetString, "mode", "0755", 0),
+              SyscallFailsWithErrno(EBUSY));
+  EXPECT_THAT(Fsconfig(fsfd.get(), kFsconfigCmdCreate, nullptr, nullptr, 0),
+              SyscallFailsWithErrno(EBUSY));
+}
+
+TEST(MountAPITest, FsconfigInvalidArguments) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  int fd;
+  ASSERT_THAT(fd = Fsopen(kTmpfs, 0), SyscallSucceeds());
+  const FileDescriptor fsfd(fd);
+
+  EXPECT_THAT(Fsconfig(fsfd.get(), kFsconfigSetFlag, "ro", "1", 0),
+              SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(Fsconfig(fsfd.get(), kFsconfigSetString, "mode", nullptr, 0),
+              SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(Fsconfig(fsfd.get(), kFsconfigCmdCreate, "mode", nullptr, 0),
+              SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(Fsconfig(fsfd.get(), 100, nullptr, nullptr, 0),
+              SyscallFailsWithErrno(EOPNOTSUPP));
+
+  // Multiple sources are rejected.
+  EXPECT_THAT(Fsconfig(fsfd.get(), kFsconfigSetString, "source", "a", 0),
+              SyscallSucceeds());
+  EXPECT_THAT(Fsconfig(fsfd.get(), kFsconfigSetString, "source", "b", 0),
+              SyscallFailsWithErrno(EINVAL));
+
+  // Only filesystem context file descriptors are accepted.
+  const FileDescriptor null =
+      ASSERT_NO_ERRNO_AND_VALUE(Open("/dev/null", O_RDONLY));
+  EXPECT_THAT(Fsconfig(null.get(), kFsconfigCmdCreate, nullptr, nullptr, 0),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+TEST(MountAPITest, FsconfigInvalidParameter) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  int fd;
+  ASSERT_THAT(fd = Fsopen(kTmpfs, 0), SyscallSucceeds());
+  const FileDescriptor fsfd(fd);
+
+  ASSERT_THAT(Fsconfig(fsfd.get(), kFsconfigSetString, "mode", "notamode", 0),
+              SyscallSucceeds());
+  EXPECT_THAT(Fsconfig(fsfd.get(), kFsconfigCmdCreate, nullptr, nullptr, 0),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+TEST(MountAPITest, FspickReconfigure) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  auto const dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  auto const mount = ASSERT_NO_ERRNO_AND_VALUE(
+      Mount("", dir.path(), kTmpfs, 0, "mode=0777", 0));
+
+  // fspick requires the root of a mount.
+  auto const child =
+      ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDirIn(dir.path()));
+  EXPECT_THAT(Fspick(AT_FDCWD, child.path().c_str(), 0),
+              SyscallFailsWithErrno(EINVAL));
+
+  int fd;
+  ASSERT_THAT(fd = Fspick(AT_FDCWD, dir.path().c_str(), 0), SyscallSucceeds());
+  const FileDescriptor fsfd(fd);
+  ASSERT_THAT(Fsconfig(fsfd.get(), kFsconfigSetFlag, "ro", nullptr, 0),
+              SyscallSucceeds());
+  ASSERT_THAT(
+      Fsconfig(fsfd.get(), kFsconfigCmdReconfigure, nullptr, nullptr, 0),
+      SyscallSucceeds());
+  EXPECT_THAT(access(dir.path().c_str(), W_OK), SyscallFailsWithErrno(EROFS));
+
+  ASSERT_THAT(Fsconfig(fsfd.get(), kFsconfigSetFlag, "rw", nullptr, 0),
+              SyscallSucceeds());
+  ASSERT_THAT(
+      Fsconfig(fsfd.get(), kFsconfigCmdReconfigure, nullptr, nullptr, 0),
+      SyscallSucceeds());
+  EXPECT_THAT(access(dir.path().c_str(), W_OK), SyscallSucceeds());
+}
+
+TEST(MountAPITest, OpenTreeClone) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  auto const dir1 = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  auto const dir2 = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  auto const mount = ASSERT_NO_ERRNO_AND_VALUE(
+      Mount("", dir1.path(), kTmpfs, 0, "mode=0777", 0));
+  ASSERT_NO_ERRNO(
+      CreateWithContents(JoinPath(dir1.path(), "foo"), "contents", 0666));
+
+  int fd;
+  ASSERT_THAT(fd = OpenTree(AT_FDCWD, dir1.path().c_str(), kOpenTreeClone),
+              SyscallSucceeds());
+  const FileDescriptor treefd(fd);
+  ASSERT_THAT(MoveMount(treefd.get(), "", AT_FDCWD, dir2.path().c_str(),
+                        kMoveMountFEmptyPath),
+              SyscallSucceeds());
+  auto const cleanup = UmountCleanup(dir2.path());
+
+  std::string contents;
+  ASSERT_NO_ERRNO(GetContents(JoinPath(dir2.path(), "foo"), &contents));
+  EXPECT_EQ(contents, "contents");
+}
+
+TEST(MountAPITest, OpenTreeRecursiveClone) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  auto const dir1 = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  auto const dir2 = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  auto const mount = ASSERT_NO_ERRNO_AND_VALUE(
+      Mount("", dir1.path(), kTmpfs, 0, "mode=0777", 0));
+  auto const child =
+      ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDirIn(dir1.path()));
+  auto const child_mount = ASSERT_NO_ERRNO_AND_VALUE(
+      Mount("", child.path(), kTmpfs, 0, "mode=0700", 0));
+
+  int fd;
+  ASSERT_THAT(fd = OpenTree(AT_FDCWD, dir1.path().c_str(),
+                            kOpenTreeClone | kAtRecursive),
+              SyscallSucceeds());
+  const FileDescriptor treefd(fd);
+  ASSERT_THAT(MoveMount(treefd.get(), "", AT_FDCWD, dir2.path().c_str(),
+                        kMoveMountFEmptyPath),
+              SyscallSucceeds());
+  auto const cleanup = UmountCleanup(dir2.path());
+
+  // The child mount was copied along with its parent.
+  const struct stat s = ASSERT_NO_ERRNO_AND_VALUE(
+      Stat(JoinPath(dir2.path(), Basename(child.path()))));
+  EXPECT_EQ(s.st_mode, S_IFDIR | 0700);
+}
+
+TEST(MountAPITest, OpenTreeWithoutClone) {
+  auto const dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+
+  int fd;
+  ASSERT_THAT(fd = OpenTree(AT_FDCWD, dir.path().c_str(), 0),
+              SyscallSucceeds());
+  const FileDescriptor treefd(fd);
+
+  // The file descriptor is opened with O_PATH.
+  EXPECT_THAT(fcntl(treefd.get(), F_GETFL),
+              SyscallSucceedsWithValue(O_PATH | O_LARGEFILE));
+
+  // AT_RECURSIVE requires OPEN_TREE_CLONE.
+  EXPECT_THAT(OpenTree(AT_FDCWD, dir.path().c_str(), kAtRecursive),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+TEST(MountAPITest, MoveMountAttached) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  auto const parent = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  auto const parent_mount = ASSERT_NO_ERRNO_AND_VALUE(
+      Mount("", parent.path(), kTmpfs, 0, "mode=0777", 0));
+  ASSERT_THAT(mount("", parent.path().c_str(), "", MS_PRIVATE, ""),
+              SyscallSucceeds());
+  auto const dir1 =
+      ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDirIn(parent.path()));
+  auto const dir2 =
+      ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDirIn(parent.path()));
+  ASSERT_THAT(mount("", dir1.path().c_str(), kTmpfs, 0, "mode=0700"),
+              SyscallSucceeds());
+
+  ASSERT_THAT(
+      MoveMount(AT_FDCWD, dir1.path().c_str(), AT_FDCWD, dir2.path().c_str(), 0),
+      SyscallSucceeds());
+  auto const cleanup = UmountCleanup(dir2.path());
+
+  EXPECT_EQ(ASSERT_NO_ERRNO_AND_VALUE(Stat(dir2.path())).st_mode,
+            S_IFDIR | 0700);
+  EXPECT_NE(ASSERT_NO_ERRNO_AND_VALUE(Stat(dir1.path())).st_mode,
+            S_IFDIR | 0700);
+}
+
+TEST(MountAPITest, MoveMountBeneathItself) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  auto const parent = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  auto const parent_mount = ASSERT_NO_ERRNO_AND_VALUE(
+      Mount("", parent.path(), kTmpfs, 0, "mode=0777", 0));
+  ASSERT_THAT(mount("", parent.path().c_str(), "", MS_PRIVATE, ""),
+              SyscallSucceeds());
+  auto const dir =
+      ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDirIn(parent.path()));
+  auto const mount = ASSERT_NO_ERRNO_AND_VALUE(
+      Mount("", dir.path(), kTmpfs, 0, "mode=0777", 0));
+  auto const child = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDirIn(dir.path()));
+
+  EXPECT_THAT(
+      MoveMount(AT_FDCWD, dir.path().c_str(), AT_FDCWD, child.path().c_str(), 0),
+      SyscallFailsWithErrno(ELOOP));
+}
+
+TEST(MountAPITest, MoveMountNotMountRoot) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  auto const dir1 = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  auto const dir2 = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  EXPECT_THAT(MoveMount(AT_FDCWD, dir1.path().c_str(), AT_FDCWD,
+                        dir2.path().c_str(), 0),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+TEST(MountAPITest, MountSetattr) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  auto const dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  auto const mount = ASSERT_NO_ERRNO_AND_VALUE(
+      Mount("", dir.path(), kTmpfs, 0, "mode=0777", 0));
+
+  MountAttr attr = {};
+  attr.attr_set = kMountAttrRdonly | kMountAttrNoexec;
+  ASSERT_THAT(
+      MountSetattr(AT_FDCWD, dir.path().c_str(), 0, &attr, sizeof(attr)),
+      SyscallSucceeds());
+  EXPECT_THAT(access(dir.path().c_str(), W_OK), SyscallFailsWithErrno(EROFS));
+
+  attr = {};
+  attr.attr_clr = kMountAttrRdonly;
+  ASSERT_THAT(
+      MountSetattr(AT_FDCWD, dir.path().c_str(), 0, &attr, sizeof(attr)),
+      SyscallSucceeds());
+  EXPECT_THAT(access(dir.path().c_str(), W_OK), SyscallSucceeds());
+
+  // The access time attributes must be cleared before they are set.
+  attr = {};
+  attr.attr_set = kMountAttrNoatime;
+  EXPECT_THAT(
+      MountSetattr(AT_FDCWD, dir.path().c_str(), 0, &attr, sizeof(attr)),
+      SyscallFailsWithErrno(EINVAL));
+  attr.attr_clr = kMountAttrAtime;
+  EXPECT_THAT(
+      MountSetattr(AT_FDCWD, dir.path().c_str(), 0, &attr, sizeof(attr)),
+      SyscallSucceeds());
+}
+
+TEST(MountAPITest, MountSetattrDetached) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  auto const dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  const FileDescriptor mntfd =
+      ASSERT_NO_ERRNO_AND_VALUE(CreateTmpfs("0777", 0));
+
+  MountAttr attr = {};
+  attr.attr_set = kMountAttrRdonly;
+  ASSERT_THAT(MountSetattr(mntfd.get(), "", AT_EMPTY_PATH, &attr, sizeof(attr)),
+              SyscallSucceeds());
+  ASSERT_THAT(MoveMount(mntfd.get(), "", AT_FDCWD, dir.path().c_str(),
+                        kMoveMountFEmptyPath),
+              SyscallSucceeds());
+  auto const cleanup = UmountCleanup(dir.path());
+
+  EXPECT_THAT(access(dir.path().c_str(), W_OK), SyscallFailsWithErrno(EROFS));
+}
+
+TEST(MountAPITest, MountSetattrInvalidSize) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  auto const dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  auto const mount = ASSERT_NO_ERRNO_AND_VALUE(
+      Mount("", dir.path(), kTmpfs, 0, "mode=0777", 0));
+
+  struct {
+    MountAttr attr;
+    uint64_t extra;
+  } big = {};
+  big.attr.attr_set = kMountAttrRdonly;
+  EXPECT_THAT(MountSetattr(AT_FDCWD, dir.path().c_str(), 0, &big.attr,
+                           sizeof(MountAttr) - 1),
+              SyscallFailsWithErrno(EINVAL));
+
+  // Unknown trailing fields must be zero.
+  big.extra = 1;
+  EXPECT_THAT(
+      MountSetattr(AT_FDCWD, dir.path().c_str(), 0, &big.attr, sizeof(big)),
+      SyscallFailsWithErrno(E2BIG));
+  big.extra = 0;
+  EXPECT_THAT(
+      MountSetattr(AT_FDCWD, dir.path().c_str(), 0, &big.attr, sizeof(big)),
+      SyscallSucceeds());
+}
+
+}  // namespace
+
+}  // namespace testing
+}  // namespace gvisor
@@ -15,17 +15,28 @@
 #include <fcntl.h>
 #include <mqueue.h>
 #include <sched.h>
+#include <signal.h>
 #include <sys/poll.h>
 #include <sys/stat.h>
+#include <sys/syscall.h>
+#include <sys/wait.h>
+#include <time.h>
 #include <unistd.h>
 
 #include <string>
+#include <vector>
+
+#include "absl/strings/str_format.h"
+#include "absl/time/clock.h"
+#include "absl/time/time.h"
 
 #include "test/util/capability_util.h"
 #include "test/util/cleanup.h"
 #include "test/util/fs_util.h"
 #include "test/util/mount_util.h"
+#include "test/util/logging.h"
 #include "test/util/posix_error.h"
+#include "test/util/signal_util.h"
 #include "test/util/temp_path.h"
 #include "test/util/test_util.h"
 
@@ -365,6 +376,238 @@ TEST(MqTest, PollEmpty) {
   ASSERT_EQ(pfd.revents, POLLOUT | POLLWRNORM);
 }
 
+// Test sending and receiving a message.
+TEST(MqTest, SendReceive) {
+  PosixQueue queue = ASSERT_NO_ERRNO_AND_VALUE(
+      MqOpen(O_RDWR | O_CREAT | O_EXCL, 0777, nullptr));
+
+  struct mq_attr attr;
+  ASSERT_THAT(mq_getattr(queue.fd(), &attr), SyscallSucceeds());
+
+  std::string sent = "hello";
+  ASSERT_THAT(mq_send(queue.fd(), sent.data(), sent.size(), 7),
+              SyscallSucceeds());
+
+  std::vector<char> buf(attr.mq_msgsize);
+  unsigned int prio = 0;
+  ASSERT_THAT(mq_receive(queue.fd(), buf.data(), buf.size(), &prio),
+              SyscallSucceedsWithValue(sent.size()));
+  EXPECT_EQ(std::string(buf.data(), sent.size()), sent);
+  EXPECT_EQ(prio, 7);
+}
+
+// Test that messages are received in priority order, and in FIFO order among
+// messages with equal priority.
+TEST(MqTest, PriorityOrder) {
+  PosixQueue queue = ASSERT_NO_ERRNO_AND_VALUE(
+      MqOpen(O_RDWR | O_CREAT | O_EXCL, 0777, nullptr));
+
+  struct mq_attr attr;
+  ASSERT_THAT(mq_getattr(queue.fd(), &attr), SyscallSucceeds());
+
+  ASSERT_THAT(mq_send(queue.fd(), "a", 1, 1), SyscallSucceeds());
+  ASSERT_THAT(mq_send(queue.fd(), "b", 1, 5), SyscallSucceeds());
+  ASSERT_THAT(mq_send(queue.fd(), "c", 1, 1), SyscallSucceeds());
+  ASSERT_THAT(mq_send(queue.fd(), "d", 1, 3), SyscallSucceeds());
+
+  std::vector<char> buf(attr.mq_msgsize);
+  std::string got;
+  for (int i = 0; i < 4; i++) {
+    ASSERT_THAT(mq_receive(queue.fd(), buf.data(), buf.size(), nullptr),
+                SyscallSucceedsWithValue(1));
+    got += buf[0];
+  }
+  EXPECT_EQ(got, "bdac");
+}
+
+// Test invalid arguments to mq_send and mq_receive.
+TEST(MqTest, SendReceiveInvalidArgs) {
+  struct mq_attr attr = {};
+  attr.mq_maxmsg = 1;
+  attr.mq_msgsize = 16;
+  PosixQueue queue = ASSERT_NO_ERRNO_AND_VALUE(
+      MqOpen(O_RDWR | O_CREAT | O_EXCL, 0777, &attr));
+
+  char buf[32] = {};
+  EXPECT_THAT(mq_send(queue.fd(), buf, sizeof(buf), 0),
+              SyscallFailsWithErrno(EMSGSIZE));
+  EXPECT_THAT(mq_send(queue.fd(), buf, 1, MQ_PRIO_MAX),
+              SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(mq_receive(queue.fd(), buf, 8, nullptr),
+              SyscallFailsWithErrno(EMSGSIZE));
+}
+
+// Test sending on a read-only queue and receiving on a write-only queue.
+TEST(MqTest, SendReceiveWrongAccess) {
+  PosixQueue queue = ASSERT_NO_ERRNO_AND_VALUE(
+      MqOpen(O_RDONLY | O_CREAT | O_EXCL, 0777, nullptr));
+  mqd_t wfd = mq_open(queue.name(), O_WRONLY);
+  ASSERT_THAT(wfd, SyscallSucceeds());
+  auto cleanup =
+      Cleanup([wfd] { EXPECT_THAT(mq_close(wfd), SyscallSucceeds()); });
+
+  char buf[8192] = {};
+  EXPECT_THAT(mq_send(queue.fd(), buf, 1, 0), SyscallFailsWithErrno(EBADF));
+  EXPECT_THAT(mq_receive(wfd, buf, sizeof(buf), nullptr),
+              SyscallFailsWithErrno(EBADF));
+}
+
+// Test non-blocking operations on empty and full queues.
+TEST(MqTest, NonBlocking) {
+  struct mq_attr attr = {};
+  attr.mq_maxmsg = 1;
+  attr.mq_msgsize = 16;
+  PosixQueue queue = ASSERT_NO_ERRNO_AND_VALUE(
+      MqOpen(O_RDWR | O_CREAT | O_EXCL | O_NONBLOCK, 0777, &attr));
+
+  char buf[16] = {};
+  EXPECT_THAT(mq_receive(queue.fd(), buf, sizeof(buf), nullptr),
+              SyscallFailsWithErrno(EAGAIN));
+  ASSERT_THAT(mq_send(queue.fd(), buf, 1, 0), SyscallSucceeds());
+  EXPECT_THAT(mq_send(queue.fd(), buf, 1, 0), SyscallFailsWithErrno(EAGAIN));
+}
+
+// Test that blocking operations time out.
+TEST(MqTest, TimedReceiveTimeout) {
+  PosixQueue queue = ASSERT_NO_ERRNO_AND_VALUE(
+      MqOpen(O_RDWR | O_CREAT | O_EXCL, 0777, nullptr));
+
+  struct mq_attr attr;
+  ASSERT_THAT(mq_getattr(queue.fd(), &attr), SyscallSucceeds());
+  std::vector<char> buf(attr.mq_msgsize);
+
+  struct timespec ts;
+  ASSERT_THAT(clock_gettime(CLOCK_REALTIME, &ts), SyscallSucceeds());
+  ts.tv_nsec += 10 * 1000 * 1000;
+  if (ts.tv_nsec >= 1000 * 1000 * 1000) {
+    ts.tv_sec++;
+    ts.tv_nsec -= 1000 * 1000 * 1000;
+  }
+  EXPECT_THAT(mq_timedreceive(queue.fd(), buf.data(), buf.size(), nullptr, &ts),
+              SyscallFailsWithErrno(ETIMEDOUT));
+
+  ts.tv_nsec = -1;
+  EXPECT_THAT(mq_timedreceive(queue.fd(), buf.data(), buf.size(), nullptr, &ts),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+// Test that a blocked receiver is woken by a sender.
+TEST(MqTest, BlockingReceive) {
+  PosixQueue queue = ASSERT_NO_ERRNO_AND_VALUE(
+      MqOpen(O_RDWR | O_CREAT | O_EXCL, 0777, nullptr));
+
+  pid_t child = fork();
+  if (child == 0) {
+    absl::SleepFor(absl::Milliseconds(100));
+    TEST_PCHECK(mq_send(queue.fd(), "x", 1, 0) == 0);
+    _exit(0);
+  }
+  ASSERT_THAT(child, SyscallSucceeds());
+
+  struct mq_attr attr;
+  ASSERT_THAT(mq_getattr(queue.fd(), &attr), SyscallSucceeds());
+  std::vector<char> buf(attr.mq_msgsize);
+  EXPECT_THAT(mq_receive(queue.fd(), buf.data(), buf.size(), nullptr),
+              SyscallSucceedsWithValue(1));
+
+  int status;
+  ASSERT_THAT(waitpid(child, &status, 0), SyscallSucceedsWithValue(child));
+  EXPECT_TRUE(WIFEXITED(status) && WEXITSTATUS(status) == 0);
+}
+
+// Test getting and setting attributes.
+TEST(MqTest, GetSetAttr) {
+  struct mq_attr attr = {};
+  attr.mq_maxmsg = 4;
+  attr.mq_msgsize = 64;
+  PosixQueue queue = ASSERT_NO_ERRNO_AND_VALUE(
+      MqOpen(O_RDWR | O_CREAT | O_EXCL, 0777, &attr));
+  ASSERT_THAT(mq_send(queue.fd(), "x", 1, 0), SyscallSucceeds());
+
+  struct mq_attr got;
+  ASSERT_THAT(mq_getattr(queue.fd(), &got), SyscallSucceeds());
+  EXPECT_EQ(got.mq_flags, 0);
+  EXPECT_EQ(got.mq_maxmsg, 4);
+  EXPECT_EQ(got.mq_msgsize, 64);
+  EXPECT_EQ(got.mq_curmsgs, 1);
+
+  struct mq_attr set = {};
+  set.mq_flags = O_NONBLOCK;
+  struct mq_attr old;
+  ASSERT_THAT(mq_setattr(queue.fd(), &set, &old), SyscallSucceeds());
+  EXPECT_EQ(old.mq_flags, 0);
+  ASSERT_THAT(mq_getattr(queue.fd(), &got), SyscallSucceeds());
+  EXPECT_EQ(got.mq_flags, O_NONBLOCK);
+
+  char buf[64];
+  ASSERT_THAT(mq_receive(queue.fd(), buf, sizeof(buf), nullptr),
+              SyscallSucceeds());
+  EXPECT_THAT(mq_receive(queue.fd(), buf, sizeof(buf), nullptr),
+              SyscallFailsWithErrno(EAGAIN));
+
+  // Only O_NONBLOCK may be set.
+  set.mq_flags = O_APPEND;
+  EXPECT_THAT(syscall(SYS_mq_getsetattr, queue.fd(), &set, nullptr),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+// Test signal notification when a message arrives on an empty queue.
+TEST(MqTest, NotifySignal) {
+  PosixQueue queue = ASSERT_NO_ERRNO_AND_VALUE(
+      MqOpen(O_RDWR | O_CREAT | O_EXCL, 0777, nullptr));
+
+  sigset_t mask;
+  sigemptyset(&mask);
+  sigaddset(&mask, SIGUSR1);
+  auto cleanup = ASSERT_NO_ERRNO_AND_VALUE(ScopedSignalMask(SIG_BLOCK, mask));
+
+  struct sigevent sev = {};
+  sev.sigev_notify = SIGEV_SIGNAL;
+  sev.sigev_signo = SIGUSR1;
+  sev.sigev_value.sival_int = 42;
+  ASSERT_THAT(mq_notify(queue.fd(), &sev), SyscallSucceeds());
+
+  // Only one process may be registered at a time.
+  EXPECT_THAT(mq_notify(queue.fd(), &sev), SyscallFailsWithErrno(EBUSY));
+
+  ASSERT_THAT(mq_send(queue.fd(), "x", 1, 0), SyscallSucceeds());
+
+  struct timespec timeout = {.tv_sec = 5};
+  siginfo_t info;
+  ASSERT_THAT(sigtimedwait(&mask, &info, &timeout),
+              SyscallSucceedsWithValue(SIGUSR1));
+  EXPECT_EQ(info.si_code, SI_MESGQ);
+  EXPECT_EQ(info.si_value.sival_int, 42);
+  EXPECT_EQ(info.si_pid, getpid());
+
+  // The registration is removed after notification.
+  ASSERT_THAT(mq_notify(queue.fd(), &sev), SyscallSucceeds());
+  ASSERT_THAT(mq_notify(queue.fd(), nullptr), SyscallSucceeds());
+}
+
+// Test that the notification state is visible through the queue file.
+TEST(MqTest, NotifyRead) {
+  PosixQueue queue = ASSERT_NO_ERRNO_AND_VALUE(
+      MqOpen(O_RDWR | O_CREAT | O_EXCL, 0777, nullptr));
+
+  struct sigevent sev = {};
+  sev.sigev_notify = SIGEV_SIGNAL;
+  sev.sigev_signo = SIGUSR2;
+  ASSERT_THAT(mq_notify(queue.fd(), &sev), SyscallSucceeds());
+
+  const size_t msgSize = 60;
+  char queueRead[msgSize];
+  queueRead[msgSize - 1] = '\0';
+  ASSERT_THAT(read(queue.fd(), &queueRead[0], msgSize - 1), SyscallSucceeds());
+
+  std::string want = absl::StrFormat(
+      "QSIZE:0          NOTIFY:0     SIGNO:%-5d NOTIFY_PID:%-6d", SIGUSR2,
+      getpid());
+  EXPECT_EQ(std::string(queueRead), want);
+
+  ASSERT_THAT(mq_notify(queue.fd(), nullptr), SyscallSucceeds());
+}
+
 }  // namespace
 }  // namespace testing
 }  // namespace gvisor
@@ -0,0 +1,255 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include <linux/errqueue.h>
+#include <netinet/in.h>
+#include <poll.h>
+#include <string.h>
+#include <sys/socket.h>
+#include <sys/uio.h>
+#include <unistd.h>
+
+#include <cerrno>
+#include <cstdint>
+#include <utility>
+
+#include "gmock/gmock.h"
+#include "gtest/gtest.h"
+#include "test/util/file_descriptor.h"
+#include "test/util/posix_error.h"
+#include "test/util/socket_util.h"
+#include "test/util/test_util.h"
+
+#ifndef SO_ZEROCOPY
+#define SO_ZEROCOPY 60
+#endif
+
+#ifndef MSG_ZEROCOPY
+#define MSG_ZEROCOPY 0x4000000
+#endif
+
+#ifndef SO_EE_ORIGIN_ZEROCOPY
+#define SO_EE_ORIGIN_ZEROCOPY 5
+#endif
+
+#ifndef SO_EE_CODE_ZEROCOPY_COPIED
+#define SO_EE_CODE_ZEROCOPY_COPIED 1
+#endif
+
+namespace gvisor {
+namespace testing {
+
+namespace {
+
+constexpr char kData[] = "zero copy data";
+
+// SocketPair is a pair of connected sockets.
+struct SocketPair {
+  FileDescriptor receiver;
+  FileDescriptor sender;
+};
+
+sockaddr_in LoopbackAddr() {
+  sockaddr_in addr = {};
+  addr.sin_family = AF_INET;
+  addr.sin_addr.s_addr = htonl(INADDR_LOOPBACK);
+  return addr;
+}
+
+PosixErrorOr<SocketPair> NewTCPPair() {
+  ASSIGN_OR_RETURN_ERRNO(FileDescriptor listener,
+                         Socket(AF_INET, SOCK_STREAM, 0));
+  sockaddr_in addr = LoopbackAddr();
+  RETURN_ERROR_IF_SYSCALL_FAIL(
+      bind(listener.get(), reinterpret_cast<sockaddr*>(&addr), sizeof(addr)));
+  RETURN_ERROR_IF_SYSCALL_FAIL(listen(listener.get(), 1));
+  socklen_t addrlen = sizeof(addr);
+  RETURN_ERROR_IF_SYSCALL_FAIL(getsockname(
+      listener.get(), reinterpret_cast<sockaddr*>(&addr), &addrlen));
+
+  ASSIGN_OR_RETURN_ERRNO(FileDescriptor sender,
+                         Socket(AF_INET, SOCK_STREAM, 0));
+  RETURN_ERROR_IF_SYSCALL_FAIL(
+      connect(sender.get(), reinterpret_cast<sockaddr*>(&addr), sizeof(addr)));
+  ASSIGN_OR_RETURN_ERRNO(FileDescriptor receiver,
+                         Accept(listener.get(), nullptr, nullptr));
+  return SocketPair{std::move(receiver), std::move(sender)};
+}
+
+PosixErrorOr<SocketPair> NewUDPPair() {
+  ASSIGN_OR_RETURN_ERRNO(FileDescriptor receiver,
+                         Socket(AF_INET, SOCK_DGRAM, 0));
+  sockaddr_in addr = LoopbackAddr();
+  RETURN_ERROR_IF_SYSCALL_FAIL(
+      bind(receiver.get(), reinterpret_cast<sockaddr*>(&addr), sizeof(addr)));
+  socklen_t addrlen = sizeof(addr);
+  RETURN_ERROR_IF_SYSCALL_FAIL(getsockname(
+      receiver.get(), reinterpret_cast<sockaddr*>(&addr), &addrlen));
+
+  ASSIGN_OR_RETURN_ERRNO(FileDescriptor sender,
+                         Socket(AF_INET, SOCK_DGRAM, 0));
+  RETURN_ERROR_IF_SYSCALL_FAIL(
+      connect(sender.get(), reinterpret_cast<sockaddr*>(&addr), sizeof(addr)));
+  return SocketPair{std::move(receiver), std::move(sender)};
+}
+
+PosixError EnableZeroCopy(int fd) {
+  constexpr int kOne = 1;
+  RETURN_ERROR_IF_SYSCALL_FAIL(
+      setsockopt(fd, SOL_SOCKET, SO_ZEROCOPY, &kOne, sizeof(kOne)));
+  return NoError();
+}
+
+// RecvCompletion waits for and returns the next zero copy completion
+// notification queued on fd.
+PosixErrorOr<sock_extended_err> RecvCompletion(int fd) {
+  struct pollfd pfd = {};
+  pfd.fd = fd;
+  constexpr int kTimeoutMs = 10000;
+  const int n = RetryEINTR(poll)(&pfd, 1, kTimeoutMs);
+  if (n < 0) {
+    return PosixError(errno, "poll");
+  }
+  if (n != 1 || !(pfd.revents & POLLERR)) {
+    return PosixError(ETIMEDOUT, "no completion notification");
+  }
+
+  char cmsgbuf[CMSG_SPACE(sizeof(sock_extended_err) + sizeof(sockaddr_in))];
+  msghdr msg = {};
+  msg.msg_control = cmsgbuf;
+  msg.msg_controllen = sizeof(cmsgbuf);
+  RETURN_ERROR_IF_SYSCALL_FAIL(RetryEINTR(recvmsg)(fd, &msg, MSG_ERRQUEUE));
+
+  cmsghdr* cmsg = CMSG_FIRSTHDR(&msg);
+  if (cmsg == nullptr || cmsg->cmsg_level != SOL_IP ||
+      cmsg->cmsg_type != IP_RECVERR) {
+    return PosixError(EINVAL, "missing IP_RECVERR control message");
+  }
+  sock_extended_err ee;
+  memcpy(&ee, CMSG_DATA(cmsg), sizeof(ee));
+  return ee;
+}
+
+TEST(MsgZeroCopyTest, SetAndGet) {
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_STREAM, 0));
+
+  int v = -1;
+  socklen_t len = sizeof(v);
+  ASSERT_THAT(getsockopt(fd.get(), SOL_SOCKET, SO_ZEROCOPY, &v, &len),
+              SyscallSucceeds());
+  EXPECT_EQ(v, 0);
+
+  ASSERT_NO_ERRNO(EnableZeroCopy(fd.get()));
+  ASSERT_THAT(getsockopt(fd.get(), SOL_SOCKET, SO_ZEROCOPY, &v, &len),
+              SyscallSucceeds());
+  EXPECT_EQ(v, 1);
+
+  constexpr int kTwo = 2;
+  EXPECT_THAT(
+      setsockopt(fd.get(), SOL_SOCKET, SO_ZEROCOPY, &kTwo, sizeof(kTwo)),
+      SyscallFailsWithErrno(EINVAL));
+}
+
+TEST(MsgZeroCopyTest, UnixNotSupported) {
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_UNIX, SOCK_STREAM, 0));
+  constexpr int kOne = 1;
+  EXPECT_THAT(
+      setsockopt(fd.get(), SOL_SOCKET, SO_ZEROCOPY, &kOne, sizeof(kOne)),
+      SyscallFailsWithErrno(EOPNOTSUPP));
+}
+
+TEST(MsgZeroCopyTest, TCPCompletion) {
+  SocketPair pair = ASSERT_NO_ERRNO_AND_VALUE(NewTCPPair());
+  ASSERT_NO_ERRNO(EnableZeroCopy(pair.sender.get()));
+
+  ASSERT_THAT(send(pair.sender.get(), kData, sizeof(kData), MSG_ZEROCOPY),
+              SyscallSucceedsWithValue(sizeof(kData)));
+  char buf[sizeof(kData)];
+  ASSERT_THAT(
+      RetryEINTR(recv)(pair.receiver.get(), buf, sizeof(buf), MSG_WAITALL),
+      SyscallSucceedsWithValue(sizeof(kData)));
+  EXPECT_EQ(memcmp(buf, kData, sizeof(kData)), 0);
+
+  const sock_extended_err ee =
+      ASSERT_NO_ERRNO_AND_VALUE(RecvCompletion(pair.sender.get()));
+  EXPECT_EQ(ee.ee_errno, 0);
+  EXPECT_EQ(ee.ee_origin, SO_EE_ORIGIN_ZEROCOPY);
+  EXPECT_EQ(ee.ee_info, 0);
+  EXPECT_EQ(ee.ee_data, 0);
+  // Data sent over loopback is always copied.
+  EXPECT_EQ(ee.ee_code, SO_EE_CODE_ZEROCOPY_COPIED);
+}
+
+TEST(MsgZeroCopyTest, TCPCompletionsAreOrdered) {
+  SocketPair pair = ASSERT_NO_ERRNO_AND_VALUE(NewTCPPair());
+  ASSERT_NO_ERRNO(EnableZeroCopy(pair.sender.get()));
+
+  constexpr uint32_t kSends = 2;
+  for (uint32_t i = 0; i < kSends; i++) {
+    ASSERT_THAT(send(pair.sender.get(), kData, sizeof(kData), MSG_ZEROCOPY),
+                SyscallSucceedsWithValue(sizeof(kData)));
+  }
+
+  // Completions of consecutive sends may be merged.
+  uint32_t next = 0;
+  while (next < kSends) {
+    const sock_extended_err ee =
+        ASSERT_NO_ERRNO_AND_VALUE(RecvCompletion(pair.sender.get()));
+    EXPECT_EQ(ee.ee_origin, SO_EE_ORIGIN_ZEROCOPY);
+    ASSERT_EQ(ee.ee_info, next);
+    ASSERT_GE(ee.ee_data, ee.ee_info);
+    next = ee.ee_data + 1;
+  }
+  EXPECT_EQ(next, kSends);
+}
+
+TEST(MsgZeroCopyTest, UDPCompletion) {
+  SocketPair pair = ASSERT_NO_ERRNO_AND_VALUE(NewUDPPair());
+  ASSERT_NO_ERRNO(EnableZeroCopy(pair.sender.get()));
+
+  ASSERT_THAT(send(pair.sender.get(), kData, sizeof(kData), MSG_ZEROCOPY),
+              SyscallSucceedsWithValue(sizeof(kData)));
+  char buf[sizeof(kData)];
+  ASSERT_THAT(RetryEINTR(recv)(pair.receiver.get(), buf, sizeof(buf), 0),
+              SyscallSucceedsWithValue(sizeof(kData)));
+  EXPECT_EQ(memcmp(buf, kData, sizeof(kData)), 0);
+
+  const sock_extended_err ee =
+      ASSERT_NO_ERRNO_AND_VALUE(RecvCompletion(pair.sender.get()));
+  EXPECT_EQ(ee.ee_errno, 0);
+  EXPECT_EQ(ee.ee_origin, SO_EE_ORIGIN_ZEROCOPY);
+  EXPECT_EQ(ee.ee_info, 0);
+  EXPECT_EQ(ee.ee_data, 0);
+}
+
+TEST(MsgZeroCopyTest, IgnoredWithoutSockOpt) {
+  SocketPair pair = ASSERT_NO_ERRNO_AND_VALUE(NewUDPPair());
+
+  ASSERT_THAT(send(pair.sender.get(), kData, sizeof(kData), MSG_ZEROCOPY),
+              SyscallSucceedsWithValue(sizeof(kData)));
+  char buf[sizeof(kData)];
+  ASSERT_THAT(RetryEINTR(recv)(pair.receiver.get(), buf, sizeof(buf), 0),
+              SyscallSucceedsWithValue(sizeof(kData)));
+
+  msghdr msg = {};
+  EXPECT_THAT(recvmsg(pair.sender.get(), &msg, MSG_ERRQUEUE | MSG_DONTWAIT),
+              SyscallFailsWithErrno(EAGAIN));
+}
+
+}  // namespace
+
+}  // namespace testing
+}  // namespace gvisor
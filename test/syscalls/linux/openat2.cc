@@ -0,0 +1,247 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include <fcntl.h>
+#include <sys/syscall.h>
+#include <unistd.h>
+
+#include <cerrno>
+#include <cstdint>
+#include <string>
+
+#include "gtest/gtest.h"
+#include "absl/strings/str_cat.h"
+#include "test/util/file_descriptor.h"
+#include "test/util/posix_error.h"
+#include "test/util/temp_path.h"
+#include "test/util/test_util.h"
+
+#ifndef SYS_openat2
+#if defined(__x86_64__) || defined(__aarch64__)
+#define SYS_openat2 437
+#else
+#error "Unknown architecture"
+#endif
+#endif  // SYS_openat2
+
+#ifndef RESOLVE_NO_XDEV
+#define RESOLVE_NO_XDEV 0x01
+#define RESOLVE_NO_MAGICLINKS 0x02
+#define RESOLVE_NO_SYMLINKS 0x04
+#define RESOLVE_BENEATH 0x08
+#define RESOLVE_IN_ROOT 0x10
+#define RESOLVE_CACHED 0x20
+#endif
+
+namespace gvisor {
+namespace testing {
+
+namespace {
+
+// OpenHow is struct open_how.
+struct OpenHow {
+  uint64_t flags;
+  uint64_t mode;
+  uint64_t resolve;
+};
+
+int openat2(int dirfd, const char* path, OpenHow* how, size_t size) {
+  return syscall(SYS_openat2, dirfd, path, how, size);
+}
+
+PosixErrorOr<FileDescriptor> Openat2(int dirfd, const std::string& path,
+                                     uint64_t flags, uint64_t resolve) {
+  OpenHow how = {};
+  how.flags = flags;
+  how.resolve = resolve;
+  int fd = openat2(dirfd, path.c_str(), &how, sizeof(how));
+  if (fd < 0) {
+    return PosixError(errno, absl::StrCat("openat2 ", path));
+  }
+  return FileDescriptor(fd);
+}
+
+bool Openat2Supported() {
+  OpenHow how = {};
+  return openat2(AT_FDCWD, "/", &how, sizeof(how)) >= 0 || errno != ENOSYS;
+}
+
+class Openat2Test : public ::testing::Test {
+ protected:
+  void SetUp() override {
+    SKIP_IF(!Openat2Supported());
+    dir_ = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+    subdir_ = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDirIn(dir_.path()));
+    file_ = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFileIn(subdir_.path()));
+    dirfd_ = ASSERT_NO_ERRNO_AND_VALUE(Open(dir_.path(), O_PATH));
+  }
+
+  // RelPath returns the path of path relative to dir_.
+  std::string RelPath(const TempPath& path) {
+    return path.path().substr(dir_.path().size() + 1);
+  }
+
+  TempPath dir_;
+  TempPath subdir_;
+  TempPath file_;
+  FileDescriptor dirfd_;
+};
+
+TEST_F(Openat2Test, Basic) {
+  ASSERT_NO_ERRNO(Openat2(dirfd_.get(), RelPath(file_), O_RDONLY, 0));
+}
+
+TEST_F(Openat2Test, InvalidSize) {
+  OpenHow how = {};
+  EXPECT_THAT(openat2(dirfd_.get(), ".", &how, sizeof(how) - 1),
+              SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(openat2(dirfd_.get(), ".", &how, 0),
+              SyscallFailsWithErrno(EINVAL));
+
+  // Larger structs are accepted as long as the extra bytes are zero.
+  struct {
+    OpenHow how;
+    uint64_t extra;
+  } big = {};
+  const int fd = openat2(dirfd_.get(), ".", &big.how, sizeof(big));
+  ASSERT_THAT(fd, SyscallSucceeds());
+  EXPECT_THAT(close(fd), SyscallSucceeds());
+  big.extra = 1;
+  EXPECT_THAT(openat2(dirfd_.get(), ".", &big.how, sizeof(big)),
+              SyscallFailsWithErrno(E2BIG));
+}
+
+TEST_F(Openat2Test, InvalidFlags) {
+  const std::string path = RelPath(file_);
+  // Unknown flags.
+  EXPECT_THAT(Openat2(dirfd_.get(), path, 1ULL << 40, 0),
+              PosixErrorIs(EINVAL));
+  EXPECT_THAT(Openat2(dirfd_.get(), path, O_RDONLY, 1ULL << 40),
+              PosixErrorIs(EINVAL));
+  // Scoping flags are mutually exclusive.
+  EXPECT_THAT(Openat2(dirfd_.get(), path, O_RDONLY,
+                      RESOLVE_BENEATH | RESOLVE_IN_ROOT),
+              PosixErrorIs(EINVAL));
+  // O_PATH only permits a few other flags.
+  EXPECT_THAT(Openat2(dirfd_.get(), path, O_PATH | O_RDWR, 0),
+              PosixErrorIs(EINVAL));
+}
+
+TEST_F(Openat2Test, InvalidMode) {
+  const std::string path = RelPath(file_);
+  OpenHow how = {};
+  how.flags = O_RDONLY;
+  how.mode = 0644;
+  EXPECT_THAT(openat2(dirfd_.get(), path.c_str(), &how, sizeof(how)),
+              SyscallFailsWithErrno(EINVAL));
+
+  how.flags = O_RDWR | O_CREAT;
+  how.mode = 010000;
+  EXPECT_THAT(openat2(dirfd_.get(), path.c_str(), &how, sizeof(how)),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+TEST_F(Openat2Test, Beneath) {
+  ASSERT_NO_ERRNO(Openat2(dirfd_.get(), RelPath(file_), O_RDONLY,
+                          RESOLVE_BENEATH));
+  ASSERT_NO_ERRNO(Openat2(dirfd_.get(),
+                          absl::StrCat(RelPath(subdir_), "/../",
+                                       RelPath(file_)),
+                          O_RDONLY, RESOLVE_BENEATH));
+
+  EXPECT_THAT(Openat2(dirfd_.get(), "..", O_PATH, RESOLVE_BENEATH),
+              PosixErrorIs(EXDEV));
+  EXPECT_THAT(Openat2(dirfd_.get(), file_.path(), O_RDONLY, RESOLVE_BENEATH),
+              PosixErrorIs(EXDEV));
+
+  const TempPath link = ASSERT_NO_ERRNO_AND_VALUE(
+      TempPath::CreateSymlinkTo(dir_.path(), file_.path()));
+  EXPECT_THAT(Openat2(dirfd_.get(), RelPath(link), O_RDONLY, RESOLVE_BENEATH),
+              PosixErrorIs(EXDEV));
+}
+
+TEST_F(Openat2Test, InRoot) {
+  // ".." at the root stays at the root.
+  ASSERT_NO_ERRNO(Openat2(dirfd_.get(), absl::StrCat("../../", RelPath(file_)),
+                          O_RDONLY, RESOLVE_IN_ROOT));
+
+  // Absolute paths are relative to dirfd.
+  ASSERT_NO_ERRNO(Openat2(dirfd_.get(), absl::StrCat("/", RelPath(file_)),
+                          O_RDONLY, RESOLVE_IN_ROOT));
+  EXPECT_THAT(
+      Openat2(dirfd_.get(), file_.path(), O_RDONLY, RESOLVE_IN_ROOT),
+      PosixErrorIs(ENOENT));
+
+  // So are absolute symlink targets.
+  const TempPath link = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateSymlinkTo(
+      dir_.path(), absl::StrCat("/", RelPath(file_))));
+  ASSERT_NO_ERRNO(
+      Openat2(dirfd_.get(), RelPath(link), O_RDONLY, RESOLVE_IN_ROOT));
+}
+
+TEST_F(Openat2Test, NoSymlinks) {
+  const TempPath link = ASSERT_NO_ERRNO_AND_VALUE(
+      TempPath::CreateSymlinkTo(dir_.path(), RelPath(file_)));
+  ASSERT_NO_ERRNO(Openat2(dirfd_.get(), RelPath(link), O_RDONLY, 0));
+  EXPECT_THAT(
+      Openat2(dirfd_.get(), RelPath(link), O_RDONLY, RESOLVE_NO_SYMLINKS),
+      PosixErrorIs(ELOOP));
+
+  // Symlinks that aren't followed are fine.
+  ASSERT_NO_ERRNO(Openat2(dirfd_.get(), RelPath(link), O_PATH | O_NOFOLLOW,
+                          RESOLVE_NO_SYMLINKS));
+}
+
+TEST_F(Openat2Test, NoMagicLinks) {
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(file_.path(), O_RDONLY));
+  const std::string magic = absl::StrCat("/proc/self/fd/", fd.get());
+  ASSERT_NO_ERRNO(Openat2(AT_FDCWD, magic, O_RDONLY, 0));
+  EXPECT_THAT(Openat2(AT_FDCWD, magic, O_RDONLY, RESOLVE_NO_MAGICLINKS),
+              PosixErrorIs(ELOOP));
+  EXPECT_THAT(Openat2(AT_FDCWD, magic, O_RDONLY, RESOLVE_NO_SYMLINKS),
+              PosixErrorIs(ELOOP));
+
+  // Magic links can't escape the starting point.
+  const FileDescriptor proc =
+      ASSERT_NO_ERRNO_AND_VALUE(Open("/proc", O_PATH));
+  EXPECT_THAT(Openat2(proc.get(), absl::StrCat("self/fd/", fd.get()),
+                      O_RDONLY, RESOLVE_BENEATH),
+              PosixErrorIs(EXDEV));
+}
+
+TEST_F(Openat2Test, NoXdev) {
+  const FileDescriptor root = ASSERT_NO_ERRNO_AND_VALUE(Open("/", O_PATH));
+  ASSERT_NO_ERRNO(Openat2(root.get(), "proc", O_PATH, 0));
+  EXPECT_THAT(Openat2(root.get(), "proc/self", O_PATH, RESOLVE_NO_XDEV),
+              PosixErrorIs(EXDEV));
+
+  const FileDescriptor proc =
+      ASSERT_NO_ERRNO_AND_VALUE(Open("/proc", O_PATH));
+  EXPECT_THAT(Openat2(proc.get(), "..", O_PATH, RESOLVE_NO_XDEV),
+              PosixErrorIs(EXDEV));
+}
+
+TEST_F(Openat2Test, CachedCreate) {
+  EXPECT_THAT(Openat2(dirfd_.get(), "new", O_RDWR | O_CREAT, RESOLVE_CACHED),
+              PosixErrorIs(EAGAIN));
+  EXPECT_THAT(Openat2(dirfd_.get(), RelPath(file_), O_RDWR | O_TRUNC,
+                      RESOLVE_CACHED),
+              PosixErrorIs(EAGAIN));
+}
+
+}  // namespace
+
+}  // namespace testing
+}  // namespace gvisor
@@ -600,18 +600,6 @@ TEST_P(RawPacketTest, GetSocketErrorBind) {
 }
 
 TEST_P(RawPacketTest, SetSocketDetachFilterNoInstalledFilter) {
-  // TODO(gvisor.dev/2746): Support SO_ATTACH_FILTER/SO_DETACH_FILTER.
-  //
-  // gVisor returns no error on SO_DETACH_FILTER even if there is no filter
-  // attached unlike linux which does return ENOENT in such cases. This is
-  // because gVisor doesn't support SO_ATTACH_FILTER and just silently returns
-  // success.
-  if (IsRunningOnGvisor()) {
-    constexpr int val = 0;
-    ASSERT_THAT(setsockopt(s_, SOL_SOCKET, SO_DETACH_FILTER, &val, sizeof(val)),
-                SyscallSucceeds());
-    return;
-  }
   constexpr int val = 0;
   ASSERT_THAT(setsockopt(s_, SOL_SOCKET, SO_DETACH_FILTER, &val, sizeof(val)),
               SyscallFailsWithErrno(ENOENT));
@@ -0,0 +1,384 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include <errno.h>
+#include <fcntl.h>
+#include <poll.h>
+#include <sched.h>
+#include <signal.h>
+#include <sys/syscall.h>
+#include <sys/types.h>
+#include <sys/wait.h>
+#include <unistd.h>
+
+#include <cstdint>
+
+#include "gtest/gtest.h"
+#include "test/util/file_descriptor.h"
+#include "test/util/posix_error.h"
+#include "test/util/test_util.h"
+#include "test/util/thread_util.h"
+
+namespace gvisor {
+namespace testing {
+
+namespace {
+
+#ifndef SYS_pidfd_send_signal
+#define SYS_pidfd_send_signal 424
+#endif  // SYS_pidfd_send_signal
+
+#ifndef SYS_pidfd_open
+#define SYS_pidfd_open 434
+#endif  // SYS_pidfd_open
+
+#ifndef SYS_clone3
+#define SYS_clone3 435
+#endif  // SYS_clone3
+
+#ifndef SYS_pidfd_getfd
+#define SYS_pidfd_getfd 438
+#endif  // SYS_pidfd_getfd
+
+#ifndef P_PIDFD
+#define P_PIDFD 3
+#endif  // P_PIDFD
+
+#ifndef PIDFD_NONBLOCK
+#define PIDFD_NONBLOCK O_NONBLOCK
+#endif  // PIDFD_NONBLOCK
+
+#ifndef CLONE_PIDFD
+#define CLONE_PIDFD 0x1000
+#endif  // CLONE_PIDFD
+
+// struct clone_args is a Linux clone struct. Old versions of glibc do not
+// expose it. See include/uapi/linux/sched.h
+struct clone_args {
+  uint64_t flags;
+  uint64_t pidfd;
+  uint64_t child_tid;
+  uint64_t parent_tid;
+  uint64_t exit_signal;
+  uint64_t stack;
+  uint64_t stack_size;
+  uint64_t tls;
+};
+
+int pidfd_open(pid_t pid, unsigned int flags) {
+  return syscall(SYS_pidfd_open, pid, flags);
+}
+
+int pidfd_send_signal(int pidfd, int sig, siginfo_t* info,
+                      unsigned int flags) {
+  return syscall(SYS_pidfd_send_signal, pidfd, sig, info, flags);
+}
+
+int pidfd_getfd(int pidfd, int targetfd, unsigned int flags) {
+  return syscall(SYS_pidfd_getfd, pidfd, targetfd, flags);
+}
+
+int clone3(struct clone_args* ca, size_t size) {
+  return syscall(SYS_clone3, ca, size);
+}
+
+PosixErrorOr<FileDescriptor> PidfdOpen(pid_t pid, unsigned int flags) {
+  int fd = pidfd_open(pid, flags);
+  if (fd < 0) {
+    return PosixError(errno, "pidfd_open");
+  }
+  return FileDescriptor(fd);
+}
+
+bool PidfdSupported() {
+  if (IsRunningOnGvisor()) {
+    return true;
+  }
+  int fd = pidfd_open(getpid(), 0);
+  if (fd < 0) {
+    return errno != ENOSYS;
+  }
+  close(fd);
+  return true;
+}
+
+// Forks a child that blocks until it is killed.
+PosixErrorOr<pid_t> ForkPausingChild() {
+  pid_t pid = fork();
+  if (pid < 0) {
+    return PosixError(errno, "fork");
+  }
+  if (pid == 0) {
+    while (true) {
+      pause();
+    }
+  }
+  return pid;
+}
+
+TEST(PidfdTest, OpenInvalidArguments) {
+  SKIP_IF(!PidfdSupported());
+
+  EXPECT_THAT(pidfd_open(getpid(), ~PIDFD_NONBLOCK),
+              SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(pidfd_open(0, 0), SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(pidfd_open(-1, 0), SyscallFailsWithErrno(EINVAL));
+}
+
+TEST(PidfdTest, OpenNonLeaderThread) {
+  SKIP_IF(!PidfdSupported());
+
+  pid_t tid = 0;
+  ScopedThread t([&] { tid = syscall(SYS_gettid); });
+  t.Join();
+  // Thread IDs aren't reused immediately, so pidfd_open fails with either
+  // EINVAL if the thread hasn't been reaped yet, or ESRCH.
+  int fd = pidfd_open(tid, 0);
+  EXPECT_EQ(fd, -1);
+  EXPECT_TRUE(errno == EINVAL || errno == ESRCH) << errno;
+}
+
+TEST(PidfdTest, CloseOnExec) {
+  SKIP_IF(!PidfdSupported());
+
+  FileDescriptor pidfd = ASSERT_NO_ERRNO_AND_VALUE(PidfdOpen(getpid(), 0));
+  EXPECT_THAT(fcntl(pidfd.get(), F_GETFD),
+              SyscallSucceedsWithValue(FD_CLOEXEC));
+  EXPECT_THAT(fcntl(pidfd.get(), F_GETFL),
+              SyscallSucceedsWithValue(O_RDWR));
+
+  FileDescriptor nonblock =
+      ASSERT_NO_ERRNO_AND_VALUE(PidfdOpen(getpid(), PIDFD_NONBLOCK));
+  EXPECT_THAT(fcntl(nonblock.get(), F_GETFL),
+              SyscallSucceedsWithValue(O_RDWR | O_NONBLOCK));
+}
+
+TEST(PidfdTest, PollExit) {
+  SKIP_IF(!PidfdSupported());
+
+  pid_t child = ASSERT_NO_ERRNO_AND_VALUE(ForkPausingChild());
+  FileDescriptor pidfd = ASSERT_NO_ERRNO_AND_VALUE(PidfdOpen(child, 0));
+
+  struct pollfd pfd = {.fd = pidfd.get(), .events = POLLIN};
+  EXPECT_THAT(poll(&pfd, 1, 0), SyscallSucceedsWithValue(0));
+
+  ASSERT_THAT(kill(child, SIGKILL), SyscallSucceeds());
+  ASSERT_THAT(poll(&pfd, 1, -1), SyscallSucceedsWithValue(1));
+  EXPECT_EQ(pfd.revents & POLLIN, POLLIN);
+
+  siginfo_t info = {};
+  ASSERT_THAT(waitid(static_cast<idtype_t>(P_PIDFD), pidfd.get(), &info,
+                     WEXITED),
+              SyscallSucceeds());
+  EXPECT_EQ(info.si_pid, child);
+  EXPECT_EQ(info.si_code, CLD_KILLED);
+  EXPECT_EQ(info.si_status, SIGKILL);
+}
+
+TEST(PidfdTest, SendSignal) {
+  SKIP_IF(!PidfdSupported());
+
+  pid_t child = ASSERT_NO_ERRNO_AND_VALUE(ForkPausingChild());
+  FileDescriptor pidfd = ASSERT_NO_ERRNO_AND_VALUE(PidfdOpen(child, 0));
+
+  // Signal 0 only checks that the process exists.
+  EXPECT_THAT(pidfd_send_signal(pidfd.get(), 0, nullptr, 0),
+              SyscallSucceeds());
+  ASSERT_THAT(pidfd_send_signal(pidfd.get(), SIGKILL, nullptr, 0),
+              SyscallSucceeds());
+
+  siginfo_t info = {};
+  ASSERT_THAT(waitid(static_cast<idtype_t>(P_PIDFD), pidfd.get(), &info,
+                     WEXITED),
+              SyscallSucceeds());
+  EXPECT_EQ(info.si_code, CLD_KILLED);
+  EXPECT_EQ(info.si_status, SIGKILL);
+
+  // The process has been reaped.
+  EXPECT_THAT(pidfd_send_signal(pidfd.get(), SIGKILL, nullptr, 0),
+              SyscallFailsWithErrno(ESRCH));
+}
+
+TEST(PidfdTest, SendSignalInvalidArguments) {
+  SKIP_IF(!PidfdSupported());
+
+  FileDescriptor pidfd = ASSERT_NO_ERRNO_AND_VALUE(PidfdOpen(getpid(), 0));
+  EXPECT_THAT(pidfd_send_signal(pidfd.get(), 0, nullptr, 1),
+              SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(pidfd_send_signal(pidfd.get(), -1, nullptr, 0),
+              SyscallFailsWithErrno(EINVAL));
+
+  // The signal number in the info must match.
+  siginfo_t info = {};
+  info.si_signo = SIGUSR1;
+  info.si_code = SI_QUEUE;
+  EXPECT_THAT(pidfd_send_signal(pidfd.get(), SIGUSR2, &info, 0),
+              SyscallFailsWithErrno(EINVAL));
+
+  int pipefds[2];
+  ASSERT_THAT(pipe(pipefds), SyscallSucceeds());
+  FileDescriptor rfd(pipefds[0]);
+  FileDescriptor wfd(pipefds[1]);
+  EXPECT_THAT(pidfd_send_signal(rfd.get(), 0, nullptr, 0),
+              SyscallFailsWithErrno(EBADF));
+}
+
+TEST(PidfdTest, SendSignalKernelCodeToOtherProcess) {
+  SKIP_IF(!PidfdSupported());
+
+  pid_t child = ASSERT_NO_ERRNO_AND_VALUE(ForkPausingChild());
+  FileDescriptor pidfd = ASSERT_NO_ERRNO_AND_VALUE(PidfdOpen(child, 0));
+
+  siginfo_t info = {};
+  info.si_signo = SIGKILL;
+  info.si_code = SI_KERNEL;
+  EXPECT_THAT(pidfd_send_signal(pidfd.get(), SIGKILL, &info, 0),
+              SyscallFailsWithErrno(EPERM));
+
+  ASSERT_THAT(kill(child, SIGKILL), SyscallSucceeds());
+  int status;
+  ASSERT_THAT(waitpid(child, &status, 0), SyscallSucceedsWithValue(child));
+}
+
+TEST(PidfdTest, WaitidNonblocking) {
+  SKIP_IF(!PidfdSupported());
+
+  pid_t child = ASSERT_NO_ERRNO_AND_VALUE(ForkPausingChild());
+  FileDescriptor pidfd =
+      ASSERT_NO_ERRNO_AND_VALUE(PidfdOpen(child, PIDFD_NONBLOCK));
+
+  siginfo_t info = {};
+  EXPECT_THAT(waitid(static_cast<idtype_t>(P_PIDFD), pidfd.get(), &info,
+                     WEXITED),
+              SyscallFailsWithErrno(EAGAIN));
+
+  ASSERT_THAT(kill(child, SIGKILL), SyscallSucceeds());
+  int status;
+  ASSERT_THAT(waitpid(child, &status, 0), SyscallSucceedsWithValue(child));
+}
+
+TEST(PidfdTest, WaitidNotAChild) {
+  SKIP_IF(!PidfdSupported());
+
+  FileDescriptor pidfd = ASSERT_NO_ERRNO_AND_VALUE(PidfdOpen(getpid(), 0));
+  siginfo_t info = {};
+  EXPECT_THAT(waitid(static_cast<idtype_t>(P_PIDFD), pidfd.get(), &info,
+                     WEXITED | WNOHANG),
+              SyscallFailsWithErrno(ECHILD));
+}
+
+TEST(PidfdTest, GetFd) {
+  SKIP_IF(!PidfdSupported());
+
+  int pipefds[2];
+  ASSERT_THAT(pipe(pipefds), SyscallSucceeds());
+  FileDescriptor rfd(pipefds[0]);
+  FileDescriptor wfd(pipefds[1]);
+
+  FileDescriptor pidfd = ASSERT_NO_ERRNO_AND_VALUE(PidfdOpen(getpid(), 0));
+  int fd;
+  ASSERT_THAT(fd = pidfd_getfd(pidfd.get(), wfd.get(), 0), SyscallSucceeds());
+  FileDescriptor dup(fd);
+  EXPECT_THAT(fcntl(dup.get(), F_GETFD), SyscallSucceedsWithValue(FD_CLOEXEC));
+
+  // The new file descriptor refers to the same file.
+  char c = 'x';
+  ASSERT_THAT(WriteFd(dup.get(), &c, 1), SyscallSucceedsWithValue(1));
+  c = 0;
+  ASSERT_THAT(ReadFd(rfd.get(), &c, 1), SyscallSucceedsWithValue(1));
+  EXPECT_EQ(c, 'x');
+
+  EXPECT_THAT(pidfd_getfd(pidfd.get(), wfd.get(), 1),
+              SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(pidfd_getfd(pidfd.get(), -1, 0), SyscallFailsWithErrno(EBADF));
+  EXPECT_THAT(pidfd_getfd(rfd.get(), wfd.get(), 0),
+              SyscallFailsWithErrno(EBADF));
+}
+
+TEST(PidfdTest, GetFdFromChild) {
+  SKIP_IF(!PidfdSupported());
+
+  int pipefds[2];
+  ASSERT_THAT(pipe(pipefds), SyscallSucceeds());
+  FileDescriptor rfd(pipefds[0]);
+  FileDescriptor wfd(pipefds[1]);
+
+  pid_t child = ASSERT_NO_ERRNO_AND_VALUE(ForkPausingChild());
+  FileDescriptor pidfd = ASSERT_NO_ERRNO_AND_VALUE(PidfdOpen(child, 0));
+
+  // The child's copy of the write end is still open after the parent's is
+  // closed.
+  int wfd_num = wfd.get();
+  wfd.reset();
+  int fd;
+  ASSERT_THAT(fd = pidfd_getfd(pidfd.get(), wfd_num, 0), SyscallSucceeds());
+  FileDescriptor dup(fd);
+  char c = 'x';
+  ASSERT_THAT(WriteFd(dup.get(), &c, 1), SyscallSucceedsWithValue(1));
+  ASSERT_THAT(ReadFd(rfd.get(), &c, 1), SyscallSucceedsWithValue(1));
+
+  ASSERT_THAT(kill(child, SIGKILL), SyscallSucceeds());
+  int status;
+  ASSERT_THAT(waitpid(child, &status, 0), SyscallSucceedsWithValue(child));
+}
+
+TEST(PidfdTest, Clone3Pidfd) {
+  SKIP_IF(!PidfdSupported());
+
+  int pidfd = -1;
+  clone_args ca = {};
+  ca.flags = CLONE_PIDFD;
+  ca.pidfd = reinterpret_cast<uint64_t>(&pidfd);
+  ca.exit_signal = SIGCHLD;
+
+  int child;
+  ASSERT_THAT(child = clone3(&ca, sizeof(ca)), SyscallSucceeds());
+  if (child == 0) {
+    _exit(42);
+  }
+  ASSERT_GE(pidfd, 0);
+  FileDescriptor fd(pidfd);
+  EXPECT_THAT(fcntl(fd.get(), F_GETFD), SyscallSucceedsWithValue(FD_CLOEXEC));
+
+  siginfo_t info = {};
+  ASSERT_THAT(
+      waitid(static_cast<idtype_t>(P_PIDFD), fd.get(), &info, WEXITED),
+      SyscallSucceeds());
+  EXPECT_EQ(info.si_pid, child);
+  EXPECT_EQ(info.si_code, CLD_EXITED);
+  EXPECT_EQ(info.si_status, 42);
+}
+
+TEST(PidfdTest, Clone3PidfdInvalidFlags) {
+  SKIP_IF(!PidfdSupported());
+
+  int pidfd = -1;
+  clone_args ca = {};
+  ca.flags = CLONE_PIDFD | CLONE_THREAD | CLONE_SIGHAND | CLONE_VM;
+  ca.pidfd = reinterpret_cast<uint64_t>(&pidfd);
+  EXPECT_THAT(clone3(&ca, sizeof(ca)), SyscallFailsWithErrno(EINVAL));
+
+  // The pidfd and the parent TID can't be stored at the same address.
+  ca = {};
+  ca.flags = CLONE_PIDFD | CLONE_PARENT_SETTID;
+  ca.pidfd = reinterpret_cast<uint64_t>(&pidfd);
+  ca.parent_tid = reinterpret_cast<uint64_t>(&pidfd);
+  ca.exit_signal = SIGCHLD;
+  EXPECT_THAT(clone3(&ca, sizeof(ca)), SyscallFailsWithErrno(EINVAL));
+}
+
+}  // namespace
+
+}  // namespace testing
+}  // namespace gvisor
@@ -15,14 +15,19 @@
 #include <arpa/inet.h>
 #include <errno.h>
 #include <netinet/in.h>
+#include <netinet/tcp.h>
 #include <poll.h>
 #include <sys/socket.h>
 #include <sys/syscall.h>
 #include <sys/types.h>
 
+#include <string>
+#include <utility>
 #include <vector>
 
+#include "gmock/gmock.h"
 #include "gtest/gtest.h"
+#include "absl/strings/ascii.h"
 #include "absl/strings/numbers.h"
 #include "absl/strings/str_cat.h"
 #include "absl/strings/str_split.h"
@@ -584,6 +589,138 @@ TEST(ProcSysNetIpv4Recovery, CanReadAndWrite) {
   EXPECT_EQ(strcmp(buf, "100\n"), 0);
 }
 
+constexpr const char kCongestionControl[] =
+    "/proc/sys/net/ipv4/tcp_congestion_control";
+constexpr const char kAvailableCongestionControl[] =
+    "/proc/sys/net/ipv4/tcp_available_congestion_control";
+
+TEST(ProcSysNetIpv4CongestionControl, DefaultIsAvailable) {
+  std::string cc = ASSERT_NO_ERRNO_AND_VALUE(GetContents(kCongestionControl));
+  std::string available =
+      ASSERT_NO_ERRNO_AND_VALUE(GetContents(kAvailableCongestionControl));
+  std::vector<std::string> names =
+      absl::StrSplit(absl::StripAsciiWhitespace(available), ' ');
+  EXPECT_THAT(names, ::testing::Contains(absl::StripAsciiWhitespace(cc)));
+}
+
+TEST(ProcSysNetIpv4CongestionControl, CanReadAndWrite) {
+  DisableSave ds;
+
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability((CAP_NET_ADMIN))) ||
+          IsRunningWithHostinet());
+
+  std::string orig =
+      ASSERT_NO_ERRNO_AND_VALUE(GetContents(kCongestionControl));
+  auto const fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(kCongestionControl, O_RDWR));
+
+  // Reno is always available.
+  constexpr char kReno[] = "reno\n";
+  EXPECT_THAT(PwriteFd(fd.get(), kReno, strlen(kReno), 0),
+              SyscallSucceedsWithValue(strlen(kReno)));
+  EXPECT_EQ(ASSERT_NO_ERRNO_AND_VALUE(GetContents(kCongestionControl)),
+            kReno);
+
+  // Unknown algorithms are rejected.
+  constexpr char kUnknown[] = "nonexistent";
+  EXPECT_THAT(PwriteFd(fd.get(), kUnknown, strlen(kUnknown), 0),
+              SyscallFailsWithErrno(ENOENT));
+  EXPECT_EQ(ASSERT_NO_ERRNO_AND_VALUE(GetContents(kCongestionControl)),
+            kReno);
+
+  EXPECT_THAT(PwriteFd(fd.get(), orig.data(), orig.size(), 0),
+              SyscallSucceedsWithValue(orig.size()));
+}
+
+constexpr const char kTcpEcn[] = "/proc/sys/net/ipv4/tcp_ecn";
+
+TEST(ProcSysNetIpv4TcpEcn, CanReadAndWrite) {
+  DisableSave ds;
+
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability((CAP_NET_ADMIN))) ||
+          IsRunningWithHostinet());
+
+  std::string orig = ASSERT_NO_ERRNO_AND_VALUE(GetContents(kTcpEcn));
+  auto const fd = ASSERT_NO_ERRNO_AND_VALUE(Open(kTcpEcn, O_RDWR));
+
+  constexpr char kEnabled[] = "1\n";
+  EXPECT_THAT(PwriteFd(fd.get(), kEnabled, strlen(kEnabled), 0),
+              SyscallSucceedsWithValue(strlen(kEnabled)));
+  EXPECT_EQ(ASSERT_NO_ERRNO_AND_VALUE(GetContents(kTcpEcn)), kEnabled);
+
+  // Only modes 0 to 2 are valid.
+  constexpr char kInvalid[] = "3";
+  EXPECT_THAT(PwriteFd(fd.get(), kInvalid, strlen(kInvalid), 0),
+              SyscallFailsWithErrno(EINVAL));
+  EXPECT_EQ(ASSERT_NO_ERRNO_AND_VALUE(GetContents(kTcpEcn)), kEnabled);
+
+  EXPECT_THAT(PwriteFd(fd.get(), orig.data(), orig.size(), 0),
+              SyscallSucceedsWithValue(orig.size()));
+}
+
+// ConnectedTcpInfoOptions returns the tcpi_options of both ends of a TCP
+// connection established over loopback.
+PosixErrorOr<std::pair<uint8_t, uint8_t>> ConnectedTcpInfoOptions() {
+  ASSIGN_OR_RETURN_ERRNO(FileDescriptor listener,
+                         Socket(AF_INET, SOCK_STREAM, IPPROTO_TCP));
+  sockaddr_in addr = {};
+  addr.sin_family = AF_INET;
+  addr.sin_addr.s_addr = htonl(INADDR_LOOPBACK);
+  RETURN_ERROR_IF_SYSCALL_FAIL(bind(
+      listener.get(), reinterpret_cast<sockaddr*>(&addr), sizeof(addr)));
+  RETURN_ERROR_IF_SYSCALL_FAIL(listen(listener.get(), 1));
+  socklen_t addrlen = sizeof(addr);
+  RETURN_ERROR_IF_SYSCALL_FAIL(getsockname(
+      listener.get(), reinterpret_cast<sockaddr*>(&addr), &addrlen));
+
+  ASSIGN_OR_RETURN_ERRNO(FileDescriptor client,
+                         Socket(AF_INET, SOCK_STREAM, IPPROTO_TCP));
+  RETURN_ERROR_IF_SYSCALL_FAIL(
+      connect(client.get(), reinterpret_cast<sockaddr*>(&addr), addrlen));
+  ASSIGN_OR_RETURN_ERRNO(FileDescriptor server,
+                         Accept(listener.get(), nullptr, nullptr));
+
+  struct tcp_info client_info = {};
+  socklen_t optlen = sizeof(client_info);
+  RETURN_ERROR_IF_SYSCALL_FAIL(getsockopt(client.get(), IPPROTO_TCP, TCP_INFO,
+                                          &client_info, &optlen));
+  struct tcp_info server_info = {};
+  optlen = sizeof(server_info);
+  RETURN_ERROR_IF_SYSCALL_FAIL(getsockopt(server.get(), IPPROTO_TCP, TCP_INFO,
+                                          &server_info, &optlen));
+  return std::make_pair(client_info.tcpi_options, server_info.tcpi_options);
+}
+
+TEST(ProcSysNetIpv4TcpEcn, Negotiation) {
+  DisableSave ds;
+
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability((CAP_NET_ADMIN))) ||
+          IsRunningWithHostinet());
+
+  std::string orig = ASSERT_NO_ERRNO_AND_VALUE(GetContents(kTcpEcn));
+  auto const fd = ASSERT_NO_ERRNO_AND_VALUE(Open(kTcpEcn, O_RDWR));
+
+  // ECN is negotiated when both ends support it.
+  constexpr char kEnabled[] = "1";
+  ASSERT_THAT(PwriteFd(fd.get(), kEnabled, strlen(kEnabled), 0),
+              SyscallSucceedsWithValue(strlen(kEnabled)));
+  auto options = ASSERT_NO_ERRNO_AND_VALUE(ConnectedTcpInfoOptions());
+  EXPECT_NE(options.first & TCPI_OPT_ECN, 0);
+  EXPECT_NE(options.second & TCPI_OPT_ECN, 0);
+
+  // Connections don't request ECN when it's only accepted on incoming
+  // connections.
+  constexpr char kServer[] = "2";
+  ASSERT_THAT(PwriteFd(fd.get(), kServer, strlen(kServer), 0),
+              SyscallSucceedsWithValue(strlen(kServer)));
+  options = ASSERT_NO_ERRNO_AND_VALUE(ConnectedTcpInfoOptions());
+  EXPECT_EQ(options.first & TCPI_OPT_ECN, 0);
+  EXPECT_EQ(options.second & TCPI_OPT_ECN, 0);
+
+  EXPECT_THAT(PwriteFd(fd.get(), orig.data(), orig.size(), 0),
+              SyscallSucceedsWithValue(orig.size()));
+}
+
 TEST(ProcSysNetIpv4IpForward, Exists) {
   auto fd = ASSERT_NO_ERRNO_AND_VALUE(Open(kIpForward, O_RDONLY));
 }
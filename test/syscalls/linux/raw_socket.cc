@@ -856,14 +856,6 @@ void RawSocketTest::ReceiveBufFrom(int sock, char* recv_buf,
 }
 
 TEST_P(RawSocketTest, SetSocketDetachFilterNoInstalledFilter) {
-  // TODO(gvisor.dev/2746): Support SO_ATTACH_FILTER/SO_DETACH_FILTER.
-  if (IsRunningOnGvisor()) {
-    constexpr int val = 0;
-    ASSERT_THAT(setsockopt(s_, SOL_SOCKET, SO_DETACH_FILTER, &val, sizeof(val)),
-                SyscallSucceeds());
-    return;
-  }
-
   constexpr int val = 0;
   ASSERT_THAT(setsockopt(s_, SOL_SOCKET, SO_DETACH_FILTER, &val, sizeof(val)),
               SyscallFailsWithErrno(ENOENT));
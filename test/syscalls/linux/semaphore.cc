@@ -12,14 +12,18 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
+#include <sched.h>
 #include <signal.h>
 #include <sys/ipc.h>
 #include <sys/sem.h>
+#include <sys/syscall.h>
 #include <sys/types.h>
+#include <unistd.h>
 
 #include <atomic>
 #include <cerrno>
 #include <ctime>
+#include <functional>
 #include <memory>
 #include <set>
 
@@ -551,6 +555,116 @@ TEST(SemaphoreTest, SemCtlGetPidFork) {
       << " status " << status;
 }
 
+// Runs fn in a child process and waits for it to exit successfully.
+void RunInChild(std::function<void()> fn) {
+  const pid_t child_pid = fork();
+  if (child_pid == 0) {
+    fn();
+    _exit(0);
+  }
+  ASSERT_THAT(child_pid, SyscallSucceeds());
+
+  int status;
+  ASSERT_THAT(RetryEINTR(waitpid)(child_pid, &status, 0),
+              SyscallSucceedsWithValue(child_pid));
+  EXPECT_TRUE(WIFEXITED(status) && WEXITSTATUS(status) == 0)
+      << " status " << status;
+}
+
+TEST(SemaphoreTest, SemUndoOnExit) {
+  AutoSem sem(semget(IPC_PRIVATE, 2, 0600 | IPC_CREAT));
+  ASSERT_THAT(sem.get(), SyscallSucceeds());
+  ASSERT_THAT(semctl(sem.get(), 1, SETVAL, 2), SyscallSucceeds());
+
+  RunInChild([&] {
+    struct sembuf bufs[2] = {{0, 3, SEM_UNDO}, {1, -1, SEM_UNDO}};
+    TEST_PCHECK(semop(sem.get(), bufs, ABSL_ARRAYSIZE(bufs)) == 0);
+    TEST_PCHECK(semctl(sem.get(), 0, GETVAL) == 3);
+    TEST_PCHECK(semctl(sem.get(), 1, GETVAL) == 1);
+  });
+
+  // The adjustments are undone when the child exits.
+  EXPECT_THAT(semctl(sem.get(), 0, GETVAL), SyscallSucceedsWithValue(0));
+  EXPECT_THAT(semctl(sem.get(), 1, GETVAL), SyscallSucceedsWithValue(2));
+}
+
+TEST(SemaphoreTest, SemUndoClampedOnExit) {
+  AutoSem sem(semget(IPC_PRIVATE, 1, 0600 | IPC_CREAT));
+  ASSERT_THAT(sem.get(), SyscallSucceeds());
+
+  RunInChild([&] {
+    struct sembuf buf = {0, 1, SEM_UNDO};
+    TEST_PCHECK(semop(sem.get(), &buf, 1) == 0);
+    buf = {0, -1, 0};
+    TEST_PCHECK(semop(sem.get(), &buf, 1) == 0);
+  });
+
+  // Undoing the increment would make the value negative.
+  EXPECT_THAT(semctl(sem.get(), 0, GETVAL), SyscallSucceedsWithValue(0));
+}
+
+TEST(SemaphoreTest, SemUndoClearedBySetVal) {
+  AutoSem sem(semget(IPC_PRIVATE, 1, 0600 | IPC_CREAT));
+  ASSERT_THAT(sem.get(), SyscallSucceeds());
+
+  RunInChild([&] {
+    struct sembuf buf = {0, 1, SEM_UNDO};
+    TEST_PCHECK(semop(sem.get(), &buf, 1) == 0);
+    TEST_PCHECK(semctl(sem.get(), 0, SETVAL, 5) == 0);
+  });
+
+  EXPECT_THAT(semctl(sem.get(), 0, GETVAL), SyscallSucceedsWithValue(5));
+}
+
+TEST(SemaphoreTest, SemUndoRange) {
+  AutoSem sem(semget(IPC_PRIVATE, 1, 0600 | IPC_CREAT));
+  ASSERT_THAT(sem.get(), SyscallSucceeds());
+
+  RunInChild([&] {
+    struct sembuf buf = {0, kSemVmx, SEM_UNDO};
+    TEST_PCHECK(semop(sem.get(), &buf, 1) == 0);
+    buf = {0, -kSemVmx, 0};
+    TEST_PCHECK(semop(sem.get(), &buf, 1) == 0);
+
+    // The adjustment would be below -(SEMAEM + 1).
+    buf = {0, 1, SEM_UNDO};
+    TEST_PCHECK(semop(sem.get(), &buf, 1) == 0);
+    buf = {0, -1, 0};
+    TEST_PCHECK(semop(sem.get(), &buf, 1) == 0);
+    buf = {0, 1, SEM_UNDO};
+    TEST_PCHECK(semop(sem.get(), &buf, 1) == -1 && errno == ERANGE);
+  });
+}
+
+TEST(SemaphoreTest, SemUndoSharedByThreads) {
+  AutoSem sem(semget(IPC_PRIVATE, 1, 0600 | IPC_CREAT));
+  ASSERT_THAT(sem.get(), SyscallSucceeds());
+
+  RunInChild([&] {
+    // Threads are created with CLONE_SYSVSEM, so the adjustment is only
+    // undone when the whole process exits.
+    ScopedThread([&] {
+      struct sembuf buf = {0, 1, SEM_UNDO};
+      TEST_PCHECK(semop(sem.get(), &buf, 1) == 0);
+    });
+    TEST_PCHECK(semctl(sem.get(), 0, GETVAL) == 1);
+  });
+
+  EXPECT_THAT(semctl(sem.get(), 0, GETVAL), SyscallSucceedsWithValue(0));
+}
+
+// A new IPC namespace can't share the adjustments of the old one.
+TEST(SemaphoreTest, CloneNewIPCWithSysVSem) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  const pid_t child =
+      syscall(SYS_clone, CLONE_NEWIPC | CLONE_SYSVSEM | SIGCHLD, 0, 0, 0, 0);
+  if (child == 0) {
+    _exit(0);
+  }
+  EXPECT_THAT(child, SyscallFailsWithErrno(EINVAL));
+}
+
 TEST(SemaphoreTest, SemIpcSet) {
   // Drop CAP_IPC_OWNER which allows us to bypass semaphore permissions.
   AutoCapability cap(CAP_IPC_OWNER, false);
@@ -0,0 +1,305 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include <linux/filter.h>
+#include <netinet/in.h>
+#include <poll.h>
+#include <string.h>
+#include <sys/socket.h>
+#include <unistd.h>
+
+#include <cstdint>
+#include <utility>
+#include <vector>
+
+#include "gmock/gmock.h"
+#include "gtest/gtest.h"
+#include "absl/base/macros.h"
+#include "test/util/file_descriptor.h"
+#include "test/util/posix_error.h"
+#include "test/util/socket_util.h"
+#include "test/util/test_util.h"
+
+#ifndef SO_LOCK_FILTER
+#define SO_LOCK_FILTER 44
+#endif
+
+#ifndef SO_ATTACH_BPF
+#define SO_ATTACH_BPF 50
+#endif
+
+namespace gvisor {
+namespace testing {
+
+namespace {
+
+constexpr char kData[] = "filtered datagram";
+
+// UDPPair is a pair of UDP sockets, where sender is connected to receiver.
+struct UDPPair {
+  FileDescriptor receiver;
+  FileDescriptor sender;
+};
+
+PosixErrorOr<UDPPair> NewUDPPair() {
+  ASSIGN_OR_RETURN_ERRNO(FileDescriptor receiver,
+                         Socket(AF_INET, SOCK_DGRAM, 0));
+  sockaddr_in addr = {};
+  addr.sin_family = AF_INET;
+  addr.sin_addr.s_addr = htonl(INADDR_LOOPBACK);
+  RETURN_ERROR_IF_SYSCALL_FAIL(
+      bind(receiver.get(), reinterpret_cast<sockaddr*>(&addr), sizeof(addr)));
+  socklen_t addrlen = sizeof(addr);
+  RETURN_ERROR_IF_SYSCALL_FAIL(getsockname(
+      receiver.get(), reinterpret_cast<sockaddr*>(&addr), &addrlen));
+
+  ASSIGN_OR_RETURN_ERRNO(FileDescriptor sender, Socket(AF_INET, SOCK_DGRAM, 0));
+  RETURN_ERROR_IF_SYSCALL_FAIL(
+      connect(sender.get(), reinterpret_cast<sockaddr*>(&addr), sizeof(addr)));
+  return UDPPair{std::move(receiver), std::move(sender)};
+}
+
+// Attach attaches the program made of insns to the socket fd.
+PosixError Attach(int fd, std::vector<sock_filter> insns) {
+  sock_fprog prog = {};
+  prog.len = insns.size();
+  prog.filter = insns.data();
+  RETURN_ERROR_IF_SYSCALL_FAIL(
+      setsockopt(fd, SOL_SOCKET, SO_ATTACH_FILTER, &prog, sizeof(prog)));
+  return NoError();
+}
+
+// Return returns a program returning k.
+std::vector<sock_filter> Return(uint32_t k) {
+  return {BPF_STMT(BPF_RET | BPF_K, k)};
+}
+
+// ExpectReceived checks that a datagram is received by fd, truncated to want
+// bytes.
+void ExpectReceived(int fd, size_t want) {
+  constexpr int kTimeoutMs = 5000;
+  struct pollfd pfd = {fd, POLLIN, 0};
+  ASSERT_THAT(RetryEINTR(poll)(&pfd, 1, kTimeoutMs),
+              SyscallSucceedsWithValue(1));
+  char buf[sizeof(kData)] = {};
+  ASSERT_THAT(RetryEINTR(recv)(fd, buf, sizeof(buf), MSG_TRUNC),
+              SyscallSucceedsWithValue(want));
+  EXPECT_EQ(memcmp(buf, kData, want), 0);
+}
+
+// ExpectNotReceived checks that no datagram is received by fd.
+void ExpectNotReceived(int fd) {
+  constexpr int kTimeoutMs = 500;
+  struct pollfd pfd = {fd, POLLIN, 0};
+  EXPECT_THAT(RetryEINTR(poll)(&pfd, 1, kTimeoutMs),
+              SyscallSucceedsWithValue(0));
+}
+
+TEST(SocketFilterTest, DropAll) {
+  UDPPair p = ASSERT_NO_ERRNO_AND_VALUE(NewUDPPair());
+  ASSERT_NO_ERRNO(Attach(p.receiver.get(), Return(0)));
+  ASSERT_THAT(send(p.sender.get(), kData, sizeof(kData), 0),
+              SyscallSucceedsWithValue(sizeof(kData)));
+  ExpectNotReceived(p.receiver.get());
+}
+
+TEST(SocketFilterTest, AcceptAll) {
+  UDPPair p = ASSERT_NO_ERRNO_AND_VALUE(NewUDPPair());
+  ASSERT_NO_ERRNO(Attach(p.receiver.get(), Return(0xffffffff)));
+  ASSERT_THAT(send(p.sender.get(), kData, sizeof(kData), 0),
+              SyscallSucceedsWithValue(sizeof(kData)));
+  ExpectReceived(p.receiver.get(), sizeof(kData));
+}
+
+TEST(SocketFilterTest, Truncate) {
+  UDPPair p = ASSERT_NO_ERRNO_AND_VALUE(NewUDPPair());
+  // The UDP header is 8 bytes long, and the filter keeps 4 bytes of payload.
+  ASSERT_NO_ERRNO(Attach(p.receiver.get(), Return(12)));
+  ASSERT_THAT(send(p.sender.get(), kData, sizeof(kData), 0),
+              SyscallSucceedsWithValue(sizeof(kData)));
+  ExpectReceived(p.receiver.get(), 4);
+}
+
+TEST(SocketFilterTest, MatchPort) {
+  UDPPair p = ASSERT_NO_ERRNO_AND_VALUE(NewUDPPair());
+  sockaddr_in addr = {};
+  socklen_t addrlen = sizeof(addr);
+  ASSERT_THAT(getsockname(p.sender.get(), reinterpret_cast<sockaddr*>(&addr),
+                          &addrlen),
+              SyscallSucceeds());
+
+  // The filter sees the packet from the UDP header, and only accepts
+  // datagrams from the sender.
+  std::vector<sock_filter> insns = {
+      BPF_STMT(BPF_LD | BPF_H | BPF_ABS, 0),
+      BPF_JUMP(BPF_JMP | BPF_JEQ | BPF_K, ntohs(addr.sin_port), 0, 1),
+      BPF_STMT(BPF_RET | BPF_K, 0xffffffff),
+      BPF_STMT(BPF_RET | BPF_K, 0),
+  };
+  ASSERT_NO_ERRNO(Attach(p.receiver.get(), insns));
+  ASSERT_THAT(send(p.sender.get(), kData, sizeof(kData), 0),
+              SyscallSucceedsWithValue(sizeof(kData)));
+  ExpectReceived(p.receiver.get(), sizeof(kData));
+
+  FileDescriptor other =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_DGRAM, 0));
+  ASSERT_THAT(getsockname(p.receiver.get(), reinterpret_cast<sockaddr*>(&addr),
+                          &addrlen),
+              SyscallSucceeds());
+  ASSERT_THAT(sendto(other.get(), kData, sizeof(kData), 0,
+                     reinterpret_cast<sockaddr*>(&addr), addrlen),
+              SyscallSucceedsWithValue(sizeof(kData)));
+  ExpectNotReceived(p.receiver.get());
+}
+
+TEST(SocketFilterTest, Detach) {
+  UDPPair p = ASSERT_NO_ERRNO_AND_VALUE(NewUDPPair());
+  ASSERT_NO_ERRNO(Attach(p.receiver.get(), Return(0)));
+  ASSERT_THAT(setsockopt(p.receiver.get(), SOL_SOCKET, SO_DETACH_FILTER,
+                         &kSockOptOn, sizeof(kSockOptOn)),
+              SyscallSucceeds());
+  ASSERT_THAT(send(p.sender.get(), kData, sizeof(kData), 0),
+              SyscallSucceedsWithValue(sizeof(kData)));
+  ExpectReceived(p.receiver.get(), sizeof(kData));
+
+  EXPECT_THAT(setsockopt(p.receiver.get(), SOL_SOCKET, SO_DETACH_FILTER,
+                         &kSockOptOn, sizeof(kSockOptOn)),
+              SyscallFailsWithErrno(ENOENT));
+}
+
+TEST(SocketFilterTest, GetFilter) {
+  FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_DGRAM, 0));
+  sock_filter got[4] = {};
+
+  // No filter is attached.
+  socklen_t len = ABSL_ARRAYSIZE(got);
+  ASSERT_THAT(getsockopt(fd.get(), SOL_SOCKET, SO_GET_FILTER, got, &len),
+              SyscallSucceeds());
+  EXPECT_EQ(len, 0);
+
+  std::vector<sock_filter> insns = {
+      BPF_STMT(BPF_LD | BPF_W | BPF_LEN, 0),
+      BPF_STMT(BPF_RET | BPF_A, 0),
+  };
+  ASSERT_NO_ERRNO(Attach(fd.get(), insns));
+
+  // The length is a number of instructions, and a zero length only returns
+  // it.
+  len = 0;
+  ASSERT_THAT(getsockopt(fd.get(), SOL_SOCKET, SO_GET_FILTER, got, &len),
+              SyscallSucceeds());
+  EXPECT_EQ(len, insns.size());
+
+  len = insns.size() - 1;
+  EXPECT_THAT(getsockopt(fd.get(), SOL_SOCKET, SO_GET_FILTER, got, &len),
+              SyscallFailsWithErrno(EINVAL));
+
+  len = ABSL_ARRAYSIZE(got);
+  ASSERT_THAT(getsockopt(fd.get(), SOL_SOCKET, SO_GET_FILTER, got, &len),
+              SyscallSucceeds());
+  ASSERT_EQ(len, insns.size());
+  EXPECT_EQ(memcmp(got, insns.data(), insns.size() * sizeof(sock_filter)), 0);
+}
+
+TEST(SocketFilterTest, Lock) {
+  UDPPair p = ASSERT_NO_ERRNO_AND_VALUE(NewUDPPair());
+  ASSERT_NO_ERRNO(Attach(p.receiver.get(), Return(0)));
+  ASSERT_THAT(setsockopt(p.receiver.get(), SOL_SOCKET, SO_LOCK_FILTER,
+                         &kSockOptOn, sizeof(kSockOptOn)),
+              SyscallSucceeds());
+
+  int v = 0;
+  socklen_t len = sizeof(v);
+  ASSERT_THAT(
+      getsockopt(p.receiver.get(), SOL_SOCKET, SO_LOCK_FILTER, &v, &len),
+      SyscallSucceeds());
+  EXPECT_EQ(v, 1);
+
+  EXPECT_THAT(setsockopt(p.receiver.get(), SOL_SOCKET, SO_DETACH_FILTER,
+                         &kSockOptOn, sizeof(kSockOptOn)),
+              SyscallFailsWithErrno(EPERM));
+  EXPECT_THAT(Attach(p.receiver.get(), Return(0xffffffff)),
+              PosixErrorIs(EPERM));
+  EXPECT_THAT(setsockopt(p.receiver.get(), SOL_SOCKET, SO_LOCK_FILTER,
+                         &kSockOptOff, sizeof(kSockOptOff)),
+              SyscallFailsWithErrno(EPERM));
+
+  // The filter still applies.
+  ASSERT_THAT(send(p.sender.get(), kData, sizeof(kData), 0),
+              SyscallSucceedsWithValue(sizeof(kData)));
+  ExpectNotReceived(p.receiver.get());
+}
+
+TEST(SocketFilterTest, InvalidProgram) {
+  FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_DGRAM, 0));
+
+  // Empty program.
+  EXPECT_THAT(Attach(fd.get(), {}), PosixErrorIs(EINVAL));
+
+  // Program not ending with a return.
+  EXPECT_THAT(Attach(fd.get(), {BPF_STMT(BPF_LD | BPF_W | BPF_LEN, 0)}),
+              PosixErrorIs(EINVAL));
+
+  // Jump out of the program.
+  EXPECT_THAT(Attach(fd.get(), {BPF_JUMP(BPF_JMP | BPF_JA, 10, 0, 0),
+                                BPF_STMT(BPF_RET | BPF_K, 0)}),
+              PosixErrorIs(EINVAL));
+}
+
+TEST(SocketFilterTest, TCPAcceptedInheritsFilter) {
+  FileDescriptor listener =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_STREAM, 0));
+  sockaddr_in addr = {};
+  addr.sin_family = AF_INET;
+  addr.sin_addr.s_addr = htonl(INADDR_LOOPBACK);
+  ASSERT_THAT(
+      bind(listener.get(), reinterpret_cast<sockaddr*>(&addr), sizeof(addr)),
+      SyscallSucceeds());
+  socklen_t addrlen = sizeof(addr);
+  ASSERT_THAT(getsockname(listener.get(), reinterpret_cast<sockaddr*>(&addr),
+                          &addrlen),
+              SyscallSucceeds());
+  ASSERT_THAT(listen(listener.get(), 1), SyscallSucceeds());
+  ASSERT_NO_ERRNO(Attach(listener.get(), Return(0xffffffff)));
+
+  FileDescriptor client =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_STREAM, 0));
+  ASSERT_THAT(RetryEINTR(connect)(client.get(),
+                                  reinterpret_cast<sockaddr*>(&addr), addrlen),
+              SyscallSucceeds());
+  FileDescriptor accepted =
+      ASSERT_NO_ERRNO_AND_VALUE(Accept(listener.get(), nullptr, nullptr));
+
+  sock_filter got[1] = {};
+  socklen_t len = ABSL_ARRAYSIZE(got);
+  ASSERT_THAT(getsockopt(accepted.get(), SOL_SOCKET, SO_GET_FILTER, got, &len),
+              SyscallSucceeds());
+  EXPECT_EQ(len, 1);
+}
+
+TEST(SocketFilterTest, AttachBPFBadFD) {
+  FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_DGRAM, 0));
+  constexpr int kBadFD = -1;
+  EXPECT_THAT(
+      setsockopt(fd.get(), SOL_SOCKET, SO_ATTACH_BPF, &kBadFD, sizeof(kBadFD)),
+      SyscallFailsWithErrno(EBADF));
+}
+
+}  // namespace
+
+}  // namespace testing
+}  // namespace gvisor
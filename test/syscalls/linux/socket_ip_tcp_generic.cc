@@ -15,10 +15,12 @@
 #include "test/syscalls/linux/socket_ip_tcp_generic.h"
 
 #include <fcntl.h>
+#include <linux/inet_diag.h>
 #include <netinet/in.h>
 #include <netinet/tcp.h>
 #include <poll.h>
 #include <stdio.h>
+#include <string.h>
 #include <sys/ioctl.h>
 #include <sys/socket.h>
 #include <sys/types.h>
@@ -98,6 +100,136 @@ TEST_P(TCPSocketPairTest, CheckTcpInfoFields) {
   EXPECT_GT(opt.tcpi_rto, 0);
 }
 
+// tcp_info as defined by Linux since v4.19. The struct tcp_info defined by
+// glibc stops at tcpi_total_retrans.
+struct tcp_info_ext {
+  struct tcp_info base;
+  uint64_t tcpi_pacing_rate;
+  uint64_t tcpi_max_pacing_rate;
+  uint64_t tcpi_bytes_acked;
+  uint64_t tcpi_bytes_received;
+  uint32_t tcpi_segs_out;
+  uint32_t tcpi_segs_in;
+  uint32_t tcpi_notsent_bytes;
+  uint32_t tcpi_min_rtt;
+  uint32_t tcpi_data_segs_in;
+  uint32_t tcpi_data_segs_out;
+  uint64_t tcpi_delivery_rate;
+  uint64_t tcpi_busy_time;
+  uint64_t tcpi_rwnd_limited;
+  uint64_t tcpi_sndbuf_limited;
+  uint32_t tcpi_delivered;
+  uint32_t tcpi_delivered_ce;
+  uint64_t tcpi_bytes_sent;
+  uint64_t tcpi_bytes_retrans;
+  uint32_t tcpi_dsack_dups;
+  uint32_t tcpi_reord_seen;
+};
+
+TEST_P(TCPSocketPairTest, CheckTcpInfoCounters) {
+  auto sockets = ASSERT_NO_ERRNO_AND_VALUE(NewSocketPair());
+
+  char buf[10] = {};
+  ASSERT_THAT(RetryEINTR(send)(sockets->first_fd(), buf, sizeof(buf), 0),
+              SyscallSucceedsWithValue(sizeof(buf)));
+
+  struct pollfd poll_fd = {sockets->second_fd(), POLLIN, 0};
+  constexpr int kPollTimeoutMs = 2000;  // Wait up to 2 seconds for the data.
+  ASSERT_THAT(RetryEINTR(poll)(&poll_fd, 1, kPollTimeoutMs),
+              SyscallSucceedsWithValue(1));
+  ASSERT_THAT(RetryEINTR(recv)(sockets->second_fd(), buf, sizeof(buf), 0),
+              SyscallSucceedsWithValue(sizeof(buf)));
+
+  struct tcp_info_ext sent = {};
+  socklen_t optLen = sizeof(sent);
+  ASSERT_THAT(
+      getsockopt(sockets->first_fd(), SOL_TCP, TCP_INFO, &sent, &optLen),
+      SyscallSucceeds());
+  ASSERT_GE(optLen, sizeof(sent));
+  EXPECT_EQ(sent.tcpi_bytes_sent, sizeof(buf));
+  EXPECT_EQ(sent.tcpi_bytes_retrans, 0);
+  EXPECT_GE(sent.tcpi_data_segs_out, 1);
+  EXPECT_GE(sent.tcpi_segs_out, sent.tcpi_data_segs_out);
+  EXPECT_EQ(sent.tcpi_notsent_bytes, 0);
+  EXPECT_EQ(sent.base.tcpi_total_retrans, 0);
+  EXPECT_GT(sent.base.tcpi_snd_mss, 0);
+
+  struct tcp_info_ext rcvd = {};
+  optLen = sizeof(rcvd);
+  ASSERT_THAT(
+      getsockopt(sockets->second_fd(), SOL_TCP, TCP_INFO, &rcvd, &optLen),
+      SyscallSucceeds());
+  ASSERT_GE(optLen, sizeof(rcvd));
+  EXPECT_EQ(rcvd.tcpi_bytes_received, sizeof(buf));
+  EXPECT_GE(rcvd.tcpi_data_segs_in, 1);
+  EXPECT_GE(rcvd.tcpi_segs_in, rcvd.tcpi_data_segs_in);
+}
+
+TEST_P(TCPSocketPairTest, TcpCCInfoEmptyForCubicAndReno) {
+  auto sockets = ASSERT_NO_ERRNO_AND_VALUE(NewSocketPair());
+
+  for (const char* algo : {"cubic", "reno"}) {
+    SCOPED_TRACE(algo);
+    ASSERT_THAT(setsockopt(sockets->first_fd(), SOL_TCP, TCP_CONGESTION, algo,
+                           strlen(algo)),
+                SyscallSucceeds());
+
+    // Neither algorithm reports specific information.
+    char info[128];
+    socklen_t optLen = sizeof(info);
+    ASSERT_THAT(
+        getsockopt(sockets->first_fd(), SOL_TCP, TCP_CC_INFO, info, &optLen),
+        SyscallSucceeds());
+    EXPECT_EQ(optLen, 0);
+  }
+}
+
+TEST_P(TCPSocketPairTest, TcpCCInfoForBBR) {
+  auto sockets = ASSERT_NO_ERRNO_AND_VALUE(NewSocketPair());
+
+  constexpr char kBBR[] = "bbr";
+  int ret = setsockopt(sockets->first_fd(), SOL_TCP, TCP_CONGESTION, kBBR,
+                       strlen(kBBR));
+  if (ret < 0 && errno == ENOENT) {
+    // The tcp_bbr module isn't loaded.
+    GTEST_SKIP() << "BBR not available";
+  }
+  ASSERT_THAT(ret, SyscallSucceeds());
+
+  char buf[10] = {};
+  ASSERT_THAT(RetryEINTR(write)(sockets->first_fd(), buf, sizeof(buf)),
+              SyscallSucceedsWithValue(sizeof(buf)));
+  ASSERT_THAT(RetryEINTR(read)(sockets->second_fd(), buf, sizeof(buf)),
+              SyscallSucceedsWithValue(sizeof(buf)));
+
+  // The bandwidth is estimated once the data is acknowledged, which may be
+  // delayed.
+  struct tcp_bbr_info info = {};
+  socklen_t optLen;
+  const absl::Time deadline = absl::Now() + absl::Seconds(5);
+  do {
+    optLen = sizeof(info);
+    ASSERT_THAT(
+        getsockopt(sockets->first_fd(), SOL_TCP, TCP_CC_INFO, &info, &optLen),
+        SyscallSucceeds());
+    ASSERT_EQ(optLen, sizeof(info));
+    if (info.bbr_bw_lo != 0 || info.bbr_bw_hi != 0) {
+      break;
+    }
+    absl::SleepFor(absl::Milliseconds(10));
+  } while (absl::Now() < deadline);
+  EXPECT_TRUE(info.bbr_bw_lo != 0 || info.bbr_bw_hi != 0);
+  EXPECT_GT(info.bbr_pacing_gain, 0);
+  EXPECT_GT(info.bbr_cwnd_gain, 0);
+
+  // The output is truncated to the length of the buffer.
+  optLen = sizeof(info.bbr_bw_lo);
+  ASSERT_THAT(
+      getsockopt(sockets->first_fd(), SOL_TCP, TCP_CC_INFO, &info, &optLen),
+      SyscallSucceeds());
+  EXPECT_EQ(optLen, sizeof(info.bbr_bw_lo));
+}
+
 // This test validates that an RST is sent instead of a FIN when data is
 // unread on calls to close(2).
 TEST_P(TCPSocketPairTest, RSTSentOnCloseWithUnreadData) {
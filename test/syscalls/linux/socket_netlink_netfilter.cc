@@ -12,6 +12,8 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
+#include <arpa/inet.h>
+#include <linux/netfilter.h>
 #include <linux/netlink.h>
 
 #include <cerrno>
@@ -153,7 +155,7 @@ TEST(NetlinkNetfilterTest, AddAndAddTableWithDormantFlag) {
                  test_table_name);
   InitNetlinkAttr(&add_tab_req_2.fattr.attr, sizeof(add_tab_req_2.fattr.flags),
                   NFTA_TABLE_FLAGS);
-  add_tab_req_2.fattr.flags = NFT_TABLE_F_DORMANT;
+  add_tab_req_2.fattr.flags = htonl(NFT_TABLE_F_DORMANT);
 
   ASSERT_NO_ERRNO(
       NetlinkRequestAckOrError(fd, kSeq, &add_tab_req, sizeof(add_tab_req)));
@@ -212,7 +214,7 @@ TEST(NetlinkNetfilterTest, AddAndRetrieveNewTable) {
       fd, &add_tab_req_2, sizeof(add_tab_req_2),
       [&](const struct nlmsghdr* hdr) {
         ASSERT_THAT(hdr->nlmsg_type, Eq(MakeNetlinkMsgType(NFNL_SUBSYS_NFTABLES,
-                                                           NFT_MSG_GETTABLE)));
+                                                           NFT_MSG_NEWTABLE)));
         ASSERT_GE(hdr->nlmsg_len, NLMSG_SPACE(sizeof(struct nfgenmsg)));
         const struct nfgenmsg* genmsg =
             reinterpret_cast<const struct nfgenmsg*>(NLMSG_DATA(hdr));
@@ -438,6 +440,302 @@ TEST(NetlinkNetfilterTest, ErrRetrieveNonexistentTable) {
       PosixErrorIs(ENOENT, _));
 }
 
+// Returns a builder holding the beginning of an nf_tables batch.
+NfMsgBuilder BeginBatch(uint32_t seq) {
+  NfMsgBuilder b;
+  b.Msg(NFNL_MSG_BATCH_BEGIN, seq, NLM_F_REQUEST, AF_UNSPEC,
+        htons(NFNL_SUBSYS_NFTABLES));
+  return b;
+}
+
+// Ends the batch in b.
+void EndBatch(NfMsgBuilder& b, uint32_t seq) {
+  b.Msg(NFNL_MSG_BATCH_END, seq, NLM_F_REQUEST, AF_UNSPEC,
+        htons(NFNL_SUBSYS_NFTABLES));
+}
+
+// Adds a message creating an input base chain to b.
+void AddBaseChain(NfMsgBuilder& b, uint32_t seq, uint16_t flags,
+                  const std::string& table, const std::string& chain) {
+  b.Msg(MakeNetlinkMsgType(NFNL_SUBSYS_NFTABLES, NFT_MSG_NEWCHAIN), seq,
+        NLM_F_REQUEST | NLM_F_CREATE | flags, AF_INET)
+      .StrAttr(NFTA_CHAIN_TABLE, table)
+      .StrAttr(NFTA_CHAIN_NAME, chain)
+      .BeginNested(NFTA_CHAIN_HOOK)
+      .U32Attr(NFTA_HOOK_HOOKNUM, NF_INET_LOCAL_IN)
+      .U32Attr(NFTA_HOOK_PRIORITY, 0)
+      .EndNested()
+      .StrAttr(NFTA_CHAIN_TYPE, "filter")
+      .U32Attr(NFTA_CHAIN_POLICY, NF_ACCEPT);
+}
+
+// Sends a message deleting the table.
+void DeleteTable(const FileDescriptor& fd, uint32_t seq,
+                 const std::string& table) {
+  NfMsgBuilder b;
+  b.Msg(MakeNetlinkMsgType(NFNL_SUBSYS_NFTABLES, NFT_MSG_DELTABLE), seq,
+        NLM_F_REQUEST | NLM_F_ACK, AF_INET)
+      .StrAttr(NFTA_TABLE_NAME, table);
+  EXPECT_NO_ERRNO(NetlinkRequestAckOrError(fd, seq, b.data(), b.size()));
+}
+
+TEST(NetlinkNetfilterTest, BatchAddChainAndRule) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_NET_ADMIN)));
+  const std::string table = "batch_table";
+  const std::string chain = "batch_chain";
+
+  FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(NetlinkBoundSocket(NETLINK_NETFILTER));
+
+  NfMsgBuilder b = BeginBatch(kSeq);
+  b.Msg(MakeNetlinkMsgType(NFNL_SUBSYS_NFTABLES, NFT_MSG_NEWTABLE), kSeq + 1,
+        NLM_F_REQUEST | NLM_F_CREATE, AF_INET)
+      .StrAttr(NFTA_TABLE_NAME, table);
+  AddBaseChain(b, kSeq + 2, 0, table, chain);
+  b.Msg(MakeNetlinkMsgType(NFNL_SUBSYS_NFTABLES, NFT_MSG_NEWRULE), kSeq + 3,
+        NLM_F_REQUEST | NLM_F_CREATE | NLM_F_APPEND | NLM_F_ACK, AF_INET)
+      .StrAttr(NFTA_RULE_TABLE, table)
+      .StrAttr(NFTA_RULE_CHAIN, chain)
+      .BeginNested(NFTA_RULE_EXPRESSIONS)
+      .BeginNested(NFTA_LIST_ELEM)
+      .StrAttr(NFTA_EXPR_NAME, "immediate")
+      .BeginNested(NFTA_EXPR_DATA)
+      .U32Attr(NFTA_IMMEDIATE_DREG, NFT_REG_VERDICT)
+      .BeginNested(NFTA_IMMEDIATE_DATA)
+      .BeginNested(NFTA_DATA_VERDICT)
+      .U32Attr(NFTA_VERDICT_CODE, NF_ACCEPT)
+      .EndNested()
+      .EndNested()
+      .EndNested()
+      .EndNested()
+      .EndNested();
+  EndBatch(b, kSeq + 4);
+  ASSERT_NO_ERRNO(NetlinkRequestAckOrError(fd, kSeq + 3, b.data(), b.size()));
+
+  NfMsgBuilder get_chain;
+  get_chain
+      .Msg(MakeNetlinkMsgType(NFNL_SUBSYS_NFTABLES, NFT_MSG_GETCHAIN),
+           kSeq + 5, NLM_F_REQUEST, AF_INET)
+      .StrAttr(NFTA_CHAIN_TABLE, table)
+      .StrAttr(NFTA_CHAIN_NAME, chain);
+  bool found_chain = false;
+  ASSERT_NO_ERRNO(NetlinkRequestResponse(
+      fd, get_chain.data(), get_chain.size(),
+      [&](const struct nlmsghdr* hdr) {
+        ASSERT_THAT(hdr->nlmsg_type, Eq(MakeNetlinkMsgType(NFNL_SUBSYS_NFTABLES,
+                                                           NFT_MSG_NEWCHAIN)));
+        const struct nfgenmsg* genmsg =
+            reinterpret_cast<const struct nfgenmsg*>(NLMSG_DATA(hdr));
+        const struct nfattr* name = FindNfAttr(hdr, genmsg, NFTA_CHAIN_NAME);
+        ASSERT_NE(name, nullptr);
+        EXPECT_EQ(std::string(reinterpret_cast<const char*>(NFA_DATA(name))),
+                  chain);
+        EXPECT_NE(FindNfAttr(hdr, genmsg, NFTA_CHAIN_HOOK), nullptr);
+        const struct nfattr* policy =
+            FindNfAttr(hdr, genmsg, NFTA_CHAIN_POLICY);
+        ASSERT_NE(policy, nullptr);
+        EXPECT_EQ(ntohl(*reinterpret_cast<const uint32_t*>(NFA_DATA(policy))),
+                  NF_ACCEPT);
+        found_chain = true;
+      },
+      false));
+  EXPECT_TRUE(found_chain);
+
+  NfMsgBuilder get_rules;
+  get_rules
+      .Msg(MakeNetlinkMsgType(NFNL_SUBSYS_NFTABLES, NFT_MSG_GETRULE),
+           kSeq + 6, NLM_F_REQUEST | NLM_F_DUMP, AF_INET)
+      .StrAttr(NFTA_RULE_TABLE, table);
+  int rules = 0;
+  ASSERT_NO_ERRNO(NetlinkRequestResponse(
+      fd, get_rules.data(), get_rules.size(),
+      [&](const struct nlmsghdr* hdr) {
+        if (hdr->nlmsg_type == NLMSG_DONE) {
+          return;
+        }
+        ASSERT_THAT(hdr->nlmsg_type, Eq(MakeNetlinkMsgType(NFNL_SUBSYS_NFTABLES,
+                                                           NFT_MSG_NEWRULE)));
+        const struct nfgenmsg* genmsg =
+            reinterpret_cast<const struct nfgenmsg*>(NLMSG_DATA(hdr));
+        EXPECT_NE(FindNfAttr(hdr, genmsg, NFTA_RULE_HANDLE), nullptr);
+        EXPECT_NE(FindNfAttr(hdr, genmsg, NFTA_RULE_EXPRESSIONS), nullptr);
+        rules++;
+      },
+      false));
+  EXPECT_EQ(rules, 1);
+
+  DeleteTable(fd, kSeq + 7, table);
+}
+
+TEST(NetlinkNetfilterTest, ErrBatchIsDiscardedOnFailure) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_NET_ADMIN)));
+  const std::string table = "discarded_table";
+
+  FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(NetlinkBoundSocket(NETLINK_NETFILTER));
+
+  NfMsgBuilder b = BeginBatch(kSeq);
+  b.Msg(MakeNetlinkMsgType(NFNL_SUBSYS_NFTABLES, NFT_MSG_NEWTABLE), kSeq + 1,
+        NLM_F_REQUEST | NLM_F_CREATE, AF_INET)
+      .StrAttr(NFTA_TABLE_NAME, table);
+  AddBaseChain(b, kSeq + 2, NLM_F_ACK, "nonexistent_table", "chain");
+  EndBatch(b, kSeq + 3);
+  ASSERT_THAT(NetlinkRequestAckOrError(fd, kSeq + 2, b.data(), b.size()),
+              PosixErrorIs(ENOENT, _));
+
+  // The table created earlier in the batch must not have been committed.
+  NfMsgBuilder get_table;
+  get_table
+      .Msg(MakeNetlinkMsgType(NFNL_SUBSYS_NFTABLES, NFT_MSG_GETTABLE),
+           kSeq + 4, NLM_F_REQUEST, AF_INET)
+      .StrAttr(NFTA_TABLE_NAME, table);
+  ASSERT_THAT(NetlinkRequestAckOrError(fd, kSeq + 4, get_table.data(),
+                                       get_table.size()),
+              PosixErrorIs(ENOENT, _));
+}
+
+TEST(NetlinkNetfilterTest, GenerationIDChangesOnCommit) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_NET_ADMIN)));
+  const std::string table = "gen_table";
+
+  FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(NetlinkBoundSocket(NETLINK_NETFILTER));
+
+  auto get_gen = [&](uint32_t seq) -> uint32_t {
+    NfMsgBuilder b;
+    b.Msg(MakeNetlinkMsgType(NFNL_SUBSYS_NFTABLES, NFT_MSG_GETGEN), seq,
+          NLM_F_REQUEST, AF_UNSPEC);
+    uint32_t gen_id = 0;
+    EXPECT_NO_ERRNO(NetlinkRequestResponse(
+        fd, b.data(), b.size(),
+        [&](const struct nlmsghdr* hdr) {
+          ASSERT_THAT(hdr->nlmsg_type,
+                      Eq(MakeNetlinkMsgType(NFNL_SUBSYS_NFTABLES,
+                                            NFT_MSG_NEWGEN)));
+          const struct nfgenmsg* genmsg =
+              reinterpret_cast<const struct nfgenmsg*>(NLMSG_DATA(hdr));
+          const struct nfattr* id = FindNfAttr(hdr, genmsg, NFTA_GEN_ID);
+          ASSERT_NE(id, nullptr);
+          gen_id = ntohl(*reinterpret_cast<const uint32_t*>(NFA_DATA(id)));
+        },
+        false));
+    return gen_id;
+  };
+
+  uint32_t before = get_gen(kSeq);
+  NfMsgBuilder b;
+  b.Msg(MakeNetlinkMsgType(NFNL_SUBSYS_NFTABLES, NFT_MSG_NEWTABLE), kSeq + 1,
+        NLM_F_REQUEST | NLM_F_CREATE | NLM_F_ACK, AF_INET)
+      .StrAttr(NFTA_TABLE_NAME, table);
+  ASSERT_NO_ERRNO(NetlinkRequestAckOrError(fd, kSeq + 1, b.data(), b.size()));
+  EXPECT_NE(get_gen(kSeq + 2), before);
+
+  DeleteTable(fd, kSeq + 3, table);
+}
+
+TEST(NetlinkNetfilterTest, DeleteChain) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_NET_ADMIN)));
+  const std::string table = "del_chain_table";
+  const std::string chain = "del_chain";
+
+  FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(NetlinkBoundSocket(NETLINK_NETFILTER));
+
+  NfMsgBuilder b = BeginBatch(kSeq);
+  b.Msg(MakeNetlinkMsgType(NFNL_SUBSYS_NFTABLES, NFT_MSG_NEWTABLE), kSeq + 1,
+        NLM_F_REQUEST | NLM_F_CREATE, AF_INET)
+      .StrAttr(NFTA_TABLE_NAME, table);
+  AddBaseChain(b, kSeq + 2, NLM_F_ACK, table, chain);
+  EndBatch(b, kSeq + 3);
+  ASSERT_NO_ERRNO(NetlinkRequestAckOrError(fd, kSeq + 2, b.data(), b.size()));
+
+  NfMsgBuilder del_chain;
+  del_chain
+      .Msg(MakeNetlinkMsgType(NFNL_SUBSYS_NFTABLES, NFT_MSG_DELCHAIN),
+           kSeq + 4, NLM_F_REQUEST | NLM_F_ACK, AF_INET)
+      .StrAttr(NFTA_CHAIN_TABLE, table)
+      .StrAttr(NFTA_CHAIN_NAME, chain);
+  ASSERT_NO_ERRNO(NetlinkRequestAckOrError(fd, kSeq + 4, del_chain.data(),
+                                           del_chain.size()));
+
+  NfMsgBuilder get_chain;
+  get_chain
+      .Msg(MakeNetlinkMsgType(NFNL_SUBSYS_NFTABLES, NFT_MSG_GETCHAIN),
+           kSeq + 5, NLM_F_REQUEST, AF_INET)
+      .StrAttr(NFTA_CHAIN_TABLE, table)
+      .StrAttr(NFTA_CHAIN_NAME, chain);
+  ASSERT_THAT(NetlinkRequestAckOrError(fd, kSeq + 5, get_chain.data(),
+                                       get_chain.size()),
+              PosixErrorIs(ENOENT, _));
+
+  DeleteTable(fd, kSeq + 6, table);
+}
+
+TEST(NetlinkNetfilterTest, AddAndDumpSetElements) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_NET_ADMIN)));
+  const std::string table = "set_table";
+  const std::string set = "addrs";
+  const uint32_t key = htonl(0x7f000001);
+
+  FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(NetlinkBoundSocket(NETLINK_NETFILTER));
+
+  NfMsgBuilder b = BeginBatch(kSeq);
+  b.Msg(MakeNetlinkMsgType(NFNL_SUBSYS_NFTABLES, NFT_MSG_NEWTABLE), kSeq + 1,
+        NLM_F_REQUEST | NLM_F_CREATE, AF_INET)
+      .StrAttr(NFTA_TABLE_NAME, table);
+  b.Msg(MakeNetlinkMsgType(NFNL_SUBSYS_NFTABLES, NFT_MSG_NEWSET), kSeq + 2,
+        NLM_F_REQUEST | NLM_F_CREATE, AF_INET)
+      .StrAttr(NFTA_SET_TABLE, table)
+      .StrAttr(NFTA_SET_NAME, set)
+      .U32Attr(NFTA_SET_KEY_LEN, sizeof(key))
+      .U32Attr(NFTA_SET_ID, 1);
+  b.Msg(MakeNetlinkMsgType(NFNL_SUBSYS_NFTABLES, NFT_MSG_NEWSETELEM), kSeq + 3,
+        NLM_F_REQUEST | NLM_F_CREATE | NLM_F_ACK, AF_INET)
+      .StrAttr(NFTA_SET_ELEM_LIST_TABLE, table)
+      .U32Attr(NFTA_SET_ELEM_LIST_SET_ID, 1)
+      .BeginNested(NFTA_SET_ELEM_LIST_ELEMENTS)
+      .BeginNested(NFTA_LIST_ELEM)
+      .BeginNested(NFTA_SET_ELEM_KEY)
+      .Attr(NFTA_DATA_VALUE, &key, sizeof(key))
+      .EndNested()
+      .EndNested()
+      .EndNested();
+  EndBatch(b, kSeq + 4);
+  ASSERT_NO_ERRNO(NetlinkRequestAckOrError(fd, kSeq + 3, b.data(), b.size()));
+
+  NfMsgBuilder get_elems;
+  get_elems
+      .Msg(MakeNetlinkMsgType(NFNL_SUBSYS_NFTABLES, NFT_MSG_GETSETELEM),
+           kSeq + 5, NLM_F_REQUEST | NLM_F_DUMP, AF_INET)
+      .StrAttr(NFTA_SET_ELEM_LIST_TABLE, table)
+      .StrAttr(NFTA_SET_ELEM_LIST_SET, set);
+  bool found_elems = false;
+  ASSERT_NO_ERRNO(NetlinkRequestResponse(
+      fd, get_elems.data(), get_elems.size(),
+      [&](const struct nlmsghdr* hdr) {
+        if (hdr->nlmsg_type == NLMSG_DONE) {
+          return;
+        }
+        ASSERT_THAT(hdr->nlmsg_type, Eq(MakeNetlinkMsgType(NFNL_SUBSYS_NFTABLES,
+                                                           NFT_MSG_NEWSETELEM)));
+        const struct nfgenmsg* genmsg =
+            reinterpret_cast<const struct nfgenmsg*>(NLMSG_DATA(hdr));
+        const struct nfattr* name =
+            FindNfAttr(hdr, genmsg, NFTA_SET_ELEM_LIST_SET);
+        ASSERT_NE(name, nullptr);
+        EXPECT_EQ(std::string(reinterpret_cast<const char*>(NFA_DATA(name))),
+                  set);
+        EXPECT_NE(FindNfAttr(hdr, genmsg, NFTA_SET_ELEM_LIST_ELEMENTS),
+                  nullptr);
+        found_elems = true;
+      },
+      false));
+  EXPECT_TRUE(found_elems);
+
+  DeleteTable(fd, kSeq + 6, table);
+}
+
 }  // namespace
 
 }  // namespace testing
@@ -14,7 +14,13 @@
 
 #include "test/syscalls/linux/socket_netlink_netfilter_util.h"
 
+#include <arpa/inet.h>
+#include <endian.h>
+
+#include <cstddef>
 #include <cstdint>
+#include <cstring>
+#include <string>
 
 namespace gvisor {
 namespace testing {
@@ -27,5 +33,65 @@ void InitNetfilterGenmsg(struct nfgenmsg* genmsg, uint8_t family,
   genmsg->res_id = res_id;
 }
 
+NfMsgBuilder& NfMsgBuilder::Msg(uint16_t type, uint32_t seq, uint16_t flags,
+                                uint8_t family, uint16_t res_id) {
+  msg_start_ = buf_.size();
+  buf_.resize(msg_start_ + NLMSG_SPACE(sizeof(struct nfgenmsg)));
+  struct nlmsghdr* hdr = reinterpret_cast<struct nlmsghdr*>(&buf_[msg_start_]);
+  hdr->nlmsg_type = type;
+  hdr->nlmsg_flags = flags;
+  hdr->nlmsg_seq = seq;
+  InitNetfilterGenmsg(reinterpret_cast<struct nfgenmsg*>(NLMSG_DATA(hdr)),
+                      family, NFNETLINK_V0, res_id);
+  UpdateMsgLen();
+  return *this;
+}
+
+NfMsgBuilder& NfMsgBuilder::Attr(uint16_t type, const void* data, size_t len) {
+  size_t start = buf_.size();
+  buf_.resize(start + NLA_ALIGN(NLA_HDRLEN + len));
+  struct nlattr* attr = reinterpret_cast<struct nlattr*>(&buf_[start]);
+  attr->nla_type = type;
+  attr->nla_len = NLA_HDRLEN + len;
+  if (len > 0) {
+    memcpy(&buf_[start + NLA_HDRLEN], data, len);
+  }
+  UpdateMsgLen();
+  return *this;
+}
+
+NfMsgBuilder& NfMsgBuilder::StrAttr(uint16_t type, const std::string& value) {
+  // Strings are NUL-terminated.
+  return Attr(type, value.c_str(), value.size() + 1);
+}
+
+NfMsgBuilder& NfMsgBuilder::U32Attr(uint16_t type, uint32_t value) {
+  uint32_t be = htonl(value);
+  return Attr(type, &be, sizeof(be));
+}
+
+NfMsgBuilder& NfMsgBuilder::U64Attr(uint16_t type, uint64_t value) {
+  uint64_t be = htobe64(value);
+  return Attr(type, &be, sizeof(be));
+}
+
+NfMsgBuilder& NfMsgBuilder::BeginNested(uint16_t type) {
+  nested_starts_.push_back(buf_.size());
+  return Attr(type | NLA_F_NESTED, nullptr, 0);
+}
+
+NfMsgBuilder& NfMsgBuilder::EndNested() {
+  size_t start = nested_starts_.back();
+  nested_starts_.pop_back();
+  struct nlattr* attr = reinterpret_cast<struct nlattr*>(&buf_[start]);
+  attr->nla_len = buf_.size() - start;
+  return *this;
+}
+
+void NfMsgBuilder::UpdateMsgLen() {
+  struct nlmsghdr* hdr = reinterpret_cast<struct nlmsghdr*>(&buf_[msg_start_]);
+  hdr->nlmsg_len = buf_.size() - msg_start_;
+}
+
 }  // namespace testing
 }  // namespace gvisor
@@ -21,7 +21,10 @@
 #include <linux/netfilter/nfnetlink_compat.h>
 #include <linux/netlink.h>
 
+#include <cstddef>
 #include <cstdint>
+#include <string>
+#include <vector>
 
 namespace gvisor {
 namespace testing {
@@ -29,6 +32,36 @@ namespace testing {
 void InitNetfilterGenmsg(struct nfgenmsg* genmsg, uint8_t family,
                          uint8_t version, uint16_t res_id);
 
+// NfMsgBuilder builds a buffer of one or more nf_tables netlink messages, such
+// as a batch. Integer attributes are converted to network byte order.
+class NfMsgBuilder {
+ public:
+  // Begins a new message with a nfgenmsg header.
+  NfMsgBuilder& Msg(uint16_t type, uint32_t seq, uint16_t flags,
+                    uint8_t family, uint16_t res_id = 0);
+
+  // Appends an attribute to the current message.
+  NfMsgBuilder& Attr(uint16_t type, const void* data, size_t len);
+  NfMsgBuilder& StrAttr(uint16_t type, const std::string& value);
+  NfMsgBuilder& U32Attr(uint16_t type, uint32_t value);
+  NfMsgBuilder& U64Attr(uint16_t type, uint64_t value);
+
+  // Begins and ends a nested attribute.
+  NfMsgBuilder& BeginNested(uint16_t type);
+  NfMsgBuilder& EndNested();
+
+  void* data() { return buf_.data(); }
+  size_t size() const { return buf_.size(); }
+
+ private:
+  // Updates the length of the current message.
+  void UpdateMsgLen();
+
+  std::vector<char> buf_;
+  size_t msg_start_ = 0;
+  std::vector<size_t> nested_starts_;
+};
+
 }  // namespace testing
 }  // namespace gvisor
 
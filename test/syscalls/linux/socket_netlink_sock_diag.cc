@@ -0,0 +1,412 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include <linux/inet_diag.h>
+#include <linux/netlink.h>
+#include <linux/sock_diag.h>
+#include <linux/unix_diag.h>
+#include <netinet/in.h>
+#include <netinet/tcp.h>
+#include <string.h>
+#include <sys/socket.h>
+#include <sys/stat.h>
+#include <sys/types.h>
+#include <unistd.h>
+
+#include <cstdint>
+
+#include "gtest/gtest.h"
+#include "test/syscalls/linux/socket_netlink_util.h"
+#include "test/util/capability_util.h"
+#include "test/util/file_descriptor.h"
+#include "test/util/socket_util.h"
+#include "test/util/test_util.h"
+
+// Tests for NETLINK_SOCK_DIAG sockets.
+
+namespace gvisor {
+namespace testing {
+
+namespace {
+
+constexpr uint32_t kSeq = 12345;
+
+// Returns the attribute of type attr_type following a message payload of
+// size payload_len in hdr, or nullptr if there is none.
+const struct rtattr* FindDiagAttr(const struct nlmsghdr* hdr,
+                                  size_t payload_len, uint16_t attr_type) {
+  const int payload_aligned = NLMSG_ALIGN(payload_len);
+  const struct rtattr* attr = reinterpret_cast<const struct rtattr*>(
+      reinterpret_cast<const char*>(NLMSG_DATA(hdr)) + payload_aligned);
+  int len = NLMSG_PAYLOAD(hdr, payload_aligned);
+  for (; RTA_OK(attr, len); attr = RTA_NEXT(attr, len)) {
+    if (attr->rta_type == attr_type) {
+      return attr;
+    }
+  }
+  return nullptr;
+}
+
+// Returns the inode number of the file referred to by fd.
+PosixErrorOr<ino_t> InodeOf(int fd) {
+  struct stat st;
+  RETURN_ERROR_IF_SYSCALL_FAIL(fstat(fd, &st));
+  return st.st_ino;
+}
+
+struct InetDiagRequest {
+  struct nlmsghdr hdr;
+  struct inet_diag_req_v2 req;
+};
+
+InetDiagRequest MakeInetDiagRequest(uint16_t type, uint16_t flags,
+                                    uint8_t protocol) {
+  InetDiagRequest req = {};
+  req.hdr.nlmsg_len = sizeof(req);
+  req.hdr.nlmsg_type = type;
+  req.hdr.nlmsg_flags = NLM_F_REQUEST | flags;
+  req.hdr.nlmsg_seq = kSeq;
+  req.req.sdiag_family = AF_INET;
+  req.req.sdiag_protocol = protocol;
+  req.req.idiag_states = ~0U;
+  req.req.id.idiag_cookie[0] = INET_DIAG_NOCOOKIE;
+  req.req.id.idiag_cookie[1] = INET_DIAG_NOCOOKIE;
+  return req;
+}
+
+TEST(NetlinkSockDiagTest, DumpTCPListener) {
+  FileDescriptor listener =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_STREAM, 0));
+  sockaddr_storage addr = InetLoopbackAddr(AF_INET);
+  socklen_t addrlen = sizeof(struct sockaddr_in);
+  ASSERT_THAT(
+      bind(listener.get(), reinterpret_cast<sockaddr*>(&addr), addrlen),
+      SyscallSucceeds());
+  ASSERT_THAT(listen(listener.get(), 5), SyscallSucceeds());
+  ASSERT_THAT(
+      getsockname(listener.get(), reinterpret_cast<sockaddr*>(&addr), &addrlen),
+      SyscallSucceeds());
+  const uint16_t port = reinterpret_cast<sockaddr_in*>(&addr)->sin_port;
+  const ino_t ino = ASSERT_NO_ERRNO_AND_VALUE(InodeOf(listener.get()));
+
+  FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(NetlinkBoundSocket(NETLINK_SOCK_DIAG));
+
+  InetDiagRequest req =
+      MakeInetDiagRequest(SOCK_DIAG_BY_FAMILY, NLM_F_DUMP, IPPROTO_TCP);
+  req.req.idiag_states = 1 << TCP_LISTEN;
+  req.req.idiag_ext = 1 << (INET_DIAG_INFO - 1);
+
+  bool found = false;
+  ASSERT_NO_ERRNO(NetlinkRequestResponse(
+      fd, &req, sizeof(req),
+      [&](const struct nlmsghdr* hdr) {
+        ASSERT_EQ(hdr->nlmsg_type, SOCK_DIAG_BY_FAMILY);
+        ASSERT_GE(hdr->nlmsg_len, NLMSG_LENGTH(sizeof(struct inet_diag_msg)));
+        const struct inet_diag_msg* msg =
+            reinterpret_cast<const struct inet_diag_msg*>(NLMSG_DATA(hdr));
+        EXPECT_EQ(msg->idiag_family, AF_INET);
+        EXPECT_EQ(msg->idiag_state, TCP_LISTEN);
+        if (msg->id.idiag_sport != port) {
+          return;
+        }
+        found = true;
+        EXPECT_EQ(msg->idiag_inode, ino);
+        EXPECT_EQ(msg->idiag_uid, getuid());
+
+        const struct rtattr* attr =
+            FindDiagAttr(hdr, sizeof(*msg), INET_DIAG_INFO);
+        ASSERT_NE(attr, nullptr);
+        ASSERT_GE(RTA_PAYLOAD(attr), sizeof(struct tcp_info));
+        const struct tcp_info* info =
+            reinterpret_cast<const struct tcp_info*>(RTA_DATA(attr));
+        EXPECT_EQ(info->tcpi_state, TCP_LISTEN);
+      },
+      false));
+  EXPECT_TRUE(found);
+}
+
+TEST(NetlinkSockDiagTest, StateFilter) {
+  FileDescriptor listener =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_STREAM, 0));
+  sockaddr_storage addr = InetLoopbackAddr(AF_INET);
+  socklen_t addrlen = sizeof(struct sockaddr_in);
+  ASSERT_THAT(
+      bind(listener.get(), reinterpret_cast<sockaddr*>(&addr), addrlen),
+      SyscallSucceeds());
+  ASSERT_THAT(listen(listener.get(), 5), SyscallSucceeds());
+  ASSERT_THAT(
+      getsockname(listener.get(), reinterpret_cast<sockaddr*>(&addr), &addrlen),
+      SyscallSucceeds());
+  const uint16_t port = reinterpret_cast<sockaddr_in*>(&addr)->sin_port;
+
+  FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(NetlinkBoundSocket(NETLINK_SOCK_DIAG));
+
+  InetDiagRequest req =
+      MakeInetDiagRequest(SOCK_DIAG_BY_FAMILY, NLM_F_DUMP, IPPROTO_TCP);
+  req.req.idiag_states = 1 << TCP_ESTABLISHED;
+
+  ASSERT_NO_ERRNO(NetlinkRequestResponse(
+      fd, &req, sizeof(req),
+      [&](const struct nlmsghdr* hdr) {
+        const struct inet_diag_msg* msg =
+            reinterpret_cast<const struct inet_diag_msg*>(NLMSG_DATA(hdr));
+        EXPECT_EQ(msg->idiag_state, TCP_ESTABLISHED);
+        EXPECT_NE(msg->id.idiag_sport, port);
+      },
+      false));
+}
+
+TEST(NetlinkSockDiagTest, GetUDPSocket) {
+  FileDescriptor sock =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_DGRAM, 0));
+  sockaddr_storage addr = InetLoopbackAddr(AF_INET);
+  socklen_t addrlen = sizeof(struct sockaddr_in);
+  ASSERT_THAT(bind(sock.get(), reinterpret_cast<sockaddr*>(&addr), addrlen),
+              SyscallSucceeds());
+  ASSERT_THAT(
+      getsockname(sock.get(), reinterpret_cast<sockaddr*>(&addr), &addrlen),
+      SyscallSucceeds());
+  const sockaddr_in* sin = reinterpret_cast<sockaddr_in*>(&addr);
+  const ino_t ino = ASSERT_NO_ERRNO_AND_VALUE(InodeOf(sock.get()));
+
+  FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(NetlinkBoundSocket(NETLINK_SOCK_DIAG));
+
+  InetDiagRequest req = MakeInetDiagRequest(SOCK_DIAG_BY_FAMILY, 0, IPPROTO_UDP);
+  req.req.id.idiag_sport = sin->sin_port;
+  memcpy(req.req.id.idiag_src, &sin->sin_addr, sizeof(sin->sin_addr));
+
+  bool found = false;
+  ASSERT_NO_ERRNO(NetlinkRequestResponseSingle(
+      fd, &req, sizeof(req), [&](const struct nlmsghdr* hdr) {
+        ASSERT_EQ(hdr->nlmsg_type, SOCK_DIAG_BY_FAMILY);
+        const struct inet_diag_msg* msg =
+            reinterpret_cast<const struct inet_diag_msg*>(NLMSG_DATA(hdr));
+        EXPECT_EQ(msg->idiag_state, TCP_CLOSE);
+        EXPECT_EQ(msg->id.idiag_sport, sin->sin_port);
+        EXPECT_EQ(msg->idiag_inode, ino);
+        found = true;
+      }));
+  EXPECT_TRUE(found);
+
+  // Looking up the socket with a wrong cookie fails.
+  req.req.id.idiag_cookie[0] = 0;
+  req.req.id.idiag_cookie[1] = 0x80000000;
+  EXPECT_THAT(NetlinkRequestAckOrError(fd, kSeq, &req, sizeof(req)),
+              PosixErrorIs(ESTALE, ::testing::_));
+}
+
+TEST(NetlinkSockDiagTest, GetMissingSocket) {
+  FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(NetlinkBoundSocket(NETLINK_SOCK_DIAG));
+
+  // Port 0 is never bound.
+  InetDiagRequest req = MakeInetDiagRequest(SOCK_DIAG_BY_FAMILY, 0, IPPROTO_UDP);
+  EXPECT_THAT(NetlinkRequestAckOrError(fd, kSeq, &req, sizeof(req)),
+              PosixErrorIs(ENOENT, ::testing::_));
+}
+
+TEST(NetlinkSockDiagTest, DestroyTCPSocket) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_NET_ADMIN)));
+  // SOCK_DESTROY depends on CONFIG_INET_DIAG_DESTROY on Linux.
+  SKIP_IF(!IsRunningOnGvisor());
+
+  FileDescriptor listener =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_STREAM, 0));
+  sockaddr_storage addr = InetLoopbackAddr(AF_INET);
+  socklen_t addrlen = sizeof(struct sockaddr_in);
+  ASSERT_THAT(
+      bind(listener.get(), reinterpret_cast<sockaddr*>(&addr), addrlen),
+      SyscallSucceeds());
+  ASSERT_THAT(listen(listener.get(), 5), SyscallSucceeds());
+  ASSERT_THAT(
+      getsockname(listener.get(), reinterpret_cast<sockaddr*>(&addr), &addrlen),
+      SyscallSucceeds());
+
+  FileDescriptor client =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_STREAM, 0));
+  ASSERT_THAT(
+      connect(client.get(), reinterpret_cast<sockaddr*>(&addr), addrlen),
+      SyscallSucceeds());
+  FileDescriptor accepted =
+      ASSERT_NO_ERRNO_AND_VALUE(Accept(listener.get(), nullptr, nullptr));
+
+  sockaddr_storage local;
+  socklen_t locallen = sizeof(local);
+  ASSERT_THAT(
+      getsockname(client.get(), reinterpret_cast<sockaddr*>(&local), &locallen),
+      SyscallSucceeds());
+  const sockaddr_in* src = reinterpret_cast<sockaddr_in*>(&local);
+  const sockaddr_in* dst = reinterpret_cast<sockaddr_in*>(&addr);
+
+  FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(NetlinkBoundSocket(NETLINK_SOCK_DIAG));
+
+  InetDiagRequest req =
+      MakeInetDiagRequest(SOCK_DESTROY, NLM_F_ACK, IPPROTO_TCP);
+  req.req.id.idiag_sport = src->sin_port;
+  req.req.id.idiag_dport = dst->sin_port;
+  memcpy(req.req.id.idiag_src, &src->sin_addr, sizeof(src->sin_addr));
+  memcpy(req.req.id.idiag_dst, &dst->sin_addr, sizeof(dst->sin_addr));
+  ASSERT_NO_ERRNO(NetlinkRequestAckOrError(fd, kSeq, &req, sizeof(req)));
+
+  char buf;
+  EXPECT_THAT(read(client.get(), &buf, sizeof(buf)),
+              SyscallFailsWithErrno(ECONNABORTED));
+}
+
+TEST(NetlinkSockDiagTest, DestroyUDPSocket) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_NET_ADMIN)));
+  // SOCK_DESTROY depends on CONFIG_INET_DIAG_DESTROY on Linux.
+  SKIP_IF(!IsRunningOnGvisor());
+
+  FileDescriptor server =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_DGRAM, 0));
+  sockaddr_storage addr = InetLoopbackAddr(AF_INET);
+  socklen_t addrlen = sizeof(struct sockaddr_in);
+  ASSERT_THAT(bind(server.get(), reinterpret_cast<sockaddr*>(&addr), addrlen),
+              SyscallSucceeds());
+  ASSERT_THAT(
+      getsockname(server.get(), reinterpret_cast<sockaddr*>(&addr), &addrlen),
+      SyscallSucceeds());
+
+  FileDescriptor client =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_DGRAM, 0));
+  ASSERT_THAT(
+      connect(client.get(), reinterpret_cast<sockaddr*>(&addr), addrlen),
+      SyscallSucceeds());
+
+  sockaddr_storage local;
+  socklen_t locallen = sizeof(local);
+  ASSERT_THAT(
+      getsockname(client.get(), reinterpret_cast<sockaddr*>(&local), &locallen),
+      SyscallSucceeds());
+  const sockaddr_in* src = reinterpret_cast<sockaddr_in*>(&local);
+  const sockaddr_in* dst = reinterpret_cast<sockaddr_in*>(&addr);
+
+  FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(NetlinkBoundSocket(NETLINK_SOCK_DIAG));
+
+  InetDiagRequest req =
+      MakeInetDiagRequest(SOCK_DESTROY, NLM_F_ACK, IPPROTO_UDP);
+  req.req.id.idiag_sport = src->sin_port;
+  req.req.id.idiag_dport = dst->sin_port;
+  memcpy(req.req.id.idiag_src, &src->sin_addr, sizeof(src->sin_addr));
+  memcpy(req.req.id.idiag_dst, &dst->sin_addr, sizeof(dst->sin_addr));
+  ASSERT_NO_ERRNO(NetlinkRequestAckOrError(fd, kSeq, &req, sizeof(req)));
+
+  // Like Linux's udp_abort(), the error is reported once and the socket is
+  // disconnected but remains usable.
+  char buf;
+  EXPECT_THAT(recv(client.get(), &buf, sizeof(buf), MSG_DONTWAIT),
+              SyscallFailsWithErrno(ECONNABORTED));
+  EXPECT_THAT(recv(client.get(), &buf, sizeof(buf), MSG_DONTWAIT),
+              SyscallFailsWithErrno(EAGAIN));
+  sockaddr_storage peer;
+  socklen_t peerlen = sizeof(peer);
+  EXPECT_THAT(
+      getpeername(client.get(), reinterpret_cast<sockaddr*>(&peer), &peerlen),
+      SyscallFailsWithErrno(ENOTCONN));
+
+  constexpr char kData = 'a';
+  ASSERT_THAT(sendto(client.get(), &kData, sizeof(kData), 0,
+                     reinterpret_cast<sockaddr*>(&addr), addrlen),
+              SyscallSucceedsWithValue(sizeof(kData)));
+  EXPECT_THAT(recv(server.get(), &buf, sizeof(buf), 0),
+              SyscallSucceedsWithValue(sizeof(buf)));
+  EXPECT_EQ(buf, kData);
+}
+
+TEST(NetlinkSockDiagTest, UnixPeerAndQueue) {
+  int fds[2];
+  ASSERT_THAT(socketpair(AF_UNIX, SOCK_STREAM, 0, fds), SyscallSucceeds());
+  FileDescriptor a(fds[0]);
+  FileDescriptor b(fds[1]);
+  const ino_t a_ino = ASSERT_NO_ERRNO_AND_VALUE(InodeOf(a.get()));
+  const ino_t b_ino = ASSERT_NO_ERRNO_AND_VALUE(InodeOf(b.get()));
+
+  constexpr char kData[] = "hello";
+  ASSERT_THAT(write(b.get(), kData, sizeof(kData)),
+              SyscallSucceedsWithValue(sizeof(kData)));
+
+  FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(NetlinkBoundSocket(NETLINK_SOCK_DIAG));
+
+  struct request {
+    struct nlmsghdr hdr;
+    struct unix_diag_req req;
+  } req = {};
+  req.hdr.nlmsg_len = sizeof(req);
+  req.hdr.nlmsg_type = SOCK_DIAG_BY_FAMILY;
+  req.hdr.nlmsg_flags = NLM_F_REQUEST | NLM_F_DUMP;
+  req.hdr.nlmsg_seq = kSeq;
+  req.req.sdiag_family = AF_UNIX;
+  req.req.udiag_states = ~0U;
+  req.req.udiag_show = UDIAG_SHOW_PEER | UDIAG_SHOW_RQLEN;
+
+  bool found = false;
+  ASSERT_NO_ERRNO(NetlinkRequestResponse(
+      fd, &req, sizeof(req),
+      [&](const struct nlmsghdr* hdr) {
+        ASSERT_EQ(hdr->nlmsg_type, SOCK_DIAG_BY_FAMILY);
+        const struct unix_diag_msg* msg =
+            reinterpret_cast<const struct unix_diag_msg*>(NLMSG_DATA(hdr));
+        if (msg->udiag_ino != a_ino) {
+          return;
+        }
+        found = true;
+        EXPECT_EQ(msg->udiag_type, SOCK_STREAM);
+        EXPECT_EQ(msg->udiag_state, TCP_ESTABLISHED);
+
+        const struct rtattr* peer =
+            FindDiagAttr(hdr, sizeof(*msg), UNIX_DIAG_PEER);
+        ASSERT_NE(peer, nullptr);
+        EXPECT_EQ(*reinterpret_cast<const uint32_t*>(RTA_DATA(peer)), b_ino);
+
+        const struct rtattr* rqlen =
+            FindDiagAttr(hdr, sizeof(*msg), UNIX_DIAG_RQLEN);
+        ASSERT_NE(rqlen, nullptr);
+        const struct unix_diag_rqlen* q =
+            reinterpret_cast<const struct unix_diag_rqlen*>(RTA_DATA(rqlen));
+        EXPECT_EQ(q->udiag_rqueue, sizeof(kData));
+      },
+      false));
+  EXPECT_TRUE(found);
+}
+
+TEST(NetlinkSockDiagTest, UnixDestroyNotSupported) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_NET_ADMIN)));
+
+  FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(NetlinkBoundSocket(NETLINK_SOCK_DIAG));
+
+  struct request {
+    struct nlmsghdr hdr;
+    struct unix_diag_req req;
+  } req = {};
+  req.hdr.nlmsg_len = sizeof(req);
+  req.hdr.nlmsg_type = SOCK_DESTROY;
+  req.hdr.nlmsg_flags = NLM_F_REQUEST | NLM_F_ACK;
+  req.hdr.nlmsg_seq = kSeq;
+  req.req.sdiag_family = AF_UNIX;
+  EXPECT_THAT(NetlinkRequestAckOrError(fd, kSeq, &req, sizeof(req)),
+              PosixErrorIs(EOPNOTSUPP, ::testing::_));
+}
+
+}  // namespace
+
+}  // namespace testing
+}  // namespace gvisor
@@ -236,7 +236,8 @@ const struct nfattr* FindNfAttr(const struct nlmsghdr* hdr,
   const struct nfattr* nfa = reinterpret_cast<const struct nfattr*>(
       reinterpret_cast<const uint8_t*>(hdr) + NLMSG_ALIGN(nf_space));
   for (; NFA_OK(nfa, attrlen); nfa = NFA_NEXT(nfa, attrlen)) {
-    if (nfa->nfa_type == attr) {
+    // Nested attributes are marked with NLA_F_NESTED.
+    if ((nfa->nfa_type & NLA_TYPE_MASK) == attr) {
       return nfa;
     }
   }
@@ -0,0 +1,463 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include <linux/errqueue.h>
+#include <linux/net_tstamp.h>
+#include <netinet/in.h>
+#include <poll.h>
+#include <string.h>
+#include <sys/socket.h>
+#include <sys/uio.h>
+#include <time.h>
+#include <unistd.h>
+
+#include <cerrno>
+#include <cstdint>
+#include <utility>
+
+#include "gmock/gmock.h"
+#include "gtest/gtest.h"
+#include "absl/time/clock.h"
+#include "absl/time/time.h"
+#include "test/util/capability_util.h"
+#include "test/util/file_descriptor.h"
+#include "test/util/posix_error.h"
+#include "test/util/socket_util.h"
+#include "test/util/test_util.h"
+
+#ifndef SO_TXTIME
+#define SO_TXTIME 61
+#define SCM_TXTIME SO_TXTIME
+#endif
+
+namespace gvisor {
+namespace testing {
+
+namespace {
+
+constexpr char kData[] = "timestamped data";
+
+// SocketPair is a pair of connected sockets.
+struct SocketPair {
+  FileDescriptor receiver;
+  FileDescriptor sender;
+};
+
+sockaddr_in LoopbackAddr() {
+  sockaddr_in addr = {};
+  addr.sin_family = AF_INET;
+  addr.sin_addr.s_addr = htonl(INADDR_LOOPBACK);
+  return addr;
+}
+
+PosixErrorOr<SocketPair> NewTCPPair() {
+  ASSIGN_OR_RETURN_ERRNO(FileDescriptor listener,
+                         Socket(AF_INET, SOCK_STREAM, 0));
+  sockaddr_in addr = LoopbackAddr();
+  RETURN_ERROR_IF_SYSCALL_FAIL(
+      bind(listener.get(), reinterpret_cast<sockaddr*>(&addr), sizeof(addr)));
+  RETURN_ERROR_IF_SYSCALL_FAIL(listen(listener.get(), 1));
+  socklen_t addrlen = sizeof(addr);
+  RETURN_ERROR_IF_SYSCALL_FAIL(getsockname(
+      listener.get(), reinterpret_cast<sockaddr*>(&addr), &addrlen));
+
+  ASSIGN_OR_RETURN_ERRNO(FileDescriptor sender,
+                         Socket(AF_INET, SOCK_STREAM, 0));
+  RETURN_ERROR_IF_SYSCALL_FAIL(
+      connect(sender.get(), reinterpret_cast<sockaddr*>(&addr), sizeof(addr)));
+  ASSIGN_OR_RETURN_ERRNO(FileDescriptor receiver,
+                         Accept(listener.get(), nullptr, nullptr));
+  return SocketPair{std::move(receiver), std::move(sender)};
+}
+
+PosixErrorOr<SocketPair> NewUDPPair() {
+  ASSIGN_OR_RETURN_ERRNO(FileDescriptor receiver,
+                         Socket(AF_INET, SOCK_DGRAM, 0));
+  sockaddr_in addr = LoopbackAddr();
+  RETURN_ERROR_IF_SYSCALL_FAIL(
+      bind(receiver.get(), reinterpret_cast<sockaddr*>(&addr), sizeof(addr)));
+  socklen_t addrlen = sizeof(addr);
+  RETURN_ERROR_IF_SYSCALL_FAIL(getsockname(
+      receiver.get(), reinterpret_cast<sockaddr*>(&addr), &addrlen));
+
+  ASSIGN_OR_RETURN_ERRNO(FileDescriptor sender,
+                         Socket(AF_INET, SOCK_DGRAM, 0));
+  RETURN_ERROR_IF_SYSCALL_FAIL(
+      connect(sender.get(), reinterpret_cast<sockaddr*>(&addr), sizeof(addr)));
+  return SocketPair{std::move(receiver), std::move(sender)};
+}
+
+PosixError SetTimestamping(int fd, int flags) {
+  RETURN_ERROR_IF_SYSCALL_FAIL(
+      setsockopt(fd, SOL_SOCKET, SO_TIMESTAMPING, &flags, sizeof(flags)));
+  return NoError();
+}
+
+// TXTimestamp is a TX timestamp read from the error queue.
+struct TXTimestamp {
+  sock_extended_err ee;
+  absl::Time ts;
+};
+
+// RecvTXTimestamp waits for and returns the next TX timestamp queued on fd.
+PosixErrorOr<TXTimestamp> RecvTXTimestamp(int fd) {
+  struct pollfd pfd = {};
+  pfd.fd = fd;
+  constexpr int kTimeoutMs = 10000;
+  const int n = RetryEINTR(poll)(&pfd, 1, kTimeoutMs);
+  if (n < 0) {
+    return PosixError(errno, "poll");
+  }
+  if (n != 1 || !(pfd.revents & POLLERR)) {
+    return PosixError(ETIMEDOUT, "no TX timestamp");
+  }
+
+  char cmsgbuf[CMSG_SPACE(sizeof(scm_timestamping)) +
+               CMSG_SPACE(sizeof(sock_extended_err) + sizeof(sockaddr_in))];
+  msghdr msg = {};
+  msg.msg_control = cmsgbuf;
+  msg.msg_controllen = sizeof(cmsgbuf);
+  RETURN_ERROR_IF_SYSCALL_FAIL(RetryEINTR(recvmsg)(fd, &msg, MSG_ERRQUEUE));
+
+  TXTimestamp ret = {};
+  bool has_ts = false;
+  bool has_ee = false;
+  for (cmsghdr* cmsg = CMSG_FIRSTHDR(&msg); cmsg != nullptr;
+       cmsg = CMSG_NXTHDR(&msg, cmsg)) {
+    if (cmsg->cmsg_level == SOL_SOCKET &&
+        cmsg->cmsg_type == SCM_TIMESTAMPING) {
+      scm_timestamping tss;
+      memcpy(&tss, CMSG_DATA(cmsg), sizeof(tss));
+      ret.ts = absl::TimeFromTimespec(tss.ts[0]);
+      has_ts = true;
+    } else if (cmsg->cmsg_level == SOL_IP && cmsg->cmsg_type == IP_RECVERR) {
+      memcpy(&ret.ee, CMSG_DATA(cmsg), sizeof(ret.ee));
+      has_ee = true;
+    }
+  }
+  if (!has_ts || !has_ee) {
+    return PosixError(EINVAL, "missing control message");
+  }
+  return ret;
+}
+
+TEST(SocketTimestampingTest, SetAndGet) {
+  SKIP_IF(IsRunningWithHostinet());
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_DGRAM, 0));
+
+  constexpr int kFlags = SOF_TIMESTAMPING_SOFTWARE |
+                         SOF_TIMESTAMPING_RX_SOFTWARE |
+                         SOF_TIMESTAMPING_TX_SOFTWARE;
+  ASSERT_NO_ERRNO(SetTimestamping(fd.get(), kFlags));
+
+  int v = 0;
+  socklen_t len = sizeof(v);
+  ASSERT_THAT(getsockopt(fd.get(), SOL_SOCKET, SO_TIMESTAMPING, &v, &len),
+              SyscallSucceeds());
+  EXPECT_EQ(len, sizeof(v));
+  EXPECT_EQ(v, kFlags);
+
+  // struct so_timestamping is accepted too.
+  so_timestamping ts = {};
+  ts.flags = SOF_TIMESTAMPING_SOFTWARE;
+  ASSERT_THAT(setsockopt(fd.get(), SOL_SOCKET, SO_TIMESTAMPING, &ts,
+                         sizeof(ts)),
+              SyscallSucceeds());
+  ts = {};
+  len = sizeof(ts);
+  ASSERT_THAT(getsockopt(fd.get(), SOL_SOCKET, SO_TIMESTAMPING, &ts, &len),
+              SyscallSucceeds());
+  EXPECT_EQ(len, sizeof(ts));
+  EXPECT_EQ(ts.flags, SOF_TIMESTAMPING_SOFTWARE);
+
+  constexpr int kInvalid = 1 << 30;
+  EXPECT_THAT(setsockopt(fd.get(), SOL_SOCKET, SO_TIMESTAMPING, &kInvalid,
+                         sizeof(kInvalid)),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+TEST(SocketTimestampingTest, TCPOptIDRequiresConnection) {
+  SKIP_IF(IsRunningWithHostinet());
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_STREAM, 0));
+  EXPECT_THAT(SetTimestamping(fd.get(), SOF_TIMESTAMPING_OPT_ID),
+              PosixErrorIs(EINVAL));
+}
+
+TEST(SocketTimestampingTest, UDPRXTimestamp) {
+  SKIP_IF(IsRunningWithHostinet());
+  SocketPair pair = ASSERT_NO_ERRNO_AND_VALUE(NewUDPPair());
+  ASSERT_NO_ERRNO(SetTimestamping(
+      pair.receiver.get(),
+      SOF_TIMESTAMPING_SOFTWARE | SOF_TIMESTAMPING_RX_SOFTWARE));
+
+  const absl::Time before = absl::Now();
+  ASSERT_THAT(send(pair.sender.get(), kData, sizeof(kData), 0),
+              SyscallSucceedsWithValue(sizeof(kData)));
+
+  char buf[sizeof(kData)];
+  iovec iov = {buf, sizeof(buf)};
+  char cmsgbuf[CMSG_SPACE(sizeof(scm_timestamping))];
+  msghdr msg = {};
+  msg.msg_iov = &iov;
+  msg.msg_iovlen = 1;
+  msg.msg_control = cmsgbuf;
+  msg.msg_controllen = sizeof(cmsgbuf);
+  ASSERT_THAT(RetryEINTR(recvmsg)(pair.receiver.get(), &msg, 0),
+              SyscallSucceedsWithValue(sizeof(kData)));
+  const absl::Time after = absl::Now();
+
+  cmsghdr* cmsg = CMSG_FIRSTHDR(&msg);
+  ASSERT_NE(cmsg, nullptr);
+  EXPECT_EQ(cmsg->cmsg_level, SOL_SOCKET);
+  EXPECT_EQ(cmsg->cmsg_type, SCM_TIMESTAMPING);
+  EXPECT_EQ(cmsg->cmsg_len, CMSG_LEN(sizeof(scm_timestamping)));
+
+  scm_timestamping tss;
+  memcpy(&tss, CMSG_DATA(cmsg), sizeof(tss));
+  const absl::Time ts = absl::TimeFromTimespec(tss.ts[0]);
+  EXPECT_LE(before - absl::Milliseconds(1), ts);
+  EXPECT_LE(ts, after + absl::Milliseconds(1));
+  // Hardware timestamps are never reported.
+  EXPECT_EQ(tss.ts[2].tv_sec, 0);
+  EXPECT_EQ(tss.ts[2].tv_nsec, 0);
+}
+
+TEST(SocketTimestampingTest, UDPTXTimestamps) {
+  SKIP_IF(IsRunningWithHostinet());
+  SocketPair pair = ASSERT_NO_ERRNO_AND_VALUE(NewUDPPair());
+  ASSERT_NO_ERRNO(SetTimestamping(
+      pair.sender.get(),
+      SOF_TIMESTAMPING_SOFTWARE | SOF_TIMESTAMPING_TX_SOFTWARE |
+          SOF_TIMESTAMPING_TX_SCHED | SOF_TIMESTAMPING_OPT_ID |
+          SOF_TIMESTAMPING_OPT_TSONLY));
+
+  constexpr uint32_t kSends = 2;
+  for (uint32_t i = 0; i < kSends; i++) {
+    ASSERT_THAT(send(pair.sender.get(), kData, sizeof(kData), 0),
+                SyscallSucceedsWithValue(sizeof(kData)));
+  }
+
+  // Each send is timestamped when it is scheduled, then when it is sent.
+  for (uint32_t i = 0; i < kSends; i++) {
+    for (uint32_t kind : {SCM_TSTAMP_SCHED, SCM_TSTAMP_SND}) {
+      const TXTimestamp ts =
+          ASSERT_NO_ERRNO_AND_VALUE(RecvTXTimestamp(pair.sender.get()));
+      EXPECT_EQ(ts.ee.ee_errno, ENOMSG);
+      EXPECT_EQ(ts.ee.ee_origin, SO_EE_ORIGIN_TIMESTAMPING);
+      EXPECT_EQ(ts.ee.ee_info, kind);
+      EXPECT_EQ(ts.ee.ee_data, i);
+      EXPECT_NE(ts.ts, absl::UnixEpoch());
+    }
+  }
+
+  msghdr msg = {};
+  EXPECT_THAT(recvmsg(pair.sender.get(), &msg, MSG_ERRQUEUE | MSG_DONTWAIT),
+              SyscallFailsWithErrno(EAGAIN));
+}
+
+TEST(SocketTimestampingTest, UDPTXTimestampWithPayload) {
+  SKIP_IF(IsRunningWithHostinet());
+  SocketPair pair = ASSERT_NO_ERRNO_AND_VALUE(NewUDPPair());
+  ASSERT_NO_ERRNO(SetTimestamping(
+      pair.sender.get(),
+      SOF_TIMESTAMPING_SOFTWARE | SOF_TIMESTAMPING_TX_SOFTWARE));
+
+  ASSERT_THAT(send(pair.sender.get(), kData, sizeof(kData), 0),
+              SyscallSucceedsWithValue(sizeof(kData)));
+
+  struct pollfd pfd = {};
+  pfd.fd = pair.sender.get();
+  ASSERT_THAT(RetryEINTR(poll)(&pfd, 1, 10000), SyscallSucceedsWithValue(1));
+  ASSERT_TRUE(pfd.revents & POLLERR);
+
+  // The timestamped packet is returned with the timestamp.
+  char buf[1024];
+  iovec iov = {buf, sizeof(buf)};
+  char cmsgbuf[1024];
+  msghdr msg = {};
+  msg.msg_iov = &iov;
+  msg.msg_iovlen = 1;
+  msg.msg_control = cmsgbuf;
+  msg.msg_controllen = sizeof(cmsgbuf);
+  ASSERT_THAT(RetryEINTR(recvmsg)(pair.sender.get(), &msg, MSG_ERRQUEUE),
+              SyscallSucceedsWithValue(::testing::Ge(sizeof(kData))));
+  EXPECT_TRUE(msg.msg_flags & MSG_ERRQUEUE);
+}
+
+TEST(SocketTimestampingTest, TCPAckTimestamp) {
+  SKIP_IF(IsRunningWithHostinet());
+  SocketPair pair = ASSERT_NO_ERRNO_AND_VALUE(NewTCPPair());
+  ASSERT_NO_ERRNO(SetTimestamping(
+      pair.sender.get(), SOF_TIMESTAMPING_SOFTWARE | SOF_TIMESTAMPING_TX_ACK |
+                             SOF_TIMESTAMPING_OPT_ID |
+                             SOF_TIMESTAMPING_OPT_TSONLY));
+
+  ASSERT_THAT(send(pair.sender.get(), kData, sizeof(kData), 0),
+              SyscallSucceedsWithValue(sizeof(kData)));
+  char buf[sizeof(kData)];
+  ASSERT_THAT(
+      RetryEINTR(recv)(pair.receiver.get(), buf, sizeof(buf), MSG_WAITALL),
+      SyscallSucceedsWithValue(sizeof(kData)));
+
+  // The ID of TCP timestamps is the offset of the last byte of the send.
+  const TXTimestamp ts =
+      ASSERT_NO_ERRNO_AND_VALUE(RecvTXTimestamp(pair.sender.get()));
+  EXPECT_EQ(ts.ee.ee_errno, ENOMSG);
+  EXPECT_EQ(ts.ee.ee_origin, SO_EE_ORIGIN_TIMESTAMPING);
+  EXPECT_EQ(ts.ee.ee_info, SCM_TSTAMP_ACK);
+  EXPECT_EQ(ts.ee.ee_data, sizeof(kData) - 1);
+}
+
+TEST(SocketTimestampingTest, TCPRXTimestamp) {
+  SKIP_IF(IsRunningWithHostinet());
+  SocketPair pair = ASSERT_NO_ERRNO_AND_VALUE(NewTCPPair());
+  ASSERT_NO_ERRNO(SetTimestamping(
+      pair.receiver.get(),
+      SOF_TIMESTAMPING_SOFTWARE | SOF_TIMESTAMPING_RX_SOFTWARE));
+
+  ASSERT_THAT(send(pair.sender.get(), kData, sizeof(kData), 0),
+              SyscallSucceedsWithValue(sizeof(kData)));
+
+  char buf[sizeof(kData)];
+  iovec iov = {buf, sizeof(buf)};
+  char cmsgbuf[CMSG_SPACE(sizeof(scm_timestamping))];
+  msghdr msg = {};
+  msg.msg_iov = &iov;
+  msg.msg_iovlen = 1;
+  msg.msg_control = cmsgbuf;
+  msg.msg_controllen = sizeof(cmsgbuf);
+  ASSERT_THAT(RetryEINTR(recvmsg)(pair.receiver.get(), &msg, MSG_WAITALL),
+              SyscallSucceedsWithValue(sizeof(kData)));
+
+  cmsghdr* cmsg = CMSG_FIRSTHDR(&msg);
+  ASSERT_NE(cmsg, nullptr);
+  EXPECT_EQ(cmsg->cmsg_level, SOL_SOCKET);
+  EXPECT_EQ(cmsg->cmsg_type, SCM_TIMESTAMPING);
+}
+
+TEST(SocketTXTimeTest, SetAndGet) {
+  SKIP_IF(IsRunningWithHostinet());
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_DGRAM, 0));
+
+  sock_txtime txtime = {};
+  txtime.clockid = CLOCK_MONOTONIC;
+  txtime.flags = SOF_TXTIME_REPORT_ERRORS;
+  ASSERT_THAT(
+      setsockopt(fd.get(), SOL_SOCKET, SO_TXTIME, &txtime, sizeof(txtime)),
+      SyscallSucceeds());
+
+  sock_txtime got = {};
+  socklen_t len = sizeof(got);
+  ASSERT_THAT(getsockopt(fd.get(), SOL_SOCKET, SO_TXTIME, &got, &len),
+              SyscallSucceeds());
+  EXPECT_EQ(len, sizeof(got));
+  EXPECT_EQ(got.clockid, CLOCK_MONOTONIC);
+  EXPECT_EQ(got.flags, SOF_TXTIME_REPORT_ERRORS);
+}
+
+TEST(SocketTXTimeTest, Invalid) {
+  SKIP_IF(IsRunningWithHostinet());
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_DGRAM, 0));
+
+  sock_txtime txtime = {};
+  txtime.clockid = CLOCK_MONOTONIC;
+  EXPECT_THAT(setsockopt(fd.get(), SOL_SOCKET, SO_TXTIME, &txtime,
+                         sizeof(txtime) - 1),
+              SyscallFailsWithErrno(EINVAL));
+
+  txtime.flags = 1 << 10;
+  EXPECT_THAT(
+      setsockopt(fd.get(), SOL_SOCKET, SO_TXTIME, &txtime, sizeof(txtime)),
+      SyscallFailsWithErrno(EINVAL));
+}
+
+// CLOCK_TAI is unimplemented in gVisor, so transmit times can't be converted
+// from it.
+TEST(SocketTXTimeTest, TAIUnsupported) {
+  SKIP_IF(IsRunningWithHostinet());
+  SKIP_IF(!IsRunningOnGvisor());
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_NET_ADMIN)));
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_DGRAM, 0));
+
+  sock_txtime txtime = {};
+  txtime.clockid = CLOCK_TAI;
+  EXPECT_THAT(
+      setsockopt(fd.get(), SOL_SOCKET, SO_TXTIME, &txtime, sizeof(txtime)),
+      SyscallFailsWithErrno(EINVAL));
+}
+
+// SendWithTXTime sends kData with the given SCM_TXTIME.
+ssize_t SendWithTXTime(int fd, uint64_t txtime) {
+  iovec iov = {const_cast<char*>(kData), sizeof(kData)};
+  char cmsgbuf[CMSG_SPACE(sizeof(txtime))] = {};
+  msghdr msg = {};
+  msg.msg_iov = &iov;
+  msg.msg_iovlen = 1;
+  msg.msg_control = cmsgbuf;
+  msg.msg_controllen = sizeof(cmsgbuf);
+  cmsghdr* cmsg = CMSG_FIRSTHDR(&msg);
+  cmsg->cmsg_level = SOL_SOCKET;
+  cmsg->cmsg_type = SCM_TXTIME;
+  cmsg->cmsg_len = CMSG_LEN(sizeof(txtime));
+  memcpy(CMSG_DATA(cmsg), &txtime, sizeof(txtime));
+  return sendmsg(fd, &msg, 0);
+}
+
+uint64_t MonotonicNowNs() {
+  struct timespec ts;
+  TEST_PCHECK(clock_gettime(CLOCK_MONOTONIC, &ts) == 0);
+  return absl::ToInt64Nanoseconds(absl::DurationFromTimespec(ts));
+}
+
+TEST(SocketTXTimeTest, RequiresSockOpt) {
+  SKIP_IF(IsRunningWithHostinet());
+  SocketPair pair = ASSERT_NO_ERRNO_AND_VALUE(NewUDPPair());
+  EXPECT_THAT(SendWithTXTime(pair.sender.get(), MonotonicNowNs()),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+TEST(SocketTXTimeTest, DelaysTransmission) {
+  // Linux only honors SO_TXTIME with the etf and fq qdiscs, which loopback
+  // doesn't use by default.
+  SKIP_IF(!IsRunningOnGvisor() || IsRunningWithHostinet());
+  SocketPair pair = ASSERT_NO_ERRNO_AND_VALUE(NewUDPPair());
+
+  sock_txtime txtime = {};
+  txtime.clockid = CLOCK_MONOTONIC;
+  ASSERT_THAT(setsockopt(pair.sender.get(), SOL_SOCKET, SO_TXTIME, &txtime,
+                         sizeof(txtime)),
+              SyscallSucceeds());
+
+  constexpr absl::Duration kDelay = absl::Milliseconds(500);
+  const absl::Time start = absl::Now();
+  ASSERT_THAT(SendWithTXTime(pair.sender.get(),
+                             MonotonicNowNs() +
+                                 absl::ToInt64Nanoseconds(kDelay)),
+              SyscallSucceedsWithValue(sizeof(kData)));
+
+  char buf[sizeof(kData)];
+  ASSERT_THAT(RetryEINTR(recv)(pair.receiver.get(), buf, sizeof(buf), 0),
+              SyscallSucceedsWithValue(sizeof(kData)));
+  EXPECT_GE(absl::Now() - start, kDelay - absl::Milliseconds(10));
+}
+
+}  // namespace
+
+}  // namespace testing
+}  // namespace gvisor
@@ -14,9 +14,11 @@
 
 #include <fcntl.h>
 #include <linux/unistd.h>
+#include <netinet/in.h>
 #include <sys/eventfd.h>
 #include <sys/resource.h>
 #include <sys/sendfile.h>
+#include <sys/socket.h>
 #include <sys/time.h>
 #include <unistd.h>
 
@@ -29,6 +31,7 @@
 #include "test/util/file_descriptor.h"
 #include "test/util/memory_util.h"
 #include "test/util/signal_util.h"
+#include "test/util/socket_util.h"
 #include "test/util/temp_path.h"
 #include "test/util/test_util.h"
 #include "test/util/thread_util.h"
@@ -946,6 +949,58 @@ TEST(SpliceTest, FromPipeWithWriterToDevNull) {
       SyscallFailsWithErrno(EAGAIN));
 }
 
+TEST(SpliceTest, TCPSocketThroughPipe) {
+  // Set up a connected pair of loopback TCP sockets.
+  FileDescriptor listener =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_STREAM, 0));
+  struct sockaddr_in addr = {};
+  addr.sin_family = AF_INET;
+  addr.sin_addr.s_addr = htonl(INADDR_LOOPBACK);
+  socklen_t addrlen = sizeof(addr);
+  ASSERT_THAT(bind(listener.get(), reinterpret_cast<struct sockaddr*>(&addr),
+                   addrlen),
+              SyscallSucceeds());
+  ASSERT_THAT(listen(listener.get(), 1), SyscallSucceeds());
+  ASSERT_THAT(getsockname(listener.get(),
+                          reinterpret_cast<struct sockaddr*>(&addr), &addrlen),
+              SyscallSucceeds());
+  FileDescriptor client =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_STREAM, 0));
+  ASSERT_THAT(connect(client.get(), reinterpret_cast<struct sockaddr*>(&addr),
+                      addrlen),
+              SyscallSucceeds());
+  FileDescriptor server =
+      ASSERT_NO_ERRNO_AND_VALUE(Accept(listener.get(), nullptr, nullptr));
+
+  int fds[2];
+  ASSERT_THAT(pipe(fds), SyscallSucceeds());
+  const FileDescriptor rfd(fds[0]);
+  const FileDescriptor wfd(fds[1]);
+
+  std::vector<char> buf(4 * kPageSize);
+  RandomizeBuffer(buf.data(), buf.size());
+  ASSERT_THAT(WriteFd(client.get(), buf.data(), buf.size()),
+              SyscallSucceedsWithValue(buf.size()));
+
+  // Echo the data back to the client through the pipe.
+  size_t done = 0;
+  while (done < buf.size()) {
+    int n;
+    ASSERT_THAT(n = splice(server.get(), nullptr, wfd.get(), nullptr,
+                           buf.size() - done, 0),
+                SyscallSucceeds());
+    ASSERT_GT(n, 0);
+    ASSERT_THAT(splice(rfd.get(), nullptr, server.get(), nullptr, n, 0),
+                SyscallSucceedsWithValue(n));
+    done += n;
+  }
+
+  std::vector<char> rbuf(buf.size());
+  ASSERT_THAT(ReadFd(client.get(), rbuf.data(), rbuf.size()),
+              SyscallSucceedsWithValue(rbuf.size()));
+  EXPECT_EQ(rbuf, buf);
+}
+
 }  // namespace
 
 }  // namespace testing
@@ -0,0 +1,289 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include <fcntl.h>
+#include <netinet/in.h>
+#include <netinet/tcp.h>
+#include <string.h>
+#include <sys/socket.h>
+#include <unistd.h>
+
+#include <string>
+
+#include "gmock/gmock.h"
+#include "gtest/gtest.h"
+#include "absl/strings/str_cat.h"
+#include "test/util/capability_util.h"
+#include "test/util/cleanup.h"
+#include "test/util/file_descriptor.h"
+#include "test/util/fs_util.h"
+#include "test/util/posix_error.h"
+#include "test/util/socket_util.h"
+#include "test/util/test_util.h"
+
+#ifndef TCPI_OPT_SYN_DATA
+#define TCPI_OPT_SYN_DATA 32
+#endif
+
+namespace gvisor {
+namespace testing {
+
+namespace {
+
+constexpr char kTCPFastOpen[] = "/proc/sys/net/ipv4/tcp_fastopen";
+
+// Values for /proc/sys/net/ipv4/tcp_fastopen from include/net/tcp.h.
+constexpr int kTFOClientEnable = 0x1;
+constexpr int kTFOServerEnable = 0x2;
+
+constexpr char kData[] = "fastopen";
+
+// SetTCPFastOpen sets /proc/sys/net/ipv4/tcp_fastopen to mode and returns a
+// Cleanup that restores its previous value.
+PosixErrorOr<Cleanup> SetTCPFastOpen(int mode) {
+  ASSIGN_OR_RETURN_ERRNO(std::string old, GetContents(kTCPFastOpen));
+  RETURN_IF_ERRNO(SetContents(kTCPFastOpen, absl::StrCat(mode)));
+  return Cleanup([old] { SetContents(kTCPFastOpen, old).IgnoreError(); });
+}
+
+// Listen returns a TCP socket listening on an ephemeral loopback port with a
+// TCP Fast Open queue, and sets addr to its address.
+PosixErrorOr<FileDescriptor> Listen(sockaddr_in* addr) {
+  ASSIGN_OR_RETURN_ERRNO(FileDescriptor fd, Socket(AF_INET, SOCK_STREAM, 0));
+  memset(addr, 0, sizeof(*addr));
+  addr->sin_family = AF_INET;
+  addr->sin_addr.s_addr = htonl(INADDR_LOOPBACK);
+  RETURN_ERROR_IF_SYSCALL_FAIL(
+      bind(fd.get(), reinterpret_cast<sockaddr*>(addr), sizeof(*addr)));
+  socklen_t addrlen = sizeof(*addr);
+  RETURN_ERROR_IF_SYSCALL_FAIL(
+      getsockname(fd.get(), reinterpret_cast<sockaddr*>(addr), &addrlen));
+  constexpr int kQueueLen = 5;
+  RETURN_ERROR_IF_SYSCALL_FAIL(setsockopt(fd.get(), IPPROTO_TCP, TCP_FASTOPEN,
+                                          &kQueueLen, sizeof(kQueueLen)));
+  RETURN_ERROR_IF_SYSCALL_FAIL(listen(fd.get(), kQueueLen));
+  return fd;
+}
+
+// ExpectSynData checks whether TCP_INFO reports that data in the SYN was
+// acknowledged.
+void ExpectSynData(int fd, bool want) {
+  struct tcp_info info;
+  socklen_t optlen = sizeof(info);
+  ASSERT_THAT(getsockopt(fd, IPPROTO_TCP, TCP_INFO, &info, &optlen),
+              SyscallSucceeds());
+  EXPECT_EQ((info.tcpi_options & TCPI_OPT_SYN_DATA) != 0, want);
+}
+
+// SendFastOpen connects to addr with sendto(MSG_FASTOPEN), and checks that the
+// data is received by the accepted connection.
+void SendFastOpen(int listen_fd, const sockaddr_in& addr, bool want_syn_data) {
+  FileDescriptor client =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_STREAM, 0));
+  ASSERT_THAT(sendto(client.get(), kData, sizeof(kData), MSG_FASTOPEN,
+                     reinterpret_cast<const sockaddr*>(&addr), sizeof(addr)),
+              SyscallSucceedsWithValue(sizeof(kData)));
+
+  FileDescriptor server =
+      ASSERT_NO_ERRNO_AND_VALUE(Accept(listen_fd, nullptr, nullptr));
+  char buf[sizeof(kData)] = {};
+  ASSERT_THAT(RetryEINTR(recv)(server.get(), buf, sizeof(buf), MSG_WAITALL),
+              SyscallSucceedsWithValue(sizeof(buf)));
+  EXPECT_STREQ(buf, kData);
+
+  ExpectSynData(client.get(), want_syn_data);
+  ExpectSynData(server.get(), want_syn_data);
+}
+
+TEST(TCPFastOpenTest, QueueLength) {
+  FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_STREAM, 0));
+  constexpr int kQueueLen = 16;
+  ASSERT_THAT(setsockopt(fd.get(), IPPROTO_TCP, TCP_FASTOPEN, &kQueueLen,
+                         sizeof(kQueueLen)),
+              SyscallSucceeds());
+  int got = 0;
+  socklen_t optlen = sizeof(got);
+  ASSERT_THAT(getsockopt(fd.get(), IPPROTO_TCP, TCP_FASTOPEN, &got, &optlen),
+              SyscallSucceeds());
+  EXPECT_EQ(got, kQueueLen);
+
+  constexpr int kInvalid = -1;
+  EXPECT_THAT(setsockopt(fd.get(), IPPROTO_TCP, TCP_FASTOPEN, &kInvalid,
+                         sizeof(kInvalid)),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+TEST(TCPFastOpenTest, NoCookie) {
+  FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_STREAM, 0));
+  ASSERT_THAT(setsockopt(fd.get(), IPPROTO_TCP, TCP_FASTOPEN_NO_COOKIE,
+                         &kSockOptOn, sizeof(kSockOptOn)),
+              SyscallSucceeds());
+  int got = 0;
+  socklen_t optlen = sizeof(got);
+  ASSERT_THAT(
+      getsockopt(fd.get(), IPPROTO_TCP, TCP_FASTOPEN_NO_COOKIE, &got, &optlen),
+      SyscallSucceeds());
+  EXPECT_EQ(got, kSockOptOn);
+
+  constexpr int kInvalid = 2;
+  EXPECT_THAT(setsockopt(fd.get(), IPPROTO_TCP, TCP_FASTOPEN_NO_COOKIE,
+                         &kInvalid, sizeof(kInvalid)),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+TEST(TCPFastOpenTest, Key) {
+  FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_STREAM, 0));
+  char key[16];
+  for (size_t i = 0; i < sizeof(key); i++) {
+    key[i] = i;
+  }
+  ASSERT_THAT(
+      setsockopt(fd.get(), IPPROTO_TCP, TCP_FASTOPEN_KEY, key, sizeof(key)),
+      SyscallSucceeds());
+  char got[sizeof(key)] = {};
+  socklen_t optlen = sizeof(got);
+  ASSERT_THAT(getsockopt(fd.get(), IPPROTO_TCP, TCP_FASTOPEN_KEY, got, &optlen),
+              SyscallSucceeds());
+  EXPECT_EQ(optlen, sizeof(key));
+  EXPECT_EQ(memcmp(got, key, sizeof(key)), 0);
+
+  EXPECT_THAT(
+      setsockopt(fd.get(), IPPROTO_TCP, TCP_FASTOPEN_KEY, key, sizeof(key) - 1),
+      SyscallFailsWithErrno(EINVAL));
+}
+
+TEST(TCPFastOpenTest, ConnectOption) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_NET_ADMIN)) ||
+          IsRunningWithHostinet());
+  DisableSave ds;  // The sysctl is not saved.
+  Cleanup restore =
+      ASSERT_NO_ERRNO_AND_VALUE(SetTCPFastOpen(kTFOClientEnable));
+
+  FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_STREAM, 0));
+  ASSERT_THAT(setsockopt(fd.get(), IPPROTO_TCP, TCP_FASTOPEN_CONNECT,
+                         &kSockOptOn, sizeof(kSockOptOn)),
+              SyscallSucceeds());
+  int got = 0;
+  socklen_t optlen = sizeof(got);
+  ASSERT_THAT(
+      getsockopt(fd.get(), IPPROTO_TCP, TCP_FASTOPEN_CONNECT, &got, &optlen),
+      SyscallSucceeds());
+  EXPECT_EQ(got, kSockOptOn);
+
+  // TCP_FASTOPEN_CONNECT requires client support.
+  ASSERT_NO_ERRNO(SetContents(kTCPFastOpen, "0"));
+  EXPECT_THAT(setsockopt(fd.get(), IPPROTO_TCP, TCP_FASTOPEN_CONNECT,
+                         &kSockOptOn, sizeof(kSockOptOn)),
+              SyscallFailsWithErrno(EOPNOTSUPP));
+}
+
+TEST(TCPFastOpenTest, SendToWithCookie) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_NET_ADMIN)) ||
+          IsRunningWithHostinet());
+  DisableSave ds;  // The sysctl is not saved.
+  Cleanup restore = ASSERT_NO_ERRNO_AND_VALUE(
+      SetTCPFastOpen(kTFOClientEnable | kTFOServerEnable));
+
+  sockaddr_in addr;
+  FileDescriptor listen_fd = ASSERT_NO_ERRNO_AND_VALUE(Listen(&addr));
+
+  // The first connection only obtains a cookie, and the data is sent once
+  // the connection is established.
+  ASSERT_NO_FATAL_FAILURE(
+      SendFastOpen(listen_fd.get(), addr, /* want_syn_data= */ false));
+
+  // The second connection sends the data in the SYN.
+  ASSERT_NO_FATAL_FAILURE(
+      SendFastOpen(listen_fd.get(), addr, /* want_syn_data= */ true));
+}
+
+TEST(TCPFastOpenTest, ConnectWithCookie) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_NET_ADMIN)) ||
+          IsRunningWithHostinet());
+  DisableSave ds;  // The sysctl is not saved.
+  Cleanup restore = ASSERT_NO_ERRNO_AND_VALUE(
+      SetTCPFastOpen(kTFOClientEnable | kTFOServerEnable));
+
+  sockaddr_in addr;
+  FileDescriptor listen_fd = ASSERT_NO_ERRNO_AND_VALUE(Listen(&addr));
+
+  // Obtain a cookie.
+  ASSERT_NO_FATAL_FAILURE(
+      SendFastOpen(listen_fd.get(), addr, /* want_syn_data= */ false));
+
+  // With a cookie, connect is deferred to the first write, which sends the
+  // data in the SYN.
+  FileDescriptor client =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_STREAM, 0));
+  ASSERT_THAT(setsockopt(client.get(), IPPROTO_TCP, TCP_FASTOPEN_CONNECT,
+                         &kSockOptOn, sizeof(kSockOptOn)),
+              SyscallSucceeds());
+  ASSERT_THAT(connect(client.get(), reinterpret_cast<sockaddr*>(&addr),
+                      sizeof(addr)),
+              SyscallSucceeds());
+  ASSERT_THAT(WriteFd(client.get(), kData, sizeof(kData)),
+              SyscallSucceedsWithValue(sizeof(kData)));
+
+  FileDescriptor server =
+      ASSERT_NO_ERRNO_AND_VALUE(Accept(listen_fd.get(), nullptr, nullptr));
+  char buf[sizeof(kData)] = {};
+  ASSERT_THAT(RetryEINTR(recv)(server.get(), buf, sizeof(buf), MSG_WAITALL),
+              SyscallSucceedsWithValue(sizeof(buf)));
+  EXPECT_STREQ(buf, kData);
+  ExpectSynData(client.get(), true);
+}
+
+TEST(TCPFastOpenTest, SendToConnected) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_NET_ADMIN)) ||
+          IsRunningWithHostinet());
+  DisableSave ds;  // The sysctl is not saved.
+  Cleanup restore = ASSERT_NO_ERRNO_AND_VALUE(
+      SetTCPFastOpen(kTFOClientEnable | kTFOServerEnable));
+
+  sockaddr_in addr;
+  FileDescriptor listen_fd = ASSERT_NO_ERRNO_AND_VALUE(Listen(&addr));
+  FileDescriptor client =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_STREAM, 0));
+  ASSERT_THAT(connect(client.get(), reinterpret_cast<sockaddr*>(&addr),
+                      sizeof(addr)),
+              SyscallSucceeds());
+  EXPECT_THAT(sendto(client.get(), kData, sizeof(kData), MSG_FASTOPEN,
+                     reinterpret_cast<sockaddr*>(&addr), sizeof(addr)),
+              SyscallFailsWithErrno(EISCONN));
+}
+
+TEST(TCPFastOpenTest, SendToClientDisabled) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_NET_ADMIN)) ||
+          IsRunningWithHostinet());
+  DisableSave ds;  // The sysctl is not saved.
+  Cleanup restore = ASSERT_NO_ERRNO_AND_VALUE(SetTCPFastOpen(0));
+
+  sockaddr_in addr = {};
+  addr.sin_family = AF_INET;
+  addr.sin_addr.s_addr = htonl(INADDR_LOOPBACK);
+  FileDescriptor client =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_STREAM, 0));
+  EXPECT_THAT(sendto(client.get(), kData, sizeof(kData), MSG_FASTOPEN,
+                     reinterpret_cast<sockaddr*>(&addr), sizeof(addr)),
+              SyscallFailsWithErrno(EOPNOTSUPP));
+}
+
+}  // namespace
+
+}  // namespace testing
+}  // namespace gvisor
@@ -0,0 +1,253 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include <fcntl.h>
+#include <netinet/in.h>
+#include <netinet/tcp.h>
+#include <poll.h>
+#include <string.h>
+#include <sys/socket.h>
+#include <unistd.h>
+
+#include <cstdint>
+#include <string>
+
+#include "gmock/gmock.h"
+#include "gtest/gtest.h"
+#include "test/util/file_descriptor.h"
+#include "test/util/posix_error.h"
+#include "test/util/socket_util.h"
+#include "test/util/test_util.h"
+
+#ifndef TCP_MD5SIG_EXT
+#define TCP_MD5SIG_EXT 32
+#endif
+
+#ifndef TCP_AO_ADD_KEY
+#define TCP_AO_ADD_KEY 38
+#endif
+
+namespace gvisor {
+namespace testing {
+
+namespace {
+
+// TCPMD5Sig is struct tcp_md5sig, from include/uapi/linux/tcp.h. Older libc
+// headers lack some of its fields.
+struct TCPMD5Sig {
+  sockaddr_storage addr;
+  uint8_t flags;
+  uint8_t prefixlen;
+  uint16_t keylen;
+  int ifindex;
+  uint8_t key[80];
+};
+
+constexpr uint8_t kFlagPrefix = 1;
+
+constexpr char kKey[] = "gvisor-md5-key";
+constexpr char kOtherKey[] = "another-md5-key";
+constexpr char kData[] = "signed";
+
+// MD5Sig returns a struct tcp_md5sig setting key for the IPv4 peer at addr.
+TCPMD5Sig MD5Sig(in_addr_t addr, const std::string& key) {
+  TCPMD5Sig sig = {};
+  sockaddr_in* sin = reinterpret_cast<sockaddr_in*>(&sig.addr);
+  sin->sin_family = AF_INET;
+  sin->sin_addr.s_addr = addr;
+  sig.keylen = key.size();
+  memcpy(sig.key, key.data(), key.size());
+  return sig;
+}
+
+PosixError SetMD5Sig(int fd, const std::string& key) {
+  TCPMD5Sig sig = MD5Sig(htonl(INADDR_LOOPBACK), key);
+  RETURN_ERROR_IF_SYSCALL_FAIL(
+      setsockopt(fd, IPPROTO_TCP, TCP_MD5SIG, &sig, sizeof(sig)));
+  return NoError();
+}
+
+// Listen returns a TCP socket listening on an ephemeral loopback port, signing
+// segments exchanged with loopback peers with key unless it is empty, and sets
+// addr to its address.
+PosixErrorOr<FileDescriptor> Listen(const std::string& key, sockaddr_in* addr) {
+  ASSIGN_OR_RETURN_ERRNO(FileDescriptor fd, Socket(AF_INET, SOCK_STREAM, 0));
+  if (!key.empty()) {
+    RETURN_IF_ERRNO(SetMD5Sig(fd.get(), key));
+  }
+  memset(addr, 0, sizeof(*addr));
+  addr->sin_family = AF_INET;
+  addr->sin_addr.s_addr = htonl(INADDR_LOOPBACK);
+  RETURN_ERROR_IF_SYSCALL_FAIL(
+      bind(fd.get(), reinterpret_cast<sockaddr*>(addr), sizeof(*addr)));
+  socklen_t addrlen = sizeof(*addr);
+  RETURN_ERROR_IF_SYSCALL_FAIL(
+      getsockname(fd.get(), reinterpret_cast<sockaddr*>(addr), &addrlen));
+  RETURN_ERROR_IF_SYSCALL_FAIL(listen(fd.get(), 5));
+  return fd;
+}
+
+// ExpectConnected connects client to addr, and checks that data can be
+// exchanged with the accepted connection.
+void ExpectConnected(int listen_fd, int client_fd, const sockaddr_in& addr) {
+  ASSERT_THAT(RetryEINTR(connect)(client_fd,
+                                  reinterpret_cast<const sockaddr*>(&addr),
+                                  sizeof(addr)),
+              SyscallSucceeds());
+  FileDescriptor server =
+      ASSERT_NO_ERRNO_AND_VALUE(Accept(listen_fd, nullptr, nullptr));
+  ASSERT_THAT(RetryEINTR(send)(client_fd, kData, sizeof(kData), 0),
+              SyscallSucceedsWithValue(sizeof(kData)));
+  char buf[sizeof(kData)] = {};
+  ASSERT_THAT(RetryEINTR(recv)(server.get(), buf, sizeof(buf), MSG_WAITALL),
+              SyscallSucceedsWithValue(sizeof(buf)));
+  EXPECT_STREQ(buf, kData);
+}
+
+// ExpectNotConnected checks that a non-blocking connect from client to addr
+// doesn't complete, as the SYNs are dropped by the listener.
+void ExpectNotConnected(int client_fd, const sockaddr_in& addr) {
+  ASSERT_THAT(fcntl(client_fd, F_SETFL, O_NONBLOCK), SyscallSucceeds());
+  ASSERT_THAT(
+      connect(client_fd, reinterpret_cast<const sockaddr*>(&addr), sizeof(addr)),
+      SyscallFailsWithErrno(EINPROGRESS));
+  constexpr int kTimeoutMs = 1500;
+  struct pollfd pfd = {client_fd, POLLOUT, 0};
+  EXPECT_THAT(RetryEINTR(poll)(&pfd, 1, kTimeoutMs),
+              SyscallSucceedsWithValue(0));
+}
+
+TEST(TCPMD5SigTest, MatchingKeys) {
+  sockaddr_in addr;
+  FileDescriptor listener = ASSERT_NO_ERRNO_AND_VALUE(Listen(kKey, &addr));
+  FileDescriptor client =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_STREAM, 0));
+  ASSERT_NO_ERRNO(SetMD5Sig(client.get(), kKey));
+  ExpectConnected(listener.get(), client.get(), addr);
+}
+
+TEST(TCPMD5SigTest, PrefixKey) {
+  sockaddr_in addr;
+  FileDescriptor listener = ASSERT_NO_ERRNO_AND_VALUE(Listen("", &addr));
+  TCPMD5Sig sig = MD5Sig(htonl(0x7f000000), kKey);
+  sig.flags = kFlagPrefix;
+  sig.prefixlen = 8;
+  ASSERT_THAT(setsockopt(listener.get(), IPPROTO_TCP, TCP_MD5SIG_EXT, &sig,
+                         sizeof(sig)),
+              SyscallSucceeds());
+
+  FileDescriptor client =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_STREAM, 0));
+  ASSERT_NO_ERRNO(SetMD5Sig(client.get(), kKey));
+  ExpectConnected(listener.get(), client.get(), addr);
+}
+
+TEST(TCPMD5SigTest, UnsignedClient) {
+  sockaddr_in addr;
+  FileDescriptor listener = ASSERT_NO_ERRNO_AND_VALUE(Listen(kKey, &addr));
+  FileDescriptor client =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_STREAM, 0));
+  ExpectNotConnected(client.get(), addr);
+}
+
+TEST(TCPMD5SigTest, UnsignedListener) {
+  sockaddr_in addr;
+  FileDescriptor listener = ASSERT_NO_ERRNO_AND_VALUE(Listen("", &addr));
+  FileDescriptor client =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_STREAM, 0));
+  ASSERT_NO_ERRNO(SetMD5Sig(client.get(), kKey));
+  ExpectNotConnected(client.get(), addr);
+}
+
+TEST(TCPMD5SigTest, DifferentKeys) {
+  sockaddr_in addr;
+  FileDescriptor listener = ASSERT_NO_ERRNO_AND_VALUE(Listen(kKey, &addr));
+  FileDescriptor client =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_STREAM, 0));
+  ASSERT_NO_ERRNO(SetMD5Sig(client.get(), kOtherKey));
+  ExpectNotConnected(client.get(), addr);
+}
+
+TEST(TCPMD5SigTest, DeletedKey) {
+  sockaddr_in addr;
+  FileDescriptor listener = ASSERT_NO_ERRNO_AND_VALUE(Listen(kKey, &addr));
+  ASSERT_NO_ERRNO(SetMD5Sig(listener.get(), ""));
+  FileDescriptor client =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_STREAM, 0));
+  ExpectConnected(listener.get(), client.get(), addr);
+}
+
+TEST(TCPMD5SigTest, DeleteMissingKey) {
+  FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_STREAM, 0));
+  EXPECT_THAT(SetMD5Sig(fd.get(), ""), PosixErrorIs(ENOENT));
+}
+
+TEST(TCPMD5SigTest, Invalid) {
+  FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_STREAM, 0));
+  TCPMD5Sig sig = MD5Sig(htonl(INADDR_LOOPBACK), kKey);
+
+  // Short option.
+  EXPECT_THAT(
+      setsockopt(fd.get(), IPPROTO_TCP, TCP_MD5SIG, &sig, sizeof(sig) - 1),
+      SyscallFailsWithErrno(EINVAL));
+
+  // Key too long.
+  TCPMD5Sig invalid = sig;
+  invalid.keylen = sizeof(invalid.key) + 1;
+  EXPECT_THAT(setsockopt(fd.get(), IPPROTO_TCP, TCP_MD5SIG, &invalid,
+                         sizeof(invalid)),
+              SyscallFailsWithErrno(EINVAL));
+
+  // Address family not matching the socket's.
+  invalid = sig;
+  invalid.addr.ss_family = AF_INET6;
+  EXPECT_THAT(setsockopt(fd.get(), IPPROTO_TCP, TCP_MD5SIG, &invalid,
+                         sizeof(invalid)),
+              SyscallFailsWithErrno(EINVAL));
+
+  // Prefix longer than the address.
+  invalid = sig;
+  invalid.flags = kFlagPrefix;
+  invalid.prefixlen = 33;
+  EXPECT_THAT(setsockopt(fd.get(), IPPROTO_TCP, TCP_MD5SIG_EXT, &invalid,
+                         sizeof(invalid)),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+TEST(TCPMD5SigTest, UnknownOption) {
+  FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_STREAM, 0));
+  constexpr int kUnknownOption = 1000;
+  EXPECT_THAT(setsockopt(fd.get(), IPPROTO_TCP, kUnknownOption, &kSockOptOn,
+                         sizeof(kSockOptOn)),
+              SyscallFailsWithErrno(ENOPROTOOPT));
+}
+
+TEST(TCPMD5SigTest, TCPAONotSupported) {
+  // Linux may support TCP-AO, which gVisor doesn't.
+  SKIP_IF(!IsRunningOnGvisor());
+  FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_STREAM, 0));
+  char buf[512] = {};
+  EXPECT_THAT(
+      setsockopt(fd.get(), IPPROTO_TCP, TCP_AO_ADD_KEY, buf, sizeof(buf)),
+      SyscallFailsWithErrno(ENOPROTOOPT));
+}
+
+}  // namespace
+
+}  // namespace testing
+}  // namespace gvisor
@@ -863,15 +863,12 @@ TEST_P(TcpSocketTest, TcpSCMPriority) {
 
   struct cmsghdr* cmsg = CMSG_FIRSTHDR(&msg);
   ASSERT_NE(cmsg, nullptr);
-  // TODO(b/78348848): SO_TIMESTAMP isn't implemented for TCP sockets.
-  if (!IsRunningOnGvisor() || cmsg->cmsg_level == SOL_SOCKET) {
-    ASSERT_EQ(cmsg->cmsg_level, SOL_SOCKET);
-    ASSERT_EQ(cmsg->cmsg_type, SO_TIMESTAMP);
-    ASSERT_EQ(cmsg->cmsg_len, CMSG_LEN(sizeof(struct timeval)));
+  ASSERT_EQ(cmsg->cmsg_level, SOL_SOCKET);
+  ASSERT_EQ(cmsg->cmsg_type, SO_TIMESTAMP);
+  ASSERT_EQ(cmsg->cmsg_len, CMSG_LEN(sizeof(struct timeval)));
 
-    cmsg = CMSG_NXTHDR(&msg, cmsg);
-    ASSERT_NE(cmsg, nullptr);
-  }
+  cmsg = CMSG_NXTHDR(&msg, cmsg);
+  ASSERT_NE(cmsg, nullptr);
   ASSERT_EQ(cmsg->cmsg_len, CMSG_LEN(sizeof(int)));
   ASSERT_EQ(cmsg->cmsg_level, SOL_TCP);
   ASSERT_EQ(cmsg->cmsg_type, TCP_INQ);
@@ -2218,8 +2215,6 @@ TEST_P(SimpleTcpSocketTest, SetTCPWindowClampAboveHalfMinRcvBuf) {
 
 #ifdef __linux__
 
-// TODO(gvisor.dev/2746): Support SO_ATTACH_FILTER/SO_DETACH_FILTER.
-// gVisor currently silently ignores attaching a filter.
 TEST_P(SimpleTcpSocketTest, SetSocketAttachDetachFilter) {
   FileDescriptor s =
       ASSERT_NO_ERRNO_AND_VALUE(Socket(GetParam(), SOCK_STREAM, IPPROTO_TCP));
@@ -2253,8 +2248,6 @@ TEST_P(SimpleTcpSocketTest, SetSocketAttachDetachFilter) {
 #endif  // __linux__
 
 TEST_P(SimpleTcpSocketTest, SetSocketDetachFilterNoInstalledFilter) {
-  // TODO(gvisor.dev/2746): Support SO_ATTACH_FILTER/SO_DETACH_FILTER.
-  SKIP_IF(IsRunningOnGvisor());
   FileDescriptor s =
       ASSERT_NO_ERRNO_AND_VALUE(Socket(GetParam(), SOCK_STREAM, IPPROTO_TCP));
   constexpr int val = 0;
@@ -0,0 +1,243 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include <errno.h>
+#include <fcntl.h>
+#include <sched.h>
+#include <signal.h>
+#include <stdio.h>
+#include <sys/stat.h>
+#include <sys/syscall.h>
+#include <sys/wait.h>
+#include <time.h>
+#include <unistd.h>
+
+#include <cstdint>
+#include <string>
+
+#include "gtest/gtest.h"
+#include "absl/strings/str_cat.h"
+#include "test/util/capability_util.h"
+#include "test/util/file_descriptor.h"
+#include "test/util/fs_util.h"
+#include "test/util/logging.h"
+#include "test/util/multiprocess_util.h"
+#include "test/util/posix_error.h"
+#include "test/util/test_util.h"
+
+namespace gvisor {
+namespace testing {
+
+namespace {
+
+#ifndef CLONE_NEWTIME
+#define CLONE_NEWTIME 0x80
+#endif  // CLONE_NEWTIME
+
+#ifndef SYS_clone3
+#define SYS_clone3 435
+#endif  // SYS_clone3
+
+// struct clone_args is a Linux clone struct. Old versions of glibc do not
+// expose it. See include/uapi/linux/sched.h
+struct clone_args {
+  uint64_t flags;
+  uint64_t pidfd;
+  uint64_t child_tid;
+  uint64_t parent_tid;
+  uint64_t exit_signal;
+  uint64_t stack;
+  uint64_t stack_size;
+  uint64_t tls;
+};
+
+constexpr int64_t kMonotonicOffsetSec = 86400;
+constexpr int64_t kBoottimeOffsetSec = 2 * 86400;
+
+int64_t ClockSeconds(clockid_t clock) {
+  struct timespec ts;
+  TEST_PCHECK(clock_gettime(clock, &ts) == 0);
+  return ts.tv_sec;
+}
+
+// TimeNamespacesSupported returns true if the caller can create time
+// namespaces and set their offsets.
+bool TimeNamespacesSupported() {
+  if (!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)) ||
+      !ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_TIME))) {
+    return false;
+  }
+  struct stat st;
+  return stat("/proc/self/ns/time", &st) == 0;
+}
+
+std::string OffsetsContents() {
+  return absl::StrCat("monotonic ", kMonotonicOffsetSec, " 0\nboottime ",
+                      kBoottimeOffsetSec, " 0\n");
+}
+
+TEST(TimeNamespaceTest, UnshareChangesChildNamespace) {
+  SKIP_IF(!TimeNamespacesSupported());
+
+  const auto rest = [] {
+    const std::string ns =
+        TEST_CHECK_NO_ERRNO_AND_VALUE(ReadLink("/proc/self/ns/time"));
+    TEST_CHECK(ns ==
+               TEST_CHECK_NO_ERRNO_AND_VALUE(
+                   ReadLink("/proc/self/ns/time_for_children")));
+
+    TEST_PCHECK(unshare(CLONE_NEWTIME) == 0);
+
+    // The caller stays in its time namespace; only its children enter the new
+    // one.
+    TEST_CHECK(ns ==
+               TEST_CHECK_NO_ERRNO_AND_VALUE(ReadLink("/proc/self/ns/time")));
+    TEST_CHECK(ns != TEST_CHECK_NO_ERRNO_AND_VALUE(
+                         ReadLink("/proc/self/ns/time_for_children")));
+  };
+  EXPECT_THAT(InForkedProcess(rest), IsPosixErrorOkAndHolds(0));
+}
+
+TEST(TimeNamespaceTest, Offsets) {
+  SKIP_IF(!TimeNamespacesSupported());
+
+  const auto rest = [] {
+    TEST_PCHECK(unshare(CLONE_NEWTIME) == 0);
+    TEST_CHECK_NO_ERRNO(
+        SetContents("/proc/self/timens_offsets", OffsetsContents()));
+    const std::string offsets =
+        TEST_CHECK_NO_ERRNO_AND_VALUE(GetContents("/proc/self/timens_offsets"));
+    char clock1[16], clock2[16];
+    int64_t sec1, nsec1, sec2, nsec2;
+    TEST_CHECK(sscanf(offsets.c_str(), "%15s %ld %ld %15s %ld %ld", clock1,
+                      &sec1, &nsec1, clock2, &sec2, &nsec2) == 6);
+    TEST_CHECK(std::string(clock1) == "monotonic");
+    TEST_CHECK(sec1 == kMonotonicOffsetSec && nsec1 == 0);
+    TEST_CHECK(std::string(clock2) == "boottime");
+    TEST_CHECK(sec2 == kBoottimeOffsetSec && nsec2 == 0);
+
+    const int64_t monotonic = ClockSeconds(CLOCK_MONOTONIC);
+    const int64_t boottime = ClockSeconds(CLOCK_BOOTTIME);
+
+    pid_t child = fork();
+    if (child == 0) {
+      // The clocks of the child are offset.
+      TEST_CHECK(ClockSeconds(CLOCK_MONOTONIC) >=
+                 monotonic + kMonotonicOffsetSec);
+      TEST_CHECK(ClockSeconds(CLOCK_MONOTONIC_COARSE) >=
+                 monotonic + kMonotonicOffsetSec);
+      TEST_CHECK(ClockSeconds(CLOCK_BOOTTIME) >= boottime + kBoottimeOffsetSec);
+      _exit(0);
+    }
+    TEST_PCHECK(child > 0);
+    int status;
+    TEST_PCHECK(RetryEINTR(waitpid)(child, &status, 0) == child);
+    TEST_CHECK(WIFEXITED(status) && WEXITSTATUS(status) == 0);
+
+    // The caller's clocks are not affected.
+    TEST_CHECK(ClockSeconds(CLOCK_MONOTONIC) < monotonic + kMonotonicOffsetSec);
+
+    // The offsets can't be changed once a task has entered the namespace.
+    TEST_CHECK(SetContents("/proc/self/timens_offsets", "monotonic 1 0")
+                   .errno_value() == EACCES);
+  };
+  EXPECT_THAT(InForkedProcess(rest), IsPosixErrorOkAndHolds(0));
+}
+
+TEST(TimeNamespaceTest, InvalidOffsets) {
+  SKIP_IF(!TimeNamespacesSupported());
+
+  const auto rest = [] {
+    TEST_PCHECK(unshare(CLONE_NEWTIME) == 0);
+    // Only CLOCK_MONOTONIC and CLOCK_BOOTTIME can be offset.
+    TEST_CHECK(SetContents("/proc/self/timens_offsets", "realtime 1 0")
+                   .errno_value() == EINVAL);
+    TEST_CHECK(SetContents("/proc/self/timens_offsets",
+                           absl::StrCat(CLOCK_REALTIME, " 1 0"))
+                   .errno_value() == EINVAL);
+    // Nanoseconds must be in [0, 1e9).
+    TEST_CHECK(
+        SetContents("/proc/self/timens_offsets", "monotonic 1 1000000000")
+            .errno_value() == EINVAL);
+    TEST_CHECK(SetContents("/proc/self/timens_offsets", "monotonic 1 -1")
+                   .errno_value() == EINVAL);
+    // The clock can't become negative.
+    TEST_CHECK(
+        SetContents("/proc/self/timens_offsets",
+                    absl::StrCat("monotonic ",
+                                 -2 * ClockSeconds(CLOCK_MONOTONIC) - 1, " 0"))
+            .errno_value() == EINVAL);
+    // Clock IDs are accepted in place of names.
+    TEST_CHECK_NO_ERRNO(SetContents("/proc/self/timens_offsets",
+                                    absl::StrCat(CLOCK_MONOTONIC, " 1 0")));
+  };
+  EXPECT_THAT(InForkedProcess(rest), IsPosixErrorOkAndHolds(0));
+}
+
+TEST(TimeNamespaceTest, Clone3) {
+  SKIP_IF(!TimeNamespacesSupported());
+
+  const std::string ns =
+      ASSERT_NO_ERRNO_AND_VALUE(ReadLink("/proc/self/ns/time"));
+
+  clone_args ca = {};
+  ca.flags = CLONE_NEWTIME;
+  ca.exit_signal = SIGCHLD;
+
+  pid_t child;
+  ASSERT_THAT(child = syscall(SYS_clone3, &ca, sizeof(ca)), SyscallSucceeds());
+  if (child == 0) {
+    // The child is created directly in the new time namespace.
+    TEST_CHECK(ns !=
+               TEST_CHECK_NO_ERRNO_AND_VALUE(ReadLink("/proc/self/ns/time")));
+    _exit(0);
+  }
+  int status;
+  ASSERT_THAT(RetryEINTR(waitpid)(child, &status, 0),
+              SyscallSucceedsWithValue(child));
+  EXPECT_TRUE(WIFEXITED(status) && WEXITSTATUS(status) == 0)
+      << "status = " << status;
+}
+
+TEST(TimeNamespaceTest, SetnsIntoNamespace) {
+  SKIP_IF(!TimeNamespacesSupported());
+
+  const auto rest = [] {
+    const FileDescriptor orig = TEST_CHECK_NO_ERRNO_AND_VALUE(
+        Open("/proc/self/ns/time", O_RDONLY));
+    TEST_PCHECK(unshare(CLONE_NEWTIME) == 0);
+    const FileDescriptor nsfd = TEST_CHECK_NO_ERRNO_AND_VALUE(
+        Open("/proc/self/ns/time_for_children", O_RDONLY));
+    TEST_CHECK_NO_ERRNO(
+        SetContents("/proc/self/timens_offsets", OffsetsContents()));
+
+    const int64_t monotonic = ClockSeconds(CLOCK_MONOTONIC);
+    TEST_PCHECK(setns(nsfd.get(), CLONE_NEWTIME) == 0);
+    TEST_CHECK(ClockSeconds(CLOCK_MONOTONIC) >=
+               monotonic + kMonotonicOffsetSec);
+
+    // Switching back restores the original clocks.
+    TEST_PCHECK(setns(orig.get(), CLONE_NEWTIME) == 0);
+    TEST_CHECK(ClockSeconds(CLOCK_MONOTONIC) < monotonic + kMonotonicOffsetSec);
+
+    // The namespace type must match.
+    TEST_CHECK(setns(nsfd.get(), CLONE_NEWUTS) < 0 && errno == EINVAL);
+  };
+  EXPECT_THAT(InForkedProcess(rest), IsPosixErrorOkAndHolds(0));
+}
+
+}  // namespace
+
+}  // namespace testing
+}  // namespace gvisor
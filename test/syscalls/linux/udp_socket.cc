@@ -1905,8 +1905,6 @@ TEST_P(UdpSocketTest, RecvBufLimits) {
 
 #ifdef __linux__
 
-// TODO(gvisor.dev/2746): Support SO_ATTACH_FILTER/SO_DETACH_FILTER.
-// gVisor currently silently ignores attaching a filter.
 TEST_P(UdpSocketTest, SetSocketDetachFilter) {
   // Program generated using sudo tcpdump -i lo udp and port 1234 -dd
   struct sock_filter code[] = {
@@ -1938,8 +1936,6 @@ TEST_P(UdpSocketTest, SetSocketDetachFilter) {
 #endif  // __linux__
 
 TEST_P(UdpSocketTest, SetSocketDetachFilterNoInstalledFilter) {
-  // TODO(gvisor.dev/2746): Support SO_ATTACH_FILTER/SO_DETACH_FILTER.
-  SKIP_IF(IsRunningOnGvisor());
   constexpr int val = 0;
   ASSERT_THAT(
       setsockopt(sock_.get(), SOL_SOCKET, SO_DETACH_FILTER, &val, sizeof(val)),
@@ -0,0 +1,418 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include <errno.h>
+#include <fcntl.h>
+#include <linux/userfaultfd.h>
+#include <poll.h>
+#include <sys/ioctl.h>
+#include <sys/mman.h>
+#include <sys/syscall.h>
+#include <unistd.h>
+
+#include <cstdint>
+#include <vector>
+
+#include "gtest/gtest.h"
+#include "test/util/file_descriptor.h"
+#include "test/util/memory_util.h"
+#include "test/util/posix_error.h"
+#include "test/util/test_util.h"
+#include "test/util/thread_util.h"
+
+namespace gvisor {
+namespace testing {
+
+namespace {
+
+#ifndef SYS_userfaultfd
+#if defined(__x86_64__)
+#define SYS_userfaultfd 323
+#elif defined(__aarch64__)
+#define SYS_userfaultfd 282
+#endif
+#endif  // SYS_userfaultfd
+
+#ifndef UFFD_USER_MODE_ONLY
+#define UFFD_USER_MODE_ONLY 1
+#endif  // UFFD_USER_MODE_ONLY
+
+#ifndef UFFDIO_REGISTER_MODE_WP
+#define UFFDIO_REGISTER_MODE_WP ((__u64)1 << 1)
+#endif  // UFFDIO_REGISTER_MODE_WP
+
+#ifndef UFFD_PAGEFAULT_FLAG_WP
+#define UFFD_PAGEFAULT_FLAG_WP (1 << 1)
+#endif  // UFFD_PAGEFAULT_FLAG_WP
+
+#ifndef UFFDIO_WRITEPROTECT
+struct uffdio_writeprotect {
+  struct uffdio_range range;
+  __u64 mode;
+};
+#define UFFDIO_WRITEPROTECT_MODE_WP ((__u64)1 << 0)
+#define UFFDIO_WRITEPROTECT_MODE_DONTWAKE ((__u64)1 << 1)
+#define UFFDIO_WRITEPROTECT _IOWR(UFFDIO, 0x06, struct uffdio_writeprotect)
+#endif  // UFFDIO_WRITEPROTECT
+
+// NewUserfaultfd returns a userfaultfd that handles user faults only, after
+// completing the UFFDIO_API handshake.
+PosixErrorOr<FileDescriptor> NewUserfaultfd() {
+  int fd = syscall(SYS_userfaultfd, O_CLOEXEC | UFFD_USER_MODE_ONLY);
+  MaybeSave();
+  if (fd < 0) {
+    return PosixError(errno, "userfaultfd");
+  }
+  FileDescriptor uffd(fd);
+  struct uffdio_api api = {};
+  api.api = UFFD_API;
+  if (ioctl(uffd.get(), UFFDIO_API, &api) < 0) {
+    return PosixError(errno, "UFFDIO_API");
+  }
+  return uffd;
+}
+
+// UserfaultfdSupported returns true if userfaultfd(2) is usable by the caller.
+bool UserfaultfdSupported() {
+  auto uffd = NewUserfaultfd();
+  return uffd.ok();
+}
+
+PosixError Register(const FileDescriptor& uffd, const Mapping& m,
+                    uint64_t mode) {
+  struct uffdio_register reg = {};
+  reg.range.start = m.addr();
+  reg.range.len = m.len();
+  reg.mode = mode;
+  if (ioctl(uffd.get(), UFFDIO_REGISTER, &reg) < 0) {
+    return PosixError(errno, "UFFDIO_REGISTER");
+  }
+  return NoError();
+}
+
+// ReadFault waits for a page fault message on uffd and returns it.
+struct uffd_msg ReadFault(const FileDescriptor& uffd) {
+  struct pollfd pfd = {uffd.get(), POLLIN, 0};
+  TEST_PCHECK(RetryEINTR(poll)(&pfd, 1, -1) == 1);
+  struct uffd_msg msg;
+  TEST_PCHECK(RetryEINTR(read)(uffd.get(), &msg, sizeof(msg)) == sizeof(msg));
+  TEST_CHECK(msg.event == UFFD_EVENT_PAGEFAULT);
+  return msg;
+}
+
+TEST(UserfaultfdTest, InvalidFlags) {
+  SKIP_IF(!UserfaultfdSupported());
+  EXPECT_THAT(syscall(SYS_userfaultfd, 0x100 | UFFD_USER_MODE_ONLY),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+TEST(UserfaultfdTest, Api) {
+  SKIP_IF(!UserfaultfdSupported());
+  int fd;
+  ASSERT_THAT(fd = syscall(SYS_userfaultfd, O_CLOEXEC | UFFD_USER_MODE_ONLY),
+              SyscallSucceeds());
+  FileDescriptor uffd(fd);
+
+  // Other ioctls fail before the handshake.
+  struct uffdio_register reg = {};
+  EXPECT_THAT(ioctl(uffd.get(), UFFDIO_REGISTER, &reg),
+              SyscallFailsWithErrno(EINVAL));
+
+  struct uffdio_api api = {};
+  api.api = 0xAB;
+  EXPECT_THAT(ioctl(uffd.get(), UFFDIO_API, &api),
+              SyscallFailsWithErrno(EINVAL));
+
+  api = {};
+  api.api = UFFD_API;
+  ASSERT_THAT(ioctl(uffd.get(), UFFDIO_API, &api), SyscallSucceeds());
+  EXPECT_NE(api.ioctls & (1ULL << _UFFDIO_REGISTER), 0);
+  EXPECT_NE(api.ioctls & (1ULL << _UFFDIO_UNREGISTER), 0);
+
+  // The handshake can only be done once.
+  api = {};
+  api.api = UFFD_API;
+  EXPECT_THAT(ioctl(uffd.get(), UFFDIO_API, &api),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+TEST(UserfaultfdTest, ReadWithoutFaultWouldBlock) {
+  SKIP_IF(!UserfaultfdSupported());
+  int fd;
+  ASSERT_THAT(fd = syscall(SYS_userfaultfd,
+                           O_CLOEXEC | O_NONBLOCK | UFFD_USER_MODE_ONLY),
+              SyscallSucceeds());
+  FileDescriptor uffd(fd);
+  struct uffdio_api api = {};
+  api.api = UFFD_API;
+  ASSERT_THAT(ioctl(uffd.get(), UFFDIO_API, &api), SyscallSucceeds());
+
+  struct uffd_msg msg;
+  EXPECT_THAT(read(uffd.get(), &msg, sizeof(msg)),
+              SyscallFailsWithErrno(EAGAIN));
+  // Buffers smaller than a message are rejected.
+  EXPECT_THAT(read(uffd.get(), &msg, sizeof(msg) - 1),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+TEST(UserfaultfdTest, RegisterReturnsIoctls) {
+  SKIP_IF(!UserfaultfdSupported());
+  const FileDescriptor uffd = ASSERT_NO_ERRNO_AND_VALUE(NewUserfaultfd());
+  const Mapping m = ASSERT_NO_ERRNO_AND_VALUE(
+      MmapAnon(2 * kPageSize, PROT_READ | PROT_WRITE, MAP_PRIVATE));
+
+  struct uffdio_register reg = {};
+  reg.range.start = m.addr();
+  reg.range.len = m.len();
+  reg.mode = UFFDIO_REGISTER_MODE_MISSING;
+  ASSERT_THAT(ioctl(uffd.get(), UFFDIO_REGISTER, &reg), SyscallSucceeds());
+  EXPECT_NE(reg.ioctls & (1ULL << _UFFDIO_COPY), 0);
+  EXPECT_NE(reg.ioctls & (1ULL << _UFFDIO_ZEROPAGE), 0);
+  EXPECT_NE(reg.ioctls & (1ULL << _UFFDIO_WAKE), 0);
+
+  struct uffdio_range range = {};
+  range.start = m.addr();
+  range.len = m.len();
+  EXPECT_THAT(ioctl(uffd.get(), UFFDIO_UNREGISTER, &range), SyscallSucceeds());
+}
+
+TEST(UserfaultfdTest, RegisterInvalid) {
+  SKIP_IF(!UserfaultfdSupported());
+  const FileDescriptor uffd = ASSERT_NO_ERRNO_AND_VALUE(NewUserfaultfd());
+  const Mapping m = ASSERT_NO_ERRNO_AND_VALUE(
+      MmapAnon(2 * kPageSize, PROT_READ | PROT_WRITE, MAP_PRIVATE));
+
+  struct uffdio_register reg = {};
+  reg.range.start = m.addr();
+  reg.range.len = m.len();
+
+  // A mode is required.
+  EXPECT_THAT(ioctl(uffd.get(), UFFDIO_REGISTER, &reg),
+              SyscallFailsWithErrno(EINVAL));
+
+  // The range must be page-aligned.
+  reg.mode = UFFDIO_REGISTER_MODE_MISSING;
+  reg.range.start = m.addr() + 1;
+  EXPECT_THAT(ioctl(uffd.get(), UFFDIO_REGISTER, &reg),
+              SyscallFailsWithErrno(EINVAL));
+
+  // The range must contain a mapping.
+  ASSERT_THAT(munmap(reinterpret_cast<void*>(m.addr() + kPageSize), kPageSize),
+              SyscallSucceeds());
+  reg.range.start = m.addr() + kPageSize;
+  reg.range.len = kPageSize;
+  EXPECT_THAT(ioctl(uffd.get(), UFFDIO_REGISTER, &reg),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+TEST(UserfaultfdTest, RegisterBusy) {
+  SKIP_IF(!UserfaultfdSupported());
+  const FileDescriptor uffd1 = ASSERT_NO_ERRNO_AND_VALUE(NewUserfaultfd());
+  const FileDescriptor uffd2 = ASSERT_NO_ERRNO_AND_VALUE(NewUserfaultfd());
+  const Mapping m = ASSERT_NO_ERRNO_AND_VALUE(
+      MmapAnon(kPageSize, PROT_READ | PROT_WRITE, MAP_PRIVATE));
+
+  ASSERT_NO_ERRNO(Register(uffd1, m, UFFDIO_REGISTER_MODE_MISSING));
+  EXPECT_THAT(Register(uffd2, m, UFFDIO_REGISTER_MODE_MISSING),
+              PosixErrorIs(EBUSY, ::testing::_));
+}
+
+TEST(UserfaultfdTest, CopyResolvesFault) {
+  SKIP_IF(!UserfaultfdSupported());
+  const FileDescriptor uffd = ASSERT_NO_ERRNO_AND_VALUE(NewUserfaultfd());
+  const Mapping m = ASSERT_NO_ERRNO_AND_VALUE(
+      MmapAnon(kPageSize, PROT_READ | PROT_WRITE, MAP_PRIVATE));
+  ASSERT_NO_ERRNO(Register(uffd, m, UFFDIO_REGISTER_MODE_MISSING));
+
+  ScopedThread handler([&] {
+    struct uffd_msg msg = ReadFault(uffd);
+    TEST_CHECK(msg.arg.pagefault.address == m.addr());
+    TEST_CHECK((msg.arg.pagefault.flags & UFFD_PAGEFAULT_FLAG_WRITE) == 0);
+
+    std::vector<char> src(kPageSize, 'a');
+    struct uffdio_copy copy = {};
+    copy.dst = m.addr();
+    copy.src = reinterpret_cast<uint64_t>(src.data());
+    copy.len = kPageSize;
+    TEST_PCHECK(ioctl(uffd.get(), UFFDIO_COPY, &copy) == 0);
+    TEST_CHECK(copy.copy == static_cast<int64_t>(kPageSize));
+  });
+
+  // This read blocks until the handler installs the page.
+  EXPECT_EQ(*reinterpret_cast<volatile char*>(m.ptr()), 'a');
+  handler.Join();
+
+  // The page is now populated, so copying to it again fails.
+  std::vector<char> src(kPageSize);
+  struct uffdio_copy copy = {};
+  copy.dst = m.addr();
+  copy.src = reinterpret_cast<uint64_t>(src.data());
+  copy.len = kPageSize;
+  EXPECT_THAT(ioctl(uffd.get(), UFFDIO_COPY, &copy),
+              SyscallFailsWithErrno(EEXIST));
+  EXPECT_EQ(copy.copy, -EEXIST);
+}
+
+TEST(UserfaultfdTest, ZeropageResolvesWriteFault) {
+  SKIP_IF(!UserfaultfdSupported());
+  const FileDescriptor uffd = ASSERT_NO_ERRNO_AND_VALUE(NewUserfaultfd());
+  const Mapping m = ASSERT_NO_ERRNO_AND_VALUE(
+      MmapAnon(kPageSize, PROT_READ | PROT_WRITE, MAP_PRIVATE));
+  ASSERT_NO_ERRNO(Register(uffd, m, UFFDIO_REGISTER_MODE_MISSING));
+
+  ScopedThread handler([&] {
+    struct uffd_msg msg = ReadFault(uffd);
+    TEST_CHECK(msg.arg.pagefault.address == m.addr());
+    TEST_CHECK(msg.arg.pagefault.flags & UFFD_PAGEFAULT_FLAG_WRITE);
+
+    struct uffdio_zeropage zp = {};
+    zp.range.start = m.addr();
+    zp.range.len = kPageSize;
+    TEST_PCHECK(ioctl(uffd.get(), UFFDIO_ZEROPAGE, &zp) == 0);
+    TEST_CHECK(zp.zeropage == static_cast<int64_t>(kPageSize));
+  });
+
+  volatile char* p = reinterpret_cast<volatile char*>(m.ptr());
+  p[1] = 'b';
+  handler.Join();
+  EXPECT_EQ(p[0], 0);
+  EXPECT_EQ(p[1], 'b');
+}
+
+TEST(UserfaultfdTest, SyscallFaultIsReported) {
+  SKIP_IF(!UserfaultfdSupported());
+  // Faults in syscalls are only reported to userfaultfds that aren't
+  // UFFD_USER_MODE_ONLY, which requires CAP_SYS_PTRACE.
+  int fd = syscall(SYS_userfaultfd, O_CLOEXEC);
+  SKIP_IF(fd < 0 && errno == EPERM);
+  ASSERT_THAT(fd, SyscallSucceeds());
+  FileDescriptor uffd(fd);
+  struct uffdio_api api = {};
+  api.api = UFFD_API;
+  ASSERT_THAT(ioctl(uffd.get(), UFFDIO_API, &api), SyscallSucceeds());
+
+  const Mapping m = ASSERT_NO_ERRNO_AND_VALUE(
+      MmapAnon(kPageSize, PROT_READ | PROT_WRITE, MAP_PRIVATE));
+  ASSERT_NO_ERRNO(Register(uffd, m, UFFDIO_REGISTER_MODE_MISSING));
+
+  ScopedThread handler([&] {
+    struct uffd_msg msg = ReadFault(uffd);
+    TEST_CHECK(msg.arg.pagefault.address == m.addr());
+    struct uffdio_zeropage zp = {};
+    zp.range.start = m.addr();
+    zp.range.len = kPageSize;
+    TEST_PCHECK(ioctl(uffd.get(), UFFDIO_ZEROPAGE, &zp) == 0);
+  });
+
+  int pipefds[2];
+  ASSERT_THAT(pipe(pipefds), SyscallSucceeds());
+  FileDescriptor rfd(pipefds[0]);
+  FileDescriptor wfd(pipefds[1]);
+  ASSERT_THAT(WriteFd(wfd.get(), "c", 1), SyscallSucceedsWithValue(1));
+  // The read's copy-out faults on the registered page.
+  EXPECT_THAT(ReadFd(rfd.get(), m.ptr(), 1), SyscallSucceedsWithValue(1));
+  handler.Join();
+  EXPECT_EQ(*reinterpret_cast<char*>(m.ptr()), 'c');
+}
+
+TEST(UserfaultfdTest, SyscallFaultFailsInUserModeOnly) {
+  SKIP_IF(!UserfaultfdSupported());
+  const FileDescriptor uffd = ASSERT_NO_ERRNO_AND_VALUE(NewUserfaultfd());
+  const Mapping m = ASSERT_NO_ERRNO_AND_VALUE(
+      MmapAnon(kPageSize, PROT_READ | PROT_WRITE, MAP_PRIVATE));
+  ASSERT_NO_ERRNO(Register(uffd, m, UFFDIO_REGISTER_MODE_MISSING));
+
+  int pipefds[2];
+  ASSERT_THAT(pipe(pipefds), SyscallSucceeds());
+  FileDescriptor rfd(pipefds[0]);
+  FileDescriptor wfd(pipefds[1]);
+  ASSERT_THAT(WriteFd(wfd.get(), "c", 1), SyscallSucceedsWithValue(1));
+  EXPECT_THAT(ReadFd(rfd.get(), m.ptr(), 1), SyscallFailsWithErrno(EFAULT));
+}
+
+TEST(UserfaultfdTest, UnregisterRestoresDefaultFaults) {
+  SKIP_IF(!UserfaultfdSupported());
+  const FileDescriptor uffd = ASSERT_NO_ERRNO_AND_VALUE(NewUserfaultfd());
+  const Mapping m = ASSERT_NO_ERRNO_AND_VALUE(
+      MmapAnon(kPageSize, PROT_READ | PROT_WRITE, MAP_PRIVATE));
+  ASSERT_NO_ERRNO(Register(uffd, m, UFFDIO_REGISTER_MODE_MISSING));
+
+  struct uffdio_range range = {};
+  range.start = m.addr();
+  range.len = m.len();
+  ASSERT_THAT(ioctl(uffd.get(), UFFDIO_UNREGISTER, &range), SyscallSucceeds());
+
+  // The page is allocated normally.
+  EXPECT_EQ(*reinterpret_cast<volatile char*>(m.ptr()), 0);
+}
+
+TEST(UserfaultfdTest, WriteProtect) {
+  SKIP_IF(!UserfaultfdSupported());
+  const FileDescriptor uffd = ASSERT_NO_ERRNO_AND_VALUE(NewUserfaultfd());
+  const Mapping m = ASSERT_NO_ERRNO_AND_VALUE(
+      MmapAnon(kPageSize, PROT_READ | PROT_WRITE, MAP_PRIVATE));
+  // Populate the page before registering, so that only write-protect faults
+  // occur.
+  *reinterpret_cast<volatile char*>(m.ptr()) = 'a';
+  auto reg = Register(uffd, m, UFFDIO_REGISTER_MODE_WP);
+  // Write-protect mode may not be supported by the host kernel.
+  SKIP_IF(reg.errno_value() == EINVAL);
+  ASSERT_NO_ERRNO(reg);
+
+  struct uffdio_writeprotect wp = {};
+  wp.range.start = m.addr();
+  wp.range.len = m.len();
+  wp.mode = UFFDIO_WRITEPROTECT_MODE_WP;
+  ASSERT_THAT(ioctl(uffd.get(), UFFDIO_WRITEPROTECT, &wp), SyscallSucceeds());
+
+  ScopedThread handler([&] {
+    struct uffd_msg msg = ReadFault(uffd);
+    TEST_CHECK(msg.arg.pagefault.address == m.addr());
+    TEST_CHECK(msg.arg.pagefault.flags & UFFD_PAGEFAULT_FLAG_WRITE);
+    TEST_CHECK(msg.arg.pagefault.flags & UFFD_PAGEFAULT_FLAG_WP);
+
+    struct uffdio_writeprotect unwp = {};
+    unwp.range.start = m.addr();
+    unwp.range.len = m.len();
+    TEST_PCHECK(ioctl(uffd.get(), UFFDIO_WRITEPROTECT, &unwp) == 0);
+  });
+
+  // Reads are not affected by write protection.
+  volatile char* p = reinterpret_cast<volatile char*>(m.ptr());
+  EXPECT_EQ(p[0], 'a');
+  // This write blocks until the handler removes write protection.
+  p[0] = 'b';
+  handler.Join();
+  EXPECT_EQ(p[0], 'b');
+}
+
+TEST(UserfaultfdTest, WriteProtectRequiresWPRegistration) {
+  SKIP_IF(!UserfaultfdSupported());
+  const FileDescriptor uffd = ASSERT_NO_ERRNO_AND_VALUE(NewUserfaultfd());
+  const Mapping m = ASSERT_NO_ERRNO_AND_VALUE(
+      MmapAnon(kPageSize, PROT_READ | PROT_WRITE, MAP_PRIVATE));
+  ASSERT_NO_ERRNO(Register(uffd, m, UFFDIO_REGISTER_MODE_MISSING));
+
+  struct uffdio_writeprotect wp = {};
+  wp.range.start = m.addr();
+  wp.range.len = m.len();
+  wp.mode = UFFDIO_WRITEPROTECT_MODE_WP;
+  EXPECT_THAT(ioctl(uffd.get(), UFFDIO_WRITEPROTECT, &wp),
+              SyscallFailsWithErrno(ENOENT));
+}
+
+}  // namespace
+
+}  // namespace testing
+}  // namespace gvisor
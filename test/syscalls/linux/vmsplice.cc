@@ -0,0 +1,204 @@
+// Copyright 2025 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include <fcntl.h>
+#include <signal.h>
+#include <sys/uio.h>
+#include <unistd.h>
+
+#include <cstring>
+#include <string>
+#include <vector>
+
+#include "gtest/gtest.h"
+#include "absl/time/clock.h"
+#include "absl/time/time.h"
+#include "test/util/file_descriptor.h"
+#include "test/util/test_util.h"
+#include "test/util/thread_util.h"
+
+namespace gvisor {
+namespace testing {
+
+namespace {
+
+TEST(VmspliceTest, ToPipe) {
+  int fds[2];
+  ASSERT_THAT(pipe(fds), SyscallSucceeds());
+  const FileDescriptor rfd(fds[0]);
+  const FileDescriptor wfd(fds[1]);
+
+  char a[] = "hello, ";
+  char b[] = "world";
+  struct iovec iov[2] = {
+      {.iov_base = a, .iov_len = strlen(a)},
+      {.iov_base = b, .iov_len = strlen(b)},
+  };
+  const size_t total = strlen(a) + strlen(b);
+  ASSERT_THAT(vmsplice(wfd.get(), iov, 2, 0),
+              SyscallSucceedsWithValue(total));
+
+  std::vector<char> buf(total);
+  ASSERT_THAT(read(rfd.get(), buf.data(), buf.size()),
+              SyscallSucceedsWithValue(total));
+  EXPECT_EQ(std::string(buf.data(), buf.size()), "hello, world");
+}
+
+TEST(VmspliceTest, FromPipe) {
+  int fds[2];
+  ASSERT_THAT(pipe(fds), SyscallSucceeds());
+  const FileDescriptor rfd(fds[0]);
+  const FileDescriptor wfd(fds[1]);
+
+  std::vector<char> buf(kPageSize);
+  RandomizeBuffer(buf.data(), buf.size());
+  ASSERT_THAT(write(wfd.get(), buf.data(), buf.size()),
+              SyscallSucceedsWithValue(buf.size()));
+
+  // Read the pipe's contents into two iovecs.
+  std::vector<char> rbuf(kPageSize);
+  struct iovec iov[2] = {
+      {.iov_base = rbuf.data(), .iov_len = kPageSize / 2},
+      {.iov_base = rbuf.data() + kPageSize / 2, .iov_len = kPageSize / 2},
+  };
+  ASSERT_THAT(vmsplice(rfd.get(), iov, 2, 0),
+              SyscallSucceedsWithValue(kPageSize));
+  EXPECT_EQ(memcmp(rbuf.data(), buf.data(), kPageSize), 0);
+}
+
+TEST(VmspliceTest, GiftedMemoryReusable) {
+  int fds[2];
+  ASSERT_THAT(pipe(fds), SyscallSucceeds());
+  const FileDescriptor rfd(fds[0]);
+  const FileDescriptor wfd(fds[1]);
+
+  std::vector<char> buf(kPageSize, 'a');
+  struct iovec iov = {.iov_base = buf.data(), .iov_len = buf.size()};
+  ASSERT_THAT(vmsplice(wfd.get(), &iov, 1, SPLICE_F_GIFT),
+              SyscallSucceedsWithValue(kPageSize));
+
+  // Modifying the memory after vmsplice returns is allowed by gVisor, which
+  // always copies into the pipe, but is undefined under Linux.
+  SKIP_IF(!IsRunningOnGvisor());
+  memset(buf.data(), 'b', buf.size());
+  std::vector<char> rbuf(kPageSize);
+  ASSERT_THAT(read(rfd.get(), rbuf.data(), rbuf.size()),
+              SyscallSucceedsWithValue(kPageSize));
+  EXPECT_EQ(rbuf, std::vector<char>(kPageSize, 'a'));
+}
+
+TEST(VmspliceTest, ThenSplice) {
+  int first[2], second[2];
+  ASSERT_THAT(pipe(first), SyscallSucceeds());
+  const FileDescriptor rfd1(first[0]);
+  const FileDescriptor wfd1(first[1]);
+  ASSERT_THAT(pipe(second), SyscallSucceeds());
+  const FileDescriptor rfd2(second[0]);
+  const FileDescriptor wfd2(second[1]);
+
+  std::vector<char> buf(kPageSize);
+  RandomizeBuffer(buf.data(), buf.size());
+  struct iovec iov = {.iov_base = buf.data(), .iov_len = buf.size()};
+  ASSERT_THAT(vmsplice(wfd1.get(), &iov, 1, 0),
+              SyscallSucceedsWithValue(kPageSize));
+  ASSERT_THAT(splice(rfd1.get(), nullptr, wfd2.get(), nullptr, kPageSize, 0),
+              SyscallSucceedsWithValue(kPageSize));
+
+  std::vector<char> rbuf(kPageSize);
+  ASSERT_THAT(read(rfd2.get(), rbuf.data(), rbuf.size()),
+              SyscallSucceedsWithValue(kPageSize));
+  EXPECT_EQ(rbuf, buf);
+}
+
+TEST(VmspliceTest, InvalidFlags) {
+  int fds[2];
+  ASSERT_THAT(pipe(fds), SyscallSucceeds());
+  const FileDescriptor rfd(fds[0]);
+  const FileDescriptor wfd(fds[1]);
+
+  char c = 'a';
+  struct iovec iov = {.iov_base = &c, .iov_len = 1};
+  EXPECT_THAT(vmsplice(wfd.get(), &iov, 1, 0x10),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+TEST(VmspliceTest, NotPipe) {
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open("/dev/null", O_WRONLY));
+  char c = 'a';
+  struct iovec iov = {.iov_base = &c, .iov_len = 1};
+  EXPECT_THAT(vmsplice(fd.get(), &iov, 1, 0), SyscallFailsWithErrno(EBADF));
+}
+
+TEST(VmspliceTest, NonBlocking) {
+  int fds[2];
+  ASSERT_THAT(pipe(fds), SyscallSucceeds());
+  const FileDescriptor rfd(fds[0]);
+  const FileDescriptor wfd(fds[1]);
+
+  // Reading from an empty pipe doesn't block.
+  char c;
+  struct iovec iov = {.iov_base = &c, .iov_len = 1};
+  EXPECT_THAT(vmsplice(rfd.get(), &iov, 1, SPLICE_F_NONBLOCK),
+              SyscallFailsWithErrno(EAGAIN));
+
+  // Nor does writing to a full pipe.
+  int size;
+  ASSERT_THAT(size = fcntl(wfd.get(), F_GETPIPE_SZ), SyscallSucceeds());
+  std::vector<char> buf(size);
+  ASSERT_THAT(write(wfd.get(), buf.data(), buf.size()),
+              SyscallSucceedsWithValue(size));
+  EXPECT_THAT(vmsplice(wfd.get(), &iov, 1, SPLICE_F_NONBLOCK),
+              SyscallFailsWithErrno(EAGAIN));
+}
+
+TEST(VmspliceTest, Blocking) {
+  int fds[2];
+  ASSERT_THAT(pipe(fds), SyscallSucceeds());
+  const FileDescriptor rfd(fds[0]);
+  const FileDescriptor wfd(fds[1]);
+
+  ScopedThread t([&]() {
+    absl::SleepFor(absl::Milliseconds(100));
+    ASSERT_THAT(write(wfd.get(), "x", 1), SyscallSucceedsWithValue(1));
+  });
+
+  // The read blocks until data is written.
+  char c = 0;
+  struct iovec iov = {.iov_base = &c, .iov_len = 1};
+  EXPECT_THAT(vmsplice(rfd.get(), &iov, 1, 0), SyscallSucceedsWithValue(1));
+  EXPECT_EQ(c, 'x');
+}
+
+TEST(VmspliceTest, NoReaders) {
+  int fds[2];
+  ASSERT_THAT(pipe(fds), SyscallSucceeds());
+  const FileDescriptor wfd(fds[1]);
+  ASSERT_THAT(close(fds[0]), SyscallSucceeds());
+
+  // Ignore SIGPIPE so that EPIPE is observable.
+  struct sigaction sa = {};
+  sa.sa_handler = SIG_IGN;
+  struct sigaction old_sa;
+  ASSERT_THAT(sigaction(SIGPIPE, &sa, &old_sa), SyscallSucceeds());
+  char c = 'a';
+  struct iovec iov = {.iov_base = &c, .iov_len = 1};
+  EXPECT_THAT(vmsplice(wfd.get(), &iov, 1, 0), SyscallFailsWithErrno(EPIPE));
+  ASSERT_THAT(sigaction(SIGPIPE, &old_sa, nullptr), SyscallSucceeds());
+}
+
+}  // namespace
+
+}  // namespace testing
+}  // namespace gvisor
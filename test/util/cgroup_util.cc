@@ -178,14 +178,23 @@ PosixErrorOr<absl::flat_hash_set<pid_t>> Cgroup::ParsePIDList(
 int64_t Cgroup::next_id_ = 0;
 
 PosixErrorOr<Cgroup> Mounter::MountCgroupfs(std::string mopts) {
+  return MountFilesystem("cgroup", mopts);
+}
+
+PosixErrorOr<Cgroup> Mounter::MountCgroup2fs(std::string mopts) {
+  return MountFilesystem("cgroup2", mopts);
+}
+
+PosixErrorOr<Cgroup> Mounter::MountFilesystem(const std::string& fstype,
+                                              const std::string& mopts) {
   ASSIGN_OR_RETURN_ERRNO(TempPath mountpoint,
                          TempPath::CreateDirIn(root_.path()));
   ASSIGN_OR_RETURN_ERRNO(
-      Cleanup mount, Mount("none", mountpoint.path(), "cgroup", 0, mopts, 0));
+      Cleanup mount, Mount("none", mountpoint.path(), fstype, 0, mopts, 0));
   const std::string mountpath = mountpoint.path();
   std::cerr << absl::StreamFormat(
-                   "Mount(\"none\", \"%s\", \"cgroup\", 0, \"%s\", 0) => OK",
-                   mountpath, mopts)
+                   "Mount(\"none\", \"%s\", \"%s\", 0, \"%s\", 0) => OK",
+                   mountpath, fstype, mopts)
             << std::endl;
   Cgroup cg = Cgroup::RootCgroup(mountpath);
   mountpoints_[cg.id()] = std::move(mountpoint);
@@ -302,10 +311,17 @@ ProcPIDCgroupEntries(pid_t pid) {
     //
     // 2:cpu:/path/to/cgroup
     // 1:memory:/
+    // 0::/
+    //
+    // The last form is the cgroup v2 unified hierarchy, which has an empty
+    // controller list.
 
     PIDCgroupEntry entry;
     std::vector<std::string> fields =
-        absl::StrSplit(line, absl::ByChar(':'), absl::SkipEmpty());
+        absl::StrSplit(line, absl::MaxSplits(':', 2));
+    if (fields.size() != 3) {
+      return PosixError(EINVAL, absl::StrCat("Malformed entry: ", line));
+    }
 
     ASSIGN_OR_RETURN_ERRNO(entry.hierarchy, Atoi<uint32_t>(fields[0]));
     entry.controllers = fields[1];
@@ -123,11 +123,17 @@ class Mounter {
 
   PosixErrorOr<Cgroup> MountCgroupfs(std::string mopts);
 
+  // Mounts the cgroup v2 unified hierarchy.
+  PosixErrorOr<Cgroup> MountCgroup2fs(std::string mopts);
+
   PosixError Unmount(const Cgroup& c);
 
   void release(const Cgroup& c);
 
  private:
+  PosixErrorOr<Cgroup> MountFilesystem(const std::string& fstype,
+                                       const std::string& mopts);
+
   // The destruction order of these members avoids errors during cleanup. We
   // first unmount (by executing the mounts_ cleanups), then delete the
   // mountpoint subdirs, then delete the root.
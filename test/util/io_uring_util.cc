@@ -60,6 +60,8 @@ IOUring::IOUring(FileDescriptor &&fd, unsigned int entries,
       reinterpret_cast<char *>(cq_ptr_) + params.cq_off.overflow);
   sq_dropped_ptr_ = reinterpret_cast<uint32_t *>(
       reinterpret_cast<char *>(sq_ptr_) + params.sq_off.dropped);
+  sq_flags_ptr_ = reinterpret_cast<uint32_t *>(
+      reinterpret_cast<char *>(sq_ptr_) + params.sq_off.flags);
 
   sq_mask_ = *(reinterpret_cast<uint32_t *>(reinterpret_cast<char *>(sq_ptr_) +
                                             params.sq_off.ring_mask));
@@ -89,6 +91,10 @@ uint32_t IOUring::load_sq_dropped() {
   return io_uring_atomic_read(sq_dropped_ptr_);
 }
 
+uint32_t IOUring::load_sq_flags() {
+  return io_uring_atomic_read(sq_flags_ptr_);
+}
+
 void IOUring::store_cq_head(uint32_t cq_head_val) {
   io_uring_atomic_write(cq_head_ptr_, cq_head_val);
 }
@@ -102,6 +108,10 @@ int IOUring::Enter(unsigned int to_submit, unsigned int min_complete,
   return IOUringEnter(iouringfd_.get(), to_submit, min_complete, flags, sig);
 }
 
+int IOUring::Register(unsigned int opcode, void *arg, unsigned int nr_args) {
+  return IOUringRegister(iouringfd_.get(), opcode, arg, nr_args);
+}
+
 IOUringCqe *IOUring::get_cqes() { return cqes_; }
 
 IOUringSqe *IOUring::get_sqes() {
@@ -32,13 +32,42 @@ namespace testing {
 
 #define __NR_io_uring_setup 425
 #define __NR_io_uring_enter 426
+#define __NR_io_uring_register 427
 
 // io_uring_setup(2) flags.
+#define IORING_SETUP_IOPOLL (1U << 0)
 #define IORING_SETUP_SQPOLL (1U << 1)
 #define IORING_SETUP_CQSIZE (1U << 3)
 
 // io_uring_enter(2) flags
 #define IORING_ENTER_GETEVENTS (1U << 0)
+#define IORING_ENTER_SQ_WAKEUP (1U << 1)
+#define IORING_ENTER_SQ_WAIT (1U << 2)
+
+// io_uring_register(2) opcodes.
+#define IORING_REGISTER_BUFFERS 0
+#define IORING_UNREGISTER_BUFFERS 1
+#define IORING_REGISTER_FILES 2
+#define IORING_UNREGISTER_FILES 3
+#define IORING_REGISTER_EVENTFD 4
+#define IORING_UNREGISTER_EVENTFD 5
+#define IORING_REGISTER_FILES_UPDATE 6
+#define IORING_REGISTER_EVENTFD_ASYNC 7
+#define IORING_REGISTER_PROBE 8
+
+// SQ ring flags.
+#define IORING_SQ_NEED_WAKEUP (1U << 0)
+
+// SQE flags.
+#define IOSQE_FIXED_FILE (1U << 0)
+#define IOSQE_IO_DRAIN (1U << 1)
+#define IOSQE_IO_LINK (1U << 2)
+#define IOSQE_IO_HARDLINK (1U << 3)
+
+// IORING_OP_TIMEOUT flags.
+#define IORING_TIMEOUT_ABS (1U << 0)
+
+#define IO_URING_OP_SUPPORTED (1U << 0)
 
 #define IORING_FEAT_SINGLE_MMAP (1U << 0)
 
@@ -49,6 +78,26 @@ namespace testing {
 // IO_URING operation codes.
 #define IORING_OP_NOP 0
 #define IORING_OP_READV 1
+#define IORING_OP_WRITEV 2
+#define IORING_OP_FSYNC 3
+#define IORING_OP_READ_FIXED 4
+#define IORING_OP_WRITE_FIXED 5
+#define IORING_OP_POLL_ADD 6
+#define IORING_OP_POLL_REMOVE 7
+#define IORING_OP_SENDMSG 9
+#define IORING_OP_RECVMSG 10
+#define IORING_OP_TIMEOUT 11
+#define IORING_OP_ACCEPT 13
+#define IORING_OP_LINK_TIMEOUT 15
+#define IORING_OP_CONNECT 16
+#define IORING_OP_OPENAT 18
+#define IORING_OP_CLOSE 19
+#define IORING_OP_STATX 21
+#define IORING_OP_READ 22
+#define IORING_OP_WRITE 23
+#define IORING_OP_SEND 26
+#define IORING_OP_RECV 27
+#define IORING_OP_LAST 28
 
 #define BLOCK_SZ kPageSize
 
@@ -151,6 +200,32 @@ struct io_uring_sqe {
   };
 };
 
+struct io_uring_probe_op {
+  uint8_t op;
+  uint8_t resv;
+  uint16_t flags;
+  uint32_t resv2;
+};
+
+struct io_uring_probe {
+  uint8_t last_op;
+  uint8_t ops_len;
+  uint16_t resv;
+  uint32_t resv2[3];
+  struct io_uring_probe_op ops[0];
+};
+
+struct io_uring_files_update {
+  uint32_t offset;
+  uint32_t resv;
+  uint64_t fds;
+};
+
+struct io_uring_timespec {
+  int64_t tv_sec;
+  int64_t tv_nsec;
+};
+
 using IOSqringOffsets = struct io_sqring_offsets;
 using ICqringOffsets = struct io_cqring_offsets;
 using IOUringCqe = struct io_uring_cqe;
@@ -173,10 +248,12 @@ class IOUring {
   uint32_t load_sq_tail();
   uint32_t load_cq_overflow();
   uint32_t load_sq_dropped();
+  uint32_t load_sq_flags();
   void store_cq_head(uint32_t cq_head_val);
   void store_sq_tail(uint32_t sq_tail_val);
   int Enter(unsigned int to_submit, unsigned int min_complete,
             unsigned int flags, sigset_t *sig);
+  int Register(unsigned int opcode, void *arg, unsigned int nr_args);
 
   IOUringCqe *get_cqes();
   IOUringSqe *get_sqes();
@@ -199,6 +276,7 @@ class IOUring {
   uint32_t *sq_tail_ptr_ = nullptr;
   uint32_t *cq_overflow_ptr_ = nullptr;
   uint32_t *sq_dropped_ptr_ = nullptr;
+  uint32_t *sq_flags_ptr_ = nullptr;
   void *sq_ptr_ = nullptr;
   void *cq_ptr_ = nullptr;
   void *sqe_ptr_ = nullptr;
@@ -216,10 +294,21 @@ inline int IOUringEnter(unsigned int fd, unsigned int to_submit,
   return syscall(__NR_io_uring_enter, fd, to_submit, min_complete, flags, sig);
 }
 
-// Returns a new iouringfd with the given number of entries.
+// This is a wrapper for the io_uring_register(2) system call.
+inline int IOUringRegister(unsigned int fd, unsigned int opcode, void *arg,
+                           unsigned int nr_args) {
+  return syscall(__NR_io_uring_register, fd, opcode, arg, nr_args);
+}
+
+// Returns a new iouringfd with the given number of entries. Only the setup
+// flags and the SQPOLL idle time of params are passed to io_uring_setup(2).
 inline PosixErrorOr<FileDescriptor> NewIOUringFD(uint32_t entries,
                                                  IOUringParams &params) {
+  uint32_t flags = params.flags;
+  uint32_t sq_thread_idle = params.sq_thread_idle;
   memset(&params, 0, sizeof(params));
+  params.flags = flags;
+  params.sq_thread_idle = sq_thread_idle;
   int fd = IOUringSetup(entries, &params);
   MaybeSave();
   if (fd < 0) {